@@ -0,0 +1,284 @@
+package v1alpha1
+
+import (
+	utilconversion "sigs.k8s.io/cluster-api/util/conversion"
+	"sigs.k8s.io/controller-runtime/pkg/conversion"
+
+	infrav1 "github.com/kube-dc/cluster-api-provider-cloudsigma/api/v1beta1"
+)
+
+// Conversions below copy field by field. Fields that only exist in v1beta1 are carried
+// across a v1beta1 -> v1alpha1 -> v1beta1 round trip in the cluster.x-k8s.io/conversion-data
+// annotation and restored in ConvertTo; objects created as v1alpha1 get their defaults there.
+
+// ConvertTo converts this CloudSigmaCluster to the hub (v1beta1) version.
+func (src *CloudSigmaCluster) ConvertTo(dstRaw conversion.Hub) error {
+	dst := dstRaw.(*infrav1.CloudSigmaCluster)
+
+	src.ObjectMeta.DeepCopyInto(&dst.ObjectMeta)
+	convertClusterSpecToHub(&src.Spec, &dst.Spec)
+	convertClusterStatusToHub(&src.Status, &dst.Status)
+
+	restored := &infrav1.CloudSigmaCluster{}
+	if ok, err := utilconversion.UnmarshalData(dst, restored); err != nil || !ok {
+		return err
+	}
+	dst.Status.Phase = restored.Status.Phase
+
+	return nil
+}
+
+// ConvertFrom converts from the hub (v1beta1) version to this CloudSigmaCluster.
+func (dst *CloudSigmaCluster) ConvertFrom(srcRaw conversion.Hub) error {
+	src := srcRaw.(*infrav1.CloudSigmaCluster)
+
+	src.ObjectMeta.DeepCopyInto(&dst.ObjectMeta)
+	convertClusterSpecFromHub(&src.Spec, &dst.Spec)
+	convertClusterStatusFromHub(&src.Status, &dst.Status)
+
+	// Preserve the hub object so fields unknown to v1alpha1 survive the round trip
+	return utilconversion.MarshalData(src, dst)
+}
+
+// ConvertTo converts this CloudSigmaClusterList to the hub (v1beta1) version.
+func (src *CloudSigmaClusterList) ConvertTo(dstRaw conversion.Hub) error {
+	dst := dstRaw.(*infrav1.CloudSigmaClusterList)
+
+	src.ListMeta.DeepCopyInto(&dst.ListMeta)
+	dst.Items = make([]infrav1.CloudSigmaCluster, len(src.Items))
+	for i := range src.Items {
+		if err := src.Items[i].ConvertTo(&dst.Items[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ConvertFrom converts from the hub (v1beta1) version to this CloudSigmaClusterList.
+func (dst *CloudSigmaClusterList) ConvertFrom(srcRaw conversion.Hub) error {
+	src := srcRaw.(*infrav1.CloudSigmaClusterList)
+
+	src.ListMeta.DeepCopyInto(&dst.ListMeta)
+	dst.Items = make([]CloudSigmaCluster, len(src.Items))
+	for i := range src.Items {
+		if err := dst.Items[i].ConvertFrom(&src.Items[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ConvertTo converts this CloudSigmaMachine to the hub (v1beta1) version.
+func (src *CloudSigmaMachine) ConvertTo(dstRaw conversion.Hub) error {
+	dst := dstRaw.(*infrav1.CloudSigmaMachine)
+
+	src.ObjectMeta.DeepCopyInto(&dst.ObjectMeta)
+	convertMachineSpecToHub(&src.Spec, &dst.Spec)
+	convertMachineStatusToHub(&src.Status, &dst.Status)
+
+	restored := &infrav1.CloudSigmaMachine{}
+	if ok, err := utilconversion.UnmarshalData(dst, restored); err != nil || !ok {
+		return err
+	}
+	// Restore v1beta1-only fields here as they are added.
+	dst.Status.VNCPasswordSecretRef = restored.Status.VNCPasswordSecretRef
+	dst.Status.Console = restored.Status.Console
+	dst.Status.AllocatedIPs = restored.Status.AllocatedIPs
+	dst.Spec.SMP = restored.Spec.SMP
+	dst.Spec.CPUModel = restored.Spec.CPUModel
+	dst.Spec.CPUFlags = restored.Spec.CPUFlags
+	for i := range dst.Spec.Disks {
+		if i < len(restored.Spec.Disks) {
+			dst.Spec.Disks[i].Clone = restored.Spec.Disks[i].Clone
+			dst.Spec.Disks[i].Media = restored.Spec.Disks[i].Media
+		}
+	}
+
+	return nil
+}
+
+// ConvertFrom converts from the hub (v1beta1) version to this CloudSigmaMachine.
+func (dst *CloudSigmaMachine) ConvertFrom(srcRaw conversion.Hub) error {
+	src := srcRaw.(*infrav1.CloudSigmaMachine)
+
+	src.ObjectMeta.DeepCopyInto(&dst.ObjectMeta)
+	convertMachineSpecFromHub(&src.Spec, &dst.Spec)
+	convertMachineStatusFromHub(&src.Status, &dst.Status)
+
+	// Preserve the hub object so fields unknown to v1alpha1 survive the round trip
+	return utilconversion.MarshalData(src, dst)
+}
+
+// ConvertTo converts this CloudSigmaMachineList to the hub (v1beta1) version.
+func (src *CloudSigmaMachineList) ConvertTo(dstRaw conversion.Hub) error {
+	dst := dstRaw.(*infrav1.CloudSigmaMachineList)
+
+	src.ListMeta.DeepCopyInto(&dst.ListMeta)
+	dst.Items = make([]infrav1.CloudSigmaMachine, len(src.Items))
+	for i := range src.Items {
+		if err := src.Items[i].ConvertTo(&dst.Items[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ConvertFrom converts from the hub (v1beta1) version to this CloudSigmaMachineList.
+func (dst *CloudSigmaMachineList) ConvertFrom(srcRaw conversion.Hub) error {
+	src := srcRaw.(*infrav1.CloudSigmaMachineList)
+
+	src.ListMeta.DeepCopyInto(&dst.ListMeta)
+	dst.Items = make([]CloudSigmaMachine, len(src.Items))
+	for i := range src.Items {
+		if err := dst.Items[i].ConvertFrom(&src.Items[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func convertClusterSpecToHub(in *CloudSigmaClusterSpec, out *infrav1.CloudSigmaClusterSpec) {
+	out.ControlPlaneEndpoint = in.ControlPlaneEndpoint
+	out.Region = in.Region
+	out.VLAN = nil
+	if in.VLAN != nil {
+		out.VLAN = &infrav1.VLANSpec{UUID: in.VLAN.UUID, Name: in.VLAN.Name, CIDR: in.VLAN.CIDR}
+	}
+	out.LoadBalancer = nil
+	if in.LoadBalancer != nil {
+		out.LoadBalancer = &infrav1.LoadBalancerSpec{Enabled: in.LoadBalancer.Enabled, Type: in.LoadBalancer.Type}
+	}
+	out.CredentialsRef = convertObjectReferenceToHub(in.CredentialsRef)
+	out.UserEmail = in.UserEmail
+	out.UserRef = convertObjectReferenceToHub(in.UserRef)
+}
+
+func convertClusterSpecFromHub(in *infrav1.CloudSigmaClusterSpec, out *CloudSigmaClusterSpec) {
+	out.ControlPlaneEndpoint = in.ControlPlaneEndpoint
+	out.Region = in.Region
+	out.VLAN = nil
+	if in.VLAN != nil {
+		out.VLAN = &VLANSpec{UUID: in.VLAN.UUID, Name: in.VLAN.Name, CIDR: in.VLAN.CIDR}
+	}
+	out.LoadBalancer = nil
+	if in.LoadBalancer != nil {
+		out.LoadBalancer = &LoadBalancerSpec{Enabled: in.LoadBalancer.Enabled, Type: in.LoadBalancer.Type}
+	}
+	out.CredentialsRef = convertObjectReferenceFromHub(in.CredentialsRef)
+	out.UserEmail = in.UserEmail
+	out.UserRef = convertObjectReferenceFromHub(in.UserRef)
+}
+
+func convertObjectReferenceToHub(in *ObjectReference) *infrav1.ObjectReference {
+	if in == nil {
+		return nil
+	}
+	return &infrav1.ObjectReference{Name: in.Name, Namespace: in.Namespace}
+}
+
+func convertObjectReferenceFromHub(in *infrav1.ObjectReference) *ObjectReference {
+	if in == nil {
+		return nil
+	}
+	return &ObjectReference{Name: in.Name, Namespace: in.Namespace}
+}
+
+func convertClusterStatusToHub(in *CloudSigmaClusterStatus, out *infrav1.CloudSigmaClusterStatus) {
+	out.Ready = in.Ready
+	out.Network = nil
+	if in.Network != nil {
+		out.Network = &infrav1.NetworkStatus{VLANUUID: in.Network.VLANUUID, CIDR: in.Network.CIDR}
+	}
+	out.LoadBalancer = nil
+	if in.LoadBalancer != nil {
+		out.LoadBalancer = &infrav1.LoadBalancerStatus{IP: in.LoadBalancer.IP, Ready: in.LoadBalancer.Ready}
+	}
+	out.Conditions = copyConditions(in.Conditions)
+	out.FailureReason = copyString(in.FailureReason)
+	out.FailureMessage = copyString(in.FailureMessage)
+}
+
+func convertClusterStatusFromHub(in *infrav1.CloudSigmaClusterStatus, out *CloudSigmaClusterStatus) {
+	out.Ready = in.Ready
+	out.Network = nil
+	if in.Network != nil {
+		out.Network = &NetworkStatus{VLANUUID: in.Network.VLANUUID, CIDR: in.Network.CIDR}
+	}
+	out.LoadBalancer = nil
+	if in.LoadBalancer != nil {
+		out.LoadBalancer = &LoadBalancerStatus{IP: in.LoadBalancer.IP, Ready: in.LoadBalancer.Ready}
+	}
+	out.Conditions = copyConditions(in.Conditions)
+	out.FailureReason = copyString(in.FailureReason)
+	out.FailureMessage = copyString(in.FailureMessage)
+}
+
+func convertMachineSpecToHub(in *CloudSigmaMachineSpec, out *infrav1.CloudSigmaMachineSpec) {
+	out.ProviderID = copyString(in.ProviderID)
+	out.CPU = in.CPU
+	out.Memory = in.Memory
+	out.Disks = nil
+	if in.Disks != nil {
+		out.Disks = make([]infrav1.CloudSigmaDisk, len(in.Disks))
+		for i, d := range in.Disks {
+			out.Disks[i] = infrav1.CloudSigmaDisk{UUID: d.UUID, Device: d.Device, BootOrder: d.BootOrder, Size: d.Size}
+		}
+	}
+	out.NICs = nil
+	if in.NICs != nil {
+		out.NICs = make([]infrav1.CloudSigmaNIC, len(in.NICs))
+		for i, n := range in.NICs {
+			out.NICs[i] = infrav1.CloudSigmaNIC{VLAN: n.VLAN, IPv4Conf: infrav1.CloudSigmaIPConf{Conf: n.IPv4Conf.Conf}}
+			if n.IPv4Conf.IP != nil {
+				out.NICs[i].IPv4Conf.IP = &infrav1.CloudSigmaIPRef{UUID: n.IPv4Conf.IP.UUID}
+			}
+		}
+	}
+	out.Tags = copyStrings(in.Tags)
+	out.Meta = copyStringMap(in.Meta)
+}
+
+func convertMachineSpecFromHub(in *infrav1.CloudSigmaMachineSpec, out *CloudSigmaMachineSpec) {
+	out.ProviderID = copyString(in.ProviderID)
+	out.CPU = in.CPU
+	out.Memory = in.Memory
+	out.Disks = nil
+	if in.Disks != nil {
+		out.Disks = make([]CloudSigmaDisk, len(in.Disks))
+		for i, d := range in.Disks {
+			out.Disks[i] = CloudSigmaDisk{UUID: d.UUID, Device: d.Device, BootOrder: d.BootOrder, Size: d.Size}
+		}
+	}
+	out.NICs = nil
+	if in.NICs != nil {
+		out.NICs = make([]CloudSigmaNIC, len(in.NICs))
+		for i, n := range in.NICs {
+			out.NICs[i] = CloudSigmaNIC{VLAN: n.VLAN, IPv4Conf: CloudSigmaIPConf{Conf: n.IPv4Conf.Conf}}
+			if n.IPv4Conf.IP != nil {
+				out.NICs[i].IPv4Conf.IP = &CloudSigmaIPRef{UUID: n.IPv4Conf.IP.UUID}
+			}
+		}
+	}
+	out.Tags = copyStrings(in.Tags)
+	out.Meta = copyStringMap(in.Meta)
+}
+
+func convertMachineStatusToHub(in *CloudSigmaMachineStatus, out *infrav1.CloudSigmaMachineStatus) {
+	out.Ready = in.Ready
+	out.InstanceID = in.InstanceID
+	out.InstanceState = in.InstanceState
+	out.Addresses = copyAddresses(in.Addresses)
+	out.Conditions = copyConditions(in.Conditions)
+	out.FailureReason = copyString(in.FailureReason)
+	out.FailureMessage = copyString(in.FailureMessage)
+}
+
+func convertMachineStatusFromHub(in *infrav1.CloudSigmaMachineStatus, out *CloudSigmaMachineStatus) {
+	out.Ready = in.Ready
+	out.InstanceID = in.InstanceID
+	out.InstanceState = in.InstanceState
+	out.Addresses = copyAddresses(in.Addresses)
+	out.Conditions = copyConditions(in.Conditions)
+	out.FailureReason = copyString(in.FailureReason)
+	out.FailureMessage = copyString(in.FailureMessage)
+}
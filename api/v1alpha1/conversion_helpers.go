@@ -0,0 +1,49 @@
+package v1alpha1
+
+import (
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+)
+
+func copyString(in *string) *string {
+	if in == nil {
+		return nil
+	}
+	out := *in
+	return &out
+}
+
+func copyStrings(in []string) []string {
+	if in == nil {
+		return nil
+	}
+	return append([]string{}, in...)
+}
+
+func copyStringMap(in map[string]string) map[string]string {
+	if in == nil {
+		return nil
+	}
+	out := make(map[string]string, len(in))
+	for k, v := range in {
+		out[k] = v
+	}
+	return out
+}
+
+func copyAddresses(in []clusterv1.MachineAddress) []clusterv1.MachineAddress {
+	if in == nil {
+		return nil
+	}
+	return append([]clusterv1.MachineAddress{}, in...)
+}
+
+func copyConditions(in clusterv1.Conditions) clusterv1.Conditions {
+	if in == nil {
+		return nil
+	}
+	out := make(clusterv1.Conditions, len(in))
+	for i := range in {
+		in[i].DeepCopyInto(&out[i])
+	}
+	return out
+}
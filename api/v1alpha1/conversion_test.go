@@ -0,0 +1,32 @@
+package v1alpha1
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	utilconversion "sigs.k8s.io/cluster-api/util/conversion"
+
+	infrav1 "github.com/kube-dc/cluster-api-provider-cloudsigma/api/v1beta1"
+)
+
+func TestFuzzyConversion(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := AddToScheme(scheme); err != nil {
+		t.Fatalf("failed to add v1alpha1 to scheme: %v", err)
+	}
+	if err := infrav1.AddToScheme(scheme); err != nil {
+		t.Fatalf("failed to add v1beta1 to scheme: %v", err)
+	}
+
+	t.Run("for CloudSigmaCluster", utilconversion.FuzzTestFunc(utilconversion.FuzzTestFuncInput{
+		Scheme: scheme,
+		Hub:    &infrav1.CloudSigmaCluster{},
+		Spoke:  &CloudSigmaCluster{},
+	}))
+
+	t.Run("for CloudSigmaMachine", utilconversion.FuzzTestFunc(utilconversion.FuzzTestFuncInput{
+		Scheme: scheme,
+		Hub:    &infrav1.CloudSigmaMachine{},
+		Spoke:  &CloudSigmaMachine{},
+	}))
+}
@@ -13,6 +13,22 @@ const (
 	NetworkCreateFailedReason = "NetworkCreateFailed"
 )
 
+// ClusterPhase is a coarse summary of where a CloudSigmaCluster is in its lifecycle
+type ClusterPhase string
+
+const (
+	// ClusterPhasePending means the cluster has not been picked up by the controller yet
+	ClusterPhasePending ClusterPhase = "Pending"
+	// ClusterPhaseProvisioning means the cluster infrastructure is being set up
+	ClusterPhaseProvisioning ClusterPhase = "Provisioning"
+	// ClusterPhaseReady means the cluster infrastructure is ready
+	ClusterPhaseReady ClusterPhase = "Ready"
+	// ClusterPhaseFailed means reconciliation hit an error that needs user intervention
+	ClusterPhaseFailed ClusterPhase = "Failed"
+	// ClusterPhaseDeleting means the cluster infrastructure is being torn down
+	ClusterPhaseDeleting ClusterPhase = "Deleting"
+)
+
 // CloudSigmaClusterSpec defines the desired state of CloudSigmaCluster
 type CloudSigmaClusterSpec struct {
 	// ControlPlaneEndpoint represents the endpoint used to communicate with the control plane.
@@ -91,6 +107,11 @@ type CloudSigmaClusterStatus struct {
 	// Ready indicates the cluster infrastructure is ready
 	Ready bool `json:"ready"`
 
+	// Phase summarizes the cluster lifecycle: Pending, Provisioning, Ready, Failed or Deleting
+	// +optional
+	// +kubebuilder:validation:Enum=Pending;Provisioning;Ready;Failed;Deleting
+	Phase ClusterPhase `json:"phase,omitempty"`
+
 	// Network contains the cluster network information
 	// +optional
 	Network *NetworkStatus `json:"network,omitempty"`
@@ -138,6 +159,7 @@ type LoadBalancerStatus struct {
 // +kubebuilder:subresource:status
 // +kubebuilder:storageversion
 // +kubebuilder:printcolumn:name="Cluster",type="string",JSONPath=".metadata.labels.cluster\\.x-k8s\\.io/cluster-name",description="Cluster"
+// +kubebuilder:printcolumn:name="Phase",type="string",JSONPath=".status.phase",description="Cluster lifecycle phase"
 // +kubebuilder:printcolumn:name="Ready",type="string",JSONPath=".status.ready",description="Cluster infrastructure is ready"
 // +kubebuilder:printcolumn:name="Region",type="string",JSONPath=".spec.region",description="CloudSigma region"
 // +kubebuilder:printcolumn:name="Endpoint",type="string",JSONPath=".spec.controlPlaneEndpoint.host",description="Control plane endpoint"
@@ -1,6 +1,7 @@
 package v1beta1
 
 import (
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
 )
@@ -17,6 +18,78 @@ const (
 
 	// ServerNotRunningReason used when server is not in running state
 	ServerNotRunningReason = "ServerNotRunning"
+
+	// ServerStartTimeoutReason used when the server did not reach running within the start timeout
+	ServerStartTimeoutReason = "ServerStartTimeout"
+
+	// QuotaExceededReason used when server creation is held off because the CloudSigma account is at capacity
+	QuotaExceededReason = "QuotaExceeded"
+
+	// ServerAdoptedReason used when an existing server was adopted instead of creating a new one
+	ServerAdoptedReason = "ServerAdopted"
+
+	// DisksResizedCondition reports whether the server's drives match the sizes in spec.disks
+	DisksResizedCondition clusterv1.ConditionType = "DisksResized"
+
+	// DiskResizingReason used while a drive is being grown (the server is stopped meanwhile)
+	DiskResizingReason = "DiskResizing"
+
+	// DiskResizeFailedReason used when growing a drive fails
+	DiskResizeFailedReason = "DiskResizeFailed"
+
+	// NodeDrainedCondition reports whether the server may be stopped on delete: the Machine's
+	// pre-terminate hooks are gone and no drain of its node is in progress (or the drain timed out)
+	NodeDrainedCondition clusterv1.ConditionType = "NodeDrained"
+
+	// WaitingForPreTerminateHookReason used while the Machine still carries a pre-terminate delete hook
+	WaitingForPreTerminateHookReason = "WaitingForPreTerminateHook"
+
+	// WaitingForNodeDrainReason used while the Machine's node is being drained
+	WaitingForNodeDrainReason = "WaitingForNodeDrain"
+
+	// NodeDrainTimeoutReason used when the server is stopped without a completed drain because the timeout passed
+	NodeDrainTimeoutReason = "NodeDrainTimeout"
+
+	// ServerRecreatingReason used while the server of a machine whose node never joined is replaced
+	// because its bootstrap data changed
+	ServerRecreatingReason = "ServerRecreating"
+
+	// AllowDiskResizeAnnotation opts a CloudSigmaMachine into growing its drives in place when
+	// spec.disks[].size is increased. Resizing stops and restarts the server.
+	AllowDiskResizeAnnotation = "cloudsigmamachine.infrastructure.cluster.x-k8s.io/allow-disk-resize"
+
+	// CreatingAnnotation records when the controller submitted a server create request (RFC3339).
+	// It is cleared once status.instanceID is persisted; while it is recent, a retry waits for the
+	// server to show up in the API instead of creating a second one.
+	CreatingAnnotation = "cloudsigmamachine.infrastructure.cluster.x-k8s.io/creating"
+
+	// StartRetriedAnnotation records when the controller stopped a server stuck starting to start
+	// it again (RFC3339). It limits the retry to once per start and is removed when the server runs.
+	StartRetriedAnnotation = "cloudsigmamachine.infrastructure.cluster.x-k8s.io/start-retried"
+
+	// BootstrapDataHashAnnotation records a hash of the bootstrap data secret name, format and
+	// content the server was created with. While the machine's node has not joined, a server whose
+	// bootstrap data changed since is recreated with the new data.
+	BootstrapDataHashAnnotation = "cloudsigmamachine.infrastructure.cluster.x-k8s.io/bootstrap-data-hash"
+
+	// OpenConsoleAnnotation requests a VNC console tunnel to the server. The value is how long the
+	// tunnel stays open as a Go duration ("30m"); "true" or an empty value uses the default. The
+	// controller removes the annotation when it closes the tunnel.
+	OpenConsoleAnnotation = "cloudsigmamachine.infrastructure.cluster.x-k8s.io/open-console"
+)
+
+// CPU flags accepted in CloudSigmaMachineSpec.CPUFlags
+const (
+	CPUFlagNUMA               = "numa"
+	CPUFlagCPUsInsteadOfCores = "cpus-instead-of-cores"
+	CPUFlagHVRelaxed          = "hv-relaxed"
+	CPUFlagHVTSC              = "hv-tsc"
+)
+
+// Drive media accepted in CloudSigmaDisk.Media
+const (
+	DiskMediaDisk  = "disk"
+	DiskMediaCDROM = "cdrom"
 )
 
 // CloudSigmaMachineSpec defines the desired state of CloudSigmaMachine
@@ -31,6 +104,25 @@ type CloudSigmaMachineSpec struct {
 	// +kubebuilder:validation:Maximum=100000
 	CPU int `json:"cpu"`
 
+	// SMP is the number of virtual CPU cores the CPU frequency is split across.
+	// CloudSigma derives it from the CPU frequency when unset.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=128
+	// +optional
+	SMP int `json:"smp,omitempty"`
+
+	// CPUModel selects the host CPU family the server runs on (CloudSigma's cpu_type), for
+	// workloads that need instruction set extensions of one vendor. CloudSigma picks one when unset.
+	// +kubebuilder:validation:Enum=amd;intel
+	// +optional
+	CPUModel string `json:"cpuModel,omitempty"`
+
+	// CPUFlags enables CPU and hypervisor options of the server: "numa" exposes the NUMA topology
+	// to the guest, "cpus-instead-of-cores" presents SMP as sockets rather than cores, and
+	// "hv-relaxed" and "hv-tsc" enable the Hyper-V relaxed timing and TSC enlightenments.
+	// +optional
+	CPUFlags []string `json:"cpuFlags,omitempty"`
+
 	// Memory is the memory size in MB
 	// +kubebuilder:validation:Minimum=512
 	// +kubebuilder:validation:Maximum=524288
@@ -49,7 +141,9 @@ type CloudSigmaMachineSpec struct {
 	// +optional
 	Tags []string `json:"tags,omitempty"`
 
-	// Meta is custom metadata for the server
+	// Meta is custom metadata for the server.
+	// The "bootstrap-format" key overrides the bootstrap data format ("cloud-config" or "ignition").
+	// It is not copied to the server; any other value marks the machine failed.
 	// +optional
 	Meta map[string]string `json:"meta,omitempty"`
 }
@@ -63,11 +157,35 @@ type CloudSigmaDisk struct {
 	// +kubebuilder:validation:Enum=virtio;ide
 	Device string `json:"device"`
 
-	// BootOrder is the boot priority
+	// BootOrder is the boot priority: 1 for the boot disk, which is attached at device
+	// channel 0:0, higher values for further bootable disks, 0 for a data disk. Data disks
+	// and further bootable disks are attached at the next free channels in list order.
 	BootOrder int `json:"boot_order"`
 
 	// Size is the disk size in bytes
 	Size int64 `json:"size"`
+
+	// Clone attaches a private copy of the drive or image named by UUID (the default). When
+	// false, the drive itself is attached so several machines can share it: it must be a drive
+	// in the account, multimount enabled to be shared, and cannot be a boot disk. CloudSigma
+	// has no read-only attachment, so the guest is expected to mount it read-only. The drive
+	// is never resized or deleted with the machine.
+	// +kubebuilder:default=true
+	// +optional
+	Clone *bool `json:"clone,omitempty"`
+
+	// Media is the CloudSigma media of the attached drive: "disk", or "cdrom" for an ISO image
+	// such as an installer or a configuration image. A clone is created with this media, and
+	// keeps the media of its source when unset; a shared drive must already have it. A cdrom
+	// keeps the size of its image, so its size must be 0.
+	// +kubebuilder:validation:Enum=disk;cdrom
+	// +optional
+	Media string `json:"media,omitempty"`
+}
+
+// IsCloned reports whether the disk is attached as a private clone of its source drive
+func (d CloudSigmaDisk) IsCloned() bool {
+	return d.Clone == nil || *d.Clone
 }
 
 // CloudSigmaNIC defines a network interface configuration
@@ -85,7 +203,9 @@ type CloudSigmaIPConf struct {
 	// +kubebuilder:validation:Enum=dhcp;static;manual
 	Conf string `json:"conf"`
 
-	// IP is the IP address reference for static configuration
+	// IP is the IP address reference for static configuration. When a static NIC does not set it,
+	// the controller allocates a free IP from the NIC's VLAN, or a public IP for a NIC without a VLAN,
+	// and releases it when the machine is deleted.
 	// +optional
 	IP *CloudSigmaIPRef `json:"ip,omitempty"`
 }
@@ -124,6 +244,37 @@ type CloudSigmaMachineStatus struct {
 	// FailureMessage indicates a human-readable message about why the machine is in a failed state
 	// +optional
 	FailureMessage *string `json:"failureMessage,omitempty"`
+
+	// VNCPasswordSecretRef names the Secret, in the machine's namespace, holding the server's
+	// console (VNC) password under the "password" key
+	// +optional
+	VNCPasswordSecretRef *corev1.LocalObjectReference `json:"vncPasswordSecretRef,omitempty"`
+
+	// Console describes the VNC console tunnel opened through the open-console annotation
+	// +optional
+	Console *ConsoleStatus `json:"console,omitempty"`
+
+	// AllocatedIPs lists the IPs the controller allocated for static NICs that do not set an IP
+	// +optional
+	AllocatedIPs []AllocatedIP `json:"allocatedIPs,omitempty"`
+}
+
+// AllocatedIP is an IP the controller allocated for a static NIC
+type AllocatedIP struct {
+	// NIC is the index of the NIC in spec.nics the IP is configured on
+	NIC int `json:"nic"`
+
+	// UUID is the IP address UUID
+	UUID string `json:"uuid"`
+}
+
+// ConsoleStatus describes an open VNC console tunnel
+type ConsoleStatus struct {
+	// SecretRef names the Secret, in the machine's namespace, holding the console URL under the "url" key
+	SecretRef corev1.LocalObjectReference `json:"secretRef"`
+
+	// ExpiresAt is when the controller closes the tunnel
+	ExpiresAt metav1.Time `json:"expiresAt"`
 }
 
 // +kubebuilder:object:root=true
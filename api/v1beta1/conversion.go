@@ -0,0 +1,13 @@
+package v1beta1
+
+// Hub marks CloudSigmaCluster as a conversion hub.
+func (*CloudSigmaCluster) Hub() {}
+
+// Hub marks CloudSigmaClusterList as a conversion hub.
+func (*CloudSigmaClusterList) Hub() {}
+
+// Hub marks CloudSigmaMachine as a conversion hub.
+func (*CloudSigmaMachine) Hub() {}
+
+// Hub marks CloudSigmaMachineList as a conversion hub.
+func (*CloudSigmaMachineList) Hub() {}
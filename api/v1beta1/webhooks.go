@@ -0,0 +1,299 @@
+package v1beta1
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// SetupWebhookWithManager registers the CloudSigmaCluster webhooks, including conversion.
+func (c *CloudSigmaCluster) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(c).
+		Complete()
+}
+
+// SetupWebhookWithManager registers the CloudSigmaMachine webhooks, including conversion,
+// defaulting and validation.
+func (m *CloudSigmaMachine) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(m).
+		WithDefaulter(&cloudSigmaMachineWebhook{}).
+		WithValidator(&cloudSigmaMachineWebhook{}).
+		Complete()
+}
+
+// SetupWebhookWithManager registers the CloudSigmaMachineTemplate defaulting and validation
+// webhooks, so a bad machine spec is rejected when the template is applied rather than once
+// per machine stamped from it.
+func (t *CloudSigmaMachineTemplate) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(t).
+		WithDefaulter(&cloudSigmaMachineTemplateWebhook{}).
+		WithValidator(&cloudSigmaMachineTemplateWebhook{}).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/mutate-infrastructure-cluster-x-k8s-io-v1beta1-cloudsigmamachine,mutating=true,failurePolicy=fail,sideEffects=None,groups=infrastructure.cluster.x-k8s.io,resources=cloudsigmamachines,verbs=create;update,versions=v1beta1,name=mcloudsigmamachine.kb.io,admissionReviewVersions=v1
+// +kubebuilder:webhook:path=/validate-infrastructure-cluster-x-k8s-io-v1beta1-cloudsigmamachine,mutating=false,failurePolicy=fail,sideEffects=None,groups=infrastructure.cluster.x-k8s.io,resources=cloudsigmamachines,verbs=create;update,versions=v1beta1,name=vcloudsigmamachine.kb.io,admissionReviewVersions=v1
+
+type cloudSigmaMachineWebhook struct{}
+
+var (
+	_ webhook.CustomDefaulter = &cloudSigmaMachineWebhook{}
+	_ webhook.CustomValidator = &cloudSigmaMachineWebhook{}
+)
+
+// Default implements webhook.CustomDefaulter
+func (*cloudSigmaMachineWebhook) Default(_ context.Context, obj runtime.Object) error {
+	m, ok := obj.(*CloudSigmaMachine)
+	if !ok {
+		return apierrors.NewBadRequest(fmt.Sprintf("expected a CloudSigmaMachine but got a %T", obj))
+	}
+	defaultCloudSigmaMachineSpec(&m.Spec)
+	return nil
+}
+
+// ValidateCreate implements webhook.CustomValidator
+func (w *cloudSigmaMachineWebhook) ValidateCreate(_ context.Context, obj runtime.Object) (admission.Warnings, error) {
+	m, allErrs, err := w.validate(obj)
+	if err != nil || len(allErrs) == 0 {
+		return nil, err
+	}
+	return nil, apierrors.NewInvalid(GroupVersion.WithKind("CloudSigmaMachine").GroupKind(), m.Name, allErrs)
+}
+
+// ValidateUpdate implements webhook.CustomValidator. Only violations the update introduces are
+// rejected, see validateUpdate.
+func (w *cloudSigmaMachineWebhook) ValidateUpdate(_ context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	_, oldErrs, err := w.validate(oldObj)
+	if err != nil {
+		return nil, err
+	}
+	m, allErrs, err := w.validate(newObj)
+	if err != nil {
+		return nil, err
+	}
+	return validateUpdate(GroupVersion.WithKind("CloudSigmaMachine").GroupKind(), m, oldErrs, allErrs)
+}
+
+// ValidateDelete implements webhook.CustomValidator
+func (*cloudSigmaMachineWebhook) ValidateDelete(_ context.Context, _ runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+func (*cloudSigmaMachineWebhook) validate(obj runtime.Object) (*CloudSigmaMachine, field.ErrorList, error) {
+	m, ok := obj.(*CloudSigmaMachine)
+	if !ok {
+		return nil, nil, apierrors.NewBadRequest(fmt.Sprintf("expected a CloudSigmaMachine but got a %T", obj))
+	}
+	return m, validateCloudSigmaMachineSpec(&m.Spec, field.NewPath("spec")), nil
+}
+
+// +kubebuilder:webhook:path=/mutate-infrastructure-cluster-x-k8s-io-v1beta1-cloudsigmamachinetemplate,mutating=true,failurePolicy=fail,sideEffects=None,groups=infrastructure.cluster.x-k8s.io,resources=cloudsigmamachinetemplates,verbs=create;update,versions=v1beta1,name=mcloudsigmamachinetemplate.kb.io,admissionReviewVersions=v1
+// +kubebuilder:webhook:path=/validate-infrastructure-cluster-x-k8s-io-v1beta1-cloudsigmamachinetemplate,mutating=false,failurePolicy=fail,sideEffects=None,groups=infrastructure.cluster.x-k8s.io,resources=cloudsigmamachinetemplates,verbs=create;update,versions=v1beta1,name=vcloudsigmamachinetemplate.kb.io,admissionReviewVersions=v1
+
+type cloudSigmaMachineTemplateWebhook struct{}
+
+var (
+	_ webhook.CustomDefaulter = &cloudSigmaMachineTemplateWebhook{}
+	_ webhook.CustomValidator = &cloudSigmaMachineTemplateWebhook{}
+)
+
+// Default implements webhook.CustomDefaulter
+func (*cloudSigmaMachineTemplateWebhook) Default(_ context.Context, obj runtime.Object) error {
+	t, ok := obj.(*CloudSigmaMachineTemplate)
+	if !ok {
+		return apierrors.NewBadRequest(fmt.Sprintf("expected a CloudSigmaMachineTemplate but got a %T", obj))
+	}
+	defaultCloudSigmaMachineSpec(&t.Spec.Template.Spec)
+	return nil
+}
+
+// ValidateCreate implements webhook.CustomValidator
+func (w *cloudSigmaMachineTemplateWebhook) ValidateCreate(_ context.Context, obj runtime.Object) (admission.Warnings, error) {
+	t, allErrs, err := w.validate(obj)
+	if err != nil || len(allErrs) == 0 {
+		return nil, err
+	}
+	return nil, apierrors.NewInvalid(GroupVersion.WithKind("CloudSigmaMachineTemplate").GroupKind(), t.Name, allErrs)
+}
+
+// ValidateUpdate implements webhook.CustomValidator. Only violations the update introduces are
+// rejected, see validateUpdate.
+func (w *cloudSigmaMachineTemplateWebhook) ValidateUpdate(_ context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	_, oldErrs, err := w.validate(oldObj)
+	if err != nil {
+		return nil, err
+	}
+	t, allErrs, err := w.validate(newObj)
+	if err != nil {
+		return nil, err
+	}
+	return validateUpdate(GroupVersion.WithKind("CloudSigmaMachineTemplate").GroupKind(), t, oldErrs, allErrs)
+}
+
+// ValidateDelete implements webhook.CustomValidator
+func (*cloudSigmaMachineTemplateWebhook) ValidateDelete(_ context.Context, _ runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+func (*cloudSigmaMachineTemplateWebhook) validate(obj runtime.Object) (*CloudSigmaMachineTemplate, field.ErrorList, error) {
+	t, ok := obj.(*CloudSigmaMachineTemplate)
+	if !ok {
+		return nil, nil, apierrors.NewBadRequest(fmt.Sprintf("expected a CloudSigmaMachineTemplate but got a %T", obj))
+	}
+	specPath := field.NewPath("spec", "template", "spec")
+	spec := &t.Spec.Template.Spec
+	allErrs := validateCloudSigmaMachineSpec(spec, specPath)
+
+	// Every machine stamped from the template gets the same spec, so per-machine values are invalid
+	if spec.ProviderID != nil && *spec.ProviderID != "" {
+		allErrs = append(allErrs, field.Forbidden(specPath.Child("providerID"),
+			"providerID is set per machine and cannot be part of a template"))
+	}
+	for i, nic := range spec.NICs {
+		if nic.IPv4Conf.IP != nil && nic.IPv4Conf.IP.UUID != "" {
+			allErrs = append(allErrs, field.Forbidden(specPath.Child("nics").Index(i).Child("ipv4_conf", "ip"),
+				"a static IP can only be assigned to one machine and cannot be part of a template"))
+		}
+	}
+
+	return t, allErrs, nil
+}
+
+// validateUpdate rejects only the violations an update introduces. Objects created before a
+// rule existed keep working: their existing violations are returned as warnings. Nothing is
+// rejected once the object is being deleted, so its finalizer can always be removed.
+func validateUpdate(kind schema.GroupKind, obj metav1.Object, oldErrs, allErrs field.ErrorList) (admission.Warnings, error) {
+	if obj.GetDeletionTimestamp() != nil {
+		return nil, nil
+	}
+	existing := make(map[string]bool, len(oldErrs))
+	for _, err := range oldErrs {
+		existing[err.Error()] = true
+	}
+	var warnings admission.Warnings
+	var newErrs field.ErrorList
+	for _, err := range allErrs {
+		if existing[err.Error()] {
+			warnings = append(warnings, err.Error())
+			continue
+		}
+		newErrs = append(newErrs, err)
+	}
+	if len(newErrs) == 0 {
+		return warnings, nil
+	}
+	return warnings, apierrors.NewInvalid(kind, obj.GetName(), newErrs)
+}
+
+// defaultCloudSigmaMachineSpec fills in values CreateServer would otherwise have to guess
+func defaultCloudSigmaMachineSpec(spec *CloudSigmaMachineSpec) {
+	for i := range spec.Disks {
+		if spec.Disks[i].Device == "" {
+			spec.Disks[i].Device = "virtio"
+		}
+	}
+	for i := range spec.NICs {
+		if spec.NICs[i].VLAN != "" && spec.NICs[i].IPv4Conf.Conf == "" {
+			spec.NICs[i].IPv4Conf.Conf = "dhcp"
+		}
+	}
+}
+
+// validateCloudSigmaMachineSpec checks the CPU options and the disk and NIC layout of a machine
+// spec beyond what the CRD schema can express
+func validateCloudSigmaMachineSpec(spec *CloudSigmaMachineSpec, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	disksPath := fldPath.Child("disks")
+	if len(spec.Disks) == 0 {
+		allErrs = append(allErrs, field.Required(disksPath, "at least one disk is required"))
+	}
+	bootOrders := make(map[int]int, len(spec.Disks))
+	for i, disk := range spec.Disks {
+		diskPath := disksPath.Index(i)
+		if disk.UUID == "" {
+			allErrs = append(allErrs, field.Required(diskPath.Child("uuid"), "drive or image UUID is required"))
+		}
+		if disk.Device != "virtio" && disk.Device != "ide" {
+			allErrs = append(allErrs, field.NotSupported(diskPath.Child("device"), disk.Device, []string{"virtio", "ide"}))
+		}
+		if disk.Size < 0 {
+			allErrs = append(allErrs, field.Invalid(diskPath.Child("size"), disk.Size, "must not be negative"))
+		}
+		if !disk.IsCloned() && disk.BootOrder != 0 {
+			allErrs = append(allErrs, field.Invalid(diskPath.Child("boot_order"), disk.BootOrder,
+				"a shared disk (clone: false) must be a data disk with boot order 0"))
+		}
+		switch disk.Media {
+		case "", DiskMediaDisk:
+		case DiskMediaCDROM:
+			if disk.Size != 0 {
+				allErrs = append(allErrs, field.Invalid(diskPath.Child("size"), disk.Size,
+					"a cdrom keeps the size of its image and must have size 0"))
+			}
+		default:
+			allErrs = append(allErrs, field.NotSupported(diskPath.Child("media"), disk.Media, []string{DiskMediaDisk, DiskMediaCDROM}))
+		}
+		// Boot order 0 marks a data disk; any other boot priority, including the boot disk's 1,
+		// may only be used once so the boot sequence is deterministic
+		switch j, ok := bootOrders[disk.BootOrder]; {
+		case disk.BootOrder < 0:
+			allErrs = append(allErrs, field.Invalid(diskPath.Child("boot_order"), disk.BootOrder, "must not be negative"))
+		case disk.BootOrder == 0:
+		case ok:
+			allErrs = append(allErrs, field.Duplicate(diskPath.Child("boot_order"),
+				fmt.Sprintf("%d (also used by disks[%d])", disk.BootOrder, j)))
+		default:
+			bootOrders[disk.BootOrder] = i
+		}
+	}
+
+	if spec.SMP < 0 || spec.SMP > 128 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("smp"), spec.SMP, "must be between 1 and 128"))
+	}
+	if spec.CPUModel != "" && spec.CPUModel != "amd" && spec.CPUModel != "intel" {
+		allErrs = append(allErrs, field.NotSupported(fldPath.Child("cpuModel"), spec.CPUModel, []string{"amd", "intel"}))
+	}
+	cpuFlags := []string{CPUFlagNUMA, CPUFlagCPUsInsteadOfCores, CPUFlagHVRelaxed, CPUFlagHVTSC}
+	seenFlags := make(map[string]bool, len(spec.CPUFlags))
+	for i, flag := range spec.CPUFlags {
+		flagPath := fldPath.Child("cpuFlags").Index(i)
+		switch {
+		case !slices.Contains(cpuFlags, flag):
+			allErrs = append(allErrs, field.NotSupported(flagPath, flag, cpuFlags))
+		case seenFlags[flag]:
+			allErrs = append(allErrs, field.Duplicate(flagPath, flag))
+		}
+		seenFlags[flag] = true
+	}
+
+	for i, nic := range spec.NICs {
+		confPath := fldPath.Child("nics").Index(i).Child("ipv4_conf")
+		hasIP := nic.IPv4Conf.IP != nil && nic.IPv4Conf.IP.UUID != ""
+		// Static NICs without an IP get one allocated by the controller
+		if nic.VLAN == "" && nic.IPv4Conf.Conf != "" && nic.IPv4Conf.Conf != "dhcp" && nic.IPv4Conf.Conf != "static" {
+			// NICs without a VLAN get a public address; manual config would be ignored
+			allErrs = append(allErrs, field.Invalid(confPath.Child("conf"), nic.IPv4Conf.Conf,
+				"only dhcp and static are supported for NICs without a vlan"))
+			continue
+		}
+		if nic.IPv4Conf.Conf != "static" && hasIP {
+			allErrs = append(allErrs, field.Invalid(confPath.Child("conf"), nic.IPv4Conf.Conf,
+				"an IP can only be set with static configuration"))
+		}
+	}
+
+	return allErrs
+}
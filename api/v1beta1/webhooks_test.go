@@ -0,0 +1,291 @@
+package v1beta1
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func validMachineSpec() CloudSigmaMachineSpec {
+	return CloudSigmaMachineSpec{
+		CPU:    2000,
+		Memory: 4096,
+		Disks: []CloudSigmaDisk{
+			{UUID: "image-uuid", Device: "virtio", BootOrder: 1, Size: 20 << 30},
+		},
+		NICs: []CloudSigmaNIC{
+			{VLAN: "vlan-uuid", IPv4Conf: CloudSigmaIPConf{Conf: "dhcp"}},
+		},
+	}
+}
+
+func TestCloudSigmaMachineTemplateValidation(t *testing.T) {
+	providerID := "cloudsigma://server-uuid"
+
+	tests := []struct {
+		name    string
+		mutate  func(spec *CloudSigmaMachineSpec)
+		wantErr string // substring of the error, empty for a valid template
+	}{
+		{name: "valid", mutate: func(*CloudSigmaMachineSpec) {}},
+		{name: "public nic", mutate: func(spec *CloudSigmaMachineSpec) { spec.NICs = []CloudSigmaNIC{{}} }},
+		{
+			name:    "no disks",
+			mutate:  func(spec *CloudSigmaMachineSpec) { spec.Disks = nil },
+			wantErr: "spec.template.spec.disks",
+		},
+		{
+			name:    "disk without uuid",
+			mutate:  func(spec *CloudSigmaMachineSpec) { spec.Disks[0].UUID = "" },
+			wantErr: "spec.template.spec.disks[0].uuid",
+		},
+		{
+			name: "duplicate boot order",
+			mutate: func(spec *CloudSigmaMachineSpec) {
+				spec.Disks = append(spec.Disks, CloudSigmaDisk{UUID: "data-uuid", Device: "virtio", BootOrder: 1})
+			},
+			wantErr: "spec.template.spec.disks[1].boot_order",
+		},
+		{
+			name: "several data disks",
+			mutate: func(spec *CloudSigmaMachineSpec) {
+				spec.Disks = append(spec.Disks,
+					CloudSigmaDisk{UUID: "data-uuid", Device: "virtio", BootOrder: 0},
+					CloudSigmaDisk{UUID: "logs-uuid", Device: "virtio", BootOrder: 0},
+					CloudSigmaDisk{UUID: "rescue-uuid", Device: "virtio", BootOrder: 2})
+			},
+		},
+		{
+			name: "shared data disk",
+			mutate: func(spec *CloudSigmaMachineSpec) {
+				noClone := false
+				spec.Disks = append(spec.Disks, CloudSigmaDisk{UUID: "dataset-uuid", Device: "virtio", Clone: &noClone})
+			},
+		},
+		{
+			name: "shared boot disk",
+			mutate: func(spec *CloudSigmaMachineSpec) {
+				noClone := false
+				spec.Disks[0].Clone = &noClone
+			},
+			wantErr: "spec.template.spec.disks[0].boot_order",
+		},
+		{
+			name: "cdrom",
+			mutate: func(spec *CloudSigmaMachineSpec) {
+				spec.Disks = append(spec.Disks, CloudSigmaDisk{UUID: "installer-iso-uuid", Device: "ide", Media: DiskMediaCDROM})
+			},
+		},
+		{
+			name: "cdrom with a size",
+			mutate: func(spec *CloudSigmaMachineSpec) {
+				spec.Disks = append(spec.Disks, CloudSigmaDisk{UUID: "installer-iso-uuid", Device: "ide", Media: DiskMediaCDROM, Size: 1 << 30})
+			},
+			wantErr: "spec.template.spec.disks[1].size",
+		},
+		{
+			name:    "unsupported media",
+			mutate:  func(spec *CloudSigmaMachineSpec) { spec.Disks[0].Media = "floppy" },
+			wantErr: "spec.template.spec.disks[0].media",
+		},
+		{
+			name:    "negative boot order",
+			mutate:  func(spec *CloudSigmaMachineSpec) { spec.Disks[0].BootOrder = -1 },
+			wantErr: "spec.template.spec.disks[0].boot_order",
+		},
+		{
+			name:    "unsupported device",
+			mutate:  func(spec *CloudSigmaMachineSpec) { spec.Disks[0].Device = "scsi" },
+			wantErr: "spec.template.spec.disks[0].device",
+		},
+		{
+			name:   "static without ip",
+			mutate: func(spec *CloudSigmaMachineSpec) { spec.NICs[0].IPv4Conf.Conf = "static" },
+		},
+		{
+			name: "static public nic",
+			mutate: func(spec *CloudSigmaMachineSpec) {
+				spec.NICs = []CloudSigmaNIC{{IPv4Conf: CloudSigmaIPConf{Conf: "static"}}}
+			},
+		},
+		{
+			name: "ip with dhcp",
+			mutate: func(spec *CloudSigmaMachineSpec) {
+				spec.NICs[0].IPv4Conf = CloudSigmaIPConf{Conf: "dhcp", IP: &CloudSigmaIPRef{UUID: "10.0.0.5"}}
+			},
+			wantErr: "spec.template.spec.nics[0].ipv4_conf.conf",
+		},
+		{
+			name: "manual config without vlan",
+			mutate: func(spec *CloudSigmaMachineSpec) {
+				spec.NICs = []CloudSigmaNIC{{IPv4Conf: CloudSigmaIPConf{Conf: "manual"}}}
+			},
+			wantErr: "spec.template.spec.nics[0].ipv4_conf",
+		},
+		{
+			name: "fixed static ip",
+			mutate: func(spec *CloudSigmaMachineSpec) {
+				spec.NICs[0].IPv4Conf = CloudSigmaIPConf{Conf: "static", IP: &CloudSigmaIPRef{UUID: "10.0.0.5"}}
+			},
+			wantErr: "static IP can only be assigned to one machine",
+		},
+		{
+			name: "cpu options",
+			mutate: func(spec *CloudSigmaMachineSpec) {
+				spec.SMP = 4
+				spec.CPUModel = "amd"
+				spec.CPUFlags = []string{CPUFlagNUMA, CPUFlagHVTSC}
+			},
+		},
+		{
+			name:    "negative smp",
+			mutate:  func(spec *CloudSigmaMachineSpec) { spec.SMP = -1 },
+			wantErr: "spec.template.spec.smp",
+		},
+		{
+			name:    "smp too large",
+			mutate:  func(spec *CloudSigmaMachineSpec) { spec.SMP = 129 },
+			wantErr: "spec.template.spec.smp",
+		},
+		{
+			name:    "unsupported cpu model",
+			mutate:  func(spec *CloudSigmaMachineSpec) { spec.CPUModel = "arm" },
+			wantErr: "spec.template.spec.cpuModel",
+		},
+		{
+			name:    "unsupported cpu flag",
+			mutate:  func(spec *CloudSigmaMachineSpec) { spec.CPUFlags = []string{CPUFlagNUMA, "avx512"} },
+			wantErr: "spec.template.spec.cpuFlags[1]",
+		},
+		{
+			name:    "duplicate cpu flag",
+			mutate:  func(spec *CloudSigmaMachineSpec) { spec.CPUFlags = []string{CPUFlagHVRelaxed, CPUFlagHVRelaxed} },
+			wantErr: "spec.template.spec.cpuFlags[1]",
+		},
+		{
+			name:    "provider id",
+			mutate:  func(spec *CloudSigmaMachineSpec) { spec.ProviderID = &providerID },
+			wantErr: "spec.template.spec.providerID",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			template := &CloudSigmaMachineTemplate{}
+			template.Name = "workers"
+			template.Spec.Template.Spec = validMachineSpec()
+			tt.mutate(&template.Spec.Template.Spec)
+
+			valid := &CloudSigmaMachineTemplate{}
+			valid.Name = "workers"
+			valid.Spec.Template.Spec = validMachineSpec()
+
+			w := &cloudSigmaMachineTemplateWebhook{}
+			_, createErr := w.ValidateCreate(context.Background(), template)
+			_, updateErr := w.ValidateUpdate(context.Background(), valid, template)
+			for _, err := range []error{createErr, updateErr} {
+				if tt.wantErr == "" {
+					if err != nil {
+						t.Errorf("validation error = %v, want none", err)
+					}
+					continue
+				}
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("validation error = %v, want it to mention %q", err, tt.wantErr)
+				}
+			}
+		})
+	}
+}
+
+func TestCloudSigmaMachineValidateUpdate(t *testing.T) {
+	// Created before the webhook existed: the disk has no UUID
+	legacy := func() *CloudSigmaMachine {
+		m := &CloudSigmaMachine{Spec: validMachineSpec()}
+		m.Name = "worker-0"
+		m.Spec.Disks[0].UUID = ""
+		return m
+	}
+	now := metav1.Now()
+
+	tests := []struct {
+		name         string
+		mutate       func(m *CloudSigmaMachine)
+		wantErr      string // substring of the error, empty if the update is allowed
+		wantWarnings int
+	}{
+		{
+			name: "existing violation",
+			mutate: func(m *CloudSigmaMachine) {
+				m.Finalizers = []string{"cloudsigmamachine.infrastructure.cluster.x-k8s.io"}
+			},
+			wantWarnings: 1,
+		},
+		{
+			name:         "new violation",
+			mutate:       func(m *CloudSigmaMachine) { m.Spec.CPUModel = "arm" },
+			wantErr:      "spec.cpuModel",
+			wantWarnings: 1,
+		},
+		{
+			name: "being deleted",
+			mutate: func(m *CloudSigmaMachine) {
+				m.DeletionTimestamp = &now
+				m.Finalizers = nil
+				m.Spec.CPUModel = "arm"
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := legacy()
+			tt.mutate(m)
+
+			warnings, err := (&cloudSigmaMachineWebhook{}).ValidateUpdate(context.Background(), legacy(), m)
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("ValidateUpdate() error = %v, want none", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("ValidateUpdate() error = %v, want it to mention %q", err, tt.wantErr)
+			}
+			if err != nil && strings.Contains(err.Error(), "spec.disks[0].uuid") {
+				t.Errorf("ValidateUpdate() error = %v, want the existing violation left out", err)
+			}
+			if len(warnings) != tt.wantWarnings {
+				t.Errorf("warnings = %q, want %d", warnings, tt.wantWarnings)
+			}
+		})
+	}
+}
+
+func TestCloudSigmaMachineValidationAllowsStaticIP(t *testing.T) {
+	m := &CloudSigmaMachine{Spec: validMachineSpec()}
+	m.Spec.NICs[0].IPv4Conf = CloudSigmaIPConf{Conf: "static", IP: &CloudSigmaIPRef{UUID: "10.0.0.5"}}
+
+	if _, err := (&cloudSigmaMachineWebhook{}).ValidateCreate(context.Background(), m); err != nil {
+		t.Errorf("ValidateCreate() error = %v, want a static IP to be allowed on a single machine", err)
+	}
+}
+
+func TestCloudSigmaMachineTemplateDefaulting(t *testing.T) {
+	template := &CloudSigmaMachineTemplate{}
+	template.Spec.Template.Spec = CloudSigmaMachineSpec{
+		Disks: []CloudSigmaDisk{{UUID: "image-uuid"}},
+		NICs:  []CloudSigmaNIC{{VLAN: "vlan-uuid"}, {}},
+	}
+
+	if err := (&cloudSigmaMachineTemplateWebhook{}).Default(context.Background(), template); err != nil {
+		t.Fatalf("Default() error = %v", err)
+	}
+	spec := template.Spec.Template.Spec
+	if spec.Disks[0].Device != "virtio" {
+		t.Errorf("disk device = %q, want virtio", spec.Disks[0].Device)
+	}
+	if spec.NICs[0].IPv4Conf.Conf != "dhcp" {
+		t.Errorf("vlan NIC conf = %q, want dhcp", spec.NICs[0].IPv4Conf.Conf)
+	}
+	if spec.NICs[1].IPv4Conf.Conf != "" {
+		t.Errorf("public NIC conf = %q, want it left empty", spec.NICs[1].IPv4Conf.Conf)
+	}
+}
@@ -23,19 +23,36 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/klog/v2"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 
+	infrav1 "github.com/kube-dc/cluster-api-provider-cloudsigma/api/v1beta1"
 	"github.com/kube-dc/cluster-api-provider-cloudsigma/ccm/controllers"
 	"github.com/kube-dc/cluster-api-provider-cloudsigma/pkg/auth"
+	"github.com/kube-dc/cluster-api-provider-cloudsigma/pkg/cloud"
+	"github.com/kube-dc/cluster-api-provider-cloudsigma/pkg/logging"
+	"github.com/kube-dc/cluster-api-provider-cloudsigma/pkg/regions"
 )
 
 func main() {
+	var logFormat string
 	var metricsAddr string
 	var probeAddr string
 	var clusterName string
+	var clusterNamespace string
 	var kubeconfig string
 	var cloudsigmaRegion string
+	var regionEndpoints string
 	// Impersonation config (default)
 	var oauthURL string
 	var clientID string
@@ -47,14 +64,30 @@ func main() {
 	var cloudsigmaPassword string
 	// CSI token provisioning
 	var csiTokenEnabled bool
+	var csiNamespace string
+	var csiSecretName string
 	// LoadBalancer IP failover (enabled by default)
 	var lbIPPoolDisabled bool
+	// LoadBalancer IP purchasing (disabled by default)
+	var lbPurchaseIPs bool
+	var lbMaxPurchasedIPs int
+	var lbIPSubscriptionPeriod string
+	var lbIPReleaseCooldown time.Duration
+	// Sync intervals
+	var nodeSyncInterval time.Duration
+	var lbSyncInterval time.Duration
+	var ipRefreshInterval time.Duration
+	var csiTokenRefreshInterval time.Duration
+	var ipReservationTTL time.Duration
 
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
+	flag.StringVar(&logFormat, "log-format", logging.FormatText, "Log output format: text or json")
 	flag.StringVar(&clusterName, "cluster-name", "", "Name of the cluster being managed.")
+	flag.StringVar(&clusterNamespace, "cluster-namespace", os.Getenv("CLUSTER_NAMESPACE"), "Namespace of the CAPI Cluster in the management cluster. When set, the CCM stops syncing nodes and LoadBalancer services while the Cluster is paused.")
 	flag.StringVar(&kubeconfig, "tenant-kubeconfig", "", "Path to kubeconfig file for connecting to the tenant cluster.")
 	flag.StringVar(&cloudsigmaRegion, "cloudsigma-region", os.Getenv("CLOUDSIGMA_REGION"), "CloudSigma region")
+	flag.StringVar(&regionEndpoints, "cloudsigma-region-endpoints", os.Getenv("CLOUDSIGMA_REGION_ENDPOINTS"), "Endpoints of CloudSigma regions not on <region>.cloudsigma.com, as comma-separated region=api-host[|direct-host] entries")
 	// Impersonation config (default mode)
 	flag.StringVar(&oauthURL, "oauth-url", os.Getenv("CLOUDSIGMA_OAUTH_URL"), "CloudSigma OAuth URL")
 	flag.StringVar(&clientID, "client-id", os.Getenv("CLOUDSIGMA_CLIENT_ID"), "OAuth client ID")
@@ -66,11 +99,65 @@ func main() {
 	flag.StringVar(&cloudsigmaPassword, "cloudsigma-password", os.Getenv("CLOUDSIGMA_PASSWORD"), "CloudSigma API password (only used with --enable-legacy-credentials)")
 	// CSI token provisioning
 	flag.BoolVar(&csiTokenEnabled, "enable-csi-token", os.Getenv("CLOUDSIGMA_ENABLE_CSI_TOKEN") == "true", "Enable CSI token provisioning - CCM will create and refresh CloudSigma API token for CSI driver")
+	flag.StringVar(&csiNamespace, "csi-namespace", controllers.CSINamespace, "Tenant cluster namespace the CSI driver token secret is written to")
+	flag.StringVar(&csiSecretName, "csi-token-secret-name", controllers.CSITokenSecretName, "Name of the CSI driver token secret")
 	// LoadBalancer IP failover (enabled by default, can be disabled)
 	flag.BoolVar(&lbIPPoolDisabled, "disable-lb-ip-pool", os.Getenv("CLOUDSIGMA_DISABLE_LB_IP_POOL") == "true", "Disable LoadBalancer IP pool management (enabled by default)")
+	// LoadBalancer IP purchasing (disabled by default, spends money on the account)
+	flag.BoolVar(&lbPurchaseIPs, "lb-purchase-ips", os.Getenv("CLOUDSIGMA_LB_PURCHASE_IPS") == "true", "Purchase an IP subscription when the static LoadBalancer IP pool has no free IP (requires --lb-max-purchased-ips)")
+	flag.IntVar(&lbMaxPurchasedIPs, "lb-max-purchased-ips", 0, "Maximum number of IPs purchased for the cluster with --lb-purchase-ips")
+	flag.StringVar(&lbIPSubscriptionPeriod, "lb-ip-subscription-period", controllers.DefaultIPSubscriptionPeriod, "Billing period of purchased IP subscriptions")
+	flag.DurationVar(&lbIPReleaseCooldown, "lb-ip-release-cooldown", controllers.DefaultIPReleaseCooldown, "How long a purchased LoadBalancer IP stays unused before its subscription renewal is stopped (0 never releases)")
+
+	// Sync intervals
+	flag.DurationVar(&nodeSyncInterval, "node-sync-interval", controllers.DefaultNodeSyncInterval, "How often tenant nodes are synced with CloudSigma")
+	flag.DurationVar(&lbSyncInterval, "lb-sync-interval", controllers.DefaultLBSyncInterval, "How often LoadBalancer services are synced")
+	flag.DurationVar(&ipRefreshInterval, "ip-refresh-interval", controllers.DefaultIPRefreshInterval, "How often owned IPs are rediscovered from the CloudSigma API")
+	flag.DurationVar(&csiTokenRefreshInterval, "csi-token-refresh-interval", controllers.TokenRefreshInterval, "How often the CSI driver token is refreshed")
+	flag.DurationVar(&ipReservationTTL, "lb-ip-reservation-ttl", controllers.DefaultIPReservationTTL, "How long the LoadBalancer IP reservation of a deleted service is kept")
 
 	flag.Parse()
 
+	if err := logging.Configure(logFormat, os.Stderr); err != nil {
+		klog.Fatalf("Invalid --log-format: %v", err)
+	}
+
+	for _, v := range []struct {
+		name     string
+		interval time.Duration
+		minimum  time.Duration
+	}{
+		{"node-sync-interval", nodeSyncInterval, controllers.MinSyncInterval},
+		{"lb-sync-interval", lbSyncInterval, controllers.MinSyncInterval},
+		{"ip-refresh-interval", ipRefreshInterval, controllers.MinIPRefreshInterval},
+		{"csi-token-refresh-interval", csiTokenRefreshInterval, controllers.MinTokenRefreshInterval},
+		{"lb-ip-reservation-ttl", ipReservationTTL, controllers.MinIPReservationTTL},
+	} {
+		if err := controllers.ValidateInterval(v.name, v.interval, v.minimum); err != nil {
+			klog.Fatal(err)
+		}
+	}
+
+	if lbIPReleaseCooldown != 0 {
+		if err := controllers.ValidateInterval("lb-ip-release-cooldown", lbIPReleaseCooldown, controllers.MinIPReleaseCooldown); err != nil {
+			klog.Fatal(err)
+		}
+	}
+	if lbPurchaseIPs && lbMaxPurchasedIPs <= 0 {
+		klog.Fatal("--lb-purchase-ips requires --lb-max-purchased-ips to be set to a positive cap")
+	}
+	if lbPurchaseIPs && clusterName == "" {
+		klog.Fatal("--lb-purchase-ips requires --cluster-name, which names the tag purchased IPs are counted on")
+	}
+
+	if err := regions.ApplyOverrides(regionEndpoints); err != nil {
+		klog.Fatalf("Invalid --cloudsigma-region-endpoints: %v", err)
+	}
+
+	if err := controllers.ValidateCSISecretLocation(csiNamespace, csiSecretName); err != nil {
+		klog.Fatalf("Invalid --csi-namespace or --csi-token-secret-name: %v", err)
+	}
+
 	if kubeconfig == "" {
 		klog.Fatal("--tenant-kubeconfig is required")
 	}
@@ -91,29 +178,10 @@ func main() {
 		cancel()
 	}()
 
-	// Start health/ready probes
-	go func() {
-		mux := http.NewServeMux()
-		mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
-			w.WriteHeader(http.StatusOK)
-			w.Write([]byte("ok"))
-		})
-		mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
-			w.WriteHeader(http.StatusOK)
-			w.Write([]byte("ok"))
-		})
-		klog.Infof("Starting health probe server on %s", probeAddr)
-		if err := http.ListenAndServe(probeAddr, mux); err != nil && err != http.ErrServerClosed {
-			klog.Errorf("Health probe server error: %v", err)
-		}
-	}()
-
-	// Start metrics server (simple placeholder)
+	// Start metrics server (LB controller metrics plus the Go runtime and process collectors)
 	go func() {
 		mux := http.NewServeMux()
-		mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
-			w.WriteHeader(http.StatusOK)
-		})
+		mux.Handle("/metrics", promhttp.Handler())
 		klog.Infof("Starting metrics server on %s", metricsAddr)
 		if err := http.ListenAndServe(metricsAddr, mux); err != nil && err != http.ErrServerClosed {
 			klog.Errorf("Metrics server error: %v", err)
@@ -132,7 +200,7 @@ func main() {
 		if err != nil {
 			klog.Fatalf("Failed to create impersonation client: %v", err)
 		}
-		klog.Infof("Impersonation mode configured (default), userEmail: %s", userEmail)
+		klog.Info("Impersonation mode configured (default)")
 	} else {
 		klog.Info("Impersonation not configured - CLOUDSIGMA_OAUTH_URL, CLOUDSIGMA_CLIENT_ID, CLOUDSIGMA_CLIENT_SECRET required")
 	}
@@ -155,6 +223,60 @@ func main() {
 		klog.Fatal("No authentication configured. Set impersonation (CLOUDSIGMA_OAUTH_URL, CLOUDSIGMA_CLIENT_ID, CLOUDSIGMA_CLIENT_SECRET) or enable legacy credentials (CLOUDSIGMA_ENABLE_LEGACY_CREDENTIALS=true)")
 	}
 
+	// Pause checks and user email resolution read the Cluster namespace of the management cluster
+	// the CCM runs in
+	var mgmtClient client.Client
+	var paused controllers.PausedFunc
+	var tearingDown controllers.TeardownFunc
+	if clusterNamespace != "" {
+		if clusterName == "" {
+			klog.Fatal("--cluster-namespace requires --cluster-name")
+		}
+		var err error
+		mgmtClient, err = newManagementClient()
+		if err != nil {
+			klog.Fatalf("Failed to create management cluster client: %v", err)
+		}
+		paused = controllers.ClusterPaused(mgmtClient, clusterNamespace, clusterName)
+		tearingDown = controllers.ClusterTearingDown(mgmtClient, clusterNamespace, clusterName)
+		klog.Infof("Syncs pause with Cluster %s/%s", clusterNamespace, clusterName)
+	}
+
+	// Impersonation needs the user to act as. Without one, the CCM only runs on legacy credentials.
+	if impersonationClient != nil {
+		email, source, err := controllers.ResolveUserEmail(ctx, mgmtClient, userEmail, clusterNamespace, clusterName)
+		switch {
+		case err == nil:
+			userEmail = email
+			klog.Infof("Impersonating user %s (from %s)", userEmail, source)
+		case legacyCredentialsEnabled && !csiTokenEnabled:
+			klog.Warningf("Impersonation disabled, falling back to legacy credentials: %v", err)
+			impersonationClient = nil
+		default:
+			klog.Fatalf("Impersonation is configured but the user to impersonate is unknown: %v", err)
+		}
+	}
+
+	// Start health/ready probes. Readiness reflects whether the CloudSigma API accepts the CCM's
+	// credentials, impersonating the resolved user when impersonation is configured.
+	apiProbe, err := cloud.APIProbe(impersonationClient, userEmail, cloudsigmaUsername, cloudsigmaPassword, []string{cloudsigmaRegion})
+	if err != nil {
+		klog.Fatalf("Failed to create CloudSigma API probe: %v", err)
+	}
+	apiCheck := cloud.NewReachabilityCheck(apiProbe, cloud.DefaultReachabilityTimeout, cloud.DefaultReachabilityCacheTTL)
+	go func() {
+		mux := http.NewServeMux()
+		mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte("ok"))
+		})
+		mux.Handle("/readyz", apiCheck)
+		klog.Infof("Starting health probe server on %s", probeAddr)
+		if err := http.ListenAndServe(probeAddr, mux); err != nil && err != http.ErrServerClosed {
+			klog.Errorf("Health probe server error: %v", err)
+		}
+	}()
+
 	klog.Infof("Starting CCM with impersonation=%v, legacyFallback=%v, csiToken=%v, lbIPPool=%v", impersonationClient != nil, legacyCredentialsEnabled, csiTokenEnabled, !lbIPPoolDisabled)
 
 	// Create and start node reconciler
@@ -167,7 +289,9 @@ func main() {
 		ImpersonationClient:      impersonationClient,
 		LegacyCredentialsEnabled: legacyCredentialsEnabled,
 		UserEmail:                userEmail,
+		SyncInterval:             nodeSyncInterval,
 	}
+	reconciler.Paused = paused
 
 	if err := reconciler.Start(ctx); err != nil {
 		klog.Fatalf("Failed to start node reconciler: %v", err)
@@ -178,17 +302,16 @@ func main() {
 		if impersonationClient == nil {
 			klog.Fatal("CSI token provisioning requires impersonation mode")
 		}
-		if userEmail == "" {
-			klog.Fatal("CSI token provisioning requires --user-email")
-		}
-
 		csiTokenController := &controllers.CSITokenController{
-			TenantClient:        reconciler.GetTenantClient(),
+			TenantClients:       reconciler,
 			ImpersonationClient: impersonationClient,
 			UserEmail:           userEmail,
 			Region:              cloudsigmaRegion,
 			ClusterName:         clusterName,
+			Namespace:           csiNamespace,
+			SecretName:          csiSecretName,
 			Enabled:             true,
+			RefreshInterval:     csiTokenRefreshInterval,
 		}
 
 		if err := csiTokenController.Start(ctx); err != nil {
@@ -202,13 +325,23 @@ func main() {
 	var lbController *controllers.LoadBalancerController
 	if impersonationClient != nil && userEmail != "" && !lbIPPoolDisabled {
 		lbController = &controllers.LoadBalancerController{
-			TenantClient:        reconciler.GetTenantClient(),
-			ImpersonationClient: impersonationClient,
-			UserEmail:           userEmail,
-			Region:              cloudsigmaRegion,
-			ClusterName:         clusterName,
-			Disabled:            false,
+			TenantClients:        reconciler,
+			ImpersonationClient:  impersonationClient,
+			UserEmail:            userEmail,
+			Region:               cloudsigmaRegion,
+			ClusterName:          clusterName,
+			Disabled:             false,
+			SyncInterval:         lbSyncInterval,
+			IPRefreshInterval:    ipRefreshInterval,
+			IPReservationTTL:     ipReservationTTL,
+			PurchaseIPs:          lbPurchaseIPs,
+			MaxPurchasedIPs:      lbMaxPurchasedIPs,
+			IPSubscriptionPeriod: lbIPSubscriptionPeriod,
+			IPReleaseCooldown:    lbIPReleaseCooldown,
+			Recorder:             newEventRecorder(ctx, reconciler),
 		}
+		lbController.Paused = paused
+		lbController.TearingDown = tearingDown
 
 		if err := lbController.Start(ctx); err != nil {
 			klog.Errorf("Failed to start LoadBalancer controller: %v", err)
@@ -216,10 +349,17 @@ func main() {
 		} else {
 			klog.Info("LoadBalancer IP pool controller started (auto-discovering owned IPs)")
 		}
-	} else if lbIPPoolDisabled {
-		klog.Info("LoadBalancer IP pool controller disabled via flag")
 	} else {
-		klog.Warning("LoadBalancer IP pool controller not started - requires impersonation mode and user-email")
+		if lbIPPoolDisabled {
+			klog.Info("LoadBalancer IP pool controller disabled via flag")
+		} else {
+			klog.Warning("LoadBalancer IP pool controller not started - requires impersonation mode and user-email")
+		}
+		// Services managed by an earlier run keep the cleanup finalizer; with no controller to
+		// release their IPs, drop it so deleting them does not hang
+		if err := controllers.RemoveLBFinalizers(ctx, reconciler.GetTenantClient()); err != nil {
+			klog.Errorf("Failed to remove LB IP cleanup finalizers: %v", err)
+		}
 	}
 
 	// Wait for context cancellation
@@ -233,3 +373,49 @@ func main() {
 
 	klog.Info("CloudSigma CCM shutdown complete")
 }
+
+// newEventRecorder returns a recorder that writes events to the tenant cluster, where users
+// look at their Services
+func newEventRecorder(ctx context.Context, tenantClients controllers.TenantClientProvider) record.EventRecorder {
+	broadcaster := record.NewBroadcaster(record.WithContext(ctx))
+	broadcaster.StartRecordingToSink(tenantEventSink{clients: tenantClients})
+	return broadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{Component: "cloudsigma-ccm"})
+}
+
+// tenantEventSink writes events through the current tenant client, so events keep flowing after
+// the node reconciler rebuilds it
+type tenantEventSink struct {
+	clients controllers.TenantClientProvider
+}
+
+func (s tenantEventSink) sink() *typedcorev1.EventSinkImpl {
+	return &typedcorev1.EventSinkImpl{Interface: s.clients.GetTenantClient().CoreV1().Events("")}
+}
+
+func (s tenantEventSink) Create(event *corev1.Event) (*corev1.Event, error) {
+	return s.sink().Create(event)
+}
+
+func (s tenantEventSink) Update(event *corev1.Event) (*corev1.Event, error) {
+	return s.sink().Update(event)
+}
+
+func (s tenantEventSink) Patch(event *corev1.Event, data []byte) (*corev1.Event, error) {
+	return s.sink().Patch(event, data)
+}
+
+// newManagementClient returns a client for the Clusters, CloudSigmaClusters and namespaces of the
+// management cluster, built from its in-cluster config
+func newManagementClient() (client.Client, error) {
+	config, err := rest.InClusterConfig()
+	if err != nil {
+		return nil, err
+	}
+	mgmtScheme := runtime.NewScheme()
+	for _, add := range []func(*runtime.Scheme) error{corev1.AddToScheme, clusterv1.AddToScheme, infrav1.AddToScheme} {
+		if err := add(mgmtScheme); err != nil {
+			return nil, err
+		}
+	}
+	return client.New(config, client.Options{Scheme: mgmtScheme})
+}
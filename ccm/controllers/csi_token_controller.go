@@ -19,23 +19,29 @@ package controllers
 import (
 	"context"
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/klog/v2"
+	"k8s.io/utils/clock"
 
 	"github.com/kube-dc/cluster-api-provider-cloudsigma/pkg/auth"
 )
 
 const (
-	// CSITokenSecretName is the name of the secret containing CSI credentials
+	// CSITokenSecretName is the default name of the secret containing CSI credentials
 	CSITokenSecretName = "cloudsigma-token"
-	// CSINamespace is the namespace where CSI driver is deployed
+	// CSINamespace is the default namespace where CSI driver is deployed
 	CSINamespace = "cloudsigma-csi"
-	// TokenRefreshInterval is how often to refresh the token
+	// TokenRefreshInterval is the default for how often to refresh the token
 	TokenRefreshInterval = 10 * time.Minute
 	// TokenRefreshBuffer is the time before expiry to refresh
 	TokenRefreshBuffer = 5 * time.Minute
@@ -43,6 +49,16 @@ const (
 	InitialRetryInterval = 5 * time.Second
 	// MaxRetryInterval is the maximum interval between retries
 	MaxRetryInterval = 2 * time.Minute
+	// CSIIdentityCheckInterval is how often the token secret is checked for a stale user or region
+	CSIIdentityCheckInterval = 30 * time.Second
+
+	// AnnotationCSIUserEmail records on the token secret the user the token was issued for
+	AnnotationCSIUserEmail = "cloudsigma.com/user-email"
+	// AnnotationCSIRegion records on the token secret the region the token was issued for
+	AnnotationCSIRegion = "cloudsigma.com/region"
+	// AnnotationCSIIdentityGeneration is bumped whenever the token secret is rewritten for another
+	// user or region; the CSI controller rebuilds its client when it changes (driver.WatchTokenSecret)
+	AnnotationCSIIdentityGeneration = "cloudsigma.com/identity-generation"
 )
 
 // CSITokenController manages CloudSigma API tokens for the CSI driver
@@ -50,6 +66,9 @@ const (
 type CSITokenController struct {
 	// TenantClient is the Kubernetes client for the tenant cluster
 	TenantClient kubernetes.Interface
+	// TenantClients, if set, is asked for the tenant client on every use instead of TenantClient,
+	// so a client rebuilt by the NodeReconciler is picked up (optional)
+	TenantClients TenantClientProvider
 	// ImpersonationClient handles OAuth token acquisition
 	ImpersonationClient *auth.ImpersonationClient
 	// UserEmail is the user to impersonate for CSI operations
@@ -58,8 +77,16 @@ type CSITokenController struct {
 	Region string
 	// ClusterName is the name of the cluster, used for tagging drives
 	ClusterName string
+	// Namespace is where the token secret is written (default: CSINamespace)
+	Namespace string
+	// SecretName is the name of the token secret (default: CSITokenSecretName)
+	SecretName string
 	// Enabled indicates if CSI token provisioning is enabled
 	Enabled bool
+	// RefreshInterval is how often the token is refreshed (default: TokenRefreshInterval)
+	RefreshInterval time.Duration
+	// Clock drives the retry and refresh timers (default: the real clock)
+	Clock clock.WithTicker
 }
 
 // Start begins the CSI token management loop
@@ -77,7 +104,12 @@ func (c *CSITokenController) Start(ctx context.Context) error {
 		return fmt.Errorf("user email required for CSI token provisioning")
 	}
 
-	klog.Infof("Starting CSI token controller for user: %s, region: %s", c.UserEmail, c.Region)
+	if err := ValidateCSISecretLocation(c.namespace(), c.secretName()); err != nil {
+		return err
+	}
+
+	klog.Infof("Starting CSI token controller for user: %s, region: %s, secret: %s/%s",
+		c.UserEmail, c.Region, c.namespace(), c.secretName())
 
 	// Start provisioning loop with retry (non-blocking)
 	go c.provisioningLoop(ctx)
@@ -85,6 +117,39 @@ func (c *CSITokenController) Start(ctx context.Context) error {
 	return nil
 }
 
+// ValidateCSISecretLocation returns an error unless namespace is a valid namespace name and name
+// a valid secret name
+func ValidateCSISecretLocation(namespace, name string) error {
+	if errs := validation.IsDNS1123Label(namespace); len(errs) > 0 {
+		return fmt.Errorf("invalid CSI namespace %q: %s", namespace, strings.Join(errs, "; "))
+	}
+	if errs := validation.IsDNS1123Subdomain(name); len(errs) > 0 {
+		return fmt.Errorf("invalid CSI token secret name %q: %s", name, strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// tenantClient returns the current tenant cluster client
+func (c *CSITokenController) tenantClient() kubernetes.Interface {
+	return tenantClientFrom(c.TenantClients, c.TenantClient)
+}
+
+// namespace returns the namespace of the token secret
+func (c *CSITokenController) namespace() string {
+	if c.Namespace == "" {
+		return CSINamespace
+	}
+	return c.Namespace
+}
+
+// secretName returns the name of the token secret
+func (c *CSITokenController) secretName() string {
+	if c.SecretName == "" {
+		return CSITokenSecretName
+	}
+	return c.SecretName
+}
+
 // provisioningLoop handles initial provisioning with exponential backoff,
 // then switches to regular refresh interval once successful
 func (c *CSITokenController) provisioningLoop(ctx context.Context) {
@@ -102,7 +167,7 @@ func (c *CSITokenController) provisioningLoop(ctx context.Context) {
 				select {
 				case <-ctx.Done():
 					return
-				case <-time.After(backoff):
+				case <-clockOrDefault(c.Clock).After(backoff):
 					// Exponential backoff with cap
 					backoff = backoff * 2
 					if backoff > MaxRetryInterval {
@@ -120,24 +185,135 @@ func (c *CSITokenController) provisioningLoop(ctx context.Context) {
 	c.refreshLoop(ctx)
 }
 
-// refreshLoop periodically refreshes the CSI token
+// refreshLoop periodically refreshes the CSI token, rewrites it right away when the secret
+// was issued for another user or region than the one configured, and re-provisions it as soon
+// as the secret or its namespace is deleted
 func (c *CSITokenController) refreshLoop(ctx context.Context) {
-	ticker := time.NewTicker(TokenRefreshInterval)
+	ticker := clockOrDefault(c.Clock).NewTicker(intervalOrDefault(c.RefreshInterval, TokenRefreshInterval))
 	defer ticker.Stop()
+	identityTicker := clockOrDefault(c.Clock).NewTicker(CSIIdentityCheckInterval)
+	defer identityTicker.Stop()
+
+	deleted := make(chan struct{}, 1)
+	go c.watchDeletions(ctx, "secret", func(ctx context.Context) (watch.Interface, error) {
+		return c.tenantClient().CoreV1().Secrets(c.namespace()).Watch(ctx, metav1.ListOptions{
+			FieldSelector: fields.OneTermEqualSelector("metadata.name", c.secretName()).String(),
+		})
+	}, deleted)
+	go c.watchDeletions(ctx, "namespace", func(ctx context.Context) (watch.Interface, error) {
+		return c.tenantClient().CoreV1().Namespaces().Watch(ctx, metav1.ListOptions{
+			FieldSelector: fields.OneTermEqualSelector("metadata.name", c.namespace()).String(),
+		})
+	}, deleted)
 
 	for {
 		select {
 		case <-ctx.Done():
 			klog.Info("CSI token refresh loop stopped")
 			return
-		case <-ticker.C:
+		case <-ticker.C():
 			if err := c.ensureCSIToken(ctx); err != nil {
 				klog.Errorf("CSI token refresh failed: %v", err)
 			}
+		case <-identityTicker.C():
+			if !c.secretIdentityStale(ctx) {
+				continue
+			}
+			if err := c.ensureCSIToken(ctx); err != nil {
+				klog.Errorf("CSI token rewrite for changed user or region failed: %v", err)
+			}
+		case <-deleted:
+			if !c.secretMissing(ctx) {
+				continue
+			}
+			klog.Info("CSI token secret deleted, re-provisioning")
+			// A secret deleted along with its namespace cannot be recreated until the namespace is
+			// gone; the namespace deletion event triggers another attempt then
+			if err := c.ensureCSIToken(ctx); err != nil {
+				klog.Errorf("CSI token re-provisioning failed: %v", err)
+			}
+		}
+	}
+}
+
+// watchDeletions watches the tenant cluster through open and signals trigger whenever the
+// watched object is deleted. The watch is re-opened when the connection drops, and trigger is
+// also signalled after each reconnect since a deletion may have been missed in between
+func (c *CSITokenController) watchDeletions(ctx context.Context, kind string, open func(context.Context) (watch.Interface, error), trigger chan<- struct{}) {
+	reconnect := false
+	for {
+		w, err := open(ctx)
+		if err != nil {
+			klog.Warningf("Failed to watch CSI %s (retrying in %v): %v", kind, InitialRetryInterval, err)
+		} else {
+			if reconnect {
+				notify(trigger)
+			}
+			reconnect = true
+			forwardDeletions(ctx, w, trigger)
+			klog.V(2).Infof("CSI %s watch closed, reopening in %v", kind, InitialRetryInterval)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-clockOrDefault(c.Clock).After(InitialRetryInterval):
+		}
+	}
+}
+
+// forwardDeletions signals trigger for every deletion event on w until w closes or ctx is done
+func forwardDeletions(ctx context.Context, w watch.Interface, trigger chan<- struct{}) {
+	defer w.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-w.ResultChan():
+			if !ok {
+				return
+			}
+			if event.Type == watch.Deleted {
+				notify(trigger)
+			}
 		}
 	}
 }
 
+// notify signals trigger without blocking; a pending notification already covers this one
+func notify(trigger chan<- struct{}) {
+	select {
+	case trigger <- struct{}{}:
+	default:
+	}
+}
+
+// secretMissing reports whether the token secret does not exist
+func (c *CSITokenController) secretMissing(ctx context.Context) bool {
+	_, err := c.tenantClient().CoreV1().Secrets(c.namespace()).Get(ctx, c.secretName(), metav1.GetOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		klog.Warningf("Failed to get CSI token secret: %v", err)
+	}
+	return errors.IsNotFound(err)
+}
+
+// secretIdentityStale reports whether the token secret exists but names another user or region
+// than the configured ones
+func (c *CSITokenController) secretIdentityStale(ctx context.Context) bool {
+	secret, err := c.tenantClient().CoreV1().Secrets(c.namespace()).Get(ctx, c.secretName(), metav1.GetOptions{})
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			klog.Warningf("Failed to get CSI token secret: %v", err)
+		}
+		return false
+	}
+	return csiIdentityChanged(secret, c.UserEmail, c.Region)
+}
+
+// csiIdentityChanged reports whether secret was written for another user or region
+func csiIdentityChanged(secret *corev1.Secret, userEmail, region string) bool {
+	return secret.Annotations[AnnotationCSIUserEmail] != userEmail || secret.Annotations[AnnotationCSIRegion] != region
+}
+
 // ensureCSIToken ensures the CSI token secret exists and is valid
 func (c *CSITokenController) ensureCSIToken(ctx context.Context) error {
 	klog.V(2).Infof("Ensuring CSI token for user: %s", c.UserEmail)
@@ -156,16 +332,17 @@ func (c *CSITokenController) ensureCSIToken(ctx context.Context) error {
 	// Create or update secret
 	secret := &corev1.Secret{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      CSITokenSecretName,
-			Namespace: CSINamespace,
+			Name:      c.secretName(),
+			Namespace: c.namespace(),
 			Labels: map[string]string{
 				"app.kubernetes.io/managed-by": "cloudsigma-ccm",
 				"app.kubernetes.io/component":  "csi-credentials",
 			},
 			Annotations: map[string]string{
-				"cloudsigma.com/user-email":   c.UserEmail,
-				"cloudsigma.com/region":       c.Region,
-				"cloudsigma.com/refreshed-at": time.Now().UTC().Format(time.RFC3339),
+				AnnotationCSIUserEmail:          c.UserEmail,
+				AnnotationCSIRegion:             c.Region,
+				AnnotationCSIIdentityGeneration: "1",
+				"cloudsigma.com/refreshed-at":   clockOrDefault(c.Clock).Now().UTC().Format(time.RFC3339),
 			},
 		},
 		Type: corev1.SecretTypeOpaque,
@@ -177,53 +354,62 @@ func (c *CSITokenController) ensureCSIToken(ctx context.Context) error {
 		},
 	}
 
-	existing, err := c.TenantClient.CoreV1().Secrets(CSINamespace).Get(ctx, CSITokenSecretName, metav1.GetOptions{})
+	existing, err := c.tenantClient().CoreV1().Secrets(c.namespace()).Get(ctx, c.secretName(), metav1.GetOptions{})
 	if err != nil {
 		if errors.IsNotFound(err) {
 			// Create new secret
-			_, err = c.TenantClient.CoreV1().Secrets(CSINamespace).Create(ctx, secret, metav1.CreateOptions{})
+			_, err = c.tenantClient().CoreV1().Secrets(c.namespace()).Create(ctx, secret, metav1.CreateOptions{})
 			if err != nil {
 				return fmt.Errorf("failed to create CSI token secret: %w", err)
 			}
-			klog.Infof("Created CSI token secret in namespace %s", CSINamespace)
+			klog.Infof("Created CSI token secret in namespace %s", c.namespace())
 			return nil
 		}
 		return fmt.Errorf("failed to get existing secret: %w", err)
 	}
 
+	// Keep the identity generation, bumping it if the token is now for another user or region
+	generation, _ := strconv.Atoi(existing.Annotations[AnnotationCSIIdentityGeneration])
+	if csiIdentityChanged(existing, c.UserEmail, c.Region) {
+		klog.Infof("CSI token identity changed from user %q region %q to user %q region %q, rewriting secret",
+			existing.Annotations[AnnotationCSIUserEmail], existing.Annotations[AnnotationCSIRegion], c.UserEmail, c.Region)
+		generation++
+	}
+	secret.Annotations[AnnotationCSIIdentityGeneration] = strconv.Itoa(max(generation, 1))
+
 	// Update existing secret
 	existing.Data = nil // Clear old data
 	existing.StringData = secret.StringData
 	existing.Labels = secret.Labels
 	existing.Annotations = secret.Annotations
 
-	_, err = c.TenantClient.CoreV1().Secrets(CSINamespace).Update(ctx, existing, metav1.UpdateOptions{})
+	_, err = c.tenantClient().CoreV1().Secrets(c.namespace()).Update(ctx, existing, metav1.UpdateOptions{})
 	if err != nil {
 		return fmt.Errorf("failed to update CSI token secret: %w", err)
 	}
 
-	klog.V(2).Infof("Updated CSI token secret in namespace %s", CSINamespace)
+	klog.V(2).Infof("Updated CSI token secret in namespace %s", c.namespace())
 	return nil
 }
 
 // ensureNamespace ensures the CSI namespace exists
 func (c *CSITokenController) ensureNamespace(ctx context.Context) error {
-	_, err := c.TenantClient.CoreV1().Namespaces().Get(ctx, CSINamespace, metav1.GetOptions{})
+	_, err := c.tenantClient().CoreV1().Namespaces().Get(ctx, c.namespace(), metav1.GetOptions{})
 	if err != nil {
 		if errors.IsNotFound(err) {
 			ns := &corev1.Namespace{
 				ObjectMeta: metav1.ObjectMeta{
-					Name: CSINamespace,
+					Name: c.namespace(),
 					Labels: map[string]string{
 						"app.kubernetes.io/managed-by": "cloudsigma-ccm",
 					},
 				},
 			}
-			_, err = c.TenantClient.CoreV1().Namespaces().Create(ctx, ns, metav1.CreateOptions{})
+			_, err = c.tenantClient().CoreV1().Namespaces().Create(ctx, ns, metav1.CreateOptions{})
 			if err != nil && !errors.IsAlreadyExists(err) {
 				return fmt.Errorf("failed to create namespace: %w", err)
 			}
-			klog.Infof("Created namespace %s", CSINamespace)
+			klog.Infof("Created namespace %s", c.namespace())
 			return nil
 		}
 		return fmt.Errorf("failed to get namespace: %w", err)
@@ -0,0 +1,339 @@
+/*
+Copyright 2025 Kube-DC Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	testingclock "k8s.io/utils/clock/testing"
+
+	cloudfake "github.com/kube-dc/cluster-api-provider-cloudsigma/pkg/cloud/fake"
+)
+
+// csiTokenSecret returns a token secret as written for userEmail and region
+func csiTokenSecret(userEmail, region, generation string) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      CSITokenSecretName,
+			Namespace: CSINamespace,
+			Annotations: map[string]string{
+				AnnotationCSIUserEmail:          userEmail,
+				AnnotationCSIRegion:             region,
+				AnnotationCSIIdentityGeneration: generation,
+			},
+		},
+		StringData: map[string]string{"region": region, "user_email": userEmail},
+	}
+}
+
+func newCSITokenTestController(t *testing.T, cs *fake.Clientset, clk *testingclock.FakeClock) *CSITokenController {
+	t.Helper()
+	api := cloudfake.NewServer()
+	t.Cleanup(api.Close)
+	impersonation, err := api.NewImpersonationClient()
+	if err != nil {
+		t.Fatalf("NewImpersonationClient() error = %v", err)
+	}
+	return &CSITokenController{
+		TenantClient:        cs,
+		ImpersonationClient: impersonation,
+		UserEmail:           "user@example.com",
+		Region:              cloudfake.Region,
+		RefreshInterval:     time.Hour,
+		Clock:               clk,
+	}
+}
+
+func TestEnsureCSIToken_IdentityGeneration(t *testing.T) {
+	tests := []struct {
+		name           string
+		existing       *corev1.Secret
+		wantGeneration string
+	}{
+		{name: "new secret", wantGeneration: "1"},
+		{name: "same identity", existing: csiTokenSecret("user@example.com", cloudfake.Region, "4"), wantGeneration: "4"},
+		{name: "user changed", existing: csiTokenSecret("old@example.com", cloudfake.Region, "4"), wantGeneration: "5"},
+		{name: "region changed", existing: csiTokenSecret("user@example.com", "sjc", "4"), wantGeneration: "5"},
+		{name: "written before generations", existing: csiTokenSecret("user@example.com", cloudfake.Region, ""), wantGeneration: "1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cs := fake.NewSimpleClientset()
+			if tt.existing != nil {
+				cs = fake.NewSimpleClientset(tt.existing)
+			}
+			c := newCSITokenTestController(t, cs, testingclock.NewFakeClock(time.Now()))
+			ctx := context.Background()
+
+			if err := c.ensureCSIToken(ctx); err != nil {
+				t.Fatalf("ensureCSIToken() error = %v", err)
+			}
+			secret, err := cs.CoreV1().Secrets(CSINamespace).Get(ctx, CSITokenSecretName, metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("Get() error = %v", err)
+			}
+			if got := secret.Annotations[AnnotationCSIIdentityGeneration]; got != tt.wantGeneration {
+				t.Errorf("%s = %q, want %q", AnnotationCSIIdentityGeneration, got, tt.wantGeneration)
+			}
+			if csiIdentityChanged(secret, c.UserEmail, c.Region) || secret.StringData["region"] != c.Region {
+				t.Errorf("secret annotations = %v, region = %q, want user %s region %s",
+					secret.Annotations, secret.StringData["region"], c.UserEmail, c.Region)
+			}
+		})
+	}
+}
+
+func TestCSITokenController_RewritesStaleIdentity(t *testing.T) {
+	for _, tt := range []struct {
+		name        string
+		existing    *corev1.Secret
+		wantRewrite bool
+	}{
+		{name: "stale region", existing: csiTokenSecret("user@example.com", "sjc", "1"), wantRewrite: true},
+		{name: "current identity", existing: csiTokenSecret("user@example.com", cloudfake.Region, "1")},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			clk := testingclock.NewFakeClock(time.Now())
+			cs := fake.NewSimpleClientset(tt.existing)
+			updates := make(chan struct{}, 16)
+			cs.PrependReactor("update", "secrets", func(k8stesting.Action) (bool, runtime.Object, error) {
+				updates <- struct{}{}
+				return false, nil, nil
+			})
+			c := newCSITokenTestController(t, cs, clk)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			done := make(chan struct{})
+			go func() {
+				c.refreshLoop(ctx)
+				close(done)
+			}()
+			defer func() {
+				cancel()
+				<-done
+			}()
+			waitForTicker(t, clk)
+
+			// Well before the hourly refresh, the identity check rewrites a stale secret. The
+			// identity ticker is registered just after the refresh one, so keep stepping until
+			// it fires; ten checks stay far below the refresh interval
+			for i := 0; i < 10; i++ {
+				clk.Step(CSIIdentityCheckInterval)
+				select {
+				case <-updates:
+					if !tt.wantRewrite {
+						t.Fatal("secret rewritten although its identity is current")
+					}
+					return
+				case <-time.After(50 * time.Millisecond):
+				}
+			}
+			if tt.wantRewrite {
+				t.Fatal("stale secret was not rewritten")
+			}
+		})
+	}
+}
+
+// startCSIRefreshLoop runs c.refreshLoop until the test ends
+func startCSIRefreshLoop(t *testing.T, c *CSITokenController) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		c.refreshLoop(ctx)
+		close(done)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+}
+
+// waitForCSITokenSecret waits for the token secret to exist, stepping clk by step between checks
+// if it is set
+func waitForCSITokenSecret(t *testing.T, cs *fake.Clientset, clk *testingclock.FakeClock, step time.Duration) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		_, err := cs.CoreV1().Secrets(CSINamespace).Get(context.Background(), CSITokenSecretName, metav1.GetOptions{})
+		if err == nil {
+			return
+		}
+		if !errors.IsNotFound(err) {
+			t.Fatalf("Get() error = %v", err)
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("CSI token secret was not re-provisioned")
+		}
+		if step > 0 {
+			clk.Step(step)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// waitForWatches waits until n watches have been opened on cs
+func waitForWatches(t *testing.T, cs *fake.Clientset, n int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		watches := 0
+		for _, action := range cs.Actions() {
+			if action.GetVerb() == "watch" {
+				watches++
+			}
+		}
+		if watches >= n {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %d watches, got %d", n, watches)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestCSITokenController_ReprovisionsOnDeletion(t *testing.T) {
+	tests := []struct {
+		name   string
+		delete func(ctx context.Context, cs *fake.Clientset) error
+	}{
+		{
+			name: "secret deleted",
+			delete: func(ctx context.Context, cs *fake.Clientset) error {
+				return cs.CoreV1().Secrets(CSINamespace).Delete(ctx, CSITokenSecretName, metav1.DeleteOptions{})
+			},
+		},
+		{
+			// The fake clientset does not cascade, so remove the secret along with its namespace
+			name: "namespace deleted",
+			delete: func(ctx context.Context, cs *fake.Clientset) error {
+				if err := cs.CoreV1().Namespaces().Delete(ctx, CSINamespace, metav1.DeleteOptions{}); err != nil {
+					return err
+				}
+				return cs.CoreV1().Secrets(CSINamespace).Delete(ctx, CSITokenSecretName, metav1.DeleteOptions{})
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			clk := testingclock.NewFakeClock(time.Now())
+			cs := fake.NewSimpleClientset()
+			c := newCSITokenTestController(t, cs, clk)
+			if err := c.ensureCSIToken(ctx); err != nil {
+				t.Fatalf("ensureCSIToken() error = %v", err)
+			}
+
+			startCSIRefreshLoop(t, c)
+			waitForWatches(t, cs, 2)
+
+			if err := tt.delete(ctx, cs); err != nil {
+				t.Fatalf("delete error = %v", err)
+			}
+			// The clock is never stepped, so only the watch can bring the secret back
+			waitForCSITokenSecret(t, cs, clk, 0)
+			if _, err := cs.CoreV1().Namespaces().Get(ctx, CSINamespace, metav1.GetOptions{}); err != nil {
+				t.Errorf("namespace Get() error = %v", err)
+			}
+		})
+	}
+}
+
+func TestCSITokenController_ReopensDroppedWatch(t *testing.T) {
+	ctx := context.Background()
+	clk := testingclock.NewFakeClock(time.Now())
+	cs := fake.NewSimpleClientset()
+	c := newCSITokenTestController(t, cs, clk)
+	if err := c.ensureCSIToken(ctx); err != nil {
+		t.Fatalf("ensureCSIToken() error = %v", err)
+	}
+
+	secretWatches := make(chan *watch.FakeWatcher, 8)
+	cs.PrependWatchReactor("secrets", func(k8stesting.Action) (bool, watch.Interface, error) {
+		w := watch.NewFake()
+		secretWatches <- w
+		return true, w, nil
+	})
+	startCSIRefreshLoop(t, c)
+
+	// The secret is deleted while the connection is down, so no deletion event is seen
+	(<-secretWatches).Stop()
+	if err := cs.CoreV1().Secrets(CSINamespace).Delete(ctx, CSITokenSecretName, metav1.DeleteOptions{}); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+
+	// Reopening the watch after the retry interval catches up on the missed deletion
+	waitForCSITokenSecret(t, cs, clk, InitialRetryInterval)
+}
+
+func TestEnsureCSIToken_ConfiguredLocation(t *testing.T) {
+	ctx := context.Background()
+	cs := fake.NewSimpleClientset()
+	c := newCSITokenTestController(t, cs, testingclock.NewFakeClock(time.Now()))
+	c.Namespace = "kube-system"
+	c.SecretName = "csi-token"
+
+	if err := c.ensureCSIToken(ctx); err != nil {
+		t.Fatalf("ensureCSIToken() error = %v", err)
+	}
+	if _, err := cs.CoreV1().Namespaces().Get(ctx, "kube-system", metav1.GetOptions{}); err != nil {
+		t.Errorf("namespace Get() error = %v", err)
+	}
+	secret, err := cs.CoreV1().Secrets("kube-system").Get(ctx, "csi-token", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("secret Get() error = %v", err)
+	}
+	if secret.StringData["access_token"] == "" {
+		t.Error("secret has no access_token")
+	}
+	if _, err := cs.CoreV1().Secrets(CSINamespace).Get(ctx, CSITokenSecretName, metav1.GetOptions{}); !errors.IsNotFound(err) {
+		t.Errorf("default secret Get() error = %v, want NotFound", err)
+	}
+}
+
+func TestValidateCSISecretLocation(t *testing.T) {
+	tests := []struct {
+		namespace string
+		name      string
+		wantErr   bool
+	}{
+		{namespace: CSINamespace, name: CSITokenSecretName},
+		{namespace: "kube-system", name: "cloudsigma.csi-token"},
+		{namespace: "", name: CSITokenSecretName, wantErr: true},
+		{namespace: "kube.system", name: CSITokenSecretName, wantErr: true},
+		{namespace: "Kube-System", name: CSITokenSecretName, wantErr: true},
+		{namespace: CSINamespace, name: "", wantErr: true},
+		{namespace: CSINamespace, name: "csi_token", wantErr: true},
+	}
+	for _, tt := range tests {
+		err := ValidateCSISecretLocation(tt.namespace, tt.name)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ValidateCSISecretLocation(%q, %q) error = %v, wantErr %v", tt.namespace, tt.name, err, tt.wantErr)
+		}
+	}
+}
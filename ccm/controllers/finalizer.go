@@ -0,0 +1,113 @@
+/*
+Copyright 2025 Kube-DC Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+)
+
+// FinalizerLBIPCleanup is set on LoadBalancer services managed by the LB controller. A deleted
+// service is kept until its IP has been untagged and its lb-ip config pod removed, so a deletion
+// while the controller is down no longer leaks the IP.
+const FinalizerLBIPCleanup = "cloudsigma.com/lb-ip-cleanup"
+
+// hasLBFinalizer reports whether the service carries FinalizerLBIPCleanup
+func hasLBFinalizer(svc *corev1.Service) bool {
+	for _, f := range svc.Finalizers {
+		if f == FinalizerLBIPCleanup {
+			return true
+		}
+	}
+	return false
+}
+
+// ensureLBFinalizer adds FinalizerLBIPCleanup to the service before it is given an IP
+func (c *LoadBalancerController) ensureLBFinalizer(ctx context.Context, svc *corev1.Service) error {
+	if hasLBFinalizer(svc) {
+		return nil
+	}
+	finalizers := append(append([]string(nil), svc.Finalizers...), FinalizerLBIPCleanup)
+	if err := patchServiceFinalizers(ctx, c.tenantClient(), svc, finalizers); err != nil {
+		return fmt.Errorf("failed to add finalizer to service %s/%s: %w", svc.Namespace, svc.Name, err)
+	}
+	return nil
+}
+
+// removeLBFinalizer drops FinalizerLBIPCleanup from the service once its IPs are released
+func (c *LoadBalancerController) removeLBFinalizer(ctx context.Context, svc *corev1.Service) error {
+	return removeLBFinalizer(ctx, c.tenantClient(), svc)
+}
+
+func removeLBFinalizer(ctx context.Context, client kubernetes.Interface, svc *corev1.Service) error {
+	if !hasLBFinalizer(svc) {
+		return nil
+	}
+	finalizers := make([]string, 0, len(svc.Finalizers))
+	for _, f := range svc.Finalizers {
+		if f != FinalizerLBIPCleanup {
+			finalizers = append(finalizers, f)
+		}
+	}
+	if err := patchServiceFinalizers(ctx, client, svc, finalizers); err != nil {
+		return fmt.Errorf("failed to remove finalizer from service %s/%s: %w", svc.Namespace, svc.Name, err)
+	}
+	klog.InfoS("Removed LB IP cleanup finalizer", "service", svc.Namespace+"/"+svc.Name)
+	return nil
+}
+
+// patchServiceFinalizers replaces the service's finalizers and refreshes svc from the result. The
+// patch carries the resourceVersion, so it fails instead of dropping a finalizer added concurrently.
+func patchServiceFinalizers(ctx context.Context, client kubernetes.Interface, svc *corev1.Service, finalizers []string) error {
+	patch, _ := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"finalizers":      finalizers,
+			"resourceVersion": svc.ResourceVersion,
+		},
+	})
+	updated, err := client.CoreV1().Services(svc.Namespace).Patch(ctx, svc.Name, types.MergePatchType, patch, metav1.PatchOptions{})
+	if err != nil {
+		return err
+	}
+	updated.DeepCopyInto(svc)
+	return nil
+}
+
+// RemoveLBFinalizers drops FinalizerLBIPCleanup from every service. It is run when the LB
+// controller is not running, so deleting a service it managed earlier does not block forever;
+// the IPs of such services are not untagged.
+func RemoveLBFinalizers(ctx context.Context, client kubernetes.Interface) error {
+	services, err := client.CoreV1().Services("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to list services: %w", err)
+	}
+	var errs []error
+	for i := range services.Items {
+		if err := removeLBFinalizer(ctx, client, &services.Items[i]); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,155 @@
+/*
+Copyright 2025 Kube-DC Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	testingclock "k8s.io/utils/clock/testing"
+)
+
+func TestSyncLoadBalancers_Finalizer(t *testing.T) {
+	const ip = "203.0.113.10"
+	nodes := lbTestNodes(1)
+	svc := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+		Spec:       corev1.ServiceSpec{Type: corev1.ServiceTypeLoadBalancer, Ports: []corev1.ServicePort{{Port: 80}}},
+	}
+	cs := fake.NewSimpleClientset(svc, &nodes[0])
+	api := &fakeTagsAPI{}
+	clk := testingclock.NewFakeClock(time.Now())
+	c := newTagsTestController(t, api, clk)
+	c.TenantClient = cs
+	c.staticIPs = []string{ip}
+	c.ipAssignments = map[string]string{}
+	c.serviceIPs = map[string]string{}
+	c.manualModeNodes = map[string]bool{lbTestNodeUUID(0): true}
+	ctx := context.Background()
+	get := func() *corev1.Service {
+		t.Helper()
+		got, err := cs.CoreV1().Services("default").Get(ctx, "web", metav1.GetOptions{})
+		if err != nil {
+			t.Fatalf("Get() error = %v", err)
+		}
+		return got
+	}
+	taggedIPs := func() map[string]string {
+		t.Helper()
+		c.invalidateTagCache()
+		tagged, err := c.getTaggedServiceIPs(ctx)
+		if err != nil {
+			t.Fatalf("getTaggedServiceIPs() error = %v", err)
+		}
+		return tagged
+	}
+
+	// Assigning the IP adds the finalizer
+	if err := c.syncLoadBalancers(ctx); err != nil {
+		t.Fatalf("syncLoadBalancers() error = %v", err)
+	}
+	if ingress := get().Status.LoadBalancer.Ingress; len(ingress) != 1 || ingress[0].IP != ip {
+		t.Fatalf("ingress = %v, want %s", ingress, ip)
+	}
+	if !hasLBFinalizer(get()) {
+		t.Errorf("finalizers = %v, want %s", get().Finalizers, FinalizerLBIPCleanup)
+	}
+	if _, ok := taggedIPs()[ip]; !ok {
+		t.Fatalf("IP %s not tagged for the service", ip)
+	}
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: lbIPPodName(ip), Namespace: "kube-system"}}
+	if _, err := cs.CoreV1().Pods("kube-system").Create(ctx, pod, metav1.CreateOptions{}); err != nil {
+		t.Fatalf("Create() pod error = %v", err)
+	}
+
+	// The service is deleted; the API server keeps it around until the finalizer is removed
+	deleting := get()
+	now := metav1.NewTime(clk.Now())
+	deleting.DeletionTimestamp = &now
+	if _, err := cs.CoreV1().Services("default").Update(ctx, deleting, metav1.UpdateOptions{}); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+
+	// While the IP can't be untagged, the finalizer stays and the release is retried
+	apiEndpoint := c.apiEndpoint
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+	c.apiEndpoint = failing.URL
+	c.invalidateTagCache()
+	if err := c.syncLoadBalancers(ctx); err != nil {
+		t.Fatalf("syncLoadBalancers() error = %v", err)
+	}
+	if !hasLBFinalizer(get()) {
+		t.Error("finalizer removed although the IP could not be untagged")
+	}
+	if _, err := cs.CoreV1().Pods("kube-system").Get(ctx, lbIPPodName(ip), metav1.GetOptions{}); err != nil {
+		t.Errorf("config pod removed before the IP was untagged: %v", err)
+	}
+
+	c.apiEndpoint = apiEndpoint
+	if err := c.syncLoadBalancers(ctx); err != nil {
+		t.Fatalf("syncLoadBalancers() error = %v", err)
+	}
+	if hasLBFinalizer(get()) {
+		t.Errorf("finalizers = %v after cleanup, want %s removed", get().Finalizers, FinalizerLBIPCleanup)
+	}
+	if _, ok := taggedIPs()[ip]; ok {
+		t.Errorf("IP %s still tagged for the deleted service", ip)
+	}
+	if _, err := cs.CoreV1().Pods("kube-system").Get(ctx, lbIPPodName(ip), metav1.GetOptions{}); !apierrors.IsNotFound(err) {
+		t.Errorf("config pod Get() error = %v, want NotFound", err)
+	}
+	if len(c.serviceIPs) != 0 || len(c.ipAssignments) != 0 {
+		t.Errorf("serviceIPs = %v, ipAssignments = %v after cleanup, want both empty", c.serviceIPs, c.ipAssignments)
+	}
+}
+
+func TestStart_DisabledRemovesFinalizers(t *testing.T) {
+	cs := fake.NewSimpleClientset(
+		&corev1.Service{ObjectMeta: metav1.ObjectMeta{
+			Name: "web", Namespace: "default", Finalizers: []string{"example.com/other", FinalizerLBIPCleanup},
+		}},
+		&corev1.Service{ObjectMeta: metav1.ObjectMeta{
+			Name: "api", Namespace: "default", Finalizers: []string{FinalizerLBIPCleanup},
+		}},
+	)
+	c := &LoadBalancerController{TenantClient: cs, Disabled: true}
+
+	if err := c.Start(context.Background()); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+
+	want := map[string][]string{"web": {"example.com/other"}, "api": nil}
+	for name, finalizers := range want {
+		svc, err := cs.CoreV1().Services("default").Get(context.Background(), name, metav1.GetOptions{})
+		if err != nil {
+			t.Fatalf("Get(%s) error = %v", name, err)
+		}
+		if len(svc.Finalizers) != len(finalizers) || (len(finalizers) > 0 && svc.Finalizers[0] != finalizers[0]) {
+			t.Errorf("service %s finalizers = %v, want %v", name, svc.Finalizers, finalizers)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+/*
+Copyright 2025 Kube-DC Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"fmt"
+	"time"
+
+	"k8s.io/utils/clock"
+)
+
+const (
+	// DefaultNodeSyncInterval is how often the node reconciler syncs tenant nodes
+	DefaultNodeSyncInterval = 30 * time.Second
+	// DefaultLBSyncInterval is how often LoadBalancer services are synced
+	DefaultLBSyncInterval = 30 * time.Second
+	// DefaultIPRefreshInterval is how often owned IPs are rediscovered from the CloudSigma API
+	DefaultIPRefreshInterval = 5 * time.Minute
+	// DefaultEndpointRetryInterval is how often LoadBalancer services waiting for endpoints are retried
+	DefaultEndpointRetryInterval = 5 * time.Second
+	// DefaultTenantHealthCheckInterval is how often the tenant API server is probed
+	DefaultTenantHealthCheckInterval = 30 * time.Second
+
+	// MinSyncInterval is the lowest accepted node/LoadBalancer sync interval
+	MinSyncInterval = 5 * time.Second
+	// MinIPRefreshInterval is the lowest accepted IP rediscovery interval
+	MinIPRefreshInterval = 30 * time.Second
+	// MinTokenRefreshInterval is the lowest accepted CSI token refresh interval
+	MinTokenRefreshInterval = time.Minute
+)
+
+// ValidateInterval returns an error if an interval flag is set below its minimum
+func ValidateInterval(name string, interval, minimum time.Duration) error {
+	if interval < minimum {
+		return fmt.Errorf("--%s must be at least %v, got %v", name, minimum, interval)
+	}
+	return nil
+}
+
+// intervalOrDefault returns interval, or def when the interval is unset
+func intervalOrDefault(interval, def time.Duration) time.Duration {
+	if interval <= 0 {
+		return def
+	}
+	return interval
+}
+
+// clockOrDefault returns c, or the real clock when c is unset
+func clockOrDefault(c clock.WithTicker) clock.WithTicker {
+	if c == nil {
+		return clock.RealClock{}
+	}
+	return c
+}
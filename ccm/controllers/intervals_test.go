@@ -0,0 +1,203 @@
+/*
+Copyright 2025 Kube-DC Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	testingclock "k8s.io/utils/clock/testing"
+
+	"github.com/kube-dc/cluster-api-provider-cloudsigma/pkg/auth"
+)
+
+// countingClientset returns a fake tenant clientset that reports every list of resource on a channel
+func countingClientset(resource string) (*fake.Clientset, <-chan struct{}) {
+	lists := make(chan struct{}, 16)
+	cs := fake.NewSimpleClientset()
+	cs.PrependReactor("list", resource, func(k8stesting.Action) (bool, runtime.Object, error) {
+		lists <- struct{}{}
+		return false, nil, nil
+	})
+	return cs, lists
+}
+
+// waitForTicker blocks until the loop under test has registered its sync ticker on the fake clock
+func waitForTicker(t *testing.T, clk *testingclock.FakeClock) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !clk.HasWaiters() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for the sync ticker")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func expectSync(t *testing.T, syncs <-chan struct{}, want bool) {
+	t.Helper()
+	timeout := 100 * time.Millisecond
+	if want {
+		timeout = 5 * time.Second
+	}
+	select {
+	case <-syncs:
+		if !want {
+			t.Fatal("sync ran before the interval elapsed")
+		}
+	case <-time.After(timeout):
+		if want {
+			t.Fatal("sync did not run after the interval elapsed")
+		}
+	}
+}
+
+func TestNodeReconcilerSyncInterval(t *testing.T) {
+	tests := []struct {
+		name     string
+		interval time.Duration
+		want     time.Duration
+	}{
+		{name: "default", want: DefaultNodeSyncInterval},
+		{name: "configured", interval: 2 * time.Minute, want: 2 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clk := testingclock.NewFakeClock(time.Now())
+			cs, syncs := countingClientset("nodes")
+			r := &NodeReconciler{SyncInterval: tt.interval, Clock: clk, tenantClient: cs}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			done := make(chan struct{})
+			go func() {
+				r.syncLoop(ctx)
+				close(done)
+			}()
+			defer func() {
+				cancel()
+				<-done
+			}()
+
+			expectSync(t, syncs, true) // initial sync
+			waitForTicker(t, clk)
+
+			clk.Step(tt.want - time.Second)
+			expectSync(t, syncs, false)
+			clk.Step(time.Second)
+			expectSync(t, syncs, true)
+		})
+	}
+}
+
+func TestLoadBalancerControllerSyncInterval(t *testing.T) {
+	clk := testingclock.NewFakeClock(time.Now())
+	cs, syncs := countingClientset("services")
+	c := &LoadBalancerController{
+		TenantClient:      cs,
+		SyncInterval:      time.Minute,
+		IPRefreshInterval: 15 * time.Minute,
+		Clock:             clk,
+		done:              make(chan struct{}),
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go c.syncLoop(ctx)
+	defer func() {
+		cancel()
+		c.WaitForShutdown()
+	}()
+
+	waitForTicker(t, clk)
+
+	clk.Step(59 * time.Second)
+	expectSync(t, syncs, false)
+	clk.Step(time.Second)
+	expectSync(t, syncs, true)
+}
+
+func TestCSITokenControllerRefreshInterval(t *testing.T) {
+	tests := []struct {
+		name     string
+		interval time.Duration
+		want     time.Duration
+	}{
+		{name: "default", want: TokenRefreshInterval},
+		{name: "configured", interval: 3 * time.Minute, want: 3 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The OAuth endpoint is unreachable, so a refresh fails right after ensuring the namespace
+			impersonationClient, err := auth.NewImpersonationClient(auth.ImpersonationConfig{
+				OAuthURL:     "http://127.0.0.1:1",
+				ClientID:     "client",
+				ClientSecret: "secret",
+			})
+			if err != nil {
+				t.Fatalf("NewImpersonationClient() error = %v", err)
+			}
+
+			clk := testingclock.NewFakeClock(time.Now())
+			cs := fake.NewSimpleClientset()
+			refreshes := make(chan struct{}, 16)
+			cs.PrependReactor("get", "namespaces", func(k8stesting.Action) (bool, runtime.Object, error) {
+				refreshes <- struct{}{}
+				return false, nil, nil
+			})
+			c := &CSITokenController{
+				TenantClient:        cs,
+				ImpersonationClient: impersonationClient,
+				UserEmail:           "user@example.com",
+				Region:              "zrh",
+				RefreshInterval:     tt.interval,
+				Clock:               clk,
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			done := make(chan struct{})
+			go func() {
+				c.refreshLoop(ctx)
+				close(done)
+			}()
+			defer func() {
+				cancel()
+				<-done
+			}()
+
+			waitForTicker(t, clk)
+
+			clk.Step(tt.want - time.Second)
+			expectSync(t, refreshes, false)
+			clk.Step(time.Second)
+			expectSync(t, refreshes, true)
+		})
+	}
+}
+
+func TestValidateInterval(t *testing.T) {
+	if err := ValidateInterval("lb-sync-interval", time.Second, MinSyncInterval); err == nil {
+		t.Error("expected an interval below the minimum to be rejected")
+	}
+	if err := ValidateInterval("lb-sync-interval", MinSyncInterval, MinSyncInterval); err != nil {
+		t.Errorf("ValidateInterval() error = %v", err)
+	}
+}
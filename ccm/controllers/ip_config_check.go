@@ -0,0 +1,143 @@
+/*
+Copyright 2025 Kube-DC Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// AnnotationNodeBootID records on an lb-ip config pod the boot ID of its node when the pod was
+	// created. A different boot ID on the node means it rebooted and lost the IP configuration.
+	AnnotationNodeBootID = "cloudsigma.com/node-boot-id"
+
+	// lbIPNotReadyTimeout is how long an lb-ip config pod may fail its readiness check (the IP or
+	// its DNAT rule missing on the node) before the IP is configured again
+	lbIPNotReadyTimeout = 2 * time.Minute
+)
+
+// lbIPConfigProblem returns why an assigned IP is not configured on its node, or "" if it is.
+// pod is the IP's config pod (nil if there is none) and node the node it runs on (nil if unknown).
+func lbIPConfigProblem(pod *corev1.Pod, node *corev1.Node, now time.Time) string {
+	if pod == nil {
+		return "config pod is missing"
+	}
+	if pod.DeletionTimestamp != nil {
+		return ""
+	}
+	if node != nil {
+		configured, current := pod.Annotations[AnnotationNodeBootID], node.Status.NodeInfo.BootID
+		if configured != "" && current != "" && configured != current {
+			return fmt.Sprintf("node %s rebooted since the IP was configured", node.Name)
+		}
+	}
+	if pod.Status.Phase == corev1.PodFailed || pod.Status.Phase == corev1.PodSucceeded {
+		return fmt.Sprintf("config pod %s", strings.ToLower(string(pod.Status.Phase)))
+	}
+
+	// A pod that has not reported readiness yet counts from its creation
+	since := pod.CreationTimestamp.Time
+	for _, cond := range pod.Status.Conditions {
+		if cond.Type != corev1.PodReady {
+			continue
+		}
+		if cond.Status == corev1.ConditionTrue {
+			return ""
+		}
+		since = cond.LastTransitionTime.Time
+	}
+	if notReady := now.Sub(since); notReady >= lbIPNotReadyTimeout {
+		return fmt.Sprintf("config pod not ready for %v", notReady.Round(time.Second))
+	}
+	return ""
+}
+
+// verifyIPConfigs checks that every assigned IP is still configured on its node and configures it
+// again if not. The controller otherwise only creates a config pod when it assigns an IP, so the
+// IP stayed down after e.g. a node reboot dropped the address and its DNAT rules.
+func (c *LoadBalancerController) verifyIPConfigs(ctx context.Context) {
+	type assignment struct{ ipKey, ip, serverUUID string }
+	c.mutex.RLock()
+	var assigned []assignment
+	for ipKey, ip := range c.serviceIPs {
+		// Services waiting for endpoints have no config pod yet
+		if serverUUID, ok := c.ipAssignments[ip]; ok && !c.waitingForEndpoints[ipKey] {
+			assigned = append(assigned, assignment{ipKey, ip, serverUUID})
+		}
+	}
+	c.mutex.RUnlock()
+	if len(assigned) == 0 {
+		return
+	}
+
+	pods, err := c.tenantClient().CoreV1().Pods("kube-system").List(ctx, metav1.ListOptions{LabelSelector: "app=cloudsigma-lb-ip"})
+	if err != nil {
+		klog.Warningf("Failed to list LB IP config pods: %v", err)
+		return
+	}
+	podsByName := make(map[string]*corev1.Pod, len(pods.Items))
+	for i := range pods.Items {
+		podsByName[pods.Items[i].Name] = &pods.Items[i]
+	}
+	nodes, err := c.tenantClient().CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		klog.Warningf("Failed to list nodes: %v", err)
+		return
+	}
+	nodesByName := make(map[string]*corev1.Node, len(nodes.Items))
+	for i := range nodes.Items {
+		nodesByName[nodes.Items[i].Name] = &nodes.Items[i]
+	}
+
+	now := clockOrDefault(c.Clock).Now()
+	for _, a := range assigned {
+		pod := podsByName[lbIPPodName(a.ip)]
+		var node *corev1.Node
+		if pod != nil {
+			node = nodesByName[pod.Spec.NodeName]
+		}
+		problem := lbIPConfigProblem(pod, node, now)
+		if problem == "" {
+			continue
+		}
+
+		namespace, name, _ := strings.Cut(serviceKeyFromIPKey(a.ipKey), "/")
+		svc, err := c.tenantClient().CoreV1().Services(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil || len(svc.Spec.Ports) == 0 {
+			continue
+		}
+		endpointIP := c.dnatTarget(ctx, svc, ipFamilyOf(a.ip), a.ipKey)
+		if endpointIP == "" {
+			continue
+		}
+
+		klog.Warningf("LoadBalancer IP %s is assigned but not configured (%s), configuring it again", a.ip, problem)
+		if err := c.configureIPOnNode(ctx, a.ip, a.serverUUID, endpointIP, svc.Spec.Ports[0].Port); err != nil {
+			klog.Errorf("Failed to reconfigure IP %s: %v", a.ip, err)
+			continue
+		}
+		c.recordEvent(svc, corev1.EventTypeWarning, EventReasonIPReconfigured,
+			"Configured LoadBalancer IP %s on its node again: %s", a.ip, problem)
+	}
+}
@@ -0,0 +1,160 @@
+/*
+Copyright 2025 Kube-DC Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	testingclock "k8s.io/utils/clock/testing"
+)
+
+func TestLBIPConfigProblem(t *testing.T) {
+	now := time.Now()
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-0"},
+		Status:     corev1.NodeStatus{NodeInfo: corev1.NodeSystemInfo{BootID: "boot-2"}},
+	}
+	pod := func(bootID string, phase corev1.PodPhase, ready corev1.ConditionStatus, since time.Duration) *corev1.Pod {
+		p := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				CreationTimestamp: metav1.NewTime(now.Add(-time.Hour)),
+				Annotations:       map[string]string{AnnotationNodeBootID: bootID},
+			},
+			Status: corev1.PodStatus{Phase: phase},
+		}
+		if ready != "" {
+			p.Status.Conditions = []corev1.PodCondition{{
+				Type: corev1.PodReady, Status: ready, LastTransitionTime: metav1.NewTime(now.Add(-since)),
+			}}
+		}
+		return p
+	}
+	deleting := pod("boot-1", corev1.PodRunning, corev1.ConditionFalse, time.Hour)
+	deleting.DeletionTimestamp = &metav1.Time{Time: now}
+
+	tests := []struct {
+		name        string
+		pod         *corev1.Pod
+		node        *corev1.Node
+		wantProblem bool
+	}{
+		{name: "ready", pod: pod("boot-2", corev1.PodRunning, corev1.ConditionTrue, time.Hour), node: node},
+		{name: "pod missing", pod: nil, node: node, wantProblem: true},
+		{name: "pod deleting", pod: deleting, node: node},
+		{name: "node rebooted", pod: pod("boot-1", corev1.PodRunning, corev1.ConditionTrue, time.Hour), node: node, wantProblem: true},
+		{name: "pod without boot ID", pod: pod("", corev1.PodRunning, corev1.ConditionTrue, time.Hour), node: node},
+		{name: "node unknown", pod: pod("boot-1", corev1.PodRunning, corev1.ConditionTrue, time.Hour)},
+		{name: "pod failed", pod: pod("boot-2", corev1.PodFailed, "", 0), node: node, wantProblem: true},
+		{name: "not ready briefly", pod: pod("boot-2", corev1.PodRunning, corev1.ConditionFalse, 30*time.Second), node: node},
+		{name: "not ready past timeout", pod: pod("boot-2", corev1.PodRunning, corev1.ConditionFalse, lbIPNotReadyTimeout), node: node, wantProblem: true},
+		{name: "never reported ready", pod: pod("boot-2", corev1.PodPending, "", 0), node: node, wantProblem: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := lbIPConfigProblem(tt.pod, tt.node, now); (got != "") != tt.wantProblem {
+				t.Errorf("lbIPConfigProblem() = %q, want problem %v", got, tt.wantProblem)
+			}
+		})
+	}
+}
+
+func TestVerifyIPConfigs_NodeRebooted(t *testing.T) {
+	const ip = "203.0.113.10"
+	nodes := lbTestNodes(1)
+	nodes[0].Status.NodeInfo.BootID = "boot-1"
+	svc := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+		Spec:       corev1.ServiceSpec{Type: corev1.ServiceTypeLoadBalancer, Ports: []corev1.ServicePort{{Port: 80}}},
+	}
+	endpoints := &corev1.Endpoints{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+		Subsets:    []corev1.EndpointSubset{{Addresses: []corev1.EndpointAddress{{IP: "10.244.1.5"}}}},
+	}
+	cs := fake.NewSimpleClientset(svc, endpoints, &nodes[0])
+	clk := testingclock.NewFakeClock(time.Now())
+	c := &LoadBalancerController{
+		TenantClient:  cs,
+		Clock:         clk,
+		ipAssignments: map[string]string{ip: lbTestNodeUUID(0)},
+		serviceIPs:    map[string]string{"default/web": ip},
+	}
+	ctx := context.Background()
+	getPod := func() *corev1.Pod {
+		t.Helper()
+		pod, err := cs.CoreV1().Pods("kube-system").Get(ctx, lbIPPodName(ip), metav1.GetOptions{})
+		if err != nil {
+			t.Fatalf("Get() pod error = %v", err)
+		}
+		return pod
+	}
+	setPodReady := func(ready corev1.ConditionStatus) {
+		t.Helper()
+		pod := getPod()
+		pod.Status.Phase = corev1.PodRunning
+		pod.Status.Conditions = []corev1.PodCondition{{
+			Type: corev1.PodReady, Status: ready, LastTransitionTime: metav1.NewTime(clk.Now()),
+		}}
+		if _, err := cs.CoreV1().Pods("kube-system").UpdateStatus(ctx, pod, metav1.UpdateOptions{}); err != nil {
+			t.Fatalf("UpdateStatus() pod error = %v", err)
+		}
+	}
+
+	// A missing config pod is created
+	c.verifyIPConfigs(ctx)
+	if got := getPod().Annotations[AnnotationNodeBootID]; got != "boot-1" {
+		t.Fatalf("config pod boot ID = %q, want boot-1", got)
+	}
+	setPodReady(corev1.ConditionTrue)
+
+	// A configured IP is left alone
+	c.verifyIPConfigs(ctx)
+	if getPod().Status.Phase != corev1.PodRunning {
+		t.Fatal("config pod recreated although the IP is configured")
+	}
+
+	// After a reboot the pod is recreated for the new boot
+	node := nodes[0].DeepCopy()
+	node.Status.NodeInfo.BootID = "boot-2"
+	if _, err := cs.CoreV1().Nodes().UpdateStatus(ctx, node, metav1.UpdateOptions{}); err != nil {
+		t.Fatalf("UpdateStatus() node error = %v", err)
+	}
+	c.verifyIPConfigs(ctx)
+	if got := getPod().Annotations[AnnotationNodeBootID]; got != "boot-2" {
+		t.Errorf("config pod boot ID = %q after reboot, want boot-2", got)
+	}
+	if getPod().Status.Phase != "" {
+		t.Error("config pod not recreated after reboot")
+	}
+
+	// A pod whose readiness check keeps failing is recreated once the timeout passes
+	setPodReady(corev1.ConditionFalse)
+	clk.Step(lbIPNotReadyTimeout / 2)
+	c.verifyIPConfigs(ctx)
+	if getPod().Status.Phase != corev1.PodRunning {
+		t.Fatal("config pod recreated before the not-ready timeout")
+	}
+	clk.Step(lbIPNotReadyTimeout)
+	c.verifyIPConfigs(ctx)
+	if getPod().Status.Phase != "" {
+		t.Error("config pod not recreated after failing its readiness check past the timeout")
+	}
+}
@@ -0,0 +1,180 @@
+/*
+Copyright 2025 Kube-DC Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// ipFamilyOf returns the address family of an IP; anything that is not an IPv4 address is IPv6
+func ipFamilyOf(ip string) corev1.IPFamily {
+	if parsed := net.ParseIP(ip); parsed != nil && parsed.To4() == nil {
+		return corev1.IPv6Protocol
+	}
+	return corev1.IPv4Protocol
+}
+
+// serviceIPFamilies returns the families a LoadBalancer IP is assigned for. Single-stack services
+// get their primary family (IPv4 when the cluster did not set one); services whose family policy
+// allows dual-stack get one IP per family in spec.ipFamilies.
+func serviceIPFamilies(svc *corev1.Service) []corev1.IPFamily {
+	if len(svc.Spec.IPFamilies) == 0 {
+		return []corev1.IPFamily{corev1.IPv4Protocol}
+	}
+	if policy := svc.Spec.IPFamilyPolicy; policy != nil && *policy != corev1.IPFamilyPolicySingleStack {
+		return svc.Spec.IPFamilies
+	}
+	return svc.Spec.IPFamilies[:1]
+}
+
+// prefersDualStack reports whether the service asks for dual-stack but settles for its primary
+// family when there is no IP of the second one
+func prefersDualStack(svc *corev1.Service) bool {
+	policy := svc.Spec.IPFamilyPolicy
+	return policy != nil && *policy == corev1.IPFamilyPolicyPreferDualStack
+}
+
+// serviceIPKey returns the serviceIPs key for a service's IP of one family. IPv4 keeps the plain
+// namespace/name key, so single-stack IPv4 state is unchanged.
+func serviceIPKey(svcKey string, family corev1.IPFamily) string {
+	if family == corev1.IPv6Protocol {
+		return svcKey + "#" + string(corev1.IPv6Protocol)
+	}
+	return svcKey
+}
+
+// serviceKeyFromIPKey returns the namespace/name of the service a serviceIPs key belongs to
+func serviceKeyFromIPKey(ipKey string) string {
+	svcKey, _, _ := strings.Cut(ipKey, "#")
+	return svcKey
+}
+
+// lbIPPodName returns the name of the pod that configures a LoadBalancer IP on its node
+func lbIPPodName(ip string) string {
+	return "lb-ip-" + strings.NewReplacer(".", "-", ":", "-").Replace(ip)
+}
+
+// ipLabelValue returns an IP in a form allowed as a label value (IPv6 colons become dashes)
+func ipLabelValue(ip string) string {
+	return strings.ReplaceAll(ip, ":", "-")
+}
+
+// lbIPFamilyCommands are the per-family pieces of the LB IP config script
+type lbIPFamilyCommands struct {
+	// AddAddress adds the IP to $PRIMARY_IF
+	AddAddress string
+	// Announce tells the upstream router the IP moved to this node
+	Announce string
+	// Tables is the netfilter tool for the family
+	Tables string
+	// Destination is the DNAT target
+	Destination string
+}
+
+func lbIPCommands(ip, backendIP string, port int32) lbIPFamilyCommands {
+	if ipFamilyOf(ip) == corev1.IPv6Protocol {
+		return lbIPFamilyCommands{
+			AddAddress: fmt.Sprintf("ip -6 addr add %s/128 dev $PRIMARY_IF nodad", ip),
+			Announce: fmt.Sprintf(`# Send unsolicited neighbour advertisements to update the upstream router's neighbour cache
+# (IPv6 has no ARP); fall back to traffic sourced from the IP if ndsend is not available
+ndsend %[1]s $PRIMARY_IF 2>/dev/null || ping -6 -c 3 -I %[1]s ff02::1%%$PRIMARY_IF >/dev/null 2>&1 &`, ip),
+			Tables:      "ip6tables",
+			Destination: fmt.Sprintf("[%s]:%d", backendIP, port),
+		}
+	}
+	return lbIPFamilyCommands{
+		AddAddress: fmt.Sprintf("ip addr add %s/32 dev $PRIMARY_IF", ip),
+		Announce: fmt.Sprintf(`# Send gratuitous ARP to update upstream router/switch MAC table
+# Critical for failover: without GARP, traffic still routes to old node's MAC
+arping -U -c 3 -I $PRIMARY_IF %[1]s 2>/dev/null &
+arping -A -c 3 -I $PRIMARY_IF %[1]s 2>/dev/null &`, ip),
+		Tables:      "iptables",
+		Destination: fmt.Sprintf("%s:%d", backendIP, port),
+	}
+}
+
+// lbIPAssertInterval is how often the config pod re-asserts the IP and its NAT rules, so they come
+// back after something removed them, e.g. a firewalld reload or kube-proxy flushing the nat table
+const lbIPAssertInterval = 30 * time.Second
+
+// lbIPConfigScript returns the script the config pod runs to:
+// 1. Add IP to primary interface (manual NIC mode allows all subscribed IPs at firewall level)
+// 2. Add DNAT rules for external (PREROUTING) and local (OUTPUT) traffic
+// 3. Add MASQUERADE for return traffic
+// Every step checks before it adds, so the script re-runs them each lbIPAssertInterval and only
+// restores what is missing. IPv6 IPs use ip -6, neighbour advertisements and ip6tables in place
+// of ip, ARP and iptables.
+func lbIPConfigScript(ip, backendIP string, port int32) string {
+	cmds := lbIPCommands(ip, backendIP, port)
+	return fmt.Sprintf(`
+echo "Configuring LoadBalancer IP %[1]s"
+
+# Find primary interface (first non-lo, non-cilium interface)
+PRIMARY_IF=$(ip -o link show | grep -v -E 'lo:|cilium|lxc|veth' | head -1 | awk -F': ' '{print $2}')
+echo "Primary interface: $PRIMARY_IF"
+
+# Add whatever is missing of the IP and its rules, listing what was added in $ADDED
+assert_config() {
+  ADDED=""
+
+  # Add LoadBalancer IP to primary interface as secondary IP
+  # NIC is in manual mode - CloudSigma firewall allows all subscribed IPs
+  ip -o addr show dev $PRIMARY_IF | grep -qF " %[1]s/" || \
+    { %[2]s && ADDED="$ADDED address"; }
+
+  # Add DNAT rules for external traffic (PREROUTING)
+  %[4]s -t nat -C PREROUTING -d %[1]s -p tcp --dport %[5]d -j DNAT --to-destination %[6]s 2>/dev/null || \
+    { %[4]s -t nat -I PREROUTING 1 -d %[1]s -p tcp --dport %[5]d -j DNAT --to-destination %[6]s && ADDED="$ADDED PREROUTING"; }
+
+  # Add DNAT rules for local traffic (OUTPUT) - needed for traffic originating from the node
+  %[4]s -t nat -C OUTPUT -d %[1]s -p tcp --dport %[5]d -j DNAT --to-destination %[6]s 2>/dev/null || \
+    { %[4]s -t nat -I OUTPUT 1 -d %[1]s -p tcp --dport %[5]d -j DNAT --to-destination %[6]s && ADDED="$ADDED OUTPUT"; }
+
+  # Add MASQUERADE for return traffic
+  %[4]s -t nat -C POSTROUTING -d %[7]s -p tcp --dport %[5]d -j MASQUERADE 2>/dev/null || \
+    { %[4]s -t nat -A POSTROUTING -d %[7]s -p tcp --dport %[5]d -j MASQUERADE && ADDED="$ADDED POSTROUTING"; }
+}
+
+assert_config
+
+%[3]s
+
+echo "Configured LoadBalancer IP %[1]s on $PRIMARY_IF with DNAT to %[6]s"
+# Keep running to maintain the rules: restore them if something removed them
+while true; do
+  sleep %[8]d
+  assert_config
+  if [ -n "$ADDED" ]; then
+    echo "Restored LoadBalancer IP %[1]s:$ADDED"
+  fi
+done
+`, ip, cmds.AddAddress, cmds.Announce, cmds.Tables, port, cmds.Destination, backendIP, int(lbIPAssertInterval.Seconds()))
+}
+
+// lbIPCheckScript returns the readiness check of the config pod: it fails once the IP is gone from
+// the node's interfaces or its PREROUTING DNAT rule is missing, e.g. after the node rebooted
+func lbIPCheckScript(ip, backendIP string, port int32) string {
+	cmds := lbIPCommands(ip, backendIP, port)
+	return fmt.Sprintf(`ip -o addr show | grep -qF " %[1]s/" && \
+%[2]s -t nat -C PREROUTING -d %[1]s -p tcp --dport %[3]d -j DNAT --to-destination %[4]s`,
+		ip, cmds.Tables, port, cmds.Destination)
+}
@@ -0,0 +1,297 @@
+/*
+Copyright 2025 Kube-DC Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestIPFamilyOf(t *testing.T) {
+	tests := map[string]corev1.IPFamily{
+		"203.0.113.10":     corev1.IPv4Protocol,
+		"::ffff:192.0.2.1": corev1.IPv4Protocol,
+		"2001:db8::10":     corev1.IPv6Protocol,
+		"fd00::5":          corev1.IPv6Protocol,
+	}
+	for ip, want := range tests {
+		if got := ipFamilyOf(ip); got != want {
+			t.Errorf("ipFamilyOf(%q) = %s, want %s", ip, got, want)
+		}
+	}
+}
+
+func TestServiceIPFamilies(t *testing.T) {
+	singleStack := corev1.IPFamilyPolicySingleStack
+	preferDual := corev1.IPFamilyPolicyPreferDualStack
+	requireDual := corev1.IPFamilyPolicyRequireDualStack
+	dual := []corev1.IPFamily{corev1.IPv6Protocol, corev1.IPv4Protocol}
+
+	tests := []struct {
+		name   string
+		spec   corev1.ServiceSpec
+		expect []corev1.IPFamily
+	}{
+		{name: "unset defaults to IPv4", expect: []corev1.IPFamily{corev1.IPv4Protocol}},
+		{name: "single-stack IPv6", spec: corev1.ServiceSpec{IPFamilyPolicy: &singleStack, IPFamilies: []corev1.IPFamily{corev1.IPv6Protocol}}, expect: []corev1.IPFamily{corev1.IPv6Protocol}},
+		{name: "single-stack keeps primary family", spec: corev1.ServiceSpec{IPFamilyPolicy: &singleStack, IPFamilies: dual}, expect: dual[:1]},
+		{name: "prefer dual-stack", spec: corev1.ServiceSpec{IPFamilyPolicy: &preferDual, IPFamilies: dual}, expect: dual},
+		{name: "require dual-stack", spec: corev1.ServiceSpec{IPFamilyPolicy: &requireDual, IPFamilies: dual}, expect: dual},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := serviceIPFamilies(&corev1.Service{Spec: tt.spec})
+			if len(got) != len(tt.expect) {
+				t.Fatalf("serviceIPFamilies() = %v, want %v", got, tt.expect)
+			}
+			for i := range got {
+				if got[i] != tt.expect[i] {
+					t.Errorf("serviceIPFamilies() = %v, want %v", got, tt.expect)
+				}
+			}
+		})
+	}
+}
+
+func TestServiceIPKey(t *testing.T) {
+	if got := serviceIPKey("default/web", corev1.IPv4Protocol); got != "default/web" {
+		t.Errorf("IPv4 key = %q, want the plain service key", got)
+	}
+	v6 := serviceIPKey("default/web", corev1.IPv6Protocol)
+	if v6 == "default/web" {
+		t.Error("IPv6 key collides with the IPv4 key")
+	}
+	if got := serviceKeyFromIPKey(v6); got != "default/web" {
+		t.Errorf("serviceKeyFromIPKey(%q) = %q, want default/web", v6, got)
+	}
+	if got := lbIPPodName("2001:db8::10"); got != "lb-ip-2001-db8--10" {
+		t.Errorf("lbIPPodName() = %q, want lb-ip-2001-db8--10", got)
+	}
+	if got := lbIPPodName("203.0.113.10"); got != "lb-ip-203-0-113-10" {
+		t.Errorf("lbIPPodName() = %q, want lb-ip-203-0-113-10", got)
+	}
+}
+
+func TestLBIPConfigScript(t *testing.T) {
+	tests := []struct {
+		name      string
+		ip        string
+		backendIP string
+		want      []string
+		notWant   []string
+	}{
+		{
+			name:      "IPv4",
+			ip:        "203.0.113.10",
+			backendIP: "10.244.1.5",
+			want: []string{
+				"ip addr add 203.0.113.10/32 dev $PRIMARY_IF",
+				"arping -U -c 3 -I $PRIMARY_IF 203.0.113.10",
+				"iptables -t nat -I PREROUTING 1 -d 203.0.113.10 -p tcp --dport 8080 -j DNAT --to-destination 10.244.1.5:8080",
+				"iptables -t nat -A POSTROUTING -d 10.244.1.5 -p tcp --dport 8080 -j MASQUERADE",
+			},
+			notWant: []string{"ip6tables", "ip -6", "ndsend"},
+		},
+		{
+			name:      "IPv6",
+			ip:        "2001:db8::10",
+			backendIP: "fd00:10:244::5",
+			want: []string{
+				"ip -6 addr add 2001:db8::10/128 dev $PRIMARY_IF",
+				"ndsend 2001:db8::10 $PRIMARY_IF",
+				"ip6tables -t nat -I PREROUTING 1 -d 2001:db8::10 -p tcp --dport 8080 -j DNAT --to-destination [fd00:10:244::5]:8080",
+				"ip6tables -t nat -I OUTPUT 1 -d 2001:db8::10 -p tcp --dport 8080 -j DNAT --to-destination [fd00:10:244::5]:8080",
+				"ip6tables -t nat -A POSTROUTING -d fd00:10:244::5 -p tcp --dport 8080 -j MASQUERADE",
+			},
+			notWant: []string{"arping", "iptables -t nat", "/32", "%!"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			script := lbIPConfigScript(tt.ip, tt.backendIP, 8080)
+			for _, s := range tt.want {
+				if !strings.Contains(script, s) {
+					t.Errorf("script is missing %q", s)
+				}
+			}
+			for _, s := range tt.notWant {
+				if strings.Contains(script, s) {
+					t.Errorf("script unexpectedly contains %q", s)
+				}
+			}
+
+			// The readiness check looks for the address and the PREROUTING rule the script adds
+			check := lbIPCheckScript(tt.ip, tt.backendIP, 8080)
+			if !strings.Contains(check, `grep -qF " `+tt.ip+`/"`) {
+				t.Errorf("check %q does not look for the address", check)
+			}
+			rule := strings.Replace(tt.want[2], "-I PREROUTING 1", "-C PREROUTING", 1)
+			if !strings.Contains(check, rule) {
+				t.Errorf("check %q does not look for %q", check, rule)
+			}
+		})
+	}
+}
+
+func TestLBIPConfigScript_ReassertsRules(t *testing.T) {
+	for _, ip := range []string{"203.0.113.10", "2001:db8::10"} {
+		t.Run(string(ipFamilyOf(ip)), func(t *testing.T) {
+			backendIP := "10.244.1.5"
+			if ipFamilyOf(ip) == corev1.IPv6Protocol {
+				backendIP = "fd00:10:244::5"
+			}
+			script := lbIPConfigScript(ip, backendIP, 8080)
+			cmds := lbIPCommands(ip, backendIP, 8080)
+
+			// Each addition only runs when its check fails, so re-running them changes nothing
+			guarded := 0
+			lines := strings.Split(script, "\n")
+			for i, line := range lines {
+				add := strings.TrimSpace(line)
+				if !strings.HasPrefix(add, "{ ") {
+					continue
+				}
+				guarded++
+				check := strings.TrimSpace(lines[i-1])
+				if !strings.HasSuffix(check, "|| \\") {
+					t.Errorf("%q is not guarded by a check, previous line is %q", add, check)
+				}
+				if strings.Contains(add, cmds.Tables) && !strings.HasPrefix(check, cmds.Tables+" -t nat -C ") {
+					t.Errorf("%q is not guarded by a rule check, previous line is %q", add, check)
+				}
+			}
+			if guarded != 4 {
+				t.Errorf("script has %d guarded additions, want 4 (address, PREROUTING, OUTPUT, POSTROUTING)", guarded)
+			}
+			if !strings.Contains(script, "{ "+cmds.AddAddress+" && ") {
+				t.Errorf("script does not add the address with %q", cmds.AddAddress)
+			}
+
+			// The additions are re-run on an interval rather than the pod sleeping forever
+			loop := fmt.Sprintf("while true; do\n  sleep %d\n  assert_config\n", int(lbIPAssertInterval.Seconds()))
+			if !strings.Contains(script, loop) {
+				t.Errorf("script does not re-assert the rules every %v", lbIPAssertInterval)
+			}
+			if strings.Contains(script, "sleep 3600") {
+				t.Errorf("script still sleeps forever")
+			}
+			if strings.Count(script, "assert_config") != 3 {
+				t.Errorf("assert_config should be defined, run once and re-run in the loop")
+			}
+		})
+	}
+}
+
+func TestReconcileService_DualStack(t *testing.T) {
+	const v4, v6 = "203.0.113.10", "2001:db8::10"
+	dualStack := corev1.IPFamilyPolicyRequireDualStack
+	svc := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+		Spec: corev1.ServiceSpec{
+			Type:           corev1.ServiceTypeLoadBalancer,
+			IPFamilyPolicy: &dualStack,
+			IPFamilies:     []corev1.IPFamily{corev1.IPv4Protocol, corev1.IPv6Protocol},
+		},
+	}
+	cs := fake.NewSimpleClientset(svc)
+	c := &LoadBalancerController{
+		TenantClient:  cs,
+		staticIPs:     []string{v4, v6},
+		ipAssignments: map[string]string{v4: lbTestNodeUUID(0), v6: lbTestNodeUUID(0)},
+		serviceIPs: map[string]string{
+			serviceIPKey("default/web", corev1.IPv4Protocol): v4,
+			serviceIPKey("default/web", corev1.IPv6Protocol): v6,
+		},
+	}
+	ctx := context.Background()
+
+	if err := c.reconcileService(ctx, svc, lbTestNodes(1)); err != nil {
+		t.Fatalf("reconcileService() error = %v", err)
+	}
+	got, err := cs.CoreV1().Services("default").Get(ctx, "web", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	ingress := got.Status.LoadBalancer.Ingress
+	if len(ingress) != 2 || ingress[0].IP != v4 || ingress[1].IP != v6 {
+		t.Errorf("ingress = %+v, want %s and %s", ingress, v4, v6)
+	}
+
+	// The IPv6 pool is drawn from separately: with its only IP taken it is exhausted
+	other := &corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "default"}}
+	ip, usage, err := c.allocateIP(ctx, other, corev1.IPv6Protocol)
+	if err != nil || ip != "" {
+		t.Fatalf("allocateIP(IPv6) = %q, %v, want an exhausted pool", ip, err)
+	}
+	if want := "static IPv6 pool exhausted: 1 of 1 IPs in use"; usage.String() != want {
+		t.Errorf("usage = %q, want %q", usage.String(), want)
+	}
+}
+
+func TestReconcileService_PreferDualStackWithoutIPv6(t *testing.T) {
+	const v4 = "203.0.113.10"
+	tests := []struct {
+		name          string
+		policy        corev1.IPFamilyPolicy
+		wantExhausted bool
+	}{
+		{name: "prefer dual-stack falls back to single-stack", policy: corev1.IPFamilyPolicyPreferDualStack},
+		{name: "require dual-stack reports the exhausted pool", policy: corev1.IPFamilyPolicyRequireDualStack, wantExhausted: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy := tt.policy
+			svc := &corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+				Spec: corev1.ServiceSpec{
+					Type:           corev1.ServiceTypeLoadBalancer,
+					IPFamilyPolicy: &policy,
+					IPFamilies:     []corev1.IPFamily{corev1.IPv4Protocol, corev1.IPv6Protocol},
+				},
+			}
+			cs := fake.NewSimpleClientset(svc)
+			// The pool has no IPv6 IPs at all
+			c := &LoadBalancerController{
+				TenantClient:  cs,
+				staticIPs:     []string{v4},
+				ipAssignments: map[string]string{v4: lbTestNodeUUID(0)},
+				serviceIPs:    map[string]string{serviceIPKey("default/web", corev1.IPv4Protocol): v4},
+			}
+			ctx := context.Background()
+
+			if err := c.reconcileService(ctx, svc, lbTestNodes(1)); err != nil {
+				t.Fatalf("reconcileService() error = %v", err)
+			}
+			got, err := cs.CoreV1().Services("default").Get(ctx, "web", metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("Get() error = %v", err)
+			}
+			if ingress := got.Status.LoadBalancer.Ingress; len(ingress) != 1 || ingress[0].IP != v4 {
+				t.Errorf("ingress = %+v, want only %s", ingress, v4)
+			}
+			if _, exhausted := got.Annotations[AnnotationIPPoolExhausted]; exhausted != tt.wantExhausted {
+				t.Errorf("%s set = %v, want %v", AnnotationIPPoolExhausted, exhausted, tt.wantExhausted)
+			}
+		})
+	}
+}
@@ -0,0 +1,281 @@
+/*
+Copyright 2025 Kube-DC Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/cloudsigma/cloudsigma-sdk-go/cloudsigma"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// ipLockTagPrefix prefixes the CloudSigma tags used to claim dynamic IPs: lock:<cluster>:<ip>
+	ipLockTagPrefix = "lock:"
+
+	// dynamicIPLockTTL is how long a lock stays valid without being renewed. Locks are renewed
+	// every LB sync, so only locks of clusters whose CCM is gone expire.
+	dynamicIPLockTTL = 5 * time.Minute
+)
+
+// ipLock is a claim on a dynamic IP by one cluster
+type ipLock struct {
+	UUID     string
+	Cluster  string
+	IP       string
+	Acquired time.Time
+	Renewed  time.Time
+}
+
+func (l ipLock) tagName() string {
+	return ipLockTagPrefix + l.Cluster + ":" + l.IP
+}
+
+func (l ipLock) live(now time.Time) bool {
+	return now.Sub(l.Renewed) < dynamicIPLockTTL
+}
+
+// ipLockStore persists dynamic IP locks; the CloudSigma tags API in production
+type ipLockStore interface {
+	listIPLocks(ctx context.Context) ([]ipLock, error)
+	createIPLock(ctx context.Context, lock ipLock) error
+	renewIPLock(ctx context.Context, lock ipLock) error
+	deleteIPLock(ctx context.Context, uuid string) error
+}
+
+func (c *LoadBalancerController) ipLocks() ipLockStore {
+	if c.lockStore == nil {
+		return &tagIPLockStore{c: c}
+	}
+	return c.lockStore
+}
+
+// acquireIPLock claims a dynamic IP for this cluster before it is assigned. Dynamic IPs carry no
+// subscription, so several clusters may see the same IP as free at once; the service:* tags that
+// mark an IP in use are eventually consistent and can't prevent that. Each contender writes its
+// own lock tag and then re-reads all locks for the IP; the oldest live lock wins (cluster name
+// breaks ties), and losers remove theirs. An existing live lock of another cluster is respected.
+func (c *LoadBalancerController) acquireIPLock(ctx context.Context, ip string) (bool, error) {
+	store := c.ipLocks()
+	now := clockOrDefault(c.Clock).Now()
+
+	locks, err := store.listIPLocks(ctx)
+	if err != nil {
+		return false, err
+	}
+	for _, l := range locks {
+		if l.IP != ip || !l.live(now) {
+			continue
+		}
+		if l.Cluster != c.ClusterName {
+			klog.V(2).Infof("Dynamic IP %s is locked by cluster %s", ip, l.Cluster)
+			return false, nil
+		}
+		return true, nil
+	}
+
+	own := ipLock{Cluster: c.ClusterName, IP: ip, Acquired: now, Renewed: now}
+	if err := store.createIPLock(ctx, own); err != nil {
+		return false, err
+	}
+
+	locks, err = store.listIPLocks(ctx)
+	if err != nil {
+		return false, err
+	}
+	var winner *ipLock
+	for i := range locks {
+		l := &locks[i]
+		if l.IP != ip || !l.live(now) {
+			continue
+		}
+		if l.Cluster == c.ClusterName {
+			own.UUID = l.UUID
+		}
+		if winner == nil || l.Acquired.Before(winner.Acquired) ||
+			(l.Acquired.Equal(winner.Acquired) && l.Cluster < winner.Cluster) {
+			winner = l
+		}
+	}
+	if winner != nil && winner.Cluster == c.ClusterName {
+		klog.InfoS("Locked dynamic IP", "ip", ip)
+		return true, nil
+	}
+
+	if own.UUID != "" {
+		if err := store.deleteIPLock(ctx, own.UUID); err != nil {
+			klog.Warningf("Failed to remove lost lock on dynamic IP %s: %v", ip, err)
+		}
+	}
+	klog.InfoS("Lost dynamic IP lock to another cluster", "ip", ip)
+	return false, nil
+}
+
+// renewIPLocks keeps this cluster's locks on the dynamic IPs it has assigned alive, recreating
+// any that went missing (e.g. released on a previous shutdown)
+func (c *LoadBalancerController) renewIPLocks(ctx context.Context) {
+	c.mutex.RLock()
+	var held []string
+	for _, ip := range c.serviceIPs {
+		if c.isDynamicIPLocked(ip) {
+			held = append(held, ip)
+		}
+	}
+	c.mutex.RUnlock()
+	if len(held) == 0 {
+		return
+	}
+
+	store := c.ipLocks()
+	now := clockOrDefault(c.Clock).Now()
+	locks, err := store.listIPLocks(ctx)
+	if err != nil {
+		klog.Warningf("Failed to list dynamic IP locks for renewal: %v", err)
+		return
+	}
+	own := make(map[string]ipLock)
+	for _, l := range locks {
+		if l.Cluster == c.ClusterName {
+			own[l.IP] = l
+		}
+	}
+
+	for _, ip := range held {
+		l, ok := own[ip]
+		switch {
+		case !ok:
+			err = store.createIPLock(ctx, ipLock{Cluster: c.ClusterName, IP: ip, Acquired: now, Renewed: now})
+		case now.Sub(l.Renewed) >= dynamicIPLockTTL/3:
+			l.Renewed = now
+			err = store.renewIPLock(ctx, l)
+		default:
+			continue
+		}
+		if err != nil {
+			klog.Warningf("Failed to renew lock on dynamic IP %s: %v", ip, err)
+		}
+	}
+}
+
+// releaseIPLock removes this cluster's lock on a dynamic IP once the IP is no longer used
+func (c *LoadBalancerController) releaseIPLock(ctx context.Context, ip string) error {
+	store := c.ipLocks()
+	locks, err := store.listIPLocks(ctx)
+	if err != nil {
+		return err
+	}
+	for _, l := range locks {
+		if l.IP == ip && l.Cluster == c.ClusterName {
+			if err := store.deleteIPLock(ctx, l.UUID); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// isDynamicIPLocked checks if an IP is in the dynamic pool (must hold mutex)
+func (c *LoadBalancerController) isDynamicIPLocked(ip string) bool {
+	for _, poolIP := range c.dynamicIPs {
+		if poolIP == ip {
+			return true
+		}
+	}
+	return false
+}
+
+// tagIPLockStore keeps dynamic IP locks as CloudSigma tags named lock:<cluster>:<ip>, with the
+// acquire and renew timestamps in the tag meta
+type tagIPLockStore struct {
+	c *LoadBalancerController
+}
+
+func (s *tagIPLockStore) listIPLocks(ctx context.Context) ([]ipLock, error) {
+	tags, err := s.c.listTags(ctx, tagPrefixFilter(ipLockTagPrefix))
+	if err != nil {
+		return nil, err
+	}
+
+	var locks []ipLock
+	for _, tag := range tags {
+		if !strings.HasPrefix(tag.Name, ipLockTagPrefix) {
+			continue
+		}
+		rest := strings.TrimPrefix(tag.Name, ipLockTagPrefix)
+		sep := strings.LastIndex(rest, ":")
+		if sep <= 0 {
+			continue
+		}
+		acquiredStr, _ := tag.Meta["acquired"].(string)
+		renewedStr, _ := tag.Meta["renewed"].(string)
+		acquired, _ := time.Parse(time.RFC3339, acquiredStr)
+		renewed, _ := time.Parse(time.RFC3339, renewedStr)
+		locks = append(locks, ipLock{
+			UUID:     tag.UUID,
+			Cluster:  rest[:sep],
+			IP:       rest[sep+1:],
+			Acquired: acquired,
+			Renewed:  renewed,
+		})
+	}
+	return locks, nil
+}
+
+func (s *tagIPLockStore) createIPLock(ctx context.Context, lock ipLock) error {
+	client, err := s.c.cloudClient()
+	if err != nil {
+		return err
+	}
+	err = client.CreateTag(ctx, lockTag(lock))
+	s.c.invalidateTagCache()
+	return err
+}
+
+func (s *tagIPLockStore) renewIPLock(ctx context.Context, lock ipLock) error {
+	client, err := s.c.cloudClient()
+	if err != nil {
+		return err
+	}
+	tag := lockTag(lock)
+	tag.UUID = lock.UUID
+	err = client.UpdateTag(ctx, tag)
+	s.c.invalidateTagCache()
+	return err
+}
+
+func (s *tagIPLockStore) deleteIPLock(ctx context.Context, uuid string) error {
+	client, err := s.c.cloudClient()
+	if err != nil {
+		return err
+	}
+	err = client.DeleteTag(ctx, uuid)
+	s.c.invalidateTagCache()
+	return err
+}
+
+func lockTag(lock ipLock) cloudsigma.Tag {
+	return cloudsigma.Tag{
+		Name:      lock.tagName(),
+		Resources: []cloudsigma.TagResource{{UUID: lock.IP}},
+		Meta: map[string]interface{}{
+			"acquired": lock.Acquired.UTC().Format(time.RFC3339),
+			"renewed":  lock.Renewed.UTC().Format(time.RFC3339),
+		},
+	}
+}
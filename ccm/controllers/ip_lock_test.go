@@ -0,0 +1,223 @@
+/*
+Copyright 2025 Kube-DC Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	testingclock "k8s.io/utils/clock/testing"
+)
+
+// memIPLockStore is an in-memory lock store shared by several controllers. With contenders set,
+// the n-th lists of all contenders read the store together, so they all see it empty before
+// locking and all see each other's locks when checking who won.
+type memIPLockStore struct {
+	mu         sync.Mutex
+	locks      map[string]ipLock
+	nextID     int
+	contenders int
+	lists      int
+	barriers   []*sync.WaitGroup
+}
+
+func newMemIPLockStore() *memIPLockStore {
+	return &memIPLockStore{locks: map[string]ipLock{}}
+}
+
+func (s *memIPLockStore) listIPLocks(context.Context) ([]ipLock, error) {
+	var before, after *sync.WaitGroup
+	if s.contenders > 0 {
+		before, after = s.phaseBarriers()
+		before.Done()
+		before.Wait()
+	}
+
+	s.mu.Lock()
+	locks := make([]ipLock, 0, len(s.locks))
+	for _, l := range s.locks {
+		locks = append(locks, l)
+	}
+	s.mu.Unlock()
+
+	if after != nil {
+		after.Done()
+		after.Wait()
+	}
+	return locks, nil
+}
+
+// phaseBarriers returns the barriers for the caller's list: one all contenders pass before
+// reading (so earlier writes are visible) and one they pass after (so later writes are not)
+func (s *memIPLockStore) phaseBarriers() (before, after *sync.WaitGroup) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	phase := s.lists / s.contenders
+	s.lists++
+	for len(s.barriers) <= 2*phase+1 {
+		wg := &sync.WaitGroup{}
+		wg.Add(s.contenders)
+		s.barriers = append(s.barriers, wg)
+	}
+	return s.barriers[2*phase], s.barriers[2*phase+1]
+}
+
+func (s *memIPLockStore) createIPLock(_ context.Context, lock ipLock) error {
+	s.mu.Lock()
+	s.nextID++
+	lock.UUID = fmt.Sprintf("tag-%d", s.nextID)
+	s.locks[lock.UUID] = lock
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *memIPLockStore) renewIPLock(_ context.Context, lock ipLock) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.locks[lock.UUID] = lock
+	return nil
+}
+
+func (s *memIPLockStore) deleteIPLock(_ context.Context, uuid string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.locks, uuid)
+	return nil
+}
+
+func (s *memIPLockStore) holders(ip string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var clusters []string
+	for _, l := range s.locks {
+		if l.IP == ip {
+			clusters = append(clusters, l.Cluster)
+		}
+	}
+	return clusters
+}
+
+func TestAcquireIPLock_Race(t *testing.T) {
+	const ip = "198.51.100.7"
+	clk := testingclock.NewFakeClock(time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC))
+
+	for round := 0; round < 20; round++ {
+		store := newMemIPLockStore()
+		store.contenders = 2
+
+		controllers := []*LoadBalancerController{
+			{ClusterName: "alpha", Clock: clk, lockStore: store},
+			{ClusterName: "bravo", Clock: clk, lockStore: store},
+		}
+		won := make([]bool, len(controllers))
+		var wg sync.WaitGroup
+		for i, c := range controllers {
+			wg.Add(1)
+			go func(i int, c *LoadBalancerController) {
+				defer wg.Done()
+				ok, err := c.acquireIPLock(context.Background(), ip)
+				if err != nil {
+					t.Errorf("%s: acquireIPLock() error = %v", c.ClusterName, err)
+				}
+				won[i] = ok
+			}(i, c)
+		}
+		wg.Wait()
+
+		if won[0] == won[1] {
+			t.Fatalf("round %d: alpha won = %v, bravo won = %v; want exactly one winner", round, won[0], won[1])
+		}
+		if holders := store.holders(ip); len(holders) != 1 {
+			t.Fatalf("round %d: lock holders = %v, want only the winner", round, holders)
+		}
+	}
+}
+
+func TestAcquireIPLock_Sequential(t *testing.T) {
+	const ip = "198.51.100.7"
+	ctx := context.Background()
+	clk := testingclock.NewFakeClock(time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC))
+	store := newMemIPLockStore()
+	alpha := &LoadBalancerController{ClusterName: "alpha", Clock: clk, lockStore: store}
+	// zulu sorts after alpha, so it would lose a tie; it must still keep the IP it locked first
+	zulu := &LoadBalancerController{ClusterName: "zulu", Clock: clk, lockStore: store}
+
+	if ok, err := zulu.acquireIPLock(ctx, ip); err != nil || !ok {
+		t.Fatalf("zulu acquireIPLock() = %v, %v, want the free IP", ok, err)
+	}
+	clk.Step(time.Second)
+	if ok, _ := alpha.acquireIPLock(ctx, ip); ok {
+		t.Error("alpha took a dynamic IP already locked by zulu")
+	}
+	if ok, _ := zulu.acquireIPLock(ctx, ip); !ok {
+		t.Error("zulu lost its own lock on a repeated acquire")
+	}
+
+	// A lock that is not renewed expires, e.g. when the owning CCM is gone
+	clk.Step(dynamicIPLockTTL)
+	if ok, _ := alpha.acquireIPLock(ctx, ip); !ok {
+		t.Error("alpha could not take a dynamic IP whose lock expired")
+	}
+
+	// Releasing frees the IP for the other cluster
+	if err := alpha.releaseIPLock(ctx, ip); err != nil {
+		t.Fatalf("releaseIPLock() error = %v", err)
+	}
+	store.mu.Lock()
+	for uuid, l := range store.locks {
+		if l.Cluster == "zulu" {
+			delete(store.locks, uuid)
+		}
+	}
+	store.mu.Unlock()
+	if ok, _ := zulu.acquireIPLock(ctx, ip); !ok {
+		t.Error("zulu could not take a released dynamic IP")
+	}
+}
+
+func TestRenewIPLocks(t *testing.T) {
+	const ip = "198.51.100.7"
+	ctx := context.Background()
+	clk := testingclock.NewFakeClock(time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC))
+	store := newMemIPLockStore()
+	c := &LoadBalancerController{
+		ClusterName: "alpha",
+		Clock:       clk,
+		lockStore:   store,
+		dynamicIPs:  []string{ip},
+		serviceIPs:  map[string]string{"default/web": ip},
+	}
+	other := &LoadBalancerController{ClusterName: "bravo", Clock: clk, lockStore: store}
+
+	// A missing lock for an assigned IP is recreated
+	c.renewIPLocks(ctx)
+	if holders := store.holders(ip); len(holders) != 1 || holders[0] != "alpha" {
+		t.Fatalf("lock holders after renewal = %v, want [alpha]", holders)
+	}
+
+	// Renewed locks outlive the TTL
+	for i := 0; i < 4; i++ {
+		clk.Step(dynamicIPLockTTL / 2)
+		c.renewIPLocks(ctx)
+	}
+	if ok, _ := other.acquireIPLock(ctx, ip); ok {
+		t.Error("bravo took a dynamic IP whose lock alpha kept renewing")
+	}
+}
@@ -0,0 +1,174 @@
+/*
+Copyright 2025 Kube-DC Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/cloudsigma/cloudsigma-sdk-go/cloudsigma"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+
+	"github.com/kube-dc/cluster-api-provider-cloudsigma/pkg/cloud"
+)
+
+const (
+	// ipPurchaseTagPrefix prefixes the CloudSigma tag listing the IPs a cluster's controller
+	// purchased: purchased:<cluster>. The tag caps the purchases across restarts and tells the
+	// operator which subscriptions to cancel once they are no longer needed.
+	ipPurchaseTagPrefix = "purchased:"
+
+	// DefaultIPSubscriptionPeriod is the billing period of purchased IP subscriptions
+	DefaultIPSubscriptionPeriod = "1 month"
+
+	// ipPurchaseRetryInterval is how long the controller waits after a failed purchase before
+	// trying again, so a rejected order is not resent on every sync
+	ipPurchaseRetryInterval = 5 * time.Minute
+)
+
+func (c *LoadBalancerController) ipPurchaseTagName() string {
+	return ipPurchaseTagPrefix + c.ClusterName
+}
+
+// ipPurchaseDecision says whether an IP may be purchased for a service its pool had no IP for,
+// and why not if it may not. Only the static pool is grown, with IPv4 subscriptions, and never
+// beyond MaxPurchasedIPs purchased IPs.
+func (c *LoadBalancerController) ipPurchaseDecision(usage ipPoolUsage, purchased int) (bool, string) {
+	switch {
+	case !c.PurchaseIPs:
+		return false, "IP purchasing is disabled"
+	case usage.Pool != IPPoolStatic:
+		return false, fmt.Sprintf("only the %s pool is grown by purchases", IPPoolStatic)
+	case usage.Family != corev1.IPv4Protocol:
+		return false, "only IPv4 subscriptions are purchased"
+	case c.MaxPurchasedIPs <= 0 || purchased >= c.MaxPurchasedIPs:
+		return false, fmt.Sprintf("%d of at most %d IPs already purchased", purchased, c.MaxPurchasedIPs)
+	}
+	return true, ""
+}
+
+// purchasedIPs returns the purchase tag and the IPs the controller purchased so far
+func (c *LoadBalancerController) purchasedIPs(ctx context.Context) (cloudsigma.Tag, int, error) {
+	name := c.ipPurchaseTagName()
+	tags, err := c.listTags(ctx, tagNameFilter(name))
+	if err != nil {
+		return cloudsigma.Tag{}, 0, err
+	}
+	for _, tag := range tags {
+		if tag.Name == name {
+			return tag, len(tag.Resources), nil
+		}
+	}
+	return cloudsigma.Tag{Name: name}, 0, nil
+}
+
+// purchaseIP buys an IP subscription when the static pool had no IP for svc and the purchase is
+// allowed by ipPurchaseDecision. The IP is recorded on the purchase tag and added to the static
+// pool right away; while recording fails no further IP is bought. It returns "" when nothing
+// was bought.
+func (c *LoadBalancerController) purchaseIP(ctx context.Context, svc *corev1.Service, usage ipPoolUsage) (string, error) {
+	if !c.PurchaseIPs {
+		return "", nil
+	}
+	now := clockOrDefault(c.Clock).Now()
+	if !c.lastIPPurchaseFailure.IsZero() && now.Sub(c.lastIPPurchaseFailure) < ipPurchaseRetryInterval {
+		return "", nil
+	}
+
+	tag, _, err := c.purchasedIPs(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to count purchased IPs: %w", err)
+	}
+	client, err := c.cloudClient()
+	if err != nil {
+		return "", err
+	}
+	// An earlier purchase missing from the tag would not count against the cap
+	tag, err = c.recordPurchases(ctx, client, tag)
+	if err != nil {
+		c.lastIPPurchaseFailure = now
+		return "", fmt.Errorf("not purchasing IPs until the purchased ones are tagged: %w", err)
+	}
+	purchased := len(tag.Resources)
+	if ok, reason := c.ipPurchaseDecision(usage, purchased); !ok {
+		klog.V(2).Infof("Not purchasing an IP for service %s/%s: %s", svc.Namespace, svc.Name, reason)
+		return "", nil
+	}
+
+	period := c.IPSubscriptionPeriod
+	if period == "" {
+		period = DefaultIPSubscriptionPeriod
+	}
+	ip, err := client.PurchaseIPSubscription(ctx, period)
+	if err != nil {
+		c.lastIPPurchaseFailure = now
+		return "", err
+	}
+	lbIPPurchases.Inc()
+
+	// The purchase is done either way. Until the IP is on the tag no other IP is purchased.
+	c.untaggedPurchases = append(c.untaggedPurchases, ip)
+	if _, err := c.recordPurchases(ctx, client, tag); err != nil {
+		klog.Errorf("Purchased IP %s but failed to add it to tag %s, no more IPs are purchased until it is: %v", ip, tag.Name, err)
+		c.lastIPPurchaseFailure = now
+	}
+
+	c.mutex.Lock()
+	c.staticIPs = append(c.staticIPs, ip)
+	c.mutex.Unlock()
+
+	klog.InfoS("Purchased IP subscription for the static pool", "ip", ip, "period", period,
+		"purchased", purchased+1, "max", c.MaxPurchasedIPs, "svcKey", fmt.Sprintf("%s/%s", svc.Namespace, svc.Name))
+	c.recordEvent(svc, corev1.EventTypeNormal, EventReasonIPPurchased,
+		"Purchased IP %s (%s subscription) as the %s pool was exhausted; %d of at most %d IPs purchased",
+		ip, period, IPPoolStatic, purchased+1, c.MaxPurchasedIPs)
+	return ip, nil
+}
+
+// recordPurchases adds the IPs in untaggedPurchases to the purchase tag, as last listed, and
+// returns the updated tag. IPs that could not be added stay in untaggedPurchases.
+func (c *LoadBalancerController) recordPurchases(ctx context.Context, client *cloud.Client, tag cloudsigma.Tag) (cloudsigma.Tag, error) {
+	if len(c.untaggedPurchases) == 0 {
+		return tag, nil
+	}
+	defer c.invalidateTagCache()
+
+	tag.Meta = map[string]interface{}{"cluster": c.ClusterName}
+	for len(c.untaggedPurchases) > 0 {
+		ip := c.untaggedPurchases[0]
+		if !slices.ContainsFunc(tag.Resources, func(r cloudsigma.TagResource) bool { return r.UUID == ip }) {
+			if err := client.AddResourceToTag(ctx, tag, ip); err != nil {
+				return tag, err
+			}
+			if tag.UUID == "" {
+				// The tag was just created; list it again for its UUID so it is not created twice
+				c.invalidateTagCache()
+				created, _, err := c.purchasedIPs(ctx)
+				if err != nil {
+					return tag, err
+				}
+				tag.UUID = created.UUID
+			}
+			tag.Resources = append(tag.Resources, cloudsigma.TagResource{UUID: ip})
+		}
+		c.untaggedPurchases = c.untaggedPurchases[1:]
+	}
+	return tag, nil
+}
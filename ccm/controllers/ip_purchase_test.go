@@ -0,0 +1,215 @@
+/*
+Copyright 2025 Kube-DC Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	testingclock "k8s.io/utils/clock/testing"
+
+	"github.com/kube-dc/cluster-api-provider-cloudsigma/pkg/cloud/fake"
+)
+
+func TestIPPurchaseDecision(t *testing.T) {
+	static := ipPoolUsage{Pool: IPPoolStatic, Family: corev1.IPv4Protocol}
+	tests := []struct {
+		name      string
+		enabled   bool
+		max       int
+		usage     ipPoolUsage
+		purchased int
+		want      bool
+	}{
+		{name: "below the cap", enabled: true, max: 3, usage: static, purchased: 2, want: true},
+		{name: "disabled", enabled: false, max: 3, usage: static},
+		{name: "cap reached", enabled: true, max: 3, usage: static, purchased: 3},
+		{name: "beyond the cap", enabled: true, max: 3, usage: static, purchased: 5},
+		{name: "no cap set", enabled: true, usage: static},
+		{name: "dynamic pool", enabled: true, max: 3, usage: ipPoolUsage{Pool: IPPoolDynamic, Family: corev1.IPv4Protocol}},
+		{name: "IPv6", enabled: true, max: 3, usage: ipPoolUsage{Pool: IPPoolStatic, Family: corev1.IPv6Protocol}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &LoadBalancerController{PurchaseIPs: tt.enabled, MaxPurchasedIPs: tt.max}
+			got, reason := c.ipPurchaseDecision(tt.usage, tt.purchased)
+			if got != tt.want {
+				t.Errorf("ipPurchaseDecision() = %v (%s), want %v", got, reason, tt.want)
+			}
+			if !got && reason == "" {
+				t.Errorf("ipPurchaseDecision() gave no reason for not purchasing")
+			}
+		})
+	}
+}
+
+func TestPurchaseIP_MaxCap(t *testing.T) {
+	api := fake.NewServer()
+	defer api.Close()
+	impersonation, err := api.NewImpersonationClient()
+	if err != nil {
+		t.Fatalf("NewImpersonationClient() error = %v", err)
+	}
+	clk := testingclock.NewFakeClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	newController := func(purchase bool) *LoadBalancerController {
+		return &LoadBalancerController{
+			ImpersonationClient: impersonation,
+			UserEmail:           fake.Username,
+			Region:              fake.Region,
+			ClusterName:         "alpha",
+			Clock:               clk,
+			PurchaseIPs:         purchase,
+			MaxPurchasedIPs:     2,
+			apiEndpoint:         api.APIEndpoint(),
+			ipAssignments:       map[string]string{},
+			serviceIPs:          map[string]string{},
+		}
+	}
+	ctx := context.Background()
+	svc := reservedTestService("web", "")
+	usage := ipPoolUsage{Pool: IPPoolStatic, Family: corev1.IPv4Protocol}
+
+	// Off by default: nothing is bought however exhausted the pool is
+	if ip, err := newController(false).purchaseIP(ctx, &svc, usage); err != nil || ip != "" {
+		t.Fatalf("purchaseIP() with purchasing disabled = %q, %v; want nothing bought", ip, err)
+	}
+
+	c := newController(true)
+	var bought []string
+	for i := 0; i < 3; i++ {
+		ip, err := c.purchaseIP(ctx, &svc, usage)
+		if err != nil {
+			t.Fatalf("purchaseIP() #%d error = %v", i+1, err)
+		}
+		if ip != "" {
+			bought = append(bought, ip)
+		}
+	}
+	if len(bought) != 2 || len(api.Subscriptions()) != 2 {
+		t.Fatalf("bought %v with %d subscriptions, want 2 IPs (the cap)", bought, len(api.Subscriptions()))
+	}
+	if len(c.staticIPs) != 2 {
+		t.Errorf("static pool = %v, want the purchased IPs %v", c.staticIPs, bought)
+	}
+
+	// Purchased IPs are tagged, which keeps the cap across controller restarts
+	tag, ok := api.GetTag("purchased:alpha")
+	if !ok || len(tag.Resources) != 2 || tag.Meta["cluster"] != "alpha" {
+		t.Fatalf("tag purchased:alpha = %+v (found %v), want both purchased IPs", tag, ok)
+	}
+	if ip, err := newController(true).purchaseIP(ctx, &svc, usage); err != nil || ip != "" {
+		t.Errorf("purchaseIP() after a restart = %q, %v; want nothing bought beyond the cap", ip, err)
+	}
+	if len(api.Subscriptions()) != 2 {
+		t.Errorf("%d subscriptions after a restart, want 2", len(api.Subscriptions()))
+	}
+}
+
+func TestPurchaseIP_TaggingFails(t *testing.T) {
+	api := fake.NewServer()
+	defer api.Close()
+	impersonation, err := api.NewImpersonationClient()
+	if err != nil {
+		t.Fatalf("NewImpersonationClient() error = %v", err)
+	}
+	clk := testingclock.NewFakeClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	c := &LoadBalancerController{
+		ImpersonationClient: impersonation,
+		UserEmail:           fake.Username,
+		Region:              fake.Region,
+		ClusterName:         "alpha",
+		Clock:               clk,
+		PurchaseIPs:         true,
+		MaxPurchasedIPs:     2,
+		apiEndpoint:         api.APIEndpoint(),
+		ipAssignments:       map[string]string{},
+		serviceIPs:          map[string]string{},
+	}
+	ctx := context.Background()
+	svc := reservedTestService("web", "")
+	usage := ipPoolUsage{Pool: IPPoolStatic, Family: corev1.IPv4Protocol}
+
+	api.SetReadOnly("tags", true)
+	first, err := c.purchaseIP(ctx, &svc, usage)
+	if err != nil || first == "" {
+		t.Fatalf("purchaseIP() = %q, %v; want an IP bought", first, err)
+	}
+	// Nothing more is bought while the purchase cannot be counted, even after the retry interval
+	if ip, err := c.purchaseIP(ctx, &svc, usage); ip != "" || err != nil {
+		t.Errorf("purchaseIP() right after the tagging failure = %q, %v; want nothing bought", ip, err)
+	}
+	clk.Step(ipPurchaseRetryInterval)
+	if ip, err := c.purchaseIP(ctx, &svc, usage); ip != "" || err == nil {
+		t.Errorf("purchaseIP() with tagging still failing = %q, %v; want an error and nothing bought", ip, err)
+	}
+	if len(api.Subscriptions()) != 1 {
+		t.Fatalf("%d subscriptions while tagging fails, want 1", len(api.Subscriptions()))
+	}
+
+	// Once tags can be written the first IP is tagged before the next purchase
+	api.SetReadOnly("tags", false)
+	clk.Step(ipPurchaseRetryInterval)
+	second, err := c.purchaseIP(ctx, &svc, usage)
+	if err != nil || second == "" {
+		t.Fatalf("purchaseIP() after tagging recovered = %q, %v; want an IP bought", second, err)
+	}
+	tag, ok := api.GetTag("purchased:alpha")
+	if !ok || len(tag.Resources) != 2 {
+		t.Fatalf("tag purchased:alpha = %+v (found %v), want %s and %s", tag, ok, first, second)
+	}
+	if ip, err := c.purchaseIP(ctx, &svc, usage); err != nil || ip != "" {
+		t.Errorf("purchaseIP() at the cap = %q, %v; want nothing bought", ip, err)
+	}
+}
+
+func TestAllocation_PurchasesWhenStaticPoolIsExhausted(t *testing.T) {
+	api := fake.NewServer()
+	defer api.Close()
+	impersonation, err := api.NewImpersonationClient()
+	if err != nil {
+		t.Fatalf("NewImpersonationClient() error = %v", err)
+	}
+	c := &LoadBalancerController{
+		ImpersonationClient: impersonation,
+		UserEmail:           fake.Username,
+		Region:              fake.Region,
+		ClusterName:         "alpha",
+		PurchaseIPs:         true,
+		MaxPurchasedIPs:     1,
+		apiEndpoint:         api.APIEndpoint(),
+		ipAssignments:       map[string]string{},
+		serviceIPs:          map[string]string{},
+	}
+	ctx := context.Background()
+	svc := reservedTestService("web", "")
+
+	ip, usage, err := c.allocateIP(ctx, &svc, corev1.IPv4Protocol)
+	if err != nil || ip != "" {
+		t.Fatalf("allocateIP() from an empty pool = %q, %v; want no IP", ip, err)
+	}
+	purchased, err := c.purchaseIP(ctx, &svc, usage)
+	if err != nil || purchased == "" {
+		t.Fatalf("purchaseIP() = %q, %v; want an IP", purchased, err)
+	}
+
+	// The purchased IP is in the pool for the next allocation too, e.g. after a failed assignment
+	if ip, _, err := c.allocateIP(ctx, &svc, corev1.IPv4Protocol); err != nil || ip != purchased {
+		t.Errorf("allocateIP() after the purchase = %q, %v; want %q", ip, err, purchased)
+	}
+}
@@ -0,0 +1,229 @@
+/*
+Copyright 2025 Kube-DC Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"time"
+
+	"github.com/cloudsigma/cloudsigma-sdk-go/cloudsigma"
+	"k8s.io/klog/v2"
+
+	"github.com/kube-dc/cluster-api-provider-cloudsigma/pkg/cloud"
+)
+
+const (
+	// ipReleaseTagPrefix prefixes the CloudSigma tag listing the purchased IPs a cluster's
+	// controller released: released:<cluster>. Their subscriptions are no longer renewed, so
+	// they are kept out of the static pool until they are gone from the account.
+	ipReleaseTagPrefix = "released:"
+
+	// DefaultIPReleaseCooldown is how long a purchased IP stays unused before it is released
+	DefaultIPReleaseCooldown = time.Hour
+	// MinIPReleaseCooldown is the lowest accepted release cooldown, other than 0 (never release)
+	MinIPReleaseCooldown = 5 * time.Minute
+)
+
+func (c *LoadBalancerController) ipReleaseTagName() string {
+	return ipReleaseTagPrefix + c.ClusterName
+}
+
+// releasedIPs returns the release tag and the IPs on it
+func (c *LoadBalancerController) releasedIPs(ctx context.Context) (cloudsigma.Tag, map[string]bool, error) {
+	name := c.ipReleaseTagName()
+	tags, err := c.listTags(ctx, tagNameFilter(name))
+	if err != nil {
+		return cloudsigma.Tag{}, nil, err
+	}
+	released := make(map[string]bool)
+	for _, tag := range tags {
+		if tag.Name != name {
+			continue
+		}
+		for _, r := range tag.Resources {
+			released[r.UUID] = true
+		}
+		return tag, released, nil
+	}
+	return cloudsigma.Tag{Name: name}, released, nil
+}
+
+// releaseUnusedIPs releases the IPs the controller purchased once they have been unused for
+// IPReleaseCooldown: no service:* tag, no assignment and no live reservation holds them. Their
+// subscription renewal is stopped and they leave the static pool. The cooldown is tracked in
+// memory, so a restarted controller waits for a full cooldown again. A cooldown of 0 disables it.
+func (c *LoadBalancerController) releaseUnusedIPs(ctx context.Context) {
+	if c.IPReleaseCooldown <= 0 {
+		return
+	}
+	purchasedTag, purchased, err := c.purchasedIPs(ctx)
+	if err != nil {
+		klog.Warningf("Failed to list purchased IPs for release: %v", err)
+		return
+	}
+	if purchased == 0 {
+		c.unusedSince = nil
+		return
+	}
+
+	releasedTag, released, err := c.releasedIPs(ctx)
+	if err != nil {
+		klog.Warningf("Failed to list released IPs: %v", err)
+		return
+	}
+	tagged, err := c.getTaggedServiceIPs(ctx)
+	if err != nil {
+		klog.Warningf("Failed to list service IP tags for release: %v", err)
+		return
+	}
+	reservations, err := c.listIPReservations(ctx)
+	if err != nil {
+		klog.Warningf("Failed to list IP reservations for release: %v", err)
+		return
+	}
+
+	now := clockOrDefault(c.Clock).Now()
+	reserved := make(map[string]bool)
+	for _, r := range reservations {
+		if r.live(now, c.ipReservationTTL()) {
+			for _, ip := range r.IPs {
+				reserved[ip] = true
+			}
+		}
+	}
+
+	c.mutex.RLock()
+	assigned := make(map[string]bool, len(c.ipAssignments))
+	for ip := range c.ipAssignments {
+		assigned[ip] = true
+	}
+	c.mutex.RUnlock()
+
+	unusedSince := make(map[string]time.Time)
+	var due []string
+	for _, r := range purchasedTag.Resources {
+		ip := r.UUID
+		if released[ip] || tagged[ip] != "" || assigned[ip] || reserved[ip] {
+			continue
+		}
+		since, ok := c.unusedSince[ip]
+		if !ok {
+			since = now
+		}
+		unusedSince[ip] = since
+		if now.Sub(since) >= c.IPReleaseCooldown {
+			due = append(due, ip)
+		}
+	}
+	c.unusedSince = unusedSince
+	if len(due) == 0 {
+		return
+	}
+
+	client, err := c.cloudClient()
+	if err != nil {
+		klog.Warningf("Failed to release unused IPs: %v", err)
+		return
+	}
+	stopped := 0
+	for _, ip := range due {
+		if err := client.StopIPSubscriptionRenewal(ctx, ip); err != nil {
+			klog.Warningf("Failed to release unused purchased IP %s: %v", ip, err)
+			continue
+		}
+		releasedTag.Resources = append(releasedTag.Resources, cloudsigma.TagResource{UUID: ip})
+		stopped++
+
+		c.mutex.Lock()
+		c.staticIPs = removeIP(c.staticIPs, ip)
+		c.mutex.Unlock()
+		delete(c.unusedSince, ip)
+		lbIPReleases.Inc()
+		klog.InfoS("Released unused purchased IP", "ip", ip, "cooldown", c.IPReleaseCooldown)
+	}
+	if stopped == 0 {
+		return
+	}
+
+	// Without the tag a released IP would return to the static pool on the next discovery and
+	// could be handed out until its subscription ends
+	releasedTag.Meta = map[string]interface{}{"cluster": c.ClusterName}
+	if releasedTag.UUID == "" {
+		err = client.CreateTag(ctx, releasedTag)
+	} else {
+		err = client.UpdateTag(ctx, releasedTag)
+	}
+	c.invalidateTagCache()
+	if err != nil {
+		klog.Errorf("Failed to record released IPs on tag %s: %v", releasedTag.Name, err)
+	}
+}
+
+// forgetExpiredIPs drops released IPs that are gone from the account, i.e. whose subscription
+// ended, from the purchase and release tags, so they no longer count against MaxPurchasedIPs
+func (c *LoadBalancerController) forgetExpiredIPs(ctx context.Context, client *cloud.Client, ips []cloud.IPDetail, releasedTag cloudsigma.Tag) {
+	owned := make(map[string]bool, len(ips))
+	for _, ip := range ips {
+		owned[ip.UUID] = true
+	}
+	expired := make(map[string]bool)
+	for _, r := range releasedTag.Resources {
+		if !owned[r.UUID] {
+			expired[r.UUID] = true
+		}
+	}
+	if len(expired) == 0 {
+		return
+	}
+	purchasedTag, _, err := c.purchasedIPs(ctx)
+	if err != nil {
+		klog.Warningf("Failed to list purchased IPs: %v", err)
+		return
+	}
+
+	for _, tag := range []cloudsigma.Tag{purchasedTag, releasedTag} {
+		if tag.UUID == "" {
+			continue
+		}
+		kept := make([]cloudsigma.TagResource, 0, len(tag.Resources))
+		for _, r := range tag.Resources {
+			if !expired[r.UUID] {
+				kept = append(kept, r)
+			}
+		}
+		if len(kept) == len(tag.Resources) {
+			continue
+		}
+		tag.Resources = kept
+		if err := client.UpdateTag(ctx, tag); err != nil {
+			klog.Warningf("Failed to remove expired IPs from tag %s: %v", tag.Name, err)
+		}
+	}
+	c.invalidateTagCache()
+	klog.InfoS("Released IPs left the account", "ips", len(expired))
+}
+
+// removeIP returns ips without ip
+func removeIP(ips []string, ip string) []string {
+	kept := ips[:0:0]
+	for _, candidate := range ips {
+		if candidate != ip {
+			kept = append(kept, candidate)
+		}
+	}
+	return kept
+}
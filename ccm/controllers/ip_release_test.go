@@ -0,0 +1,179 @@
+/*
+Copyright 2025 Kube-DC Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	testingclock "k8s.io/utils/clock/testing"
+
+	"github.com/kube-dc/cluster-api-provider-cloudsigma/pkg/cloud/fake"
+)
+
+// newReleaseTestController returns a controller that purchased n IPs from api
+func newReleaseTestController(t *testing.T, api *fake.Server, clk *testingclock.FakeClock, n int) (*LoadBalancerController, []string) {
+	t.Helper()
+	impersonation, err := api.NewImpersonationClient()
+	if err != nil {
+		t.Fatalf("NewImpersonationClient() error = %v", err)
+	}
+	c := &LoadBalancerController{
+		ImpersonationClient: impersonation,
+		UserEmail:           fake.Username,
+		Region:              fake.Region,
+		ClusterName:         "alpha",
+		Clock:               clk,
+		PurchaseIPs:         true,
+		MaxPurchasedIPs:     n,
+		IPReleaseCooldown:   time.Hour,
+		IPReservationTTL:    2 * time.Hour,
+		apiEndpoint:         api.APIEndpoint(),
+		ipAssignments:       map[string]string{},
+		serviceIPs:          map[string]string{},
+	}
+	svc := reservedTestService("web", "")
+	var ips []string
+	for i := 0; i < n; i++ {
+		ip, err := c.purchaseIP(context.Background(), &svc, ipPoolUsage{Pool: IPPoolStatic, Family: corev1.IPv4Protocol})
+		if err != nil || ip == "" {
+			t.Fatalf("purchaseIP() = %q, %v; want an IP", ip, err)
+		}
+		ips = append(ips, ip)
+	}
+	return c, ips
+}
+
+// autoRenewed returns whether the subscription of ip is still renewed
+func autoRenewed(t *testing.T, api *fake.Server, ip string) bool {
+	t.Helper()
+	for _, sub := range api.Subscriptions() {
+		if sub.SubscribedObject == ip {
+			return sub.AutoRenew
+		}
+	}
+	t.Fatalf("no subscription for IP %s", ip)
+	return false
+}
+
+func TestReleaseUnusedIPs_Cooldown(t *testing.T) {
+	api := fake.NewServer()
+	defer api.Close()
+	clk := testingclock.NewFakeClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	c, ips := newReleaseTestController(t, api, clk, 2)
+	used, unused := ips[0], ips[1]
+	ctx := context.Background()
+
+	c.ipAssignments[used] = lbTestNodeUUID(0)
+
+	// The cooldown starts when an IP is first seen unused
+	c.releaseUnusedIPs(ctx)
+	clk.Step(59 * time.Minute)
+	c.releaseUnusedIPs(ctx)
+	if !autoRenewed(t, api, unused) {
+		t.Fatalf("IP %s released before the cooldown ended", unused)
+	}
+
+	// An IP used again during its cooldown starts over once it is freed
+	c.ipAssignments[unused] = lbTestNodeUUID(0)
+	c.releaseUnusedIPs(ctx)
+	delete(c.ipAssignments, unused)
+	c.releaseUnusedIPs(ctx)
+	clk.Step(59 * time.Minute)
+	c.releaseUnusedIPs(ctx)
+	if !autoRenewed(t, api, unused) {
+		t.Fatalf("IP %s released although it was used %v ago", unused, 59*time.Minute)
+	}
+
+	clk.Step(time.Minute)
+	c.releaseUnusedIPs(ctx)
+	if autoRenewed(t, api, unused) {
+		t.Errorf("IP %s still renewed after being unused for the cooldown", unused)
+	}
+	if !autoRenewed(t, api, used) {
+		t.Errorf("assigned IP %s was released", used)
+	}
+	if slices.Contains(c.staticIPs, unused) {
+		t.Errorf("static pool %v still has the released IP %s", c.staticIPs, unused)
+	}
+	if tag, ok := api.GetTag("released:alpha"); !ok || len(tag.Resources) != 1 || tag.Resources[0].UUID != unused {
+		t.Errorf("tag released:alpha = %+v (found %v), want only %s", tag, ok, unused)
+	}
+
+	// The released IP keeps its subscription for now, but is not rediscovered into the pool,
+	// and it still counts against the purchase cap
+	if err := c.discoverOwnedIPs(ctx); err != nil {
+		t.Fatalf("discoverOwnedIPs() error = %v", err)
+	}
+	if !slices.Equal(c.staticIPs, []string{used}) {
+		t.Errorf("static pool after discovery = %v, want only %s", c.staticIPs, used)
+	}
+	if _, purchased, _ := c.purchasedIPs(ctx); purchased != 2 {
+		t.Errorf("purchased IPs = %d, want 2 while the released one is still billed", purchased)
+	}
+}
+
+func TestReleaseUnusedIPs_SkipsReserved(t *testing.T) {
+	api := fake.NewServer()
+	defer api.Close()
+	clk := testingclock.NewFakeClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	c, ips := newReleaseTestController(t, api, clk, 1)
+	ip := ips[0]
+	ctx := context.Background()
+
+	// The IP's service is gone, but its reservation keeps the IP for a recreated service
+	if err := c.writeIPReservation(ctx, ipReservation{Name: "shop", Cluster: "alpha", Service: "default/web",
+		IPs: []string{ip}, Renewed: clk.Now()}); err != nil {
+		t.Fatalf("writeIPReservation() error = %v", err)
+	}
+	for i := 0; i < 4; i++ {
+		c.releaseUnusedIPs(ctx)
+		clk.Step(30 * time.Minute)
+	}
+	if !autoRenewed(t, api, ip) {
+		t.Fatalf("reserved IP %s was released", ip)
+	}
+
+	// Once the reservation expired the IP is unused and released after a full cooldown
+	c.releaseUnusedIPs(ctx)
+	clk.Step(time.Hour)
+	c.releaseUnusedIPs(ctx)
+	if autoRenewed(t, api, ip) {
+		t.Errorf("IP %s still renewed a cooldown after its reservation expired", ip)
+	}
+}
+
+func TestReleaseUnusedIPs_Disabled(t *testing.T) {
+	api := fake.NewServer()
+	defer api.Close()
+	clk := testingclock.NewFakeClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	c, ips := newReleaseTestController(t, api, clk, 1)
+	c.IPReleaseCooldown = 0
+
+	c.releaseUnusedIPs(context.Background())
+	clk.Step(24 * time.Hour)
+	c.releaseUnusedIPs(context.Background())
+	if !autoRenewed(t, api, ips[0]) {
+		t.Errorf("IP %s released with releasing disabled", ips[0])
+	}
+	if _, ok := api.GetTag("released:alpha"); ok {
+		t.Errorf("tag released:alpha created with releasing disabled")
+	}
+}
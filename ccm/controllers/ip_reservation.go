@@ -0,0 +1,242 @@
+/*
+Copyright 2025 Kube-DC Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cloudsigma/cloudsigma-sdk-go/cloudsigma"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// ipReservationTagPrefix prefixes the CloudSigma tags that reserve pool IPs to a name given
+	// with AnnotationLBIPReservation: reservation:<name>
+	ipReservationTagPrefix = "reservation:"
+
+	// DefaultIPReservationTTL is how long a reservation outlives its service. Reservations are
+	// renewed while a service holds the IPs, so only reservations of deleted services expire.
+	DefaultIPReservationTTL = 24 * time.Hour
+	// MinIPReservationTTL is the lowest accepted reservation TTL
+	MinIPReservationTTL = 5 * time.Minute
+)
+
+// ipReservation is a CloudSigma tag reserving the IPs of a service to a name, so a service
+// recreated with the same name gets them again
+type ipReservation struct {
+	UUID    string
+	Name    string
+	Cluster string
+	Service string
+	IPs     []string
+	Renewed time.Time
+}
+
+func (r ipReservation) tagName() string {
+	return ipReservationTagPrefix + r.Name
+}
+
+func (r ipReservation) live(now time.Time, ttl time.Duration) bool {
+	return now.Sub(r.Renewed) < ttl
+}
+
+// ipOf returns the reserved IP of family, or "" if there is none
+func (r ipReservation) ipOf(family corev1.IPFamily) string {
+	for _, ip := range r.IPs {
+		if ipFamilyOf(ip) == family {
+			return ip
+		}
+	}
+	return ""
+}
+
+// reservationName returns the reservation a service asks for with AnnotationLBIPReservation
+func reservationName(svc *corev1.Service) string {
+	return strings.TrimSpace(svc.Annotations[AnnotationLBIPReservation])
+}
+
+func (c *LoadBalancerController) ipReservationTTL() time.Duration {
+	return intervalOrDefault(c.IPReservationTTL, DefaultIPReservationTTL)
+}
+
+// listIPReservations returns all reservations of the account, live or expired
+func (c *LoadBalancerController) listIPReservations(ctx context.Context) ([]ipReservation, error) {
+	tags, err := c.listTags(ctx, tagPrefixFilter(ipReservationTagPrefix))
+	if err != nil {
+		return nil, err
+	}
+
+	var reservations []ipReservation
+	for _, tag := range tags {
+		name := strings.TrimPrefix(tag.Name, ipReservationTagPrefix)
+		if name == tag.Name || name == "" {
+			continue
+		}
+		cluster, _ := tag.Meta["cluster"].(string)
+		service, _ := tag.Meta["service"].(string)
+		renewedStr, _ := tag.Meta["renewed"].(string)
+		renewed, _ := time.Parse(time.RFC3339, renewedStr)
+		r := ipReservation{UUID: tag.UUID, Name: name, Cluster: cluster, Service: service, Renewed: renewed}
+		for _, res := range tag.Resources {
+			r.IPs = append(r.IPs, res.UUID)
+		}
+		sort.Strings(r.IPs)
+		reservations = append(reservations, r)
+	}
+	return reservations, nil
+}
+
+// reservedIPs returns the IP of family reserved to the service's reservation, if it has one, and
+// the IPs held by all other live reservations, which are not handed to other services
+func (c *LoadBalancerController) reservedIPs(ctx context.Context, svc *corev1.Service, family corev1.IPFamily) (string, map[string]bool, error) {
+	reservations, err := c.listIPReservations(ctx)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to list IP reservations: %w", err)
+	}
+
+	name := reservationName(svc)
+	now := clockOrDefault(c.Clock).Now()
+	var own string
+	others := make(map[string]bool)
+	for _, r := range reservations {
+		if !r.live(now, c.ipReservationTTL()) {
+			continue
+		}
+		if name != "" && r.Name == name && r.Cluster == c.ClusterName {
+			own = r.ipOf(family)
+			continue
+		}
+		for _, ip := range r.IPs {
+			others[ip] = true
+		}
+	}
+	return own, others, nil
+}
+
+// syncIPReservations records the IPs of services with AnnotationLBIPReservation in their
+// reservation, renewing it while they hold them, and deletes reservations that expired. Only
+// one service may hold a reservation; a live reservation of another cluster is left alone.
+func (c *LoadBalancerController) syncIPReservations(ctx context.Context, services []corev1.Service) {
+	reservations, err := c.listIPReservations(ctx)
+	if err != nil {
+		klog.Warningf("Failed to list IP reservations: %v", err)
+		return
+	}
+	byName := make(map[string]ipReservation, len(reservations))
+	for _, r := range reservations {
+		byName[r.Name] = r
+	}
+
+	c.mutex.RLock()
+	held := make(map[string][]string)
+	for ipKey, ip := range c.serviceIPs {
+		svcKey := serviceKeyFromIPKey(ipKey)
+		held[svcKey] = append(held[svcKey], ip)
+	}
+	c.mutex.RUnlock()
+
+	now := clockOrDefault(c.Clock).Now()
+	ttl := c.ipReservationTTL()
+	claimed := make(map[string]string)
+	for i := range services {
+		svc := &services[i]
+		name := reservationName(svc)
+		svcKey := fmt.Sprintf("%s/%s", svc.Namespace, svc.Name)
+		ips := held[svcKey]
+		if name == "" || len(ips) == 0 || svc.Spec.Type != corev1.ServiceTypeLoadBalancer || svc.DeletionTimestamp != nil {
+			continue
+		}
+		if owner, ok := claimed[name]; ok {
+			klog.Warningf("IP reservation %s is already held by service %s, not recording it for %s", name, owner, svcKey)
+			continue
+		}
+		claimed[name] = svcKey
+		sort.Strings(ips)
+
+		existing, ok := byName[name]
+		if ok && existing.Cluster != c.ClusterName && existing.live(now, ttl) {
+			klog.Warningf("IP reservation %s is held by cluster %s, not recording it for %s", name, existing.Cluster, svcKey)
+			continue
+		}
+		if ok && existing.Cluster == c.ClusterName && existing.Service == svcKey &&
+			strings.Join(existing.IPs, ",") == strings.Join(ips, ",") && now.Sub(existing.Renewed) < ttl/3 {
+			continue
+		}
+
+		r := ipReservation{UUID: existing.UUID, Name: name, Cluster: c.ClusterName, Service: svcKey, IPs: ips, Renewed: now}
+		if err := c.writeIPReservation(ctx, r); err != nil {
+			klog.Warningf("Failed to record IP reservation %s for service %s: %v", name, svcKey, err)
+			continue
+		}
+		if !ok || strings.Join(existing.IPs, ",") != strings.Join(ips, ",") {
+			klog.InfoS("Reserved service IPs", "svcKey", svcKey, "reservation", name, "ips", ips)
+		}
+	}
+
+	for _, r := range reservations {
+		if _, ok := claimed[r.Name]; ok || r.live(now, ttl) {
+			continue
+		}
+		if err := c.deleteIPReservation(ctx, r); err != nil {
+			klog.Warningf("Failed to delete expired IP reservation %s: %v", r.Name, err)
+			continue
+		}
+		klog.InfoS("Deleted expired IP reservation", "reservation", r.Name, "ips", r.IPs, "svcKey", r.Service)
+	}
+}
+
+// writeIPReservation creates the reservation's tag, or replaces it if r.UUID is set
+func (c *LoadBalancerController) writeIPReservation(ctx context.Context, r ipReservation) error {
+	client, err := c.cloudClient()
+	if err != nil {
+		return err
+	}
+	tag := cloudsigma.Tag{
+		UUID: r.UUID,
+		Name: r.tagName(),
+		Meta: map[string]interface{}{
+			"cluster": r.Cluster,
+			"service": r.Service,
+			"renewed": r.Renewed.UTC().Format(time.RFC3339),
+		},
+	}
+	for _, ip := range r.IPs {
+		tag.Resources = append(tag.Resources, cloudsigma.TagResource{UUID: ip})
+	}
+	if r.UUID == "" {
+		err = client.CreateTag(ctx, tag)
+	} else {
+		err = client.UpdateTag(ctx, tag)
+	}
+	c.invalidateTagCache()
+	return err
+}
+
+func (c *LoadBalancerController) deleteIPReservation(ctx context.Context, r ipReservation) error {
+	client, err := c.cloudClient()
+	if err != nil {
+		return err
+	}
+	err = client.DeleteTag(ctx, r.UUID)
+	c.invalidateTagCache()
+	return err
+}
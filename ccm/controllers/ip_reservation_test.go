@@ -0,0 +1,168 @@
+/*
+Copyright 2025 Kube-DC Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	testingclock "k8s.io/utils/clock/testing"
+
+	"github.com/kube-dc/cluster-api-provider-cloudsigma/pkg/cloud/fake"
+)
+
+func reservedTestService(name, reservation string) corev1.Service {
+	svc := corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		Spec:       corev1.ServiceSpec{Type: corev1.ServiceTypeLoadBalancer},
+	}
+	if reservation != "" {
+		svc.Annotations = map[string]string{AnnotationLBIPReservation: reservation}
+	}
+	return svc
+}
+
+func TestIPReservation_ReserveReuseExpire(t *testing.T) {
+	const reservedIP, otherIP = "203.0.113.10", "203.0.113.11"
+
+	api := fake.NewServer()
+	defer api.Close()
+	impersonation, err := api.NewImpersonationClient()
+	if err != nil {
+		t.Fatalf("NewImpersonationClient() error = %v", err)
+	}
+	clk := testingclock.NewFakeClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	c := &LoadBalancerController{
+		ImpersonationClient: impersonation,
+		UserEmail:           fake.Username,
+		Region:              fake.Region,
+		ClusterName:         "alpha",
+		Clock:               clk,
+		IPReservationTTL:    time.Hour,
+		apiEndpoint:         api.APIEndpoint(),
+		staticIPs:           []string{reservedIP, otherIP},
+		ipAssignments:       map[string]string{},
+		serviceIPs:          map[string]string{},
+	}
+	ctx := context.Background()
+	allocate := func(svc corev1.Service) string {
+		t.Helper()
+		ip, _, err := c.allocateIP(ctx, &svc, corev1.IPv4Protocol)
+		if err != nil {
+			t.Fatalf("allocateIP(%s) error = %v", svc.Name, err)
+		}
+		return ip
+	}
+	assign := func(svc corev1.Service, ip string) {
+		c.serviceIPs[svc.Namespace+"/"+svc.Name] = ip
+		c.ipAssignments[ip] = lbTestNodeUUID(0)
+	}
+	release := func(svc corev1.Service) {
+		ip := c.serviceIPs[svc.Namespace+"/"+svc.Name]
+		delete(c.serviceIPs, svc.Namespace+"/"+svc.Name)
+		delete(c.ipAssignments, ip)
+	}
+
+	// Reserve: the IP of an annotated service is recorded in its reservation
+	web := reservedTestService("web", "shop")
+	if ip := allocate(web); ip != reservedIP {
+		t.Fatalf("allocateIP(web) = %q, want %q", ip, reservedIP)
+	}
+	assign(web, reservedIP)
+	c.syncIPReservations(ctx, []corev1.Service{web})
+	tag, ok := api.GetTag("reservation:shop")
+	if !ok || len(tag.Resources) != 1 || tag.Resources[0].UUID != reservedIP {
+		t.Fatalf("tag reservation:shop = %+v (found %v), want it on %s", tag, ok, reservedIP)
+	}
+	if tag.Meta["cluster"] != "alpha" || tag.Meta["service"] != "default/web" {
+		t.Errorf("reservation meta = %v, want cluster alpha and service default/web", tag.Meta)
+	}
+
+	// Reuse: once the service is gone its IP is kept for the reservation, not handed out
+	release(web)
+	c.syncIPReservations(ctx, nil)
+	clk.Step(30 * time.Minute)
+	if ip := allocate(reservedTestService("api", "")); ip != otherIP {
+		t.Errorf("allocateIP(api) = %q, want %q while %s is reserved", ip, otherIP, reservedIP)
+	}
+	recreated := reservedTestService("web-v2", "shop")
+	if ip := allocate(recreated); ip != reservedIP {
+		t.Fatalf("allocateIP(web-v2) = %q, want the reserved %q", ip, reservedIP)
+	}
+	assign(recreated, reservedIP)
+	c.syncIPReservations(ctx, []corev1.Service{recreated})
+	if tag, _ := api.GetTag("reservation:shop"); tag.Meta["service"] != "default/web-v2" || tag.Meta["renewed"] != clk.Now().Format(time.RFC3339) {
+		t.Errorf("reservation meta after reuse = %v, want it renewed for default/web-v2", tag.Meta)
+	}
+
+	// Expire: a reservation nobody renews for the TTL is deleted and its IP is free again
+	release(recreated)
+	clk.Step(time.Hour)
+	c.syncIPReservations(ctx, nil)
+	if _, ok := api.GetTag("reservation:shop"); ok {
+		t.Errorf("tag reservation:shop still exists after the TTL")
+	}
+	if ip := allocate(reservedTestService("api", "")); ip != reservedIP {
+		t.Errorf("allocateIP(api) after expiry = %q, want %q", ip, reservedIP)
+	}
+}
+
+func TestIPReservation_OtherCluster(t *testing.T) {
+	const reservedIP, otherIP = "203.0.113.10", "203.0.113.11"
+
+	api := fake.NewServer()
+	defer api.Close()
+	impersonation, err := api.NewImpersonationClient()
+	if err != nil {
+		t.Fatalf("NewImpersonationClient() error = %v", err)
+	}
+	clk := testingclock.NewFakeClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	newController := func(cluster string) *LoadBalancerController {
+		return &LoadBalancerController{
+			ImpersonationClient: impersonation,
+			UserEmail:           fake.Username,
+			Region:              fake.Region,
+			ClusterName:         cluster,
+			Clock:               clk,
+			apiEndpoint:         api.APIEndpoint(),
+			staticIPs:           []string{reservedIP, otherIP},
+			ipAssignments:       map[string]string{},
+			serviceIPs:          map[string]string{},
+		}
+	}
+	ctx := context.Background()
+
+	alpha := newController("alpha")
+	web := reservedTestService("web", "shop")
+	alpha.serviceIPs["default/web"] = reservedIP
+	alpha.syncIPReservations(ctx, []corev1.Service{web})
+
+	// The same reservation name in another cluster of the account does not get the IP
+	bravo := newController("bravo")
+	ip, _, err := bravo.allocateIP(ctx, &web, corev1.IPv4Protocol)
+	if err != nil || ip != otherIP {
+		t.Fatalf("allocateIP() in bravo = %q, %v; want %q", ip, err, otherIP)
+	}
+	bravo.serviceIPs["default/web"] = otherIP
+	bravo.syncIPReservations(ctx, []corev1.Service{web})
+	if tag, _ := api.GetTag("reservation:shop"); tag.Meta["cluster"] != "alpha" || len(tag.Resources) != 1 || tag.Resources[0].UUID != reservedIP {
+		t.Errorf("reservation:shop = %+v, want alpha's reservation of %s kept", tag, reservedIP)
+	}
+}
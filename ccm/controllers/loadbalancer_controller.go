@@ -20,18 +20,24 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
-	"net/http"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/cloudsigma/cloudsigma-sdk-go/cloudsigma"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/klog/v2"
+	"k8s.io/utils/clock"
 
 	"github.com/kube-dc/cluster-api-provider-cloudsigma/pkg/auth"
+	"github.com/kube-dc/cluster-api-provider-cloudsigma/pkg/cloud"
 )
 
 const (
@@ -39,10 +45,34 @@ const (
 	// Values: "static" (default), "dynamic"
 	AnnotationIPPoolType = "cloudsigma.com/ip-pool"
 
+	// AnnotationIPPoolExhausted is set on a LoadBalancer service that is waiting for an IP because
+	// its pool has none free; the value names the pool and its usage. It is removed once an IP is assigned.
+	AnnotationIPPoolExhausted = "cloudsigma.com/ip-pool-exhausted"
+
+	// AnnotationLBNode records the server UUID of the node a service's LoadBalancer IP is
+	// configured on, so a restarted controller keeps the existing placement
+	AnnotationLBNode = "cloudsigma.com/lb-node"
+
+	// AnnotationLBIPReservation reserves the IPs a service gets to the name given as its value, so
+	// a service recreated with the same reservation gets the same IPs again; see syncIPReservations
+	AnnotationLBIPReservation = "cloudsigma.com/lb-ip-reservation"
+
 	// IPPoolStatic uses static IPs (owned IPs with subscription)
 	IPPoolStatic = "static"
 	// IPPoolDynamic uses dynamic IPs (unassigned IPs without server attachment)
 	IPPoolDynamic = "dynamic"
+
+	// ConditionEndpointsReady is the service status condition reporting whether the LoadBalancer
+	// IP's DNAT rules point at a pod. It is False while the service has no endpoints.
+	ConditionEndpointsReady = "cloudsigma.com/EndpointsReady"
+
+	// Event and condition reasons recorded on LoadBalancer services
+	EventReasonIPPoolExhausted     = "IPPoolExhausted"
+	EventReasonIPAllocated         = "IPAllocated"
+	EventReasonWaitingForEndpoints = "WaitingForEndpoints"
+	EventReasonEndpointsReady      = "EndpointsReady"
+	EventReasonIPReconfigured      = "IPReconfigured"
+	EventReasonIPPurchased         = "IPPurchased"
 )
 
 // LoadBalancerController manages LoadBalancer service IPs using CloudSigma's
@@ -53,6 +83,10 @@ type LoadBalancerController struct {
 	// TenantClient is the Kubernetes client for the tenant cluster
 	TenantClient kubernetes.Interface
 
+	// TenantClients, if set, is asked for the tenant client on every use instead of TenantClient,
+	// so a client rebuilt by the NodeReconciler is picked up (optional)
+	TenantClients TenantClientProvider
+
 	// ImpersonationClient for CloudSigma API access
 	ImpersonationClient *auth.ImpersonationClient
 
@@ -68,6 +102,48 @@ type LoadBalancerController struct {
 	// Disabled allows disabling the controller (enabled by default)
 	Disabled bool
 
+	// SyncInterval is how often LoadBalancer services are synced (default: DefaultLBSyncInterval)
+	SyncInterval time.Duration
+
+	// IPRefreshInterval is how often owned IPs are rediscovered (default: DefaultIPRefreshInterval)
+	IPRefreshInterval time.Duration
+
+	// EndpointRetryInterval is how often services waiting for endpoints are retried
+	// (default: DefaultEndpointRetryInterval)
+	EndpointRetryInterval time.Duration
+
+	// IPReservationTTL is how long the IP reservation of a deleted service is kept
+	// (default: DefaultIPReservationTTL)
+	IPReservationTTL time.Duration
+
+	// PurchaseIPs lets the controller buy an IP subscription when the static pool has no IP for
+	// a service, up to MaxPurchasedIPs; see purchaseIP. It is off by default as it spends money.
+	PurchaseIPs bool
+
+	// MaxPurchasedIPs caps the IPs purchased for the cluster, counted on the purchase tag
+	MaxPurchasedIPs int
+
+	// IPSubscriptionPeriod is the billing period of purchased IPs (default: DefaultIPSubscriptionPeriod)
+	IPSubscriptionPeriod string
+
+	// IPReleaseCooldown is how long a purchased IP stays unused before releaseUnusedIPs releases
+	// it; 0 never releases purchased IPs
+	IPReleaseCooldown time.Duration
+
+	// Clock drives the sync loops (default: the real clock)
+	Clock clock.WithTicker
+
+	// Recorder emits events on LoadBalancer services (optional)
+	Recorder record.EventRecorder
+
+	// Paused reports whether the managing Cluster is paused; services are not synced while it
+	// is (optional)
+	Paused PausedFunc
+
+	// TearingDown reports whether the managing Cluster is being deleted; node NICs are only
+	// reverted from manual mode on shutdown when it is (optional)
+	TearingDown TeardownFunc
+
 	// mutex for thread safety
 	mutex sync.RWMutex
 
@@ -82,9 +158,37 @@ type LoadBalancerController struct {
 	ipAssignments map[string]string
 
 	// serviceIPs tracks which service has which IP
-	// key: namespace/name, value: IP address
+	// key: namespace/name (IPv4) or namespace/name#IPv6, see serviceIPKey; value: IP address
 	serviceIPs map[string]string
 
+	// waitingForEndpoints tracks service IPs whose DNAT rules are not configured yet because the
+	// service has no endpoints; key: as for serviceIPs
+	waitingForEndpoints map[string]bool
+
+	// lockStore holds the locks on dynamic IPs (default: CloudSigma tags)
+	lockStore ipLockStore
+
+	// apiEndpoint is the CloudSigma API base URL (default: https://<region>.cloudsigma.com/api/2.0)
+	apiEndpoint string
+
+	// tokenSource returns CloudSigma API tokens (default: ImpersonationClient)
+	tokenSource func(ctx context.Context) (string, error)
+
+	// tagCache holds recent tag listings, keyed by their query; see listTags.
+	// Guarded by tagCacheMutex rather than mutex, which is held across some tag updates.
+	tagCache      map[string]tagListing
+	tagCacheMutex sync.Mutex
+
+	// lastIPPurchaseFailure is when an IP purchase last failed; see ipPurchaseRetryInterval
+	lastIPPurchaseFailure time.Time
+
+	// untaggedPurchases are purchased IPs not yet added to the purchase tag. They are added
+	// before any further purchase, which is refused while that fails; see recordPurchases.
+	untaggedPurchases []string
+
+	// unusedSince tracks since when each purchased IP is unused; see releaseUnusedIPs
+	unusedSince map[string]time.Time
+
 	// manualModeNodes tracks which nodes have already been switched to manual NIC mode
 	// key: server UUID
 	manualModeNodes map[string]bool
@@ -93,21 +197,11 @@ type LoadBalancerController struct {
 	done chan struct{}
 }
 
-// CloudSigmaIP represents an IP from the CloudSigma API
-type CloudSigmaIP struct {
-	UUID         string            `json:"uuid"`
-	Server       *CloudSigmaServer `json:"server"`
-	Subscription *struct {
-		ID int `json:"id"`
-	} `json:"subscription"`
+// tenantClient returns the current tenant cluster client
+func (c *LoadBalancerController) tenantClient() kubernetes.Interface {
+	return tenantClientFrom(c.TenantClients, c.TenantClient)
 }
 
-// CloudSigmaServer represents a server reference
-type CloudSigmaServer struct {
-	UUID string `json:"uuid"`
-}
-
-
 // WaitForShutdown blocks until the controller's shutdown cleanup is complete.
 // Must be called after Start() and after the context is cancelled.
 func (c *LoadBalancerController) WaitForShutdown() {
@@ -120,11 +214,16 @@ func (c *LoadBalancerController) WaitForShutdown() {
 func (c *LoadBalancerController) Start(ctx context.Context) error {
 	if c.Disabled {
 		klog.Info("LoadBalancer IP pool controller is disabled")
+		// Nothing will release the IPs of services managed earlier; don't let their deletion hang
+		if err := RemoveLBFinalizers(ctx, c.tenantClient()); err != nil {
+			klog.Errorf("Failed to remove LB IP cleanup finalizers: %v", err)
+		}
 		return nil
 	}
 
 	c.ipAssignments = make(map[string]string)
 	c.serviceIPs = make(map[string]string)
+	c.waitingForEndpoints = make(map[string]bool)
 	c.manualModeNodes = make(map[string]bool)
 	c.done = make(chan struct{})
 
@@ -139,6 +238,13 @@ func (c *LoadBalancerController) Start(ctx context.Context) error {
 		klog.Errorf("Failed to recover service state: %v", err)
 	}
 
+	// Remove config pods left behind for services deleted while the controller was down
+	if !isPaused(ctx, c.Paused, "orphaned LB IP config pod cleanup") {
+		if err := c.cleanupOrphanedIPPods(ctx); err != nil {
+			klog.Errorf("Failed to clean up orphaned LB IP config pods: %v", err)
+		}
+	}
+
 	klog.Infof("Starting LoadBalancer controller with static IPs: %v, dynamic IPs: %v", c.staticIPs, c.dynamicIPs)
 
 	// Initial sync
@@ -153,30 +259,24 @@ func (c *LoadBalancerController) Start(ctx context.Context) error {
 }
 
 // discoverOwnedIPs queries CloudSigma API to find owned IPs (with subscription) and recover assignment state
+// The listing is left unfiltered: both pools come from it, static IPs with a subscription and
+// dynamic IPs without one. It runs once per IPRefreshInterval.
 func (c *LoadBalancerController) discoverOwnedIPs(ctx context.Context) error {
-	token, err := c.ImpersonationClient.GetImpersonatedToken(ctx, c.UserEmail, c.Region)
+	client, err := c.cloudClient()
 	if err != nil {
-		return fmt.Errorf("failed to get token: %w", err)
+		return err
 	}
-
-	url := fmt.Sprintf("https://%s.cloudsigma.com/api/2.0/ips/detail/", c.Region)
-	req, _ := http.NewRequestWithContext(ctx, "GET", url, nil)
-	req.Header.Set("Authorization", "Bearer "+token)
-
-	resp, err := http.DefaultClient.Do(req)
+	ips, err := client.ListIPsDetail(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to list IPs: %w", err)
-	}
-	defer resp.Body.Close()
-
-	body, _ := io.ReadAll(resp.Body)
-
-	var result struct {
-		Objects []CloudSigmaIP `json:"objects"`
+		return err
 	}
-	if err := json.Unmarshal(body, &result); err != nil {
-		return fmt.Errorf("failed to parse IPs: %w", err)
+	// Purchased IPs released by releaseUnusedIPs keep their subscription until it ends, but
+	// must not be handed out again
+	releasedTag, released, err := c.releasedIPs(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list released IPs: %w", err)
 	}
+	c.forgetExpiredIPs(ctx, client, ips, releasedTag)
 
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
@@ -184,7 +284,11 @@ func (c *LoadBalancerController) discoverOwnedIPs(ctx context.Context) error {
 	c.staticIPs = nil
 	c.dynamicIPs = nil
 
-	for _, ip := range result.Objects {
+	for _, ip := range ips {
+		if released[ip.UUID] {
+			klog.V(2).Infof("Skipping released IP: %s", ip.UUID)
+			continue
+		}
 		// Static IPs: owned IPs with subscription
 		if ip.Subscription != nil {
 			c.staticIPs = append(c.staticIPs, ip.UUID)
@@ -202,7 +306,7 @@ func (c *LoadBalancerController) discoverOwnedIPs(ctx context.Context) error {
 
 // recoverServiceState recovers serviceIPs mapping from existing LoadBalancer services
 func (c *LoadBalancerController) recoverServiceState(ctx context.Context) error {
-	services, err := c.TenantClient.CoreV1().Services("").List(ctx, metav1.ListOptions{})
+	services, err := c.tenantClient().CoreV1().Services("").List(ctx, metav1.ListOptions{})
 	if err != nil {
 		return fmt.Errorf("failed to list services: %w", err)
 	}
@@ -218,8 +322,8 @@ func (c *LoadBalancerController) recoverServiceState(ctx context.Context) error
 		svcKey := fmt.Sprintf("%s/%s", svc.Namespace, svc.Name)
 		for _, ingress := range svc.Status.LoadBalancer.Ingress {
 			if c.isPoolIPLocked(ingress.IP) {
-				c.serviceIPs[svcKey] = ingress.IP
-				klog.Infof("Recovered service IP mapping: %s -> %s", svcKey, ingress.IP)
+				c.serviceIPs[serviceIPKey(svcKey, ipFamilyOf(ingress.IP))] = ingress.IP
+				klog.InfoS("Recovered service IP mapping", "svcKey", svcKey, "ip", ingress.IP)
 			}
 		}
 	}
@@ -228,6 +332,42 @@ func (c *LoadBalancerController) recoverServiceState(ctx context.Context) error
 	return nil
 }
 
+// cleanupOrphanedIPPods deletes lb-ip config pods whose IP is not the external IP of any live
+// LoadBalancer service. Such pods are left when a service is deleted while the controller is down,
+// and would otherwise keep the IP and its DNAT rules configured on the node.
+func (c *LoadBalancerController) cleanupOrphanedIPPods(ctx context.Context) error {
+	services, err := c.tenantClient().CoreV1().Services("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to list services: %w", err)
+	}
+	live := make(map[string]bool)
+	for _, svc := range services.Items {
+		if svc.Spec.Type != corev1.ServiceTypeLoadBalancer {
+			continue
+		}
+		for _, ingress := range svc.Status.LoadBalancer.Ingress {
+			if ingress.IP != "" {
+				live[lbIPPodName(ingress.IP)] = true
+			}
+		}
+	}
+
+	pods, err := c.tenantClient().CoreV1().Pods("kube-system").List(ctx, metav1.ListOptions{LabelSelector: "app=cloudsigma-lb-ip"})
+	if err != nil {
+		return fmt.Errorf("failed to list LB IP config pods: %w", err)
+	}
+	for _, pod := range pods.Items {
+		if live[pod.Name] {
+			continue
+		}
+		klog.InfoS("Deleting orphaned LB IP config pod", "pod", pod.Name, "ip", pod.Labels["cloudsigma.com/ip"], "node", pod.Spec.NodeName)
+		if err := c.tenantClient().CoreV1().Pods("kube-system").Delete(ctx, pod.Name, metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+			klog.Warningf("Failed to delete orphaned LB IP config pod %s: %v", pod.Name, err)
+		}
+	}
+	return nil
+}
+
 // isPoolIPLocked checks if an IP is in any pool (must hold mutex)
 func (c *LoadBalancerController) isPoolIPLocked(ip string) bool {
 	for _, poolIP := range c.staticIPs {
@@ -245,44 +385,105 @@ func (c *LoadBalancerController) isPoolIPLocked(ip string) bool {
 
 // syncLoop periodically syncs LoadBalancer services
 func (c *LoadBalancerController) syncLoop(ctx context.Context) {
-	ticker := time.NewTicker(30 * time.Second)
+	clk := clockOrDefault(c.Clock)
+	ticker := clk.NewTicker(intervalOrDefault(c.SyncInterval, DefaultLBSyncInterval))
 	defer ticker.Stop()
 
-	// Refresh IP discovery every 5 minutes
-	ipRefreshTicker := time.NewTicker(5 * time.Minute)
+	// Periodically refresh IP discovery
+	ipRefreshTicker := clk.NewTicker(intervalOrDefault(c.IPRefreshInterval, DefaultIPRefreshInterval))
 	defer ipRefreshTicker.Stop()
 
+	// Services waiting for endpoints are retried sooner than the full sync; the timer is only
+	// armed while there are any
+	var endpointRetry <-chan time.Time
+
 	for {
+		if endpointRetry == nil && c.hasWaitingForEndpoints() {
+			endpointRetry = clk.After(intervalOrDefault(c.EndpointRetryInterval, DefaultEndpointRetryInterval))
+		}
+
 		select {
 		case <-ctx.Done():
 			klog.Info("LoadBalancer sync loop stopping, cleaning up IP tags...")
 			c.cleanupAllIPTags()
+			c.revertAllManualModeNodes()
 			klog.Info("LoadBalancer sync loop stopped")
 			close(c.done)
 			return
-		case <-ipRefreshTicker.C:
+		case <-ipRefreshTicker.C():
 			// Periodically refresh discovered IPs
 			if err := c.discoverOwnedIPs(ctx); err != nil {
 				klog.Errorf("Failed to refresh owned IPs: %v", err)
 			}
-		case <-ticker.C:
+		case <-ticker.C():
 			if err := c.syncLoadBalancers(ctx); err != nil {
 				klog.Errorf("LoadBalancer sync failed: %v", err)
 			}
+		case <-endpointRetry:
+			endpointRetry = nil
+			if err := c.reconcileWaitingServices(ctx); err != nil {
+				klog.Errorf("LoadBalancer endpoint retry failed: %v", err)
+			}
 		}
 	}
 }
 
+// reconcileWaitingServices reconciles only the services whose DNAT rules are waiting for
+// endpoints, so they are configured soon after their pods become ready
+func (c *LoadBalancerController) reconcileWaitingServices(ctx context.Context) error {
+	c.mutex.RLock()
+	svcKeys := make(map[string]bool)
+	for ipKey := range c.waitingForEndpoints {
+		svcKeys[serviceKeyFromIPKey(ipKey)] = true
+	}
+	c.mutex.RUnlock()
+	if len(svcKeys) == 0 || isPaused(ctx, c.Paused, "LoadBalancer sync") {
+		return nil
+	}
+
+	nodes, err := c.tenantClient().CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to list nodes: %w", err)
+	}
+	healthyNodes := c.getHealthyNodes(nodes.Items)
+	if len(healthyNodes) == 0 {
+		return nil
+	}
+
+	for svcKey := range svcKeys {
+		parts := strings.SplitN(svcKey, "/", 2)
+		svc, err := c.tenantClient().CoreV1().Services(parts[0]).Get(ctx, parts[1], metav1.GetOptions{})
+		if err != nil || svc.Spec.Type != corev1.ServiceTypeLoadBalancer {
+			// Deleted or changed services are released by the next full sync
+			klog.V(2).Infof("Skipping endpoint retry for service %s: %v", svcKey, err)
+			continue
+		}
+		if err := c.reconcileService(ctx, svc, healthyNodes); err != nil {
+			klog.Errorf("Failed to reconcile service %s: %v", svcKey, err)
+		}
+	}
+	return nil
+}
+
 // syncLoadBalancers syncs all LoadBalancer services
 func (c *LoadBalancerController) syncLoadBalancers(ctx context.Context) error {
+	defer c.updateIPMetrics()
+
+	// No IPs are assigned, moved or released while the managing Cluster is paused. Locks on the
+	// dynamic IPs already in use are still renewed, so the services keep them when it resumes.
+	if isPaused(ctx, c.Paused, "LoadBalancer sync") {
+		c.renewIPLocks(ctx)
+		return nil
+	}
+
 	// Get all services
-	services, err := c.TenantClient.CoreV1().Services("").List(ctx, metav1.ListOptions{})
+	services, err := c.tenantClient().CoreV1().Services("").List(ctx, metav1.ListOptions{})
 	if err != nil {
 		return fmt.Errorf("failed to list services: %w", err)
 	}
 
 	// Get healthy nodes
-	nodes, err := c.TenantClient.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	nodes, err := c.tenantClient().CoreV1().Nodes().List(ctx, metav1.ListOptions{})
 	if err != nil {
 		return fmt.Errorf("failed to list nodes: %w", err)
 	}
@@ -293,159 +494,273 @@ func (c *LoadBalancerController) syncLoadBalancers(ctx context.Context) error {
 		return nil
 	}
 
-	// Build set of current LoadBalancer services
+	// Build set of current LoadBalancer services. Services being deleted, or no longer of type
+	// LoadBalancer, that still carry the finalizer have their IPs released below.
 	currentServices := make(map[string]bool)
-	for _, svc := range services.Items {
-		if svc.Spec.Type == corev1.ServiceTypeLoadBalancer {
-			svcKey := fmt.Sprintf("%s/%s", svc.Namespace, svc.Name)
+	var releasing []*corev1.Service
+	for i := range services.Items {
+		svc := &services.Items[i]
+		svcKey := fmt.Sprintf("%s/%s", svc.Namespace, svc.Name)
+		switch {
+		case svc.Spec.Type == corev1.ServiceTypeLoadBalancer && svc.DeletionTimestamp == nil:
 			currentServices[svcKey] = true
+		case hasLBFinalizer(svc):
+			releasing = append(releasing, svc)
+		}
+	}
+
+	c.mutex.Lock()
+	// An IP whose assignment was lost (e.g. the service changed type while the controller was
+	// down) is still released, as long as the service status shows it
+	for _, svc := range releasing {
+		svcKey := fmt.Sprintf("%s/%s", svc.Namespace, svc.Name)
+		for _, ingress := range svc.Status.LoadBalancer.Ingress {
+			ipKey := serviceIPKey(svcKey, ipFamilyOf(ingress.IP))
+			if _, ok := c.serviceIPs[ipKey]; !ok && c.isPoolIPLocked(ingress.IP) {
+				c.serviceIPs[ipKey] = ingress.IP
+			}
 		}
 	}
 
 	// Cleanup deleted services - release IPs and untag them
 	// Note: With manual NIC mode, no NIC detach is needed - the node's NIC stays in
 	// manual mode and simply allows all subscribed IPs. We just remove the local config.
-	c.mutex.Lock()
-	for svcKey, ip := range c.serviceIPs {
+	// An IP whose untagging or config pod removal fails stays assigned and is retried next sync.
+	for ipKey, ip := range c.serviceIPs {
+		svcKey := serviceKeyFromIPKey(ipKey)
 		if !currentServices[svcKey] {
-			klog.Infof("Service %s deleted, releasing IP %s", svcKey, ip)
+			klog.InfoS("Service deleted, releasing IP", "svcKey", svcKey, "ip", ip)
 			// Untag IP in CloudSigma
 			if err := c.untagIPInCloudSigma(ctx, ip); err != nil {
 				klog.Warningf("Failed to untag IP %s: %v", ip, err)
+				continue
 			}
 			// Delete config pod (removes local IP + iptables rules)
-			c.deleteIPConfigPod(ctx, ip)
+			if err := c.deleteIPConfigPod(ctx, ip); err != nil {
+				klog.Warningf("Failed to delete config pod for IP %s: %v", ip, err)
+				continue
+			}
+			// Let other clusters use a released dynamic IP
+			if c.isDynamicIPLocked(ip) {
+				if err := c.releaseIPLock(ctx, ip); err != nil {
+					klog.Warningf("Failed to release lock on dynamic IP %s: %v", ip, err)
+				}
+			}
 			// Remove from assignments
-			delete(c.serviceIPs, svcKey)
+			delete(c.serviceIPs, ipKey)
 			delete(c.ipAssignments, ip)
+			delete(c.waitingForEndpoints, ipKey)
 		}
 	}
+	released := make(map[string]bool, len(releasing))
+	for _, svc := range releasing {
+		released[fmt.Sprintf("%s/%s", svc.Namespace, svc.Name)] = true
+	}
+	for ipKey := range c.serviceIPs {
+		delete(released, serviceKeyFromIPKey(ipKey))
+	}
 	c.mutex.Unlock()
 
+	// Let the API server finish deleting services whose IPs are released
+	for _, svc := range releasing {
+		if !released[fmt.Sprintf("%s/%s", svc.Namespace, svc.Name)] {
+			continue
+		}
+		if err := c.removeLBFinalizer(ctx, svc); err != nil {
+			klog.Errorf("%v", err)
+		}
+	}
+
 	// Process each LoadBalancer service
-	for _, svc := range services.Items {
-		if svc.Spec.Type != corev1.ServiceTypeLoadBalancer {
+	for i := range services.Items {
+		svc := &services.Items[i]
+		if !currentServices[fmt.Sprintf("%s/%s", svc.Namespace, svc.Name)] {
 			continue
 		}
 
-		if err := c.reconcileService(ctx, &svc, healthyNodes); err != nil {
+		if err := c.reconcileService(ctx, svc, healthyNodes); err != nil {
 			klog.Errorf("Failed to reconcile service %s/%s: %v", svc.Namespace, svc.Name, err)
 		}
 	}
 
-	// Check for IP failover (if a node with assigned IP is unhealthy)
+	// Check for IP failover (if a node with assigned IP is unhealthy). Failover keeps the IP in
+	// this cluster, so its dynamic IP lock is kept too.
 	if err := c.checkIPFailover(ctx, healthyNodes); err != nil {
 		klog.Errorf("IP failover check failed: %v", err)
 	}
 
+	// Configure IPs again whose node lost their configuration, e.g. in a reboot
+	c.verifyIPConfigs(ctx)
+
+	c.renewIPLocks(ctx)
+
+	c.syncIPReservations(ctx, services.Items)
+
+	c.releaseUnusedIPs(ctx)
+
 	return nil
 }
 
-// reconcileService ensures a LoadBalancer service has an IP assigned
+// reconcileService ensures a LoadBalancer service has an IP assigned for each IP family it uses
 func (c *LoadBalancerController) reconcileService(ctx context.Context, svc *corev1.Service, healthyNodes []corev1.Node) error {
+	// The finalizer goes on before an IP does, so the IP can't outlive the service
+	if err := c.ensureLBFinalizer(ctx, svc); err != nil {
+		return err
+	}
+
+	families := serviceIPFamilies(svc)
+	var ips []string
+	recorded := true
+	missing := false
+	for i, family := range families {
+		optional := i > 0 && prefersDualStack(svc)
+		ip, inStatus, err := c.reconcileServiceIP(ctx, svc, healthyNodes, family, optional)
+		if err != nil {
+			return err
+		}
+		if ip != "" {
+			ips = append(ips, ip)
+			recorded = recorded && inStatus
+		} else if !optional {
+			missing = true
+		}
+	}
+
+	if !missing {
+		c.clearPoolExhausted(ctx, svc)
+	}
+	if len(ips) == 0 {
+		return nil
+	}
+
+	// Update service status
+	if !recorded {
+		if err := c.updateServiceStatus(ctx, svc, ips...); err != nil {
+			return err
+		}
+	}
+	if len(svc.Spec.Ports) > 0 {
+		c.reportEndpoints(ctx, svc, c.isWaitingForEndpoints(fmt.Sprintf("%s/%s", svc.Namespace, svc.Name), families))
+	}
+	return nil
+}
+
+// reconcileServiceIP ensures the service has an IP of one family assigned and configured. It
+// returns the IP ("" if the pool is exhausted) and whether the service status already shows it.
+// An exhausted pool is not reported for an optional family, so the service stays single-stack.
+func (c *LoadBalancerController) reconcileServiceIP(ctx context.Context, svc *corev1.Service, healthyNodes []corev1.Node, family corev1.IPFamily, optional bool) (string, bool, error) {
 	svcKey := fmt.Sprintf("%s/%s", svc.Namespace, svc.Name)
+	ipKey := serviceIPKey(svcKey, family)
 
 	// Check if service already has an external IP from our pool
 	for _, ingress := range svc.Status.LoadBalancer.Ingress {
-		if c.isPoolIP(ingress.IP) {
-			klog.V(2).Infof("Service %s already has pool IP %s", svcKey, ingress.IP)
+		if ipFamilyOf(ingress.IP) == family && c.isPoolIP(ingress.IP) {
+			klog.V(2).InfoS("Service already has pool IP", "svcKey", svcKey, "ip", ingress.IP)
 			// Ensure IP is configured on the node (in case of CCM restart)
 			c.mutex.RLock()
 			serverUUID, hasAssignment := c.ipAssignments[ingress.IP]
 			c.mutex.RUnlock()
 
-			// If no assignment tracking, use first healthy node
+			// If no assignment tracking, prefer the node recorded on the service, else the least-loaded one
 			if !hasAssignment && len(healthyNodes) > 0 {
-				serverUUID = c.getNodeUUID(&healthyNodes[0])
-				if serverUUID != "" {
-					c.mutex.Lock()
+				c.mutex.Lock()
+				node := c.selectNode(healthyNodes, c.nodeLoadLocked(), svc.Annotations[AnnotationLBNode])
+				if node != nil {
+					serverUUID = c.getNodeUUID(node)
 					c.ipAssignments[ingress.IP] = serverUUID
-					c.serviceIPs[svcKey] = ingress.IP
-					c.mutex.Unlock()
+					c.serviceIPs[ipKey] = ingress.IP
 					hasAssignment = true
-					klog.Infof("Recovered IP assignment: %s -> %s", ingress.IP, healthyNodes[0].Name)
+				}
+				c.mutex.Unlock()
+				if node != nil {
+					klog.Infof("Recovered IP assignment: %s -> %s", ingress.IP, node.Name)
+					c.persistNodeAssignment(ctx, svc, serverUUID)
 				}
 			}
 
 			if hasAssignment && len(svc.Spec.Ports) > 0 {
-				// Get endpoint IP (pod IP) for direct routing - ClusterIP routing may be broken
-				endpointIP := c.getEndpointIP(ctx, svc)
-				if endpointIP == "" {
-					endpointIP = svc.Spec.ClusterIP // fallback to ClusterIP
+				if endpointIP := c.dnatTarget(ctx, svc, family, ipKey); endpointIP != "" {
+					c.ensureIPConfigured(ctx, ingress.IP, serverUUID, endpointIP, svc.Spec.Ports[0].Port)
 				}
-				c.ensureIPConfigured(ctx, ingress.IP, serverUUID, endpointIP, svc.Spec.Ports[0].Port)
 
 				// Ensure IP is tagged (in case of CCM restart or missed tagging)
 				if err := c.tagIPInCloudSigma(ctx, ingress.IP, svcKey); err != nil {
 					klog.V(2).Infof("Failed to ensure tags for IP %s: %v", ingress.IP, err)
 				}
 			}
-			return nil
+			return ingress.IP, true, nil
 		}
 	}
 
 	// Check if we already assigned an IP to this service
 	c.mutex.RLock()
-	existingIP, hasIP := c.serviceIPs[svcKey]
+	existingIP, hasIP := c.serviceIPs[ipKey]
 	c.mutex.RUnlock()
 
 	if hasIP {
-		// Update service status with the IP
-		return c.updateServiceStatus(ctx, svc, existingIP)
+		return existingIP, false, nil
 	}
 
 	// Allocate a new IP from the pool (static or dynamic based on annotation)
-	ip, err := c.allocateIP(ctx, svc)
+	ip, usage, err := c.allocateIP(ctx, svc, family)
 	if err != nil {
-		return fmt.Errorf("failed to allocate IP: %w", err)
+		return "", false, fmt.Errorf("failed to allocate IP: %w", err)
 	}
 
 	if ip == "" {
-		poolType := c.getIPPoolType(svc)
-		klog.Warningf("No available IPs in %s pool for service %s", poolType, svcKey)
-		return nil
+		if ip, err = c.purchaseIP(ctx, svc, usage); err != nil {
+			klog.Warningf("Failed to purchase an IP for service %s: %v", svcKey, err)
+		}
 	}
+	if ip == "" && optional {
+		klog.V(2).Infof("No available %s IPs in %s pool for service %s, keeping it single-stack", family, usage.Pool, svcKey)
+		return "", false, nil
+	}
+	if ip == "" {
+		klog.Warningf("No available %s IPs in %s pool for service %s", family, usage.Pool, svcKey)
+		c.reportPoolExhausted(ctx, svc, usage)
+		return "", false, nil
+	}
+	c.recordEvent(svc, corev1.EventTypeNormal, EventReasonIPAllocated, "Allocated IP %s from the %s pool", ip, usage.Pool)
 
-	// Assign IP to a healthy node
-	if len(healthyNodes) > 0 {
-		nodeUUID := c.getNodeUUID(&healthyNodes[0])
-		if nodeUUID != "" {
-			// Ensure the node's NIC is in manual mode (one-time per node).
-			// Manual mode opens the CloudSigma firewall for ALL subscribed IPs,
-			// eliminating the need for per-IP NIC attachment.
-			if err := c.ensureNodeManualMode(ctx, nodeUUID); err != nil {
-				return fmt.Errorf("failed to switch node %s to manual NIC mode: %w", nodeUUID, err)
-			}
+	// Assign IP to the least-loaded healthy node
+	c.mutex.RLock()
+	node := c.selectNode(healthyNodes, c.nodeLoadLocked(), svc.Annotations[AnnotationLBNode])
+	c.mutex.RUnlock()
+	if node != nil {
+		nodeUUID := c.getNodeUUID(node)
+		// Ensure the node's NIC is in manual mode (one-time per node).
+		// Manual mode opens the CloudSigma firewall for ALL subscribed IPs,
+		// eliminating the need for per-IP NIC attachment.
+		if err := c.ensureNodeManualMode(ctx, nodeUUID); err != nil {
+			return "", false, fmt.Errorf("failed to switch node %s to manual NIC mode: %w", nodeUUID, err)
+		}
 
-			c.mutex.Lock()
-			c.ipAssignments[ip] = nodeUUID
-			c.serviceIPs[svcKey] = ip
-			c.mutex.Unlock()
+		c.mutex.Lock()
+		c.ipAssignments[ip] = nodeUUID
+		c.serviceIPs[ipKey] = ip
+		c.mutex.Unlock()
 
-			// Tag IP in CloudSigma for tracking (non-blocking)
-			if err := c.tagIPInCloudSigma(ctx, ip, svcKey); err != nil {
-				klog.Warningf("Failed to tag IP %s in CloudSigma: %v", ip, err)
-			}
+		// Tag IP in CloudSigma for tracking (non-blocking)
+		if err := c.tagIPInCloudSigma(ctx, ip, svcKey); err != nil {
+			klog.Warningf("Failed to tag IP %s in CloudSigma: %v", ip, err)
+		}
 
-			// Configure the IP on the node and set up iptables rules
-			if len(svc.Spec.Ports) > 0 {
-				port := svc.Spec.Ports[0].Port
-				// Get endpoint IP (pod IP) for direct routing - ClusterIP routing may be broken
-				endpointIP := c.getEndpointIP(ctx, svc)
-				if endpointIP == "" {
-					endpointIP = svc.Spec.ClusterIP // fallback to ClusterIP
-				}
+		// Configure the IP on the node and set up iptables rules, once the service has endpoints
+		if len(svc.Spec.Ports) > 0 {
+			port := svc.Spec.Ports[0].Port
+			if endpointIP := c.dnatTarget(ctx, svc, family, ipKey); endpointIP != "" {
 				if err := c.configureIPOnNode(ctx, ip, nodeUUID, endpointIP, port); err != nil {
 					klog.Warningf("Failed to configure IP %s on node: %v", ip, err)
 				}
 			}
-
-			klog.Infof("Assigned IP %s to service %s (node: %s)", ip, svcKey, healthyNodes[0].Name)
 		}
+
+		c.persistNodeAssignment(ctx, svc, nodeUUID)
+		klog.InfoS("Assigned IP to service", "svcKey", svcKey, "ip", ip, "node", node.Name)
 	}
 
-	// Update service status
-	return c.updateServiceStatus(ctx, svc, ip)
+	return ip, false, nil
 }
 
 // checkIPFailover checks if any IPs need to be moved due to node failure
@@ -467,20 +782,28 @@ func (c *LoadBalancerController) checkIPFailover(ctx context.Context, healthyNod
 	for ip, uuid := range c.ipAssignments {
 		assignments[ip] = uuid
 	}
+	load := c.nodeLoadLocked()
 	c.mutex.RUnlock()
 
-	for ip, currentUUID := range assignments {
+	// Move IPs in a fixed order so the same failure always produces the same placement
+	ips := make([]string, 0, len(assignments))
+	for ip := range assignments {
+		ips = append(ips, ip)
+	}
+	sort.Strings(ips)
+
+	for _, ip := range ips {
+		currentUUID := assignments[ip]
 		if !healthyUUIDs[currentUUID] {
 			// Current node is unhealthy, move IP to a healthy node
 			klog.Warningf("Node %s with IP %s is unhealthy, initiating failover", currentUUID, ip)
 
-			// Pick first healthy node
-			newNode := &healthyNodes[0]
-			newUUID := c.getNodeUUID(newNode)
-
-			if newUUID == "" {
+			// Pick the least-loaded healthy node
+			newNode := c.selectNode(healthyNodes, load, "")
+			if newNode == nil {
 				continue
 			}
+			newUUID := c.getNodeUUID(newNode)
 
 			// Ensure new node is in manual mode (allows all subscribed IPs)
 			if err := c.ensureNodeManualMode(ctx, newUUID); err != nil {
@@ -490,9 +813,9 @@ func (c *LoadBalancerController) checkIPFailover(ctx context.Context, healthyNod
 
 			// Force-delete old lb-ip pod with zero grace period to avoid race condition
 			// where the pod is still terminating when we try to create the new one
-			podName := fmt.Sprintf("lb-ip-%s", strings.ReplaceAll(ip, ".", "-"))
+			podName := lbIPPodName(ip)
 			gracePeriod := int64(0)
-			if err := c.TenantClient.CoreV1().Pods("kube-system").Delete(ctx, podName, metav1.DeleteOptions{
+			if err := c.tenantClient().CoreV1().Pods("kube-system").Delete(ctx, podName, metav1.DeleteOptions{
 				GracePeriodSeconds: &gracePeriod,
 			}); err != nil {
 				klog.V(2).Infof("Failed to delete old lb-ip pod %s: %v", podName, err)
@@ -501,13 +824,16 @@ func (c *LoadBalancerController) checkIPFailover(ctx context.Context, healthyNod
 			c.mutex.Lock()
 			c.ipAssignments[ip] = newUUID
 			c.mutex.Unlock()
+			load[currentUUID]--
+			load[newUUID]++
 
 			// Find service for this IP and configure lb-ip pod on new node
 			c.mutex.RLock()
-			var svcKey string
+			var ipKey, svcKey string
 			for key, svcIP := range c.serviceIPs {
 				if svcIP == ip {
-					svcKey = key
+					ipKey = key
+					svcKey = serviceKeyFromIPKey(key)
 					break
 				}
 			}
@@ -516,20 +842,22 @@ func (c *LoadBalancerController) checkIPFailover(ctx context.Context, healthyNod
 			if svcKey != "" {
 				parts := strings.SplitN(svcKey, "/", 2)
 				if len(parts) == 2 {
-					svc, err := c.TenantClient.CoreV1().Services(parts[0]).Get(ctx, parts[1], metav1.GetOptions{})
+					svc, err := c.tenantClient().CoreV1().Services(parts[0]).Get(ctx, parts[1], metav1.GetOptions{})
+					if err == nil {
+						c.persistNodeAssignment(ctx, svc, newUUID)
+					}
 					if err == nil && len(svc.Spec.Ports) > 0 {
 						port := svc.Spec.Ports[0].Port
-						endpointIP := c.getEndpointIP(ctx, svc)
-						if endpointIP == "" {
-							endpointIP = svc.Spec.ClusterIP
-						}
-						if err := c.configureIPOnNode(ctx, ip, newUUID, endpointIP, port); err != nil {
-							klog.Errorf("Failed to configure IP %s on new node: %v", ip, err)
+						if endpointIP := c.dnatTarget(ctx, svc, ipFamilyOf(ip), ipKey); endpointIP != "" {
+							if err := c.configureIPOnNode(ctx, ip, newUUID, endpointIP, port); err != nil {
+								klog.Errorf("Failed to configure IP %s on new node: %v", ip, err)
+							}
 						}
 					}
 				}
 			}
 
+			lbFailovers.Inc()
 			klog.Infof("IP failover complete: %s moved from %s to %s", ip, currentUUID, newUUID)
 		}
 	}
@@ -549,8 +877,26 @@ func (c *LoadBalancerController) getIPPoolType(svc *corev1.Service) string {
 	return IPPoolStatic
 }
 
-// allocateIP finds an available IP from the appropriate pool based on service annotation
-func (c *LoadBalancerController) allocateIP(ctx context.Context, svc *corev1.Service) (string, error) {
+// ipPoolUsage describes the pool an allocation was attempted from
+type ipPoolUsage struct {
+	Pool   string
+	Family corev1.IPFamily
+	Size   int
+	InUse  int
+}
+
+func (u ipPoolUsage) String() string {
+	if u.Family == corev1.IPv6Protocol {
+		return fmt.Sprintf("%s IPv6 pool exhausted: %d of %d IPs in use", u.Pool, u.InUse, u.Size)
+	}
+	return fmt.Sprintf("%s IP pool exhausted: %d of %d IPs in use", u.Pool, u.InUse, u.Size)
+}
+
+// allocateIP finds an available IP from the appropriate pool based on service annotation.
+// The IP reserved to the service's reservation is tried first, and IPs of other live
+// reservations are skipped. An empty IP means the pool is exhausted; the returned usage says
+// how it is used.
+func (c *LoadBalancerController) allocateIP(ctx context.Context, svc *corev1.Service, family corev1.IPFamily) (string, ipPoolUsage, error) {
 	poolType := c.getIPPoolType(svc)
 
 	c.mutex.RLock()
@@ -560,34 +906,72 @@ func (c *LoadBalancerController) allocateIP(ctx context.Context, svc *corev1.Ser
 	}
 
 	// Select the appropriate pool based on annotation
-	var pool []string
+	source := c.staticIPs
 	if poolType == IPPoolDynamic {
-		pool = make([]string, len(c.dynamicIPs))
-		copy(pool, c.dynamicIPs)
-	} else {
-		pool = make([]string, len(c.staticIPs))
-		copy(pool, c.staticIPs)
+		source = c.dynamicIPs
+	}
+	var pool []string
+	free := false
+	for _, ip := range source {
+		if ipFamilyOf(ip) == family {
+			pool = append(pool, ip)
+			free = free || !usedIPs[ip]
+		}
 	}
 	c.mutex.RUnlock()
 
 	klog.V(2).Infof("Allocating IP from %s pool (%d IPs available) for service %s/%s",
 		poolType, len(pool), svc.Namespace, svc.Name)
 
+	usage := ipPoolUsage{Pool: poolType, Family: family, Size: len(pool)}
+
+	// Reservations only matter if there is an IP left to choose from
+	var reservedIP string
+	var reserved map[string]bool
+	if free {
+		var err error
+		if reservedIP, reserved, err = c.reservedIPs(ctx, svc, family); err != nil {
+			return "", usage, err
+		}
+		for i, ip := range pool {
+			if ip == reservedIP {
+				pool = append(append([]string{ip}, pool[:i]...), pool[i+1:]...)
+				break
+			}
+		}
+	}
+
 	for _, ip := range pool {
-		if !usedIPs[ip] {
+		if !usedIPs[ip] && !reserved[ip] {
 			// Verify IP is available via API
 			available, err := c.isIPAvailable(ctx, ip)
 			if err != nil {
 				klog.Errorf("Failed to check IP %s availability: %v", ip, err)
 				continue
 			}
+			if available && poolType == IPPoolDynamic {
+				// Another cluster may be taking the same dynamic IP right now; claim it first
+				if available, err = c.acquireIPLock(ctx, ip); err != nil {
+					klog.Errorf("Failed to lock dynamic IP %s: %v", ip, err)
+					continue
+				}
+			}
 			if available {
-				return ip, nil
+				if ip == reservedIP {
+					klog.InfoS("Reusing reserved IP", "svcKey", fmt.Sprintf("%s/%s", svc.Namespace, svc.Name),
+						"reservation", reservationName(svc), "ip", ip)
+				}
+				return ip, usage, nil
 			}
 		}
+		if ip == reservedIP {
+			klog.Warningf("Reserved IP %s of reservation %s is in use, allocating another one", ip, reservationName(svc))
+		}
+		usage.InUse++
 	}
 
-	return "", nil
+	lbAssignmentFailures.Inc()
+	return "", usage, nil
 }
 
 // isIPAvailable checks if an IP is available by looking at CloudSigma tags.
@@ -605,36 +989,14 @@ func (c *LoadBalancerController) isIPAvailable(ctx context.Context, ip string) (
 // getTaggedServiceIPs returns a map of IPs that have service:* tags (i.e., assigned to LB services).
 // This is used to check IP availability since IPs are no longer attached to servers with manual NIC mode.
 func (c *LoadBalancerController) getTaggedServiceIPs(ctx context.Context) (map[string]string, error) {
-	token, err := c.ImpersonationClient.GetImpersonatedToken(ctx, c.UserEmail, c.Region)
-	if err != nil {
-		return nil, err
-	}
-
-	listURL := fmt.Sprintf("https://%s.cloudsigma.com/api/2.0/tags/", c.Region)
-	req, _ := http.NewRequestWithContext(ctx, "GET", listURL, nil)
-	req.Header.Set("Authorization", "Bearer "+token)
-
-	resp, err := http.DefaultClient.Do(req)
+	tags, err := c.listTags(ctx, tagPrefixFilter("service:"))
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
-
-	var tagList struct {
-		Objects []struct {
-			UUID      string `json:"uuid"`
-			Name      string `json:"name"`
-			Resources []struct {
-				UUID string `json:"uuid"`
-			} `json:"resources"`
-		} `json:"objects"`
-	}
-	body, _ := io.ReadAll(resp.Body)
-	json.Unmarshal(body, &tagList)
 
 	// Build map: IP -> service tag name (for IPs that have service:* tags)
 	result := make(map[string]string)
-	for _, tag := range tagList.Objects {
+	for _, tag := range tags {
 		if strings.HasPrefix(tag.Name, "service:") {
 			for _, r := range tag.Resources {
 				result[r.UUID] = tag.Name
@@ -647,8 +1009,8 @@ func (c *LoadBalancerController) getTaggedServiceIPs(ctx context.Context) (map[s
 // ensureNodeManualMode switches a server's NIC from dhcp/static to "manual" mode.
 // With manual mode, the CloudSigma cloud firewall allows traffic for ALL IPs owned
 // by the user (with subscription), eliminating the need for per-IP NIC attachment.
-// This is a one-time operation per node - once in manual mode, all subscribed IPs
-// can be used without further API calls.
+// The switch is done once per node and kept until the controller shuts down with the cluster
+// being deleted, when revertAllManualModeNodes restores the NIC configuration saved on the server.
 func (c *LoadBalancerController) ensureNodeManualMode(ctx context.Context, serverUUID string) error {
 	c.mutex.RLock()
 	if c.manualModeNodes[serverUUID] {
@@ -657,122 +1019,81 @@ func (c *LoadBalancerController) ensureNodeManualMode(ctx context.Context, serve
 	}
 	c.mutex.RUnlock()
 
-	token, err := c.ImpersonationClient.GetImpersonatedToken(ctx, c.UserEmail, c.Region)
+	client, err := c.cloudClient()
 	if err != nil {
-		return fmt.Errorf("failed to get token: %w", err)
+		return err
 	}
-
-	// Get current server
-	serverURL := fmt.Sprintf("https://%s.cloudsigma.com/api/2.0/servers/%s/", c.Region, serverUUID)
-	req, _ := http.NewRequestWithContext(ctx, "GET", serverURL, nil)
-	req.Header.Set("Authorization", "Bearer "+token)
-
-	resp, err := http.DefaultClient.Do(req)
+	switched, err := client.SetNICManualMode(ctx, serverUUID)
 	if err != nil {
-		return fmt.Errorf("failed to get server: %w", err)
-	}
-	defer resp.Body.Close()
-
-	var server map[string]interface{}
-	body, _ := io.ReadAll(resp.Body)
-	if err := json.Unmarshal(body, &server); err != nil {
-		return fmt.Errorf("failed to parse server: %w", err)
+		return fmt.Errorf("failed to switch server %s NIC to manual mode: %w", serverUUID, err)
 	}
 
-	// Check if already in manual mode
-	nics, _ := server["nics"].([]interface{})
-	alreadyManual := false
-	for _, nic := range nics {
-		nicMap, ok := nic.(map[string]interface{})
-		if !ok {
-			continue
-		}
-		ipv4Conf, _ := nicMap["ip_v4_conf"].(map[string]interface{})
-		if ipv4Conf == nil {
-			continue
-		}
-		conf, _ := ipv4Conf["conf"].(string)
-		if conf == "manual" {
-			alreadyManual = true
-			break
-		}
-	}
+	c.mutex.Lock()
+	c.manualModeNodes[serverUUID] = true
+	c.mutex.Unlock()
 
-	if alreadyManual {
+	if !switched {
 		klog.V(2).Infof("Server %s NIC already in manual mode", serverUUID)
-		c.mutex.Lock()
-		c.manualModeNodes[serverUUID] = true
-		c.mutex.Unlock()
 		return nil
 	}
+	klog.Infof("Switched server %s NIC to manual mode (all subscribed IPs now allowed)", serverUUID)
+	return nil
+}
 
-	// Switch first public NIC (ip_v4_conf) from dhcp/static to manual
-	switched := false
-	for _, nic := range nics {
-		nicMap, ok := nic.(map[string]interface{})
-		if !ok {
-			continue
-		}
-		ipv4Conf, _ := nicMap["ip_v4_conf"].(map[string]interface{})
-		if ipv4Conf == nil {
-			continue
-		}
-		// Switch to manual mode - remove the specific IP binding
-		ipv4Conf["conf"] = "manual"
-		delete(ipv4Conf, "ip")
-		switched = true
-		break
+// revertNodeManualMode restores the NIC configuration a server had before
+// ensureNodeManualMode switched it to manual mode. Servers switched by someone
+// else, or already reverted, are left alone.
+func (c *LoadBalancerController) revertNodeManualMode(ctx context.Context, serverUUID string) error {
+	client, err := c.cloudClient()
+	if err != nil {
+		return err
 	}
-
-	if !switched {
-		return fmt.Errorf("no public NIC found on server %s to switch to manual mode", serverUUID)
+	reverted, err := client.RevertNICManualMode(ctx, serverUUID)
+	if err != nil {
+		return fmt.Errorf("failed to revert server %s NIC from manual mode: %w", serverUUID, err)
 	}
 
-	// Update server - preserve all required fields including vnc_password
-	server["nics"] = nics
-	// Remove read-only fields that can't be sent in update
-	delete(server, "resource_uri")
-	delete(server, "runtime")
-	delete(server, "status")
-	delete(server, "uuid")
-	delete(server, "owner")
-	delete(server, "permissions")
-	delete(server, "mounted_on")
-	delete(server, "grantees")
+	c.mutex.Lock()
+	delete(c.manualModeNodes, serverUUID)
+	c.mutex.Unlock()
 
-	updateBody, _ := json.Marshal(server)
+	if reverted {
+		klog.Infof("Reverted server %s NIC from manual mode", serverUUID)
+	}
+	return nil
+}
 
-	req, _ = http.NewRequestWithContext(ctx, "PUT", serverURL, strings.NewReader(string(updateBody)))
-	req.Header.Set("Authorization", "Bearer "+token)
-	req.Header.Set("Content-Type", "application/json")
+// revertAllManualModeNodes reverts the NICs of all nodes switched to manual mode when the
+// controller shuts down because the cluster is being deleted, so servers outlive the cluster
+// in the network state they had before. On any other shutdown the nodes keep serving their
+// LoadBalancer IPs.
+func (c *LoadBalancerController) revertAllManualModeNodes() {
+	// Use a fresh context with timeout since the parent context is cancelled
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
 
-	resp, err = http.DefaultClient.Do(req)
-	if err != nil {
-		return fmt.Errorf("failed to update server NIC: %w", err)
+	c.mutex.RLock()
+	nodes := make([]string, 0, len(c.manualModeNodes))
+	for serverUUID := range c.manualModeNodes {
+		nodes = append(nodes, serverUUID)
 	}
-	defer resp.Body.Close()
+	c.mutex.RUnlock()
 
-	if resp.StatusCode >= 400 {
-		respBody, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("failed to switch NIC to manual mode: %s", string(respBody))
+	if len(nodes) == 0 || !isTearingDown(ctx, c.TearingDown, "reverting node NICs from manual mode") {
+		return
 	}
 
-	c.mutex.Lock()
-	c.manualModeNodes[serverUUID] = true
-	c.mutex.Unlock()
-
-	klog.Infof("Switched server %s NIC to manual mode (all subscribed IPs now allowed)", serverUUID)
-	return nil
+	klog.Infof("Reverting %d node NICs from manual mode on shutdown", len(nodes))
+	for _, serverUUID := range nodes {
+		if err := c.revertNodeManualMode(ctx, serverUUID); err != nil {
+			klog.Warningf("Failed to revert node NIC on shutdown: %v", err)
+		}
+	}
 }
 
 // tagIPInCloudSigma adds tags to an IP in CloudSigma to track which cluster/service is using it.
 // It also cleans stale tags from the IP (e.g., old service:* or cluster:* tags from previous assignments).
 func (c *LoadBalancerController) tagIPInCloudSigma(ctx context.Context, ip, serviceName string) error {
-	token, err := c.ImpersonationClient.GetImpersonatedToken(ctx, c.UserEmail, c.Region)
-	if err != nil {
-		return fmt.Errorf("failed to get token for IP tagging: %w", err)
-	}
-
 	// Desired tags for this IP
 	desiredTags := map[string]bool{
 		fmt.Sprintf("cluster:%s", c.ClusterName):                                true,
@@ -781,16 +1102,22 @@ func (c *LoadBalancerController) tagIPInCloudSigma(ctx context.Context, ip, serv
 	}
 
 	// Clean stale tags: remove this IP from any CCM-managed tags that don't match current assignment
-	if err := c.cleanStaleTags(ctx, token, ip, desiredTags); err != nil {
+	if err := c.cleanStaleTags(ctx, ip, desiredTags); err != nil {
 		klog.Warningf("Failed to clean stale tags from IP %s: %v", ip, err)
 	}
 
 	// Add IP to desired tags
+	var failed []string
 	for tagName := range desiredTags {
-		if err := c.ensureTagWithIP(ctx, token, tagName, ip); err != nil {
+		if err := c.ensureTagWithIP(ctx, tagName, ip); err != nil {
 			klog.Warningf("Failed to add IP %s to tag %s: %v", ip, tagName, err)
+			failed = append(failed, tagName)
 		}
 	}
+	if len(failed) > 0 {
+		sort.Strings(failed)
+		return fmt.Errorf("failed to add IP %s to tags %s", ip, strings.Join(failed, ", "))
+	}
 
 	klog.Infof("Tagged IP %s with cluster=%s, service=%s", ip, c.ClusterName, serviceName)
 	return nil
@@ -798,34 +1125,21 @@ func (c *LoadBalancerController) tagIPInCloudSigma(ctx context.Context, ip, serv
 
 // cleanStaleTags removes an IP from any CCM-managed tags (cluster:*, service:*, managed-by:*)
 // that are NOT in the desiredTags set. This cleans up stale tags from previous assignments.
-func (c *LoadBalancerController) cleanStaleTags(ctx context.Context, token, ip string, desiredTags map[string]bool) error {
-	listURL := fmt.Sprintf("https://%s.cloudsigma.com/api/2.0/tags/", c.Region)
-	req, _ := http.NewRequestWithContext(ctx, "GET", listURL, nil)
-	req.Header.Set("Authorization", "Bearer "+token)
-
-	resp, err := http.DefaultClient.Do(req)
+func (c *LoadBalancerController) cleanStaleTags(ctx context.Context, ip string, desiredTags map[string]bool) error {
+	// The managed tags share no single name prefix, so this reads the full (cached) tag list
+	tags, err := c.listTags(ctx, nil)
 	if err != nil {
-		return fmt.Errorf("failed to list tags: %w", err)
+		return err
 	}
-	defer resp.Body.Close()
 
-	var tagList struct {
-		Objects []struct {
-			UUID      string `json:"uuid"`
-			Name      string `json:"name"`
-			Resources []struct {
-				UUID string `json:"uuid"`
-			} `json:"resources"`
-		} `json:"objects"`
+	client, err := c.cloudClient()
+	if err != nil {
+		return err
 	}
-	body, _ := io.ReadAll(resp.Body)
-	json.Unmarshal(body, &tagList)
 
-	for _, tag := range tagList.Objects {
+	for _, tag := range tags {
 		// Only process CCM-managed tags
-		if !strings.HasPrefix(tag.Name, "cluster:") &&
-			!strings.HasPrefix(tag.Name, "service:") &&
-			tag.Name != "managed-by:cloudsigma-ccm" {
+		if !isCCMTag(tag.Name) {
 			continue
 		}
 
@@ -834,157 +1148,67 @@ func (c *LoadBalancerController) cleanStaleTags(ctx context.Context, token, ip s
 			continue
 		}
 
-		// Check if this stale tag contains our IP
-		var newResources []string
-		found := false
-		for _, r := range tag.Resources {
-			if r.UUID == ip {
-				found = true
-			} else {
-				newResources = append(newResources, r.UUID)
-			}
+		// Remove IP from this stale tag; tags without it are left untouched
+		if !tagHasResource(tag, ip) {
+			continue
 		}
-
-		if found {
-			// Remove IP from this stale tag - use resource objects format [{"uuid": "..."}]
-			updateURL := fmt.Sprintf("https://%s.cloudsigma.com/api/2.0/tags/%s/", c.Region, tag.UUID)
-			resourceObjects := make([]map[string]string, 0, len(newResources))
-			for _, uuid := range newResources {
-				resourceObjects = append(resourceObjects, map[string]string{"uuid": uuid})
-			}
-			payload := map[string]interface{}{
-				"name":      tag.Name,
-				"resources": resourceObjects,
-			}
-			body, _ := json.Marshal(payload)
-			klog.V(4).Infof("Cleaning stale tag %s: PUT %s body=%s", tag.Name, updateURL, string(body))
-			req, _ := http.NewRequestWithContext(ctx, "PUT", updateURL, strings.NewReader(string(body)))
-			req.Header.Set("Authorization", "Bearer "+token)
-			req.Header.Set("Content-Type", "application/json")
-
-			resp, err := http.DefaultClient.Do(req)
-			if err != nil {
-				klog.Warningf("Failed to remove IP %s from stale tag %s: %v", ip, tag.Name, err)
-				continue
-			}
-			respBody, _ := io.ReadAll(resp.Body)
-			resp.Body.Close()
-			if resp.StatusCode >= 400 {
-				klog.Warningf("Failed to clean stale tag %s from IP %s: HTTP %d: %s", tag.Name, ip, resp.StatusCode, string(respBody))
-			} else {
-				klog.Infof("Cleaned stale tag %s from IP %s (HTTP %d)", tag.Name, ip, resp.StatusCode)
-			}
+		err := client.RemoveResourceFromTag(ctx, tag, ip)
+		c.invalidateTagCache()
+		if err != nil {
+			klog.Warningf("Failed to clean stale tag %s from IP %s: %v", tag.Name, ip, err)
+		} else {
+			klog.Infof("Cleaned stale tag %s from IP %s", tag.Name, ip)
 		}
 	}
 	return nil
 }
 
 // ensureTagWithIP creates a tag if it doesn't exist and adds the IP to it
-func (c *LoadBalancerController) ensureTagWithIP(ctx context.Context, token, tagName, ip string) error {
-	// First, list all tags and find by name
-	listURL := fmt.Sprintf("https://%s.cloudsigma.com/api/2.0/tags/", c.Region)
-	req, _ := http.NewRequestWithContext(ctx, "GET", listURL, nil)
-	req.Header.Set("Authorization", "Bearer "+token)
-
-	resp, err := http.DefaultClient.Do(req)
+func (c *LoadBalancerController) ensureTagWithIP(ctx context.Context, tagName, ip string) error {
+	// First, look the tag up by name
+	tags, err := c.listTags(ctx, tagNameFilter(tagName))
 	if err != nil {
-		return fmt.Errorf("failed to list tags: %w", err)
-	}
-	defer resp.Body.Close()
-
-	var tagList struct {
-		Objects []struct {
-			UUID      string `json:"uuid"`
-			Name      string `json:"name"`
-			Resources []struct {
-				UUID string `json:"uuid"`
-			} `json:"resources"`
-		} `json:"objects"`
+		return err
 	}
-	body, _ := io.ReadAll(resp.Body)
-	json.Unmarshal(body, &tagList)
 
-	var tagUUID string
-	var existingResourceUUIDs []string
-
-	// Check if tag exists
-	for _, t := range tagList.Objects {
+	tag := cloudsigma.Tag{Name: tagName}
+	for _, t := range tags {
 		if t.Name == tagName {
-			tagUUID = t.UUID
-			for _, r := range t.Resources {
-				existingResourceUUIDs = append(existingResourceUUIDs, r.UUID)
-			}
+			tag = t
 			break
 		}
 	}
+	if tag.UUID != "" && tagHasResource(tag, ip) {
+		return nil // Already tagged
+	}
 
-	if tagUUID == "" {
-		// Create new tag with the IP
-		createURL := fmt.Sprintf("https://%s.cloudsigma.com/api/2.0/tags/", c.Region)
-		payload := map[string]interface{}{
-			"objects": []map[string]interface{}{
-				{
-					"name":      tagName,
-					"resources": []string{ip},
-				},
-			},
-		}
-		body, _ := json.Marshal(payload)
-		req, _ := http.NewRequestWithContext(ctx, "POST", createURL, strings.NewReader(string(body)))
-		req.Header.Set("Authorization", "Bearer "+token)
-		req.Header.Set("Content-Type", "application/json")
-
-		resp, err := http.DefaultClient.Do(req)
-		if err != nil {
-			return fmt.Errorf("failed to create tag: %w", err)
-		}
-		defer resp.Body.Close()
-
-		if resp.StatusCode >= 400 {
-			respBody, _ := io.ReadAll(resp.Body)
-			return fmt.Errorf("failed to create tag: %s", string(respBody))
-		}
+	client, err := c.cloudClient()
+	if err != nil {
+		return err
+	}
+	err = client.AddResourceToTag(ctx, tag, ip)
+	c.invalidateTagCache()
+	if err != nil {
+		return err
+	}
+	if tag.UUID == "" {
 		klog.V(2).Infof("Created tag %s with IP %s", tagName, ip)
 	} else {
-		// Check if IP already in tag
-		for _, uuid := range existingResourceUUIDs {
-			if uuid == ip {
-				return nil // Already tagged
-			}
-		}
-
-		// Update existing tag to add the IP - use resource objects format [{"uuid": "..."}]
-		updateURL := fmt.Sprintf("https://%s.cloudsigma.com/api/2.0/tags/%s/", c.Region, tagUUID)
-		allUUIDs := append(existingResourceUUIDs, ip)
-		resourceObjects := make([]map[string]string, 0, len(allUUIDs))
-		for _, uuid := range allUUIDs {
-			resourceObjects = append(resourceObjects, map[string]string{"uuid": uuid})
-		}
-		payload := map[string]interface{}{
-			"name":      tagName,
-			"resources": resourceObjects,
-		}
-		body, _ := json.Marshal(payload)
-		req, _ := http.NewRequestWithContext(ctx, "PUT", updateURL, strings.NewReader(string(body)))
-		req.Header.Set("Authorization", "Bearer "+token)
-		req.Header.Set("Content-Type", "application/json")
-
-		resp, err := http.DefaultClient.Do(req)
-		if err != nil {
-			return fmt.Errorf("failed to update tag: %w", err)
-		}
-		defer resp.Body.Close()
-
-		if resp.StatusCode >= 400 {
-			respBody, _ := io.ReadAll(resp.Body)
-			return fmt.Errorf("failed to update tag: %s", string(respBody))
-		}
 		klog.V(2).Infof("Added IP %s to existing tag %s", ip, tagName)
 	}
-
 	return nil
 }
 
+// tagHasResource reports whether tag has the resource with the given UUID
+func tagHasResource(tag cloudsigma.Tag, uuid string) bool {
+	for _, r := range tag.Resources {
+		if r.UUID == uuid {
+			return true
+		}
+	}
+	return false
+}
+
 // cleanupAllIPTags removes all CCM-managed tags from IPs tracked by this controller.
 // Called during shutdown to ensure IPs are released for reuse by new clusters.
 func (c *LoadBalancerController) cleanupAllIPTags() {
@@ -994,8 +1218,8 @@ func (c *LoadBalancerController) cleanupAllIPTags() {
 
 	c.mutex.Lock()
 	ipsToClean := make(map[string]string) // ip -> svcKey
-	for svcKey, ip := range c.serviceIPs {
-		ipsToClean[ip] = svcKey
+	for ipKey, ip := range c.serviceIPs {
+		ipsToClean[ip] = serviceKeyFromIPKey(ipKey)
 	}
 	c.mutex.Unlock()
 
@@ -1011,87 +1235,41 @@ func (c *LoadBalancerController) cleanupAllIPTags() {
 		} else {
 			klog.Infof("Cleaned up tags for IP %s (service %s) on shutdown", ip, svcKey)
 		}
+		c.mutex.RLock()
+		dynamic := c.isDynamicIPLocked(ip)
+		c.mutex.RUnlock()
+		if dynamic {
+			if err := c.releaseIPLock(ctx, ip); err != nil {
+				klog.Warningf("Failed to release lock on dynamic IP %s on shutdown: %v", ip, err)
+			}
+		}
 	}
 }
 
 // untagIPInCloudSigma removes an IP from CCM-managed tags in CloudSigma when it's released
 func (c *LoadBalancerController) untagIPInCloudSigma(ctx context.Context, ip string) error {
-	token, err := c.ImpersonationClient.GetImpersonatedToken(ctx, c.UserEmail, c.Region)
+	// List all (cached) tags to find ones containing this IP
+	tags, err := c.listTags(ctx, nil)
 	if err != nil {
-		return fmt.Errorf("failed to get token for IP untagging: %w", err)
+		return err
 	}
 
-	// List all tags to find ones containing this IP
-	listURL := fmt.Sprintf("https://%s.cloudsigma.com/api/2.0/tags/", c.Region)
-	req, _ := http.NewRequestWithContext(ctx, "GET", listURL, nil)
-	req.Header.Set("Authorization", "Bearer "+token)
-
-	resp, err := http.DefaultClient.Do(req)
+	client, err := c.cloudClient()
 	if err != nil {
-		return fmt.Errorf("failed to list tags: %w", err)
-	}
-	defer resp.Body.Close()
-
-	var tagList struct {
-		Objects []struct {
-			UUID      string `json:"uuid"`
-			Name      string `json:"name"`
-			Resources []struct {
-				UUID string `json:"uuid"`
-			} `json:"resources"`
-		} `json:"objects"`
+		return err
 	}
-	body, _ := io.ReadAll(resp.Body)
-	json.Unmarshal(body, &tagList)
 
 	// Remove IP from any CCM-managed tags
-	for _, tag := range tagList.Objects {
-		// Only process CCM-managed tags
-		if !strings.HasPrefix(tag.Name, "cluster:") &&
-			!strings.HasPrefix(tag.Name, "service:") &&
-			tag.Name != "managed-by:cloudsigma-ccm" {
+	for _, tag := range tags {
+		if !isCCMTag(tag.Name) || !tagHasResource(tag, ip) {
 			continue
 		}
-
-		// Check if this tag contains the IP
-		var newResources []string
-		found := false
-		for _, r := range tag.Resources {
-			if r.UUID == ip {
-				found = true
-			} else {
-				newResources = append(newResources, r.UUID)
-			}
-		}
-
-		if found {
-			// Update tag to remove the IP - use resource objects format [{"uuid": "..."}]
-			updateURL := fmt.Sprintf("https://%s.cloudsigma.com/api/2.0/tags/%s/", c.Region, tag.UUID)
-			resourceObjects := make([]map[string]string, 0, len(newResources))
-			for _, uuid := range newResources {
-				resourceObjects = append(resourceObjects, map[string]string{"uuid": uuid})
-			}
-			payload := map[string]interface{}{
-				"name":      tag.Name,
-				"resources": resourceObjects,
-			}
-			body, _ := json.Marshal(payload)
-			req, _ := http.NewRequestWithContext(ctx, "PUT", updateURL, strings.NewReader(string(body)))
-			req.Header.Set("Authorization", "Bearer "+token)
-			req.Header.Set("Content-Type", "application/json")
-
-			resp, err := http.DefaultClient.Do(req)
-			if err != nil {
-				klog.Warningf("Failed to remove IP %s from tag %s: %v", ip, tag.Name, err)
-				continue
-			}
-			resp.Body.Close()
-
-			if resp.StatusCode >= 400 {
-				klog.Warningf("Failed to remove IP %s from tag %s: status %d", ip, tag.Name, resp.StatusCode)
-			} else {
-				klog.V(2).Infof("Removed IP %s from tag %s", ip, tag.Name)
-			}
+		err := client.RemoveResourceFromTag(ctx, tag, ip)
+		c.invalidateTagCache()
+		if err != nil {
+			klog.Warningf("Failed to remove IP %s from tag %s: %v", ip, tag.Name, err)
+		} else {
+			klog.V(2).Infof("Removed IP %s from tag %s", ip, tag.Name)
 		}
 	}
 
@@ -1099,20 +1277,24 @@ func (c *LoadBalancerController) untagIPInCloudSigma(ctx context.Context, ip str
 	return nil
 }
 
-// deleteIPConfigPod deletes the LB IP config pod for an IP
-func (c *LoadBalancerController) deleteIPConfigPod(ctx context.Context, ip string) {
-	podName := fmt.Sprintf("lb-ip-%s", strings.ReplaceAll(ip, ".", "-"))
-	err := c.TenantClient.CoreV1().Pods("kube-system").Delete(ctx, podName, metav1.DeleteOptions{})
+// deleteIPConfigPod deletes the LB IP config pod for an IP; a pod that is already gone is not an error
+func (c *LoadBalancerController) deleteIPConfigPod(ctx context.Context, ip string) error {
+	podName := lbIPPodName(ip)
+	err := c.tenantClient().CoreV1().Pods("kube-system").Delete(ctx, podName, metav1.DeleteOptions{})
+	if apierrors.IsNotFound(err) {
+		klog.V(2).Infof("Config pod %s for IP %s already deleted", podName, ip)
+		return nil
+	}
 	if err != nil {
-		klog.V(2).Infof("Failed to delete config pod %s: %v", podName, err)
-	} else {
-		klog.Infof("Deleted config pod %s for IP %s", podName, ip)
+		return err
 	}
+	klog.Infof("Deleted config pod %s for IP %s", podName, ip)
+	return nil
 }
 
-// getEndpointIP returns the first endpoint IP (pod IP) for a service
-func (c *LoadBalancerController) getEndpointIP(ctx context.Context, svc *corev1.Service) string {
-	endpoints, err := c.TenantClient.CoreV1().Endpoints(svc.Namespace).Get(ctx, svc.Name, metav1.GetOptions{})
+// getEndpointIP returns the first endpoint IP (pod IP) of the given family for a service
+func (c *LoadBalancerController) getEndpointIP(ctx context.Context, svc *corev1.Service, family corev1.IPFamily) string {
+	endpoints, err := c.tenantClient().CoreV1().Endpoints(svc.Namespace).Get(ctx, svc.Name, metav1.GetOptions{})
 	if err != nil {
 		klog.V(2).Infof("Failed to get endpoints for service %s/%s: %v", svc.Namespace, svc.Name, err)
 		return ""
@@ -1120,7 +1302,7 @@ func (c *LoadBalancerController) getEndpointIP(ctx context.Context, svc *corev1.
 
 	for _, subset := range endpoints.Subsets {
 		for _, addr := range subset.Addresses {
-			if addr.IP != "" {
+			if addr.IP != "" && ipFamilyOf(addr.IP) == family {
 				klog.V(2).Infof("Using endpoint IP %s for service %s/%s", addr.IP, svc.Namespace, svc.Name)
 				return addr.IP
 			}
@@ -1130,12 +1312,54 @@ func (c *LoadBalancerController) getEndpointIP(ctx context.Context, svc *corev1.
 	return ""
 }
 
+// dnatTarget returns the pod IP DNAT rules for the service IP tracked under ipKey point at, and
+// records whether that IP is waiting for endpoints. There is no ClusterIP fallback, since ClusterIP
+// routing from the node may be broken: with no endpoint it returns "" and the IP is left
+// unconfigured until the endpoint retry finds one.
+func (c *LoadBalancerController) dnatTarget(ctx context.Context, svc *corev1.Service, family corev1.IPFamily, ipKey string) string {
+	ip := c.getEndpointIP(ctx, svc, family)
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if ip != "" {
+		delete(c.waitingForEndpoints, ipKey)
+		return ip
+	}
+	if !c.waitingForEndpoints[ipKey] {
+		klog.InfoS("Service has no endpoints, waiting before configuring its LoadBalancer IP", "svcKey", serviceKeyFromIPKey(ipKey), "family", family)
+	}
+	if c.waitingForEndpoints == nil {
+		c.waitingForEndpoints = make(map[string]bool)
+	}
+	c.waitingForEndpoints[ipKey] = true
+	return ""
+}
+
+// isWaitingForEndpoints reports whether any of the service's IP families is waiting for endpoints
+func (c *LoadBalancerController) isWaitingForEndpoints(svcKey string, families []corev1.IPFamily) bool {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	for _, family := range families {
+		if c.waitingForEndpoints[serviceIPKey(svcKey, family)] {
+			return true
+		}
+	}
+	return false
+}
+
+// hasWaitingForEndpoints reports whether any service IP is waiting for endpoints
+func (c *LoadBalancerController) hasWaitingForEndpoints() bool {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return len(c.waitingForEndpoints) > 0
+}
+
 // ensureIPConfigured checks if the LB IP config pod exists and creates it if not
 func (c *LoadBalancerController) ensureIPConfigured(ctx context.Context, ip, serverUUID, clusterIP string, port int32) {
-	podName := fmt.Sprintf("lb-ip-%s", strings.ReplaceAll(ip, ".", "-"))
+	podName := lbIPPodName(ip)
 
 	// Check if pod already exists
-	_, err := c.TenantClient.CoreV1().Pods("kube-system").Get(ctx, podName, metav1.GetOptions{})
+	_, err := c.tenantClient().CoreV1().Pods("kube-system").Get(ctx, podName, metav1.GetOptions{})
 	if err == nil {
 		// Pod exists, nothing to do
 		return
@@ -1153,7 +1377,7 @@ func (c *LoadBalancerController) ensureIPConfigured(ctx context.Context, ip, ser
 // so we only need to configure the IP at the OS level + iptables DNAT.
 func (c *LoadBalancerController) configureIPOnNode(ctx context.Context, ip, serverUUID, clusterIP string, port int32) error {
 	// Find the node by its providerID
-	nodes, err := c.TenantClient.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	nodes, err := c.tenantClient().CoreV1().Nodes().List(ctx, metav1.ListOptions{})
 	if err != nil {
 		return fmt.Errorf("failed to list nodes: %w", err)
 	}
@@ -1161,58 +1385,23 @@ func (c *LoadBalancerController) configureIPOnNode(ctx context.Context, ip, serv
 	var targetNode *corev1.Node
 	for i := range nodes.Items {
 		node := &nodes.Items[i]
-		if strings.HasSuffix(node.Spec.ProviderID, serverUUID) {
+		if c.getNodeUUID(node) == serverUUID {
 			targetNode = node
 			break
 		}
 	}
 
 	if targetNode == nil {
-		return fmt.Errorf("node with providerID containing %s not found", serverUUID)
+		return fmt.Errorf("no node has providerID %s%s (checked %d nodes)", cloud.ProviderIDPrefix, serverUUID, len(nodes.Items))
 	}
 
 	// Create a privileged pod to configure the IP and iptables on the node
-	podName := fmt.Sprintf("lb-ip-%s", strings.ReplaceAll(ip, ".", "-"))
+	podName := lbIPPodName(ip)
 
 	privileged := true
 	hostNetwork := true
 
-	// Script to:
-	// 1. Add IP to primary interface (manual NIC mode allows all subscribed IPs at firewall level)
-	// 2. Add iptables DNAT rules for external (PREROUTING) and local (OUTPUT) traffic
-	// 3. Add iptables MASQUERADE for return traffic
-	configScript := fmt.Sprintf(`
-echo "Configuring LoadBalancer IP %s"
-
-# Find primary interface (first non-lo, non-cilium interface)
-PRIMARY_IF=$(ip -o link show | grep -v -E 'lo:|cilium|lxc|veth' | head -1 | awk -F': ' '{print $2}')
-echo "Primary interface: $PRIMARY_IF"
-
-# Add LoadBalancer IP to primary interface as secondary IP
-# NIC is in manual mode - CloudSigma firewall allows all subscribed IPs
-ip addr add %s/32 dev $PRIMARY_IF 2>/dev/null || echo "IP already configured on $PRIMARY_IF"
-
-# Send gratuitous ARP to update upstream router/switch MAC table
-# Critical for failover: without GARP, traffic still routes to old node's MAC
-arping -U -c 3 -I $PRIMARY_IF %s 2>/dev/null &
-arping -A -c 3 -I $PRIMARY_IF %s 2>/dev/null &
-
-# Add iptables DNAT rules for external traffic (PREROUTING)
-iptables -t nat -C PREROUTING -d %s -p tcp --dport %d -j DNAT --to-destination %s:%d 2>/dev/null || \
-  iptables -t nat -I PREROUTING 1 -d %s -p tcp --dport %d -j DNAT --to-destination %s:%d
-
-# Add iptables DNAT rules for local traffic (OUTPUT) - needed for traffic originating from the node
-iptables -t nat -C OUTPUT -d %s -p tcp --dport %d -j DNAT --to-destination %s:%d 2>/dev/null || \
-  iptables -t nat -I OUTPUT 1 -d %s -p tcp --dport %d -j DNAT --to-destination %s:%d
-
-# Add MASQUERADE for return traffic
-iptables -t nat -C POSTROUTING -d %s -p tcp --dport %d -j MASQUERADE 2>/dev/null || \
-  iptables -t nat -A POSTROUTING -d %s -p tcp --dport %d -j MASQUERADE
-
-echo "Configured LoadBalancer IP %s on $PRIMARY_IF with DNAT to %s:%d"
-# Keep running to maintain the iptables rules
-while true; do sleep 3600; done
-`, ip, ip, ip, ip, ip, port, clusterIP, port, ip, port, clusterIP, port, ip, port, clusterIP, port, ip, port, clusterIP, port, clusterIP, port, clusterIP, port, ip, clusterIP, port)
+	configScript := lbIPConfigScript(ip, clusterIP, port)
 
 	pod := &corev1.Pod{
 		ObjectMeta: metav1.ObjectMeta{
@@ -1220,8 +1409,11 @@ while true; do sleep 3600; done
 			Namespace: "kube-system",
 			Labels: map[string]string{
 				"app":                "cloudsigma-lb-ip",
-				"cloudsigma.com/ip":  ip,
-				"cloudsigma.com/svc": clusterIP,
+				"cloudsigma.com/ip":  ipLabelValue(ip),
+				"cloudsigma.com/svc": ipLabelValue(clusterIP),
+			},
+			Annotations: map[string]string{
+				AnnotationNodeBootID: targetNode.Status.NodeInfo.BootID,
 			},
 		},
 		Spec: corev1.PodSpec{
@@ -1237,6 +1429,15 @@ while true; do sleep 3600; done
 					SecurityContext: &corev1.SecurityContext{
 						Privileged: &privileged,
 					},
+					ReadinessProbe: &corev1.Probe{
+						ProbeHandler: corev1.ProbeHandler{
+							Exec: &corev1.ExecAction{
+								Command: []string{"/bin/sh", "-c", lbIPCheckScript(ip, clusterIP, port)},
+							},
+						},
+						PeriodSeconds:    10,
+						FailureThreshold: 3,
+					},
 				},
 			},
 			Tolerations: []corev1.Toleration{
@@ -1246,13 +1447,13 @@ while true; do sleep 3600; done
 	}
 
 	// Delete existing pod if any
-	_ = c.TenantClient.CoreV1().Pods("kube-system").Delete(ctx, podName, metav1.DeleteOptions{})
+	_ = c.tenantClient().CoreV1().Pods("kube-system").Delete(ctx, podName, metav1.DeleteOptions{})
 
 	// Wait briefly for deletion
-	time.Sleep(2 * time.Second)
+	clockOrDefault(c.Clock).Sleep(2 * time.Second)
 
 	// Create the pod
-	_, err = c.TenantClient.CoreV1().Pods("kube-system").Create(ctx, pod, metav1.CreateOptions{})
+	_, err = c.tenantClient().CoreV1().Pods("kube-system").Create(ctx, pod, metav1.CreateOptions{})
 	if err != nil {
 		return fmt.Errorf("failed to create LB IP config pod: %w", err)
 	}
@@ -1261,26 +1462,28 @@ while true; do sleep 3600; done
 	return nil
 }
 
-// updateServiceStatus updates the LoadBalancer service status with the assigned IP
-func (c *LoadBalancerController) updateServiceStatus(ctx context.Context, svc *corev1.Service, ip string) error {
-	if ip == "" {
+// updateServiceStatus updates the LoadBalancer service status with the assigned IPs (one per IP family)
+func (c *LoadBalancerController) updateServiceStatus(ctx context.Context, svc *corev1.Service, ips ...string) error {
+	if len(ips) == 0 {
 		klog.Warningf("Cannot update service %s/%s status: no IP assigned", svc.Namespace, svc.Name)
 		return nil
 	}
 
 	svcCopy := svc.DeepCopy()
-	svcCopy.Status.LoadBalancer.Ingress = []corev1.LoadBalancerIngress{
-		{IP: ip},
+	svcCopy.Status.LoadBalancer.Ingress = make([]corev1.LoadBalancerIngress, 0, len(ips))
+	for _, ip := range ips {
+		svcCopy.Status.LoadBalancer.Ingress = append(svcCopy.Status.LoadBalancer.Ingress, corev1.LoadBalancerIngress{IP: ip})
 	}
 
-	klog.Infof("Updating service %s/%s status with IP %s", svc.Namespace, svc.Name, ip)
-	_, err := c.TenantClient.CoreV1().Services(svc.Namespace).UpdateStatus(ctx, svcCopy, metav1.UpdateOptions{})
+	klog.Infof("Updating service %s/%s status with IP %s", svc.Namespace, svc.Name, strings.Join(ips, ","))
+	updated, err := c.tenantClient().CoreV1().Services(svc.Namespace).UpdateStatus(ctx, svcCopy, metav1.UpdateOptions{})
 	if err != nil {
 		klog.Errorf("Failed to update service %s/%s status: %v", svc.Namespace, svc.Name, err)
 		return fmt.Errorf("failed to update service status: %w", err)
 	}
+	updated.DeepCopyInto(svc)
 
-	klog.Infof("Successfully updated service %s/%s with LoadBalancer IP %s", svc.Namespace, svc.Name, ip)
+	klog.Infof("Successfully updated service %s/%s with LoadBalancer IP %s", svc.Namespace, svc.Name, strings.Join(ips, ","))
 	return nil
 }
 
@@ -1298,17 +1501,140 @@ func (c *LoadBalancerController) getHealthyNodes(nodes []corev1.Node) []corev1.N
 	return healthy
 }
 
-// getNodeUUID extracts the CloudSigma VM UUID from a node's providerID
-func (c *LoadBalancerController) getNodeUUID(node *corev1.Node) string {
-	if node.Spec.ProviderID == "" {
-		return ""
+// nodeLoadLocked counts the LoadBalancer IPs assigned to each server UUID (must hold mutex)
+func (c *LoadBalancerController) nodeLoadLocked() map[string]int {
+	load := make(map[string]int, len(c.ipAssignments))
+	for _, uuid := range c.ipAssignments {
+		load[uuid]++
 	}
-	// Format: cloudsigma://UUID (prefix is 13 characters)
-	const prefix = "cloudsigma://"
-	if strings.HasPrefix(node.Spec.ProviderID, prefix) {
-		return node.Spec.ProviderID[len(prefix):]
+	return load
+}
+
+// selectNode picks the node a LoadBalancer IP should be configured on. The preferred server
+// UUID (the placement recorded on the service) wins while that node is healthy; otherwise the
+// healthy node with the fewest assigned IPs is chosen, ties broken by node name so the choice
+// is deterministic. Nodes without a CloudSigma providerID are skipped.
+func (c *LoadBalancerController) selectNode(healthyNodes []corev1.Node, load map[string]int, preferred string) *corev1.Node {
+	var best *corev1.Node
+	bestLoad := 0
+	for i := range healthyNodes {
+		node := &healthyNodes[i]
+		uuid := c.getNodeUUID(node)
+		if uuid == "" {
+			continue
+		}
+		if uuid == preferred {
+			return node
+		}
+		if best == nil || load[uuid] < bestLoad || (load[uuid] == bestLoad && node.Name < best.Name) {
+			best = node
+			bestLoad = load[uuid]
+		}
 	}
-	return ""
+	return best
+}
+
+// persistNodeAssignment records the chosen node on the service so a restart keeps the placement.
+// Failure is logged only; the in-memory assignment is still used until the next restart.
+func (c *LoadBalancerController) persistNodeAssignment(ctx context.Context, svc *corev1.Service, serverUUID string) {
+	if svc.Annotations[AnnotationLBNode] == serverUUID {
+		return
+	}
+	if err := c.patchServiceAnnotation(ctx, svc, AnnotationLBNode, &serverUUID); err != nil {
+		klog.Warningf("Failed to record node %s on service %s/%s: %v", serverUUID, svc.Namespace, svc.Name, err)
+	}
+}
+
+// reportPoolExhausted tells the user why a service has no external IP: a Warning event and the
+// ip-pool-exhausted annotation. Both are only written when the pool usage changes, not every sync.
+func (c *LoadBalancerController) reportPoolExhausted(ctx context.Context, svc *corev1.Service, usage ipPoolUsage) {
+	message := usage.String()
+	if svc.Annotations[AnnotationIPPoolExhausted] == message {
+		return
+	}
+	c.recordEvent(svc, corev1.EventTypeWarning, EventReasonIPPoolExhausted,
+		"No free IP for this service: %s; release an IP or switch pools with the %s annotation", message, AnnotationIPPoolType)
+	if err := c.patchServiceAnnotation(ctx, svc, AnnotationIPPoolExhausted, &message); err != nil {
+		klog.Warningf("Failed to mark service %s/%s as waiting for an IP: %v", svc.Namespace, svc.Name, err)
+	}
+}
+
+// reportEndpoints sets the EndpointsReady condition on the service: False while its LoadBalancer
+// IP waits for endpoints, True once DNAT rules point at a pod. The status is only written, and an
+// event recorded, when the condition changes.
+func (c *LoadBalancerController) reportEndpoints(ctx context.Context, svc *corev1.Service, waiting bool) {
+	condition := metav1.Condition{
+		Type:               ConditionEndpointsReady,
+		Status:             metav1.ConditionTrue,
+		Reason:             EventReasonEndpointsReady,
+		Message:            "LoadBalancer traffic is forwarded to a service endpoint",
+		ObservedGeneration: svc.Generation,
+	}
+	if waiting {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = EventReasonWaitingForEndpoints
+		condition.Message = "Waiting for endpoints: the service has no ready pods to forward LoadBalancer traffic to"
+	}
+
+	previous := meta.FindStatusCondition(svc.Status.Conditions, ConditionEndpointsReady)
+	svcCopy := svc.DeepCopy()
+	if !meta.SetStatusCondition(&svcCopy.Status.Conditions, condition) {
+		return
+	}
+	if waiting {
+		c.recordEvent(svc, corev1.EventTypeWarning, EventReasonWaitingForEndpoints,
+			"Waiting for endpoints before configuring the LoadBalancer IP; retrying every %v",
+			intervalOrDefault(c.EndpointRetryInterval, DefaultEndpointRetryInterval))
+	} else if previous != nil && previous.Status == metav1.ConditionFalse {
+		c.recordEvent(svc, corev1.EventTypeNormal, EventReasonEndpointsReady, "Endpoints found, LoadBalancer IP configured")
+	}
+
+	updated, err := c.tenantClient().CoreV1().Services(svc.Namespace).UpdateStatus(ctx, svcCopy, metav1.UpdateOptions{})
+	if err != nil {
+		klog.Warningf("Failed to set %s on service %s/%s: %v", ConditionEndpointsReady, svc.Namespace, svc.Name, err)
+		return
+	}
+	updated.DeepCopyInto(svc)
+}
+
+// clearPoolExhausted removes the ip-pool-exhausted annotation once the service has an IP
+func (c *LoadBalancerController) clearPoolExhausted(ctx context.Context, svc *corev1.Service) {
+	if _, ok := svc.Annotations[AnnotationIPPoolExhausted]; !ok {
+		return
+	}
+	if err := c.patchServiceAnnotation(ctx, svc, AnnotationIPPoolExhausted, nil); err != nil {
+		klog.Warningf("Failed to clear %s on service %s/%s: %v", AnnotationIPPoolExhausted, svc.Namespace, svc.Name, err)
+	}
+}
+
+// patchServiceAnnotation sets (or, for a nil value, removes) one annotation on the service and
+// refreshes svc from the result so a later status update does not conflict
+func (c *LoadBalancerController) patchServiceAnnotation(ctx context.Context, svc *corev1.Service, key string, value *string) error {
+	patch, _ := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]*string{key: value},
+		},
+	})
+	updated, err := c.tenantClient().CoreV1().Services(svc.Namespace).Patch(ctx, svc.Name, types.MergePatchType, patch, metav1.PatchOptions{})
+	if err != nil {
+		return err
+	}
+	updated.DeepCopyInto(svc)
+	return nil
+}
+
+// recordEvent emits an event on the service if a recorder is configured
+func (c *LoadBalancerController) recordEvent(svc *corev1.Service, eventType, reason, messageFmt string, args ...interface{}) {
+	if c.Recorder != nil {
+		c.Recorder.Eventf(svc, eventType, reason, messageFmt, args...)
+	}
+}
+
+// getNodeUUID extracts the CloudSigma VM UUID from a node's providerID, or "" for nodes that
+// are not CloudSigma servers
+func (c *LoadBalancerController) getNodeUUID(node *corev1.Node) string {
+	uuid, _ := cloud.ParseProviderID(node.Spec.ProviderID)
+	return uuid
 }
 
 // isPoolIP checks if an IP is in any pool (static or dynamic)
@@ -0,0 +1,504 @@
+/*
+Copyright 2025 Kube-DC Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
+	testingclock "k8s.io/utils/clock/testing"
+)
+
+// lbTestNodeUUID is the server UUID of the i-th node of lbTestNodes
+func lbTestNodeUUID(i int) string {
+	return fmt.Sprintf("00000000-0000-4000-8000-%012d", i)
+}
+
+func lbTestNodes(n int) []corev1.Node {
+	nodes := make([]corev1.Node, 0, n)
+	for i := 0; i < n; i++ {
+		nodes = append(nodes, corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("node-%d", i)},
+			Spec:       corev1.NodeSpec{ProviderID: "cloudsigma://" + lbTestNodeUUID(i)},
+			Status: corev1.NodeStatus{Conditions: []corev1.NodeCondition{
+				{Type: corev1.NodeReady, Status: corev1.ConditionTrue},
+			}},
+		})
+	}
+	return nodes
+}
+
+func TestSelectNode_Distribution(t *testing.T) {
+	tests := []struct {
+		nodes, services int
+	}{
+		{nodes: 1, services: 4},
+		{nodes: 3, services: 9},
+		{nodes: 4, services: 10},
+		{nodes: 5, services: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%d nodes %d services", tt.nodes, tt.services), func(t *testing.T) {
+			c := &LoadBalancerController{}
+			nodes := lbTestNodes(tt.nodes)
+			load := map[string]int{}
+			for i := 0; i < tt.services; i++ {
+				node := c.selectNode(nodes, load, "")
+				if node == nil {
+					t.Fatal("selectNode() returned no node")
+				}
+				load[c.getNodeUUID(node)]++
+			}
+
+			lowest, highest := tt.services, 0
+			for i := range nodes {
+				n := load[c.getNodeUUID(&nodes[i])]
+				lowest = min(lowest, n)
+				highest = max(highest, n)
+			}
+			if highest-lowest > 1 {
+				t.Errorf("uneven distribution %v: spread %d, want at most 1", load, highest-lowest)
+			}
+		})
+	}
+}
+
+func TestSelectNode_Preference(t *testing.T) {
+	c := &LoadBalancerController{}
+	nodes := lbTestNodes(3)
+	nodes = append(nodes, corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-external"}})
+	load := map[string]int{lbTestNodeUUID(0): 0, lbTestNodeUUID(1): 5, lbTestNodeUUID(2): 1}
+
+	if got := c.selectNode(nodes, load, lbTestNodeUUID(1)); got == nil || got.Name != "node-1" {
+		t.Errorf("selectNode() with healthy preferred node = %v, want node-1", got)
+	}
+	if got := c.selectNode(nodes, load, "uuid-gone"); got == nil || got.Name != "node-0" {
+		t.Errorf("selectNode() with unhealthy preferred node = %v, want least-loaded node-0", got)
+	}
+	if got := c.selectNode(nodes[3:], load, ""); got != nil {
+		t.Errorf("selectNode() with no CloudSigma nodes = %v, want nil", got.Name)
+	}
+}
+
+func TestCheckIPFailover_Balanced(t *testing.T) {
+	nodes := lbTestNodes(3)
+	var objects []runtime.Object
+	for i := range nodes {
+		objects = append(objects, &nodes[i])
+	}
+	assignments := map[string]string{}
+	serviceIPs := map[string]string{}
+	for i := 0; i < 4; i++ {
+		ip := fmt.Sprintf("203.0.113.%d", i+10)
+		name := fmt.Sprintf("svc-%d", i)
+		objects = append(objects, &corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", Annotations: map[string]string{AnnotationLBNode: lbTestNodeUUID(0)}},
+			Spec:       corev1.ServiceSpec{Type: corev1.ServiceTypeLoadBalancer, Ports: []corev1.ServicePort{{Port: 80}}},
+		})
+		assignments[ip] = lbTestNodeUUID(0)
+		serviceIPs["default/"+name] = ip
+	}
+	cs := fake.NewSimpleClientset(objects...)
+
+	c := &LoadBalancerController{
+		TenantClient:    cs,
+		Clock:           testingclock.NewFakeClock(time.Now()),
+		ipAssignments:   assignments,
+		serviceIPs:      serviceIPs,
+		manualModeNodes: map[string]bool{lbTestNodeUUID(1): true, lbTestNodeUUID(2): true},
+	}
+
+	// node-0 went away; its four IPs must be split across the two remaining nodes
+	if err := c.checkIPFailover(context.Background(), nodes[1:]); err != nil {
+		t.Fatalf("checkIPFailover() error = %v", err)
+	}
+
+	load := c.nodeLoadLocked()
+	if load[lbTestNodeUUID(1)] != 2 || load[lbTestNodeUUID(2)] != 2 {
+		t.Errorf("load after failover = %v, want 2 IPs on each of node-1 and node-2", load)
+	}
+	want := map[string]string{
+		"203.0.113.10": lbTestNodeUUID(1), "203.0.113.11": lbTestNodeUUID(2),
+		"203.0.113.12": lbTestNodeUUID(1), "203.0.113.13": lbTestNodeUUID(2),
+	}
+	for ip, uuid := range want {
+		if c.ipAssignments[ip] != uuid {
+			t.Errorf("IP %s moved to %s, want %s", ip, c.ipAssignments[ip], uuid)
+		}
+	}
+
+	for key, ip := range serviceIPs {
+		svc, err := cs.CoreV1().Services("default").Get(context.Background(), key[len("default/"):], metav1.GetOptions{})
+		if err != nil {
+			t.Fatalf("Get(%s) error = %v", key, err)
+		}
+		if got := svc.Annotations[AnnotationLBNode]; got != want[ip] {
+			t.Errorf("service %s %s = %q, want %q", key, AnnotationLBNode, got, want[ip])
+		}
+	}
+}
+
+func TestCheckIPFailover_WaitsForEndpoints(t *testing.T) {
+	nodes := lbTestNodes(2)
+	newService := func(name string) *corev1.Service {
+		return &corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Spec: corev1.ServiceSpec{
+				Type:      corev1.ServiceTypeLoadBalancer,
+				ClusterIP: "10.96.0.10",
+				Ports:     []corev1.ServicePort{{Port: 80}},
+			},
+		}
+	}
+	// Only "backed" has a ready pod
+	endpoints := &corev1.Endpoints{
+		ObjectMeta: metav1.ObjectMeta{Name: "backed", Namespace: "default"},
+		Subsets:    []corev1.EndpointSubset{{Addresses: []corev1.EndpointAddress{{IP: "10.244.1.5"}}}},
+	}
+	cs := fake.NewSimpleClientset(&nodes[0], &nodes[1], newService("backed"), newService("idle"), endpoints)
+
+	c := &LoadBalancerController{
+		TenantClient: cs,
+		Clock:        testingclock.NewFakeClock(time.Now()),
+		ipAssignments: map[string]string{
+			"203.0.113.10": lbTestNodeUUID(0),
+			"203.0.113.11": lbTestNodeUUID(0),
+		},
+		serviceIPs:      map[string]string{"default/backed": "203.0.113.10", "default/idle": "203.0.113.11"},
+		manualModeNodes: map[string]bool{lbTestNodeUUID(1): true},
+	}
+	ctx := context.Background()
+
+	// node-0 went away; both IPs move to node-1
+	if err := c.checkIPFailover(ctx, nodes[1:]); err != nil {
+		t.Fatalf("checkIPFailover() error = %v", err)
+	}
+
+	pod, err := cs.CoreV1().Pods("kube-system").Get(ctx, lbIPPodName("203.0.113.10"), metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("config pod of the backed service not created: %v", err)
+	}
+	if got, want := pod.Labels["cloudsigma.com/svc"], ipLabelValue("10.244.1.5"); got != want {
+		t.Errorf("config pod backend label = %q, want the pod IP %q", got, want)
+	}
+
+	// Without endpoints the IP is not pointed at the ClusterIP, but waits for a pod
+	if _, err := cs.CoreV1().Pods("kube-system").Get(ctx, lbIPPodName("203.0.113.11"), metav1.GetOptions{}); err == nil {
+		t.Error("config pod of the service without endpoints created, want none")
+	}
+	if !c.isWaitingForEndpoints("default/idle", []corev1.IPFamily{corev1.IPv4Protocol}) {
+		t.Error("service without endpoints not tracked as waiting")
+	}
+	if c.isWaitingForEndpoints("default/backed", []corev1.IPFamily{corev1.IPv4Protocol}) {
+		t.Error("service with endpoints tracked as waiting")
+	}
+}
+
+func TestReconcileService_PoolExhaustion(t *testing.T) {
+	const usedIP = "203.0.113.10"
+	svc := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+		Spec:       corev1.ServiceSpec{Type: corev1.ServiceTypeLoadBalancer, Ports: []corev1.ServicePort{{Port: 80}}},
+	}
+	cs := fake.NewSimpleClientset(svc)
+	recorder := record.NewFakeRecorder(10)
+	c := &LoadBalancerController{
+		TenantClient:  cs,
+		Recorder:      recorder,
+		staticIPs:     []string{usedIP},
+		ipAssignments: map[string]string{usedIP: lbTestNodeUUID(0)},
+		serviceIPs:    map[string]string{"default/other": usedIP},
+	}
+	ctx := context.Background()
+	get := func() *corev1.Service {
+		t.Helper()
+		got, err := cs.CoreV1().Services("default").Get(ctx, "web", metav1.GetOptions{})
+		if err != nil {
+			t.Fatalf("Get() error = %v", err)
+		}
+		return got
+	}
+
+	if err := c.reconcileService(ctx, svc, lbTestNodes(1)); err != nil {
+		t.Fatalf("reconcileService() error = %v", err)
+	}
+	want := "static IP pool exhausted: 1 of 1 IPs in use"
+	if got := get().Annotations[AnnotationIPPoolExhausted]; got != want {
+		t.Errorf("%s = %q, want %q", AnnotationIPPoolExhausted, got, want)
+	}
+	select {
+	case event := <-recorder.Events:
+		if !strings.HasPrefix(event, "Warning "+EventReasonIPPoolExhausted) || !strings.Contains(event, want) {
+			t.Errorf("event = %q, want an %s warning naming the pool usage", event, EventReasonIPPoolExhausted)
+		}
+	default:
+		t.Error("no event recorded for the exhausted pool")
+	}
+
+	// A second sync with the pool still exhausted must not repeat the event
+	if err := c.reconcileService(ctx, get(), lbTestNodes(1)); err != nil {
+		t.Fatalf("reconcileService() error = %v", err)
+	}
+	if len(recorder.Events) != 0 {
+		t.Errorf("event repeated while pool usage is unchanged: %q", <-recorder.Events)
+	}
+
+	// The service gets an IP once one is free; the next sync clears the annotation
+	svc = get()
+	svc.Status.LoadBalancer.Ingress = []corev1.LoadBalancerIngress{{IP: usedIP}}
+	c.serviceIPs = map[string]string{"default/web": usedIP}
+	if err := c.reconcileService(ctx, svc, lbTestNodes(1)); err != nil {
+		t.Fatalf("reconcileService() error = %v", err)
+	}
+	if got, ok := get().Annotations[AnnotationIPPoolExhausted]; ok {
+		t.Errorf("%s = %q after the service got an IP, want it removed", AnnotationIPPoolExhausted, got)
+	}
+}
+
+func TestReconcileService_WaitsForEndpoints(t *testing.T) {
+	const ip = "203.0.113.10"
+	svc := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+		Spec: corev1.ServiceSpec{
+			Type:      corev1.ServiceTypeLoadBalancer,
+			ClusterIP: "10.96.0.10",
+			Ports:     []corev1.ServicePort{{Port: 80}},
+		},
+	}
+	nodes := lbTestNodes(1)
+	cs := fake.NewSimpleClientset(svc, &nodes[0])
+	recorder := record.NewFakeRecorder(10)
+	c := newTagsTestController(t, &fakeTagsAPI{}, testingclock.NewFakeClock(time.Now()))
+	c.TenantClient = cs
+	c.Recorder = recorder
+	c.staticIPs = []string{ip}
+	c.ipAssignments = map[string]string{}
+	c.serviceIPs = map[string]string{}
+	c.manualModeNodes = map[string]bool{lbTestNodeUUID(0): true}
+	ctx := context.Background()
+	get := func() *corev1.Service {
+		t.Helper()
+		got, err := cs.CoreV1().Services("default").Get(ctx, "web", metav1.GetOptions{})
+		if err != nil {
+			t.Fatalf("Get() error = %v", err)
+		}
+		return got
+	}
+	condition := func() *metav1.Condition {
+		return meta.FindStatusCondition(get().Status.Conditions, ConditionEndpointsReady)
+	}
+	events := func() []string {
+		var got []string
+		for len(recorder.Events) > 0 {
+			got = append(got, <-recorder.Events)
+		}
+		return got
+	}
+
+	// No endpoints: the IP is allocated and published, but no DNAT is configured
+	if err := c.reconcileService(ctx, svc, nodes); err != nil {
+		t.Fatalf("reconcileService() error = %v", err)
+	}
+	if ingress := get().Status.LoadBalancer.Ingress; len(ingress) != 1 || ingress[0].IP != ip {
+		t.Errorf("ingress = %v, want %s", ingress, ip)
+	}
+	if _, err := cs.CoreV1().Pods("kube-system").Get(ctx, lbIPPodName(ip), metav1.GetOptions{}); err == nil {
+		t.Error("LB IP config pod created without endpoints, want none")
+	}
+	if cond := condition(); cond == nil || cond.Status != metav1.ConditionFalse || cond.Reason != EventReasonWaitingForEndpoints {
+		t.Errorf("%s = %+v, want False/%s", ConditionEndpointsReady, cond, EventReasonWaitingForEndpoints)
+	}
+	if !c.hasWaitingForEndpoints() {
+		t.Error("service not tracked as waiting for endpoints")
+	}
+	if got := events(); len(got) != 2 || !strings.HasPrefix(got[1], "Warning "+EventReasonWaitingForEndpoints) {
+		t.Errorf("events = %q, want IPAllocated then a %s warning", got, EventReasonWaitingForEndpoints)
+	}
+
+	// A retry that still finds no endpoints changes nothing
+	if err := c.reconcileWaitingServices(ctx); err != nil {
+		t.Fatalf("reconcileWaitingServices() error = %v", err)
+	}
+	if got := events(); len(got) != 0 {
+		t.Errorf("events repeated while still waiting: %q", got)
+	}
+
+	// Once a pod backs the service, the retry points DNAT at it rather than the ClusterIP
+	endpoints := &corev1.Endpoints{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+		Subsets:    []corev1.EndpointSubset{{Addresses: []corev1.EndpointAddress{{IP: "10.244.1.5"}}}},
+	}
+	if _, err := cs.CoreV1().Endpoints("default").Create(ctx, endpoints, metav1.CreateOptions{}); err != nil {
+		t.Fatalf("Create() endpoints error = %v", err)
+	}
+	if err := c.reconcileWaitingServices(ctx); err != nil {
+		t.Fatalf("reconcileWaitingServices() error = %v", err)
+	}
+	pod, err := cs.CoreV1().Pods("kube-system").Get(ctx, lbIPPodName(ip), metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("LB IP config pod not created after endpoints appeared: %v", err)
+	}
+	if got, want := pod.Labels["cloudsigma.com/svc"], ipLabelValue("10.244.1.5"); got != want {
+		t.Errorf("config pod backend label = %q, want %q", got, want)
+	}
+	if cond := condition(); cond == nil || cond.Status != metav1.ConditionTrue {
+		t.Errorf("%s = %+v, want True", ConditionEndpointsReady, cond)
+	}
+	if c.hasWaitingForEndpoints() {
+		t.Error("service still tracked as waiting after endpoints appeared")
+	}
+	if got := events(); len(got) != 1 || !strings.HasPrefix(got[0], "Normal "+EventReasonEndpointsReady) {
+		t.Errorf("events = %q, want one %s event", got, EventReasonEndpointsReady)
+	}
+}
+
+func TestCleanupOrphanedIPPods(t *testing.T) {
+	lbPod := func(ip string) *corev1.Pod {
+		return &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+			Name:      lbIPPodName(ip),
+			Namespace: "kube-system",
+			Labels:    map[string]string{"app": "cloudsigma-lb-ip", "cloudsigma.com/ip": ipLabelValue(ip)},
+		}}
+	}
+	objects := []runtime.Object{
+		&corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+			Spec:       corev1.ServiceSpec{Type: corev1.ServiceTypeLoadBalancer},
+			Status: corev1.ServiceStatus{LoadBalancer: corev1.LoadBalancerStatus{Ingress: []corev1.LoadBalancerIngress{
+				{IP: "203.0.113.10"}, {IP: "2001:db8::10"},
+			}}},
+		},
+		// A service that is no longer a LoadBalancer does not keep its old IP's pod alive
+		&corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: "was-lb", Namespace: "default"},
+			Spec:       corev1.ServiceSpec{Type: corev1.ServiceTypeClusterIP},
+			Status: corev1.ServiceStatus{LoadBalancer: corev1.LoadBalancerStatus{Ingress: []corev1.LoadBalancerIngress{
+				{IP: "203.0.113.12"},
+			}}},
+		},
+		lbPod("203.0.113.10"),
+		lbPod("2001:db8::10"),
+		lbPod("203.0.113.11"),
+		lbPod("203.0.113.12"),
+		lbPod("2001:db8::11"),
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "coredns", Namespace: "kube-system", Labels: map[string]string{"app": "coredns"}}},
+	}
+	cs := fake.NewSimpleClientset(objects...)
+	c := &LoadBalancerController{TenantClient: cs}
+
+	if err := c.cleanupOrphanedIPPods(context.Background()); err != nil {
+		t.Fatalf("cleanupOrphanedIPPods() error = %v", err)
+	}
+
+	pods, err := cs.CoreV1().Pods("kube-system").List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	var remaining []string
+	for _, pod := range pods.Items {
+		remaining = append(remaining, pod.Name)
+	}
+	sort.Strings(remaining)
+	want := []string{"coredns", "lb-ip-2001-db8--10", "lb-ip-203-0-113-10"}
+	if strings.Join(remaining, ",") != strings.Join(want, ",") {
+		t.Errorf("remaining pods = %v, want %v", remaining, want)
+	}
+}
+
+func TestConfigureIPOnNode_ExactProviderID(t *testing.T) {
+	uuid := lbTestNodeUUID(1)
+	target := lbTestNodes(2)[1]
+	node := func(name, providerID string) *corev1.Node {
+		return &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: name}, Spec: corev1.NodeSpec{ProviderID: providerID}}
+	}
+
+	// In every case the nodes listed before the target have providerIDs ending like the server's
+	tests := []struct {
+		name     string
+		nodes    []*corev1.Node
+		server   string
+		wantNode string
+	}{
+		{
+			name: "other scheme and nested path",
+			nodes: []*corev1.Node{
+				node("node-other-scheme", "aws://"+uuid),
+				node("node-nested", "cloudsigma://zrh/"+uuid),
+				&target,
+			},
+			server:   uuid,
+			wantNode: "node-1",
+		},
+		{
+			name: "UUIDs sharing the last segment",
+			nodes: []*corev1.Node{
+				node("node-a", "cloudsigma://11111111-1111-4111-8111-0000000000ab"),
+				node("node-b", "cloudsigma://22222222-2222-4222-8222-0000000000ab"),
+			},
+			server:   "22222222-2222-4222-8222-0000000000ab",
+			wantNode: "node-b",
+		},
+		{
+			name: "UUID suffix",
+			nodes: []*corev1.Node{
+				node("node-a", "cloudsigma://11111111-1111-4111-8111-0000000000ab"),
+				node("node-b", "cloudsigma://22222222-2222-4222-8222-0000000000ab"),
+			},
+			server: "0000000000ab",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objects := make([]runtime.Object, 0, len(tt.nodes))
+			for _, n := range tt.nodes {
+				objects = append(objects, n)
+			}
+			cs := fake.NewSimpleClientset(objects...)
+			c := &LoadBalancerController{TenantClient: cs, Clock: testingclock.NewFakeClock(time.Now())}
+			ctx := context.Background()
+
+			err := c.configureIPOnNode(ctx, "203.0.113.10", tt.server, "10.96.0.10", 80)
+			if tt.wantNode == "" {
+				if err == nil || !strings.Contains(err.Error(), "no node has providerID cloudsigma://"+tt.server) {
+					t.Errorf("configureIPOnNode() = %v, want no-matching-node error", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("configureIPOnNode() = %v", err)
+			}
+			pod, err := cs.CoreV1().Pods("kube-system").Get(ctx, lbIPPodName("203.0.113.10"), metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("LB IP config pod not created: %v", err)
+			}
+			if pod.Spec.NodeName != tt.wantNode {
+				t.Errorf("LB IP config pod scheduled on %q, want %s", pod.Spec.NodeName, tt.wantNode)
+			}
+		})
+	}
+}
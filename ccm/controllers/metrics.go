@@ -0,0 +1,74 @@
+/*
+Copyright 2025 Kube-DC Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	// lbPoolIPs is the number of IPs discovered in each LB IP pool (static or dynamic)
+	lbPoolIPs = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "lb_pool_ips_total",
+		Help: "Number of IPs in the LoadBalancer IP pool, by pool (static or dynamic).",
+	}, []string{"pool"})
+
+	// lbIPsAssigned is the number of IPs currently assigned to LoadBalancer services
+	lbIPsAssigned = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "lb_ips_assigned",
+		Help: "Number of IPs assigned to LoadBalancer services.",
+	})
+
+	// lbFailovers counts IPs moved off an unhealthy node
+	lbFailovers = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "lb_failover_total",
+		Help: "Number of LoadBalancer IPs moved to another node because their node became unhealthy.",
+	})
+
+	// lbAssignmentFailures counts allocation attempts that found no free IP in the service's pool.
+	// A service waiting for an IP is counted again on every sync.
+	lbAssignmentFailures = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "lb_assignment_failures_total",
+		Help: "Number of LoadBalancer IP allocation attempts that found no free IP in the pool.",
+	})
+
+	// lbIPPurchases counts IP subscriptions purchased to grow the static pool
+	lbIPPurchases = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "lb_ip_purchases_total",
+		Help: "Number of IP subscriptions purchased for the static LoadBalancer IP pool.",
+	})
+
+	// lbIPReleases counts purchased IPs released after staying unused for the release cooldown
+	lbIPReleases = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "lb_ip_releases_total",
+		Help: "Number of purchased LoadBalancer IPs whose subscription renewal was stopped because they were unused.",
+	})
+)
+
+func init() {
+	prometheus.MustRegister(lbPoolIPs, lbIPsAssigned, lbFailovers, lbAssignmentFailures, lbIPPurchases, lbIPReleases)
+}
+
+// updateIPMetrics sets the pool and assignment gauges from the controller's current state
+func (c *LoadBalancerController) updateIPMetrics() {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	lbPoolIPs.WithLabelValues(IPPoolStatic).Set(float64(len(c.staticIPs)))
+	lbPoolIPs.WithLabelValues(IPPoolDynamic).Set(float64(len(c.dynamicIPs)))
+	lbIPsAssigned.Set(float64(len(c.serviceIPs)))
+}
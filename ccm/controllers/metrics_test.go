@@ -0,0 +1,98 @@
+/*
+Copyright 2025 Kube-DC Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	testingclock "k8s.io/utils/clock/testing"
+)
+
+func TestSyncLoadBalancers_Metrics(t *testing.T) {
+	const ip = "203.0.113.10"
+	nodes := lbTestNodes(2)
+	objects := []runtime.Object{&nodes[0], &nodes[1]}
+	for _, name := range []string{"api", "web"} {
+		objects = append(objects, &corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Spec:       corev1.ServiceSpec{Type: corev1.ServiceTypeLoadBalancer, Ports: []corev1.ServicePort{{Port: 80}}},
+		})
+	}
+	cs := fake.NewSimpleClientset(objects...)
+	c := newTagsTestController(t, &fakeTagsAPI{}, testingclock.NewFakeClock(time.Now()))
+	c.TenantClient = cs
+	c.staticIPs = []string{ip}
+	c.dynamicIPs = []string{"203.0.113.20", "203.0.113.21"}
+	c.ipAssignments = map[string]string{}
+	c.serviceIPs = map[string]string{}
+	c.manualModeNodes = map[string]bool{lbTestNodeUUID(0): true, lbTestNodeUUID(1): true}
+	ctx := context.Background()
+
+	failovers := testutil.ToFloat64(lbFailovers)
+	failures := testutil.ToFloat64(lbAssignmentFailures)
+
+	// One static IP for two services: one gets it, the other finds the pool exhausted
+	if err := c.syncLoadBalancers(ctx); err != nil {
+		t.Fatalf("syncLoadBalancers() error = %v", err)
+	}
+	if got := testutil.ToFloat64(lbPoolIPs.WithLabelValues(IPPoolStatic)); got != 1 {
+		t.Errorf("lb_pool_ips_total{pool=static} = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(lbPoolIPs.WithLabelValues(IPPoolDynamic)); got != 2 {
+		t.Errorf("lb_pool_ips_total{pool=dynamic} = %v, want 2", got)
+	}
+	if got := testutil.ToFloat64(lbIPsAssigned); got != 1 {
+		t.Errorf("lb_ips_assigned = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(lbAssignmentFailures) - failures; got != 1 {
+		t.Errorf("lb_assignment_failures_total increased by %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(lbFailovers) - failovers; got != 0 {
+		t.Errorf("lb_failover_total increased by %v without a node failure, want 0", got)
+	}
+
+	// The node holding the IP goes NotReady; the next sync moves the IP to the other node
+	holder := c.ipAssignments[ip]
+	for i := range nodes {
+		if c.getNodeUUID(&nodes[i]) != holder {
+			continue
+		}
+		nodes[i].Status.Conditions[0].Status = corev1.ConditionFalse
+		if _, err := cs.CoreV1().Nodes().UpdateStatus(ctx, &nodes[i], metav1.UpdateOptions{}); err != nil {
+			t.Fatalf("UpdateStatus() error = %v", err)
+		}
+	}
+	if err := c.syncLoadBalancers(ctx); err != nil {
+		t.Fatalf("syncLoadBalancers() error = %v", err)
+	}
+	if c.ipAssignments[ip] == holder {
+		t.Fatalf("IP %s still on unhealthy node %s", ip, holder)
+	}
+	if got := testutil.ToFloat64(lbFailovers) - failovers; got != 1 {
+		t.Errorf("lb_failover_total increased by %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(lbIPsAssigned); got != 1 {
+		t.Errorf("lb_ips_assigned after failover = %v, want 1", got)
+	}
+}
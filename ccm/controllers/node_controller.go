@@ -18,6 +18,7 @@ package controllers
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"strings"
 	"sync"
@@ -27,11 +28,16 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/util/retry"
 	"k8s.io/klog/v2"
+	"k8s.io/utils/clock"
 
 	"github.com/kube-dc/cluster-api-provider-cloudsigma/pkg/auth"
+	"github.com/kube-dc/cluster-api-provider-cloudsigma/pkg/cloud"
+	"github.com/kube-dc/cluster-api-provider-cloudsigma/pkg/regions"
 )
 
 // NodeReconciler reconciles nodes in the tenant cluster
@@ -50,26 +56,43 @@ type NodeReconciler struct {
 	LegacyCredentialsEnabled bool
 	CloudSigmaUsername       string
 	CloudSigmaPassword       string
+	// SyncInterval is how often nodes are synced (default: DefaultNodeSyncInterval)
+	SyncInterval time.Duration
+	// Clock drives the sync loop (default: the real clock)
+	Clock clock.WithTicker
+	// HealthCheckInterval is how often the tenant API server is probed (default: DefaultTenantHealthCheckInterval)
+	HealthCheckInterval time.Duration
+	// NewTenantClient builds the tenant client from the kubeconfig at path (default: newTenantClient)
+	NewTenantClient func(path string) (kubernetes.Interface, error)
+	// Paused reports whether the managing Cluster is paused; nodes are not synced while it is (optional)
+	Paused PausedFunc
 
 	tenantClient       kubernetes.Interface
 	cloudsigmaClient   *cloudsigma.Client
 	clientMutex        sync.RWMutex
 	staleNodeFailures  map[string]int // tracks consecutive 403 failures per node
+
+	// The node sync loop, restarted when the tenant client is rebuilt
+	stopSync context.CancelFunc
+	syncDone chan struct{}
+	// healthFailures counts consecutive failed tenant health checks
+	healthFailures int
 }
 
+// TenantHealthFailureThreshold is how many consecutive tenant health checks must fail before the
+// tenant client is rebuilt from the kubeconfig
+const TenantHealthFailureThreshold = 3
+
+// tenantHealthCheckTimeout bounds a single tenant health check
+const tenantHealthCheckTimeout = 10 * time.Second
+
 // Start initializes the tenant client and starts the node sync loop
 func (r *NodeReconciler) Start(ctx context.Context) error {
-	// Load tenant cluster kubeconfig
-	config, err := clientcmd.BuildConfigFromFlags("", r.TenantKubeconfig)
-	if err != nil {
-		return fmt.Errorf("failed to load tenant kubeconfig: %w", err)
-	}
-
-	// Create tenant client
-	r.tenantClient, err = kubernetes.NewForConfig(config)
+	client, err := r.buildTenantClient()
 	if err != nil {
-		return fmt.Errorf("failed to create tenant client: %w", err)
+		return err
 	}
+	r.tenantClient = client
 
 	klog.Infof("Connected to tenant cluster: %s", r.ClusterName)
 
@@ -78,12 +101,99 @@ func (r *NodeReconciler) Start(ctx context.Context) error {
 		klog.Warningf("Initial CloudSigma client creation failed: %v", err)
 	}
 
-	// Start node sync loop
-	go r.syncLoop(ctx)
+	// Start node sync loop, and the health loop that rebuilds the tenant client when it stops working
+	r.startSyncLoop(ctx)
+	go r.healthLoop(ctx)
 
 	return nil
 }
 
+// buildTenantClient creates the tenant client from the current kubeconfig
+func (r *NodeReconciler) buildTenantClient() (kubernetes.Interface, error) {
+	build := r.NewTenantClient
+	if build == nil {
+		build = newTenantClient
+	}
+	return build(r.TenantKubeconfig)
+}
+
+// newTenantClient creates a client from the kubeconfig file at path
+func newTenantClient(path string) (kubernetes.Interface, error) {
+	// Load tenant cluster kubeconfig
+	config, err := clientcmd.BuildConfigFromFlags("", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load tenant kubeconfig: %w", err)
+	}
+
+	// Create tenant client
+	client, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create tenant client: %w", err)
+	}
+	return client, nil
+}
+
+// startSyncLoop runs the node sync loop until ctx is done or it is stopped for a client rebuild
+func (r *NodeReconciler) startSyncLoop(ctx context.Context) {
+	syncCtx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	r.stopSync, r.syncDone = cancel, done
+	go func() {
+		defer close(done)
+		r.syncLoop(syncCtx)
+	}()
+}
+
+// healthLoop periodically checks that the tenant API server is reachable
+func (r *NodeReconciler) healthLoop(ctx context.Context) {
+	ticker := clockOrDefault(r.Clock).NewTicker(intervalOrDefault(r.HealthCheckInterval, DefaultTenantHealthCheckInterval))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C():
+			r.checkTenantHealth(ctx)
+		}
+	}
+}
+
+// checkTenantHealth probes the tenant API server with a lightweight request. After
+// TenantHealthFailureThreshold consecutive failures, e.g. because the kubeconfig was rotated or the
+// control plane moved, the tenant client is rebuilt from the current kubeconfig and the node sync
+// loop restarted with it.
+func (r *NodeReconciler) checkTenantHealth(ctx context.Context) {
+	probeCtx, cancel := context.WithTimeout(ctx, tenantHealthCheckTimeout)
+	_, err := r.GetTenantClient().CoreV1().Namespaces().Get(probeCtx, metav1.NamespaceSystem, metav1.GetOptions{})
+	cancel()
+	if err == nil || errors.IsNotFound(err) {
+		r.healthFailures = 0
+		return
+	}
+
+	r.healthFailures++
+	klog.Warningf("Tenant cluster health check failed (%d/%d): %v", r.healthFailures, TenantHealthFailureThreshold, err)
+	if r.healthFailures < TenantHealthFailureThreshold {
+		return
+	}
+
+	client, err := r.buildTenantClient()
+	if err != nil {
+		klog.Errorf("Failed to rebuild tenant client: %v", err)
+		return
+	}
+
+	r.stopSync()
+	<-r.syncDone
+	r.clientMutex.Lock()
+	r.tenantClient = client
+	r.clientMutex.Unlock()
+	r.healthFailures = 0
+	r.startSyncLoop(ctx)
+	klog.Infof("Rebuilt tenant client for cluster %s from %s and restarted node sync", r.ClusterName, r.TenantKubeconfig)
+}
+
 // refreshCloudSigmaClient creates or refreshes the CloudSigma client
 // For impersonation, this gets a fresh token (cached by ImpersonationClient)
 func (r *NodeReconciler) refreshCloudSigmaClient(ctx context.Context) error {
@@ -103,8 +213,7 @@ func (r *NodeReconciler) refreshCloudSigmaClient(ctx context.Context) error {
 			return fmt.Errorf("failed to get impersonated token: %w", err)
 		}
 		cred := cloudsigma.NewTokenCredentialsProvider(token)
-		directLocation := "direct." + region
-		r.cloudsigmaClient = cloudsigma.NewClient(cred, cloudsigma.WithLocation(directLocation))
+		r.cloudsigmaClient = regions.NewSDKClient(cred, regions.Lookup(region).DirectHost)
 		klog.V(2).Infof("CloudSigma client refreshed with impersonation for region: %s (using direct endpoint)", region)
 		return nil
 	}
@@ -114,7 +223,7 @@ func (r *NodeReconciler) refreshCloudSigmaClient(ctx context.Context) error {
 		if r.cloudsigmaClient == nil {
 			klog.Info("Using legacy username/password credentials (explicitly enabled)")
 			cred := cloudsigma.NewUsernamePasswordCredentialsProvider(r.CloudSigmaUsername, r.CloudSigmaPassword)
-			r.cloudsigmaClient = cloudsigma.NewClient(cred, cloudsigma.WithLocation(region))
+			r.cloudsigmaClient = regions.NewSDKClient(cred, regions.Lookup(region).APIHost)
 			klog.Infof("CloudSigma client initialized for region: %s", region)
 		}
 		return nil
@@ -138,7 +247,7 @@ func (r *NodeReconciler) syncLoop(ctx context.Context) {
 		klog.Errorf("Initial node sync failed: %v", err)
 	}
 
-	ticker := time.NewTicker(30 * time.Second)
+	ticker := clockOrDefault(r.Clock).NewTicker(intervalOrDefault(r.SyncInterval, DefaultNodeSyncInterval))
 	defer ticker.Stop()
 
 	for {
@@ -146,7 +255,7 @@ func (r *NodeReconciler) syncLoop(ctx context.Context) {
 		case <-ctx.Done():
 			klog.Info("Node sync loop stopped")
 			return
-		case <-ticker.C:
+		case <-ticker.C():
 			if err := r.syncNodes(ctx); err != nil {
 				klog.Errorf("Node sync failed: %v", err)
 			}
@@ -156,6 +265,11 @@ func (r *NodeReconciler) syncLoop(ctx context.Context) {
 
 // syncNodes syncs all nodes - removes cloud-provider taint and updates addresses
 func (r *NodeReconciler) syncNodes(ctx context.Context) error {
+	// Taints, labels and addresses are left alone while the managing Cluster is paused
+	if isPaused(ctx, r.Paused, "node sync") {
+		return nil
+	}
+
 	// Refresh CloudSigma client (gets fresh token if using impersonation)
 	if err := r.refreshCloudSigmaClient(ctx); err != nil {
 		klog.Errorf("Failed to refresh CloudSigma client: %v", err)
@@ -173,41 +287,46 @@ func (r *NodeReconciler) syncNodes(ctx context.Context) error {
 		}
 	}
 
+	// Apply the labels and annotations the management cluster stashed for each node
+	r.syncNodeMetadata(ctx, nodes.Items)
+
 	return nil
 }
 
-// reconcileNode handles a single node - removes initialization taint and sets addresses
+// reconcileNode handles a single node - backfills a missing providerID, removes initialization
+// taint, adds required labels and sets addresses. Nodes that need none of these are not written to.
 func (r *NodeReconciler) reconcileNode(ctx context.Context, node *corev1.Node) error {
-	// Check if node has the cloud-provider initialization taint
-	hasTaint := false
-	var newTaints []corev1.Taint
-	for _, taint := range node.Spec.Taints {
-		if taint.Key == "node.cloudprovider.kubernetes.io/uninitialized" ||
-			taint.Key == "node.cluster.x-k8s.io/uninitialized" {
-			hasTaint = true
-			continue
+	// Nodes registered without kubelet --provider-id are matched to their server first
+	if node.Spec.ProviderID == "" {
+		if err := r.backfillProviderID(ctx, node); err != nil {
+			klog.Errorf("Failed to backfill providerID for node %s: %v", node.Name, err)
 		}
-		newTaints = append(newTaints, taint)
 	}
 
+	hasTaint := hasInitTaint(node)
+	labels := r.requiredNodeLabels()
+	needsLabels := len(missingLabels(node, labels)) > 0
+
 	// Check if node needs address update
 	needsAddressUpdate := !r.hasIPAddress(node)
 
-	if !hasTaint && !needsAddressUpdate {
+	if !hasTaint && !needsLabels && !needsAddressUpdate {
 		// Node already initialized and has addresses
 		return nil
 	}
 
-	klog.Infof("Reconciling node %s (hasTaint=%v, needsAddressUpdate=%v)", node.Name, hasTaint, needsAddressUpdate)
-
-	nodeCopy := node.DeepCopy()
+	klog.Infof("Reconciling node %s (hasTaint=%v, needsLabels=%v, needsAddressUpdate=%v)", node.Name, hasTaint, needsLabels, needsAddressUpdate)
 
 	// Get node addresses from providerID (CloudSigma VM UUID)
-	if node.Spec.ProviderID != "" && r.cloudsigmaClient != nil && needsAddressUpdate {
-		vmUUID := strings.TrimPrefix(node.Spec.ProviderID, "cloudsigma://")
+	var addresses []corev1.NodeAddress
+	vmUUID, isCloudSigmaNode := cloud.ParseProviderID(node.Spec.ProviderID)
+	if node.Spec.ProviderID != "" && !isCloudSigmaNode {
+		klog.Warningf("Node %s has providerID %q, which is not cloudsigma://<uuid>; not fetching its addresses", node.Name, node.Spec.ProviderID)
+	}
+	if isCloudSigmaNode && r.cloudsigmaClient != nil && needsAddressUpdate {
 		klog.V(2).Infof("Fetching VM details for node %s (UUID: %s)", node.Name, vmUUID)
 
-		addresses, err := r.getVMAddresses(ctx, vmUUID)
+		vmAddresses, err := r.getVMAddresses(ctx, vmUUID)
 		if err != nil {
 			klog.Errorf("Failed to get VM addresses for %s: %v", vmUUID, err)
 
@@ -216,42 +335,120 @@ func (r *NodeReconciler) reconcileNode(ctx context.Context, node *corev1.Node) e
 			if strings.Contains(errStr, "403") || strings.Contains(errStr, "permission") {
 				return r.handleStaleNode(ctx, node, vmUUID, err)
 			}
-		} else if len(addresses) > 0 {
-			nodeCopy.Status.Addresses = addresses
+		} else if len(vmAddresses) > 0 {
+			addresses = vmAddresses
 			klog.Infof("Setting addresses for node %s: %v", node.Name, addresses)
 		}
 	}
 
-	// Remove the initialization taint if present
-	if hasTaint {
-		nodeCopy.Spec.Taints = newTaints
+	// Remove the initialization taint and add missing labels in a single patch
+	if hasTaint || needsLabels {
+		if err := r.initializeNode(ctx, node, labels); err != nil {
+			return fmt.Errorf("failed to patch node: %w", err)
+		}
+		klog.Infof("Initialized node %s (removed taint=%v, added labels=%v)", node.Name, hasTaint, needsLabels)
 	}
 
-	// Update node spec (taints)
-	if hasTaint {
-		_, err := r.tenantClient.CoreV1().Nodes().Update(ctx, nodeCopy, metav1.UpdateOptions{})
+	// Update node status (addresses). The merge patch replaces the address list as a whole, so it
+	// cannot conflict with other writers of the node.
+	if len(addresses) > 0 {
+		patch, _ := json.Marshal(map[string]interface{}{
+			"status": map[string]interface{}{"addresses": addresses},
+		})
+		_, err := r.tenantClient.CoreV1().Nodes().Patch(ctx, node.Name, types.MergePatchType, patch, metav1.PatchOptions{}, "status")
 		if err != nil {
-			if errors.IsConflict(err) {
-				return nil
-			}
-			return fmt.Errorf("failed to update node spec: %w", err)
+			return fmt.Errorf("failed to patch node status: %w", err)
 		}
-		klog.Infof("Removed initialization taint from node %s", node.Name)
+		klog.Infof("Updated addresses for node %s", node.Name)
 	}
 
-	// Update node status (addresses)
-	if needsAddressUpdate && len(nodeCopy.Status.Addresses) > 0 {
-		_, err := r.tenantClient.CoreV1().Nodes().UpdateStatus(ctx, nodeCopy, metav1.UpdateOptions{})
-		if err != nil {
-			if errors.IsConflict(err) {
-				return nil
+	return nil
+}
+
+// initializeNode removes the initialization taints from node and adds the labels it is missing.
+// Taints can only be replaced as a whole, so the patch carries the resourceVersion to not drop a
+// taint added concurrently; on conflict it is recomputed against the latest node and retried.
+func (r *NodeReconciler) initializeNode(ctx context.Context, node *corev1.Node, labels map[string]string) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		patch := nodeInitPatch(node, labels)
+		if patch == nil {
+			return nil
+		}
+		_, err := r.tenantClient.CoreV1().Nodes().Patch(ctx, node.Name, types.MergePatchType, patch, metav1.PatchOptions{})
+		if errors.IsConflict(err) {
+			latest, getErr := r.tenantClient.CoreV1().Nodes().Get(ctx, node.Name, metav1.GetOptions{})
+			if getErr != nil {
+				return getErr
 			}
-			return fmt.Errorf("failed to update node status: %w", err)
+			node = latest
 		}
-		klog.Infof("Updated addresses for node %s", node.Name)
+		return err
+	})
+}
+
+// nodeInitPatch returns the merge patch removing the initialization taints from node and adding
+// the labels it is missing, or nil if it needs neither
+func nodeInitPatch(node *corev1.Node, labels map[string]string) []byte {
+	missing := missingLabels(node, labels)
+	if !hasInitTaint(node) && len(missing) == 0 {
+		return nil
 	}
+	metadata := map[string]interface{}{"resourceVersion": node.ResourceVersion}
+	if len(missing) > 0 {
+		metadata["labels"] = missing
+	}
+	patch := map[string]interface{}{"metadata": metadata}
+	if hasInitTaint(node) {
+		taints := []corev1.Taint{}
+		for _, taint := range node.Spec.Taints {
+			if !isInitTaint(taint) {
+				taints = append(taints, taint)
+			}
+		}
+		patch["spec"] = map[string]interface{}{"taints": taints}
+	}
+	data, _ := json.Marshal(patch)
+	return data
+}
 
-	return nil
+// isInitTaint reports whether taint keeps a node uninitialized until the CCM has processed it
+func isInitTaint(taint corev1.Taint) bool {
+	return taint.Key == "node.cloudprovider.kubernetes.io/uninitialized" ||
+		taint.Key == "node.cluster.x-k8s.io/uninitialized"
+}
+
+// hasInitTaint reports whether node still carries an initialization taint
+func hasInitTaint(node *corev1.Node) bool {
+	for _, taint := range node.Spec.Taints {
+		if isInitTaint(taint) {
+			return true
+		}
+	}
+	return false
+}
+
+// requiredNodeLabels returns the labels every node of the cluster must carry
+func (r *NodeReconciler) requiredNodeLabels() map[string]string {
+	if r.CloudSigmaRegion == "" {
+		return nil
+	}
+	return map[string]string{corev1.LabelTopologyRegion: r.CloudSigmaRegion}
+}
+
+// missingLabels returns the labels node lacks. A label the node already has is left as it is,
+// even with another value, since it may have been set by the kubelet or an administrator.
+func missingLabels(node *corev1.Node, labels map[string]string) map[string]string {
+	var missing map[string]string
+	for key, value := range labels {
+		if _, ok := node.Labels[key]; ok {
+			continue
+		}
+		if missing == nil {
+			missing = make(map[string]string)
+		}
+		missing[key] = value
+	}
+	return missing
 }
 
 // handleStaleNode deletes a node from the tenant cluster when its VM is inaccessible (403).
@@ -294,6 +491,8 @@ func (r *NodeReconciler) handleStaleNode(ctx context.Context, node *corev1.Node,
 
 // GetTenantClient returns the tenant cluster Kubernetes client
 func (r *NodeReconciler) GetTenantClient() kubernetes.Interface {
+	r.clientMutex.RLock()
+	defer r.clientMutex.RUnlock()
 	return r.tenantClient
 }
 
@@ -0,0 +1,356 @@
+/*
+Copyright 2025 Kube-DC Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/cloudsigma/cloudsigma-sdk-go/cloudsigma"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"github.com/kube-dc/cluster-api-provider-cloudsigma/pkg/cloud"
+	cloudfake "github.com/kube-dc/cluster-api-provider-cloudsigma/pkg/cloud/fake"
+)
+
+// testNode returns a node with an internal IP carrying labels and taints
+func testNode(labels map[string]string, taints ...corev1.Taint) *corev1.Node {
+	return &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-1", Labels: labels},
+		Spec:       corev1.NodeSpec{Taints: taints},
+		Status: corev1.NodeStatus{
+			Addresses: []corev1.NodeAddress{{Type: corev1.NodeInternalIP, Address: "10.0.0.1"}},
+		},
+	}
+}
+
+var (
+	uninitializedTaint = corev1.Taint{Key: "node.cloudprovider.kubernetes.io/uninitialized", Value: "true", Effect: corev1.TaintEffectNoSchedule}
+	otherTaint         = corev1.Taint{Key: "dedicated", Value: "db", Effect: corev1.TaintEffectNoSchedule}
+)
+
+// nodeWrites returns the write actions recorded on nodes
+func nodeWrites(cs *fake.Clientset) []k8stesting.Action {
+	var writes []k8stesting.Action
+	for _, action := range cs.Actions() {
+		if action.GetResource().Resource == "nodes" && action.GetVerb() != "get" && action.GetVerb() != "list" {
+			writes = append(writes, action)
+		}
+	}
+	return writes
+}
+
+func TestReconcileNode(t *testing.T) {
+	region := map[string]string{corev1.LabelTopologyRegion: "zrh"}
+	tests := []struct {
+		name       string
+		node       *corev1.Node
+		wantWrite  bool
+		wantTaints []corev1.Taint
+		wantRegion string
+	}{
+		{name: "initialized", node: testNode(region, otherTaint), wantTaints: []corev1.Taint{otherTaint}},
+		{name: "uninitialized", node: testNode(region, otherTaint, uninitializedTaint), wantWrite: true, wantTaints: []corev1.Taint{otherTaint}},
+		{name: "missing region label", node: testNode(nil), wantWrite: true},
+		{name: "other region label is kept", node: testNode(map[string]string{corev1.LabelTopologyRegion: "sjc"})},
+		{name: "other region label is kept on initialization", node: testNode(map[string]string{corev1.LabelTopologyRegion: "sjc"}, uninitializedTaint), wantWrite: true, wantRegion: "sjc"},
+		{name: "taint and label", node: testNode(nil, uninitializedTaint), wantWrite: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			cs := fake.NewSimpleClientset(tt.node)
+			r := &NodeReconciler{CloudSigmaRegion: "zrh", tenantClient: cs}
+
+			if err := r.reconcileNode(ctx, tt.node); err != nil {
+				t.Fatalf("reconcileNode() error = %v", err)
+			}
+
+			writes := nodeWrites(cs)
+			if !tt.wantWrite {
+				if len(writes) != 0 {
+					t.Fatalf("reconcileNode() wrote an initialized node: %v", writes)
+				}
+				return
+			}
+			if len(writes) != 1 {
+				t.Fatalf("reconcileNode() made %d writes, want 1 patch", len(writes))
+			}
+			patch, ok := writes[0].(k8stesting.PatchAction)
+			if !ok || patch.GetPatchType() != types.MergePatchType {
+				t.Fatalf("reconcileNode() write = %v, want a merge patch", writes[0])
+			}
+
+			node, err := cs.CoreV1().Nodes().Get(ctx, tt.node.Name, metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("Get() error = %v", err)
+			}
+			if hasInitTaint(node) || len(node.Spec.Taints) != len(tt.wantTaints) {
+				t.Errorf("taints = %v, want %v", node.Spec.Taints, tt.wantTaints)
+			}
+			wantRegion := tt.wantRegion
+			if wantRegion == "" {
+				wantRegion = "zrh"
+			}
+			if got := node.Labels[corev1.LabelTopologyRegion]; got != wantRegion {
+				t.Errorf("region label = %q, want %s", got, wantRegion)
+			}
+		})
+	}
+}
+
+func TestReconcileNode_RetriesConflict(t *testing.T) {
+	ctx := context.Background()
+	node := testNode(nil, uninitializedTaint)
+	cs := fake.NewSimpleClientset(node)
+	conflicted := false
+	cs.PrependReactor("patch", "nodes", func(k8stesting.Action) (bool, runtime.Object, error) {
+		if conflicted {
+			return false, nil, nil
+		}
+		conflicted = true
+		// Another writer added a taint in the meantime
+		latest, _ := cs.Tracker().Get(schema.GroupVersionResource{Version: "v1", Resource: "nodes"}, "", node.Name)
+		latestNode := latest.(*corev1.Node).DeepCopy()
+		latestNode.Spec.Taints = append(latestNode.Spec.Taints, otherTaint)
+		_ = cs.Tracker().Update(schema.GroupVersionResource{Version: "v1", Resource: "nodes"}, latestNode, "")
+		return true, nil, errors.NewConflict(schema.GroupResource{Resource: "nodes"}, node.Name, nil)
+	})
+	r := &NodeReconciler{CloudSigmaRegion: "zrh", tenantClient: cs}
+
+	if err := r.reconcileNode(ctx, node); err != nil {
+		t.Fatalf("reconcileNode() error = %v", err)
+	}
+	got, err := cs.CoreV1().Nodes().Get(ctx, node.Name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if hasInitTaint(got) || len(got.Spec.Taints) != 1 || got.Spec.Taints[0].Key != otherTaint.Key {
+		t.Errorf("taints = %v, want only the concurrently added %s", got.Spec.Taints, otherTaint.Key)
+	}
+	if got.Labels[corev1.LabelTopologyRegion] != "zrh" {
+		t.Errorf("labels = %v, want the region label", got.Labels)
+	}
+}
+
+// createTestServer creates a server on the fake CloudSigma API, with a static NIC for each IP
+func createTestServer(t *testing.T, client *cloudsigma.Client, name string, ips ...string) string {
+	t.Helper()
+	server := cloudsigma.Server{Name: name, CPU: 2000, Memory: 2 << 30, VNCPassword: "secret"}
+	for _, ip := range ips {
+		server.NICs = append(server.NICs, cloudsigma.ServerNIC{
+			IP4Configuration: &cloudsigma.ServerIPConfiguration{Type: "static", IPAddress: &cloudsigma.IP{UUID: ip}},
+		})
+	}
+	created, _, err := client.Servers.Create(context.Background(), &cloudsigma.ServerCreateRequest{Servers: []cloudsigma.Server{server}})
+	if err != nil {
+		t.Fatalf("Servers.Create(%s) error = %v", name, err)
+	}
+	return created[0].UUID
+}
+
+func TestReconcileNode_BackfillsProviderID(t *testing.T) {
+	tests := []struct {
+		name string
+		// servers creates the servers and returns the UUID the node should be matched to, if any
+		servers func(t *testing.T, api *cloudfake.Server, client *cloudsigma.Client) string
+	}{
+		{
+			name: "name match",
+			servers: func(t *testing.T, _ *cloudfake.Server, client *cloudsigma.Client) string {
+				createTestServer(t, client, "node-2")
+				return createTestServer(t, client, "node-1")
+			},
+		},
+		{
+			name: "internal IP match",
+			servers: func(t *testing.T, api *cloudfake.Server, client *cloudsigma.Client) string {
+				api.AddIP(cloud.IPDetail{UUID: "10.0.0.1"})
+				api.AddIP(cloud.IPDetail{UUID: "10.0.0.2"})
+				createTestServer(t, client, "vm-b", "10.0.0.2")
+				return createTestServer(t, client, "vm-a", "10.0.0.1")
+			},
+		},
+		{
+			name: "ambiguous name",
+			servers: func(t *testing.T, _ *cloudfake.Server, client *cloudsigma.Client) string {
+				createTestServer(t, client, "node-1")
+				createTestServer(t, client, "node-1")
+				return ""
+			},
+		},
+		{
+			name: "ambiguous internal IP",
+			servers: func(t *testing.T, api *cloudfake.Server, client *cloudsigma.Client) string {
+				api.AddIP(cloud.IPDetail{UUID: "10.0.0.1"})
+				createTestServer(t, client, "vm-a", "10.0.0.1")
+				createTestServer(t, client, "vm-b", "10.0.0.1")
+				return ""
+			},
+		},
+		{
+			name: "no match",
+			servers: func(t *testing.T, _ *cloudfake.Server, client *cloudsigma.Client) string {
+				createTestServer(t, client, "vm-a")
+				return ""
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			api := cloudfake.NewServer()
+			defer api.Close()
+			client := api.NewSDKClient()
+			wantUUID := tt.servers(t, api, client)
+
+			node := testNode(map[string]string{corev1.LabelTopologyRegion: "zrh"}, uninitializedTaint)
+			cs := fake.NewSimpleClientset(node)
+			r := &NodeReconciler{CloudSigmaRegion: "zrh", tenantClient: cs, cloudsigmaClient: client}
+
+			if err := r.reconcileNode(ctx, node.DeepCopy()); err != nil {
+				t.Fatalf("reconcileNode() error = %v", err)
+			}
+			got, err := cs.CoreV1().Nodes().Get(ctx, node.Name, metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("Get() error = %v", err)
+			}
+			wantProviderID := ""
+			if wantUUID != "" {
+				wantProviderID = cloud.ProviderIDPrefix + wantUUID
+			}
+			if got.Spec.ProviderID != wantProviderID {
+				t.Errorf("providerID = %q, want %q", got.Spec.ProviderID, wantProviderID)
+			}
+			// Initialization proceeds either way
+			if hasInitTaint(got) {
+				t.Errorf("taints = %v, want the initialization taint removed", got.Spec.Taints)
+			}
+		})
+	}
+}
+
+// failingClientset returns a clientset whose tenant health checks fail while *failing is set
+func failingClientset(failing *bool) *fake.Clientset {
+	cs := fake.NewSimpleClientset()
+	cs.PrependReactor("get", "namespaces", func(k8stesting.Action) (bool, runtime.Object, error) {
+		if *failing {
+			return true, nil, fmt.Errorf("connection refused")
+		}
+		return true, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: metav1.NamespaceSystem}}, nil
+	})
+	return cs
+}
+
+// listedNodes reports whether a node list was made through cs
+func listedNodes(cs *fake.Clientset) bool {
+	for _, action := range cs.Actions() {
+		if action.GetVerb() == "list" && action.GetResource().Resource == "nodes" {
+			return true
+		}
+	}
+	return false
+}
+
+func TestCheckTenantHealth_RebuildsFailingClient(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	failing := true
+	broken := failingClientset(&failing)
+	rebuilt := fake.NewSimpleClientset()
+	builds := 0
+	r := &NodeReconciler{
+		TenantKubeconfig: "/etc/kubernetes/tenant.conf",
+		SyncInterval:     time.Hour,
+		tenantClient:     broken,
+		NewTenantClient: func(path string) (kubernetes.Interface, error) {
+			builds++
+			return rebuilt, nil
+		},
+	}
+	r.startSyncLoop(ctx)
+
+	for i := 1; i < TenantHealthFailureThreshold; i++ {
+		r.checkTenantHealth(ctx)
+	}
+	if builds != 0 {
+		t.Fatalf("tenant client rebuilt after %d failures, want %d", TenantHealthFailureThreshold-1, TenantHealthFailureThreshold)
+	}
+
+	r.checkTenantHealth(ctx)
+	if builds != 1 {
+		t.Fatalf("tenant client built %d times, want 1", builds)
+	}
+	if r.GetTenantClient() != rebuilt {
+		t.Fatal("GetTenantClient() still returns the failing client")
+	}
+
+	// Controllers sharing the reconciler as their client provider switch over with it
+	lb := &LoadBalancerController{TenantClient: broken, TenantClients: r}
+	if lb.tenantClient() != rebuilt {
+		t.Error("LB controller still uses the failing client")
+	}
+	csi := &CSITokenController{TenantClient: broken, TenantClients: r, Namespace: "csi-test"}
+	if err := csi.ensureNamespace(ctx); err != nil {
+		t.Fatalf("ensureNamespace() error = %v", err)
+	}
+	if _, err := rebuilt.CoreV1().Namespaces().Get(ctx, "csi-test", metav1.GetOptions{}); err != nil {
+		t.Errorf("CSI token controller did not use the rebuilt client: %v", err)
+	}
+
+	// The restarted sync loop syncs nodes through the new client right away
+	deadline := time.Now().Add(5 * time.Second)
+	for !listedNodes(rebuilt) {
+		if time.Now().After(deadline) {
+			t.Fatal("node sync did not restart with the rebuilt client")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestCheckTenantHealth_IntermittentFailures(t *testing.T) {
+	ctx := context.Background()
+	failing := false
+	builds := 0
+	r := &NodeReconciler{
+		tenantClient: failingClientset(&failing),
+		NewTenantClient: func(path string) (kubernetes.Interface, error) {
+			builds++
+			return fake.NewSimpleClientset(), nil
+		},
+	}
+
+	// A success in between resets the count, so the threshold is never reached
+	for _, fail := range []bool{true, true, false, true, true} {
+		failing = fail
+		r.checkTenantHealth(ctx)
+	}
+	if builds != 0 {
+		t.Errorf("tenant client rebuilt %d times after intermittent failures, want 0", builds)
+	}
+}
@@ -0,0 +1,141 @@
+/*
+Copyright 2025 Kube-DC Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/cloudsigma/cloudsigma-sdk-go/cloudsigma"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+
+	"github.com/kube-dc/cluster-api-provider-cloudsigma/pkg/cloud"
+)
+
+const (
+	// AnnotationLabelsFromMachine lists the node labels applied from the Machine's server meta, so
+	// labels removed from the Machine are removed from the node
+	AnnotationLabelsFromMachine = "cloudsigma.com/labels-from-machine"
+	// AnnotationAnnotationsFromMachine lists the node annotations applied from the Machine's
+	// server meta
+	AnnotationAnnotationsFromMachine = "cloudsigma.com/annotations-from-machine"
+)
+
+// syncNodeMetadata applies to each node the labels and annotations of its Machine, which the
+// management cluster stashes in the server meta (see cloud.NodeMeta). Nodes whose server is not
+// listed are left alone.
+func (r *NodeReconciler) syncNodeMetadata(ctx context.Context, nodes []corev1.Node) {
+	r.clientMutex.RLock()
+	client := r.cloudsigmaClient
+	r.clientMutex.RUnlock()
+	if client == nil {
+		return
+	}
+
+	servers, err := listAll[cloudsigma.Server](ctx, client, "servers/detail/")
+	if err != nil {
+		klog.Errorf("Failed to list servers for node metadata: %v", err)
+		return
+	}
+	meta := make(map[string]map[string]interface{}, len(servers))
+	for _, server := range servers {
+		meta[server.UUID] = server.Meta
+	}
+
+	for i := range nodes {
+		node := &nodes[i]
+		vmUUID, ok := cloud.ParseProviderID(node.Spec.ProviderID)
+		if !ok {
+			continue
+		}
+		serverMeta, ok := meta[vmUUID]
+		if !ok {
+			continue
+		}
+		labels, annotations := cloud.ParseNodeMeta(serverMeta)
+		patch := nodeMetadataPatch(node, labels, annotations)
+		if patch == nil {
+			continue
+		}
+		if _, err := r.tenantClient.CoreV1().Nodes().Patch(ctx, node.Name, types.MergePatchType, patch, metav1.PatchOptions{}); err != nil {
+			klog.Errorf("Failed to apply Machine labels and annotations to node %s: %v", node.Name, err)
+			continue
+		}
+		klog.Infof("Applied Machine labels and annotations to node %s", node.Name)
+	}
+}
+
+// nodeMetadataPatch returns the merge patch setting the labels and annotations on node and
+// removing the ones applied before that are no longer wanted, or nil if node is up to date. The
+// applied keys are recorded in AnnotationLabelsFromMachine and AnnotationAnnotationsFromMachine.
+func nodeMetadataPatch(node *corev1.Node, labels, annotations map[string]string) []byte {
+	labelPatch := metadataPatch(node.Labels, labels, node.Annotations[AnnotationLabelsFromMachine])
+	annotationPatch := metadataPatch(node.Annotations, annotations, node.Annotations[AnnotationAnnotationsFromMachine])
+	recordApplied(annotationPatch, node.Annotations, AnnotationLabelsFromMachine, labels)
+	recordApplied(annotationPatch, node.Annotations, AnnotationAnnotationsFromMachine, annotations)
+	if len(labelPatch) == 0 && len(annotationPatch) == 0 {
+		return nil
+	}
+
+	metadata := map[string]interface{}{}
+	if len(labelPatch) > 0 {
+		metadata["labels"] = labelPatch
+	}
+	if len(annotationPatch) > 0 {
+		metadata["annotations"] = annotationPatch
+	}
+	data, _ := json.Marshal(map[string]interface{}{"metadata": metadata})
+	return data
+}
+
+// metadataPatch returns the merge patch entries bringing current in line with want, deleting
+// (with a nil value) the keys in the comma-separated applied list that want no longer has
+func metadataPatch(current, want map[string]string, applied string) map[string]interface{} {
+	patch := map[string]interface{}{}
+	for key, value := range want {
+		if existing, ok := current[key]; !ok || existing != value {
+			patch[key] = value
+		}
+	}
+	for _, key := range strings.Split(applied, ",") {
+		if _, wanted := want[key]; key != "" && !wanted {
+			if _, ok := current[key]; ok {
+				patch[key] = nil
+			}
+		}
+	}
+	return patch
+}
+
+// recordApplied adds to patch the update of the annotation listing the keys of want, if the list
+// changed
+func recordApplied(patch map[string]interface{}, current map[string]string, annotation string, want map[string]string) {
+	list := strings.Join(slices.Sorted(maps.Keys(want)), ",")
+	existing, ok := current[annotation]
+	switch {
+	case list == "" && ok:
+		patch[annotation] = nil
+	case list != "" && existing != list:
+		patch[annotation] = list
+	}
+}
@@ -0,0 +1,118 @@
+/*
+Copyright 2025 Kube-DC Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/cloudsigma/cloudsigma-sdk-go/cloudsigma"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/kube-dc/cluster-api-provider-cloudsigma/pkg/cloud"
+	cloudfake "github.com/kube-dc/cluster-api-provider-cloudsigma/pkg/cloud/fake"
+)
+
+func TestSyncNodeMetadata(t *testing.T) {
+	ctx := context.Background()
+	api := cloudfake.NewServer()
+	defer api.Close()
+	client := api.NewSDKClient()
+
+	servers, _, err := client.Servers.Create(ctx, &cloudsigma.ServerCreateRequest{
+		Servers: []cloudsigma.Server{{Name: "node-1", CPU: 2000, Memory: 2 << 30, VNCPassword: "secret"}},
+	})
+	if err != nil {
+		t.Fatalf("Servers.Create() error = %v", err)
+	}
+	setMeta := func(labels, annotations map[string]string) {
+		t.Helper()
+		meta := map[string]interface{}{"machine-uid": "uid-1"}
+		for key, value := range cloud.NodeMeta(labels, annotations) {
+			meta[key] = value
+		}
+		server, _ := api.GetServer(servers[0].UUID)
+		server.Meta = meta
+		if _, _, err := client.Servers.Update(ctx, server.UUID, &cloudsigma.ServerUpdateRequest{Server: &server}); err != nil {
+			t.Fatalf("Servers.Update() error = %v", err)
+		}
+	}
+
+	node := testNode(map[string]string{"node.cluster.x-k8s.io/pool": "old", "team": "a"})
+	node.Spec.ProviderID = cloud.ProviderIDPrefix + servers[0].UUID
+	other := testNode(nil)
+	other.Name, other.Spec.ProviderID = "node-2", cloud.ProviderIDPrefix+"0d2a5cb1-8b7e-4c1f-9f5a-2e6b7c8d9e0f"
+	cs := fake.NewSimpleClientset(node, other)
+	r := &NodeReconciler{tenantClient: cs, cloudsigmaClient: client}
+	sync := func() *corev1.Node {
+		t.Helper()
+		nodes, err := cs.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+		if err != nil {
+			t.Fatalf("List() error = %v", err)
+		}
+		r.syncNodeMetadata(ctx, nodes.Items)
+		got, err := cs.CoreV1().Nodes().Get(ctx, node.Name, metav1.GetOptions{})
+		if err != nil {
+			t.Fatalf("Get() error = %v", err)
+		}
+		return got
+	}
+
+	// The Machine's node labels and annotations are applied and recorded
+	setMeta(map[string]string{"node-role.kubernetes.io/worker": "", "node.cluster.x-k8s.io/pool": "gpu"},
+		map[string]string{"node.cluster.x-k8s.io/owner": "team-a"})
+	got := sync()
+	wantLabels := map[string]string{"node-role.kubernetes.io/worker": "", "node.cluster.x-k8s.io/pool": "gpu", "team": "a"}
+	if !reflect.DeepEqual(got.Labels, wantLabels) {
+		t.Errorf("labels = %v, want %v", got.Labels, wantLabels)
+	}
+	wantAnnotations := map[string]string{
+		"node.cluster.x-k8s.io/owner":    "team-a",
+		AnnotationLabelsFromMachine:      "node-role.kubernetes.io/worker,node.cluster.x-k8s.io/pool",
+		AnnotationAnnotationsFromMachine: "node.cluster.x-k8s.io/owner",
+	}
+	if !reflect.DeepEqual(got.Annotations, wantAnnotations) {
+		t.Errorf("annotations = %v, want %v", got.Annotations, wantAnnotations)
+	}
+
+	// An up-to-date node is not written to
+	cs.ClearActions()
+	sync()
+	if writes := nodeWrites(cs); len(writes) != 0 {
+		t.Errorf("node writes on a synced node = %v, want none", writes)
+	}
+
+	// Labels and annotations removed from the Machine are removed from the node, others are kept
+	setMeta(map[string]string{"node-role.kubernetes.io/worker": ""}, nil)
+	got = sync()
+	wantLabels = map[string]string{"node-role.kubernetes.io/worker": "", "team": "a"}
+	if !reflect.DeepEqual(got.Labels, wantLabels) {
+		t.Errorf("labels after removal = %v, want %v", got.Labels, wantLabels)
+	}
+	wantAnnotations = map[string]string{AnnotationLabelsFromMachine: "node-role.kubernetes.io/worker"}
+	if !reflect.DeepEqual(got.Annotations, wantAnnotations) {
+		t.Errorf("annotations after removal = %v, want %v", got.Annotations, wantAnnotations)
+	}
+
+	// A node whose server is not listed is left alone
+	if untouched, _ := cs.CoreV1().Nodes().Get(ctx, other.Name, metav1.GetOptions{}); len(untouched.Labels) != 0 || len(untouched.Annotations) != 0 {
+		t.Errorf("node-2 = %v, %v; want it untouched", untouched.Labels, untouched.Annotations)
+	}
+}
@@ -0,0 +1,156 @@
+/*
+Copyright 2025 Kube-DC Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/cloudsigma/cloudsigma-sdk-go/cloudsigma"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+
+	"github.com/kube-dc/cluster-api-provider-cloudsigma/pkg/cloud"
+)
+
+// cloudListPageSize is the page size used when listing CloudSigma resources
+const cloudListPageSize = 100
+
+// backfillProviderID sets the providerID of a node registered without one by matching it to a
+// CloudSigma server by name or, failing that, by internal IP. The providerID is only set when
+// exactly one server matches; node is refreshed from the patched object.
+func (r *NodeReconciler) backfillProviderID(ctx context.Context, node *corev1.Node) error {
+	r.clientMutex.RLock()
+	client := r.cloudsigmaClient
+	r.clientMutex.RUnlock()
+	if client == nil {
+		return nil
+	}
+
+	servers, err := listAll[cloudsigma.Server](ctx, client, "servers/detail/")
+	if err != nil {
+		return fmt.Errorf("failed to list servers: %w", err)
+	}
+	candidates, how := serversByName(servers, node.Name), "name"
+	if len(candidates) == 0 {
+		ips, err := listAll[cloudsigma.IP](ctx, client, "ips/detail/")
+		if err != nil {
+			return fmt.Errorf("failed to list IPs: %w", err)
+		}
+		candidates, how = serversByInternalIP(servers, ips, node), "internal IP"
+	}
+	switch len(candidates) {
+	case 0:
+		klog.V(2).Infof("Node %s has no providerID and matches no CloudSigma server", node.Name)
+		return nil
+	case 1:
+	default:
+		klog.Warningf("Node %s has no providerID and matches %d CloudSigma servers by %s (%s); not backfilling it",
+			node.Name, len(candidates), how, strings.Join(candidates, ", "))
+		return nil
+	}
+
+	providerID := cloud.ProviderIDPrefix + candidates[0]
+	patch, _ := json.Marshal(map[string]interface{}{
+		"spec": map[string]interface{}{"providerID": providerID},
+	})
+	updated, err := r.tenantClient.CoreV1().Nodes().Patch(ctx, node.Name, types.MergePatchType, patch, metav1.PatchOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to set providerID: %w", err)
+	}
+	updated.DeepCopyInto(node)
+	klog.Infof("Backfilled providerID %s on node %s (matched by %s)", providerID, node.Name, how)
+	return nil
+}
+
+// serversByName returns the UUIDs of the servers named name
+func serversByName(servers []cloudsigma.Server, name string) []string {
+	var uuids []string
+	for _, server := range servers {
+		if server.Name == name {
+			uuids = append(uuids, server.UUID)
+		}
+	}
+	return uuids
+}
+
+// serversByInternalIP returns the UUIDs of the servers holding one of the node's internal IPs.
+// IPs are linked to servers through the IP list and the servers' NIC configuration.
+func serversByInternalIP(servers []cloudsigma.Server, ips []cloudsigma.IP, node *corev1.Node) []string {
+	nodeIPs := map[string]bool{}
+	for _, addr := range node.Status.Addresses {
+		if addr.Type == corev1.NodeInternalIP {
+			nodeIPs[addr.Address] = true
+		}
+	}
+	if len(nodeIPs) == 0 {
+		return nil
+	}
+
+	matched := map[string]bool{}
+	for _, ip := range ips {
+		if ip.Server != nil && nodeIPs[ip.UUID] {
+			matched[ip.Server.UUID] = true
+		}
+	}
+	for _, server := range servers {
+		for _, nic := range server.NICs {
+			if conf := nic.IP4Configuration; conf != nil && conf.IPAddress != nil && nodeIPs[conf.IPAddress.UUID] {
+				matched[server.UUID] = true
+			}
+		}
+		if server.Runtime != nil {
+			for _, nic := range server.Runtime.RuntimeNICs {
+				if nodeIPs[nic.IPv4.UUID] {
+					matched[server.UUID] = true
+				}
+			}
+		}
+	}
+	return slices.Sorted(maps.Keys(matched))
+}
+
+// listAll collects every page of a CloudSigma list endpoint. The SDK's List methods only return
+// the first page.
+func listAll[T any](ctx context.Context, client *cloudsigma.Client, path string) ([]T, error) {
+	var items []T
+	for offset := 0; ; {
+		req, err := client.NewRequest(http.MethodGet, fmt.Sprintf("%s?limit=%d&offset=%d", path, cloudListPageSize, offset), nil)
+		if err != nil {
+			return nil, err
+		}
+		var page struct {
+			Meta    cloudsigma.Meta `json:"meta"`
+			Objects []T             `json:"objects"`
+		}
+		if _, err := client.Do(ctx, req, &page); err != nil {
+			return nil, err
+		}
+		items = append(items, page.Objects...)
+		offset += len(page.Objects)
+		if len(page.Objects) == 0 || offset >= page.Meta.TotalCount {
+			return items, nil
+		}
+	}
+}
@@ -0,0 +1,61 @@
+/*
+Copyright 2025 Kube-DC Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/annotations"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// PausedFunc reports whether the CAPI Cluster managing the tenant cluster is paused
+type PausedFunc func(ctx context.Context) (bool, error)
+
+// ClusterPaused returns a PausedFunc that reads the Cluster namespace/name from the management
+// cluster. The Cluster is paused by spec.paused or the cluster.x-k8s.io/paused annotation, the
+// same as for the CAPI reconcilers.
+func ClusterPaused(c client.Reader, namespace, name string) PausedFunc {
+	return func(ctx context.Context) (bool, error) {
+		cluster := &clusterv1.Cluster{}
+		if err := c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, cluster); err != nil {
+			return false, err
+		}
+		return cluster.Spec.Paused || annotations.HasPaused(cluster), nil
+	}
+}
+
+// isPaused reports whether the step named what must be skipped. A Cluster that cannot be read
+// does not pause the CCM, so losing access to the management cluster does not stop node
+// initialization and LB IP assignment.
+func isPaused(ctx context.Context, paused PausedFunc, what string) bool {
+	if paused == nil {
+		return false
+	}
+	p, err := paused(ctx)
+	if err != nil {
+		klog.Warningf("Failed to check whether the cluster is paused, running %s anyway: %v", what, err)
+		return false
+	}
+	if p {
+		klog.Infof("Cluster is paused, skipping %s", what)
+	}
+	return p
+}
@@ -0,0 +1,162 @@
+/*
+Copyright 2025 Kube-DC Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	testingclock "k8s.io/utils/clock/testing"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	crfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestClusterPaused(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := clusterv1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	cluster := func(name string, mutate func(*clusterv1.Cluster)) *clusterv1.Cluster {
+		c := &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "tenants"}}
+		if mutate != nil {
+			mutate(c)
+		}
+		return c
+	}
+	c := crfake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		cluster("running", nil),
+		cluster("spec-paused", func(c *clusterv1.Cluster) { c.Spec.Paused = true }),
+		cluster("annotated", func(c *clusterv1.Cluster) { c.Annotations = map[string]string{clusterv1.PausedAnnotation: ""} }),
+	).Build()
+
+	tests := []struct {
+		name    string
+		want    bool
+		wantErr bool
+	}{
+		{name: "running"},
+		{name: "spec-paused", want: true},
+		{name: "annotated", want: true},
+		{name: "missing", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ClusterPaused(c, "tenants", tt.name)(context.Background())
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("ClusterPaused() = %v, %v; want %v, error %v", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestIsPaused(t *testing.T) {
+	ctx := context.Background()
+	if isPaused(ctx, nil, "sync") {
+		t.Error("isPaused() without a PausedFunc = true, want false")
+	}
+	failing := func(context.Context) (bool, error) { return true, errors.New("forbidden") }
+	if isPaused(ctx, failing, "sync") {
+		t.Error("isPaused() with an unreadable Cluster = true, want false")
+	}
+}
+
+func TestSyncNodes_Paused(t *testing.T) {
+	ctx := context.Background()
+	node := testNode(nil, uninitializedTaint)
+	cs := fake.NewSimpleClientset(node)
+	paused := true
+	r := &NodeReconciler{
+		CloudSigmaRegion: "zrh",
+		Paused:           func(context.Context) (bool, error) { return paused, nil },
+		tenantClient:     cs,
+	}
+
+	if err := r.syncNodes(ctx); err != nil {
+		t.Fatalf("syncNodes() error = %v", err)
+	}
+	if writes := nodeWrites(cs); len(writes) != 0 {
+		t.Fatalf("syncNodes() wrote nodes while paused: %v", writes)
+	}
+
+	paused = false
+	if err := r.syncNodes(ctx); err != nil {
+		t.Fatalf("syncNodes() error = %v", err)
+	}
+	got, err := cs.CoreV1().Nodes().Get(ctx, node.Name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if hasInitTaint(got) || got.Labels[corev1.LabelTopologyRegion] != "zrh" {
+		t.Errorf("node after resuming: taints %v, labels %v; want initialized", got.Spec.Taints, got.Labels)
+	}
+}
+
+func TestSyncLoadBalancers_Paused(t *testing.T) {
+	const ip = "203.0.113.10"
+	svc := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+		Spec: corev1.ServiceSpec{
+			Type:      corev1.ServiceTypeLoadBalancer,
+			ClusterIP: "10.96.0.10",
+			Ports:     []corev1.ServicePort{{Port: 80}},
+		},
+	}
+	nodes := lbTestNodes(1)
+	cs := fake.NewSimpleClientset(svc, &nodes[0])
+	c := newTagsTestController(t, &fakeTagsAPI{}, testingclock.NewFakeClock(time.Now()))
+	c.TenantClient = cs
+	c.staticIPs = []string{ip}
+	c.ipAssignments = map[string]string{}
+	c.serviceIPs = map[string]string{}
+	c.waitingForEndpoints = map[string]bool{}
+	c.manualModeNodes = map[string]bool{lbTestNodeUUID(0): true}
+	paused := true
+	c.Paused = func(context.Context) (bool, error) { return paused, nil }
+	ctx := context.Background()
+	ingress := func() []corev1.LoadBalancerIngress {
+		t.Helper()
+		got, err := cs.CoreV1().Services("default").Get(ctx, "web", metav1.GetOptions{})
+		if err != nil {
+			t.Fatalf("Get() error = %v", err)
+		}
+		return got.Status.LoadBalancer.Ingress
+	}
+
+	if err := c.syncLoadBalancers(ctx); err != nil {
+		t.Fatalf("syncLoadBalancers() error = %v", err)
+	}
+	if got := ingress(); len(got) != 0 {
+		t.Fatalf("ingress = %v while paused, want none", got)
+	}
+	if len(c.serviceIPs) != 0 {
+		t.Fatalf("service IPs = %v while paused, want none", c.serviceIPs)
+	}
+
+	paused = false
+	if err := c.syncLoadBalancers(ctx); err != nil {
+		t.Fatalf("syncLoadBalancers() error = %v", err)
+	}
+	if got := ingress(); len(got) != 1 || got[0].IP != ip {
+		t.Errorf("ingress after resuming = %v, want %s", got, ip)
+	}
+}
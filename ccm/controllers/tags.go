@@ -0,0 +1,137 @@
+/*
+Copyright 2025 Kube-DC Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/cloudsigma/cloudsigma-sdk-go/cloudsigma"
+	"k8s.io/klog/v2"
+
+	"github.com/kube-dc/cluster-api-provider-cloudsigma/pkg/cloud"
+	"github.com/kube-dc/cluster-api-provider-cloudsigma/pkg/regions"
+)
+
+// tagListCacheTTL bounds how long a tag listing is reused. A sync looks tags up many times in quick
+// succession (per candidate IP while allocating, per tag while tagging), so lookups within this
+// window share one API call. Any tag write by the controller drops the cached listings.
+const tagListCacheTTL = 5 * time.Second
+
+// tagListing is a cached result of listTags
+type tagListing struct {
+	tags    []cloudsigma.Tag
+	fetched time.Time
+}
+
+// tagNameFilter narrows a tag listing to the tag with exactly this name
+func tagNameFilter(name string) url.Values {
+	return url.Values{"name": {name}}
+}
+
+// tagPrefixFilter narrows a tag listing to tags whose name starts with prefix
+func tagPrefixFilter(prefix string) url.Values {
+	return url.Values{"name__startswith": {prefix}}
+}
+
+// apiEndpointURL returns the CloudSigma API root in the controller's region
+func (c *LoadBalancerController) apiEndpointURL() string {
+	if c.apiEndpoint != "" {
+		return strings.TrimSuffix(c.apiEndpoint, "/")
+	}
+	return regions.Lookup(c.Region).APIEndpoint()
+}
+
+// apiToken returns a token for the CloudSigma API as the impersonated user
+func (c *LoadBalancerController) apiToken(ctx context.Context) (string, error) {
+	if c.tokenSource != nil {
+		return c.tokenSource(ctx)
+	}
+	return c.ImpersonationClient.GetImpersonatedToken(ctx, c.UserEmail, c.Region)
+}
+
+// clearAPIToken drops the cached impersonated token so the next apiToken call fetches a new one
+func (c *LoadBalancerController) clearAPIToken() {
+	if c.tokenSource == nil && c.ImpersonationClient != nil {
+		c.ImpersonationClient.ClearUserToken(c.UserEmail, c.Region)
+	}
+}
+
+// lbTokenSource hands the controller's impersonated tokens to the cloud client, which retries a
+// request rejected with 401 once after invalidating the token
+type lbTokenSource struct {
+	c *LoadBalancerController
+}
+
+func (s lbTokenSource) Token(ctx context.Context) (string, error) { return s.c.apiToken(ctx) }
+
+func (s lbTokenSource) Invalidate() { s.c.clearAPIToken() }
+
+// cloudClient returns a CloudSigma API client acting as the impersonated user
+func (c *LoadBalancerController) cloudClient() (*cloud.Client, error) {
+	return cloud.NewClientWithTokenSource(c.apiEndpointURL(), lbTokenSource{c})
+}
+
+// listTags lists the tags matching query, or all tags for an empty query. The API applies the
+// filters server side; callers still check names themselves, since a filter only narrows the list.
+func (c *LoadBalancerController) listTags(ctx context.Context, query url.Values) ([]cloudsigma.Tag, error) {
+	key := query.Encode()
+	now := clockOrDefault(c.Clock).Now()
+
+	c.tagCacheMutex.Lock()
+	if cached, ok := c.tagCache[key]; ok && now.Sub(cached.fetched) < tagListCacheTTL {
+		c.tagCacheMutex.Unlock()
+		return cached.tags, nil
+	}
+	c.tagCacheMutex.Unlock()
+
+	client, err := c.cloudClient()
+	if err != nil {
+		return nil, err
+	}
+	tags, err := client.ListTags(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	klog.V(4).Infof("Listed %d tags (filter %q)", len(tags), key)
+
+	c.tagCacheMutex.Lock()
+	if c.tagCache == nil {
+		c.tagCache = make(map[string]tagListing)
+	}
+	c.tagCache[key] = tagListing{tags: tags, fetched: now}
+	c.tagCacheMutex.Unlock()
+
+	return tags, nil
+}
+
+// invalidateTagCache drops all cached tag listings; called after every tag write
+func (c *LoadBalancerController) invalidateTagCache() {
+	c.tagCacheMutex.Lock()
+	c.tagCache = nil
+	c.tagCacheMutex.Unlock()
+}
+
+// isCCMTag reports whether a tag is one of the cluster:*, service:* or managed-by tags the
+// controller puts on LB IPs
+func isCCMTag(name string) bool {
+	return strings.HasPrefix(name, "cluster:") ||
+		strings.HasPrefix(name, "service:") ||
+		name == "managed-by:cloudsigma-ccm"
+}
@@ -0,0 +1,284 @@
+/*
+Copyright 2025 Kube-DC Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cloudsigma/cloudsigma-sdk-go/cloudsigma"
+	testingclock "k8s.io/utils/clock/testing"
+
+	"github.com/kube-dc/cluster-api-provider-cloudsigma/pkg/cloud"
+	"github.com/kube-dc/cluster-api-provider-cloudsigma/pkg/cloud/fake"
+)
+
+// fakeTagsAPI serves GET/POST /tags/ and PUT /tags/<uuid>/ from memory, honouring the name and
+// name__startswith filters, and records the query of every listing
+type fakeTagsAPI struct {
+	mu      sync.Mutex
+	tags    []cloudsigma.Tag
+	queries []string
+}
+
+func (f *fakeTagsAPI) handler(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/tags/":
+		f.queries = append(f.queries, r.URL.RawQuery)
+		name, prefix := r.URL.Query().Get("name"), r.URL.Query().Get("name__startswith")
+		objects := []cloudsigma.Tag{}
+		for _, t := range f.tags {
+			if (name == "" || t.Name == name) && strings.HasPrefix(t.Name, prefix) {
+				objects = append(objects, t)
+			}
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"objects": objects})
+	case r.Method == http.MethodPost && r.URL.Path == "/tags/":
+		var req struct {
+			Objects []cloudsigma.Tag `json:"objects"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		for _, tag := range req.Objects {
+			tag.UUID = "tag-" + tag.Name
+			f.tags = append(f.tags, tag)
+		}
+	case r.Method == http.MethodPut && strings.HasPrefix(r.URL.Path, "/tags/"):
+		uuid := strings.Trim(strings.TrimPrefix(r.URL.Path, "/tags/"), "/")
+		var tag cloudsigma.Tag
+		_ = json.NewDecoder(r.Body).Decode(&tag)
+		for i := range f.tags {
+			if f.tags[i].UUID == uuid {
+				f.tags[i].Resources = tag.Resources
+			}
+		}
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (f *fakeTagsAPI) takeQueries() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	q := f.queries
+	f.queries = nil
+	return q
+}
+
+func newTagsTestController(t *testing.T, api *fakeTagsAPI, clk *testingclock.FakeClock) *LoadBalancerController {
+	server := httptest.NewServer(http.HandlerFunc(api.handler))
+	t.Cleanup(server.Close)
+	return &LoadBalancerController{
+		ClusterName: "alpha",
+		Clock:       clk,
+		apiEndpoint: server.URL,
+		tokenSource: func(context.Context) (string, error) { return "token", nil },
+	}
+}
+
+func TestTagLookups_Filters(t *testing.T) {
+	api := &fakeTagsAPI{tags: []cloudsigma.Tag{
+		{UUID: "t1", Name: "service:default-web", Resources: []cloudsigma.TagResource{{UUID: "10.0.0.1"}}},
+		{UUID: "t2", Name: "lock:bravo:10.0.0.9", Meta: map[string]interface{}{
+			"acquired": "2025-01-01T00:00:00Z", "renewed": "2025-01-01T00:00:00Z",
+		}},
+		{UUID: "t3", Name: "unrelated"},
+	}}
+	c := newTagsTestController(t, api, testingclock.NewFakeClock(time.Now()))
+	ctx := context.Background()
+
+	available, err := c.isIPAvailable(ctx, "10.0.0.1")
+	if err != nil || available {
+		t.Fatalf("isIPAvailable(10.0.0.1) = %v, %v; want false, nil", available, err)
+	}
+	if got := api.takeQueries(); len(got) != 1 || got[0] != "limit=0&name__startswith=service%3A" {
+		t.Errorf("service tag lookup queries = %q, want [limit=0&name__startswith=service%%3A]", got)
+	}
+
+	locks, err := c.ipLocks().listIPLocks(ctx)
+	if err != nil {
+		t.Fatalf("listIPLocks: %v", err)
+	}
+	if len(locks) != 1 || locks[0].Cluster != "bravo" || locks[0].IP != "10.0.0.9" {
+		t.Errorf("listIPLocks = %+v, want the bravo lock on 10.0.0.9", locks)
+	}
+	if got := api.takeQueries(); len(got) != 1 || got[0] != "limit=0&name__startswith=lock%3A" {
+		t.Errorf("lock lookup queries = %q, want [limit=0&name__startswith=lock%%3A]", got)
+	}
+
+	if err := c.ensureTagWithIP(ctx, "cluster:alpha", "10.0.0.1"); err != nil {
+		t.Fatalf("ensureTagWithIP: %v", err)
+	}
+	if got := api.takeQueries(); len(got) != 1 || got[0] != "limit=0&name=cluster%3Aalpha" {
+		t.Errorf("tag lookup queries = %q, want [limit=0&name=cluster%%3Aalpha]", got)
+	}
+}
+
+func TestTagLookups_Cache(t *testing.T) {
+	api := &fakeTagsAPI{}
+	clk := testingclock.NewFakeClock(time.Now())
+	c := newTagsTestController(t, api, clk)
+	ctx := context.Background()
+
+	// Checking several candidate IPs in one allocation shares one listing
+	for _, ip := range []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"} {
+		if available, err := c.isIPAvailable(ctx, ip); err != nil || !available {
+			t.Fatalf("isIPAvailable(%s) = %v, %v; want true, nil", ip, available, err)
+		}
+	}
+	if got := api.takeQueries(); len(got) != 1 {
+		t.Errorf("listings for 3 lookups = %d, want 1", len(got))
+	}
+
+	// A tag write drops the cache, so the new tag is seen right away
+	if err := c.tagIPInCloudSigma(ctx, "10.0.0.2", "default/web"); err != nil {
+		t.Fatalf("tagIPInCloudSigma: %v", err)
+	}
+	api.takeQueries()
+	if available, err := c.isIPAvailable(ctx, "10.0.0.2"); err != nil || available {
+		t.Fatalf("isIPAvailable(10.0.0.2) after tagging = %v, %v; want false, nil", available, err)
+	}
+	if got := api.takeQueries(); len(got) != 1 {
+		t.Errorf("listings after a tag write = %d, want 1", len(got))
+	}
+
+	// Listings expire after tagListCacheTTL
+	if _, err := c.isIPAvailable(ctx, "10.0.0.3"); err != nil {
+		t.Fatal(err)
+	}
+	if got := api.takeQueries(); len(got) != 0 {
+		t.Errorf("listings within the TTL = %d, want 0", len(got))
+	}
+	clk.Step(tagListCacheTTL)
+	if _, err := c.isIPAvailable(ctx, "10.0.0.3"); err != nil {
+		t.Fatal(err)
+	}
+	if got := api.takeQueries(); len(got) != 1 {
+		t.Errorf("listings after the TTL = %d, want 1", len(got))
+	}
+}
+
+func TestLBCloudCalls_RefreshRevokedToken(t *testing.T) {
+	api := fake.NewServer()
+	defer api.Close()
+	api.AddIP(cloud.IPDetail{UUID: "203.0.113.10", Subscription: &cloud.IPSubscription{ID: 1}})
+	impersonation, err := api.NewImpersonationClient()
+	if err != nil {
+		t.Fatalf("NewImpersonationClient() error = %v", err)
+	}
+	c := &LoadBalancerController{
+		ImpersonationClient: impersonation,
+		UserEmail:           fake.Username,
+		Region:              fake.Region,
+		ClusterName:         "alpha",
+		apiEndpoint:         api.APIEndpoint(),
+	}
+	ctx := context.Background()
+
+	if err := c.discoverOwnedIPs(ctx); err != nil {
+		t.Fatalf("discoverOwnedIPs() error = %v", err)
+	}
+
+	// The cached impersonated token stops working between fetching it and using it
+	api.RevokeUserTokens()
+	c.staticIPs = nil
+	if err := c.discoverOwnedIPs(ctx); err != nil {
+		t.Fatalf("discoverOwnedIPs() after revocation error = %v", err)
+	}
+	if len(c.staticIPs) != 1 {
+		t.Errorf("staticIPs = %v, want the one owned IP", c.staticIPs)
+	}
+
+	// Writes are retried too, with their body resent
+	api.RevokeUserTokens()
+	if err := c.ensureTagWithIP(ctx, "cluster:alpha", "203.0.113.10"); err != nil {
+		t.Fatalf("ensureTagWithIP() after revocation error = %v", err)
+	}
+	tag, ok := api.GetTag("cluster:alpha")
+	if !ok || len(tag.Resources) != 1 || tag.Resources[0].UUID != "203.0.113.10" {
+		t.Errorf("tag cluster:alpha = %+v (found %v), want it on 203.0.113.10", tag, ok)
+	}
+}
+
+func TestNodeManualMode_RevertOnShutdown(t *testing.T) {
+	api := fake.NewServer()
+	defer api.Close()
+	api.AddIP(cloud.IPDetail{UUID: "203.0.113.20", Subscription: &cloud.IPSubscription{ID: 1}})
+	ctx := context.Background()
+
+	servers, _, err := api.NewSDKClient().Servers.Create(ctx, &cloudsigma.ServerCreateRequest{
+		Servers: []cloudsigma.Server{{Name: "node-0", CPU: 2000, Memory: 2 << 30, VNCPassword: "secret",
+			NICs: []cloudsigma.ServerNIC{{IP4Configuration: &cloudsigma.ServerIPConfiguration{
+				Type: "static", IPAddress: &cloudsigma.IP{UUID: "203.0.113.20"}}}}}},
+	})
+	if err != nil {
+		t.Fatalf("Servers.Create() error = %v", err)
+	}
+	serverUUID := servers[0].UUID
+
+	impersonation, err := api.NewImpersonationClient()
+	if err != nil {
+		t.Fatalf("NewImpersonationClient() error = %v", err)
+	}
+	c := &LoadBalancerController{
+		ImpersonationClient: impersonation,
+		UserEmail:           fake.Username,
+		Region:              fake.Region,
+		ClusterName:         "alpha",
+		apiEndpoint:         api.APIEndpoint(),
+		manualModeNodes:     map[string]bool{},
+	}
+	nicConf := func() *cloudsigma.ServerIPConfiguration {
+		server, _ := api.GetServer(serverUUID)
+		return server.NICs[0].IP4Configuration
+	}
+
+	if err := c.ensureNodeManualMode(ctx, serverUUID); err != nil {
+		t.Fatalf("ensureNodeManualMode() error = %v", err)
+	}
+	if conf := nicConf(); conf == nil || conf.Type != "manual" {
+		t.Fatalf("ip_v4_conf after switch = %+v, want manual", conf)
+	}
+
+	// A plain shutdown, e.g. a CCM restart, keeps the nodes serving LoadBalancer IPs
+	tearingDown := false
+	c.TearingDown = func(context.Context) (bool, error) { return tearingDown, nil }
+	c.revertAllManualModeNodes()
+	if conf := nicConf(); conf == nil || conf.Type != "manual" {
+		t.Fatalf("ip_v4_conf after shutdown = %+v, want manual kept", conf)
+	}
+
+	tearingDown = true
+	c.revertAllManualModeNodes()
+	if conf := nicConf(); conf == nil || conf.Type != "static" || conf.IPAddress == nil || conf.IPAddress.UUID != "203.0.113.20" {
+		t.Errorf("ip_v4_conf after revert = %+v, want static with 203.0.113.20", conf)
+	}
+	if len(c.manualModeNodes) != 0 {
+		t.Errorf("manualModeNodes after revert = %v, want none", c.manualModeNodes)
+	}
+	if server, _ := api.GetServer(serverUUID); len(server.Meta) != 0 {
+		t.Errorf("server meta after revert = %v, want the saved configuration dropped", server.Meta)
+	}
+}
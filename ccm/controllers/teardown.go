@@ -0,0 +1,77 @@
+/*
+Copyright 2025 Kube-DC Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	infrav1 "github.com/kube-dc/cluster-api-provider-cloudsigma/api/v1beta1"
+)
+
+// TeardownFunc reports whether the CAPI Cluster managing the tenant cluster is being deleted
+type TeardownFunc func(ctx context.Context) (bool, error)
+
+// ClusterTearingDown returns a TeardownFunc that reads the Cluster namespace/name and its
+// CloudSigmaCluster from the management cluster. The cluster is being torn down once either is
+// gone or has a deletion timestamp.
+func ClusterTearingDown(c client.Reader, namespace, name string) TeardownFunc {
+	return func(ctx context.Context) (bool, error) {
+		cluster := &clusterv1.Cluster{}
+		if err := c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, cluster); err != nil {
+			return apierrors.IsNotFound(err), client.IgnoreNotFound(err)
+		}
+		if !cluster.DeletionTimestamp.IsZero() {
+			return true, nil
+		}
+
+		ref := cluster.Spec.InfrastructureRef
+		if ref == nil || ref.Kind != "CloudSigmaCluster" {
+			return false, nil
+		}
+		csCluster := &infrav1.CloudSigmaCluster{}
+		if err := c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: ref.Name}, csCluster); err != nil {
+			return apierrors.IsNotFound(err), client.IgnoreNotFound(err)
+		}
+		return !csCluster.DeletionTimestamp.IsZero(), nil
+	}
+}
+
+// isTearingDown reports whether the cluster is being torn down, so that the step named what,
+// which undoes the CCM's changes to the servers, may run. Without a TeardownFunc, or with a
+// Cluster that cannot be read, the cluster is assumed to live on: a CCM restart or upgrade must
+// not undo what the running cluster depends on.
+func isTearingDown(ctx context.Context, teardown TeardownFunc, what string) bool {
+	if teardown == nil {
+		klog.Infof("Cluster teardown cannot be detected, skipping %s", what)
+		return false
+	}
+	t, err := teardown(ctx)
+	if err != nil {
+		klog.Warningf("Failed to check whether the cluster is being deleted, skipping %s: %v", what, err)
+		return false
+	}
+	if !t {
+		klog.Infof("Cluster is not being deleted, skipping %s", what)
+	}
+	return t
+}
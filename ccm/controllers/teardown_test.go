@@ -0,0 +1,95 @@
+/*
+Copyright 2025 Kube-DC Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	crfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	infrav1 "github.com/kube-dc/cluster-api-provider-cloudsigma/api/v1beta1"
+)
+
+func TestClusterTearingDown(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clusterv1.AddToScheme(scheme)
+	_ = infrav1.AddToScheme(scheme)
+	now := metav1.Now()
+	cluster := func(name, infra string, deleting bool) *clusterv1.Cluster {
+		c := &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "tenants"}}
+		if infra != "" {
+			c.Spec.InfrastructureRef = &corev1.ObjectReference{Kind: "CloudSigmaCluster", Name: infra}
+		}
+		if deleting {
+			c.DeletionTimestamp = &now
+			c.Finalizers = []string{clusterv1.ClusterFinalizer}
+		}
+		return c
+	}
+	c := crfake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		cluster("running", "running", false),
+		cluster("deleting", "deleting", true),
+		cluster("infra-deleting", "infra-deleting", false),
+		cluster("infra-gone", "infra-gone", false),
+		cluster("no-infra", "", false),
+		&infrav1.CloudSigmaCluster{ObjectMeta: metav1.ObjectMeta{Name: "running", Namespace: "tenants"}},
+		&infrav1.CloudSigmaCluster{ObjectMeta: metav1.ObjectMeta{
+			Name: "infra-deleting", Namespace: "tenants", DeletionTimestamp: &now, Finalizers: []string{"cloudsigmacluster.infrastructure.cluster.x-k8s.io"},
+		}},
+	).Build()
+
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{name: "running"},
+		{name: "no-infra"},
+		{name: "deleting", want: true},
+		{name: "infra-deleting", want: true},
+		{name: "infra-gone", want: true},
+		{name: "missing", want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ClusterTearingDown(c, "tenants", tt.name)(context.Background())
+			if err != nil || got != tt.want {
+				t.Errorf("ClusterTearingDown() = %v, %v; want %v", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsTearingDown(t *testing.T) {
+	ctx := context.Background()
+	if isTearingDown(ctx, nil, "revert") {
+		t.Error("isTearingDown() without a TeardownFunc = true, want false")
+	}
+	failing := func(context.Context) (bool, error) { return true, errors.New("forbidden") }
+	if isTearingDown(ctx, failing, "revert") {
+		t.Error("isTearingDown() with an unreadable Cluster = true, want false")
+	}
+	deleting := func(context.Context) (bool, error) { return true, nil }
+	if !isTearingDown(ctx, deleting, "revert") {
+		t.Error("isTearingDown() with a deleted Cluster = false, want true")
+	}
+}
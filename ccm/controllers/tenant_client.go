@@ -0,0 +1,36 @@
+/*
+Copyright 2025 Kube-DC Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"k8s.io/client-go/kubernetes"
+)
+
+// TenantClientProvider returns the current tenant cluster client. The NodeReconciler is one: it
+// rebuilds its client when the tenant API server stops answering (see checkTenantHealth), and
+// controllers sharing it pick the new client up on their next request.
+type TenantClientProvider interface {
+	GetTenantClient() kubernetes.Interface
+}
+
+// tenantClientFrom returns the provider's current client, or static without a provider
+func tenantClientFrom(provider TenantClientProvider, static kubernetes.Interface) kubernetes.Interface {
+	if provider != nil {
+		return provider.GetTenantClient()
+	}
+	return static
+}
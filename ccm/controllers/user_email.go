@@ -0,0 +1,107 @@
+/*
+Copyright 2025 Kube-DC Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	infrav1 "github.com/kube-dc/cluster-api-provider-cloudsigma/api/v1beta1"
+)
+
+// AnnotationOwnerEmail is the annotation on a CloudSigmaCluster or its management namespace
+// naming the CloudSigma user that owns the tenant cluster
+const AnnotationOwnerEmail = "cloudsigma.com/owner-email"
+
+// Sources ResolveUserEmail reports the user email was resolved from
+const (
+	UserEmailSourceFlag              = "--user-email"
+	UserEmailSourceCloudSigmaCluster = "CloudSigmaCluster"
+	UserEmailSourceNamespace         = "namespace"
+)
+
+// ResolveUserEmail returns the CloudSigma user the CCM impersonates, and where it was found. It
+// tries, in order, the explicit --user-email value, the spec.userEmail or owner email annotation
+// of the CloudSigmaCluster behind the Cluster namespace/clusterName, and the owner email
+// annotation of the management namespace. The management cluster is only read when c is set and
+// namespace is not empty. If no source names a user, the error lists the ones tried.
+func ResolveUserEmail(ctx context.Context, c client.Reader, explicit, namespace, clusterName string) (email, source string, err error) {
+	if explicit != "" {
+		return explicit, UserEmailSourceFlag, nil
+	}
+	if c == nil || namespace == "" {
+		return "", "", fmt.Errorf("no user email to impersonate: set --user-email, or set --cluster-namespace so it is read from the CloudSigmaCluster or namespace")
+	}
+
+	csCluster, err := cloudSigmaClusterFor(ctx, c, namespace, clusterName)
+	if err != nil {
+		return "", "", err
+	}
+	if csCluster != nil {
+		if csCluster.Spec.UserEmail != "" {
+			return csCluster.Spec.UserEmail, UserEmailSourceCloudSigmaCluster, nil
+		}
+		if email := csCluster.Annotations[AnnotationOwnerEmail]; email != "" {
+			return email, UserEmailSourceCloudSigmaCluster, nil
+		}
+	}
+
+	ns := &corev1.Namespace{}
+	if err := c.Get(ctx, types.NamespacedName{Name: namespace}, ns); err != nil && !apierrors.IsNotFound(err) {
+		return "", "", fmt.Errorf("failed to get namespace %s: %w", namespace, err)
+	}
+	if email := ns.Annotations[AnnotationOwnerEmail]; email != "" {
+		return email, UserEmailSourceNamespace, nil
+	}
+
+	return "", "", fmt.Errorf("no user email to impersonate: set --user-email, spec.userEmail or the %s annotation on the CloudSigmaCluster of Cluster %s/%s, or the %s annotation on namespace %s",
+		AnnotationOwnerEmail, namespace, clusterName, AnnotationOwnerEmail, namespace)
+}
+
+// cloudSigmaClusterFor returns the CloudSigmaCluster the Cluster namespace/name references, or nil
+// if there is no such Cluster or it has no CloudSigmaCluster
+func cloudSigmaClusterFor(ctx context.Context, c client.Reader, namespace, name string) (*infrav1.CloudSigmaCluster, error) {
+	if name == "" {
+		return nil, nil
+	}
+	cluster := &clusterv1.Cluster{}
+	if err := c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, cluster); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get Cluster %s/%s: %w", namespace, name, err)
+	}
+	ref := cluster.Spec.InfrastructureRef
+	if ref == nil || ref.Kind != "CloudSigmaCluster" {
+		return nil, nil
+	}
+
+	csCluster := &infrav1.CloudSigmaCluster{}
+	if err := c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: ref.Name}, csCluster); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get CloudSigmaCluster %s/%s: %w", namespace, ref.Name, err)
+	}
+	return csCluster, nil
+}
@@ -0,0 +1,125 @@
+/*
+Copyright 2025 Kube-DC Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	crfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	infrav1 "github.com/kube-dc/cluster-api-provider-cloudsigma/api/v1beta1"
+)
+
+func TestResolveUserEmail(t *testing.T) {
+	scheme := runtime.NewScheme()
+	for _, add := range []func(*runtime.Scheme) error{corev1.AddToScheme, clusterv1.AddToScheme, infrav1.AddToScheme} {
+		if err := add(scheme); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cluster := &clusterv1.Cluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "tenant", Namespace: "tenants"},
+		Spec: clusterv1.ClusterSpec{InfrastructureRef: &corev1.ObjectReference{
+			APIVersion: infrav1.GroupVersion.String(), Kind: "CloudSigmaCluster", Name: "tenant-cs",
+		}},
+	}
+	csCluster := func(spec, annotation string) *infrav1.CloudSigmaCluster {
+		c := &infrav1.CloudSigmaCluster{ObjectMeta: metav1.ObjectMeta{Name: "tenant-cs", Namespace: "tenants"}}
+		c.Spec.UserEmail = spec
+		if annotation != "" {
+			c.Annotations = map[string]string{AnnotationOwnerEmail: annotation}
+		}
+		return c
+	}
+	namespace := func(annotation string) *corev1.Namespace {
+		ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "tenants"}}
+		if annotation != "" {
+			ns.Annotations = map[string]string{AnnotationOwnerEmail: annotation}
+		}
+		return ns
+	}
+
+	tests := []struct {
+		name       string
+		explicit   string
+		namespace  string
+		objects    []client.Object
+		wantEmail  string
+		wantSource string
+	}{
+		{
+			name:     "flag wins",
+			explicit: "flag@example.com", namespace: "tenants",
+			objects:   []client.Object{cluster, csCluster("spec@example.com", ""), namespace("ns@example.com")},
+			wantEmail: "flag@example.com", wantSource: UserEmailSourceFlag,
+		},
+		{
+			name:      "CloudSigmaCluster spec",
+			namespace: "tenants",
+			objects:   []client.Object{cluster, csCluster("spec@example.com", "annotated@example.com"), namespace("ns@example.com")},
+			wantEmail: "spec@example.com", wantSource: UserEmailSourceCloudSigmaCluster,
+		},
+		{
+			name:      "CloudSigmaCluster annotation",
+			namespace: "tenants",
+			objects:   []client.Object{cluster, csCluster("", "annotated@example.com"), namespace("ns@example.com")},
+			wantEmail: "annotated@example.com", wantSource: UserEmailSourceCloudSigmaCluster,
+		},
+		{
+			name:      "namespace owner",
+			namespace: "tenants",
+			objects:   []client.Object{cluster, csCluster("", ""), namespace("ns@example.com")},
+			wantEmail: "ns@example.com", wantSource: UserEmailSourceNamespace,
+		},
+		{
+			name:      "namespace owner without a CloudSigmaCluster",
+			namespace: "tenants",
+			objects:   []client.Object{namespace("ns@example.com")},
+			wantEmail: "ns@example.com", wantSource: UserEmailSourceNamespace,
+		},
+		{
+			name:      "unresolved",
+			namespace: "tenants",
+			objects:   []client.Object{cluster, csCluster("", ""), namespace("")},
+		},
+		{
+			name: "unresolved without a cluster namespace",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := crfake.NewClientBuilder().WithScheme(scheme).WithObjects(tt.objects...).Build()
+			email, source, err := ResolveUserEmail(context.Background(), c, tt.explicit, tt.namespace, "tenant")
+			if email != tt.wantEmail || source != tt.wantSource {
+				t.Errorf("ResolveUserEmail() = %q, %q; want %q, %q", email, source, tt.wantEmail, tt.wantSource)
+			}
+			if tt.wantEmail == "" && (err == nil || !strings.Contains(err.Error(), "--user-email")) {
+				t.Errorf("ResolveUserEmail() error = %v, want one naming --user-email", err)
+			}
+			if tt.wantEmail != "" && err != nil {
+				t.Errorf("ResolveUserEmail() error = %v", err)
+			}
+		})
+	}
+}
@@ -17,8 +17,13 @@ limitations under the License.
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
+	"net/http"
 	"os"
+	"sort"
+	"time"
 
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
@@ -30,12 +35,18 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
 
+	infrav1alpha1 "github.com/kube-dc/cluster-api-provider-cloudsigma/api/v1alpha1"
 	infrav1 "github.com/kube-dc/cluster-api-provider-cloudsigma/api/v1beta1"
 	"github.com/kube-dc/cluster-api-provider-cloudsigma/controllers"
 	"github.com/kube-dc/cluster-api-provider-cloudsigma/pkg/auth"
+	"github.com/kube-dc/cluster-api-provider-cloudsigma/pkg/cloud"
+	"github.com/kube-dc/cluster-api-provider-cloudsigma/pkg/regions"
 	// +kubebuilder:scaffold:imports
 )
 
+// startupVerifyTimeout bounds the impersonation check run before the manager starts
+const startupVerifyTimeout = 30 * time.Second
+
 var (
 	scheme   = runtime.NewScheme()
 	setupLog = ctrl.Log.WithName("setup")
@@ -44,6 +55,7 @@ var (
 func init() {
 	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
 	utilruntime.Must(clusterv1.AddToScheme(scheme))
+	utilruntime.Must(infrav1alpha1.AddToScheme(scheme))
 	utilruntime.Must(infrav1.AddToScheme(scheme))
 	// +kubebuilder:scaffold:scheme
 }
@@ -52,34 +64,61 @@ func main() {
 	var metricsAddr string
 	var enableLeaderElection bool
 	var probeAddr string
+	var enableWebhooks bool
 
 	// Legacy credential-based authentication (only used when explicitly enabled)
 	var cloudsigmaUsername string
 	var cloudsigmaPassword string
 	var cloudsigmaRegion string
+	var regionEndpoints string
 	var legacyCredentialsEnabled bool
 
 	// Impersonation-based authentication (default)
 	var oauthURL string
 	var clientID string
 	var clientSecret string
+	var oauthAudience string
+	var verifyUserEmail string
+
+	// Reconcile intervals
+	var machineRequeueInterval time.Duration
+	var machineSyncInterval time.Duration
+	var serverStartTimeout time.Duration
+	var retryStuckServerStart bool
+	var maxConcurrentReconciles int
+
+	// Server inventory meta
+	var serverInventoryKeys string
 
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
+	flag.BoolVar(&enableWebhooks, "enable-webhooks", os.Getenv("ENABLE_WEBHOOKS") == "true",
+		"Serve the v1alpha1 <-> v1beta1 conversion and the defaulting/validation webhooks. Requires serving certificates in the webhook cert dir.")
 
 	// Impersonation configuration (default mode)
 	flag.StringVar(&oauthURL, "oauth-url", os.Getenv("CLOUDSIGMA_OAUTH_URL"), "CloudSigma OAuth/Keycloak URL for impersonation")
 	flag.StringVar(&clientID, "client-id", os.Getenv("CLOUDSIGMA_CLIENT_ID"), "Service account client ID for impersonation")
 	flag.StringVar(&clientSecret, "client-secret", os.Getenv("CLOUDSIGMA_CLIENT_SECRET"), "Service account client secret for impersonation")
+	flag.StringVar(&oauthAudience, "oauth-audience", auth.DefaultAudience, "Audience requested in the OAuth RPT token exchange; change only for a custom Keycloak setup")
+	flag.StringVar(&verifyUserEmail, "verify-user-email", os.Getenv("CLOUDSIGMA_VERIFY_USER_EMAIL"), "User impersonated at startup and by the readiness probe to verify the API of every configured region accepts impersonated tokens; without it only the OAuth token exchange is verified")
 
 	// Legacy credentials (must be explicitly enabled)
 	flag.BoolVar(&legacyCredentialsEnabled, "enable-legacy-credentials", os.Getenv("CLOUDSIGMA_ENABLE_LEGACY_CREDENTIALS") == "true", "Enable legacy username/password authentication as fallback")
 	flag.StringVar(&cloudsigmaUsername, "cloudsigma-username", os.Getenv("CLOUDSIGMA_USERNAME"), "CloudSigma API username (only used with --enable-legacy-credentials)")
 	flag.StringVar(&cloudsigmaPassword, "cloudsigma-password", os.Getenv("CLOUDSIGMA_PASSWORD"), "CloudSigma API password (only used with --enable-legacy-credentials)")
 	flag.StringVar(&cloudsigmaRegion, "cloudsigma-region", os.Getenv("CLOUDSIGMA_REGION"), "CloudSigma region (default: zrh)")
+	flag.StringVar(&regionEndpoints, "cloudsigma-region-endpoints", os.Getenv("CLOUDSIGMA_REGION_ENDPOINTS"), "Endpoints of CloudSigma regions not on <region>.cloudsigma.com, as comma-separated region=api-host[|direct-host] entries")
+
+	// Reconcile intervals
+	flag.DurationVar(&machineRequeueInterval, "machine-requeue-interval", controllers.DefaultMachineRequeueInterval, "How often a CloudSigmaMachine whose server is still provisioning is re-checked")
+	flag.DurationVar(&machineSyncInterval, "machine-sync-interval", controllers.DefaultMachineSyncInterval, "How often a ready CloudSigmaMachine is re-checked against the CloudSigma API")
+	flag.DurationVar(&serverStartTimeout, "server-start-timeout", controllers.DefaultServerStartTimeout, "How long a server may take to reach running before the CloudSigmaMachine's ServerReady condition reports a timeout")
+	flag.BoolVar(&retryStuckServerStart, "retry-stuck-server-start", false, "Stop and start a server once when it is still starting after --server-start-timeout, before reporting the timeout")
+	flag.StringVar(&serverInventoryKeys, "server-inventory-keys", "", "Comma-separated CloudSigmaMachine label and annotation keys copied into server meta, prefixed with "+controllers.InventoryMetaPrefix+", and kept in sync")
+	flag.IntVar(&maxConcurrentReconciles, "max-concurrent-reconciles", controllers.DefaultMaxConcurrentReconciles, "Number of CloudSigmaMachines and CloudSigmaClusters each reconciled in parallel")
 
 	opts := zap.Options{
 		Development: true,
@@ -89,6 +128,31 @@ func main() {
 
 	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
 
+	if err := regions.ApplyOverrides(regionEndpoints); err != nil {
+		setupLog.Error(err, "invalid flag")
+		os.Exit(1)
+	}
+	if err := controllers.ValidateInterval("machine-requeue-interval", machineRequeueInterval, controllers.MinMachineRequeueInterval); err != nil {
+		setupLog.Error(err, "invalid flag")
+		os.Exit(1)
+	}
+	if err := controllers.ValidateInterval("machine-sync-interval", machineSyncInterval, controllers.MinMachineSyncInterval); err != nil {
+		setupLog.Error(err, "invalid flag")
+		os.Exit(1)
+	}
+	if err := controllers.ValidateInterval("server-start-timeout", serverStartTimeout, controllers.MinServerStartTimeout); err != nil {
+		setupLog.Error(err, "invalid flag")
+		os.Exit(1)
+	}
+	if err := controllers.ValidateMaxConcurrentReconciles(maxConcurrentReconciles); err != nil {
+		setupLog.Error(err, "invalid flag")
+		os.Exit(1)
+	}
+	if oauthAudience == "" {
+		setupLog.Error(errors.New("--oauth-audience must not be empty"), "invalid flag")
+		os.Exit(1)
+	}
+
 	// Determine authentication mode - impersonation is default
 	var impersonationClient *auth.ImpersonationClient
 
@@ -99,12 +163,13 @@ func main() {
 			OAuthURL:     oauthURL,
 			ClientID:     clientID,
 			ClientSecret: clientSecret,
+			Audience:     oauthAudience,
 		})
 		if err != nil {
 			setupLog.Error(err, "Failed to create impersonation client")
 			os.Exit(1)
 		}
-		setupLog.Info("Impersonation mode configured (default)", "oauthURL", oauthURL, "clientID", clientID)
+		setupLog.Info("Impersonation mode configured (default)", "oauthURL", oauthURL, "clientID", clientID, "audience", oauthAudience)
 	} else {
 		setupLog.Info("Impersonation not configured - CLOUDSIGMA_OAUTH_URL, CLOUDSIGMA_CLIENT_ID, CLOUDSIGMA_CLIENT_SECRET required")
 	}
@@ -135,10 +200,42 @@ func main() {
 
 	setupLog.Info("Starting CAPCS", "region", cloudsigmaRegion, "impersonation", impersonationClient != nil, "legacyFallback", legacyCredentialsEnabled)
 
+	// The configured regions are the default one and every one with overridden endpoints
+	configuredRegions := []string{cloudsigmaRegion}
+	overridden, _ := regions.ParseOverrides(regionEndpoints)
+	for region := range overridden {
+		if region != cloudsigmaRegion {
+			configuredRegions = append(configuredRegions, region)
+		}
+	}
+	sort.Strings(configuredRegions[1:])
+
+	// Surface OAuth misconfiguration now rather than at the first reconcile
+	if impersonationClient != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), startupVerifyTimeout)
+		err := cloud.VerifyImpersonation(ctx, impersonationClient, verifyUserEmail, configuredRegions)
+		cancel()
+		if err != nil {
+			setupLog.Error(err, "unable to verify impersonation", "oauthURL", oauthURL, "regions", configuredRegions)
+			os.Exit(1)
+		}
+		setupLog.Info("Verified impersonation", "user", verifyUserEmail, "regions", configuredRegions)
+	}
+
+	// CloudSigma API reachability is served next to the metrics rather than as a readiness check:
+	// the webhooks run in this pod, and an API outage must not take their endpoints away
+	apiProbe, err := cloud.APIProbe(impersonationClient, verifyUserEmail, cloudsigmaUsername, cloudsigmaPassword, configuredRegions)
+	if err != nil {
+		setupLog.Error(err, "unable to create CloudSigma API probe")
+		os.Exit(1)
+	}
+	apiCheck := cloud.NewReachabilityCheck(apiProbe, cloud.DefaultReachabilityTimeout, cloud.DefaultReachabilityCacheTTL)
+
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
 		Scheme: scheme,
 		Metrics: metricsserver.Options{
-			BindAddress: metricsAddr,
+			BindAddress:   metricsAddr,
+			ExtraHandlers: map[string]http.Handler{"/cloudsigma-api": apiCheck},
 		},
 		HealthProbeBindAddress: probeAddr,
 		LeaderElection:         enableLeaderElection,
@@ -157,6 +254,8 @@ func main() {
 		CloudSigmaPassword:       cloudsigmaPassword,
 		CloudSigmaRegion:         cloudsigmaRegion,
 		ImpersonationClient:      impersonationClient,
+		Recorder:                 mgr.GetEventRecorderFor("cloudsigmacluster-controller"),
+		MaxConcurrentReconciles:  maxConcurrentReconciles,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "CloudSigmaCluster")
 		os.Exit(1)
@@ -170,10 +269,32 @@ func main() {
 		CloudSigmaPassword:       cloudsigmaPassword,
 		CloudSigmaRegion:         cloudsigmaRegion,
 		ImpersonationClient:      impersonationClient,
+		Recorder:                 mgr.GetEventRecorderFor("cloudsigmamachine-controller"),
+		RequeueInterval:          machineRequeueInterval,
+		SyncInterval:             machineSyncInterval,
+		ServerStartTimeout:       serverStartTimeout,
+		RetryStuckStart:          retryStuckServerStart,
+		InventoryMetaKeys:        controllers.ParseInventoryMetaKeys(serverInventoryKeys),
+		MaxConcurrentReconciles:  maxConcurrentReconciles,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "CloudSigmaMachine")
 		os.Exit(1)
 	}
+
+	if enableWebhooks {
+		if err = (&infrav1.CloudSigmaCluster{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "CloudSigmaCluster")
+			os.Exit(1)
+		}
+		if err = (&infrav1.CloudSigmaMachine{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "CloudSigmaMachine")
+			os.Exit(1)
+		}
+		if err = (&infrav1.CloudSigmaMachineTemplate{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "CloudSigmaMachineTemplate")
+			os.Exit(1)
+		}
+	}
 	// +kubebuilder:scaffold:builder
 
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
@@ -22,8 +22,10 @@ import (
 	"time"
 
 	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
 	"sigs.k8s.io/cluster-api/util"
 	"sigs.k8s.io/cluster-api/util/annotations"
@@ -55,12 +57,23 @@ type CloudSigmaClusterReconciler struct {
 
 	// Impersonation-based authentication (preferred)
 	ImpersonationClient *auth.ImpersonationClient
+
+	// Recorder emits Kubernetes events for cluster lifecycle transitions
+	Recorder record.EventRecorder
+
+	// MaxConcurrentReconciles is the number of clusters reconciled in parallel
+	// (default: DefaultMaxConcurrentReconciles)
+	MaxConcurrentReconciles int
+
+	// throttle holds off reconciles while CloudSigma is rate limiting the controller
+	throttle apiThrottle
 }
 
 // +kubebuilder:rbac:groups=infrastructure.cluster.x-k8s.io,resources=cloudsigmaclusters,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=infrastructure.cluster.x-k8s.io,resources=cloudsigmaclusters/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=infrastructure.cluster.x-k8s.io,resources=cloudsigmaclusters/finalizers,verbs=update
 // +kubebuilder:rbac:groups=cluster.x-k8s.io,resources=clusters;clusters/status,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=events,verbs=create;patch
 
 func (r *CloudSigmaClusterReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	log := ctrl.LoggerFrom(ctx)
@@ -92,6 +105,12 @@ func (r *CloudSigmaClusterReconciler) Reconcile(ctx context.Context, req ctrl.Re
 		return ctrl.Result{}, nil
 	}
 
+	// While CloudSigma is rate limiting the controller, leave the API alone
+	if wait := r.throttle.wait(time.Now()); wait > 0 {
+		log.V(2).Info("CloudSigma API is rate limiting requests, deferring reconcile", "requeueAfter", wait)
+		return ctrl.Result{RequeueAfter: wait}, nil
+	}
+
 	// Initialize the cloud client
 	cloudClient, err := r.getCloudClient(ctx, cloudSigmaCluster)
 	if err != nil {
@@ -100,13 +119,18 @@ func (r *CloudSigmaClusterReconciler) Reconcile(ctx context.Context, req ctrl.Re
 		return ctrl.Result{RequeueAfter: 30 * time.Second}, nil
 	}
 
-	// Handle deleted clusters
+	var result ctrl.Result
 	if !cloudSigmaCluster.ObjectMeta.DeletionTimestamp.IsZero() {
-		return r.reconcileDelete(ctx, cloudClient, cloudSigmaCluster)
+		// Handle deleted clusters
+		result, err = r.reconcileDelete(ctx, cloudClient, cloudSigmaCluster)
+	} else {
+		// Handle non-deleted clusters
+		result, err = r.reconcileNormal(ctx, cloudClient, cluster, cloudSigmaCluster)
 	}
-
-	// Handle non-deleted clusters
-	return r.reconcileNormal(ctx, cloudClient, cluster, cloudSigmaCluster)
+	if throttled, ok := r.throttle.requeue(ctx, err); ok {
+		return throttled, nil
+	}
+	return result, err
 }
 
 // getCloudClient creates a CloudSigma client, using impersonation if configured
@@ -158,16 +182,34 @@ func (r *CloudSigmaClusterReconciler) reconcileNormal(
 		}
 	}
 
+	// Surface that provisioning has started before doing any cloud work
+	if phase := clusterPhase(cloudSigmaCluster); phase != cloudSigmaCluster.Status.Phase {
+		cloudSigmaCluster.Status.Phase = phase
+		if err := r.Status().Update(ctx, cloudSigmaCluster); err != nil {
+			return ctrl.Result{}, errors.Wrap(err, "failed to update status")
+		}
+	}
+
 	// Reconcile VLAN if specified
 	if cloudSigmaCluster.Spec.VLAN != nil {
 		if err := r.reconcileVLAN(ctx, cloudClient, cloudSigmaCluster); err != nil {
+			conditions.MarkFalse(cloudSigmaCluster, infrav1.NetworkReadyCondition, infrav1.NetworkCreateFailedReason, clusterv1.ConditionSeverityWarning, "%s", err.Error())
+			r.Recorder.Eventf(cloudSigmaCluster, corev1.EventTypeWarning, EventReasonVLANReconcileFailed, "Failed to reconcile VLAN: %v", err)
+			cloudSigmaCluster.Status.Phase = clusterPhase(cloudSigmaCluster)
+			if updateErr := r.Status().Update(ctx, cloudSigmaCluster); updateErr != nil {
+				log.Error(updateErr, "Failed to update status after VLAN error")
+			}
 			return ctrl.Result{}, errors.Wrap(err, "failed to reconcile VLAN")
 		}
 	}
 
 	// Mark cluster as ready
+	if !cloudSigmaCluster.Status.Ready {
+		r.Recorder.Event(cloudSigmaCluster, corev1.EventTypeNormal, EventReasonClusterReady, "CloudSigma infrastructure is ready")
+	}
 	cloudSigmaCluster.Status.Ready = true
 	conditions.MarkTrue(cloudSigmaCluster, infrav1.NetworkReadyCondition)
+	cloudSigmaCluster.Status.Phase = clusterPhase(cloudSigmaCluster)
 
 	if err := r.Status().Update(ctx, cloudSigmaCluster); err != nil {
 		return ctrl.Result{}, errors.Wrap(err, "failed to update status")
@@ -184,6 +226,14 @@ func (r *CloudSigmaClusterReconciler) reconcileDelete(
 ) (ctrl.Result, error) {
 	log := ctrl.LoggerFrom(ctx)
 
+	if cloudSigmaCluster.Status.Phase != infrav1.ClusterPhaseDeleting {
+		r.Recorder.Event(cloudSigmaCluster, corev1.EventTypeNormal, EventReasonClusterDeleting, "Cleaning up CloudSigma resources")
+		cloudSigmaCluster.Status.Phase = infrav1.ClusterPhaseDeleting
+		if err := r.Status().Update(ctx, cloudSigmaCluster); err != nil {
+			return ctrl.Result{}, errors.Wrap(err, "failed to update status")
+		}
+	}
+
 	// TODO: Clean up VLAN if it was created by us
 	log.Info("Cleaning up CloudSigma resources")
 
@@ -229,10 +279,31 @@ func (r *CloudSigmaClusterReconciler) reconcileVLAN(
 	return nil
 }
 
+// clusterPhase derives the lifecycle phase from deletion, failure, readiness and network state
+func clusterPhase(cloudSigmaCluster *infrav1.CloudSigmaCluster) infrav1.ClusterPhase {
+	switch {
+	case !cloudSigmaCluster.DeletionTimestamp.IsZero():
+		return infrav1.ClusterPhaseDeleting
+	case cloudSigmaCluster.Status.FailureReason != nil:
+		return infrav1.ClusterPhaseFailed
+	case conditions.IsFalse(cloudSigmaCluster, infrav1.NetworkReadyCondition) &&
+		conditions.GetSeverity(cloudSigmaCluster, infrav1.NetworkReadyCondition) != nil &&
+		*conditions.GetSeverity(cloudSigmaCluster, infrav1.NetworkReadyCondition) == clusterv1.ConditionSeverityError:
+		return infrav1.ClusterPhaseFailed
+	case cloudSigmaCluster.Status.Ready:
+		return infrav1.ClusterPhaseReady
+	case controllerutil.ContainsFinalizer(cloudSigmaCluster, CloudSigmaClusterFinalizer):
+		return infrav1.ClusterPhaseProvisioning
+	default:
+		return infrav1.ClusterPhasePending
+	}
+}
+
 // SetupWithManager sets up the controller with the Manager.
 func (r *CloudSigmaClusterReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&infrav1.CloudSigmaCluster{}).
 		WithEventFilter(predicates.ResourceNotPaused(ctrl.LoggerFrom(context.Background()))).
+		WithOptions(controllerOptions(r.MaxConcurrentReconciles)).
 		Complete(r)
 }
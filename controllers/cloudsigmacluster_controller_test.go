@@ -0,0 +1,187 @@
+/*
+Copyright 2025 Kube-DC Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	infrav1 "github.com/kube-dc/cluster-api-provider-cloudsigma/api/v1beta1"
+)
+
+func TestCloudSigmaClusterReconcilePhase(t *testing.T) {
+	tests := []struct {
+		name       string
+		paused     bool
+		wantPhases []infrav1.ClusterPhase
+		wantPhase  infrav1.ClusterPhase
+	}{
+		{
+			name:       "happy path",
+			wantPhases: []infrav1.ClusterPhase{infrav1.ClusterPhaseProvisioning, infrav1.ClusterPhaseReady},
+			wantPhase:  infrav1.ClusterPhaseReady,
+		},
+		{
+			name:       "paused",
+			paused:     true,
+			wantPhases: nil,
+			wantPhase:  "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scheme := runtime.NewScheme()
+			_ = clusterv1.AddToScheme(scheme)
+			_ = infrav1.AddToScheme(scheme)
+
+			cluster := &clusterv1.Cluster{
+				ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default", UID: "cluster-uid"},
+				Spec:       clusterv1.ClusterSpec{Paused: tt.paused},
+			}
+			cloudSigmaCluster := &infrav1.CloudSigmaCluster{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test",
+					Namespace: "default",
+					OwnerReferences: []metav1.OwnerReference{{
+						APIVersion: clusterv1.GroupVersion.String(),
+						Kind:       "Cluster",
+						Name:       cluster.Name,
+						UID:        cluster.UID,
+					}},
+				},
+				Spec: infrav1.CloudSigmaClusterSpec{Region: "zrh"},
+			}
+
+			var phases []infrav1.ClusterPhase
+			c := fake.NewClientBuilder().
+				WithScheme(scheme).
+				WithObjects(cluster, cloudSigmaCluster).
+				WithStatusSubresource(&infrav1.CloudSigmaCluster{}).
+				WithInterceptorFuncs(interceptor.Funcs{
+					SubResourceUpdate: func(ctx context.Context, c client.Client, subResource string, obj client.Object, opts ...client.SubResourceUpdateOption) error {
+						if csc, ok := obj.(*infrav1.CloudSigmaCluster); ok {
+							phases = append(phases, csc.Status.Phase)
+						}
+						return c.SubResource(subResource).Update(ctx, obj, opts...)
+					},
+				}).
+				Build()
+
+			r := &CloudSigmaClusterReconciler{
+				Client:                   c,
+				Scheme:                   scheme,
+				LegacyCredentialsEnabled: true,
+				CloudSigmaUsername:       "user",
+				CloudSigmaPassword:       "pass",
+				Recorder:                 record.NewFakeRecorder(10),
+			}
+
+			key := types.NamespacedName{Name: "test", Namespace: "default"}
+			if _, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: key}); err != nil {
+				t.Fatalf("Reconcile() error = %v", err)
+			}
+
+			if len(phases) != len(tt.wantPhases) {
+				t.Fatalf("phase transitions = %v, want %v", phases, tt.wantPhases)
+			}
+			for i := range phases {
+				if phases[i] != tt.wantPhases[i] {
+					t.Errorf("phase transitions = %v, want %v", phases, tt.wantPhases)
+					break
+				}
+			}
+
+			got := &infrav1.CloudSigmaCluster{}
+			if err := c.Get(context.Background(), key, got); err != nil {
+				t.Fatalf("failed to get CloudSigmaCluster: %v", err)
+			}
+			if got.Status.Phase != tt.wantPhase {
+				t.Errorf("Status.Phase = %q, want %q", got.Status.Phase, tt.wantPhase)
+			}
+		})
+	}
+}
+
+func TestClusterPhase(t *testing.T) {
+	now := metav1.Now()
+	reason := "InvalidConfiguration"
+
+	tests := []struct {
+		name    string
+		cluster *infrav1.CloudSigmaCluster
+		want    infrav1.ClusterPhase
+	}{
+		{
+			name:    "new",
+			cluster: &infrav1.CloudSigmaCluster{},
+			want:    infrav1.ClusterPhasePending,
+		},
+		{
+			name: "finalizer added",
+			cluster: &infrav1.CloudSigmaCluster{ObjectMeta: metav1.ObjectMeta{
+				Finalizers: []string{CloudSigmaClusterFinalizer},
+			}},
+			want: infrav1.ClusterPhaseProvisioning,
+		},
+		{
+			name:    "ready",
+			cluster: &infrav1.CloudSigmaCluster{Status: infrav1.CloudSigmaClusterStatus{Ready: true}},
+			want:    infrav1.ClusterPhaseReady,
+		},
+		{
+			name:    "failed",
+			cluster: &infrav1.CloudSigmaCluster{Status: infrav1.CloudSigmaClusterStatus{FailureReason: &reason}},
+			want:    infrav1.ClusterPhaseFailed,
+		},
+		{
+			name: "network error",
+			cluster: &infrav1.CloudSigmaCluster{Status: infrav1.CloudSigmaClusterStatus{Conditions: clusterv1.Conditions{{
+				Type:     infrav1.NetworkReadyCondition,
+				Status:   "False",
+				Severity: clusterv1.ConditionSeverityError,
+			}}}},
+			want: infrav1.ClusterPhaseFailed,
+		},
+		{
+			name: "deleting",
+			cluster: &infrav1.CloudSigmaCluster{
+				ObjectMeta: metav1.ObjectMeta{DeletionTimestamp: &now},
+				Status:     infrav1.CloudSigmaClusterStatus{Ready: true},
+			},
+			want: infrav1.ClusterPhaseDeleting,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := clusterPhase(tt.cluster); got != tt.want {
+				t.Errorf("clusterPhase() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
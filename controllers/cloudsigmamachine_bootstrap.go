@@ -0,0 +1,158 @@
+/*
+Copyright 2025 Kube-DC Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/cloudsigma/cloudsigma-sdk-go/cloudsigma"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	capierrors "sigs.k8s.io/cluster-api/errors"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	infrav1 "github.com/kube-dc/cluster-api-provider-cloudsigma/api/v1beta1"
+	"github.com/kube-dc/cluster-api-provider-cloudsigma/pkg/cloud"
+)
+
+// bootstrapFormatError is a bootstrap format the server cannot be created with. Requeueing does not
+// fix it, so the machine is marked failed.
+type bootstrapFormatError struct {
+	err error
+}
+
+func (e *bootstrapFormatError) Error() string {
+	return e.err.Error()
+}
+
+// isBootstrapFormatError reports whether err is a bootstrapFormatError
+func isBootstrapFormatError(err error) bool {
+	var formatErr *bootstrapFormatError
+	return errors.As(err, &formatErr)
+}
+
+// markBootstrapFormatFailed records an unsupported bootstrap format as a terminal failure; the
+// caller persists the status
+func markBootstrapFormatFailed(m *infrav1.CloudSigmaMachine, err error) {
+	reason := string(capierrors.InvalidConfigurationMachineError)
+	message := fmt.Sprintf("cannot create server: %v", err)
+	m.Status.FailureReason = &reason
+	m.Status.FailureMessage = &message
+	m.Status.Ready = false
+	conditions.MarkFalse(m, infrav1.ServerReadyCondition, infrav1.ServerCreateFailedReason,
+		clusterv1.ConditionSeverityError, "%s", message)
+}
+
+// bootstrapDataHash identifies the bootstrap data a server is created with: the secret it was read
+// from, its format and its content
+func bootstrapDataHash(secretName, data string, format cloud.BootstrapFormat) string {
+	sum := sha256.Sum256([]byte(secretName + "\x00" + string(format) + "\x00" + data))
+	return hex.EncodeToString(sum[:])
+}
+
+// setBootstrapDataHash records the hash of the bootstrap data the server is about to be created
+// with; the caller persists the change
+func setBootstrapDataHash(m *infrav1.CloudSigmaMachine, hash string) {
+	if m.Annotations == nil {
+		m.Annotations = map[string]string{}
+	}
+	m.Annotations[infrav1.BootstrapDataHashAnnotation] = hash
+}
+
+// bootstrapDataStale reports whether the server should be recreated with the bootstrap data
+// hashed to current: the data changed since the server was created, and the machine's node has
+// not joined. Servers created before the hash was recorded, or adopted without one, are kept.
+func bootstrapDataStale(machine *clusterv1.Machine, m *infrav1.CloudSigmaMachine, current string) bool {
+	if machine.Status.NodeRef != nil {
+		return false
+	}
+	recorded, ok := m.Annotations[infrav1.BootstrapDataHashAnnotation]
+	return ok && recorded != current
+}
+
+// reconcileBootstrapData recreates the server of a machine whose node never joined when its
+// bootstrap data changed since the server was created, such as after the bootstrap provider
+// rotated an expired join token. The server is stopped, then deleted with its drives, and the
+// instance ID cleared so the next reconcile creates it again from the new data. A non-zero result
+// means the recreation is in progress and reconcileNormal should return it.
+func (r *CloudSigmaMachineReconciler) reconcileBootstrapData(
+	ctx context.Context,
+	cloudClient *cloud.Client,
+	machine *clusterv1.Machine,
+	cloudSigmaMachine *infrav1.CloudSigmaMachine,
+	server *cloudsigma.Server,
+) (ctrl.Result, error) {
+	log := ctrl.LoggerFrom(ctx)
+
+	if machine.Status.NodeRef != nil || cloudSigmaMachine.Annotations[infrav1.BootstrapDataHashAnnotation] == "" {
+		return ctrl.Result{}, nil
+	}
+	data, format, err := r.getBootstrapData(ctx, machine, cloudSigmaMachine)
+	if err != nil {
+		log.V(2).Info("Cannot read bootstrap data to check it for changes", "error", err.Error())
+		return ctrl.Result{}, nil
+	}
+	if !bootstrapDataStale(machine, cloudSigmaMachine, bootstrapDataHash(*machine.Spec.Bootstrap.DataSecretName, data, format)) {
+		return ctrl.Result{}, nil
+	}
+
+	if !conditions.IsFalse(cloudSigmaMachine, infrav1.ServerReadyCondition) ||
+		conditions.GetReason(cloudSigmaMachine, infrav1.ServerReadyCondition) != infrav1.ServerRecreatingReason {
+		log.Info("Bootstrap data changed before the node joined, recreating server", "instanceID", server.UUID)
+		r.Recorder.Eventf(cloudSigmaMachine, corev1.EventTypeNormal, EventReasonBootstrapDataChanged,
+			"Bootstrap data changed before the node joined, recreating server %s", server.UUID)
+	}
+	cloudSigmaMachine.Status.Ready = false
+	conditions.MarkFalse(cloudSigmaMachine, infrav1.ServerReadyCondition, infrav1.ServerRecreatingReason,
+		clusterv1.ConditionSeverityInfo, "Recreating server %s with changed bootstrap data", server.UUID)
+
+	switch server.Status {
+	case "running", "starting":
+		if err := cloudClient.StopServer(ctx, server.UUID); err != nil {
+			r.Recorder.Eventf(cloudSigmaMachine, corev1.EventTypeWarning, EventReasonServerStopFailed,
+				"Failed to stop server %s for recreation: %v", server.UUID, err)
+			return ctrl.Result{}, errors.Wrap(err, "failed to stop server for recreation")
+		}
+		r.Recorder.Eventf(cloudSigmaMachine, corev1.EventTypeNormal, EventReasonServerStopping,
+			"Stopping server %s for recreation", server.UUID)
+	case "stopped":
+		if err := cloudClient.DeleteServer(ctx, server.UUID); err != nil {
+			r.Recorder.Eventf(cloudSigmaMachine, corev1.EventTypeWarning, EventReasonServerDeleteFailed,
+				"Failed to delete server %s for recreation: %v", server.UUID, err)
+			return ctrl.Result{}, errors.Wrap(err, "failed to delete server for recreation")
+		}
+		r.Recorder.Eventf(cloudSigmaMachine, corev1.EventTypeNormal, EventReasonServerDeleted,
+			"Deleted server %s for recreation", server.UUID)
+		if err := releaseAllocatedIPs(ctx, cloudClient, cloudSigmaMachine); err != nil {
+			// The IPs that failed stay recorded and are reused by the new server while still ours
+			log.Error(err, "Failed to release allocated IPs for recreation (continuing)")
+		}
+		cloudSigmaMachine.Status.InstanceID = ""
+		cloudSigmaMachine.Status.InstanceState = ""
+		cloudSigmaMachine.Status.Addresses = nil
+	}
+
+	if err := r.Status().Update(ctx, cloudSigmaMachine); err != nil {
+		log.V(4).Info("Failed to update recreation status", "error", err)
+	}
+	return ctrl.Result{RequeueAfter: r.phaseRequeue(serverPhasePending)}, nil
+}
@@ -0,0 +1,234 @@
+/*
+Copyright 2025 Kube-DC Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cloudsigma/cloudsigma-sdk-go/cloudsigma"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	infrav1 "github.com/kube-dc/cluster-api-provider-cloudsigma/api/v1beta1"
+	"github.com/kube-dc/cluster-api-provider-cloudsigma/pkg/cloud"
+	cloudfake "github.com/kube-dc/cluster-api-provider-cloudsigma/pkg/cloud/fake"
+)
+
+func TestBootstrapDataStale(t *testing.T) {
+	created := bootstrapDataHash("worker-0-bootstrap", "I2Nsb3VkLWNvbmZpZw==", cloud.BootstrapFormatCloudConfig)
+	joined := &clusterv1.Machine{Status: clusterv1.MachineStatus{NodeRef: &corev1.ObjectReference{Name: "worker-0"}}}
+
+	tests := []struct {
+		name     string
+		machine  *clusterv1.Machine
+		recorded map[string]string
+		current  string
+		want     bool
+	}{
+		{
+			name:     "unchanged",
+			recorded: map[string]string{infrav1.BootstrapDataHashAnnotation: created},
+			current:  bootstrapDataHash("worker-0-bootstrap", "I2Nsb3VkLWNvbmZpZw==", cloud.BootstrapFormatCloudConfig),
+		},
+		{
+			name:     "content changed",
+			recorded: map[string]string{infrav1.BootstrapDataHashAnnotation: created},
+			current:  bootstrapDataHash("worker-0-bootstrap", "bmV3LXRva2Vu", cloud.BootstrapFormatCloudConfig),
+			want:     true,
+		},
+		{
+			name:     "secret renamed",
+			recorded: map[string]string{infrav1.BootstrapDataHashAnnotation: created},
+			current:  bootstrapDataHash("worker-0-bootstrap-2", "I2Nsb3VkLWNvbmZpZw==", cloud.BootstrapFormatCloudConfig),
+			want:     true,
+		},
+		{
+			name:     "format changed",
+			recorded: map[string]string{infrav1.BootstrapDataHashAnnotation: created},
+			current:  bootstrapDataHash("worker-0-bootstrap", "I2Nsb3VkLWNvbmZpZw==", cloud.BootstrapFormatIgnition),
+			want:     true,
+		},
+		{
+			name:     "node joined",
+			machine:  joined,
+			recorded: map[string]string{infrav1.BootstrapDataHashAnnotation: created},
+			current:  bootstrapDataHash("worker-0-bootstrap", "bmV3LXRva2Vu", cloud.BootstrapFormatCloudConfig),
+		},
+		{
+			name:    "no hash recorded",
+			current: created,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			machine := tt.machine
+			if machine == nil {
+				machine = &clusterv1.Machine{}
+			}
+			m := &infrav1.CloudSigmaMachine{ObjectMeta: metav1.ObjectMeta{Annotations: tt.recorded}}
+			if got := bootstrapDataStale(machine, m, tt.current); got != tt.want {
+				t.Errorf("bootstrapDataStale() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReconcileBootstrapData(t *testing.T) {
+	ctx := context.Background()
+	setup := func(t *testing.T, nodeRef *corev1.ObjectReference) (*CloudSigmaMachineReconciler, *cloudfake.Server, *cloud.Client, *clusterv1.Machine, *infrav1.CloudSigmaMachine, string) {
+		api := cloudfake.NewServer()
+		t.Cleanup(api.Close)
+		servers, _, err := api.NewSDKClient().Servers.Create(ctx, &cloudsigma.ServerCreateRequest{
+			Servers: []cloudsigma.Server{{Name: "worker-0", CPU: 2000, Memory: 2 << 30, VNCPassword: "secret"}},
+		})
+		if err != nil {
+			t.Fatalf("Servers.Create() error = %v", err)
+		}
+		cloudClient, err := api.NewClient()
+		if err != nil {
+			t.Fatalf("NewClient() error = %v", err)
+		}
+		if err := cloudClient.StartServer(ctx, servers[0].UUID); err != nil {
+			t.Fatalf("StartServer() error = %v", err)
+		}
+
+		machine, secret, m := newTestMachine("worker-0")
+		machine.Status.NodeRef = nodeRef
+		// The join token was rotated after the server was created
+		secret.Data["value"] = []byte("#cloud-config\n# new token\n")
+		m.Annotations = map[string]string{
+			infrav1.BootstrapDataHashAnnotation: bootstrapDataHash(secret.Name, "I2Nsb3VkLWNvbmZpZwo=", cloud.BootstrapFormatCloudConfig),
+		}
+		m.Status = infrav1.CloudSigmaMachineStatus{InstanceID: servers[0].UUID, Ready: true}
+		r, _ := newTestReconciler(newTestClientBuilder(machine, secret, m).Build())
+		return r, api, cloudClient, machine, m, servers[0].UUID
+	}
+	reconcile := func(t *testing.T, r *CloudSigmaMachineReconciler, api *cloudfake.Server, cloudClient *cloud.Client, machine *clusterv1.Machine, m *infrav1.CloudSigmaMachine, uuid string) ctrl.Result {
+		t.Helper()
+		server, ok := api.GetServer(uuid)
+		if !ok {
+			t.Fatalf("server %s does not exist", uuid)
+		}
+		result, err := r.reconcileBootstrapData(ctx, cloudClient, machine, m, &server)
+		if err != nil {
+			t.Fatalf("reconcileBootstrapData() error = %v", err)
+		}
+		return result
+	}
+
+	t.Run("node not joined", func(t *testing.T) {
+		r, api, cloudClient, machine, m, uuid := setup(t, nil)
+
+		// The running server is stopped first
+		if result := reconcile(t, r, api, cloudClient, machine, m, uuid); result.IsZero() {
+			t.Error("reconcileBootstrapData() while stopping = zero result, want a requeue")
+		}
+		if server, _ := api.GetServer(uuid); server.Status != "stopped" {
+			t.Fatalf("server status = %s, want stopped", server.Status)
+		}
+		if m.Status.Ready || conditions.GetReason(m, infrav1.ServerReadyCondition) != infrav1.ServerRecreatingReason {
+			t.Errorf("ready = %v, ServerReady reason = %q; want not ready, %s", m.Status.Ready,
+				conditions.GetReason(m, infrav1.ServerReadyCondition), infrav1.ServerRecreatingReason)
+		}
+
+		// Then deleted, leaving the next reconcile to create it from the new data
+		if result := reconcile(t, r, api, cloudClient, machine, m, uuid); result.IsZero() {
+			t.Error("reconcileBootstrapData() after deleting = zero result, want a requeue")
+		}
+		if _, ok := api.GetServer(uuid); ok {
+			t.Error("server still exists, want it deleted")
+		}
+		if m.Status.InstanceID != "" {
+			t.Errorf("instance ID = %q, want it cleared", m.Status.InstanceID)
+		}
+	})
+
+	t.Run("node joined", func(t *testing.T) {
+		r, api, cloudClient, machine, m, uuid := setup(t, &corev1.ObjectReference{Name: "worker-0"})
+
+		if result := reconcile(t, r, api, cloudClient, machine, m, uuid); !result.IsZero() {
+			t.Errorf("reconcileBootstrapData() = %+v, want a zero result", result)
+		}
+		if server, _ := api.GetServer(uuid); server.Status != "running" || m.Status.InstanceID != uuid {
+			t.Errorf("server status = %s, instance ID = %q; want the server left running", server.Status, m.Status.InstanceID)
+		}
+	})
+}
+
+func TestCloudSigmaMachineReconcile_BootstrapFormatOverride(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name       string
+		format     string
+		wantServer bool
+	}{
+		{name: "supported format", format: "ignition", wantServer: true},
+		{name: "unsupported format", format: "butane"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := cloudfake.NewServer()
+			defer api.Close()
+			cloudClient, err := api.NewClient()
+			if err != nil {
+				t.Fatalf("NewClient() error = %v", err)
+			}
+
+			machine, secret, m := newTestMachine("worker-0")
+			secret.Data["value"] = []byte(`{"ignition":{"version":"3.3.0"}}`)
+			m.Spec.Meta = map[string]string{BootstrapFormatMetaKey: tt.format}
+			r, _ := newTestReconciler(newTestClientBuilder(machine, secret, m).Build())
+
+			// Only the created server matters here, not how far the reconcile gets after creating it
+			result, _ := r.reconcileNormal(ctx, cloudClient, machine, m)
+
+			servers, _, err := api.NewSDKClient().Servers.List(ctx)
+			if err != nil {
+				t.Fatalf("Servers.List() error = %v", err)
+			}
+			if !tt.wantServer {
+				if len(servers) != 0 {
+					t.Errorf("servers = %d, want none", len(servers))
+				}
+				if !result.IsZero() {
+					t.Errorf("reconcileNormal() = %+v, want no requeue", result)
+				}
+				if severity := conditions.GetSeverity(m, infrav1.ServerReadyCondition); m.Status.FailureReason == nil ||
+					severity == nil || *severity != clusterv1.ConditionSeverityError {
+					t.Errorf("failure reason = %v, ServerReady severity = %v; want the machine marked failed",
+						m.Status.FailureReason, severity)
+				}
+				return
+			}
+
+			if len(servers) != 1 {
+				t.Fatalf("servers = %d, want 1", len(servers))
+			}
+			server, _ := api.GetServer(servers[0].UUID)
+			if _, ok := server.Meta[BootstrapFormatMetaKey]; ok {
+				t.Errorf("server meta has %s, want it left out", BootstrapFormatMetaKey)
+			}
+			if _, ok := server.Meta["ignition"]; !ok {
+				t.Error("server meta has no ignition data")
+			}
+		})
+	}
+}
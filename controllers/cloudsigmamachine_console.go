@@ -0,0 +1,204 @@
+/*
+Copyright 2025 Kube-DC Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"time"
+
+	"github.com/cloudsigma/cloudsigma-sdk-go/cloudsigma"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	infrav1 "github.com/kube-dc/cluster-api-provider-cloudsigma/api/v1beta1"
+	"github.com/kube-dc/cluster-api-provider-cloudsigma/pkg/cloud"
+)
+
+// ConsoleURLSecretKey is the data key of the tunnel URL in the console Secret
+const ConsoleURLSecretKey = "url"
+
+// consoleSecretName returns the name of the Secret holding a machine's console tunnel URL
+func consoleSecretName(m *infrav1.CloudSigmaMachine) string {
+	return m.Name + "-console"
+}
+
+// consoleTTL parses the open-console annotation value. "true" and "" select DefaultConsoleTTL;
+// durations above MaxConsoleTTL are capped.
+func consoleTTL(value string) (time.Duration, error) {
+	if value == "" || value == "true" {
+		return DefaultConsoleTTL, nil
+	}
+	ttl, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, errors.Wrapf(err, "invalid %s annotation %q", infrav1.OpenConsoleAnnotation, value)
+	}
+	if ttl <= 0 {
+		return 0, errors.Errorf("invalid %s annotation %q: must be positive", infrav1.OpenConsoleAnnotation, value)
+	}
+	if ttl > MaxConsoleTTL {
+		ttl = MaxConsoleTTL
+	}
+	return ttl, nil
+}
+
+// reconcileConsole opens a VNC console tunnel while the machine carries the open-console
+// annotation, and closes it again once its TTL passes or the annotation is removed. The tunnel
+// URL is kept in a Secret owned by the machine rather than in status. The returned duration is
+// when the tunnel must be closed, or zero if no tunnel is open.
+func (r *CloudSigmaMachineReconciler) reconcileConsole(
+	ctx context.Context,
+	cloudClient *cloud.Client,
+	cloudSigmaMachine *infrav1.CloudSigmaMachine,
+	server *cloudsigma.Server,
+	now time.Time,
+) (time.Duration, error) {
+	log := ctrl.LoggerFrom(ctx)
+
+	value, requested := cloudSigmaMachine.Annotations[infrav1.OpenConsoleAnnotation]
+	console := cloudSigmaMachine.Status.Console
+
+	if console != nil {
+		if requested && now.Before(console.ExpiresAt.Time) {
+			return console.ExpiresAt.Sub(now), nil
+		}
+		return 0, r.closeConsole(ctx, cloudClient, cloudSigmaMachine, server.UUID, requested)
+	}
+
+	if !requested || server.Status != "running" {
+		return 0, nil
+	}
+
+	ttl, err := consoleTTL(value)
+	if err != nil {
+		r.Recorder.Eventf(cloudSigmaMachine, corev1.EventTypeWarning, EventReasonConsoleOpenFailed, "%v", err)
+		return 0, r.removeConsoleAnnotation(ctx, cloudSigmaMachine)
+	}
+
+	url, err := cloudClient.OpenVNCTunnel(ctx, server.UUID)
+	if err != nil {
+		r.Recorder.Eventf(cloudSigmaMachine, corev1.EventTypeWarning, EventReasonConsoleOpenFailed,
+			"Failed to open console for server %s: %v", server.UUID, err)
+		return 0, errors.Wrap(err, "failed to open console")
+	}
+	if err := r.writeConsoleSecret(ctx, cloudSigmaMachine, url); err != nil {
+		if closeErr := cloudClient.CloseVNCTunnel(ctx, server.UUID); closeErr != nil {
+			log.Error(closeErr, "Failed to close console after secret write failed", "instanceID", server.UUID)
+		}
+		return 0, err
+	}
+
+	expiresAt := metav1.NewTime(now.Add(ttl))
+	cloudSigmaMachine.Status.Console = &infrav1.ConsoleStatus{
+		SecretRef: corev1.LocalObjectReference{Name: consoleSecretName(cloudSigmaMachine)},
+		ExpiresAt: expiresAt,
+	}
+	if err := r.Status().Update(ctx, cloudSigmaMachine); err != nil {
+		return 0, errors.Wrap(err, "failed to record console status")
+	}
+	log.Info("Opened console", "instanceID", server.UUID, "expiresAt", expiresAt.Time)
+	r.Recorder.Eventf(cloudSigmaMachine, corev1.EventTypeNormal, EventReasonConsoleOpened,
+		"Opened console for server %s until %s, URL in Secret %s", server.UUID,
+		expiresAt.UTC().Format(time.RFC3339), consoleSecretName(cloudSigmaMachine))
+	return ttl, nil
+}
+
+// closeConsole closes the server's tunnel, deletes the URL Secret and clears status.console.
+// The annotation is removed as well so an expired tunnel isn't reopened.
+func (r *CloudSigmaMachineReconciler) closeConsole(
+	ctx context.Context,
+	cloudClient *cloud.Client,
+	cloudSigmaMachine *infrav1.CloudSigmaMachine,
+	serverUUID string,
+	annotated bool,
+) error {
+	if err := cloudClient.CloseVNCTunnel(ctx, serverUUID); err != nil {
+		if !cloud.IsTerminalError(err) {
+			return errors.Wrap(err, "failed to close console")
+		}
+		// Rejected requests mean there is no tunnel left to close, e.g. after a server restart
+		ctrl.LoggerFrom(ctx).Info("Console tunnel could not be closed, assuming it is gone", "instanceID", serverUUID, "error", err.Error())
+	}
+
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{
+		Namespace: cloudSigmaMachine.Namespace,
+		Name:      cloudSigmaMachine.Status.Console.SecretRef.Name,
+	}}
+	if err := r.Delete(ctx, secret); err != nil && !apierrors.IsNotFound(err) {
+		return errors.Wrap(err, "failed to delete console secret")
+	}
+
+	cloudSigmaMachine.Status.Console = nil
+	if err := r.Status().Update(ctx, cloudSigmaMachine); err != nil {
+		return errors.Wrap(err, "failed to clear console status")
+	}
+	ctrl.LoggerFrom(ctx).Info("Closed console", "instanceID", serverUUID)
+	r.Recorder.Eventf(cloudSigmaMachine, corev1.EventTypeNormal, EventReasonConsoleClosed,
+		"Closed console for server %s", serverUUID)
+
+	if !annotated {
+		return nil
+	}
+	return r.removeConsoleAnnotation(ctx, cloudSigmaMachine)
+}
+
+// removeConsoleAnnotation drops the open-console annotation from the machine
+func (r *CloudSigmaMachineReconciler) removeConsoleAnnotation(ctx context.Context, cloudSigmaMachine *infrav1.CloudSigmaMachine) error {
+	delete(cloudSigmaMachine.Annotations, infrav1.OpenConsoleAnnotation)
+	if err := r.Update(ctx, cloudSigmaMachine); err != nil {
+		return errors.Wrap(err, "failed to remove open-console annotation")
+	}
+	return nil
+}
+
+// writeConsoleSecret creates or updates the machine's console Secret with the tunnel URL
+func (r *CloudSigmaMachineReconciler) writeConsoleSecret(ctx context.Context, cloudSigmaMachine *infrav1.CloudSigmaMachine, url string) error {
+	secret := &corev1.Secret{}
+	key := client.ObjectKey{Namespace: cloudSigmaMachine.Namespace, Name: consoleSecretName(cloudSigmaMachine)}
+	err := r.Get(ctx, key, secret)
+	switch {
+	case apierrors.IsNotFound(err):
+		secret = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      key.Name,
+				Namespace: key.Namespace,
+				Labels:    map[string]string{clusterv1.ClusterNameLabel: cloudSigmaMachine.Labels[clusterv1.ClusterNameLabel]},
+			},
+			Type: corev1.SecretTypeOpaque,
+			Data: map[string][]byte{ConsoleURLSecretKey: []byte(url)},
+		}
+		if err := controllerutil.SetControllerReference(cloudSigmaMachine, secret, r.Scheme); err != nil {
+			return errors.Wrap(err, "failed to set owner of console secret")
+		}
+		if err := r.Create(ctx, secret); err != nil {
+			return errors.Wrap(err, "failed to create console secret")
+		}
+	case err != nil:
+		return errors.Wrap(err, "failed to get console secret")
+	default:
+		secret.Data = map[string][]byte{ConsoleURLSecretKey: []byte(url)}
+		if err := r.Update(ctx, secret); err != nil {
+			return errors.Wrap(err, "failed to update console secret")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,249 @@
+/*
+Copyright 2025 Kube-DC Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cloudsigma/cloudsigma-sdk-go/cloudsigma"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	infrav1 "github.com/kube-dc/cluster-api-provider-cloudsigma/api/v1beta1"
+	"github.com/kube-dc/cluster-api-provider-cloudsigma/pkg/cloud"
+)
+
+func TestConsoleTTL(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{value: "", want: DefaultConsoleTTL},
+		{value: "true", want: DefaultConsoleTTL},
+		{value: "15m", want: 15 * time.Minute},
+		{value: "100h", want: MaxConsoleTTL},
+		{value: "0s", wantErr: true},
+		{value: "-5m", wantErr: true},
+		{value: "yes", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := consoleTTL(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("consoleTTL(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("consoleTTL(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+// consoleAPI counts open_vnc/close_vnc server actions
+type consoleAPI struct {
+	mu     sync.Mutex
+	opens  int
+	closes int
+}
+
+func (a *consoleAPI) calls() (int, int) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.opens, a.closes
+}
+
+func newConsoleTestClient(t *testing.T, serverUUID string) (*cloud.Client, *consoleAPI) {
+	t.Helper()
+
+	api := &consoleAPI{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/2.0/servers/"+serverUUID+"/action/", func(w http.ResponseWriter, r *http.Request) {
+		api.mu.Lock()
+		defer api.mu.Unlock()
+		resp := map[string]string{"action": r.URL.Query().Get("do"), "result": "success", "uuid": serverUUID}
+		switch r.URL.Query().Get("do") {
+		case "open_vnc":
+			api.opens++
+			resp["vnc_url"] = "vnc://direct.zrh.cloudsigma.com:41234"
+		case "close_vnc":
+			api.closes++
+		}
+		writeJSON(w, resp)
+	})
+	cloudClient := newTestClient(t, mux)
+	return cloudClient, api
+}
+
+func TestReconcileConsole_Lifecycle(t *testing.T) {
+	const serverUUID = "7c1e4f6e-0b2f-4a0c-9a51-3d6c2d6f1a22"
+
+	tests := []struct {
+		name string
+		// removeAnnotation drops the annotation halfway through the TTL instead of letting it expire
+		removeAnnotation bool
+	}{
+		{name: "closed after TTL"},
+		{name: "closed when annotation removed", removeAnnotation: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cloudClient, api := newConsoleTestClient(t, serverUUID)
+			m := &infrav1.CloudSigmaMachine{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "worker-0",
+					Namespace:   "default",
+					UID:         "machine-uid",
+					Annotations: map[string]string{infrav1.OpenConsoleAnnotation: "1h"},
+				},
+				Status: infrav1.CloudSigmaMachineStatus{InstanceID: serverUUID},
+			}
+			c := newTestClientBuilder(m).Build()
+			r, _ := newTestReconciler(c)
+			ctx := context.Background()
+			server := &cloudsigma.Server{UUID: serverUUID, Status: "running"}
+			now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+			secretKey := client.ObjectKey{Namespace: "default", Name: "worker-0-console"}
+
+			// Open
+			expiry, err := r.reconcileConsole(ctx, cloudClient, m, server, now)
+			if err != nil {
+				t.Fatalf("reconcileConsole() open error = %v", err)
+			}
+			if expiry != time.Hour {
+				t.Errorf("expiry = %v, want 1h", expiry)
+			}
+			stored := &infrav1.CloudSigmaMachine{}
+			if err := c.Get(ctx, client.ObjectKeyFromObject(m), stored); err != nil {
+				t.Fatalf("Get() error = %v", err)
+			}
+			console := stored.Status.Console
+			if console == nil || console.SecretRef.Name != secretKey.Name || !console.ExpiresAt.Time.Equal(now.Add(time.Hour)) {
+				t.Fatalf("Status.Console = %+v, want secret %s expiring at %v", console, secretKey.Name, now.Add(time.Hour))
+			}
+			secret := &corev1.Secret{}
+			if err := c.Get(ctx, secretKey, secret); err != nil {
+				t.Fatalf("console secret not created: %v", err)
+			}
+			if got := string(secret.Data[ConsoleURLSecretKey]); got != "vnc://direct.zrh.cloudsigma.com:41234" {
+				t.Errorf("console secret url = %q", got)
+			}
+			if len(secret.OwnerReferences) != 1 || secret.OwnerReferences[0].Name != "worker-0" {
+				t.Errorf("console secret owner references = %+v, want worker-0", secret.OwnerReferences)
+			}
+
+			// Still open halfway through the TTL
+			expiry, err = r.reconcileConsole(ctx, cloudClient, m, server, now.Add(30*time.Minute))
+			if err != nil {
+				t.Fatalf("reconcileConsole() error = %v", err)
+			}
+			if opens, closes := api.calls(); opens != 1 || closes != 0 {
+				t.Errorf("open_vnc/close_vnc calls = %d/%d, want 1/0 while the tunnel is live", opens, closes)
+			}
+
+			closeAt := now.Add(time.Hour + time.Second)
+			if tt.removeAnnotation {
+				if expiry != 30*time.Minute {
+					t.Errorf("expiry = %v, want 30m", expiry)
+				}
+				delete(m.Annotations, infrav1.OpenConsoleAnnotation)
+				if err := c.Update(ctx, m); err != nil {
+					t.Fatalf("Update() error = %v", err)
+				}
+				closeAt = now.Add(31 * time.Minute)
+			}
+
+			// Close
+			if _, err := r.reconcileConsole(ctx, cloudClient, m, server, closeAt); err != nil {
+				t.Fatalf("reconcileConsole() close error = %v", err)
+			}
+			if opens, closes := api.calls(); opens != 1 || closes != 1 {
+				t.Errorf("open_vnc/close_vnc calls = %d/%d, want 1/1", opens, closes)
+			}
+			if err := c.Get(ctx, secretKey, &corev1.Secret{}); !apierrors.IsNotFound(err) {
+				t.Errorf("console secret still present after close: %v", err)
+			}
+			if err := c.Get(ctx, client.ObjectKeyFromObject(m), stored); err != nil {
+				t.Fatalf("Get() error = %v", err)
+			}
+			if stored.Status.Console != nil {
+				t.Errorf("Status.Console = %+v after close, want nil", stored.Status.Console)
+			}
+			if _, ok := stored.Annotations[infrav1.OpenConsoleAnnotation]; ok {
+				t.Error("open-console annotation kept after close, tunnel would reopen")
+			}
+
+			// Not reopened
+			if _, err := r.reconcileConsole(ctx, cloudClient, stored, server, closeAt.Add(time.Minute)); err != nil {
+				t.Fatalf("reconcileConsole() error = %v", err)
+			}
+			if opens, _ := api.calls(); opens != 1 {
+				t.Errorf("open_vnc calls = %d after close, want 1", opens)
+			}
+		})
+	}
+}
+
+func TestReconcileConsole_NotOpened(t *testing.T) {
+	const serverUUID = "7c1e4f6e-0b2f-4a0c-9a51-3d6c2d6f1a33"
+
+	tests := []struct {
+		name           string
+		annotation     string
+		serverStatus   string
+		wantAnnotation bool
+		wantEvent      bool
+	}{
+		{name: "server stopped", annotation: "true", serverStatus: "stopped", wantAnnotation: true},
+		{name: "invalid duration", annotation: "forever", serverStatus: "running", wantEvent: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cloudClient, api := newConsoleTestClient(t, serverUUID)
+			m := &infrav1.CloudSigmaMachine{ObjectMeta: metav1.ObjectMeta{
+				Name:        "worker-0",
+				Namespace:   "default",
+				Annotations: map[string]string{infrav1.OpenConsoleAnnotation: tt.annotation},
+			}}
+			r, recorder := newTestReconciler(newTestClientBuilder(m).Build())
+
+			server := &cloudsigma.Server{UUID: serverUUID, Status: tt.serverStatus}
+			if _, err := r.reconcileConsole(context.Background(), cloudClient, m, server, time.Now()); err != nil {
+				t.Fatalf("reconcileConsole() error = %v", err)
+			}
+			if opens, _ := api.calls(); opens != 0 {
+				t.Errorf("open_vnc calls = %d, want 0", opens)
+			}
+			if m.Status.Console != nil {
+				t.Errorf("Status.Console = %+v, want nil", m.Status.Console)
+			}
+			if _, ok := m.Annotations[infrav1.OpenConsoleAnnotation]; ok != tt.wantAnnotation {
+				t.Errorf("annotation present = %v, want %v", ok, tt.wantAnnotation)
+			}
+			if got := len(recorder.Events) > 0; got != tt.wantEvent {
+				t.Errorf("event recorded = %v, want %v", got, tt.wantEvent)
+			}
+		})
+	}
+}
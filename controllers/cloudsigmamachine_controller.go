@@ -21,6 +21,7 @@ import (
 	"encoding/base64"
 	"fmt"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/cloudsigma/cloudsigma-sdk-go/cloudsigma"
@@ -28,15 +29,18 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	capierrors "sigs.k8s.io/cluster-api/errors"
 	"sigs.k8s.io/cluster-api/util"
 	"sigs.k8s.io/cluster-api/util/annotations"
 	"sigs.k8s.io/cluster-api/util/conditions"
 	"sigs.k8s.io/cluster-api/util/predicates"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
-	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 
 	infrav1 "github.com/kube-dc/cluster-api-provider-cloudsigma/api/v1beta1"
 	"github.com/kube-dc/cluster-api-provider-cloudsigma/pkg/auth"
@@ -45,6 +49,13 @@ import (
 
 const (
 	CloudSigmaMachineFinalizer = "cloudsigmamachine.infrastructure.cluster.x-k8s.io"
+
+	// BootstrapFormatMetaKey in spec.meta overrides the bootstrap data format ("cloud-config" or "ignition")
+	BootstrapFormatMetaKey = "bootstrap-format"
+
+	// VNCPasswordMetaKey in spec.meta sets the server console password instead of a generated one.
+	// It is stored in the machine's VNC password Secret and not passed on as server meta.
+	VNCPasswordMetaKey = "vnc-password"
 )
 
 // CloudSigmaMachineReconciler reconciles a CloudSigmaMachine object
@@ -61,13 +72,50 @@ type CloudSigmaMachineReconciler struct {
 	// Impersonation-based authentication (preferred)
 	// When set, the controller will use OAuth impersonation to create VMs in user accounts
 	ImpersonationClient *auth.ImpersonationClient
+
+	// Recorder emits Kubernetes events for server lifecycle transitions
+	Recorder record.EventRecorder
+
+	// RequeueInterval is how often a server that is still provisioning is checked
+	// (default: DefaultMachineRequeueInterval)
+	RequeueInterval time.Duration
+
+	// SyncInterval is how often a ready server is re-checked (default: DefaultMachineSyncInterval)
+	SyncInterval time.Duration
+
+	// ServerStartTimeout is how long a server may take to reach running before ServerReady is
+	// marked with ServerStartTimeoutReason (default: DefaultServerStartTimeout)
+	ServerStartTimeout time.Duration
+
+	// RetryStuckStart stops and starts a server once when it is still starting after
+	// ServerStartTimeout, before ServerReady reports the timeout
+	RetryStuckStart bool
+
+	// InventoryMetaKeys names the CloudSigmaMachine labels and annotations copied into server
+	// meta under InventoryMetaPrefix, and kept in sync, for the CloudSigma inventory
+	InventoryMetaKeys []string
+
+	// MaxConcurrentReconciles is the number of machines reconciled in parallel
+	// (default: DefaultMaxConcurrentReconciles)
+	MaxConcurrentReconciles int
+
+	// Jitter returns a random value in [0, 1) used to spread requeues (default: rand.Float64)
+	Jitter func() float64
+
+	// started records the machines reconciled since the controller started, so that the first
+	// checks of ready machines can be spread out
+	started sync.Map
+
+	// throttle holds off reconciles while CloudSigma is rate limiting the controller
+	throttle apiThrottle
 }
 
 // +kubebuilder:rbac:groups=infrastructure.cluster.x-k8s.io,resources=cloudsigmamachines,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=infrastructure.cluster.x-k8s.io,resources=cloudsigmamachines/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=infrastructure.cluster.x-k8s.io,resources=cloudsigmamachines/finalizers,verbs=update
 // +kubebuilder:rbac:groups=cluster.x-k8s.io,resources=machines;machines/status,verbs=get;list;watch
-// +kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch;create;update;delete
+// +kubebuilder:rbac:groups="",resources=events,verbs=create;patch
 
 func (r *CloudSigmaMachineReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	log := ctrl.LoggerFrom(ctx)
@@ -108,6 +156,19 @@ func (r *CloudSigmaMachineReconciler) Reconcile(ctx context.Context, req ctrl.Re
 		return ctrl.Result{}, nil
 	}
 
+	// After a restart, spread the first checks of ready machines rather than querying every server at once
+	if delay := r.startupDelay(cloudSigmaMachine); delay > 0 {
+		log.V(2).Info("Deferring first check of ready machine", "requeueAfter", delay)
+		return ctrl.Result{RequeueAfter: delay}, nil
+	}
+
+	// While CloudSigma is rate limiting the controller, leave the API alone and spread the retries
+	if wait := r.throttle.wait(time.Now()); wait > 0 {
+		wait += time.Duration(r.jitter() * RequeueJitterFraction * float64(wait))
+		log.V(2).Info("CloudSigma API is rate limiting requests, deferring reconcile", "requeueAfter", wait)
+		return ctrl.Result{RequeueAfter: wait}, nil
+	}
+
 	// Fetch the CloudSigmaCluster to get user email for impersonation
 	// Note: InfrastructureRef may point to KubevirtCluster (for Kamaji compatibility),
 	// so we look up CloudSigmaCluster by the CAPI cluster name directly
@@ -132,21 +193,38 @@ func (r *CloudSigmaMachineReconciler) Reconcile(ctx context.Context, req ctrl.Re
 		if isProvisioned && !isBeingDeleted {
 			// Already running machine with config issue (e.g. missing userEmail) - no action needed, check back later
 			log.V(2).Info("CloudSigma client unavailable for provisioned machine, will retry in 5m", "error", err)
-			return ctrl.Result{RequeueAfter: 5 * time.Minute}, nil
+			return r.jitterRequeue(ctrl.Result{RequeueAfter: 5 * time.Minute}, nil)
 		}
 
 		// Machine needs API access (creating or deleting) - retry sooner
 		log.Error(err, "Failed to create CloudSigma client, will retry in 30s")
-		return ctrl.Result{RequeueAfter: 30 * time.Second}, nil
+		return r.jitterRequeue(ctrl.Result{RequeueAfter: 30 * time.Second}, nil)
 	}
 
-	// Handle deleted machines
+	return r.reconcile(ctx, cloudClient, machine, cloudSigmaMachine)
+}
+
+// reconcile reconciles the machine's server with cloudClient. A reconcile that CloudSigma
+// throttled is requeued after the delay it asked for; other requeues are jittered.
+func (r *CloudSigmaMachineReconciler) reconcile(
+	ctx context.Context,
+	cloudClient *cloud.Client,
+	machine *clusterv1.Machine,
+	cloudSigmaMachine *infrav1.CloudSigmaMachine,
+) (ctrl.Result, error) {
+	var result ctrl.Result
+	var err error
 	if !cloudSigmaMachine.ObjectMeta.DeletionTimestamp.IsZero() {
-		return r.reconcileDelete(ctx, cloudClient, cloudSigmaMachine)
+		// Handle deleted machines
+		result, err = r.reconcileDelete(ctx, cloudClient, machine, cloudSigmaMachine)
+	} else {
+		// Handle non-deleted machines
+		result, err = r.reconcileNormal(ctx, cloudClient, machine, cloudSigmaMachine)
 	}
-
-	// Handle non-deleted machines
-	return r.reconcileNormal(ctx, cloudClient, machine, cloudSigmaMachine)
+	if throttled, ok := r.throttle.requeue(ctx, err); ok {
+		return throttled, nil
+	}
+	return r.jitterRequeue(result, err)
 }
 
 // getCloudClient creates a CloudSigma client, using impersonation if configured
@@ -238,6 +316,13 @@ func (r *CloudSigmaMachineReconciler) reconcileNormal(
 		}
 	}
 
+	// A terminal failure was recorded - stop reconciling so CAPI marks the Machine as failed
+	if cloudSigmaMachine.Status.FailureReason != nil {
+		log.Info("CloudSigmaMachine has a terminal failure, skipping reconciliation",
+			"failureReason", *cloudSigmaMachine.Status.FailureReason)
+		return ctrl.Result{}, nil
+	}
+
 	// Check if server already exists (idempotency check)
 	var server *cloudsigma.Server
 	var err error
@@ -316,15 +401,41 @@ func (r *CloudSigmaMachineReconciler) reconcileNormal(
 		// Check if server already exists by name or metadata (race condition protection)
 		existingServer, err := cloudClient.FindServerByNameOrMeta(ctx, cloudSigmaMachine.Name, machineUID)
 		if err != nil {
+			if cloud.IsDuplicateServerNameError(err) {
+				// Adopting either server could leave the other running unmanaged - needs a human
+				log.Error(err, "Several servers match this machine, not adopting or creating any")
+				r.Recorder.Eventf(cloudSigmaMachine, corev1.EventTypeWarning, EventReasonDuplicateServerName, "%v", err)
+				return ctrl.Result{RequeueAfter: r.syncInterval()}, nil
+			}
 			log.Error(err, "Failed to check for existing server")
-			return ctrl.Result{RequeueAfter: 10 * time.Second}, nil
+			return ctrl.Result{RequeueAfter: r.requeueInterval()}, nil
+		}
+
+		if existingServer != nil {
+			adoptable, err := canAdoptServer(ctx, cloudClient, cloudSigmaMachine, existingServer)
+			if err != nil {
+				log.Error(err, "Failed to check whether existing server can be adopted", "instanceID", existingServer.UUID)
+				return ctrl.Result{RequeueAfter: r.requeueInterval()}, nil
+			}
+			if !adoptable {
+				log.Info("Ignoring existing server that is not managed by this provider",
+					"instanceID", existingServer.UUID, "name", existingServer.Name)
+				r.Recorder.Eventf(cloudSigmaMachine, corev1.EventTypeWarning, EventReasonServerNotAdopted,
+					"Server %s (%s) has the same name but lacks the %s and cluster tags, creating a new one",
+					existingServer.Name, existingServer.UUID, cloud.ManagedByTag)
+				existingServer = nil
+			}
 		}
 
 		if existingServer != nil {
-			// Server already exists, update status and continue
+			// Server already exists (e.g. created before a controller crash), adopt it and continue
 			log.Info("Found existing server, updating status", "instanceID", existingServer.UUID, "name", cloudSigmaMachine.Name)
+			r.Recorder.Eventf(cloudSigmaMachine, corev1.EventTypeNormal, EventReasonServerAdopted,
+				"Adopted existing server %s (%s)", existingServer.Name, existingServer.UUID)
 			cloudSigmaMachine.Status.InstanceID = existingServer.UUID
 			cloudSigmaMachine.Status.InstanceState = existingServer.Status
+			conditions.MarkFalse(cloudSigmaMachine, infrav1.ServerReadyCondition, infrav1.ServerAdoptedReason,
+				clusterv1.ConditionSeverityInfo, "Adopted existing server %s", existingServer.UUID)
 			if err := r.Status().Update(ctx, cloudSigmaMachine); err != nil {
 				log.Error(err, "Failed to update status with existing server", "instanceID", existingServer.UUID)
 				return ctrl.Result{RequeueAfter: 5 * time.Second}, nil
@@ -332,19 +443,54 @@ func (r *CloudSigmaMachineReconciler) reconcileNormal(
 			// Also set providerID in spec (required for Machine to transition to Running)
 			providerID := fmt.Sprintf("cloudsigma://%s", existingServer.UUID)
 			cloudSigmaMachine.Spec.ProviderID = &providerID
+			clearCreationMarker(cloudSigmaMachine)
 			if err := r.Update(ctx, cloudSigmaMachine); err != nil {
 				log.Error(err, "Failed to update spec with providerID for existing server", "instanceID", existingServer.UUID)
 				return ctrl.Result{RequeueAfter: 5 * time.Second}, nil
 			}
 			server = existingServer
+		} else if creationInFlight(cloudSigmaMachine, time.Now()) {
+			// A previous reconcile sent a create request but never persisted the instance ID;
+			// the server may not be listed yet, so wait for it rather than creating a duplicate
+			log.Info("Server creation already in flight, waiting for it to appear",
+				"name", cloudSigmaMachine.Name,
+				"since", cloudSigmaMachine.Annotations[infrav1.CreatingAnnotation])
+			return ctrl.Result{RequeueAfter: r.requeueInterval()}, nil
 		} else {
+			// Drives cloned by an attempt that crashed before creating the server would otherwise leak
+			deleted, wait, err := reapOrphanedDrives(ctx, cloudClient, cloudSigmaMachine, time.Now())
+			if len(deleted) > 0 {
+				log.Info("Deleted drives orphaned by an earlier create attempt", "drives", deleted)
+				r.Recorder.Eventf(cloudSigmaMachine, corev1.EventTypeNormal, EventReasonOrphanedDrivesDeleted,
+					"Deleted drives orphaned by an earlier create attempt: %s", strings.Join(deleted, ", "))
+			}
+			if err != nil {
+				log.Error(err, "Failed to clean up orphaned drives")
+			}
+			if wait {
+				log.Info("Drives from an earlier create attempt still exist, waiting before creating the server", "name", cloudSigmaMachine.Name)
+				return ctrl.Result{RequeueAfter: r.requeueInterval()}, nil
+			}
+
 			log.Info("No existing server found, creating new CloudSigma server", "name", cloudSigmaMachine.Name, "machineUID", machineUID)
 
 			// Get bootstrap data
-			bootstrapData, err := r.getBootstrapData(ctx, machine)
+			bootstrapData, bootstrapFormat, err := r.getBootstrapData(ctx, machine, cloudSigmaMachine)
+			if isBootstrapFormatError(err) {
+				log.Error(err, "Bootstrap data has an unsupported format, not creating server")
+				markBootstrapFormatFailed(cloudSigmaMachine, err)
+				r.Recorder.Eventf(cloudSigmaMachine, corev1.EventTypeWarning, EventReasonServerCreateFailed,
+					"Cannot create server: %v", err)
+				if err := r.Status().Update(ctx, cloudSigmaMachine); err != nil {
+					return ctrl.Result{}, errors.Wrap(err, "failed to record unsupported bootstrap format")
+				}
+				return ctrl.Result{}, nil
+			}
 			if err != nil {
 				log.Info("Bootstrap data not ready yet")
-				return ctrl.Result{RequeueAfter: 10 * time.Second}, nil
+				r.Recorder.Eventf(cloudSigmaMachine, corev1.EventTypeNormal, EventReasonBootstrapDataNotReady,
+					"Waiting for bootstrap data: %v", err)
+				return ctrl.Result{RequeueAfter: r.requeueInterval()}, nil
 			}
 
 			// Create server with machine-uid in metadata for identification
@@ -353,33 +499,100 @@ func (r *CloudSigmaMachineReconciler) reconcileNormal(
 			for k, v := range cloudSigmaMachine.Spec.Meta {
 				meta[k] = v
 			}
+			// The guest can read server meta, so the console password must not end up there
+			delete(meta, VNCPasswordMetaKey)
+			// The format override only selects the meta field the bootstrap data goes to
+			delete(meta, BootstrapFormatMetaKey)
 			// Add machine-uid for duplicate detection
 			meta["machine-uid"] = machineUID
 			meta["cluster"] = cloudSigmaMachine.Labels["cluster.x-k8s.io/cluster-name"]
 			meta["pool"] = cloudSigmaMachine.Labels["cluster.x-k8s.io/deployment-name"]
+			for k, v := range inventoryMeta(cloudSigmaMachine, r.InventoryMetaKeys) {
+				meta[k] = v
+			}
 
 			serverSpec := cloud.ServerSpec{
-				Name:          cloudSigmaMachine.Name,
-				CPU:           cloudSigmaMachine.Spec.CPU,
-				Memory:        cloudSigmaMachine.Spec.Memory,
-				Disks:         cloudSigmaMachine.Spec.Disks,
-				NICs:          cloudSigmaMachine.Spec.NICs,
-				Tags:          cloudSigmaMachine.Spec.Tags,
-				Meta:          meta,
-				BootstrapData: bootstrapData,
+				Name:            cloudSigmaMachine.Name,
+				CPU:             cloudSigmaMachine.Spec.CPU,
+				Memory:          cloudSigmaMachine.Spec.Memory,
+				SMP:             cloudSigmaMachine.Spec.SMP,
+				CPUModel:        cloudSigmaMachine.Spec.CPUModel,
+				CPUFlags:        cloudSigmaMachine.Spec.CPUFlags,
+				Disks:           cloudSigmaMachine.Spec.Disks,
+				NICs:            cloudSigmaMachine.Spec.NICs,
+				Tags:            cloudSigmaMachine.Spec.Tags,
+				Meta:            meta,
+				BootstrapData:   bootstrapData,
+				BootstrapFormat: bootstrapFormat,
+			}
+
+			// Hold off while the account is at capacity rather than failing CreateServer every requeue
+			quotaErr := cloudClient.CheckServerQuota(ctx, serverSpec)
+			if requeueAfter, holdOff := quotaRequeue(quotaErr); holdOff {
+				log.Info("Account quota exhausted, not creating server", "error", quotaErr.Error(), "requeueAfter", requeueAfter)
+				conditions.MarkFalse(cloudSigmaMachine, infrav1.ServerReadyCondition, infrav1.QuotaExceededReason,
+					clusterv1.ConditionSeverityWarning, "%v", quotaErr)
+				r.Recorder.Eventf(cloudSigmaMachine, corev1.EventTypeWarning, EventReasonQuotaExceeded,
+					"Cannot create server: %v", quotaErr)
+				if err := r.Status().Update(ctx, cloudSigmaMachine); err != nil {
+					log.V(4).Info("Failed to update quota status", "error", err)
+				}
+				return ctrl.Result{RequeueAfter: requeueAfter}, nil
+			} else if quotaErr != nil {
+				log.V(2).Info("Skipping quota pre-flight check", "error", quotaErr.Error())
+			}
+
+			nics, err := r.serverNICs(ctx, cloudClient, cloudSigmaMachine)
+			if err != nil {
+				log.Error(err, "Failed to allocate IPs for static NICs")
+				r.Recorder.Eventf(cloudSigmaMachine, corev1.EventTypeWarning, EventReasonIPAllocationFailed,
+					"Cannot create server: %v", err)
+				return ctrl.Result{RequeueAfter: r.requeueInterval()}, nil
+			}
+			serverSpec.NICs = nics
+
+			vncPassword, err := r.ensureVNCPassword(ctx, cloudSigmaMachine)
+			if err != nil {
+				log.Error(err, "Failed to prepare VNC password")
+				return ctrl.Result{RequeueAfter: r.requeueInterval()}, nil
+			}
+			serverSpec.VNCPassword = vncPassword
+
+			// Record the attempt before sending it, so a lost status update cannot lead to a second server.
+			// The bootstrap data hash goes with it, for recreating the server if the data changes.
+			setBootstrapDataHash(cloudSigmaMachine, bootstrapDataHash(*machine.Spec.Bootstrap.DataSecretName, bootstrapData, bootstrapFormat))
+			if err := r.setCreationMarker(ctx, cloudSigmaMachine, time.Now()); err != nil {
+				log.Error(err, "Failed to record server creation marker")
+				return ctrl.Result{RequeueAfter: r.requeueInterval()}, nil
 			}
 
 			server, err = cloudClient.CreateServer(ctx, serverSpec)
 			if err != nil {
-				log.Error(err, "Failed to create server")
-				conditions.MarkFalse(cloudSigmaMachine, infrav1.ServerReadyCondition, infrav1.ServerCreateFailedReason, clusterv1.ConditionSeverityError, err.Error())
-				return ctrl.Result{}, errors.Wrap(err, "failed to create server")
+				log.Error(err, "Failed to create server", "terminal", cloud.IsTerminalError(err))
+				// Nothing was created, so the next attempt need not wait for the grace period
+				clearCreationMarker(cloudSigmaMachine)
+				if updateErr := r.Update(ctx, cloudSigmaMachine); updateErr != nil {
+					log.V(4).Info("Failed to clear server creation marker", "error", updateErr)
+				}
+				result, reconcileErr := handleCreateServerError(cloudSigmaMachine, err)
+				r.Recorder.Eventf(cloudSigmaMachine, corev1.EventTypeWarning, EventReasonServerCreateFailed,
+					"Failed to create server: %v", err)
+				if cloudSigmaMachine.Status.FailureReason != nil {
+					r.Recorder.Eventf(cloudSigmaMachine, corev1.EventTypeWarning, EventReasonMachineFailed,
+						"Machine marked as failed (%s): %s", *cloudSigmaMachine.Status.FailureReason, *cloudSigmaMachine.Status.FailureMessage)
+				}
+				if updateErr := r.Status().Update(ctx, cloudSigmaMachine); updateErr != nil {
+					log.Error(updateErr, "Failed to update status after server creation failure")
+				}
+				return result, reconcileErr
 			}
 
 			log.Info("Server created successfully", 
 				"instanceID", server.UUID,
 				"name", cloudSigmaMachine.Name,
 				"impersonatedUser", cloudClient.ImpersonatedUser())
+			r.Recorder.Eventf(cloudSigmaMachine, corev1.EventTypeNormal, EventReasonServerCreated,
+				"Created server %s (%s)", server.Name, server.UUID)
 
 			// Tag the server in CloudSigma for tracking
 			clusterName := cloudSigmaMachine.Labels["cluster.x-k8s.io/cluster-name"]
@@ -389,6 +602,7 @@ func (r *CloudSigmaMachineReconciler) reconcileNormal(
 			// Update status first (this is critical to prevent duplicates)
 			cloudSigmaMachine.Status.InstanceID = server.UUID
 			cloudSigmaMachine.Status.InstanceState = server.Status
+			cloudSigmaMachine.Status.VNCPasswordSecretRef = &corev1.LocalObjectReference{Name: vncPasswordSecretName(cloudSigmaMachine)}
 			if err := r.Status().Update(ctx, cloudSigmaMachine); err != nil {
 				// If status update fails due to conflict, DON'T return error immediately
 				// Delay requeue to give CloudSigma API time to propagate the server
@@ -397,12 +611,13 @@ func (r *CloudSigmaMachineReconciler) reconcileNormal(
 					"instanceID", server.UUID,
 					"machineName", cloudSigmaMachine.Name,
 					"impersonatedUser", cloudClient.ImpersonatedUser())
-				return ctrl.Result{RequeueAfter: 10 * time.Second}, nil
+				return ctrl.Result{RequeueAfter: r.requeueInterval()}, nil
 			}
 
-			// Set providerID in spec (separate update)
+			// Set providerID in spec (separate update) and drop the creation marker
 			providerID := fmt.Sprintf("cloudsigma://%s", server.UUID)
 			cloudSigmaMachine.Spec.ProviderID = &providerID
+			clearCreationMarker(cloudSigmaMachine)
 			if err := r.Update(ctx, cloudSigmaMachine); err != nil {
 				// This is less critical - if it fails, we'll retry but won't create duplicates
 				log.Error(err, "Failed to update spec with providerID", "instanceID", server.UUID)
@@ -413,16 +628,21 @@ func (r *CloudSigmaMachineReconciler) reconcileNormal(
 			if server.Status != "running" {
 				log.Info("Starting server", "instanceID", server.UUID)
 				if err := cloudClient.StartServer(ctx, server.UUID); err != nil {
+					r.Recorder.Eventf(cloudSigmaMachine, corev1.EventTypeWarning, EventReasonServerStartFailed,
+						"Failed to start server %s: %v", server.UUID, err)
 					return ctrl.Result{}, errors.Wrap(err, "failed to start server")
 				}
+				r.Recorder.Eventf(cloudSigmaMachine, corev1.EventTypeNormal, EventReasonServerStarting,
+					"Starting server %s", server.UUID)
 			}
 
 			// Requeue to check status
-			return ctrl.Result{RequeueAfter: 10 * time.Second}, nil
+			return ctrl.Result{RequeueAfter: r.phaseRequeue(serverPhaseStarting)}, nil
 		}
 	}
 
 	// Server exists, update its state
+	requeueAfter := r.syncInterval()
 	if server != nil {
 		cloudSigmaMachine.Status.InstanceState = server.Status
 
@@ -458,52 +678,87 @@ func (r *CloudSigmaMachineReconciler) reconcileNormal(
 			// Don't fail on status update conflicts here
 		}
 
+		// Replace a server whose node never joined if its bootstrap data changed since it was created
+		if result, err := r.reconcileBootstrapData(ctx, cloudClient, machine, cloudSigmaMachine, server); err != nil || !result.IsZero() {
+			return result, err
+		}
+
+		// Keep the inventory meta in line with the machine's labels and annotations
+		r.reconcileInventoryMeta(ctx, cloudClient, cloudSigmaMachine, server)
+
+		// Hand the Machine's node labels and annotations to the CCM through the server meta
+		r.reconcileNodeMeta(ctx, cloudClient, machine, cloudSigmaMachine, server)
+
+		// Grow drives whose spec.disks size was increased (opt-in, stops the server)
+		if result, err := r.reconcileDiskSize(ctx, cloudClient, cloudSigmaMachine, server); err != nil || !result.IsZero() {
+			return result, err
+		}
+
 		// Ensure server is running
 		if server.Status == "stopped" {
 			log.Info("Starting stopped server", "instanceID", server.UUID)
 			if err := cloudClient.StartServer(ctx, server.UUID); err != nil {
+				r.Recorder.Eventf(cloudSigmaMachine, corev1.EventTypeWarning, EventReasonServerStartFailed,
+					"Failed to start server %s: %v", server.UUID, err)
 				return ctrl.Result{}, errors.Wrap(err, "failed to start server")
 			}
-			return ctrl.Result{RequeueAfter: 10 * time.Second}, nil
+			r.Recorder.Eventf(cloudSigmaMachine, corev1.EventTypeNormal, EventReasonServerStarting,
+				"Starting stopped server %s", server.UUID)
+			return ctrl.Result{RequeueAfter: r.markServerPhase(ctx, cloudSigmaMachine, server, serverPhaseStarting, time.Now())}, nil
 		}
 
-		// Set ready condition when server is running and has addresses
-		if server.Status == "running" {
-			conditions.MarkTrue(cloudSigmaMachine, infrav1.ServerReadyCondition)
-			cloudSigmaMachine.Status.Ready = true
-			if err := r.Status().Update(ctx, cloudSigmaMachine); err != nil {
-				log.V(4).Info("Failed to update ready status", "error", err)
-			}
+		// Open or close the on-demand console tunnel requested through the open-console annotation
+		consoleExpiry, err := r.reconcileConsole(ctx, cloudClient, cloudSigmaMachine, server, time.Now())
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		if consoleExpiry > 0 && consoleExpiry < requeueAfter {
+			requeueAfter = consoleExpiry
+		}
 
-			// If server is running but no addresses yet, requeue to check again
-			if len(addresses) == 0 {
-				log.Info("Server running but waiting for IP address assignment", "instanceID", server.UUID)
-				return ctrl.Result{RequeueAfter: 5 * time.Second}, nil
-			}
-		} else {
-			// Server is not running - mark as not ready
-			conditions.MarkFalse(cloudSigmaMachine, infrav1.ServerReadyCondition,
-				infrav1.ServerNotRunningReason, clusterv1.ConditionSeverityWarning,
-				"Server status: %s", server.Status)
-			cloudSigmaMachine.Status.Ready = false
-			if err := r.Status().Update(ctx, cloudSigmaMachine); err != nil {
-				log.V(4).Info("Failed to update ready status", "error", err)
-			}
+		// Poll according to how far the server is from being ready
+		phase := serverPhaseFor(server.Status, len(addresses) > 0)
+		if result, err := r.retryStuckStart(ctx, cloudClient, cloudSigmaMachine, server, phase, time.Now()); err != nil || !result.IsZero() {
+			return result, err
+		}
+		if phaseRequeue := r.markServerPhase(ctx, cloudSigmaMachine, server, phase, time.Now()); phaseRequeue < requeueAfter {
+			requeueAfter = phaseRequeue
 		}
 	}
 
-	// Always requeue to periodically check server status (every 60 seconds)
-	return ctrl.Result{RequeueAfter: 60 * time.Second}, nil
+	// Always requeue to periodically check server status
+	return ctrl.Result{RequeueAfter: requeueAfter}, nil
 }
 
 func (r *CloudSigmaMachineReconciler) reconcileDelete(
 	ctx context.Context,
 	cloudClient *cloud.Client,
+	machine *clusterv1.Machine,
 	cloudSigmaMachine *infrav1.CloudSigmaMachine,
 ) (ctrl.Result, error) {
 	log := ctrl.LoggerFrom(ctx)
 
 	if cloudSigmaMachine.Status.InstanceID != "" {
+		// Keep the server running until the node is drained and pre-terminate hooks are released
+		gate := checkDrainGate(machine, cloudSigmaMachine, time.Now())
+		previous := conditions.Get(cloudSigmaMachine, infrav1.NodeDrainedCondition)
+		markDrainGate(cloudSigmaMachine, gate)
+		if gate.Wait {
+			log.Info("Waiting before stopping server", "instanceID", cloudSigmaMachine.Status.InstanceID, "reason", gate.Reason, "message", gate.Message)
+			if previous == nil || previous.Reason != gate.Reason {
+				r.Recorder.Eventf(cloudSigmaMachine, corev1.EventTypeNormal, EventReasonWaitingForNodeDrain,
+					"Not stopping server %s yet: %s", cloudSigmaMachine.Status.InstanceID, gate.Message)
+			}
+			if err := r.Status().Update(ctx, cloudSigmaMachine); err != nil {
+				return ctrl.Result{}, errors.Wrap(err, "failed to update status")
+			}
+			return ctrl.Result{RequeueAfter: r.requeueInterval()}, nil
+		}
+		if gate.Reason == infrav1.NodeDrainTimeoutReason && (previous == nil || previous.Reason != gate.Reason) {
+			r.Recorder.Eventf(cloudSigmaMachine, corev1.EventTypeWarning, EventReasonNodeDrainTimeout,
+				"Stopping server %s: %s", cloudSigmaMachine.Status.InstanceID, gate.Message)
+		}
+
 		log.Info("Deleting server", "instanceID", cloudSigmaMachine.Status.InstanceID)
 
 		// Untag the server before deletion
@@ -523,6 +778,7 @@ func (r *CloudSigmaMachineReconciler) reconcileDelete(
 				if updateErr := r.Update(ctx, cloudSigmaMachine); updateErr != nil {
 					return ctrl.Result{}, errors.Wrap(updateErr, "failed to remove finalizer after permission denied")
 				}
+				r.forgetStarted(cloudSigmaMachine)
 				return ctrl.Result{}, nil
 			}
 			log.Error(err, "Failed to get server for deletion", "instanceID", cloudSigmaMachine.Status.InstanceID)
@@ -542,39 +798,28 @@ func (r *CloudSigmaMachineReconciler) reconcileDelete(
 
 				if err := cloudClient.StopServer(ctx, cloudSigmaMachine.Status.InstanceID); err != nil {
 					log.Error(err, "Failed to stop server", "instanceID", cloudSigmaMachine.Status.InstanceID)
+					r.Recorder.Eventf(cloudSigmaMachine, corev1.EventTypeWarning, EventReasonServerStopFailed,
+						"Failed to stop server %s: %v", cloudSigmaMachine.Status.InstanceID, err)
 					return ctrl.Result{}, errors.Wrap(err, "failed to stop server")
 				}
+				r.Recorder.Eventf(cloudSigmaMachine, corev1.EventTypeNormal, EventReasonServerStopping,
+					"Stopping server %s before deletion", cloudSigmaMachine.Status.InstanceID)
 
 				log.Info("Server stop initiated, waiting for stopped state", "instanceID", cloudSigmaMachine.Status.InstanceID)
 			}
 
 			// Wait for server to stop (poll inline instead of requeue)
-			// Max 2 minutes (12 * 10s) - after that, force delete anyway
-			stoppedOrTimeout := false
-			for i := 0; i < 12; i++ {
-				server, err = cloudClient.GetServer(ctx, cloudSigmaMachine.Status.InstanceID)
-				if err != nil {
-					log.Error(err, "Failed to get server status during deletion", "instanceID", cloudSigmaMachine.Status.InstanceID)
-					return ctrl.Result{}, errors.Wrap(err, "failed to get server status")
-				}
-				if server == nil {
-					log.Info("Server no longer exists", "instanceID", cloudSigmaMachine.Status.InstanceID)
-					stoppedOrTimeout = true
-					break
-				}
-				if server.Status == "stopped" {
-					stoppedOrTimeout = true
-					break
-				}
-				log.Info("Waiting for server to stop", "instanceID", cloudSigmaMachine.Status.InstanceID, "status", server.Status)
-				time.Sleep(10 * time.Second)
-			}
-
-			// If still not stopped after timeout, log warning and try force delete anyway
-			if !stoppedOrTimeout && server != nil {
+			// Max 2 minutes - after that, force delete anyway
+			server, err = cloudClient.WaitForServerStatus(ctx, cloudSigmaMachine.Status.InstanceID, "stopped", 2*time.Minute, 10*time.Second)
+			if cloud.IsServerStatusTimeoutError(err) {
 				log.Info("Server stuck in stopping state, attempting force delete after timeout",
 					"instanceID", cloudSigmaMachine.Status.InstanceID,
 					"status", server.Status)
+			} else if err != nil {
+				log.Error(err, "Failed to get server status during deletion", "instanceID", cloudSigmaMachine.Status.InstanceID)
+				return ctrl.Result{}, errors.Wrap(err, "failed to get server status")
+			} else if server == nil {
+				log.Info("Server no longer exists", "instanceID", cloudSigmaMachine.Status.InstanceID)
 			}
 
 			// Delete the server if it still exists
@@ -589,10 +834,14 @@ func (r *CloudSigmaMachineReconciler) reconcileDelete(
 						log.Info("Server already deleting/stopping or deleted, proceeding to remove finalizer", "instanceID", cloudSigmaMachine.Status.InstanceID)
 					} else {
 						log.Error(err, "Failed to delete server", "instanceID", cloudSigmaMachine.Status.InstanceID)
+						r.Recorder.Eventf(cloudSigmaMachine, corev1.EventTypeWarning, EventReasonServerDeleteFailed,
+							"Failed to delete server %s: %v", cloudSigmaMachine.Status.InstanceID, err)
 						return ctrl.Result{}, errors.Wrap(err, "failed to delete server")
 					}
 				} else {
 					log.Info("Server deleted successfully", "instanceID", cloudSigmaMachine.Status.InstanceID)
+					r.Recorder.Eventf(cloudSigmaMachine, corev1.EventTypeNormal, EventReasonServerDeleted,
+						"Deleted server %s", cloudSigmaMachine.Status.InstanceID)
 				}
 			}
 		}
@@ -600,11 +849,20 @@ func (r *CloudSigmaMachineReconciler) reconcileDelete(
 		log.Info("No instance ID set, nothing to delete")
 	}
 
+	// The server no longer holds the IPs allocated for its static NICs
+	if err := releaseAllocatedIPs(ctx, cloudClient, cloudSigmaMachine); err != nil {
+		if updateErr := r.Status().Update(ctx, cloudSigmaMachine); updateErr != nil {
+			log.V(4).Info("Failed to record released IPs", "error", updateErr)
+		}
+		return ctrl.Result{}, err
+	}
+
 	// Remove finalizer
 	controllerutil.RemoveFinalizer(cloudSigmaMachine, CloudSigmaMachineFinalizer)
 	if err := r.Update(ctx, cloudSigmaMachine); err != nil {
 		return ctrl.Result{}, errors.Wrap(err, "failed to remove finalizer")
 	}
+	r.forgetStarted(cloudSigmaMachine)
 
 	log.Info("CloudSigmaMachine deletion completed")
 	return ctrl.Result{}, nil
@@ -626,33 +884,85 @@ func (r *CloudSigmaMachineReconciler) updateStatus(
 	return ctrl.Result{}, nil
 }
 
-func (r *CloudSigmaMachineReconciler) getBootstrapData(ctx context.Context, machine *clusterv1.Machine) (string, error) {
+// handleCreateServerError records a failed CreateServer call on the machine status and decides
+// whether to retry. Terminal errors (permission denied, 4xx) set FailureReason/FailureMessage and
+// stop requeueing; anything else (5xx, not-ready, network) is returned so it is retried with backoff.
+func handleCreateServerError(cloudSigmaMachine *infrav1.CloudSigmaMachine, err error) (ctrl.Result, error) {
+	if cloud.IsTerminalError(err) {
+		reason := string(capierrors.CreateMachineError)
+		if cloud.IsPermissionDeniedError(err) {
+			reason = string(capierrors.InvalidConfigurationMachineError)
+		}
+		message := fmt.Sprintf("failed to create server: %v", err)
+		cloudSigmaMachine.Status.FailureReason = &reason
+		cloudSigmaMachine.Status.FailureMessage = &message
+		cloudSigmaMachine.Status.Ready = false
+		conditions.MarkFalse(cloudSigmaMachine, infrav1.ServerReadyCondition, infrav1.ServerCreateFailedReason,
+			clusterv1.ConditionSeverityError, "%s", message)
+		return ctrl.Result{}, nil
+	}
+
+	conditions.MarkFalse(cloudSigmaMachine, infrav1.ServerReadyCondition, infrav1.ServerCreateFailedReason,
+		clusterv1.ConditionSeverityWarning, "%s", err.Error())
+	return ctrl.Result{}, errors.Wrap(err, "failed to create server")
+}
+
+// getBootstrapData returns the base64-encoded bootstrap data and its format. The format comes from
+// the bootstrap secret's "format" key (set by CAPI bootstrap providers) and can be overridden with
+// spec.meta["bootstrap-format"]; it defaults to cloud-config.
+func (r *CloudSigmaMachineReconciler) getBootstrapData(ctx context.Context, machine *clusterv1.Machine, cloudSigmaMachine *infrav1.CloudSigmaMachine) (string, cloud.BootstrapFormat, error) {
 	if machine.Spec.Bootstrap.DataSecretName == nil {
-		return "", errors.New("bootstrap data secret is not set")
+		return "", "", errors.New("bootstrap data secret is not set")
 	}
 
 	secret := &corev1.Secret{}
 	key := client.ObjectKey{Namespace: machine.Namespace, Name: *machine.Spec.Bootstrap.DataSecretName}
 	if err := r.Get(ctx, key, secret); err != nil {
-		return "", errors.Wrap(err, "failed to get bootstrap data secret")
+		return "", "", errors.Wrap(err, "failed to get bootstrap data secret")
 	}
 
 	data, ok := secret.Data["value"]
 	if !ok {
-		return "", errors.New("bootstrap data secret does not contain 'value' key")
+		return "", "", errors.New("bootstrap data secret does not contain 'value' key")
 	}
 
-	// Base64 encode for cloud-init
-	return base64.StdEncoding.EncodeToString(data), nil
+	rawFormat := string(secret.Data["format"])
+	if override := cloudSigmaMachine.Spec.Meta[BootstrapFormatMetaKey]; override != "" {
+		rawFormat = override
+	}
+	format, err := cloud.ParseBootstrapFormat(rawFormat)
+	if err != nil {
+		return "", "", &bootstrapFormatError{err: err}
+	}
+
+	// Base64 encode; the field is listed in base64_fields so the guest decodes it
+	return base64.StdEncoding.EncodeToString(data), format, nil
 }
 
 // SetupWithManager sets up the controller with the Manager.
+// Besides its own objects it watches the owning Machines and their bootstrap data Secrets, so
+// a server is created as soon as bootstrap data is available; the bootstrap poll stays as a fallback.
+// Machines are also reconciled when their CloudSigmaCluster's network becomes ready.
 func (r *CloudSigmaMachineReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &clusterv1.Machine{},
+		machineBootstrapSecretIndex, indexMachineByBootstrapSecret); err != nil {
+		return errors.Wrap(err, "failed to index machines by bootstrap secret")
+	}
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &infrav1.CloudSigmaMachine{},
+		InstanceIDIndex, indexCloudSigmaMachineByInstanceID); err != nil {
+		return errors.Wrap(err, "failed to index CloudSigmaMachines by instance ID")
+	}
+
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&infrav1.CloudSigmaMachine{}).
+		Watches(&clusterv1.Machine{}, handler.EnqueueRequestsFromMapFunc(
+			util.MachineToInfrastructureMapFunc(infrav1.GroupVersion.WithKind("CloudSigmaMachine")))).
+		Watches(&corev1.Secret{}, handler.EnqueueRequestsFromMapFunc(r.bootstrapSecretToCloudSigmaMachines)).
+		Watches(&infrav1.CloudSigmaCluster{}, handler.EnqueueRequestsFromMapFunc(r.cloudSigmaClusterToCloudSigmaMachines),
+			builder.WithPredicates(clusterNetworkChanged)).
 		WithEventFilter(predicates.ResourceNotPaused(ctrl.LoggerFrom(context.Background()))).
-		// Limit to 1 concurrent reconcile to prevent duplicate VM creation
-		// due to race conditions with CloudSigma API eventual consistency
-		WithOptions(controller.Options{MaxConcurrentReconciles: 1}).
+		// Parallel workers are safe: controller-runtime never hands the same machine to two workers
+		// at once, and the creation marker guards against creating a server twice across reconciles
+		WithOptions(controllerOptions(r.MaxConcurrentReconciles)).
 		Complete(r)
 }
@@ -0,0 +1,268 @@
+/*
+Copyright 2025 Kube-DC Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	capierrors "sigs.k8s.io/cluster-api/errors"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	infrav1 "github.com/kube-dc/cluster-api-provider-cloudsigma/api/v1beta1"
+	"github.com/kube-dc/cluster-api-provider-cloudsigma/pkg/cloud"
+)
+
+// newTestScheme returns a scheme with the core, Cluster API and provider types
+func newTestScheme() *runtime.Scheme {
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	_ = clusterv1.AddToScheme(scheme)
+	_ = infrav1.AddToScheme(scheme)
+	return scheme
+}
+
+// newTestMachine returns a Machine of the "test" cluster, its bootstrap data Secret and a
+// CloudSigmaMachine whose server has not been created yet
+func newTestMachine(name string) (*clusterv1.Machine, *corev1.Secret, *infrav1.CloudSigmaMachine) {
+	dataSecretName := name + "-bootstrap"
+	machine := &clusterv1.Machine{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		Spec: clusterv1.MachineSpec{
+			ClusterName: "test",
+			Bootstrap:   clusterv1.Bootstrap{DataSecretName: &dataSecretName},
+		},
+	}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: dataSecretName, Namespace: "default"},
+		Data:       map[string][]byte{"value": []byte("#cloud-config\n")},
+	}
+	cloudSigmaMachine := &infrav1.CloudSigmaMachine{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "default",
+			UID:       "machine-uid",
+			Labels:    map[string]string{clusterv1.ClusterNameLabel: "test"},
+		},
+		Spec: infrav1.CloudSigmaMachineSpec{CPU: 2000, Memory: 4096},
+	}
+	return machine, secret, cloudSigmaMachine
+}
+
+// newTestClientBuilder returns a fake client builder holding objects, with the
+// CloudSigmaMachine status subresource enabled
+func newTestClientBuilder(objects ...client.Object) *fake.ClientBuilder {
+	return fake.NewClientBuilder().
+		WithScheme(newTestScheme()).
+		WithObjects(objects...).
+		WithStatusSubresource(&infrav1.CloudSigmaMachine{})
+}
+
+// newTestReconciler returns a machine reconciler using c and the recorder it reports events to
+func newTestReconciler(c client.Client) (*CloudSigmaMachineReconciler, *record.FakeRecorder) {
+	recorder := record.NewFakeRecorder(20)
+	return &CloudSigmaMachineReconciler{Client: c, Scheme: c.Scheme(), Recorder: recorder}, recorder
+}
+
+// newTestClient returns a CloudSigma client talking to handler, for API behaviour the fake
+// in pkg/cloud/fake does not model
+func newTestClient(t *testing.T, handler http.Handler) *cloud.Client {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	cloudClient, err := cloud.NewClientWithEndpoint("user", "pass", server.URL+"/api/2.0")
+	if err != nil {
+		t.Fatalf("NewClientWithEndpoint() error = %v", err)
+	}
+	return cloudClient
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func TestHandleCreateServerError(t *testing.T) {
+	tests := []struct {
+		name         string
+		err          error
+		wantFailure  bool
+		wantReason   string
+		wantRequeue  bool
+		wantSeverity clusterv1.ConditionSeverity
+	}{
+		{
+			name:         "permission denied is terminal",
+			err:          cloud.NewPermissionDeniedError("server", "", 403, "user@example.com", errors.New("forbidden")),
+			wantFailure:  true,
+			wantReason:   string(capierrors.InvalidConfigurationMachineError),
+			wantRequeue:  false,
+			wantSeverity: clusterv1.ConditionSeverityError,
+		},
+		{
+			name:         "invalid image is terminal",
+			err:          fmt.Errorf("failed to clone drive: %w", &cloud.APIError{StatusCode: 400, Body: "invalid uuid"}),
+			wantFailure:  true,
+			wantReason:   string(capierrors.CreateMachineError),
+			wantRequeue:  false,
+			wantSeverity: clusterv1.ConditionSeverityError,
+		},
+		{
+			name:         "server error is retryable",
+			err:          &cloud.APIError{StatusCode: 503, Body: "unavailable"},
+			wantFailure:  false,
+			wantRequeue:  true,
+			wantSeverity: clusterv1.ConditionSeverityWarning,
+		},
+		{
+			name:         "drive not ready is retryable",
+			err:          errors.New("drive did not become ready: timeout waiting for drive to be ready"),
+			wantFailure:  false,
+			wantRequeue:  true,
+			wantSeverity: clusterv1.ConditionSeverityWarning,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			machine := &infrav1.CloudSigmaMachine{}
+			result, err := handleCreateServerError(machine, tt.err)
+
+			// A returned error makes controller-runtime requeue with backoff
+			if requeued := err != nil || result.Requeue || result.RequeueAfter > 0; requeued != tt.wantRequeue {
+				t.Errorf("requeue = %v, want %v", requeued, tt.wantRequeue)
+			}
+
+			if gotFailure := machine.Status.FailureReason != nil; gotFailure != tt.wantFailure {
+				t.Fatalf("FailureReason set = %v, want %v", gotFailure, tt.wantFailure)
+			}
+			if tt.wantFailure {
+				if *machine.Status.FailureReason != tt.wantReason {
+					t.Errorf("FailureReason = %q, want %q", *machine.Status.FailureReason, tt.wantReason)
+				}
+				if machine.Status.FailureMessage == nil || *machine.Status.FailureMessage == "" {
+					t.Error("FailureMessage not set")
+				}
+			}
+
+			cond := conditions.Get(machine, infrav1.ServerReadyCondition)
+			if cond == nil {
+				t.Fatal("ServerReady condition not set")
+			}
+			if cond.Reason != infrav1.ServerCreateFailedReason || cond.Severity != tt.wantSeverity {
+				t.Errorf("condition = %s/%s, want %s/%s", cond.Reason, cond.Severity, infrav1.ServerCreateFailedReason, tt.wantSeverity)
+			}
+		})
+	}
+}
+
+func TestGetBootstrapData(t *testing.T) {
+	tests := []struct {
+		name       string
+		secretData map[string][]byte
+		meta       map[string]string
+		wantFormat cloud.BootstrapFormat
+		wantErr    bool
+		terminal   bool // the error is an unsupported format
+	}{
+		{
+			name:       "cloud-init without format key",
+			secretData: map[string][]byte{"value": []byte("#cloud-config\n")},
+			wantFormat: cloud.BootstrapFormatCloudConfig,
+		},
+		{
+			name:       "cloud-config format key",
+			secretData: map[string][]byte{"value": []byte("#cloud-config\n"), "format": []byte("cloud-config")},
+			wantFormat: cloud.BootstrapFormatCloudConfig,
+		},
+		{
+			name:       "ignition format key",
+			secretData: map[string][]byte{"value": []byte(`{"ignition":{"version":"3.3.0"}}`), "format": []byte("ignition")},
+			wantFormat: cloud.BootstrapFormatIgnition,
+		},
+		{
+			name:       "meta override",
+			secretData: map[string][]byte{"value": []byte(`{"ignition":{"version":"3.3.0"}}`)},
+			meta:       map[string]string{BootstrapFormatMetaKey: "ignition"},
+			wantFormat: cloud.BootstrapFormatIgnition,
+		},
+		{
+			name:       "unsupported format",
+			secretData: map[string][]byte{"value": []byte("data"), "format": []byte("butane")},
+			wantErr:    true,
+			terminal:   true,
+		},
+		{
+			name:       "missing value",
+			secretData: map[string][]byte{"format": []byte("ignition")},
+			wantErr:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scheme := runtime.NewScheme()
+			_ = corev1.AddToScheme(scheme)
+
+			secretName := "worker-0-bootstrap"
+			secret := &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: secretName, Namespace: "default"},
+				Data:       tt.secretData,
+			}
+			r := &CloudSigmaMachineReconciler{
+				Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(secret).Build(),
+			}
+			machine := &clusterv1.Machine{
+				ObjectMeta: metav1.ObjectMeta{Name: "worker-0", Namespace: "default"},
+				Spec:       clusterv1.MachineSpec{Bootstrap: clusterv1.Bootstrap{DataSecretName: &secretName}},
+			}
+			cloudSigmaMachine := &infrav1.CloudSigmaMachine{Spec: infrav1.CloudSigmaMachineSpec{Meta: tt.meta}}
+
+			data, format, err := r.getBootstrapData(context.Background(), machine, cloudSigmaMachine)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("getBootstrapData() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if isBootstrapFormatError(err) != tt.terminal {
+				t.Errorf("isBootstrapFormatError(%v) = %v, want %v", err, !tt.terminal, tt.terminal)
+			}
+			if tt.wantErr {
+				return
+			}
+			if format != tt.wantFormat {
+				t.Errorf("format = %q, want %q", format, tt.wantFormat)
+			}
+			if want := base64.StdEncoding.EncodeToString(tt.secretData["value"]); data != want {
+				t.Errorf("data = %q, want %q", data, want)
+			}
+		})
+	}
+}
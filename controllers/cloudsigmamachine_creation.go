@@ -0,0 +1,122 @@
+/*
+Copyright 2025 Kube-DC Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/cloudsigma/cloudsigma-sdk-go/cloudsigma"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+
+	infrav1 "github.com/kube-dc/cluster-api-provider-cloudsigma/api/v1beta1"
+	"github.com/kube-dc/cluster-api-provider-cloudsigma/pkg/cloud"
+)
+
+// creationGracePeriod is how long after a create request a missing server is assumed to be
+// still propagating through the CloudSigma API rather than never created
+var creationGracePeriod = 2 * time.Minute
+
+// orphanedDriveGracePeriod is how long after it was cloned a drive is left alone when no server
+// was created from it, since another create attempt may still be cloning or using it
+var orphanedDriveGracePeriod = 10 * time.Minute
+
+// creationMarker returns when the last create request for this machine was submitted
+func creationMarker(m *infrav1.CloudSigmaMachine) (time.Time, bool) {
+	value, ok := m.Annotations[infrav1.CreatingAnnotation]
+	if !ok {
+		return time.Time{}, false
+	}
+	started, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return started, true
+}
+
+// creationInFlight reports whether a previous reconcile submitted a create request for this
+// machine recently enough that the server may exist without being listed yet
+func creationInFlight(m *infrav1.CloudSigmaMachine, now time.Time) bool {
+	started, ok := creationMarker(m)
+	return ok && now.Sub(started) < creationGracePeriod
+}
+
+// reapOrphanedDrives deletes drives cloned for the machine by an earlier create attempt that never
+// produced a server, such as when the controller crashed between cloning and creating the server.
+// It must only run once no server was found or adopted for the machine, since it would otherwise
+// race with a server that is still being created. Only drives carrying CreateServer's clone mark
+// for the machine are considered, never drives attached to any server. It returns true while
+// orphans remain that were cloned within orphanedDriveGracePeriod or are still busy (e.g.
+// cloning); the caller should then wait instead of cloning a second set of drives under the same
+// names.
+func reapOrphanedDrives(ctx context.Context, cloudClient *cloud.Client, m *infrav1.CloudSigmaMachine, now time.Time) (deleted []string, wait bool, err error) {
+	orphans, err := cloudClient.FindOrphanedClonedDrives(ctx, m.Name)
+	if err != nil || len(orphans) == 0 {
+		return nil, false, err
+	}
+
+	for _, drive := range orphans {
+		clonedAt, ok := cloud.ClonedAt(drive)
+		if (ok && now.Sub(clonedAt) < orphanedDriveGracePeriod) || drive.Status != "unmounted" {
+			wait = true
+			continue
+		}
+		if err := cloudClient.DeleteDrive(ctx, drive.UUID); err != nil {
+			return deleted, true, fmt.Errorf("failed to delete orphaned drive %s (%s): %w", drive.Name, drive.UUID, err)
+		}
+		deleted = append(deleted, drive.Name)
+	}
+	return deleted, wait, nil
+}
+
+// setCreationMarker persists the creating annotation before a server create request is sent,
+// so a retry after a lost status update can tell that a server may already exist
+func (r *CloudSigmaMachineReconciler) setCreationMarker(ctx context.Context, m *infrav1.CloudSigmaMachine, now time.Time) error {
+	if m.Annotations == nil {
+		m.Annotations = map[string]string{}
+	}
+	m.Annotations[infrav1.CreatingAnnotation] = now.UTC().Format(time.RFC3339)
+	return r.Update(ctx, m)
+}
+
+// clearCreationMarker removes the creating annotation; the caller persists the change
+func clearCreationMarker(m *infrav1.CloudSigmaMachine) {
+	delete(m.Annotations, infrav1.CreatingAnnotation)
+}
+
+// canAdoptServer decides whether an existing server found for a machine without an instance ID
+// may be adopted. A server carrying the machine's UID in its metadata was created for it; a
+// server that only matches by name must bear this provider's managed-by and cluster tags, so an
+// unrelated VM that happens to share the name is never taken over.
+func canAdoptServer(ctx context.Context, cloudClient *cloud.Client, m *infrav1.CloudSigmaMachine, server *cloudsigma.Server) (bool, error) {
+	if uid, ok := server.Meta["machine-uid"]; ok && uid == string(m.UID) {
+		return true, nil
+	}
+	return cloudClient.IsClusterServer(ctx, server.UUID, m.Labels[clusterv1.ClusterNameLabel])
+}
+
+// quotaRequeue decides what a failed quota pre-flight check means for server creation. An
+// exhausted account holds creation off for QuotaExceededRequeueInterval instead of letting
+// CreateServer fail every requeue; any other error is ignored, since the check is advisory
+// and CreateServer reports the real outcome.
+func quotaRequeue(err error) (time.Duration, bool) {
+	if cloud.IsQuotaExceededError(err) {
+		return QuotaExceededRequeueInterval, true
+	}
+	return 0, false
+}
@@ -0,0 +1,374 @@
+/*
+Copyright 2025 Kube-DC Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cloudsigma/cloudsigma-sdk-go/cloudsigma"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	infrav1 "github.com/kube-dc/cluster-api-provider-cloudsigma/api/v1beta1"
+	"github.com/kube-dc/cluster-api-provider-cloudsigma/pkg/cloud"
+	cloudfake "github.com/kube-dc/cluster-api-provider-cloudsigma/pkg/cloud/fake"
+)
+
+func TestCreationInFlight(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		want        bool
+	}{
+		{name: "no marker"},
+		{name: "recent marker", annotations: map[string]string{infrav1.CreatingAnnotation: now.Add(-30 * time.Second).Format(time.RFC3339)}, want: true},
+		{name: "expired marker", annotations: map[string]string{infrav1.CreatingAnnotation: now.Add(-creationGracePeriod).Format(time.RFC3339)}},
+		{name: "malformed marker", annotations: map[string]string{infrav1.CreatingAnnotation: "yesterday"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &infrav1.CloudSigmaMachine{ObjectMeta: metav1.ObjectMeta{Annotations: tt.annotations}}
+			if got := creationInFlight(m, now); got != tt.want {
+				t.Errorf("creationInFlight() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReapOrphanedDrives(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	clone := func(name, owner string, age time.Duration, status string) cloudsigma.Drive {
+		return cloudsigma.Drive{Name: name, Size: 1 << 30, Status: status, Meta: map[string]interface{}{
+			cloud.ClonedForMetaKey: owner,
+			cloud.ClonedAtMetaKey:  now.Add(-age).Format(time.RFC3339),
+		}}
+	}
+	old := orphanedDriveGracePeriod
+
+	tests := []struct {
+		name        string
+		drives      []cloudsigma.Drive
+		attached    string
+		wantDeleted []string
+		wantWait    bool
+	}{
+		{name: "no orphans"},
+		{
+			name:        "orphan of an old attempt",
+			drives:      []cloudsigma.Drive{clone("worker-0-drive-0", "worker-0", old, "unmounted")},
+			wantDeleted: []string{"worker-0-drive-0"},
+		},
+		{
+			name:     "orphan cloned recently",
+			drives:   []cloudsigma.Drive{clone("worker-0-drive-0", "worker-0", time.Minute, "unmounted")},
+			wantWait: true,
+		},
+		{
+			name: "orphan still cloning",
+			drives: []cloudsigma.Drive{
+				clone("worker-0-drive-0", "worker-0", old, "unmounted"),
+				clone("worker-0-drive-1", "worker-0", old, "cloning_dst"),
+			},
+			wantDeleted: []string{"worker-0-drive-0"},
+			wantWait:    true,
+		},
+		{
+			name:     "clone attached to a server",
+			drives:   []cloudsigma.Drive{clone("worker-0-drive-0", "worker-0", old, "unmounted")},
+			attached: "worker-0-drive-0",
+		},
+		{
+			name: "drives of other machines and user drives are kept",
+			drives: []cloudsigma.Drive{
+				clone("worker-1-drive-0", "worker-1", old, "unmounted"),
+				{Name: "worker-0-drive-0", Size: 1 << 30},
+				{Name: "worker-0-data", Size: 1 << 30},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := cloudfake.NewServer()
+			defer api.Close()
+			uuids := map[string]string{}
+			for _, drive := range tt.drives {
+				uuids[api.AddDrive(drive).UUID] = drive.Name
+			}
+			if tt.attached != "" {
+				var drives []cloudsigma.ServerDrive
+				for uuid, name := range uuids {
+					if name == tt.attached {
+						drives = append(drives, cloudsigma.ServerDrive{DevChannel: "0:0", Device: "virtio", Drive: &cloudsigma.Drive{UUID: uuid}})
+					}
+				}
+				if _, _, err := api.NewSDKClient().Servers.Create(context.Background(), &cloudsigma.ServerCreateRequest{
+					Servers: []cloudsigma.Server{{Name: "worker-2", CPU: 2000, Memory: 2 << 30, VNCPassword: "secret", Drives: drives}},
+				}); err != nil {
+					t.Fatalf("Servers.Create() error = %v", err)
+				}
+			}
+			cloudClient, err := api.NewClient()
+			if err != nil {
+				t.Fatalf("NewClient() error = %v", err)
+			}
+			m := &infrav1.CloudSigmaMachine{ObjectMeta: metav1.ObjectMeta{Name: "worker-0"}}
+
+			deleted, wait, err := reapOrphanedDrives(context.Background(), cloudClient, m, now)
+			if err != nil {
+				t.Fatalf("reapOrphanedDrives() error = %v", err)
+			}
+			if wait != tt.wantWait {
+				t.Errorf("wait = %v, want %v", wait, tt.wantWait)
+			}
+			if strings.Join(deleted, ",") != strings.Join(tt.wantDeleted, ",") {
+				t.Errorf("deleted drives = %v, want %v", deleted, tt.wantDeleted)
+			}
+			for uuid, name := range uuids {
+				_, exists := api.GetDrive(uuid)
+				if want := !slices.Contains(tt.wantDeleted, name); exists != want {
+					t.Errorf("drive %s exists = %v, want %v", name, exists, want)
+				}
+			}
+		})
+	}
+}
+
+func TestCloudSigmaMachineReconcile_NoDuplicateCreateAfterStatusFailure(t *testing.T) {
+	const serverUUID = "5d0e6f2a-1b3c-4d5e-8f90-a1b2c3d4e5f6"
+
+	var creates atomic.Int32
+	mux := http.NewServeMux()
+	// The new server is never listed, as if the API had not caught up yet
+	mux.HandleFunc("/api/2.0/servers/detail/", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]interface{}{"meta": map[string]int{"total_count": 0}, "objects": []interface{}{}})
+	})
+	mux.HandleFunc("/api/2.0/servers/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.NotFound(w, r)
+			return
+		}
+		creates.Add(1)
+		writeJSON(w, map[string]interface{}{"objects": []map[string]string{{
+			"uuid": serverUUID, "name": "worker-0", "status": "stopped",
+		}}})
+	})
+	cloudClient := newTestClient(t, mux)
+
+	machine, bootstrapSecret, cloudSigmaMachine := newTestMachine("worker-0")
+	c := newTestClientBuilder(machine, bootstrapSecret, cloudSigmaMachine).
+		WithInterceptorFuncs(interceptor.Funcs{
+			SubResourceUpdate: func(ctx context.Context, c client.Client, subResourceName string, obj client.Object, opts ...client.SubResourceUpdateOption) error {
+				return errors.New("conflict")
+			},
+		}).
+		Build()
+
+	r, _ := newTestReconciler(c)
+	ctx := context.Background()
+	key := client.ObjectKeyFromObject(cloudSigmaMachine)
+
+	for i := 0; i < 2; i++ {
+		latest := &infrav1.CloudSigmaMachine{}
+		if err := c.Get(ctx, key, latest); err != nil {
+			t.Fatalf("Get() error = %v", err)
+		}
+		result, err := r.reconcileNormal(ctx, cloudClient, machine, latest)
+		if err != nil {
+			t.Fatalf("reconcileNormal() #%d error = %v", i+1, err)
+		}
+		if result.RequeueAfter == 0 {
+			t.Errorf("reconcileNormal() #%d did not requeue", i+1)
+		}
+	}
+	if got := creates.Load(); got != 1 {
+		t.Fatalf("server created %d times, want 1", got)
+	}
+
+	// Once the grace period has passed the server is assumed lost and created again
+	latest := &infrav1.CloudSigmaMachine{}
+	if err := c.Get(ctx, key, latest); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	latest.Annotations[infrav1.CreatingAnnotation] = time.Now().Add(-creationGracePeriod).UTC().Format(time.RFC3339)
+	if _, err := r.reconcileNormal(ctx, cloudClient, machine, latest); err != nil {
+		t.Fatalf("reconcileNormal() error = %v", err)
+	}
+	if got := creates.Load(); got != 2 {
+		t.Errorf("server created %d times after grace period, want 2", got)
+	}
+}
+
+func TestCloudSigmaMachineReconcile_AdoptOrCreate(t *testing.T) {
+	tests := []struct {
+		name      string
+		meta      map[string]interface{}
+		tags      []string
+		wantAdopt bool
+	}{
+		{name: "managed and cluster tags", tags: []string{cloud.ManagedByTag, "cluster:test"}, wantAdopt: true},
+		{name: "machine-uid metadata", meta: map[string]interface{}{"machine-uid": "machine-uid"}, wantAdopt: true},
+		{name: "no tags"},
+		{name: "managed tag only", tags: []string{cloud.ManagedByTag}},
+		{name: "other cluster", tags: []string{cloud.ManagedByTag, "cluster:other"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			api := cloudfake.NewServer()
+			defer api.Close()
+			sdk := api.NewSDKClient()
+			existing, _, err := sdk.Servers.Create(ctx, &cloudsigma.ServerCreateRequest{
+				Servers: []cloudsigma.Server{{Name: "worker-0", CPU: 2000, Memory: 2 << 30, VNCPassword: "secret", Meta: tt.meta}},
+			})
+			if err != nil {
+				t.Fatalf("Servers.Create() error = %v", err)
+			}
+			for _, name := range tt.tags {
+				if _, _, err := sdk.Tags.Create(ctx, &cloudsigma.TagCreateRequest{
+					Tags: []cloudsigma.Tag{{Name: name, Resources: []cloudsigma.TagResource{{UUID: existing[0].UUID}}}},
+				}); err != nil {
+					t.Fatalf("Tags.Create() error = %v", err)
+				}
+			}
+			cloudClient, err := api.NewClient()
+			if err != nil {
+				t.Fatalf("NewClient() error = %v", err)
+			}
+
+			machine, bootstrapSecret, cloudSigmaMachine := newTestMachine("worker-0")
+			r, _ := newTestReconciler(newTestClientBuilder(machine, bootstrapSecret, cloudSigmaMachine).Build())
+			if _, err := r.reconcileNormal(ctx, cloudClient, machine, cloudSigmaMachine); err != nil {
+				t.Fatalf("reconcileNormal() error = %v", err)
+			}
+
+			servers, _, err := sdk.Servers.List(ctx)
+			if err != nil {
+				t.Fatalf("Servers.List() error = %v", err)
+			}
+			wantServers := 2
+			if tt.wantAdopt {
+				wantServers = 1
+			}
+			if len(servers) != wantServers {
+				t.Fatalf("servers = %d, want %d", len(servers), wantServers)
+			}
+			wantUUID := existing[0].UUID
+			if !tt.wantAdopt {
+				for _, server := range servers {
+					if server.UUID != existing[0].UUID {
+						wantUUID = server.UUID
+					}
+				}
+			}
+			if cloudSigmaMachine.Status.InstanceID != wantUUID {
+				t.Errorf("Status.InstanceID = %q, want %q", cloudSigmaMachine.Status.InstanceID, wantUUID)
+			}
+			wantProviderID := "cloudsigma://" + wantUUID
+			if cloudSigmaMachine.Spec.ProviderID == nil || *cloudSigmaMachine.Spec.ProviderID != wantProviderID {
+				t.Errorf("Spec.ProviderID = %v, want %s", cloudSigmaMachine.Spec.ProviderID, wantProviderID)
+			}
+			if tt.wantAdopt && !conditions.Has(cloudSigmaMachine, infrav1.ServerReadyCondition) {
+				t.Error("expected ServerReady condition to be set on adoption")
+			}
+		})
+	}
+}
+
+func TestQuotaRequeue(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		wantAfter time.Duration
+		wantHold  bool
+	}{
+		{name: "quota available"},
+		{name: "quota exceeded", err: &cloud.QuotaExceededError{Resources: []string{"cpu"}}, wantAfter: QuotaExceededRequeueInterval, wantHold: true},
+		{name: "wrapped quota exceeded", err: fmt.Errorf("pre-flight: %w", &cloud.QuotaExceededError{Resources: []string{"ip"}}), wantAfter: QuotaExceededRequeueInterval, wantHold: true},
+		{name: "usage endpoint unavailable", err: errors.New("failed to get account usage: API error 503")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			after, hold := quotaRequeue(tt.err)
+			if after != tt.wantAfter || hold != tt.wantHold {
+				t.Errorf("quotaRequeue() = (%v, %v), want (%v, %v)", after, hold, tt.wantAfter, tt.wantHold)
+			}
+		})
+	}
+}
+
+func TestCloudSigmaMachineReconcile_QuotaExceeded(t *testing.T) {
+	var creates atomic.Int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/2.0/servers/detail/", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]interface{}{"meta": map[string]int{"total_count": 0}, "objects": []interface{}{}})
+	})
+	mux.HandleFunc("/api/2.0/servers/", func(w http.ResponseWriter, r *http.Request) {
+		creates.Add(1)
+		http.Error(w, "unexpected create", http.StatusBadRequest)
+	})
+	// The fake API has no usage endpoint, so the exhausted quota is served here
+	mux.HandleFunc("/api/2.0/currentusage/", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]interface{}{
+			"balance": map[string]string{"balance": "0", "currency": "USD"},
+			"usage": map[string]interface{}{
+				"cpu": map[string]int64{"subscribed": 10000, "using": 10000},
+				"mem": map[string]int64{"subscribed": 17179869184, "using": 0},
+			},
+		})
+	})
+	cloudClient := newTestClient(t, mux)
+
+	machine, bootstrapSecret, cloudSigmaMachine := newTestMachine("worker-0")
+	r, recorder := newTestReconciler(newTestClientBuilder(machine, bootstrapSecret, cloudSigmaMachine).Build())
+	result, err := r.reconcileNormal(context.Background(), cloudClient, machine, cloudSigmaMachine)
+	if err != nil {
+		t.Fatalf("reconcileNormal() error = %v", err)
+	}
+
+	if got := creates.Load(); got != 0 {
+		t.Errorf("server created %d times, want 0", got)
+	}
+	if result.RequeueAfter != QuotaExceededRequeueInterval {
+		t.Errorf("RequeueAfter = %v, want %v", result.RequeueAfter, QuotaExceededRequeueInterval)
+	}
+	if reason := conditions.GetReason(cloudSigmaMachine, infrav1.ServerReadyCondition); reason != infrav1.QuotaExceededReason {
+		t.Errorf("ServerReady reason = %q, want %q", reason, infrav1.QuotaExceededReason)
+	}
+	if cloudSigmaMachine.Status.FailureReason != nil {
+		t.Error("quota exhaustion must not mark the machine as failed")
+	}
+	select {
+	case event := <-recorder.Events:
+		if !strings.HasPrefix(event, corev1.EventTypeWarning+" "+EventReasonQuotaExceeded+" ") || !strings.Contains(event, "cpu") {
+			t.Errorf("event = %q, want a %s event naming cpu", event, EventReasonQuotaExceeded)
+		}
+	default:
+		t.Error("expected a QuotaExceeded event")
+	}
+}
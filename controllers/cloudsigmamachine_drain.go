@@ -0,0 +1,104 @@
+/*
+Copyright 2025 Kube-DC Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+
+	infrav1 "github.com/kube-dc/cluster-api-provider-cloudsigma/api/v1beta1"
+)
+
+// drainGate is the outcome of checking whether a deleted machine's server may be stopped
+type drainGate struct {
+	// Wait is true while the server must be kept running
+	Wait bool
+	// Reason is the NodeDrained condition reason; empty once the node is drained
+	Reason  string
+	Message string
+}
+
+// checkDrainGate decides whether the server backing a deleted machine may be stopped. Stopping
+// waits for every pre-terminate delete hook on the Machine to be removed and, while the Machine
+// controller reports a drain in progress (DrainingSucceeded False), for that drain to finish.
+// A drain the Machine controller skipped, e.g. for the exclude-node-draining annotation or an
+// unreachable node, never sets the condition and does not hold the server. The drain wait ends
+// after the Machine's spec.nodeDrainTimeout (DefaultNodeDrainTimeout if unset), counted from when
+// deletion started; hooks have no timeout, as with the Machine controller itself.
+func checkDrainGate(machine *clusterv1.Machine, m *infrav1.CloudSigmaMachine, now time.Time) drainGate {
+	if hooks := preTerminateHooks(machine); len(hooks) > 0 {
+		return drainGate{
+			Wait:    true,
+			Reason:  infrav1.WaitingForPreTerminateHookReason,
+			Message: fmt.Sprintf("waiting for pre-terminate hooks: %s", strings.Join(hooks, ", ")),
+		}
+	}
+
+	_, excluded := machine.Annotations[clusterv1.ExcludeNodeDrainingAnnotation]
+	if machine.Status.NodeRef == nil || excluded || !conditions.IsFalse(machine, clusterv1.DrainingSucceededCondition) {
+		return drainGate{}
+	}
+
+	timeout := DefaultNodeDrainTimeout
+	if machine.Spec.NodeDrainTimeout != nil && machine.Spec.NodeDrainTimeout.Duration > 0 {
+		timeout = machine.Spec.NodeDrainTimeout.Duration
+	}
+	started := m.DeletionTimestamp
+	if !machine.DeletionTimestamp.IsZero() {
+		started = machine.DeletionTimestamp
+	}
+	if started != nil && now.Sub(started.Time) >= timeout {
+		return drainGate{
+			Reason:  infrav1.NodeDrainTimeoutReason,
+			Message: fmt.Sprintf("node %s was not drained within %v", machine.Status.NodeRef.Name, timeout),
+		}
+	}
+	return drainGate{
+		Wait:    true,
+		Reason:  infrav1.WaitingForNodeDrainReason,
+		Message: fmt.Sprintf("waiting for node %s to be drained", machine.Status.NodeRef.Name),
+	}
+}
+
+// preTerminateHooks returns the sorted pre-terminate delete hook annotations set on the Machine
+func preTerminateHooks(machine *clusterv1.Machine) []string {
+	var hooks []string
+	for key := range machine.Annotations {
+		if strings.HasPrefix(key, clusterv1.PreTerminateDeleteHookAnnotationPrefix) {
+			hooks = append(hooks, key)
+		}
+	}
+	sort.Strings(hooks)
+	return hooks
+}
+
+// markDrainGate records the gate outcome on the NodeDrained condition
+func markDrainGate(m *infrav1.CloudSigmaMachine, gate drainGate) {
+	switch {
+	case gate.Wait:
+		conditions.MarkFalse(m, infrav1.NodeDrainedCondition, gate.Reason, clusterv1.ConditionSeverityInfo, "%s", gate.Message)
+	case gate.Reason != "":
+		conditions.MarkFalse(m, infrav1.NodeDrainedCondition, gate.Reason, clusterv1.ConditionSeverityWarning, "%s", gate.Message)
+	default:
+		conditions.MarkTrue(m, infrav1.NodeDrainedCondition)
+	}
+}
@@ -0,0 +1,208 @@
+/*
+Copyright 2025 Kube-DC Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	infrav1 "github.com/kube-dc/cluster-api-provider-cloudsigma/api/v1beta1"
+)
+
+func TestCheckDrainGate(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	deleted := metav1.NewTime(now.Add(-time.Minute))
+	longAgo := metav1.NewTime(now.Add(-time.Hour))
+	hook := clusterv1.PreTerminateDeleteHookAnnotationPrefix + "/backup"
+	drained := clusterv1.Conditions{{Type: clusterv1.DrainingSucceededCondition, Status: corev1.ConditionTrue}}
+	draining := clusterv1.Conditions{{Type: clusterv1.DrainingSucceededCondition, Status: corev1.ConditionFalse, Reason: clusterv1.DrainingReason}}
+
+	tests := []struct {
+		name       string
+		machine    clusterv1.Machine
+		wantWait   bool
+		wantReason string
+	}{
+		{
+			name:    "no node, no hooks",
+			machine: clusterv1.Machine{ObjectMeta: metav1.ObjectMeta{DeletionTimestamp: &deleted}},
+		},
+		{
+			name: "pre-terminate hook present",
+			machine: clusterv1.Machine{
+				ObjectMeta: metav1.ObjectMeta{DeletionTimestamp: &longAgo, Annotations: map[string]string{hook: ""}},
+				Status:     clusterv1.MachineStatus{Conditions: drained},
+			},
+			wantWait:   true,
+			wantReason: infrav1.WaitingForPreTerminateHookReason,
+		},
+		{
+			name: "node being drained",
+			machine: clusterv1.Machine{
+				ObjectMeta: metav1.ObjectMeta{DeletionTimestamp: &deleted},
+				Status:     clusterv1.MachineStatus{NodeRef: &corev1.ObjectReference{Name: "worker-0"}, Conditions: draining},
+			},
+			wantWait:   true,
+			wantReason: infrav1.WaitingForNodeDrainReason,
+		},
+		{
+			// The Machine controller skipped the drain, e.g. for an unreachable node
+			name: "drain not started",
+			machine: clusterv1.Machine{
+				ObjectMeta: metav1.ObjectMeta{DeletionTimestamp: &deleted},
+				Status:     clusterv1.MachineStatus{NodeRef: &corev1.ObjectReference{Name: "worker-0"}},
+			},
+		},
+		{
+			name: "node drained",
+			machine: clusterv1.Machine{
+				ObjectMeta: metav1.ObjectMeta{DeletionTimestamp: &deleted},
+				Status:     clusterv1.MachineStatus{NodeRef: &corev1.ObjectReference{Name: "worker-0"}, Conditions: drained},
+			},
+		},
+		{
+			name: "draining excluded",
+			machine: clusterv1.Machine{
+				ObjectMeta: metav1.ObjectMeta{DeletionTimestamp: &deleted, Annotations: map[string]string{clusterv1.ExcludeNodeDrainingAnnotation: ""}},
+				Status:     clusterv1.MachineStatus{NodeRef: &corev1.ObjectReference{Name: "worker-0"}, Conditions: draining},
+			},
+		},
+		{
+			name: "default drain timeout passed",
+			machine: clusterv1.Machine{
+				ObjectMeta: metav1.ObjectMeta{DeletionTimestamp: &longAgo},
+				Status:     clusterv1.MachineStatus{NodeRef: &corev1.ObjectReference{Name: "worker-0"}, Conditions: draining},
+			},
+			wantReason: infrav1.NodeDrainTimeoutReason,
+		},
+		{
+			name: "machine drain timeout passed",
+			machine: clusterv1.Machine{
+				ObjectMeta: metav1.ObjectMeta{DeletionTimestamp: &deleted},
+				Spec:       clusterv1.MachineSpec{NodeDrainTimeout: &metav1.Duration{Duration: 30 * time.Second}},
+				Status:     clusterv1.MachineStatus{NodeRef: &corev1.ObjectReference{Name: "worker-0"}, Conditions: draining},
+			},
+			wantReason: infrav1.NodeDrainTimeoutReason,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &infrav1.CloudSigmaMachine{ObjectMeta: metav1.ObjectMeta{DeletionTimestamp: &deleted}}
+			gate := checkDrainGate(&tt.machine, m, now)
+			if gate.Wait != tt.wantWait || gate.Reason != tt.wantReason {
+				t.Errorf("checkDrainGate() = {Wait: %v, Reason: %q}, want {Wait: %v, Reason: %q}",
+					gate.Wait, gate.Reason, tt.wantWait, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestCloudSigmaMachineReconcileDelete_DrainGate(t *testing.T) {
+	const serverUUID = "5c6d7e8f-9a0b-4c1d-8e2f-3a4b5c6d7e8f"
+
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		wantStop    bool
+	}{
+		{name: "pre-terminate hook holds the server", annotations: map[string]string{clusterv1.PreTerminateDeleteHookAnnotationPrefix + "/backup": ""}},
+		{name: "no hook stops the server", wantStop: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			stopped := false
+			mux := http.NewServeMux()
+			mux.HandleFunc("/api/2.0/tags/", func(w http.ResponseWriter, r *http.Request) {
+				writeJSON(w, map[string]interface{}{"meta": map[string]int{"total_count": 0}, "objects": []interface{}{}})
+			})
+			mux.HandleFunc("/api/2.0/servers/"+serverUUID+"/action/", func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				stopped = r.URL.Query().Get("do") == "stop"
+				mu.Unlock()
+				w.Header().Set("Content-Type", "application/json")
+				_, _ = w.Write([]byte(`{"action":"stop","result":"success"}`))
+			})
+			mux.HandleFunc("/api/2.0/servers/"+serverUUID+"/", func(w http.ResponseWriter, r *http.Request) {
+				if r.Method == http.MethodDelete {
+					w.WriteHeader(http.StatusNoContent)
+					return
+				}
+				mu.Lock()
+				status := "running"
+				if stopped {
+					status = "stopped"
+				}
+				mu.Unlock()
+				w.Header().Set("Content-Type", "application/json")
+				_, _ = w.Write([]byte(`{"uuid":"` + serverUUID + `","name":"worker-0","status":"` + status + `"}`))
+			})
+			cloudClient := newTestClient(t, mux)
+
+			now := metav1.Now()
+			machine := &clusterv1.Machine{
+				ObjectMeta: metav1.ObjectMeta{Name: "worker-0", Namespace: "default", Annotations: tt.annotations},
+			}
+			cloudSigmaMachine := &infrav1.CloudSigmaMachine{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "worker-0", Namespace: "default",
+					DeletionTimestamp: &now, Finalizers: []string{CloudSigmaMachineFinalizer},
+				},
+				Status: infrav1.CloudSigmaMachineStatus{InstanceID: serverUUID},
+			}
+			c := newTestClientBuilder(machine, cloudSigmaMachine).Build()
+			r, _ := newTestReconciler(c)
+			result, err := r.reconcileDelete(context.Background(), cloudClient, machine, cloudSigmaMachine)
+			if err != nil {
+				t.Fatalf("reconcileDelete() error = %v", err)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			if stopped != tt.wantStop {
+				t.Errorf("server stopped = %v, want %v", stopped, tt.wantStop)
+			}
+			if tt.wantStop {
+				return
+			}
+			if result.RequeueAfter == 0 {
+				t.Error("reconcileDelete() did not requeue while waiting for the hook")
+			}
+			stored := &infrav1.CloudSigmaMachine{}
+			if err := c.Get(context.Background(), client.ObjectKeyFromObject(cloudSigmaMachine), stored); err != nil {
+				t.Fatalf("Get() error = %v", err)
+			}
+			if got := conditions.GetReason(stored, infrav1.NodeDrainedCondition); got != infrav1.WaitingForPreTerminateHookReason {
+				t.Errorf("NodeDrained reason = %q, want %q", got, infrav1.WaitingForPreTerminateHookReason)
+			}
+			if len(stored.Finalizers) == 0 {
+				t.Error("finalizer removed while the server is still held")
+			}
+		})
+	}
+}
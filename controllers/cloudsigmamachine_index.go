@@ -0,0 +1,62 @@
+/*
+Copyright 2025 Kube-DC Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pkg/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	infrav1 "github.com/kube-dc/cluster-api-provider-cloudsigma/api/v1beta1"
+)
+
+// InstanceIDIndex indexes CloudSigmaMachines by status.instanceID, the UUID of their server
+const InstanceIDIndex = "status.instanceID"
+
+// indexCloudSigmaMachineByInstanceID is the index function for InstanceIDIndex
+func indexCloudSigmaMachineByInstanceID(o client.Object) []string {
+	machine, ok := o.(*infrav1.CloudSigmaMachine)
+	if !ok || machine.Status.InstanceID == "" {
+		return nil
+	}
+	return []string{machine.Status.InstanceID}
+}
+
+// CloudSigmaMachineForInstanceID returns the CloudSigmaMachine whose server has UUID instanceID,
+// in any namespace, or nil if there is none. c must be backed by a cache with InstanceIDIndex,
+// which CloudSigmaMachineReconciler.SetupWithManager registers. Two machines claiming the same
+// server is an error.
+func CloudSigmaMachineForInstanceID(ctx context.Context, c client.Reader, instanceID string) (*infrav1.CloudSigmaMachine, error) {
+	if instanceID == "" {
+		return nil, nil
+	}
+	machines := &infrav1.CloudSigmaMachineList{}
+	if err := c.List(ctx, machines, client.MatchingFields{InstanceIDIndex: instanceID}); err != nil {
+		return nil, errors.Wrapf(err, "failed to list CloudSigmaMachines with instance ID %s", instanceID)
+	}
+	switch len(machines.Items) {
+	case 0:
+		return nil, nil
+	case 1:
+		return &machines.Items[0], nil
+	default:
+		return nil, fmt.Errorf("server %s is claimed by %d CloudSigmaMachines, including %s/%s and %s/%s", instanceID,
+			len(machines.Items), machines.Items[0].Namespace, machines.Items[0].Name, machines.Items[1].Namespace, machines.Items[1].Name)
+	}
+}
@@ -0,0 +1,95 @@
+/*
+Copyright 2025 Kube-DC Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	infrav1 "github.com/kube-dc/cluster-api-provider-cloudsigma/api/v1beta1"
+)
+
+func TestCloudSigmaMachineForInstanceID(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = infrav1.AddToScheme(scheme)
+
+	newMachine := func(name, namespace, instanceID string) *infrav1.CloudSigmaMachine {
+		return &infrav1.CloudSigmaMachine{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+			Status:     infrav1.CloudSigmaMachineStatus{InstanceID: instanceID},
+		}
+	}
+
+	c := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(
+			newMachine("worker-0", "default", "server-0"),
+			newMachine("worker-1", "tenant-a", "server-1"),
+			newMachine("pending", "default", ""),
+			newMachine("dup-a", "default", "server-dup"),
+			newMachine("dup-b", "tenant-a", "server-dup"),
+		).
+		WithStatusSubresource(&infrav1.CloudSigmaMachine{}).
+		WithIndex(&infrav1.CloudSigmaMachine{}, InstanceIDIndex, indexCloudSigmaMachineByInstanceID).
+		Build()
+	ctx := context.Background()
+
+	tests := []struct {
+		instanceID string
+		want       string
+		wantErr    bool
+	}{
+		{instanceID: "server-0", want: "default/worker-0"},
+		{instanceID: "server-1", want: "tenant-a/worker-1"},
+		{instanceID: "unknown"},
+		{instanceID: ""},
+		{instanceID: "server-dup", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.instanceID, func(t *testing.T) {
+			got, err := CloudSigmaMachineForInstanceID(ctx, c, tt.instanceID)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("CloudSigmaMachineForInstanceID(%q) error = %v, wantErr %v", tt.instanceID, err, tt.wantErr)
+			}
+			name := ""
+			if got != nil {
+				name = got.Namespace + "/" + got.Name
+			}
+			if name != tt.want {
+				t.Errorf("CloudSigmaMachineForInstanceID(%q) = %q, want %q", tt.instanceID, name, tt.want)
+			}
+		})
+	}
+
+	// The index follows status updates, e.g. when a machine adopts a server
+	m := &infrav1.CloudSigmaMachine{}
+	if err := c.Get(ctx, client.ObjectKey{Namespace: "default", Name: "pending"}, m); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	m.Status.InstanceID = "server-2"
+	if err := c.Status().Update(ctx, m); err != nil {
+		t.Fatalf("Status().Update() error = %v", err)
+	}
+	if got, err := CloudSigmaMachineForInstanceID(ctx, c, "server-2"); err != nil || got == nil || got.Name != "pending" {
+		t.Errorf("CloudSigmaMachineForInstanceID(server-2) after status update = %v, %v; want default/pending", got, err)
+	}
+}
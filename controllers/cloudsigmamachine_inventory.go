@@ -0,0 +1,113 @@
+/*
+Copyright 2025 Kube-DC Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"strings"
+
+	"github.com/cloudsigma/cloudsigma-sdk-go/cloudsigma"
+	corev1 "k8s.io/api/core/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	infrav1 "github.com/kube-dc/cluster-api-provider-cloudsigma/api/v1beta1"
+	"github.com/kube-dc/cluster-api-provider-cloudsigma/pkg/cloud"
+)
+
+// InventoryMetaPrefix prefixes the server meta keys copied from CloudSigmaMachine labels and
+// annotations, so they never collide with the meta keys the provider and the guest use
+const InventoryMetaPrefix = "k8s-"
+
+// ParseInventoryMetaKeys splits a comma-separated list of label and annotation keys, dropping
+// blanks
+func ParseInventoryMetaKeys(list string) []string {
+	var keys []string
+	for _, key := range strings.Split(list, ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// inventoryMeta returns the server meta keys that show the machine's Kubernetes ownership in the
+// CloudSigma inventory: each label or annotation named in keys (the label if both exist) under
+// InventoryMetaPrefix. Prefixed keys set in spec.meta keep their spec value.
+func inventoryMeta(m *infrav1.CloudSigmaMachine, keys []string) map[string]string {
+	meta := make(map[string]string)
+	for _, key := range keys {
+		if value, ok := m.Labels[key]; ok {
+			meta[InventoryMetaPrefix+key] = value
+		} else if value, ok := m.Annotations[key]; ok {
+			meta[InventoryMetaPrefix+key] = value
+		}
+	}
+	for key, value := range m.Spec.Meta {
+		if strings.HasPrefix(key, InventoryMetaPrefix) {
+			meta[key] = value
+		}
+	}
+	return meta
+}
+
+// inventoryMetaChanged reports whether the server's prefixed meta keys differ from want
+func inventoryMetaChanged(server *cloudsigma.Server, want map[string]string) bool {
+	return serverMetaChanged(server, InventoryMetaPrefix, want)
+}
+
+// serverMetaChanged reports whether the server's meta keys starting with prefix differ from want
+func serverMetaChanged(server *cloudsigma.Server, prefix string, want map[string]string) bool {
+	have := 0
+	for key, value := range server.Meta {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		have++
+		wanted, ok := want[key]
+		if current, isString := value.(string); !ok || !isString || current != wanted {
+			return true
+		}
+	}
+	return have != len(want)
+}
+
+// reconcileInventoryMeta updates the server's inventory meta when the machine's labels or
+// annotations changed. Only meta is written, so the server keeps running; failures are reported
+// and retried on the next reconcile rather than failing it.
+func (r *CloudSigmaMachineReconciler) reconcileInventoryMeta(
+	ctx context.Context,
+	cloudClient *cloud.Client,
+	cloudSigmaMachine *infrav1.CloudSigmaMachine,
+	server *cloudsigma.Server,
+) {
+	log := ctrl.LoggerFrom(ctx)
+
+	want := inventoryMeta(cloudSigmaMachine, r.InventoryMetaKeys)
+	if !inventoryMetaChanged(server, want) {
+		return
+	}
+	updated, err := cloudClient.SyncServerMeta(ctx, server.UUID, InventoryMetaPrefix, want)
+	if err != nil {
+		log.Error(err, "Failed to update server inventory meta", "instanceID", server.UUID)
+		r.Recorder.Eventf(cloudSigmaMachine, corev1.EventTypeWarning, EventReasonServerMetaUpdateFailed,
+			"Failed to update inventory meta of server %s: %v", server.UUID, err)
+		return
+	}
+	if updated {
+		log.Info("Updated server inventory meta", "instanceID", server.UUID)
+	}
+}
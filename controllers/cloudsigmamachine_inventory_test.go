@@ -0,0 +1,152 @@
+/*
+Copyright 2025 Kube-DC Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/cloudsigma/cloudsigma-sdk-go/cloudsigma"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+
+	infrav1 "github.com/kube-dc/cluster-api-provider-cloudsigma/api/v1beta1"
+	cloudfake "github.com/kube-dc/cluster-api-provider-cloudsigma/pkg/cloud/fake"
+)
+
+func TestParseInventoryMetaKeys(t *testing.T) {
+	got := ParseInventoryMetaKeys(" cluster.x-k8s.io/cluster-name, ,team,")
+	want := []string{"cluster.x-k8s.io/cluster-name", "team"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseInventoryMetaKeys() = %q, want %q", got, want)
+	}
+	if got := ParseInventoryMetaKeys(""); len(got) != 0 {
+		t.Errorf("ParseInventoryMetaKeys(\"\") = %q, want none", got)
+	}
+}
+
+func TestInventoryMeta(t *testing.T) {
+	tests := []struct {
+		name        string
+		labels      map[string]string
+		annotations map[string]string
+		specMeta    map[string]string
+		keys        []string
+		want        map[string]string
+	}{
+		{
+			name:   "labels",
+			labels: map[string]string{"cluster.x-k8s.io/cluster-name": "prod", "cluster.x-k8s.io/set-name": "md-0-abc", "other": "x"},
+			keys:   []string{"cluster.x-k8s.io/cluster-name", "cluster.x-k8s.io/set-name"},
+			want:   map[string]string{"k8s-cluster.x-k8s.io/cluster-name": "prod", "k8s-cluster.x-k8s.io/set-name": "md-0-abc"},
+		},
+		{
+			name:        "annotations, with the label winning",
+			labels:      map[string]string{"owner": "team-a"},
+			annotations: map[string]string{"owner": "team-b", "cost-center": "42"},
+			keys:        []string{"owner", "cost-center"},
+			want:        map[string]string{"k8s-owner": "team-a", "k8s-cost-center": "42"},
+		},
+		{
+			name:   "missing keys are skipped",
+			labels: map[string]string{"owner": "team-a"},
+			keys:   []string{"owner", "missing"},
+			want:   map[string]string{"k8s-owner": "team-a"},
+		},
+		{
+			// Functional keys like machine-uid or cluster can't be overwritten through a label
+			name:   "keys are prefixed",
+			labels: map[string]string{"machine-uid": "forged", "cluster": "other"},
+			keys:   []string{"machine-uid", "cluster"},
+			want:   map[string]string{"k8s-machine-uid": "forged", "k8s-cluster": "other"},
+		},
+		{
+			name:     "spec.meta wins over labels",
+			labels:   map[string]string{"owner": "team-a"},
+			specMeta: map[string]string{"k8s-owner": "platform", "plain": "kept out"},
+			keys:     []string{"owner"},
+			want:     map[string]string{"k8s-owner": "platform"},
+		},
+		{
+			name:   "no keys configured",
+			labels: map[string]string{"owner": "team-a"},
+			want:   map[string]string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &infrav1.CloudSigmaMachine{
+				ObjectMeta: metav1.ObjectMeta{Labels: tt.labels, Annotations: tt.annotations},
+				Spec:       infrav1.CloudSigmaMachineSpec{Meta: tt.specMeta},
+			}
+			if got := inventoryMeta(m, tt.keys); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("inventoryMeta() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReconcileInventoryMeta(t *testing.T) {
+	ctx := context.Background()
+	api := cloudfake.NewServer()
+	defer api.Close()
+
+	servers, _, err := api.NewSDKClient().Servers.Create(ctx, &cloudsigma.ServerCreateRequest{
+		Servers: []cloudsigma.Server{{Name: "node-1", CPU: 2000, Memory: 2 << 30, VNCPassword: "secret",
+			Meta: map[string]interface{}{"machine-uid": "uid-1", "k8s-owner": "team-a", "k8s-retired": "x"}}},
+	})
+	if err != nil {
+		t.Fatalf("Servers.Create() error = %v", err)
+	}
+	cloudClient, err := api.NewClient()
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+
+	r := &CloudSigmaMachineReconciler{Recorder: record.NewFakeRecorder(10), InventoryMetaKeys: []string{"owner", "cluster.x-k8s.io/set-name"}}
+	m := &infrav1.CloudSigmaMachine{ObjectMeta: metav1.ObjectMeta{
+		Labels: map[string]string{"owner": "team-b", "cluster.x-k8s.io/set-name": "md-0-abc"},
+	}}
+	server := func() *cloudsigma.Server {
+		s, _ := api.GetServer(servers[0].UUID)
+		return &s
+	}
+
+	// Changed and new keys are written, stale prefixed keys dropped and other meta kept
+	r.reconcileInventoryMeta(ctx, cloudClient, m, server())
+	want := map[string]interface{}{
+		"machine-uid":                   "uid-1",
+		"k8s-owner":                     "team-b",
+		"k8s-cluster.x-k8s.io/set-name": "md-0-abc",
+	}
+	if got := server(); !reflect.DeepEqual(got.Meta, want) || got.Status != "stopped" {
+		t.Errorf("server meta = %v (status %s), want %v with the server untouched otherwise", got.Meta, got.Status, want)
+	}
+	if inventoryMetaChanged(server(), inventoryMeta(m, r.InventoryMetaKeys)) {
+		t.Errorf("inventoryMetaChanged() after sync = true, want false")
+	}
+
+	// Dropping a label removes its key on the next reconcile
+	delete(m.Labels, "owner")
+	r.reconcileInventoryMeta(ctx, cloudClient, m, server())
+	delete(want, "k8s-owner")
+	if got := server().Meta; !reflect.DeepEqual(got, want) {
+		t.Errorf("server meta after removing the owner label = %v, want %v", got, want)
+	}
+}
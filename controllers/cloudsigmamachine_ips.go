@@ -0,0 +1,174 @@
+/*
+Copyright 2025 Kube-DC Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"slices"
+
+	"github.com/pkg/errors"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	infrav1 "github.com/kube-dc/cluster-api-provider-cloudsigma/api/v1beta1"
+	"github.com/kube-dc/cluster-api-provider-cloudsigma/pkg/cloud"
+)
+
+// needsAllocatedIP reports whether the controller has to allocate the IP of a NIC
+func needsAllocatedIP(nic infrav1.CloudSigmaNIC) bool {
+	return nic.IPv4Conf.Conf == "static" && (nic.IPv4Conf.IP == nil || nic.IPv4Conf.IP.UUID == "")
+}
+
+// allocatedIPFor returns the IP allocated for the NIC at index nic, or "" if there is none
+func allocatedIPFor(m *infrav1.CloudSigmaMachine, nic int) string {
+	for _, ip := range m.Status.AllocatedIPs {
+		if ip.NIC == nic {
+			return ip.UUID
+		}
+	}
+	return ""
+}
+
+// serverNICs returns the machine's NICs with an IP set on every static NIC that does not name one.
+// IPs allocated by an earlier attempt are reused while they are still reserved for the machine.
+// New ones come from the NIC's VLAN, or the public pool for a NIC without a VLAN, are reserved
+// for the machine and recorded in status.allocatedIPs before the server is created, so that
+// releaseAllocatedIPs frees them on delete even if creation never succeeds.
+func (r *CloudSigmaMachineReconciler) serverNICs(ctx context.Context, cloudClient *cloud.Client, m *infrav1.CloudSigmaMachine) ([]infrav1.CloudSigmaNIC, error) {
+	log := ctrl.LoggerFrom(ctx)
+	owner := ipReservationOwner(m)
+
+	changed := false
+	kept := m.Status.AllocatedIPs[:0:0]
+	for _, ip := range m.Status.AllocatedIPs {
+		reserved, err := cloudClient.IsIPReservedFor(ctx, ip.UUID, owner)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to check IP allocated for NIC %d", ip.NIC)
+		}
+		if !reserved {
+			log.Info("Allocated IP is no longer reserved for this machine, allocating another", "nic", ip.NIC, "ip", ip.UUID)
+			changed = true
+			continue
+		}
+		kept = append(kept, ip)
+	}
+	m.Status.AllocatedIPs = kept
+
+	nics := make([]infrav1.CloudSigmaNIC, len(m.Spec.NICs))
+	var picked []string
+	for _, ip := range m.Status.AllocatedIPs {
+		picked = append(picked, ip.UUID)
+	}
+	for i, nic := range m.Spec.NICs {
+		nics[i] = nic
+		if !needsAllocatedIP(nic) {
+			continue
+		}
+
+		ipUUID := allocatedIPFor(m, i)
+		if ipUUID == "" {
+			var err error
+			ipUUID, err = reserveNICIP(ctx, cloudClient, m, nic, picked)
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to allocate IP for NIC %d", i)
+			}
+			log.Info("Allocated IP for static NIC", "nic", i, "ip", ipUUID)
+			m.Status.AllocatedIPs = append(m.Status.AllocatedIPs, infrav1.AllocatedIP{NIC: i, UUID: ipUUID})
+			picked = append(picked, ipUUID)
+			changed = true
+		}
+		nics[i].IPv4Conf.IP = &infrav1.CloudSigmaIPRef{UUID: ipUUID}
+	}
+
+	if changed {
+		if err := r.Status().Update(ctx, m); err != nil {
+			return nil, errors.Wrap(err, "failed to record allocated IPs")
+		}
+	}
+	return nics, nil
+}
+
+// ipReservationOwner names the machine in the meta of the IPs reserved for it
+func ipReservationOwner(m *infrav1.CloudSigmaMachine) string {
+	return string(m.UID)
+}
+
+// reserveNICIP picks a free IP for the NIC and reserves it for the machine. An IP another machine
+// reserved first is skipped, as are the IPs in exclude.
+func reserveNICIP(ctx context.Context, cloudClient *cloud.Client, m *infrav1.CloudSigmaMachine, nic infrav1.CloudSigmaNIC, exclude []string) (string, error) {
+	exclude = slices.Clone(exclude)
+	for {
+		var ipUUID string
+		if nic.VLAN != "" {
+			ip, err := cloudClient.AllocateVLANIP(ctx, nic.VLAN, exclude...)
+			if err != nil {
+				return "", err
+			}
+			ipUUID = ip.UUID
+		} else {
+			ip, err := cloudClient.AllocatePublicIP(ctx, m.Name, exclude...)
+			if err != nil {
+				return "", err
+			}
+			ipUUID = ip.UUID
+		}
+
+		reserved, err := cloudClient.ReserveIP(ctx, ipUUID, ipReservationOwner(m))
+		if err != nil {
+			return "", err
+		}
+		if !reserved {
+			exclude = append(exclude, ipUUID)
+			continue
+		}
+		if nic.VLAN == "" {
+			// Public IPs are only released by DeleteIP once tagged as ours
+			cloudClient.TagIP(ctx, ipUUID, m.Labels[clusterv1.ClusterNameLabel])
+		}
+		return ipUUID, nil
+	}
+}
+
+// releaseAllocatedIPs releases the IPs recorded in status.allocatedIPs once the server is gone.
+// IPs no longer reserved for the machine are dropped without being released. IPs that failed to
+// release are kept in status.allocatedIPs and returned as an error, so the caller can retry.
+func releaseAllocatedIPs(ctx context.Context, cloudClient *cloud.Client, m *infrav1.CloudSigmaMachine) error {
+	log := ctrl.LoggerFrom(ctx)
+
+	var failed []infrav1.AllocatedIP
+	for _, ip := range m.Status.AllocatedIPs {
+		reserved, err := cloudClient.IsIPReservedFor(ctx, ip.UUID, ipReservationOwner(m))
+		if err == nil && !reserved {
+			log.Info("Allocated IP is no longer reserved for this machine, not releasing", "nic", ip.NIC, "ip", ip.UUID)
+			continue
+		}
+		if err == nil {
+			err = cloudClient.DeleteIP(ctx, ip.UUID)
+		}
+		if err != nil {
+			log.Error(err, "Failed to release allocated IP", "nic", ip.NIC, "ip", ip.UUID)
+			failed = append(failed, ip)
+			continue
+		}
+		log.Info("Released allocated IP", "nic", ip.NIC, "ip", ip.UUID)
+	}
+	m.Status.AllocatedIPs = failed
+	if len(failed) > 0 {
+		return errors.Errorf("failed to release %d allocated IPs", len(failed))
+	}
+	return nil
+}
@@ -0,0 +1,239 @@
+/*
+Copyright 2025 Kube-DC Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/cloudsigma/cloudsigma-sdk-go/cloudsigma"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	infrav1 "github.com/kube-dc/cluster-api-provider-cloudsigma/api/v1beta1"
+	"github.com/kube-dc/cluster-api-provider-cloudsigma/pkg/cloud"
+	cloudfake "github.com/kube-dc/cluster-api-provider-cloudsigma/pkg/cloud/fake"
+)
+
+// reservedFor returns IP meta reserving the IP for the machine with the given UID
+func reservedFor(uid string) map[string]interface{} {
+	return map[string]interface{}{cloud.IPReservedForMetaKey: uid}
+}
+
+func TestCloudSigmaMachineReconcile_AllocatesStaticNICIPs(t *testing.T) {
+	api := cloudfake.NewServer()
+	defer api.Close()
+	api.AddVLAN(cloudsigma.VLAN{UUID: "vlan-1", Subscription: &cloudsigma.VLANSubscription{ID: 42}})
+	api.AddVLAN(cloudsigma.VLAN{UUID: "vlan-2", Subscription: &cloudsigma.VLANSubscription{ID: 43}})
+	for _, ip := range []cloud.IPDetail{
+		{UUID: "public-0", Meta: reservedFor("other-uid")},
+		{UUID: "public-1"},
+		{UUID: "vlan-1-lost", Subscription: &cloud.IPSubscription{ID: 42}, Meta: reservedFor("other-uid")},
+		{UUID: "vlan-1-free", Subscription: &cloud.IPSubscription{ID: 42}},
+		{UUID: "vlan-2-ip", Subscription: &cloud.IPSubscription{ID: 43}, Meta: reservedFor("machine-uid")},
+	} {
+		api.AddIP(ip)
+	}
+	cloudClient, err := api.NewClient()
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+
+	machine, bootstrapSecret, cloudSigmaMachine := newTestMachine("cp-0")
+	cloudSigmaMachine.Spec.NICs = []infrav1.CloudSigmaNIC{
+		{IPv4Conf: infrav1.CloudSigmaIPConf{Conf: "static"}},
+		{VLAN: "vlan-1", IPv4Conf: infrav1.CloudSigmaIPConf{Conf: "static"}},
+		{VLAN: "vlan-2", IPv4Conf: infrav1.CloudSigmaIPConf{Conf: "static"}},
+		{VLAN: "vlan-1", IPv4Conf: infrav1.CloudSigmaIPConf{Conf: "dhcp"}},
+	}
+	// An earlier attempt allocated the IPs of the second and third NIC, but another machine
+	// has since reserved the second one
+	cloudSigmaMachine.Status.AllocatedIPs = []infrav1.AllocatedIP{
+		{NIC: 1, UUID: "vlan-1-lost"},
+		{NIC: 2, UUID: "vlan-2-ip"},
+	}
+	c := newTestClientBuilder(machine, bootstrapSecret, cloudSigmaMachine).Build()
+	r, _ := newTestReconciler(c)
+	ctx := context.Background()
+
+	latest := &infrav1.CloudSigmaMachine{}
+	if err := c.Get(ctx, client.ObjectKeyFromObject(cloudSigmaMachine), latest); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	// Only the created server matters here, not how far the reconcile gets after creating it
+	_, _ = r.reconcileNormal(ctx, cloudClient, machine, latest)
+
+	servers, _, err := api.NewSDKClient().Servers.List(ctx)
+	if err != nil {
+		t.Fatalf("Servers.List() error = %v", err)
+	}
+	if len(servers) != 1 {
+		t.Fatalf("servers = %d, want 1", len(servers))
+	}
+	server, _ := api.GetServer(servers[0].UUID)
+	var gotIPs []string
+	for _, nic := range server.NICs {
+		ip := ""
+		if conf := nic.IP4Configuration; conf != nil && conf.IPAddress != nil {
+			ip = conf.IPAddress.UUID
+		}
+		gotIPs = append(gotIPs, ip)
+	}
+	if want := []string{"public-1", "vlan-1-free", "vlan-2-ip", ""}; !reflect.DeepEqual(gotIPs, want) {
+		t.Errorf("server NIC IPs = %v, want %v", gotIPs, want)
+	}
+
+	stored := &infrav1.CloudSigmaMachine{}
+	if err := c.Get(ctx, client.ObjectKeyFromObject(cloudSigmaMachine), stored); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	wantAllocated := []infrav1.AllocatedIP{{NIC: 2, UUID: "vlan-2-ip"}, {NIC: 0, UUID: "public-1"}, {NIC: 1, UUID: "vlan-1-free"}}
+	if !reflect.DeepEqual(stored.Status.AllocatedIPs, wantAllocated) {
+		t.Errorf("status.allocatedIPs = %+v, want %+v", stored.Status.AllocatedIPs, wantAllocated)
+	}
+	if spec := stored.Spec.NICs[0].IPv4Conf.IP; spec != nil {
+		t.Errorf("spec.nics[0].ipv4_conf.ip = %+v, want the allocated IP kept out of the spec", spec)
+	}
+	for _, uuid := range []string{"public-1", "vlan-1-free"} {
+		if ip, _ := api.GetIP(uuid); cloud.IPReservedFor(ip.Meta) != "machine-uid" {
+			t.Errorf("IP %s reserved for %q, want machine-uid", uuid, cloud.IPReservedFor(ip.Meta))
+		}
+	}
+	if ip, _ := api.GetIP("vlan-1-lost"); cloud.IPReservedFor(ip.Meta) != "other-uid" {
+		t.Errorf("IP vlan-1-lost reserved for %q, want the other machine's reservation kept", cloud.IPReservedFor(ip.Meta))
+	}
+	tag, _ := api.GetTag(cloud.ManagedByTag)
+	if !slices.ContainsFunc(tag.Resources, func(res cloudsigma.TagResource) bool { return res.UUID == "public-1" }) {
+		t.Errorf("managed tag resources = %+v, want the allocated public IP tagged", tag.Resources)
+	}
+}
+
+func TestServerNICs_MachinesDoNotShareIPs(t *testing.T) {
+	api := cloudfake.NewServer()
+	defer api.Close()
+	api.AddIP(cloud.IPDetail{UUID: "public-0"})
+	api.AddIP(cloud.IPDetail{UUID: "public-1"})
+	cloudClient, err := api.NewClient()
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+
+	var machines []client.Object
+	for _, uid := range []string{"uid-0", "uid-1", "uid-2"} {
+		machines = append(machines, &infrav1.CloudSigmaMachine{
+			ObjectMeta: metav1.ObjectMeta{Name: "worker-" + uid, Namespace: "default", UID: types.UID(uid)},
+			Spec:       infrav1.CloudSigmaMachineSpec{NICs: []infrav1.CloudSigmaNIC{{IPv4Conf: infrav1.CloudSigmaIPConf{Conf: "static"}}}},
+		})
+	}
+	r, _ := newTestReconciler(newTestClientBuilder(machines...).Build())
+	ctx := context.Background()
+
+	var got []string
+	for _, obj := range machines[:2] {
+		nics, err := r.serverNICs(ctx, cloudClient, obj.(*infrav1.CloudSigmaMachine))
+		if err != nil {
+			t.Fatalf("serverNICs(%s) error = %v", obj.GetName(), err)
+		}
+		got = append(got, nics[0].IPv4Conf.IP.UUID)
+	}
+	if want := []string{"public-0", "public-1"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("allocated IPs = %v, want %v", got, want)
+	}
+	// Both IPs are reserved even though no server uses them yet
+	if _, err := r.serverNICs(ctx, cloudClient, machines[2].(*infrav1.CloudSigmaMachine)); !cloud.IsIPPoolExhaustedError(err) {
+		t.Errorf("serverNICs() for a third machine error = %v, want the pool exhausted", err)
+	}
+}
+
+func TestCloudSigmaMachineReconcileDelete_ReleasesAllocatedIPs(t *testing.T) {
+	api := cloudfake.NewServer()
+	defer api.Close()
+	api.AddIP(cloud.IPDetail{UUID: "public-ip", Meta: reservedFor("machine-uid")})
+	api.AddIP(cloud.IPDetail{UUID: "taken-ip", Meta: reservedFor("other-uid")})
+
+	allocated := []infrav1.AllocatedIP{
+		{NIC: 0, UUID: "public-ip"},
+		// Reserved by another machine since, so no longer ours to release
+		{NIC: 1, UUID: "taken-ip"},
+		// Already gone
+		{NIC: 2, UUID: "gone-ip"},
+	}
+	newMachine := func() (*CloudSigmaMachineReconciler, *infrav1.CloudSigmaMachine) {
+		m := &infrav1.CloudSigmaMachine{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:       "cp-0",
+				Namespace:  "default",
+				UID:        "machine-uid",
+				Finalizers: []string{CloudSigmaMachineFinalizer},
+			},
+			Status: infrav1.CloudSigmaMachineStatus{AllocatedIPs: slices.Clone(allocated)},
+		}
+		r, _ := newTestReconciler(newTestClientBuilder(m).Build())
+		return r, m
+	}
+	machine := &clusterv1.Machine{ObjectMeta: metav1.ObjectMeta{Name: "cp-0", Namespace: "default"}}
+	ctx := context.Background()
+
+	t.Run("release fails", func(t *testing.T) {
+		broken, err := cloud.NewClientWithEndpoint(cloudfake.Username, "wrong-password", api.APIEndpoint())
+		if err != nil {
+			t.Fatalf("NewClientWithEndpoint() error = %v", err)
+		}
+		r, m := newMachine()
+		if _, err := r.reconcileDelete(ctx, broken, machine, m); err == nil {
+			t.Fatal("reconcileDelete() error = nil, want the failed release reported")
+		}
+		stored := &infrav1.CloudSigmaMachine{}
+		if err := r.Get(ctx, client.ObjectKeyFromObject(m), stored); err != nil {
+			t.Fatalf("Get() error = %v", err)
+		}
+		if !reflect.DeepEqual(stored.Status.AllocatedIPs, allocated) {
+			t.Errorf("status.allocatedIPs = %+v, want the failed IPs kept", stored.Status.AllocatedIPs)
+		}
+		if !slices.Contains(stored.Finalizers, CloudSigmaMachineFinalizer) {
+			t.Error("finalizer removed while IPs are still allocated")
+		}
+	})
+
+	t.Run("release succeeds", func(t *testing.T) {
+		cloudClient, err := api.NewClient()
+		if err != nil {
+			t.Fatalf("NewClient() error = %v", err)
+		}
+		r, m := newMachine()
+		r.started.Store(m.UID, struct{}{})
+		if _, err := r.reconcileDelete(ctx, cloudClient, machine, m); err != nil {
+			t.Fatalf("reconcileDelete() error = %v", err)
+		}
+		if slices.Contains(m.Finalizers, CloudSigmaMachineFinalizer) {
+			t.Error("finalizer kept after the allocated IPs were released")
+		}
+		if _, ok := r.started.Load(m.UID); ok {
+			t.Error("deleted machine is still recorded as started")
+		}
+		if ip, _ := api.GetIP("public-ip"); cloud.IPReservedFor(ip.Meta) != "" {
+			t.Errorf("IP public-ip reserved for %q after release, want no reservation", cloud.IPReservedFor(ip.Meta))
+		}
+		if ip, _ := api.GetIP("taken-ip"); cloud.IPReservedFor(ip.Meta) != "other-uid" {
+			t.Errorf("IP taken-ip reserved for %q, want the other machine's reservation kept", cloud.IPReservedFor(ip.Meta))
+		}
+	})
+}
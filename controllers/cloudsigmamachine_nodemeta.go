@@ -0,0 +1,63 @@
+/*
+Copyright 2025 Kube-DC Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	"github.com/cloudsigma/cloudsigma-sdk-go/cloudsigma"
+	corev1 "k8s.io/api/core/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	infrav1 "github.com/kube-dc/cluster-api-provider-cloudsigma/api/v1beta1"
+	"github.com/kube-dc/cluster-api-provider-cloudsigma/pkg/cloud"
+)
+
+// nodeMeta returns the server meta keys carrying the Machine's labels and annotations in the
+// domains CAPI propagates to nodes
+func nodeMeta(machine *clusterv1.Machine) map[string]string {
+	return cloud.NodeMeta(machine.Labels, machine.Annotations)
+}
+
+// reconcileNodeMeta stashes the Machine's node labels and annotations in the server meta when
+// they changed, for the CCM of the workload cluster to apply to the node. Failures are reported
+// and retried on the next reconcile rather than failing it.
+func (r *CloudSigmaMachineReconciler) reconcileNodeMeta(
+	ctx context.Context,
+	cloudClient *cloud.Client,
+	machine *clusterv1.Machine,
+	cloudSigmaMachine *infrav1.CloudSigmaMachine,
+	server *cloudsigma.Server,
+) {
+	log := ctrl.LoggerFrom(ctx)
+
+	want := nodeMeta(machine)
+	if !serverMetaChanged(server, cloud.NodeMetaPrefix, want) {
+		return
+	}
+	updated, err := cloudClient.SyncServerMeta(ctx, server.UUID, cloud.NodeMetaPrefix, want)
+	if err != nil {
+		log.Error(err, "Failed to update server node meta", "instanceID", server.UUID)
+		r.Recorder.Eventf(cloudSigmaMachine, corev1.EventTypeWarning, EventReasonServerMetaUpdateFailed,
+			"Failed to update node meta of server %s: %v", server.UUID, err)
+		return
+	}
+	if updated {
+		log.Info("Updated server node meta", "instanceID", server.UUID)
+	}
+}
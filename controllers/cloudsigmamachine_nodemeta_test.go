@@ -0,0 +1,83 @@
+/*
+Copyright 2025 Kube-DC Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/cloudsigma/cloudsigma-sdk-go/cloudsigma"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+
+	infrav1 "github.com/kube-dc/cluster-api-provider-cloudsigma/api/v1beta1"
+	cloudfake "github.com/kube-dc/cluster-api-provider-cloudsigma/pkg/cloud/fake"
+)
+
+func TestReconcileNodeMeta(t *testing.T) {
+	ctx := context.Background()
+	api := cloudfake.NewServer()
+	defer api.Close()
+
+	servers, _, err := api.NewSDKClient().Servers.Create(ctx, &cloudsigma.ServerCreateRequest{
+		Servers: []cloudsigma.Server{{Name: "node-1", CPU: 2000, Memory: 2 << 30, VNCPassword: "secret",
+			Meta: map[string]interface{}{"machine-uid": "uid-1", "node-meta.label.node.cluster.x-k8s.io/retired": "x"}}},
+	})
+	if err != nil {
+		t.Fatalf("Servers.Create() error = %v", err)
+	}
+	cloudClient, err := api.NewClient()
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+
+	r := &CloudSigmaMachineReconciler{Recorder: record.NewFakeRecorder(10)}
+	machine := &clusterv1.Machine{ObjectMeta: metav1.ObjectMeta{
+		Labels: map[string]string{
+			"node-role.kubernetes.io/worker": "",
+			"node.cluster.x-k8s.io/pool":     "gpu",
+			"cluster.x-k8s.io/cluster-name":  "prod",
+		},
+		Annotations: map[string]string{"node.cluster.x-k8s.io/owner": "team-a", "other": "x"},
+	}}
+	server := func() *cloudsigma.Server {
+		s, _ := api.GetServer(servers[0].UUID)
+		return &s
+	}
+
+	// Node labels and annotations are stashed, stale ones dropped and other meta kept
+	r.reconcileNodeMeta(ctx, cloudClient, machine, &infrav1.CloudSigmaMachine{}, server())
+	want := map[string]interface{}{
+		"machine-uid": "uid-1",
+		"node-meta.label.node-role.kubernetes.io/worker":   "",
+		"node-meta.label.node.cluster.x-k8s.io/pool":       "gpu",
+		"node-meta.annotation.node.cluster.x-k8s.io/owner": "team-a",
+	}
+	if got := server().Meta; !reflect.DeepEqual(got, want) {
+		t.Errorf("server meta = %v, want %v", got, want)
+	}
+
+	// Removing a label from the Machine removes it from the meta
+	delete(machine.Labels, "node.cluster.x-k8s.io/pool")
+	r.reconcileNodeMeta(ctx, cloudClient, machine, &infrav1.CloudSigmaMachine{}, server())
+	delete(want, "node-meta.label.node.cluster.x-k8s.io/pool")
+	if got := server().Meta; !reflect.DeepEqual(got, want) {
+		t.Errorf("server meta after removing the pool label = %v, want %v", got, want)
+	}
+}
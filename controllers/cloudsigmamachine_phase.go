@@ -0,0 +1,214 @@
+/*
+Copyright 2025 Kube-DC Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"time"
+
+	"github.com/cloudsigma/cloudsigma-sdk-go/cloudsigma"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	infrav1 "github.com/kube-dc/cluster-api-provider-cloudsigma/api/v1beta1"
+	"github.com/kube-dc/cluster-api-provider-cloudsigma/pkg/cloud"
+)
+
+// serverPhase is how far a machine's server is on its way to being ready, derived from the live
+// server status and whether its addresses are known
+type serverPhase string
+
+const (
+	// serverPhasePending is a server that has not been started (stopped, or any status other than
+	// starting and running)
+	serverPhasePending serverPhase = "pending"
+	// serverPhaseStarting is a server CloudSigma is booting
+	serverPhaseStarting serverPhase = "starting"
+	// serverPhaseRunning is a running server whose addresses are not known yet
+	serverPhaseRunning serverPhase = "running"
+	// serverPhaseReady is a running server with addresses
+	serverPhaseReady serverPhase = "ready"
+)
+
+// serverPhaseFor maps a server status and address presence to a phase
+func serverPhaseFor(status string, hasAddresses bool) serverPhase {
+	switch status {
+	case "running":
+		if hasAddresses {
+			return serverPhaseReady
+		}
+		return serverPhaseRunning
+	case "starting":
+		return serverPhaseStarting
+	default:
+		return serverPhasePending
+	}
+}
+
+func (r *CloudSigmaMachineReconciler) serverStartTimeout() time.Duration {
+	if r.ServerStartTimeout <= 0 {
+		return DefaultServerStartTimeout
+	}
+	return r.ServerStartTimeout
+}
+
+// phaseRequeue returns how long to wait before checking a server in phase again. Starting servers
+// and running servers without addresses change within seconds, so they are polled faster than
+// the requeue interval; ready servers only need the periodic sync.
+func (r *CloudSigmaMachineReconciler) phaseRequeue(phase serverPhase) time.Duration {
+	switch phase {
+	case serverPhaseStarting:
+		return min(r.requeueInterval(), ServerStartingRequeueInterval)
+	case serverPhaseRunning:
+		return min(r.requeueInterval(), ServerAddressRequeueInterval)
+	case serverPhaseReady:
+		return r.syncInterval()
+	default:
+		return r.requeueInterval()
+	}
+}
+
+// markServerPhase records phase in the ServerReady condition and status.ready and returns when to
+// check the server again.
+//
+// While the server is pending or starting, ServerReady is False with ServerNotRunningReason and a
+// message that does not change between the two, so its last transition time is when the server
+// last left running. Once that, or a retry of a stuck start, is longer ago than the start timeout,
+// the condition moves to ServerStartTimeoutReason until the server runs, and the server is only
+// re-checked at the sync interval. A running
+// server is ready even before its addresses are known (VLAN-only servers may never report any);
+// it is polled for addresses until ServerAddressWaitTimeout after it became ready.
+func (r *CloudSigmaMachineReconciler) markServerPhase(ctx context.Context, m *infrav1.CloudSigmaMachine, server *cloudsigma.Server, phase serverPhase, now time.Time) time.Duration {
+	log := ctrl.LoggerFrom(ctx)
+	requeueAfter := r.phaseRequeue(phase)
+
+	switch phase {
+	case serverPhaseRunning, serverPhaseReady:
+		if !m.Status.Ready {
+			r.Recorder.Eventf(m, corev1.EventTypeNormal, EventReasonServerReady, "Server %s is running", server.UUID)
+		}
+		conditions.MarkTrue(m, infrav1.ServerReadyCondition)
+		m.Status.Ready = true
+		if phase == serverPhaseRunning {
+			if since := conditions.GetLastTransitionTime(m, infrav1.ServerReadyCondition); since != nil && now.Sub(since.Time) >= ServerAddressWaitTimeout {
+				log.V(2).Info("Server running without known addresses, falling back to the sync interval", "instanceID", server.UUID)
+				requeueAfter = r.syncInterval()
+			} else {
+				log.Info("Server running but waiting for IP address assignment", "instanceID", server.UUID)
+			}
+		}
+	default:
+		m.Status.Ready = false
+		// The timeout sticks until the server runs, whatever status it moves through meanwhile
+		timedOut := conditions.GetReason(m, infrav1.ServerReadyCondition) == infrav1.ServerStartTimeoutReason
+		if !timedOut {
+			conditions.MarkFalse(m, infrav1.ServerReadyCondition, infrav1.ServerNotRunningReason,
+				clusterv1.ConditionSeverityInfo, "Waiting for server %s to reach running", server.UUID)
+			if timedOut = r.startTimedOut(m, now); timedOut {
+				r.Recorder.Eventf(m, corev1.EventTypeWarning, EventReasonServerStartTimeout,
+					"Server %s did not reach running within %v", server.UUID, r.serverStartTimeout())
+			}
+		}
+		if timedOut {
+			conditions.MarkFalse(m, infrav1.ServerReadyCondition, infrav1.ServerStartTimeoutReason,
+				clusterv1.ConditionSeverityError, "Server %s did not reach running within %v, last status: %s",
+				server.UUID, r.serverStartTimeout(), server.Status)
+			requeueAfter = r.syncInterval()
+		}
+	}
+
+	if err := r.Status().Update(ctx, m); err != nil {
+		log.V(4).Info("Failed to update ready status", "error", err)
+	}
+	return requeueAfter
+}
+
+// startTimedOut reports whether the server has been on its way to running for longer than the
+// start timeout: since ServerReady last turned False, or since the start was retried
+func (r *CloudSigmaMachineReconciler) startTimedOut(m *infrav1.CloudSigmaMachine, now time.Time) bool {
+	since := conditions.GetLastTransitionTime(m, infrav1.ServerReadyCondition)
+	if since == nil {
+		return false
+	}
+	start := since.Time
+	if retried, ok := startRetried(m); ok && retried.After(start) {
+		start = retried
+	}
+	return now.Sub(start) >= r.serverStartTimeout()
+}
+
+// startRetried returns when a stuck start of the machine's server was retried
+func startRetried(m *infrav1.CloudSigmaMachine) (time.Time, bool) {
+	value, ok := m.Annotations[infrav1.StartRetriedAnnotation]
+	if !ok {
+		return time.Time{}, false
+	}
+	retried, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return retried, true
+}
+
+// retryStuckStart stops a server that is still starting after the start timeout, once, so the
+// next reconcile starts it again. The retry is recorded in StartRetriedAnnotation, which restarts
+// the timeout window and is removed once the server runs. It returns a non-zero result when the
+// server was stopped.
+func (r *CloudSigmaMachineReconciler) retryStuckStart(ctx context.Context, cloudClient *cloud.Client, m *infrav1.CloudSigmaMachine, server *cloudsigma.Server, phase serverPhase, now time.Time) (ctrl.Result, error) {
+	log := ctrl.LoggerFrom(ctx)
+	_, retried := m.Annotations[infrav1.StartRetriedAnnotation]
+
+	if phase == serverPhaseRunning || phase == serverPhaseReady {
+		if retried {
+			delete(m.Annotations, infrav1.StartRetriedAnnotation)
+			if err := r.Update(ctx, m); err != nil {
+				log.V(4).Info("Failed to clear start retry annotation", "error", err)
+			}
+		}
+		return ctrl.Result{}, nil
+	}
+	if !r.RetryStuckStart || retried || phase != serverPhaseStarting || conditions.IsTrue(m, infrav1.ServerReadyCondition) || !r.startTimedOut(m, now) {
+		return ctrl.Result{}, nil
+	}
+
+	log.Info("Server stuck starting, stopping it to start it again", "instanceID", server.UUID, "timeout", r.serverStartTimeout())
+	if err := cloudClient.StopServer(ctx, server.UUID); err != nil {
+		r.Recorder.Eventf(m, corev1.EventTypeWarning, EventReasonServerStopFailed,
+			"Failed to stop server %s stuck starting: %v", server.UUID, err)
+		return ctrl.Result{}, errors.Wrap(err, "failed to stop server stuck starting")
+	}
+	r.Recorder.Eventf(m, corev1.EventTypeWarning, EventReasonServerStartRetry,
+		"Server %s did not reach running within %v, stopping it to start it again", server.UUID, r.serverStartTimeout())
+
+	if m.Annotations == nil {
+		m.Annotations = map[string]string{}
+	}
+	m.Annotations[infrav1.StartRetriedAnnotation] = now.UTC().Format(time.RFC3339)
+	if err := r.Update(ctx, m); err != nil {
+		return ctrl.Result{}, errors.Wrap(err, "failed to record start retry")
+	}
+	m.Status.Ready = false
+	conditions.MarkFalse(m, infrav1.ServerReadyCondition, infrav1.ServerNotRunningReason,
+		clusterv1.ConditionSeverityInfo, "Waiting for server %s to reach running", server.UUID)
+	if err := r.Status().Update(ctx, m); err != nil {
+		log.V(4).Info("Failed to update ready status", "error", err)
+	}
+	return ctrl.Result{RequeueAfter: r.phaseRequeue(serverPhasePending)}, nil
+}
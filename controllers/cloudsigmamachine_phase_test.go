@@ -0,0 +1,328 @@
+/*
+Copyright 2025 Kube-DC Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cloudsigma/cloudsigma-sdk-go/cloudsigma"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+
+	infrav1 "github.com/kube-dc/cluster-api-provider-cloudsigma/api/v1beta1"
+)
+
+func TestServerPhaseFor(t *testing.T) {
+	tests := []struct {
+		status       string
+		hasAddresses bool
+		want         serverPhase
+	}{
+		{status: "stopped", want: serverPhasePending},
+		{status: "unavailable", want: serverPhasePending},
+		{status: "starting", want: serverPhaseStarting},
+		{status: "starting", hasAddresses: true, want: serverPhaseStarting},
+		{status: "running", want: serverPhaseRunning},
+		{status: "running", hasAddresses: true, want: serverPhaseReady},
+	}
+	for _, tt := range tests {
+		if got := serverPhaseFor(tt.status, tt.hasAddresses); got != tt.want {
+			t.Errorf("serverPhaseFor(%q, %v) = %s, want %s", tt.status, tt.hasAddresses, got, tt.want)
+		}
+	}
+}
+
+func TestPhaseRequeue(t *testing.T) {
+	defaults := &CloudSigmaMachineReconciler{}
+	fast := &CloudSigmaMachineReconciler{RequeueInterval: 2 * time.Second, SyncInterval: 30 * time.Second}
+
+	tests := []struct {
+		phase        serverPhase
+		want         time.Duration
+		wantWithFast time.Duration
+	}{
+		{phase: serverPhasePending, want: DefaultMachineRequeueInterval, wantWithFast: 2 * time.Second},
+		{phase: serverPhaseStarting, want: ServerStartingRequeueInterval, wantWithFast: 2 * time.Second},
+		{phase: serverPhaseRunning, want: ServerAddressRequeueInterval, wantWithFast: 2 * time.Second},
+		{phase: serverPhaseReady, want: DefaultMachineSyncInterval, wantWithFast: 30 * time.Second},
+	}
+	for _, tt := range tests {
+		if got := defaults.phaseRequeue(tt.phase); got != tt.want {
+			t.Errorf("phaseRequeue(%s) = %v, want %v", tt.phase, got, tt.want)
+		}
+		if got := fast.phaseRequeue(tt.phase); got != tt.wantWithFast {
+			t.Errorf("phaseRequeue(%s) with short intervals = %v, want %v", tt.phase, got, tt.wantWithFast)
+		}
+	}
+}
+
+// newPhaseTestReconciler returns a reconciler and a machine whose server already exists
+func newPhaseTestReconciler(t *testing.T, serverUUID string) (*CloudSigmaMachineReconciler, *clusterv1.Machine, *infrav1.CloudSigmaMachine, *record.FakeRecorder) {
+	t.Helper()
+	providerID := "cloudsigma://" + serverUUID
+	machine, bootstrapSecret, cloudSigmaMachine := newTestMachine("worker-0")
+	cloudSigmaMachine.Spec.ProviderID = &providerID
+	cloudSigmaMachine.Status.InstanceID = serverUUID
+
+	r, recorder := newTestReconciler(newTestClientBuilder(machine, bootstrapSecret, cloudSigmaMachine).Build())
+	return r, machine, cloudSigmaMachine, recorder
+}
+
+func TestReconcileNormal_ServerPhases(t *testing.T) {
+	const serverUUID = "0b2f5a0c-7c1e-4f6e-9a51-3d6c2d6f1a11"
+	const serverIP = "185.12.6.10"
+
+	var status string
+	var withIP bool
+	starts := 0
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/2.0/servers/"+serverUUID+"/", func(w http.ResponseWriter, r *http.Request) {
+		server := map[string]interface{}{"uuid": serverUUID, "name": "worker-0", "status": status}
+		if withIP {
+			server["runtime"] = map[string]interface{}{"nics": []interface{}{map[string]interface{}{"ip_v4": map[string]string{"uuid": serverIP}}}}
+		}
+		writeJSON(w, server)
+	})
+	mux.HandleFunc("/api/2.0/servers/"+serverUUID+"/action/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("do") == "start" {
+			starts++
+		}
+		writeJSON(w, map[string]string{"action": r.URL.Query().Get("do"), "result": "success", "uuid": serverUUID})
+	})
+	mux.HandleFunc("/api/2.0/ips/"+serverIP+"/", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]string{"uuid": serverIP})
+	})
+	cloudClient := newTestClient(t, mux)
+	r, machine, cloudSigmaMachine, _ := newPhaseTestReconciler(t, serverUUID)
+
+	steps := []struct {
+		status      string
+		withIP      bool
+		wantStarts  int
+		wantRequeue time.Duration
+		wantReady   bool
+	}{
+		{status: "stopped", wantStarts: 1, wantRequeue: ServerStartingRequeueInterval},
+		{status: "starting", wantStarts: 1, wantRequeue: ServerStartingRequeueInterval},
+		{status: "running", wantStarts: 1, wantRequeue: ServerAddressRequeueInterval, wantReady: true},
+		{status: "running", withIP: true, wantStarts: 1, wantRequeue: DefaultMachineSyncInterval, wantReady: true},
+	}
+	for i, step := range steps {
+		status, withIP = step.status, step.withIP
+		result, err := r.reconcileNormal(context.Background(), cloudClient, machine, cloudSigmaMachine)
+		if err != nil {
+			t.Fatalf("step %d (%s): reconcileNormal() error = %v", i, step.status, err)
+		}
+		if result.RequeueAfter != step.wantRequeue {
+			t.Errorf("step %d (%s): RequeueAfter = %v, want %v", i, step.status, result.RequeueAfter, step.wantRequeue)
+		}
+		if starts != step.wantStarts {
+			t.Errorf("step %d (%s): server started %d times, want %d", i, step.status, starts, step.wantStarts)
+		}
+		if cloudSigmaMachine.Status.Ready != step.wantReady {
+			t.Errorf("step %d (%s): Status.Ready = %v, want %v", i, step.status, cloudSigmaMachine.Status.Ready, step.wantReady)
+		}
+		if got := conditions.IsTrue(cloudSigmaMachine, infrav1.ServerReadyCondition); got != step.wantReady {
+			t.Errorf("step %d (%s): ServerReady = %v, want %v", i, step.status, got, step.wantReady)
+		}
+		if !step.wantReady {
+			if reason := conditions.GetReason(cloudSigmaMachine, infrav1.ServerReadyCondition); reason != infrav1.ServerNotRunningReason {
+				t.Errorf("step %d (%s): ServerReady reason = %q, want %q", i, step.status, reason, infrav1.ServerNotRunningReason)
+			}
+		}
+	}
+}
+
+func TestMarkServerPhase_Timeouts(t *testing.T) {
+	const serverUUID = "server-1"
+	now := time.Now()
+
+	setCondition := func(m *infrav1.CloudSigmaMachine, status corev1.ConditionStatus, reason string, age time.Duration) {
+		m.Status.Conditions = clusterv1.Conditions{{
+			Type:               infrav1.ServerReadyCondition,
+			Status:             status,
+			Reason:             reason,
+			Severity:           clusterv1.ConditionSeverityInfo,
+			Message:            "Waiting for server " + serverUUID + " to reach running",
+			LastTransitionTime: metav1.NewTime(now.Add(-age)),
+		}}
+		if status == corev1.ConditionTrue {
+			m.Status.Conditions[0].Severity, m.Status.Conditions[0].Reason, m.Status.Conditions[0].Message = "", "", ""
+		}
+	}
+
+	t.Run("starting within the timeout", func(t *testing.T) {
+		r, _, m, recorder := newPhaseTestReconciler(t, serverUUID)
+		setCondition(m, corev1.ConditionFalse, infrav1.ServerNotRunningReason, DefaultServerStartTimeout-time.Minute)
+
+		requeue := r.markServerPhase(context.Background(), m, &cloudsigma.Server{UUID: serverUUID, Status: "starting"}, serverPhaseStarting, now)
+		if requeue != ServerStartingRequeueInterval {
+			t.Errorf("requeue = %v, want %v", requeue, ServerStartingRequeueInterval)
+		}
+		if reason := conditions.GetReason(m, infrav1.ServerReadyCondition); reason != infrav1.ServerNotRunningReason {
+			t.Errorf("ServerReady reason = %q, want %q", reason, infrav1.ServerNotRunningReason)
+		}
+		if len(recorder.Events) != 0 {
+			t.Errorf("unexpected event %q", <-recorder.Events)
+		}
+	})
+
+	t.Run("starting past the timeout", func(t *testing.T) {
+		r, _, m, recorder := newPhaseTestReconciler(t, serverUUID)
+		r.ServerStartTimeout = 5 * time.Minute
+		setCondition(m, corev1.ConditionFalse, infrav1.ServerNotRunningReason, 5*time.Minute)
+
+		for i := 0; i < 2; i++ {
+			requeue := r.markServerPhase(context.Background(), m, &cloudsigma.Server{UUID: serverUUID, Status: "starting"}, serverPhaseStarting, now)
+			if requeue != DefaultMachineSyncInterval {
+				t.Errorf("call %d: requeue = %v, want %v", i, requeue, DefaultMachineSyncInterval)
+			}
+			if reason := conditions.GetReason(m, infrav1.ServerReadyCondition); reason != infrav1.ServerStartTimeoutReason {
+				t.Errorf("call %d: ServerReady reason = %q, want %q", i, reason, infrav1.ServerStartTimeoutReason)
+			}
+			if severity := conditions.GetSeverity(m, infrav1.ServerReadyCondition); severity == nil || *severity != clusterv1.ConditionSeverityError {
+				t.Errorf("call %d: ServerReady severity = %v, want %s", i, severity, clusterv1.ConditionSeverityError)
+			}
+		}
+		if len(recorder.Events) != 1 {
+			t.Fatalf("got %d events, want one ServerStartTimeout event", len(recorder.Events))
+		}
+		if event := <-recorder.Events; !strings.HasPrefix(event, corev1.EventTypeWarning+" "+EventReasonServerStartTimeout+" ") {
+			t.Errorf("event = %q, want %s", event, EventReasonServerStartTimeout)
+		}
+
+		// Reaching running clears the timeout
+		r.markServerPhase(context.Background(), m, &cloudsigma.Server{UUID: serverUUID, Status: "running"}, serverPhaseReady, now)
+		if !conditions.IsTrue(m, infrav1.ServerReadyCondition) || !m.Status.Ready {
+			t.Error("expected the machine to be ready once the server runs")
+		}
+	})
+
+	t.Run("running without addresses", func(t *testing.T) {
+		r, _, m, _ := newPhaseTestReconciler(t, serverUUID)
+		m.Status.Ready = true
+		server := &cloudsigma.Server{UUID: serverUUID, Status: "running"}
+
+		setCondition(m, corev1.ConditionTrue, "", ServerAddressWaitTimeout-time.Second)
+		if requeue := r.markServerPhase(context.Background(), m, server, serverPhaseRunning, now); requeue != ServerAddressRequeueInterval {
+			t.Errorf("requeue = %v, want %v while addresses are expected", requeue, ServerAddressRequeueInterval)
+		}
+		setCondition(m, corev1.ConditionTrue, "", ServerAddressWaitTimeout)
+		if requeue := r.markServerPhase(context.Background(), m, server, serverPhaseRunning, now); requeue != DefaultMachineSyncInterval {
+			t.Errorf("requeue = %v, want %v after the address wait", requeue, DefaultMachineSyncInterval)
+		}
+	})
+}
+
+func TestReconcileNormal_StuckStart(t *testing.T) {
+	const serverUUID = "server-1"
+
+	for _, retry := range []bool{false, true} {
+		t.Run(map[bool]string{false: "without retry", true: "with retry"}[retry], func(t *testing.T) {
+			status := "starting"
+			actions := map[string]int{}
+			mux := http.NewServeMux()
+			mux.HandleFunc("/api/2.0/servers/"+serverUUID+"/", func(w http.ResponseWriter, r *http.Request) {
+				writeJSON(w, map[string]interface{}{"uuid": serverUUID, "name": "worker-0", "status": status})
+			})
+			mux.HandleFunc("/api/2.0/servers/"+serverUUID+"/action/", func(w http.ResponseWriter, r *http.Request) {
+				actions[r.URL.Query().Get("do")]++
+				writeJSON(w, map[string]string{"action": r.URL.Query().Get("do"), "result": "success", "uuid": serverUUID})
+			})
+			cloudClient := newTestClient(t, mux)
+			r, machine, m, recorder := newPhaseTestReconciler(t, serverUUID)
+			r.RetryStuckStart = retry
+			reconcile := func() {
+				t.Helper()
+				if _, err := r.reconcileNormal(context.Background(), cloudClient, machine, m); err != nil {
+					t.Fatalf("reconcileNormal() error = %v", err)
+				}
+			}
+			// The server has been starting for longer than the timeout
+			m.Status.Conditions = clusterv1.Conditions{{
+				Type:               infrav1.ServerReadyCondition,
+				Status:             corev1.ConditionFalse,
+				Reason:             infrav1.ServerNotRunningReason,
+				Severity:           clusterv1.ConditionSeverityInfo,
+				Message:            "Waiting for server " + serverUUID + " to reach running",
+				LastTransitionTime: metav1.NewTime(time.Now().Add(-DefaultServerStartTimeout)),
+			}}
+			if err := r.Status().Update(context.Background(), m); err != nil {
+				t.Fatalf("Status().Update() error = %v", err)
+			}
+
+			reconcile()
+			if !retry {
+				if reason := conditions.GetReason(m, infrav1.ServerReadyCondition); reason != infrav1.ServerStartTimeoutReason {
+					t.Errorf("ServerReady reason = %q, want %q", reason, infrav1.ServerStartTimeoutReason)
+				}
+				if actions["stop"] != 0 {
+					t.Errorf("server stopped %d times without retry", actions["stop"])
+				}
+				return
+			}
+
+			// The first timeout stops the server, and the next reconcile starts it
+			if actions["stop"] != 1 || m.Annotations[infrav1.StartRetriedAnnotation] == "" {
+				t.Fatalf("stops = %d, annotations = %v; want one stop recorded in the annotation", actions["stop"], m.Annotations)
+			}
+			if reason := conditions.GetReason(m, infrav1.ServerReadyCondition); reason != infrav1.ServerNotRunningReason {
+				t.Errorf("ServerReady reason after retry = %q, want %q", reason, infrav1.ServerNotRunningReason)
+			}
+			if event := <-recorder.Events; !strings.HasPrefix(event, corev1.EventTypeWarning+" "+EventReasonServerStartRetry+" ") {
+				t.Errorf("event = %q, want %s", event, EventReasonServerStartRetry)
+			}
+			status = "stopped"
+			reconcile()
+			if actions["start"] != 1 {
+				t.Errorf("server started %d times after the retry stop, want 1", actions["start"])
+			}
+
+			// Still starting within the new window, then past it: no second retry, the timeout is reported
+			status = "starting"
+			reconcile()
+			if reason := conditions.GetReason(m, infrav1.ServerReadyCondition); reason != infrav1.ServerNotRunningReason {
+				t.Errorf("ServerReady reason within the retry window = %q, want %q", reason, infrav1.ServerNotRunningReason)
+			}
+			m.Annotations[infrav1.StartRetriedAnnotation] = time.Now().Add(-DefaultServerStartTimeout).UTC().Format(time.RFC3339)
+			if err := r.Update(context.Background(), m); err != nil {
+				t.Fatalf("Update() error = %v", err)
+			}
+			reconcile()
+			if reason := conditions.GetReason(m, infrav1.ServerReadyCondition); reason != infrav1.ServerStartTimeoutReason {
+				t.Errorf("ServerReady reason after the retry timed out = %q, want %q", reason, infrav1.ServerStartTimeoutReason)
+			}
+			if actions["stop"] != 1 {
+				t.Errorf("server stopped %d times, want a single retry", actions["stop"])
+			}
+
+			// Running clears the retry, so a later stuck start is retried again
+			status = "running"
+			reconcile()
+			if _, ok := m.Annotations[infrav1.StartRetriedAnnotation]; ok || !m.Status.Ready {
+				t.Errorf("annotations = %v, ready = %v after running; want retry cleared and ready", m.Annotations, m.Status.Ready)
+			}
+		})
+	}
+}
@@ -0,0 +1,140 @@
+/*
+Copyright 2025 Kube-DC Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	"github.com/cloudsigma/cloudsigma-sdk-go/cloudsigma"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	infrav1 "github.com/kube-dc/cluster-api-provider-cloudsigma/api/v1beta1"
+	"github.com/kube-dc/cluster-api-provider-cloudsigma/pkg/cloud"
+)
+
+// diskResize is a server drive that is smaller than its spec.disks entry asks for
+type diskResize struct {
+	DriveUUID string
+	Current   int64
+	Desired   int64
+}
+
+// diskSizeDrift matches spec disks to the server's drives and returns those that need to grow.
+// CreateServer names the clone of spec.disks[i] cloud.ClonedDriveName(server name, i), which is
+// how disks are matched back; unlike the device channel, the name does not depend on how channels
+// were allocated when the server was created. Disks without a size, or whose drive is unknown, are
+// skipped; shrinking is never requested.
+func diskSizeDrift(disks []infrav1.CloudSigmaDisk, server *cloudsigma.Server, drives map[string]cloudsigma.Drive) []diskResize {
+	byName := make(map[string]cloudsigma.Drive, len(server.Drives))
+	for _, sd := range server.Drives {
+		if sd.Drive == nil {
+			continue
+		}
+		if drive, ok := drives[sd.Drive.UUID]; ok {
+			byName[drive.Name] = drive
+		}
+	}
+
+	var drift []diskResize
+	for i, disk := range disks {
+		// Shared drives belong to every server they are attached to and are never resized, and
+		// cdroms keep the size of their image
+		if disk.Size <= 0 || !disk.IsCloned() || disk.Media == infrav1.DiskMediaCDROM {
+			continue
+		}
+		drive, ok := byName[cloud.ClonedDriveName(server.Name, i)]
+		if !ok || int64(drive.Size) >= disk.Size {
+			continue
+		}
+		drift = append(drift, diskResize{DriveUUID: drive.UUID, Current: int64(drive.Size), Desired: disk.Size})
+	}
+	return drift
+}
+
+// reconcileDiskSize grows the server's drives when spec.disks sizes were increased. It only acts
+// on machines carrying the allow-disk-resize annotation, since resizing stops the server.
+func (r *CloudSigmaMachineReconciler) reconcileDiskSize(
+	ctx context.Context,
+	cloudClient *cloud.Client,
+	cloudSigmaMachine *infrav1.CloudSigmaMachine,
+	server *cloudsigma.Server,
+) (ctrl.Result, error) {
+	log := ctrl.LoggerFrom(ctx)
+
+	if cloudSigmaMachine.Annotations[infrav1.AllowDiskResizeAnnotation] != "true" {
+		return ctrl.Result{}, nil
+	}
+
+	drives := make(map[string]cloudsigma.Drive, len(server.Drives))
+	for _, sd := range server.Drives {
+		if sd.Drive == nil {
+			continue
+		}
+		drive, err := cloudClient.GetDrive(ctx, sd.Drive.UUID)
+		if err != nil {
+			return ctrl.Result{}, errors.Wrap(err, "failed to get drive")
+		}
+		if drive != nil {
+			drives[drive.UUID] = *drive
+		}
+	}
+
+	drift := diskSizeDrift(cloudSigmaMachine.Spec.Disks, server, drives)
+	if len(drift) == 0 {
+		if !conditions.IsTrue(cloudSigmaMachine, infrav1.DisksResizedCondition) {
+			conditions.MarkTrue(cloudSigmaMachine, infrav1.DisksResizedCondition)
+			if err := r.Status().Update(ctx, cloudSigmaMachine); err != nil {
+				log.V(4).Info("Failed to update disk resize status", "error", err)
+			}
+		}
+		return ctrl.Result{}, nil
+	}
+
+	conditions.MarkFalse(cloudSigmaMachine, infrav1.DisksResizedCondition, infrav1.DiskResizingReason,
+		clusterv1.ConditionSeverityInfo, "Resizing %d drive(s)", len(drift))
+	if err := r.Status().Update(ctx, cloudSigmaMachine); err != nil {
+		log.V(4).Info("Failed to update disk resize status", "error", err)
+	}
+
+	for _, d := range drift {
+		log.Info("Resizing drive", "driveUUID", d.DriveUUID, "from", d.Current, "to", d.Desired)
+		if err := cloudClient.ResizeServerDrive(ctx, d.DriveUUID, d.Desired); err != nil {
+			conditions.MarkFalse(cloudSigmaMachine, infrav1.DisksResizedCondition, infrav1.DiskResizeFailedReason,
+				clusterv1.ConditionSeverityWarning, "Failed to resize drive %s: %v", d.DriveUUID, err)
+			if updateErr := r.Status().Update(ctx, cloudSigmaMachine); updateErr != nil {
+				log.V(4).Info("Failed to update disk resize status", "error", updateErr)
+			}
+			r.Recorder.Eventf(cloudSigmaMachine, corev1.EventTypeWarning, EventReasonDiskResizeFailed,
+				"Failed to resize drive %s: %v", d.DriveUUID, err)
+			return ctrl.Result{}, errors.Wrap(err, "failed to resize drive")
+		}
+		r.Recorder.Eventf(cloudSigmaMachine, corev1.EventTypeNormal, EventReasonDiskResized,
+			"Resized drive %s from %d to %d bytes", d.DriveUUID, d.Current, d.Desired)
+	}
+
+	conditions.MarkTrue(cloudSigmaMachine, infrav1.DisksResizedCondition)
+	if err := r.Status().Update(ctx, cloudSigmaMachine); err != nil {
+		log.V(4).Info("Failed to update disk resize status", "error", err)
+	}
+
+	// The server was restarted - check its state again shortly
+	return ctrl.Result{RequeueAfter: r.requeueInterval()}, nil
+}
@@ -0,0 +1,84 @@
+/*
+Copyright 2025 Kube-DC Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/cloudsigma/cloudsigma-sdk-go/cloudsigma"
+
+	infrav1 "github.com/kube-dc/cluster-api-provider-cloudsigma/api/v1beta1"
+)
+
+func TestDiskSizeDrift(t *testing.T) {
+	const gib = int64(1024 * 1024 * 1024)
+
+	// worker-0 was created with the disks data, boot; the CSI driver later attached a volume
+	server := &cloudsigma.Server{Name: "worker-0", Drives: []cloudsigma.ServerDrive{
+		{DevChannel: "0:0", Drive: &cloudsigma.Drive{UUID: "boot"}},
+		{DevChannel: "0:2", Drive: &cloudsigma.Drive{UUID: "data"}},
+		{DevChannel: "1:0", Drive: &cloudsigma.Drive{UUID: "csi-volume"}},
+	}}
+	drives := map[string]cloudsigma.Drive{
+		"boot":       {UUID: "boot", Name: "worker-0-drive-1", Size: int(20 * gib)},
+		"data":       {UUID: "data", Name: "worker-0-drive-0", Size: int(50 * gib)},
+		"csi-volume": {UUID: "csi-volume", Name: "pvc-1", Size: int(10 * gib)},
+	}
+
+	tests := []struct {
+		name  string
+		disks []infrav1.CloudSigmaDisk
+		want  []diskResize
+	}{
+		{
+			name:  "no drift",
+			disks: []infrav1.CloudSigmaDisk{{BootOrder: 0, Size: 50 * gib}, {BootOrder: 1, Size: 20 * gib}},
+		},
+		{
+			name:  "boot disk grown",
+			disks: []infrav1.CloudSigmaDisk{{BootOrder: 0, Size: 50 * gib}, {BootOrder: 1, Size: 40 * gib}},
+			want:  []diskResize{{DriveUUID: "boot", Current: 20 * gib, Desired: 40 * gib}},
+		},
+		{
+			name:  "shrink is ignored",
+			disks: []infrav1.CloudSigmaDisk{{BootOrder: 0, Size: 10 * gib}},
+		},
+		{
+			name:  "data disk grown",
+			disks: []infrav1.CloudSigmaDisk{{BootOrder: 0, Size: 60 * gib}},
+			want:  []diskResize{{DriveUUID: "data", Current: 50 * gib, Desired: 60 * gib}},
+		},
+		{
+			name:  "size unset keeps source size",
+			disks: []infrav1.CloudSigmaDisk{{BootOrder: 0}},
+		},
+		{
+			name:  "disk not attached",
+			disks: []infrav1.CloudSigmaDisk{{}, {}, {BootOrder: 0, Size: 100 * gib}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := diskSizeDrift(tt.disks, server, drives)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("diskSizeDrift() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,87 @@
+/*
+Copyright 2025 Kube-DC Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	infrav1 "github.com/kube-dc/cluster-api-provider-cloudsigma/api/v1beta1"
+	"github.com/kube-dc/cluster-api-provider-cloudsigma/pkg/cloud"
+)
+
+// VNCPasswordSecretKey is the data key of the console password in the VNC password Secret
+const VNCPasswordSecretKey = "password"
+
+// vncPasswordSecretName returns the name of the Secret holding a machine's console password
+func vncPasswordSecretName(m *infrav1.CloudSigmaMachine) string {
+	return m.Name + "-vnc"
+}
+
+// ensureVNCPassword returns the machine's console password, creating the Secret that holds it
+// on first use. The password comes from spec.meta["vnc-password"] if set, otherwise it is
+// generated once per machine. The Secret is owned by the CloudSigmaMachine, so it is garbage
+// collected with it; the caller records it in status.vncPasswordSecretRef once the server exists.
+func (r *CloudSigmaMachineReconciler) ensureVNCPassword(ctx context.Context, m *infrav1.CloudSigmaMachine) (string, error) {
+	override := m.Spec.Meta[VNCPasswordMetaKey]
+
+	secret := &corev1.Secret{}
+	key := client.ObjectKey{Namespace: m.Namespace, Name: vncPasswordSecretName(m)}
+	err := r.Get(ctx, key, secret)
+	switch {
+	case apierrors.IsNotFound(err):
+		password := override
+		if password == "" {
+			if password, err = cloud.GenerateVNCPassword(); err != nil {
+				return "", err
+			}
+		}
+		secret = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      key.Name,
+				Namespace: key.Namespace,
+				Labels:    map[string]string{clusterv1.ClusterNameLabel: m.Labels[clusterv1.ClusterNameLabel]},
+			},
+			Type: corev1.SecretTypeOpaque,
+			Data: map[string][]byte{VNCPasswordSecretKey: []byte(password)},
+		}
+		if err := controllerutil.SetControllerReference(m, secret, r.Scheme); err != nil {
+			return "", errors.Wrap(err, "failed to set owner of VNC password secret")
+		}
+		if err := r.Create(ctx, secret); err != nil {
+			return "", errors.Wrap(err, "failed to create VNC password secret")
+		}
+	case err != nil:
+		return "", errors.Wrap(err, "failed to get VNC password secret")
+	case override != "" && string(secret.Data[VNCPasswordSecretKey]) != override:
+		secret.Data = map[string][]byte{VNCPasswordSecretKey: []byte(override)}
+		if err := r.Update(ctx, secret); err != nil {
+			return "", errors.Wrap(err, "failed to update VNC password secret")
+		}
+	case len(secret.Data[VNCPasswordSecretKey]) == 0:
+		return "", errors.Errorf("VNC password secret %s has no %q key", key, VNCPasswordSecretKey)
+	}
+
+	return string(secret.Data[VNCPasswordSecretKey]), nil
+}
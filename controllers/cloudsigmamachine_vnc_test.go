@@ -0,0 +1,134 @@
+/*
+Copyright 2025 Kube-DC Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	infrav1 "github.com/kube-dc/cluster-api-provider-cloudsigma/api/v1beta1"
+	"github.com/kube-dc/cluster-api-provider-cloudsigma/pkg/cloud"
+	cloudfake "github.com/kube-dc/cluster-api-provider-cloudsigma/pkg/cloud/fake"
+)
+
+func TestEnsureVNCPassword(t *testing.T) {
+	newMachine := func(name string, meta map[string]string) *infrav1.CloudSigmaMachine {
+		return &infrav1.CloudSigmaMachine{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", UID: types.UID("uid-" + name)},
+			Spec:       infrav1.CloudSigmaMachineSpec{Meta: meta},
+		}
+	}
+	worker0 := newMachine("worker-0", nil)
+	worker1 := newMachine("worker-1", nil)
+	custom := newMachine("worker-2", map[string]string{VNCPasswordMetaKey: "chosen-by-operator"})
+
+	c := newTestClientBuilder(worker0, worker1, custom).Build()
+	r, _ := newTestReconciler(c)
+	ctx := context.Background()
+
+	first, err := r.ensureVNCPassword(ctx, worker0)
+	if err != nil {
+		t.Fatalf("ensureVNCPassword() error = %v", err)
+	}
+	if len(first) != cloud.VNCPasswordLength || first == "kubernetes" {
+		t.Errorf("generated password %q, want %d random characters", first, cloud.VNCPasswordLength)
+	}
+	again, err := r.ensureVNCPassword(ctx, worker0)
+	if err != nil {
+		t.Fatalf("ensureVNCPassword() second call error = %v", err)
+	}
+	if again != first {
+		t.Errorf("password changed between reconciles: %q then %q", first, again)
+	}
+	other, err := r.ensureVNCPassword(ctx, worker1)
+	if err != nil {
+		t.Fatalf("ensureVNCPassword() error = %v", err)
+	}
+	if other == first {
+		t.Error("two machines got the same VNC password")
+	}
+
+	secret := &corev1.Secret{}
+	if err := c.Get(ctx, client.ObjectKey{Namespace: "default", Name: "worker-0-vnc"}, secret); err != nil {
+		t.Fatalf("VNC password secret not persisted: %v", err)
+	}
+	if got := string(secret.Data[VNCPasswordSecretKey]); got != first {
+		t.Errorf("secret password = %q, want %q", got, first)
+	}
+	if len(secret.OwnerReferences) != 1 || secret.OwnerReferences[0].Name != "worker-0" ||
+		secret.OwnerReferences[0].Controller == nil || !*secret.OwnerReferences[0].Controller {
+		t.Errorf("secret owner references = %+v, want controller reference to worker-0", secret.OwnerReferences)
+	}
+
+	overridden, err := r.ensureVNCPassword(ctx, custom)
+	if err != nil {
+		t.Fatalf("ensureVNCPassword() error = %v", err)
+	}
+	if overridden != "chosen-by-operator" {
+		t.Errorf("password = %q, want the spec.meta override", overridden)
+	}
+	custom.Spec.Meta[VNCPasswordMetaKey] = "rotated"
+	if rotated, err := r.ensureVNCPassword(ctx, custom); err != nil || rotated != "rotated" {
+		t.Errorf("ensureVNCPassword() after override change = %q, %v, want rotated", rotated, err)
+	}
+}
+
+func TestCloudSigmaMachineReconcile_VNCPassword(t *testing.T) {
+	api := cloudfake.NewServer()
+	defer api.Close()
+	cloudClient, err := api.NewClient()
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+
+	machine, bootstrapSecret, cloudSigmaMachine := newTestMachine("worker-0")
+	cloudSigmaMachine.Spec.Meta = map[string]string{VNCPasswordMetaKey: "console-secret"}
+	c := newTestClientBuilder(machine, bootstrapSecret, cloudSigmaMachine).Build()
+	r, _ := newTestReconciler(c)
+
+	ctx := context.Background()
+	// Only the created server matters here, not how far the reconcile gets after creating it
+	_, _ = r.reconcileNormal(ctx, cloudClient, machine, cloudSigmaMachine)
+
+	servers, _, err := api.NewSDKClient().Servers.List(ctx)
+	if err != nil {
+		t.Fatalf("Servers.List() error = %v", err)
+	}
+	if len(servers) != 1 {
+		t.Fatalf("servers = %d, want 1", len(servers))
+	}
+	server, _ := api.GetServer(servers[0].UUID)
+	if server.VNCPassword != "console-secret" {
+		t.Errorf("vnc_password = %q, want the spec.meta override", server.VNCPassword)
+	}
+	if _, ok := server.Meta[VNCPasswordMetaKey]; ok {
+		t.Error("VNC password leaked into server meta")
+	}
+
+	stored := &infrav1.CloudSigmaMachine{}
+	if err := c.Get(ctx, client.ObjectKeyFromObject(cloudSigmaMachine), stored); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if ref := stored.Status.VNCPasswordSecretRef; ref == nil || ref.Name != "worker-0-vnc" {
+		t.Errorf("Status.VNCPasswordSecretRef = %+v, want worker-0-vnc", ref)
+	}
+}
@@ -0,0 +1,143 @@
+/*
+Copyright 2025 Kube-DC Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	infrav1 "github.com/kube-dc/cluster-api-provider-cloudsigma/api/v1beta1"
+)
+
+// machineBootstrapSecretIndex indexes Machines by spec.bootstrap.dataSecretName
+const machineBootstrapSecretIndex = "spec.bootstrap.dataSecretName"
+
+// indexMachineByBootstrapSecret is the index function for machineBootstrapSecretIndex
+func indexMachineByBootstrapSecret(o client.Object) []string {
+	machine, ok := o.(*clusterv1.Machine)
+	if !ok || machine.Spec.Bootstrap.DataSecretName == nil {
+		return nil
+	}
+	return []string{*machine.Spec.Bootstrap.DataSecretName}
+}
+
+// bootstrapSecretToCloudSigmaMachines maps a bootstrap data Secret to the CloudSigmaMachines of the
+// Machines that reference it, so a machine waiting for bootstrap data is reconciled as soon as the
+// secret is written instead of on its next poll
+func (r *CloudSigmaMachineReconciler) bootstrapSecretToCloudSigmaMachines(ctx context.Context, o client.Object) []reconcile.Request {
+	secret, ok := o.(*corev1.Secret)
+	if !ok {
+		return nil
+	}
+
+	machines := &clusterv1.MachineList{}
+	if err := r.List(ctx, machines,
+		client.InNamespace(secret.Namespace),
+		client.MatchingFields{machineBootstrapSecretIndex: secret.Name},
+	); err != nil {
+		return nil
+	}
+
+	infraGK := infrav1.GroupVersion.WithKind("CloudSigmaMachine").GroupKind()
+	var requests []reconcile.Request
+	for _, machine := range machines.Items {
+		ref := machine.Spec.InfrastructureRef
+		if ref.GroupVersionKind().GroupKind() != infraGK || ref.Name == "" {
+			continue
+		}
+		requests = append(requests, reconcile.Request{
+			NamespacedName: client.ObjectKey{Namespace: machine.Namespace, Name: ref.Name},
+		})
+	}
+	return requests
+}
+
+// cloudSigmaClusterToCloudSigmaMachines maps a CloudSigmaCluster to the CloudSigmaMachines of its
+// cluster. The machines carry the name of the owning CAPI Cluster in the cluster-name label, which
+// need not match the CloudSigmaCluster's name; without an owner yet, the CloudSigmaCluster's own
+// cluster-name label is used.
+func (r *CloudSigmaMachineReconciler) cloudSigmaClusterToCloudSigmaMachines(ctx context.Context, o client.Object) []reconcile.Request {
+	cloudSigmaCluster, ok := o.(*infrav1.CloudSigmaCluster)
+	if !ok {
+		return nil
+	}
+
+	clusterName := cloudSigmaCluster.Labels[clusterv1.ClusterNameLabel]
+	cluster, err := util.GetOwnerCluster(ctx, r.Client, cloudSigmaCluster.ObjectMeta)
+	if err != nil {
+		ctrl.LoggerFrom(ctx).V(4).Info("Failed to get owner Cluster", "cloudSigmaCluster", cloudSigmaCluster.Name, "error", err)
+	} else if cluster != nil {
+		clusterName = cluster.Name
+	}
+	if clusterName == "" {
+		return nil
+	}
+
+	machines := &infrav1.CloudSigmaMachineList{}
+	if err := r.List(ctx, machines,
+		client.InNamespace(cloudSigmaCluster.Namespace),
+		client.MatchingLabels{clusterv1.ClusterNameLabel: clusterName},
+	); err != nil {
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for _, machine := range machines.Items {
+		requests = append(requests, reconcile.Request{
+			NamespacedName: client.ObjectKey{Namespace: machine.Namespace, Name: machine.Name},
+		})
+	}
+	return requests
+}
+
+// clusterNetworkChanged passes CloudSigmaCluster updates that change the VLAN or the NetworkReady
+// condition, so machines waiting for the cluster network are reconciled once it is ready without
+// reacting to every status write of the cluster
+var clusterNetworkChanged = predicate.Funcs{
+	CreateFunc:  func(event.CreateEvent) bool { return false },
+	DeleteFunc:  func(event.DeleteEvent) bool { return false },
+	GenericFunc: func(event.GenericEvent) bool { return false },
+	UpdateFunc: func(e event.UpdateEvent) bool {
+		oldCluster, ok := e.ObjectOld.(*infrav1.CloudSigmaCluster)
+		if !ok {
+			return false
+		}
+		newCluster, ok := e.ObjectNew.(*infrav1.CloudSigmaCluster)
+		if !ok {
+			return false
+		}
+		return clusterVLAN(oldCluster) != clusterVLAN(newCluster) ||
+			conditions.IsTrue(oldCluster, infrav1.NetworkReadyCondition) != conditions.IsTrue(newCluster, infrav1.NetworkReadyCondition)
+	},
+}
+
+// clusterVLAN returns the VLAN UUID recorded in the cluster status, if any
+func clusterVLAN(cluster *infrav1.CloudSigmaCluster) string {
+	if cluster.Status.Network == nil {
+		return ""
+	}
+	return cluster.Status.Network.VLANUUID
+}
@@ -0,0 +1,211 @@
+/*
+Copyright 2025 Kube-DC Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	infrav1 "github.com/kube-dc/cluster-api-provider-cloudsigma/api/v1beta1"
+)
+
+func TestBootstrapSecretToCloudSigmaMachines(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	_ = clusterv1.AddToScheme(scheme)
+	_ = infrav1.AddToScheme(scheme)
+
+	newMachine := func(name, namespace, secretName, infraKind, infraName string) *clusterv1.Machine {
+		machine := &clusterv1.Machine{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+			Spec: clusterv1.MachineSpec{
+				ClusterName: "test",
+				InfrastructureRef: corev1.ObjectReference{
+					APIVersion: infrav1.GroupVersion.String(),
+					Kind:       infraKind,
+					Name:       infraName,
+				},
+			},
+		}
+		if secretName != "" {
+			machine.Spec.Bootstrap.DataSecretName = &secretName
+		}
+		return machine
+	}
+
+	c := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(
+			newMachine("worker-0", "default", "worker-0-bootstrap", "CloudSigmaMachine", "worker-0-infra"),
+			newMachine("worker-1", "default", "worker-1-bootstrap", "CloudSigmaMachine", "worker-1-infra"),
+			newMachine("pending", "default", "", "CloudSigmaMachine", "pending-infra"),
+			newMachine("other-provider", "default", "worker-0-bootstrap", "DockerMachine", "docker-infra"),
+			newMachine("worker-0", "other", "worker-0-bootstrap", "CloudSigmaMachine", "other-infra"),
+		).
+		WithIndex(&clusterv1.Machine{}, machineBootstrapSecretIndex, indexMachineByBootstrapSecret).
+		Build()
+	r := &CloudSigmaMachineReconciler{Client: c, Scheme: scheme}
+
+	tests := []struct {
+		name   string
+		object client.Object
+		want   []reconcile.Request
+	}{
+		{
+			name:   "bootstrap secret appears",
+			object: &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "worker-0-bootstrap", Namespace: "default"}},
+			want: []reconcile.Request{{
+				NamespacedName: client.ObjectKey{Namespace: "default", Name: "worker-0-infra"},
+			}},
+		},
+		{
+			name:   "unrelated secret",
+			object: &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "kubeconfig", Namespace: "default"}},
+		},
+		{
+			name:   "not a secret",
+			object: &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "worker-0-bootstrap", Namespace: "default"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := r.bootstrapSecretToCloudSigmaMachines(context.Background(), tt.object)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("bootstrapSecretToCloudSigmaMachines() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCloudSigmaClusterToCloudSigmaMachines(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clusterv1.AddToScheme(scheme)
+	_ = infrav1.AddToScheme(scheme)
+
+	newMachine := func(name, namespace, clusterName string) *infrav1.CloudSigmaMachine {
+		return &infrav1.CloudSigmaMachine{ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+			Labels:    map[string]string{clusterv1.ClusterNameLabel: clusterName},
+		}}
+	}
+	// The CloudSigmaCluster of a Cluster may be named differently, e.g. when created from a
+	// ClusterClass template
+	ownedBy := func(cluster string) []metav1.OwnerReference {
+		return []metav1.OwnerReference{{APIVersion: clusterv1.GroupVersion.String(), Kind: "Cluster", Name: cluster}}
+	}
+	c := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(
+			&clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"}},
+			newMachine("test-cp-0", "default", "test"),
+			newMachine("test-worker-0", "default", "test"),
+			newMachine("other-worker-0", "default", "other"),
+			newMachine("test-worker-0", "staging", "test"),
+		).
+		Build()
+	want := []reconcile.Request{
+		{NamespacedName: client.ObjectKey{Namespace: "default", Name: "test-cp-0"}},
+		{NamespacedName: client.ObjectKey{Namespace: "default", Name: "test-worker-0"}},
+	}
+	r := &CloudSigmaMachineReconciler{Client: c, Scheme: scheme}
+
+	tests := []struct {
+		name   string
+		object client.Object
+		want   []reconcile.Request
+	}{
+		{
+			name: "owned by the cluster",
+			object: &infrav1.CloudSigmaCluster{ObjectMeta: metav1.ObjectMeta{
+				Name: "test-x7k2p", Namespace: "default", OwnerReferences: ownedBy("test"),
+			}},
+			want: want,
+		},
+		{
+			name: "not owned yet",
+			object: &infrav1.CloudSigmaCluster{ObjectMeta: metav1.ObjectMeta{
+				Name: "test-x7k2p", Namespace: "default", Labels: map[string]string{clusterv1.ClusterNameLabel: "test"},
+			}},
+			want: want,
+		},
+		{
+			name:   "neither owner nor label",
+			object: &infrav1.CloudSigmaCluster{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"}},
+		},
+		{
+			name: "cluster without machines",
+			object: &infrav1.CloudSigmaCluster{ObjectMeta: metav1.ObjectMeta{
+				Name: "empty", Namespace: "default", Labels: map[string]string{clusterv1.ClusterNameLabel: "empty"},
+			}},
+		},
+		{
+			name:   "not a cluster",
+			object: &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := r.cloudSigmaClusterToCloudSigmaMachines(context.Background(), tt.object)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("cloudSigmaClusterToCloudSigmaMachines() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClusterNetworkChanged(t *testing.T) {
+	newCluster := func(vlan string, networkReady bool) *infrav1.CloudSigmaCluster {
+		cluster := &infrav1.CloudSigmaCluster{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"}}
+		if vlan != "" {
+			cluster.Status.Network = &infrav1.NetworkStatus{VLANUUID: vlan}
+		}
+		if networkReady {
+			conditions.MarkTrue(cluster, infrav1.NetworkReadyCondition)
+		}
+		return cluster
+	}
+
+	tests := []struct {
+		name     string
+		old, new *infrav1.CloudSigmaCluster
+		want     bool
+	}{
+		{name: "VLAN set", old: newCluster("", false), new: newCluster("vlan-1", false), want: true},
+		{name: "network becomes ready", old: newCluster("vlan-1", false), new: newCluster("vlan-1", true), want: true},
+		{name: "VLAN replaced", old: newCluster("vlan-1", true), new: newCluster("vlan-2", true), want: true},
+		{name: "unrelated update", old: newCluster("vlan-1", true), new: newCluster("vlan-1", true)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := clusterNetworkChanged.Update(event.UpdateEvent{ObjectOld: tt.old, ObjectNew: tt.new}); got != tt.want {
+				t.Errorf("clusterNetworkChanged.Update() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+/*
+Copyright 2025 Kube-DC Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"fmt"
+
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+)
+
+// DefaultMaxConcurrentReconciles is the number of workers per controller when none is configured
+const DefaultMaxConcurrentReconciles = 1
+
+// ValidateMaxConcurrentReconciles returns an error if the worker count flag is not positive
+func ValidateMaxConcurrentReconciles(n int) error {
+	if n < 1 {
+		return fmt.Errorf("--max-concurrent-reconciles must be at least 1, got %d", n)
+	}
+	return nil
+}
+
+// controllerOptions returns the options shared by the CloudSigma controllers
+func controllerOptions(maxConcurrentReconciles int) controller.Options {
+	if maxConcurrentReconciles <= 0 {
+		maxConcurrentReconciles = DefaultMaxConcurrentReconciles
+	}
+	return controller.Options{MaxConcurrentReconciles: maxConcurrentReconciles}
+}
@@ -0,0 +1,116 @@
+/*
+Copyright 2025 Kube-DC Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/types"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	infrav1 "github.com/kube-dc/cluster-api-provider-cloudsigma/api/v1beta1"
+	cloudfake "github.com/kube-dc/cluster-api-provider-cloudsigma/pkg/cloud/fake"
+)
+
+func TestControllerOptions(t *testing.T) {
+	tests := []struct {
+		configured int
+		want       int
+	}{
+		{configured: 0, want: DefaultMaxConcurrentReconciles},
+		{configured: 1, want: 1},
+		{configured: 8, want: 8},
+	}
+	for _, tt := range tests {
+		if got := controllerOptions(tt.configured).MaxConcurrentReconciles; got != tt.want {
+			t.Errorf("controllerOptions(%d).MaxConcurrentReconciles = %d, want %d", tt.configured, got, tt.want)
+		}
+	}
+
+	if err := ValidateMaxConcurrentReconciles(0); err == nil {
+		t.Error("ValidateMaxConcurrentReconciles(0) = nil, want error")
+	}
+	if err := ValidateMaxConcurrentReconciles(4); err != nil {
+		t.Errorf("ValidateMaxConcurrentReconciles(4) error = %v", err)
+	}
+}
+
+func TestCloudSigmaMachineReconcile_ParallelWorkers(t *testing.T) {
+	const workers = 5
+
+	api := cloudfake.NewServer()
+	defer api.Close()
+	cloudClient, err := api.NewClient()
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+
+	// Every worker reconciles its own machine, as controller-runtime never hands one machine to
+	// two workers at once
+	var objects []client.Object
+	machines := make([]*clusterv1.Machine, workers)
+	for i := range machines {
+		machine, secret, cloudSigmaMachine := newTestMachine(fmt.Sprintf("worker-%d", i))
+		cloudSigmaMachine.UID = types.UID(machine.Name + "-uid")
+		machines[i] = machine
+		objects = append(objects, machine, secret, cloudSigmaMachine)
+	}
+	c := newTestClientBuilder(objects...).Build()
+	r, _ := newTestReconciler(c)
+	r.MaxConcurrentReconciles = workers
+
+	ctx := context.Background()
+	var wg sync.WaitGroup
+	for _, machine := range machines {
+		latest := &infrav1.CloudSigmaMachine{}
+		if err := c.Get(ctx, client.ObjectKeyFromObject(machine), latest); err != nil {
+			t.Fatalf("Get() error = %v", err)
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// Only the created server matters here, not how far the reconcile gets after creating it
+			_, _ = r.reconcileNormal(ctx, cloudClient, machine, latest)
+		}()
+	}
+	wg.Wait()
+
+	servers, _, err := api.NewSDKClient().Servers.List(ctx)
+	if err != nil {
+		t.Fatalf("Servers.List() error = %v", err)
+	}
+	if len(servers) != workers {
+		t.Fatalf("servers created = %d, want one per machine (%d)", len(servers), workers)
+	}
+	byName := make(map[string]string)
+	for _, server := range servers {
+		byName[server.Name] = server.UUID
+	}
+	for _, machine := range machines {
+		stored := &infrav1.CloudSigmaMachine{}
+		if err := c.Get(ctx, client.ObjectKeyFromObject(machine), stored); err != nil {
+			t.Fatalf("Get() error = %v", err)
+		}
+		if want := byName[machine.Name]; want == "" || stored.Status.InstanceID != want {
+			t.Errorf("%s Status.InstanceID = %q, want its own server %q", machine.Name, stored.Status.InstanceID, want)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+/*
+Copyright 2025 Kube-DC Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+// Event reasons emitted on CloudSigmaMachine objects
+const (
+	EventReasonBootstrapDataNotReady  = "BootstrapDataNotReady"
+	EventReasonServerCreated          = "ServerCreated"
+	EventReasonServerCreateFailed     = "ServerCreateFailed"
+	EventReasonServerAdopted          = "ServerAdopted"
+	EventReasonServerNotAdopted       = "ServerNotAdopted"
+	EventReasonQuotaExceeded          = "QuotaExceeded"
+	EventReasonIPAllocationFailed     = "IPAllocationFailed"
+	EventReasonDuplicateServerName    = "DuplicateServerName"
+	EventReasonOrphanedDrivesDeleted  = "OrphanedDrivesDeleted"
+	EventReasonServerStarting         = "ServerStarting"
+	EventReasonServerStartFailed      = "ServerStartFailed"
+	EventReasonServerStartTimeout     = "ServerStartTimeout"
+	EventReasonServerStartRetry       = "ServerStartRetry"
+	EventReasonServerReady            = "ServerReady"
+	EventReasonWaitingForNodeDrain    = "WaitingForNodeDrain"
+	EventReasonNodeDrainTimeout       = "NodeDrainTimeout"
+	EventReasonServerStopping         = "ServerStopping"
+	EventReasonServerStopFailed       = "ServerStopFailed"
+	EventReasonServerDeleted          = "ServerDeleted"
+	EventReasonServerDeleteFailed     = "ServerDeleteFailed"
+	EventReasonMachineFailed          = "MachineFailed"
+	EventReasonDiskResized            = "DiskResized"
+	EventReasonDiskResizeFailed       = "DiskResizeFailed"
+	EventReasonConsoleOpened          = "ConsoleOpened"
+	EventReasonConsoleOpenFailed      = "ConsoleOpenFailed"
+	EventReasonConsoleClosed          = "ConsoleClosed"
+	EventReasonServerMetaUpdateFailed = "ServerMetaUpdateFailed"
+	EventReasonBootstrapDataChanged   = "BootstrapDataChanged"
+)
+
+// Event reasons emitted on CloudSigmaCluster objects
+const (
+	EventReasonVLANReconcileFailed = "VLANReconcileFailed"
+	EventReasonClusterReady        = "ClusterReady"
+	EventReasonClusterDeleting     = "ClusterDeleting"
+)
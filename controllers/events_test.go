@@ -0,0 +1,132 @@
+/*
+Copyright 2025 Kube-DC Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+
+	infrav1 "github.com/kube-dc/cluster-api-provider-cloudsigma/api/v1beta1"
+)
+
+func TestCloudSigmaMachineReconcileEvents(t *testing.T) {
+	const serverUUID = "0b2f5a0c-7c1e-4f6e-9a51-3d6c2d6f1a11"
+	const serverIP = "185.12.6.10"
+
+	running := false
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/2.0/servers/detail/", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]interface{}{"meta": map[string]int{"total_count": 0}, "objects": []interface{}{}})
+	})
+	mux.HandleFunc("/api/2.0/servers/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.NotFound(w, r)
+			return
+		}
+		writeJSON(w, map[string]interface{}{"objects": []map[string]string{{
+			"uuid": serverUUID, "name": "worker-0", "status": "stopped",
+		}}})
+	})
+	mux.HandleFunc("/api/2.0/servers/"+serverUUID+"/", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]interface{}{
+			"uuid":    serverUUID,
+			"name":    "worker-0",
+			"status":  "running",
+			"runtime": map[string]interface{}{"nics": []interface{}{map[string]interface{}{"ip_v4": map[string]string{"uuid": serverIP}}}},
+		})
+	})
+	mux.HandleFunc("/api/2.0/servers/"+serverUUID+"/action/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("do") == "start" {
+			running = true
+		}
+		writeJSON(w, map[string]string{"action": r.URL.Query().Get("do"), "result": "success", "uuid": serverUUID})
+	})
+	mux.HandleFunc("/api/2.0/ips/"+serverIP+"/", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]string{"uuid": serverIP})
+	})
+	cloudClient := newTestClient(t, mux)
+
+	machine, bootstrapSecret, cloudSigmaMachine := newTestMachine("worker-0")
+	r, recorder := newTestReconciler(newTestClientBuilder(machine, bootstrapSecret, cloudSigmaMachine).Build())
+
+	ctx := context.Background()
+	if _, err := r.reconcileNormal(ctx, cloudClient, machine, cloudSigmaMachine); err != nil {
+		t.Fatalf("first reconcileNormal() error = %v", err)
+	}
+	if !running {
+		t.Fatal("expected server to be started after creation")
+	}
+	if _, err := r.reconcileNormal(ctx, cloudClient, machine, cloudSigmaMachine); err != nil {
+		t.Fatalf("second reconcileNormal() error = %v", err)
+	}
+	if !cloudSigmaMachine.Status.Ready {
+		t.Error("expected CloudSigmaMachine to be ready")
+	}
+
+	wantEvents := []string{
+		corev1.EventTypeNormal + " " + EventReasonServerCreated,
+		corev1.EventTypeNormal + " " + EventReasonServerStarting,
+		corev1.EventTypeNormal + " " + EventReasonServerReady,
+	}
+	close(recorder.Events)
+	var gotEvents []string
+	for event := range recorder.Events {
+		gotEvents = append(gotEvents, event)
+	}
+	if len(gotEvents) != len(wantEvents) {
+		t.Fatalf("events = %q, want prefixes %q", gotEvents, wantEvents)
+	}
+	for i, want := range wantEvents {
+		if !strings.HasPrefix(gotEvents[i], want+" ") {
+			t.Errorf("event[%d] = %q, want prefix %q", i, gotEvents[i], want)
+		}
+	}
+}
+
+func TestCloudSigmaMachineReconcileEvents_BootstrapNotReady(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/2.0/servers/detail/", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]interface{}{"meta": map[string]int{"total_count": 0}, "objects": []interface{}{}})
+	})
+	cloudClient := newTestClient(t, mux)
+
+	// The machine has no bootstrap data yet
+	machine := &clusterv1.Machine{ObjectMeta: metav1.ObjectMeta{Name: "worker-0", Namespace: "default"}}
+	cloudSigmaMachine := &infrav1.CloudSigmaMachine{
+		ObjectMeta: metav1.ObjectMeta{Name: "worker-0", Namespace: "default", UID: "machine-uid"},
+	}
+	r, recorder := newTestReconciler(newTestClientBuilder(machine, cloudSigmaMachine).Build())
+
+	if _, err := r.reconcileNormal(context.Background(), cloudClient, machine, cloudSigmaMachine); err != nil {
+		t.Fatalf("reconcileNormal() error = %v", err)
+	}
+
+	select {
+	case event := <-recorder.Events:
+		if !strings.HasPrefix(event, corev1.EventTypeNormal+" "+EventReasonBootstrapDataNotReady+" ") {
+			t.Errorf("event = %q, want %s", event, EventReasonBootstrapDataNotReady)
+		}
+	default:
+		t.Fatal("expected a BootstrapDataNotReady event")
+	}
+}
@@ -0,0 +1,126 @@
+/*
+Copyright 2025 Kube-DC Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"fmt"
+	"math/rand"
+	"time"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	infrav1 "github.com/kube-dc/cluster-api-provider-cloudsigma/api/v1beta1"
+)
+
+const (
+	// DefaultMachineRequeueInterval is how often a provisioning server is polled
+	DefaultMachineRequeueInterval = 10 * time.Second
+	// DefaultMachineSyncInterval is how often a ready server is re-checked
+	DefaultMachineSyncInterval = 60 * time.Second
+	// ServerStartingRequeueInterval caps how often a starting server is polled
+	ServerStartingRequeueInterval = 5 * time.Second
+	// ServerAddressRequeueInterval caps how often a running server without addresses is polled
+	ServerAddressRequeueInterval = 3 * time.Second
+	// ServerAddressWaitTimeout is how long a running server is polled for its addresses before
+	// falling back to the sync interval
+	ServerAddressWaitTimeout = 2 * time.Minute
+	// DefaultServerStartTimeout is how long a server may take to reach running before its
+	// ServerReady condition reports a timeout
+	DefaultServerStartTimeout = 10 * time.Minute
+	// QuotaExceededRequeueInterval is how long server creation is held off once the account is out of quota
+	QuotaExceededRequeueInterval = 5 * time.Minute
+	// DefaultNodeDrainTimeout is how long deletion waits for the node to be drained when the
+	// Machine does not set spec.nodeDrainTimeout
+	DefaultNodeDrainTimeout = 10 * time.Minute
+	// DefaultConsoleTTL is how long a console tunnel stays open when the open-console annotation
+	// does not set a duration
+	DefaultConsoleTTL = 30 * time.Minute
+	// MaxConsoleTTL caps the lifetime of a console tunnel
+	MaxConsoleTTL = 8 * time.Hour
+
+	// MinMachineRequeueInterval is the lowest accepted provisioning poll interval
+	MinMachineRequeueInterval = time.Second
+	// MinMachineSyncInterval is the lowest accepted resync interval for ready servers
+	MinMachineSyncInterval = 10 * time.Second
+	// MinServerStartTimeout is the lowest accepted server start timeout
+	MinServerStartTimeout = time.Minute
+
+	// RequeueJitterFraction is how far a machine requeue may be moved either way, as a fraction of
+	// its interval, so that machines reconciled together do not keep hitting the API together
+	RequeueJitterFraction = 0.2
+)
+
+// ValidateInterval returns an error if an interval flag is set below its minimum
+func ValidateInterval(name string, interval, minimum time.Duration) error {
+	if interval < minimum {
+		return fmt.Errorf("--%s must be at least %v, got %v", name, minimum, interval)
+	}
+	return nil
+}
+
+func (r *CloudSigmaMachineReconciler) requeueInterval() time.Duration {
+	if r.RequeueInterval <= 0 {
+		return DefaultMachineRequeueInterval
+	}
+	return r.RequeueInterval
+}
+
+func (r *CloudSigmaMachineReconciler) syncInterval() time.Duration {
+	if r.SyncInterval <= 0 {
+		return DefaultMachineSyncInterval
+	}
+	return r.SyncInterval
+}
+
+func (r *CloudSigmaMachineReconciler) jitter() float64 {
+	if r.Jitter == nil {
+		return rand.Float64()
+	}
+	return r.Jitter()
+}
+
+// jitterRequeue moves result.RequeueAfter by up to RequeueJitterFraction of it either way
+func (r *CloudSigmaMachineReconciler) jitterRequeue(result ctrl.Result, err error) (ctrl.Result, error) {
+	if result.RequeueAfter > 0 {
+		result.RequeueAfter = jittered(result.RequeueAfter, r.jitter())
+	}
+	return result, err
+}
+
+// jittered maps f in [0, 1) to a duration in [d*(1-RequeueJitterFraction), d*(1+RequeueJitterFraction))
+func jittered(d time.Duration, f float64) time.Duration {
+	return d + time.Duration((2*f-1)*RequeueJitterFraction*float64(d))
+}
+
+// startupDelay returns how long to defer the first reconcile since the controller started of a
+// machine whose server is ready, spread over one sync interval. It returns 0 for later reconciles
+// and for machines that still need provisioning or deletion.
+func (r *CloudSigmaMachineReconciler) startupDelay(m *infrav1.CloudSigmaMachine) time.Duration {
+	if _, seen := r.started.LoadOrStore(m.UID, struct{}{}); seen {
+		return 0
+	}
+	if !m.Status.Ready || m.Status.InstanceID == "" || !m.DeletionTimestamp.IsZero() {
+		return 0
+	}
+	return time.Duration(r.jitter() * float64(r.syncInterval()))
+}
+
+// forgetStarted drops a machine whose finalizer was removed from started, so the map does not keep
+// every machine ever reconciled
+func (r *CloudSigmaMachineReconciler) forgetStarted(m *infrav1.CloudSigmaMachine) {
+	r.started.Delete(m.UID)
+}
@@ -0,0 +1,151 @@
+/*
+Copyright 2025 Kube-DC Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	infrav1 "github.com/kube-dc/cluster-api-provider-cloudsigma/api/v1beta1"
+)
+
+func TestCloudSigmaMachineRequeueInterval(t *testing.T) {
+	tests := []struct {
+		name     string
+		interval time.Duration
+		want     time.Duration
+	}{
+		{name: "default", want: DefaultMachineRequeueInterval},
+		{name: "configured", interval: 42 * time.Second, want: 42 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mux := http.NewServeMux()
+			mux.HandleFunc("/api/2.0/servers/detail/", func(w http.ResponseWriter, r *http.Request) {
+				writeJSON(w, map[string]interface{}{"meta": map[string]int{"total_count": 0}, "objects": []interface{}{}})
+			})
+			cloudClient := newTestClient(t, mux)
+
+			// No bootstrap data yet, so the machine is polled at the requeue interval
+			machine := &clusterv1.Machine{ObjectMeta: metav1.ObjectMeta{Name: "worker-0", Namespace: "default"}}
+			cloudSigmaMachine := &infrav1.CloudSigmaMachine{
+				ObjectMeta: metav1.ObjectMeta{Name: "worker-0", Namespace: "default", UID: "machine-uid"},
+			}
+			r, _ := newTestReconciler(newTestClientBuilder(machine, cloudSigmaMachine).Build())
+			r.RequeueInterval = tt.interval
+
+			result, err := r.reconcileNormal(context.Background(), cloudClient, machine, cloudSigmaMachine)
+			if err != nil {
+				t.Fatalf("reconcileNormal() error = %v", err)
+			}
+			if result.RequeueAfter != tt.want {
+				t.Errorf("RequeueAfter = %v, want %v", result.RequeueAfter, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateInterval(t *testing.T) {
+	if err := ValidateInterval("machine-sync-interval", time.Second, MinMachineSyncInterval); err == nil {
+		t.Error("expected an interval below the minimum to be rejected")
+	}
+	if err := ValidateInterval("machine-sync-interval", DefaultMachineSyncInterval, MinMachineSyncInterval); err != nil {
+		t.Errorf("ValidateInterval() error = %v", err)
+	}
+}
+
+func TestJitterRequeue(t *testing.T) {
+	const base = 60 * time.Second
+	tests := []struct {
+		name   string
+		jitter float64
+		want   time.Duration
+	}{
+		{name: "lowest", jitter: 0, want: 48 * time.Second},
+		{name: "middle", jitter: 0.5, want: base},
+		{name: "high", jitter: 0.75, want: 66 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &CloudSigmaMachineReconciler{Jitter: func() float64 { return tt.jitter }}
+			result, err := r.jitterRequeue(ctrl.Result{RequeueAfter: base}, nil)
+			if err != nil {
+				t.Fatalf("jitterRequeue() error = %v", err)
+			}
+			if result.RequeueAfter != tt.want {
+				t.Errorf("RequeueAfter = %v, want %v", result.RequeueAfter, tt.want)
+			}
+		})
+	}
+
+	t.Run("default source stays in range", func(t *testing.T) {
+		r := &CloudSigmaMachineReconciler{}
+		low, high := time.Duration(float64(base)*(1-RequeueJitterFraction)), time.Duration(float64(base)*(1+RequeueJitterFraction))
+		for i := 0; i < 1000; i++ {
+			result, _ := r.jitterRequeue(ctrl.Result{RequeueAfter: base}, nil)
+			if result.RequeueAfter < low || result.RequeueAfter >= high {
+				t.Fatalf("RequeueAfter = %v, want in [%v, %v)", result.RequeueAfter, low, high)
+			}
+		}
+	})
+
+	t.Run("no requeue", func(t *testing.T) {
+		r := &CloudSigmaMachineReconciler{Jitter: func() float64 { return 0.9 }}
+		wantErr := errors.New("boom")
+		result, err := r.jitterRequeue(ctrl.Result{}, wantErr)
+		if result.RequeueAfter != 0 || err != wantErr {
+			t.Errorf("jitterRequeue() = %v, %v, want no requeue and %v", result, err, wantErr)
+		}
+	})
+}
+
+func TestStartupDelay(t *testing.T) {
+	ready := func(uid string) *infrav1.CloudSigmaMachine {
+		return &infrav1.CloudSigmaMachine{
+			ObjectMeta: metav1.ObjectMeta{UID: types.UID(uid)},
+			Status:     infrav1.CloudSigmaMachineStatus{Ready: true, InstanceID: "server-" + uid},
+		}
+	}
+	r := &CloudSigmaMachineReconciler{SyncInterval: time.Minute, Jitter: func() float64 { return 0.25 }}
+
+	if got := r.startupDelay(ready("a")); got != 15*time.Second {
+		t.Errorf("first startupDelay() = %v, want 15s", got)
+	}
+	if got := r.startupDelay(ready("a")); got != 0 {
+		t.Errorf("second startupDelay() = %v, want 0", got)
+	}
+
+	provisioning := ready("b")
+	provisioning.Status.Ready = false
+	if got := r.startupDelay(provisioning); got != 0 {
+		t.Errorf("startupDelay() of a provisioning machine = %v, want 0", got)
+	}
+	deleting := ready("c")
+	deleting.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+	if got := r.startupDelay(deleting); got != 0 {
+		t.Errorf("startupDelay() of a deleting machine = %v, want 0", got)
+	}
+}
@@ -0,0 +1,87 @@
+/*
+Copyright 2025 Kube-DC Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	"github.com/kube-dc/cluster-api-provider-cloudsigma/pkg/cloud"
+)
+
+const (
+	// RateLimitBackoff is how long a controller leaves the CloudSigma API alone after a 429 without
+	// Retry-After. It doubles with each further 429 in a row, up to MaxRateLimitBackoff.
+	RateLimitBackoff = 5 * time.Second
+	// MaxRateLimitBackoff caps the backoff after repeated 429s without Retry-After
+	MaxRateLimitBackoff = 5 * time.Minute
+)
+
+// apiThrottle holds off all reconciles of a controller while CloudSigma is throttling it, so
+// requeued objects do not keep the account over its rate limit. The zero value is ready to use.
+type apiThrottle struct {
+	mu      sync.Mutex
+	until   time.Time
+	backoff time.Duration
+}
+
+// wait returns how long reconciles are still held off at now, or 0 if they are not
+func (t *apiThrottle) wait(now time.Time) time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if d := t.until.Sub(now); d > 0 {
+		return d
+	}
+	return 0
+}
+
+// observe records the outcome of a reconcile at now. After a 429 it holds reconciles off for the
+// Retry-After delay, or for the backoff if the response had none, and returns that delay. Any
+// other outcome resets the backoff.
+func (t *apiThrottle) observe(err error, now time.Time) (time.Duration, bool) {
+	delay, limited := cloud.RetryAfterFromError(err)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !limited {
+		t.backoff = 0
+		return 0, false
+	}
+	if delay <= 0 {
+		t.backoff = min(max(2*t.backoff, RateLimitBackoff), MaxRateLimitBackoff)
+		delay = t.backoff
+	}
+	if until := now.Add(delay); until.After(t.until) {
+		t.until = until
+	}
+	return delay, true
+}
+
+// requeue returns the result for a reconcile that ended with err: if CloudSigma throttled it, a
+// requeue after the delay it asked for and true. The error is dropped so that controller-runtime
+// honors RequeueAfter instead of its own backoff.
+func (t *apiThrottle) requeue(ctx context.Context, err error) (ctrl.Result, bool) {
+	delay, limited := t.observe(err, time.Now())
+	if !limited {
+		return ctrl.Result{}, false
+	}
+	ctrl.LoggerFrom(ctx).Info("CloudSigma API is rate limiting requests, backing off", "requeueAfter", delay, "error", err.Error())
+	return ctrl.Result{RequeueAfter: delay}, true
+}
@@ -0,0 +1,157 @@
+/*
+Copyright 2025 Kube-DC Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	infrav1 "github.com/kube-dc/cluster-api-provider-cloudsigma/api/v1beta1"
+	"github.com/kube-dc/cluster-api-provider-cloudsigma/pkg/cloud"
+)
+
+func TestAPIThrottle(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	throttled := func(retryAfter time.Duration) error {
+		return &cloud.APIError{StatusCode: http.StatusTooManyRequests, RetryAfter: retryAfter}
+	}
+	var th apiThrottle
+
+	if got := th.wait(now); got != 0 {
+		t.Fatalf("wait() before any 429 = %v, want 0", got)
+	}
+
+	// Retry-After is honored as given
+	if delay, ok := th.observe(throttled(42*time.Second), now); !ok || delay != 42*time.Second {
+		t.Errorf("observe(429, Retry-After 42s) = %v, %v; want 42s, true", delay, ok)
+	}
+	if got := th.wait(now.Add(2 * time.Second)); got != 40*time.Second {
+		t.Errorf("wait() 2s later = %v, want 40s", got)
+	}
+
+	// Without Retry-After the backoff doubles with each 429 in a row, up to the cap
+	for i, want := range []time.Duration{RateLimitBackoff, 2 * RateLimitBackoff, 4 * RateLimitBackoff} {
+		if delay, _ := th.observe(throttled(0), now); delay != want {
+			t.Errorf("observe() of 429 #%d without Retry-After = %v, want %v", i+1, delay, want)
+		}
+	}
+	th.backoff = MaxRateLimitBackoff
+	if delay, _ := th.observe(throttled(0), now); delay != MaxRateLimitBackoff {
+		t.Errorf("observe() at the cap = %v, want %v", delay, MaxRateLimitBackoff)
+	}
+	// Reconciles are held off for the longest delay asked for so far
+	if got := th.wait(now); got != MaxRateLimitBackoff {
+		t.Errorf("wait() = %v, want %v", got, MaxRateLimitBackoff)
+	}
+
+	// Any other outcome resets the backoff
+	if _, ok := th.observe(errors.New("connection reset"), now); ok {
+		t.Errorf("observe() of a non-429 error reported a rate limit")
+	}
+	if delay, _ := th.observe(throttled(0), now); delay != RateLimitBackoff {
+		t.Errorf("observe() after a reset = %v, want %v", delay, RateLimitBackoff)
+	}
+}
+
+func TestCloudSigmaMachineReconcile_RateLimited(t *testing.T) {
+	const serverUUID = "9f2c1f4e-6d3a-4c8e-9a1b-2b3c4d5e6f70"
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/2.0/servers/"+serverUUID+"/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Retry-After", "42")
+		w.WriteHeader(http.StatusTooManyRequests)
+		_, _ = w.Write([]byte(`[{"error_type":"throttled","error_message":"Request was throttled"}]`))
+	})
+	cloudClient := newTestClient(t, mux)
+
+	machine := &clusterv1.Machine{ObjectMeta: metav1.ObjectMeta{Name: "worker-0", Namespace: "default"}}
+	cloudSigmaMachine := &infrav1.CloudSigmaMachine{
+		ObjectMeta: metav1.ObjectMeta{Name: "worker-0", Namespace: "default", UID: "machine-uid"},
+		Status:     infrav1.CloudSigmaMachineStatus{InstanceID: serverUUID},
+	}
+	r, _ := newTestReconciler(newTestClientBuilder(machine, cloudSigmaMachine).Build())
+	r.Jitter = func() float64 { return 0.9 }
+
+	// The throttled reconcile is requeued after Retry-After, unjittered and without an error
+	result, err := r.reconcile(context.Background(), cloudClient, machine, cloudSigmaMachine)
+	if err != nil {
+		t.Fatalf("reconcile() error = %v, want the 429 turned into a requeue", err)
+	}
+	if result.RequeueAfter != 42*time.Second {
+		t.Errorf("RequeueAfter = %v, want the Retry-After of 42s", result.RequeueAfter)
+	}
+
+	// The whole controller holds off until then
+	if wait := r.throttle.wait(time.Now()); wait <= 40*time.Second || wait > 42*time.Second {
+		t.Errorf("throttle wait = %v, want about 42s", wait)
+	}
+}
+
+func TestCloudSigmaClusterReconcile_Throttled(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clusterv1.AddToScheme(scheme)
+	_ = infrav1.AddToScheme(scheme)
+
+	cluster := &clusterv1.Cluster{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default", UID: "cluster-uid"}}
+	cloudSigmaCluster := &infrav1.CloudSigmaCluster{ObjectMeta: metav1.ObjectMeta{
+		Name:      "test",
+		Namespace: "default",
+		OwnerReferences: []metav1.OwnerReference{{
+			APIVersion: clusterv1.GroupVersion.String(), Kind: "Cluster", Name: cluster.Name, UID: cluster.UID,
+		}},
+	}}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(cluster, cloudSigmaCluster).
+		WithStatusSubresource(&infrav1.CloudSigmaCluster{}).Build()
+	r := &CloudSigmaClusterReconciler{
+		Client:                   c,
+		Scheme:                   scheme,
+		LegacyCredentialsEnabled: true,
+		CloudSigmaUsername:       "user",
+		CloudSigmaPassword:       "pass",
+		Recorder:                 record.NewFakeRecorder(10),
+	}
+	r.throttle.observe(&cloud.APIError{StatusCode: http.StatusTooManyRequests, RetryAfter: time.Minute}, time.Now())
+
+	// While throttled the cluster is not reconciled, only requeued for when the hold-off ends
+	key := types.NamespacedName{Name: "test", Namespace: "default"}
+	result, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: key})
+	if err != nil {
+		t.Fatalf("Reconcile() error = %v", err)
+	}
+	if result.RequeueAfter <= 58*time.Second || result.RequeueAfter > time.Minute {
+		t.Errorf("RequeueAfter = %v, want about 1m", result.RequeueAfter)
+	}
+	got := &infrav1.CloudSigmaCluster{}
+	if err := c.Get(context.Background(), key, got); err != nil {
+		t.Fatalf("failed to get CloudSigmaCluster: %v", err)
+	}
+	if len(got.Finalizers) != 0 || got.Status.Phase != "" {
+		t.Errorf("CloudSigmaCluster was reconciled while throttled: finalizers %v, phase %q", got.Finalizers, got.Status.Phase)
+	}
+}
@@ -17,34 +17,78 @@ limitations under the License.
 package main
 
 import (
+	"context"
 	"flag"
 	"os"
+	"strings"
+	"time"
 
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
 	"k8s.io/klog/v2"
 
 	"github.com/kube-dc/cluster-api-provider-cloudsigma/csi/driver"
+	"github.com/kube-dc/cluster-api-provider-cloudsigma/pkg/devicechannel"
+	"github.com/kube-dc/cluster-api-provider-cloudsigma/pkg/logging"
+	"github.com/kube-dc/cluster-api-provider-cloudsigma/pkg/regions"
 )
 
 func main() {
+	var logFormat string
 	var endpoint string
 	var region string
+	var regionEndpoints string
 	var cloudsigmaUsername string
 	var cloudsigmaPassword string
 	var cloudsigmaToken string
 	var tokenFile string
+	var tokenSecretNamespace string
+	var tokenSecretName string
 	var clusterName string
+	var defaultStorageType string
+	var detachPollAttempts int
+	var detachPollInterval time.Duration
+	var disableDetachEscalation bool
+	var volumeEvents bool
+	var channelPolicy string
+	var volumeDeletePolicy string
+	var tlsCertFile string
+	var tlsKeyFile string
+	var tlsClientCAFile string
 
 	flag.StringVar(&endpoint, "endpoint", "unix:///csi/csi.sock", "CSI endpoint")
 	flag.StringVar(&region, "region", os.Getenv("CLOUDSIGMA_REGION"), "CloudSigma region")
+	flag.StringVar(&regionEndpoints, "region-endpoints", os.Getenv("CLOUDSIGMA_REGION_ENDPOINTS"), "Endpoints of CloudSigma regions not on <region>.cloudsigma.com, as comma-separated region=api-host[|direct-host] entries")
 	flag.StringVar(&cloudsigmaUsername, "cloudsigma-username", os.Getenv("CLOUDSIGMA_USERNAME"), "CloudSigma API username (legacy)")
 	flag.StringVar(&cloudsigmaPassword, "cloudsigma-password", os.Getenv("CLOUDSIGMA_PASSWORD"), "CloudSigma API password (legacy)")
 	flag.StringVar(&cloudsigmaToken, "cloudsigma-token", os.Getenv("CLOUDSIGMA_ACCESS_TOKEN"), "CloudSigma API access token (recommended)")
 	flag.StringVar(&tokenFile, "token-file", os.Getenv("CLOUDSIGMA_TOKEN_FILE"), "Path to file containing access token (refreshed by CCM)")
+	flag.StringVar(&tokenSecretNamespace, "token-secret-namespace", envOrDefault("CLOUDSIGMA_TOKEN_SECRET_NAMESPACE", driver.DefaultTokenSecretNamespace), "Namespace of the token secret provisioned by CCM, read when no token or token file is set")
+	flag.StringVar(&tokenSecretName, "token-secret-name", envOrDefault("CLOUDSIGMA_TOKEN_SECRET_NAME", driver.DefaultTokenSecretName), "Name of the token secret provisioned by CCM")
 	flag.StringVar(&clusterName, "cluster-name", os.Getenv("CLUSTER_NAME"), "Cluster name for tagging drives in CloudSigma")
+	flag.StringVar(&defaultStorageType, "default-storage-type", driver.StorageTypeDSSD, "Storage type for volumes whose StorageClass doesn't set storageType (dssd or zadara)")
+	flag.StringVar(&channelPolicy, "channel-policy", envOrDefault("CSI_CHANNEL_POLICY", devicechannel.PolicyDefault), "Device channel layout for attached volumes: "+strings.Join(devicechannel.PolicyNames(), ", "))
+	flag.StringVar(&volumeDeletePolicy, "volume-delete-policy", envOrDefault("CSI_VOLUME_DELETE_POLICY", driver.VolumeDeletePolicyDelete), "What DeleteVolume does with the drive: delete, or retain to only untag it")
+	flag.StringVar(&tlsCertFile, "tls-cert-file", os.Getenv("CSI_TLS_CERT_FILE"), "Server certificate for a tcp:// endpoint (ignored for unix sockets)")
+	flag.StringVar(&tlsKeyFile, "tls-key-file", os.Getenv("CSI_TLS_KEY_FILE"), "Key of --tls-cert-file")
+	flag.StringVar(&tlsClientCAFile, "tls-client-ca-file", os.Getenv("CSI_TLS_CLIENT_CA_FILE"), "CA bundle client certificates must be signed by; enables mTLS")
+	flag.StringVar(&logFormat, "log-format", logging.FormatText, "Log output format: text or json")
+	flag.IntVar(&detachPollAttempts, "detach-poll-attempts", 30, "Drive status checks after a detach before escalating")
+	flag.DurationVar(&detachPollInterval, "detach-poll-interval", time.Second, "Delay between drive status checks after a detach")
+	flag.BoolVar(&disableDetachEscalation, "disable-detach-escalation", false, "Don't force a second detach when a volume is still attached after polling")
+	flag.BoolVar(&volumeEvents, "volume-events", false, "Record create, delete and attach failures as events on the PVC or PV (needs RBAC to get PVCs, list PVs and create events in all namespaces)")
 
 	klog.InitFlags(nil)
 	flag.Parse()
 
+	if err := logging.Configure(logFormat, os.Stderr); err != nil {
+		klog.Fatalf("Invalid --log-format: %v", err)
+	}
+
+	if err := regions.ApplyOverrides(regionEndpoints); err != nil {
+		klog.Fatalf("Invalid --region-endpoints: %v", err)
+	}
+
 	// Token-based auth takes priority
 	if cloudsigmaToken == "" && tokenFile != "" {
 		// Read token from file (CCM refreshes this)
@@ -57,6 +101,32 @@ func main() {
 		}
 	}
 
+	// In-cluster Kubernetes client is optional; it enables Node attachment annotations and reading
+	// the token secret provisioned by CCM
+	var kubeClient kubernetes.Interface
+	if restConfig, err := rest.InClusterConfig(); err != nil {
+		klog.Warningf("Not running in-cluster, Node attachment annotations disabled: %v", err)
+	} else if kubeClient, err = kubernetes.NewForConfig(restConfig); err != nil {
+		klog.Warningf("Failed to create Kubernetes client, Node attachment annotations disabled: %v", err)
+		kubeClient = nil
+	}
+
+	// Without a token or token file, read the token secret CCM provisions
+	watchTokenSecret := false
+	if cloudsigmaToken == "" && kubeClient != nil {
+		token, secretRegion, err := driver.TokenFromSecret(context.Background(), kubeClient, tokenSecretNamespace, tokenSecretName)
+		if err != nil {
+			klog.Warningf("Failed to read token secret: %v", err)
+		} else {
+			cloudsigmaToken = token
+			if region == "" {
+				region = secretRegion
+			}
+			watchTokenSecret = true
+			klog.Infof("Loaded access token from secret %s/%s", tokenSecretNamespace, tokenSecretName)
+		}
+	}
+
 	// Validate we have some auth method
 	if cloudsigmaToken == "" && (cloudsigmaUsername == "" || cloudsigmaPassword == "") {
 		klog.Fatal("CloudSigma credentials required: set CLOUDSIGMA_ACCESS_TOKEN or CLOUDSIGMA_USERNAME/CLOUDSIGMA_PASSWORD")
@@ -77,6 +147,19 @@ func main() {
 		CloudSigmaToken:    cloudsigmaToken,
 		TokenFile:          tokenFile,
 		ClusterName:        clusterName,
+		DefaultStorageType: defaultStorageType,
+		ChannelPolicy:      channelPolicy,
+		VolumeDeletePolicy: volumeDeletePolicy,
+		KubeClient:         kubeClient,
+		VolumeEvents:       volumeEvents,
+
+		DetachPollAttempts:      detachPollAttempts,
+		DetachPollInterval:      detachPollInterval,
+		DisableDetachEscalation: disableDetachEscalation,
+
+		TLSCertFile:     tlsCertFile,
+		TLSKeyFile:      tlsKeyFile,
+		TLSClientCAFile: tlsClientCAFile,
 	}
 
 	drv, err := driver.NewDriver(cfg)
@@ -84,7 +167,20 @@ func main() {
 		klog.Fatalf("Failed to create driver: %v", err)
 	}
 
+	// Follow the CCM refreshing the token or switching the secret to another user or region
+	if watchTokenSecret {
+		go drv.WatchTokenSecret(context.Background(), tokenSecretNamespace, tokenSecretName, driver.DefaultTokenSecretCheckInterval)
+	}
+
 	if err := drv.Run(); err != nil {
 		klog.Fatalf("Failed to run driver: %v", err)
 	}
 }
+
+// envOrDefault returns the environment variable key, or def when it is unset
+func envOrDefault(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
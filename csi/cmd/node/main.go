@@ -19,28 +19,77 @@ package main
 import (
 	"flag"
 	"os"
+	"strings"
+	"time"
 
 	"k8s.io/klog/v2"
 
 	"github.com/kube-dc/cluster-api-provider-cloudsigma/csi/driver"
+	"github.com/kube-dc/cluster-api-provider-cloudsigma/pkg/logging"
+	"github.com/kube-dc/cluster-api-provider-cloudsigma/pkg/regions"
 )
 
 func main() {
+	var logFormat string
 	var endpoint string
 	var nodeID string
 	var region string
+	var regionEndpoints string
+	var cloudsigmaUsername string
+	var cloudsigmaPassword string
+	var cloudsigmaToken string
+	var tokenFile string
+	var tlsCertFile string
+	var tlsKeyFile string
+	var tlsClientCAFile string
+	var allowedMountOptions string
+	var fsck bool
+	var fsckTimeout time.Duration
 
 	flag.StringVar(&endpoint, "endpoint", "unix:///csi/csi.sock", "CSI endpoint")
 	flag.StringVar(&nodeID, "node-id", os.Getenv("NODE_ID"), "Node ID (server UUID)")
-	flag.StringVar(&region, "region", os.Getenv("CLOUDSIGMA_REGION"), "CloudSigma region")
+	flag.StringVar(&region, "region", os.Getenv("CLOUDSIGMA_REGION"), "CloudSigma region, used for topology when the node's server can't be looked up")
+	flag.StringVar(&regionEndpoints, "region-endpoints", os.Getenv("CLOUDSIGMA_REGION_ENDPOINTS"), "Endpoints of CloudSigma regions not on <region>.cloudsigma.com, as comma-separated region=api-host[|direct-host] entries")
+	flag.StringVar(&cloudsigmaUsername, "cloudsigma-username", os.Getenv("CLOUDSIGMA_USERNAME"), "CloudSigma API username (legacy, optional)")
+	flag.StringVar(&cloudsigmaPassword, "cloudsigma-password", os.Getenv("CLOUDSIGMA_PASSWORD"), "CloudSigma API password (legacy, optional)")
+	flag.StringVar(&cloudsigmaToken, "cloudsigma-token", os.Getenv("CLOUDSIGMA_ACCESS_TOKEN"), "CloudSigma API access token (optional), lets the node look up the region its server runs in")
+	flag.StringVar(&tokenFile, "token-file", os.Getenv("CLOUDSIGMA_TOKEN_FILE"), "Path to file containing access token (refreshed by CCM)")
+	flag.StringVar(&tlsCertFile, "tls-cert-file", os.Getenv("CSI_TLS_CERT_FILE"), "Server certificate for a tcp:// endpoint (ignored for unix sockets)")
+	flag.StringVar(&tlsKeyFile, "tls-key-file", os.Getenv("CSI_TLS_KEY_FILE"), "Key of --tls-cert-file")
+	flag.StringVar(&tlsClientCAFile, "tls-client-ca-file", os.Getenv("CSI_TLS_CLIENT_CA_FILE"), "CA bundle client certificates must be signed by; enables mTLS")
+	flag.StringVar(&allowedMountOptions, "allowed-mount-options", envOrDefault("CSI_ALLOWED_MOUNT_OPTIONS", strings.Join(driver.DefaultAllowedMountOptions, ",")), "Comma-separated names of the StorageClass mount options passed to mount; others are dropped, and SELinux context, dev, suid and bind-like options are rejected unless listed")
+	flag.BoolVar(&fsck, "fsck", os.Getenv("CSI_FSCK") == "true", "Check the filesystem of formatted volumes with fsck before mounting them, unless their StorageClass sets the fsck parameter")
+	flag.DurationVar(&fsckTimeout, "fsck-timeout", driver.DefaultFsckTimeout, "How long a filesystem check may run before staging the volume fails")
+	flag.StringVar(&logFormat, "log-format", logging.FormatText, "Log output format: text or json")
 
 	klog.InitFlags(nil)
 	flag.Parse()
 
+	if err := logging.Configure(logFormat, os.Stderr); err != nil {
+		klog.Fatalf("Invalid --log-format: %v", err)
+	}
+
 	if nodeID == "" {
 		klog.Fatal("Node ID is required (--node-id or NODE_ID env)")
 	}
 
+	if err := regions.ApplyOverrides(regionEndpoints); err != nil {
+		klog.Fatalf("Invalid --region-endpoints: %v", err)
+	}
+
+	// Credentials are optional on the node; without them the topology uses --region as is
+	if cloudsigmaToken == "" && tokenFile != "" {
+		data, err := os.ReadFile(tokenFile)
+		if err != nil {
+			klog.Warningf("Failed to read token file %s: %v", tokenFile, err)
+		} else {
+			cloudsigmaToken = strings.TrimSpace(string(data))
+		}
+	}
+	if cloudsigmaToken == "" && (cloudsigmaUsername == "" || cloudsigmaPassword == "") {
+		klog.Infof("No CloudSigma credentials, using region %q for topology without looking up the server", region)
+	}
+
 	klog.Infof("Starting CloudSigma CSI Node")
 	klog.Infof("Endpoint: %s", endpoint)
 	klog.Infof("Node ID: %s", nodeID)
@@ -53,6 +102,20 @@ func main() {
 		NodeID:   nodeID,
 		Region:   region,
 		Mode:     driver.NodeMode,
+
+		CloudSigmaUsername: cloudsigmaUsername,
+		CloudSigmaPassword: cloudsigmaPassword,
+		CloudSigmaToken:    cloudsigmaToken,
+		TokenFile:          tokenFile,
+
+		AllowedMountOptions: strings.Split(allowedMountOptions, ","),
+
+		Fsck:        fsck,
+		FsckTimeout: fsckTimeout,
+
+		TLSCertFile:     tlsCertFile,
+		TLSKeyFile:      tlsKeyFile,
+		TLSClientCAFile: tlsClientCAFile,
 	}
 
 	drv, err := driver.NewDriver(cfg)
@@ -64,3 +127,11 @@ func main() {
 		klog.Fatalf("Failed to run driver: %v", err)
 	}
 }
+
+// envOrDefault returns the environment variable key, or def when it is unset
+func envOrDefault(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
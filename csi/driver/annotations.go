@@ -0,0 +1,122 @@
+/*
+Copyright 2025 Kube-DC Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/cloudsigma/cloudsigma-sdk-go/cloudsigma"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+)
+
+// AttachedAnnotationPrefix prefixes the Node annotation recording the device channel
+// a CloudSigma drive is attached at, e.g. csi.cloudsigma.com/attached-<drive-uuid>: "1:0"
+const AttachedAnnotationPrefix = "csi.cloudsigma.com/attached-"
+
+// attachedAnnotationKey returns the Node annotation key for a volume
+func attachedAnnotationKey(volumeID string) string {
+	return AttachedAnnotationPrefix + volumeID
+}
+
+// setNodeAttachmentAnnotation records (channel != "") or removes (channel == "") the
+// volume→channel annotation on the Node backed by server. It is best effort: failures
+// are logged and never fail the CSI call.
+func (d *Driver) setNodeAttachmentAnnotation(ctx context.Context, server *cloudsigma.Server, volumeID, channel string) {
+	if d.kubeClient == nil || server == nil {
+		return
+	}
+
+	node, err := d.findNodeForServer(ctx, server)
+	if err != nil {
+		klog.Warningf("Failed to look up node for server %s: %v", server.UUID, err)
+		return
+	}
+	if node == nil {
+		klog.V(4).Infof("No Kubernetes node found for server %s, skipping attachment annotation", server.UUID)
+		return
+	}
+
+	// A null value in a merge patch deletes the key
+	var value interface{}
+	if channel != "" {
+		value = channel
+	}
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]interface{}{
+				attachedAnnotationKey(volumeID): value,
+			},
+		},
+	})
+	if err != nil {
+		klog.Warningf("Failed to build annotation patch for node %s: %v", node.Name, err)
+		return
+	}
+
+	if _, err := d.kubeClient.CoreV1().Nodes().Patch(ctx, node.Name, types.MergePatchType, patch, metav1.PatchOptions{}); err != nil {
+		klog.Warningf("Failed to update attachment annotation of volume %s on node %s: %v", volumeID, node.Name, err)
+		return
+	}
+	klog.V(4).Infof("Updated attachment annotation of volume %s on node %s (channel %q)", volumeID, node.Name, channel)
+}
+
+// findNodeForServer returns the Node backed by server. Nodes are normally named after their
+// server, so the Node of that name is fetched and used when its providerID names the server or is
+// not set yet. Only if that fails are all Nodes listed to find the server's providerID; the name
+// found is remembered so the next lookup is a single get again.
+func (d *Driver) findNodeForServer(ctx context.Context, server *cloudsigma.Server) (*corev1.Node, error) {
+	providerID := fmt.Sprintf("cloudsigma://%s", server.UUID)
+
+	var names []string
+	if cached, ok := d.serverNodeNames.Load(server.UUID); ok {
+		names = append(names, cached.(string))
+	}
+	if server.Name != "" {
+		names = append(names, server.Name)
+	}
+	for _, name := range names {
+		node, err := d.kubeClient.CoreV1().Nodes().Get(ctx, name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if node.Spec.ProviderID == providerID || (node.Spec.ProviderID == "" && name == server.Name) {
+			return node, nil
+		}
+	}
+
+	nodes, err := d.kubeClient.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for i := range nodes.Items {
+		node := &nodes.Items[i]
+		if node.Spec.ProviderID == providerID {
+			d.serverNodeNames.Store(server.UUID, node.Name)
+			return node, nil
+		}
+	}
+	return nil, nil
+}
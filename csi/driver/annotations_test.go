@@ -0,0 +1,212 @@
+/*
+Copyright 2025 Kube-DC Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/cloudsigma/cloudsigma-sdk-go/cloudsigma"
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestNodeAttachmentAnnotationLifecycle(t *testing.T) {
+	const (
+		nodeID   = "11111111-2222-3333-4444-555555555555"
+		volumeID = "vol-1"
+	)
+
+	var mu sync.Mutex
+	server := cloudsigma.Server{UUID: nodeID, Name: "worker-0", Status: "running"}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/2.0/servers/"+nodeID+"/", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.Method == http.MethodPut {
+			var updated cloudsigma.Server
+			if err := json.NewDecoder(r.Body).Decode(&updated); err != nil {
+				t.Errorf("failed to decode server update: %v", err)
+			}
+			server.Drives = updated.Drives
+		}
+		writeJSON(w, server)
+	})
+	mux.HandleFunc("/api/2.0/drives/", func(w http.ResponseWriter, r *http.Request) {
+		uuid := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/api/2.0/drives/"), "/")
+		writeJSON(w, cloudsigma.Drive{UUID: uuid, Status: "unmounted"})
+	})
+
+	kubeClient := fake.NewSimpleClientset(
+		&corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: "other"},
+			Spec:       corev1.NodeSpec{ProviderID: "cloudsigma://other"},
+		},
+		&corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: "worker-0"},
+			Spec:       corev1.NodeSpec{ProviderID: "cloudsigma://" + nodeID},
+		},
+	)
+
+	d := newTestDriver(t, mux)
+	d.kubeClient = kubeClient
+
+	nodeAnnotations := func() map[string]string {
+		t.Helper()
+		node, err := kubeClient.CoreV1().Nodes().Get(context.Background(), "worker-0", metav1.GetOptions{})
+		if err != nil {
+			t.Fatalf("failed to get node: %v", err)
+		}
+		return node.Annotations
+	}
+
+	resp, err := d.ControllerPublishVolume(context.Background(), &csi.ControllerPublishVolumeRequest{VolumeId: volumeID, NodeId: nodeID})
+	if err != nil {
+		t.Fatalf("ControllerPublishVolume() error = %v", err)
+	}
+	if got, want := nodeAnnotations()[attachedAnnotationKey(volumeID)], resp.PublishContext["channel"]; got != want {
+		t.Errorf("attachment annotation = %q, want %q", got, want)
+	}
+
+	if _, err := d.ControllerUnpublishVolume(context.Background(), &csi.ControllerUnpublishVolumeRequest{VolumeId: volumeID, NodeId: nodeID}); err != nil {
+		t.Fatalf("ControllerUnpublishVolume() error = %v", err)
+	}
+	if _, ok := nodeAnnotations()[attachedAnnotationKey(volumeID)]; ok {
+		t.Errorf("attachment annotation still present after unpublish")
+	}
+}
+
+func TestControllerUnpublishVolume_ClearsStaleAnnotation(t *testing.T) {
+	const (
+		nodeID   = "11111111-2222-3333-4444-555555555555"
+		volumeID = "vol-1"
+	)
+
+	tests := []struct {
+		name string
+		// attached is whether the volume is on the server when unpublish starts
+		attached bool
+	}{
+		// The volume went away without an unpublish, e.g. detached by hand
+		{name: "not attached"},
+		// The update errors out although CloudSigma detached the drive
+		{name: "detached despite a failed update", attached: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			server := cloudsigma.Server{UUID: nodeID, Name: "worker-0", Status: "running"}
+			if tt.attached {
+				server.Drives = []cloudsigma.ServerDrive{{BootOrder: 0, DevChannel: "0:1", Device: "virtio", Drive: &cloudsigma.Drive{UUID: volumeID}}}
+			}
+
+			mux := http.NewServeMux()
+			mux.HandleFunc("/api/2.0/servers/"+nodeID+"/", func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				defer mu.Unlock()
+				if r.Method == http.MethodPut {
+					server.Drives = nil
+					http.Error(w, `[{"error_type":"backend","error_message":"timeout"}]`, http.StatusInternalServerError)
+					return
+				}
+				writeJSON(w, server)
+			})
+
+			kubeClient := fake.NewSimpleClientset(&corev1.Node{
+				ObjectMeta: metav1.ObjectMeta{Name: "worker-0", Annotations: map[string]string{attachedAnnotationKey(volumeID): "0:1"}},
+				Spec:       corev1.NodeSpec{ProviderID: "cloudsigma://" + nodeID},
+			})
+			d := newTestDriver(t, mux)
+			d.kubeClient = kubeClient
+
+			if _, err := d.ControllerUnpublishVolume(context.Background(), &csi.ControllerUnpublishVolumeRequest{VolumeId: volumeID, NodeId: nodeID}); err != nil {
+				t.Fatalf("ControllerUnpublishVolume() error = %v", err)
+			}
+			node, err := kubeClient.CoreV1().Nodes().Get(context.Background(), "worker-0", metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("failed to get node: %v", err)
+			}
+			if value, ok := node.Annotations[attachedAnnotationKey(volumeID)]; ok {
+				t.Errorf("attachment annotation = %q after unpublish, want it removed", value)
+			}
+		})
+	}
+}
+
+func TestFindNodeForServer(t *testing.T) {
+	const serverID = "11111111-2222-3333-4444-555555555555"
+	node := func(name, providerID string) *corev1.Node {
+		return &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: name}, Spec: corev1.NodeSpec{ProviderID: providerID}}
+	}
+
+	tests := []struct {
+		name      string
+		nodes     []runtime.Object
+		wantNode  string
+		wantLists int
+	}{
+		{name: "named after the server", nodes: []runtime.Object{node("worker-0", "cloudsigma://"+serverID)}, wantNode: "worker-0"},
+		{name: "providerID not set yet", nodes: []runtime.Object{node("worker-0", "")}, wantNode: "worker-0"},
+		{name: "named differently", nodes: []runtime.Object{node("host-a", "cloudsigma://"+serverID)}, wantNode: "host-a", wantLists: 1},
+		{name: "name taken by another server", nodes: []runtime.Object{node("worker-0", "cloudsigma://other")}, wantLists: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kubeClient := fake.NewSimpleClientset(tt.nodes...)
+			d := &Driver{kubeClient: kubeClient}
+			server := &cloudsigma.Server{UUID: serverID, Name: "worker-0"}
+
+			// The second lookup reuses the name the first one found
+			for i := 0; i < 2; i++ {
+				got, err := d.findNodeForServer(context.Background(), server)
+				if err != nil {
+					t.Fatalf("findNodeForServer() error = %v", err)
+				}
+				gotName := ""
+				if got != nil {
+					gotName = got.Name
+				}
+				if gotName != tt.wantNode {
+					t.Errorf("findNodeForServer() = %q, want %q", gotName, tt.wantNode)
+				}
+			}
+
+			lists := 0
+			for _, action := range kubeClient.Actions() {
+				if action.GetVerb() == "list" {
+					lists++
+				}
+			}
+			// A server without a Node is listed for on every lookup
+			wantLists := tt.wantLists
+			if tt.wantNode == "" {
+				wantLists *= 2
+			}
+			if lists != wantLists {
+				t.Errorf("listed nodes %d times, want %d", lists, wantLists)
+			}
+		})
+	}
+}
@@ -0,0 +1,168 @@
+/*
+Copyright 2025 Kube-DC Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/cloudsigma/cloudsigma-sdk-go/cloudsigma"
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"k8s.io/klog/v2"
+)
+
+// attachReadyPollAttempts is how many times a creating or cloning drive is re-checked before
+// ControllerPublishVolume gives up and lets the external-attacher retry
+const attachReadyPollAttempts = 10
+
+// attachReadyPollInterval is the delay between pre-attach drive status checks
+var attachReadyPollInterval = 1 * time.Second
+
+// attachDecision is what ControllerPublishVolume does with a drive in a given status
+type attachDecision int
+
+const (
+	// attachProceed means the drive can be attached now
+	attachProceed attachDecision = iota
+	// attachWait means the drive is still being provisioned and should be polled again
+	attachWait
+	// attachAbort means the drive is in a state that must not be attached
+	attachAbort
+)
+
+// attachDecisionFor maps a CloudSigma drive status to an attach decision. "unmounted" is the
+// ready state WaitForDriveReady waits for; "mounted" drives are attached via the existing
+// detach-from-old-node path. Attaching a drive that is still being written, e.g. by a clone,
+// can corrupt it, so every other status is refused.
+func attachDecisionFor(driveStatus string) attachDecision {
+	switch driveStatus {
+	case "unmounted", "mounted":
+		return attachProceed
+	case "creating", "cloning":
+		return attachWait
+	default:
+		return attachAbort
+	}
+}
+
+// waitForAttachReady returns the drive once it can be attached, polling briefly while it is
+// still being created or cloned. It returns codes.Aborted, which the external-attacher retries,
+// if the drive doesn't become ready in time or is in any other transitional or error state.
+func (d *Driver) waitForAttachReady(ctx context.Context, drive *cloudsigma.Drive) (*cloudsigma.Drive, error) {
+	for i := 0; ; i++ {
+		switch attachDecisionFor(drive.Status) {
+		case attachProceed:
+			return drive, nil
+		case attachAbort:
+			return nil, status.Errorf(codes.Aborted, "volume %s is %q, not ready to attach", drive.UUID, drive.Status)
+		}
+
+		if i >= attachReadyPollAttempts {
+			return nil, status.Errorf(codes.Aborted, "volume %s is still %q after %d checks, not ready to attach",
+				drive.UUID, drive.Status, attachReadyPollAttempts)
+		}
+		klog.V(4).Infof("Volume %s is %s, waiting before attach... (retry %d/%d)", drive.UUID, drive.Status, i+1, attachReadyPollAttempts)
+
+		select {
+		case <-ctx.Done():
+			return nil, status.Errorf(codes.Aborted, "volume %s is %q: %v", drive.UUID, drive.Status, ctx.Err())
+		case <-time.After(attachReadyPollInterval):
+		}
+
+		volumeID := drive.UUID
+		next, _, err := d.cloud().Drives.Get(ctx, volumeID)
+		if err != nil {
+			return nil, status.Errorf(codes.Aborted, "failed to re-check volume %s status: %v", volumeID, err)
+		}
+		drive = next
+	}
+}
+
+// attachUpdateFailure is why the server update attaching a drive was refused
+type attachUpdateFailure int
+
+const (
+	// attachUpdateFailed is any failure not classified below; it is worth retrying
+	attachUpdateFailed attachUpdateFailure = iota
+	// attachUpdateAlreadyAttached means CloudSigma refused the drive because it is already mounted
+	attachUpdateAlreadyAttached
+	// attachUpdateRejected means CloudSigma refused the update body itself, e.g. a field failed validation
+	attachUpdateRejected
+)
+
+// classifyAttachUpdateError classifies a failed server update by the errors in the CloudSigma
+// response body, and returns them as a readable detail for the CSI error
+func classifyAttachUpdateError(err error) (attachUpdateFailure, string) {
+	var sdkErr *cloudsigma.ErrorResponse
+	if !errors.As(err, &sdkErr) || len(sdkErr.Errors) == 0 {
+		return attachUpdateFailed, err.Error()
+	}
+
+	failure := attachUpdateFailed
+	details := make([]string, 0, len(sdkErr.Errors))
+	for _, e := range sdkErr.Errors {
+		detail := e.Message
+		if e.Point != "" {
+			detail = fmt.Sprintf("%s: %s", e.Point, e.Message)
+		}
+		details = append(details, detail)
+
+		message := strings.ToLower(e.Message)
+		switch {
+		case strings.Contains(message, "already attached"), strings.Contains(message, "already mounted"):
+			failure = attachUpdateAlreadyAttached
+		case failure == attachUpdateFailed &&
+			(e.Type == "validation" || (sdkErr.Response != nil && sdkErr.Response.StatusCode == http.StatusBadRequest)):
+			failure = attachUpdateRejected
+		}
+	}
+	return failure, strings.Join(details, "; ")
+}
+
+// attachUpdateError turns a failed server update attaching volumeID to nodeID into the
+// ControllerPublishVolume result. When CloudSigma reports the drive as already attached, the
+// server is re-read: if the drive is on it, an earlier attempt went through after all and the
+// publish succeeds.
+func (d *Driver) attachUpdateError(ctx context.Context, volumeID, nodeID string, err error) (*csi.ControllerPublishVolumeResponse, error) {
+	failure, detail := classifyAttachUpdateError(err)
+	switch failure {
+	case attachUpdateAlreadyAttached:
+		server, _, getErr := d.getServer(ctx, nodeID)
+		if getErr != nil {
+			return nil, status.Errorf(codes.Internal, "volume %s reported as already attached, but failed to re-read node %s: %v",
+				volumeID, nodeID, getErr)
+		}
+		for _, sd := range server.Drives {
+			if sd.Drive != nil && sd.Drive.UUID == volumeID {
+				klog.InfoS("Volume already attached", "volumeId", volumeID, "nodeId", nodeID, "channel", sd.DevChannel)
+				return &csi.ControllerPublishVolumeResponse{PublishContext: publishContext(volumeID, sd.DevChannel)}, nil
+			}
+		}
+		return nil, status.Errorf(codes.FailedPrecondition, "volume %s is attached to another node: %s", volumeID, detail)
+	case attachUpdateRejected:
+		return nil, status.Errorf(codes.Internal, "CloudSigma rejected the update of node %s attaching volume %s: %s",
+			nodeID, volumeID, detail)
+	default:
+		return nil, status.Errorf(codes.Internal, "failed to attach volume: %v", err)
+	}
+}
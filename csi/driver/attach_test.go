@@ -0,0 +1,195 @@
+/*
+Copyright 2025 Kube-DC Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cloudsigma/cloudsigma-sdk-go/cloudsigma"
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestControllerPublishVolume_DriveStatus(t *testing.T) {
+	origInterval := attachReadyPollInterval
+	attachReadyPollInterval = time.Millisecond
+	defer func() { attachReadyPollInterval = origInterval }()
+
+	const (
+		nodeID   = "node-1"
+		volumeID = "vol-1"
+	)
+
+	stillCreating := make([]string, attachReadyPollAttempts+5)
+	for i := range stillCreating {
+		stillCreating[i] = "creating"
+	}
+
+	tests := []struct {
+		name       string
+		statuses   []string // drive status per Drives.Get; the last one repeats
+		wantCode   codes.Code
+		wantAttach bool
+	}{
+		{name: "unmounted", statuses: []string{"unmounted"}, wantCode: codes.OK, wantAttach: true},
+		{name: "creating then unmounted", statuses: []string{"creating", "creating", "unmounted"}, wantCode: codes.OK, wantAttach: true},
+		{name: "cloning then unmounted", statuses: []string{"cloning", "unmounted"}, wantCode: codes.OK, wantAttach: true},
+		{name: "still creating", statuses: stillCreating, wantCode: codes.Aborted},
+		{name: "cloning then unavailable", statuses: []string{"cloning", "unavailable"}, wantCode: codes.Aborted},
+		{name: "resizing", statuses: []string{"resizing"}, wantCode: codes.Aborted},
+		{name: "unavailable", statuses: []string{"unavailable"}, wantCode: codes.Aborted},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			gets := 0
+			attached := false
+
+			mux := http.NewServeMux()
+			mux.HandleFunc("/api/2.0/servers/"+nodeID+"/", func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				defer mu.Unlock()
+				if r.Method == http.MethodPut {
+					attached = true
+				}
+				writeJSON(w, cloudsigma.Server{UUID: nodeID, Status: "running"})
+			})
+			mux.HandleFunc("/api/2.0/drives/"+volumeID+"/", func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				defer mu.Unlock()
+				driveStatus := tt.statuses[len(tt.statuses)-1]
+				if gets < len(tt.statuses) {
+					driveStatus = tt.statuses[gets]
+				}
+				gets++
+				writeJSON(w, cloudsigma.Drive{UUID: volumeID, Status: driveStatus})
+			})
+
+			d := newTestDriver(t, mux)
+			_, err := d.ControllerPublishVolume(context.Background(), &csi.ControllerPublishVolumeRequest{
+				VolumeId: volumeID,
+				NodeId:   nodeID,
+			})
+
+			if got := status.Code(err); got != tt.wantCode {
+				t.Fatalf("ControllerPublishVolume() code = %v, want %v (err: %v)", got, tt.wantCode, err)
+			}
+			if attached != tt.wantAttach {
+				t.Errorf("server updated = %v, want %v", attached, tt.wantAttach)
+			}
+		})
+	}
+}
+
+func TestControllerPublishVolume_UpdateErrors(t *testing.T) {
+	const (
+		nodeID   = "node-1"
+		volumeID = "vol-1"
+	)
+
+	tests := []struct {
+		name        string
+		statusCode  int
+		errors      []cloudsigma.Error
+		onServer    bool // whether the drive shows up on the server after the failed update
+		wantCode    codes.Code
+		wantMessage string
+	}{
+		{
+			name:       "already attached to this node",
+			statusCode: http.StatusBadRequest,
+			errors:     []cloudsigma.Error{{Type: "permission", Message: "Drive vol-1 is already mounted on server node-1"}},
+			onServer:   true,
+			wantCode:   codes.OK,
+		},
+		{
+			name:        "already attached to another node",
+			statusCode:  http.StatusBadRequest,
+			errors:      []cloudsigma.Error{{Type: "permission", Message: "Drive vol-1 is already attached to server node-2"}},
+			wantCode:    codes.FailedPrecondition,
+			wantMessage: "attached to another node",
+		},
+		{
+			name:        "validation",
+			statusCode:  http.StatusBadRequest,
+			errors:      []cloudsigma.Error{{Type: "validation", Point: "nics.0.ip_v4_conf.conf", Message: "Invalid value"}},
+			wantCode:    codes.Internal,
+			wantMessage: "CloudSigma rejected the update of node node-1 attaching volume vol-1: nics.0.ip_v4_conf.conf: Invalid value",
+		},
+		{
+			name:        "backend",
+			statusCode:  http.StatusInternalServerError,
+			errors:      []cloudsigma.Error{{Type: "backend", Message: "Internal error"}},
+			wantCode:    codes.Internal,
+			wantMessage: "failed to attach volume",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			server := cloudsigma.Server{UUID: nodeID, Status: "running"}
+
+			mux := http.NewServeMux()
+			mux.HandleFunc("/api/2.0/servers/"+nodeID+"/", func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				defer mu.Unlock()
+				if r.Method == http.MethodPut {
+					if tt.onServer {
+						server.Drives = []cloudsigma.ServerDrive{{DevChannel: "0:5", Device: "virtio", Drive: &cloudsigma.Drive{UUID: volumeID}}}
+					}
+					w.Header().Set("Content-Type", "application/json")
+					w.WriteHeader(tt.statusCode)
+					_ = json.NewEncoder(w).Encode(tt.errors)
+					return
+				}
+				writeJSON(w, server)
+			})
+			mux.HandleFunc("/api/2.0/drives/"+volumeID+"/", func(w http.ResponseWriter, r *http.Request) {
+				writeJSON(w, cloudsigma.Drive{UUID: volumeID, Status: "unmounted"})
+			})
+
+			d := newTestDriver(t, mux)
+			d.serverCache = newServerCache(time.Minute)
+			resp, err := d.ControllerPublishVolume(context.Background(), &csi.ControllerPublishVolumeRequest{
+				VolumeId: volumeID,
+				NodeId:   nodeID,
+			})
+
+			if got := status.Code(err); got != tt.wantCode {
+				t.Fatalf("ControllerPublishVolume() code = %v, want %v (err: %v)", got, tt.wantCode, err)
+			}
+			if err != nil {
+				if msg := status.Convert(err).Message(); !strings.Contains(msg, tt.wantMessage) {
+					t.Errorf("ControllerPublishVolume() message = %q, want it to contain %q", msg, tt.wantMessage)
+				}
+				return
+			}
+			if got := resp.PublishContext["channel"]; got != "0:5" {
+				t.Errorf("PublishContext[channel] = %q, want the channel the drive is attached at, 0:5", got)
+			}
+		})
+	}
+}
@@ -28,6 +28,8 @@ import (
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 	"k8s.io/klog/v2"
+
+	"github.com/kube-dc/cluster-api-provider-cloudsigma/pkg/devicechannel"
 )
 
 const (
@@ -41,15 +43,61 @@ const (
 	// Storage types
 	StorageTypeDSSD     = "dssd"
 	StorageTypeMagnetic = "zadara"
+
+	// Volume delete policies
+	VolumeDeletePolicyDelete = "delete"
+	VolumeDeletePolicyRetain = "retain"
+
+	// deleteVolumeMountedRetries is how many times DeleteVolume re-checks a drive that
+	// still reports "mounted" before giving up with FailedPrecondition
+	deleteVolumeMountedRetries = 10
+
+	// listPageSize is the number of drives requested per page when enumerating drives
+	listPageSize = 100
 )
 
-// CreateVolume creates a new CloudSigma drive
+// storageTypes are the storageType parameter values CloudSigma accepts
+var storageTypes = []string{StorageTypeDSSD, StorageTypeMagnetic}
+
+// validateStorageType returns an error listing the valid options if storageType is unknown
+func validateStorageType(storageType string) error {
+	for _, st := range storageTypes {
+		if storageType == st {
+			return nil
+		}
+	}
+	return fmt.Errorf("unsupported storageType %q, must be one of: %s", storageType, strings.Join(storageTypes, ", "))
+}
+
+// validateVolumeDeletePolicy returns an error listing the valid options if policy is unknown
+func validateVolumeDeletePolicy(policy string) error {
+	if policy != VolumeDeletePolicyDelete && policy != VolumeDeletePolicyRetain {
+		return fmt.Errorf("unsupported volume delete policy %q, must be one of: %s, %s",
+			policy, VolumeDeletePolicyDelete, VolumeDeletePolicyRetain)
+	}
+	return nil
+}
+
+// deleteVolumeRetryInterval is the delay between DeleteVolume mount-state polls
+var deleteVolumeRetryInterval = 1 * time.Second
+
+// CreateVolume creates a new CloudSigma drive, recording a failure on the PVC
 func (d *Driver) CreateVolume(ctx context.Context, req *csi.CreateVolumeRequest) (*csi.CreateVolumeResponse, error) {
+	resp, err := d.createVolume(ctx, req)
+	if err != nil {
+		d.recordPVCEvent(ctx, req.GetParameters(), EventReasonVolumeCreateFailed,
+			fmt.Sprintf("Failed to create volume %s: %s", req.GetName(), status.Convert(err).Message()))
+	}
+	return resp, err
+}
+
+// createVolume creates a new CloudSigma drive
+func (d *Driver) createVolume(ctx context.Context, req *csi.CreateVolumeRequest) (*csi.CreateVolumeResponse, error) {
 	if req.Name == "" {
 		return nil, status.Error(codes.InvalidArgument, "volume name is required")
 	}
 
-	if d.cloudClient == nil {
+	if d.cloud() == nil {
 		return nil, status.Error(codes.Internal, "CloudSigma client not initialized")
 	}
 
@@ -84,22 +132,54 @@ func (d *Driver) CreateVolume(ctx context.Context, req *csi.CreateVolumeRequest)
 	sizeInt := int(size)
 
 	// Get storage type from parameters
-	storageType := StorageTypeDSSD
+	storageType := d.defaultStorageType
 	if req.Parameters != nil {
 		if st, ok := req.Parameters["storageType"]; ok {
 			storageType = st
 		}
 	}
+	if err := validateStorageType(storageType); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	qos, err := parseVolumeQoS(req.Parameters)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err := validateVolumeQoS(qos, storageType); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if _, _, err := parseFsckParameter(req.Parameters); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	media, err := parseMediaParameter(req.Parameters)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
 
-	klog.Infof("Creating volume: name=%s, size=%d, storageType=%s", req.Name, size, storageType)
+	if err := d.checkAccessibility(req.AccessibilityRequirements); err != nil {
+		return nil, err
+	}
+
+	name := driveName(req.Name, req.Parameters)
+	klog.Infof("Creating volume: name=%s, driveName=%s, size=%d, storageType=%s, media=%s", req.Name, name, size, storageType, media)
 
 	// Check if volume already exists (idempotency)
-	existingDrive, err := d.findDriveByName(ctx, req.Name)
+	existingDrive, err := d.findVolumeDrive(ctx, req.Name)
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "failed to check existing volume: %v", err)
 	}
 	if existingDrive != nil {
+		// The CSI spec requires a repeated CreateVolume to fail when the existing volume does not
+		// satisfy the requested capacity range
+		if int64(existingDrive.Size) < size || (req.CapacityRange != nil && req.CapacityRange.LimitBytes > 0 && int64(existingDrive.Size) > req.CapacityRange.LimitBytes) {
+			return nil, status.Errorf(codes.AlreadyExists, "volume %s already exists with size %d, incompatible with the requested capacity", req.Name, existingDrive.Size)
+		}
+		if existingDrive.Media != "" && existingDrive.Media != media {
+			return nil, status.Errorf(codes.AlreadyExists, "volume %s already exists with media %s, not the requested %s", req.Name, existingDrive.Media, media)
+		}
 		klog.Infof("Volume already exists: %s (%s)", req.Name, existingDrive.UUID)
+		// A previous attempt may have created the drive but failed before tagging it
+		d.tagDrive(ctx, existingDrive.UUID, req.Name)
 		return &csi.CreateVolumeResponse{
 			Volume: &csi.Volume{
 				VolumeId:      existingDrive.UUID,
@@ -108,7 +188,7 @@ func (d *Driver) CreateVolume(ctx context.Context, req *csi.CreateVolumeRequest)
 				AccessibleTopology: []*csi.Topology{
 					{
 						Segments: map[string]string{
-							TopologyKey: d.region,
+							TopologyKey: d.cloudRegion(),
 						},
 					},
 				},
@@ -120,15 +200,16 @@ func (d *Driver) CreateVolume(ctx context.Context, req *csi.CreateVolumeRequest)
 	createReq := &cloudsigma.DriveCreateRequest{
 		Drives: []cloudsigma.Drive{
 			{
-				Name:        req.Name,
+				Name:        name,
 				Size:        sizeInt,
 				StorageType: storageType,
-				Media:       "disk",
+				Media:       media,
+				Meta:        map[string]interface{}{volumeNameMetaKey: req.Name},
 			},
 		},
 	}
 
-	drives, _, err := d.cloudClient.Drives.Create(ctx, createReq)
+	drives, _, err := d.cloud().Drives.Create(ctx, createReq)
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "failed to create volume: %v", err)
 	}
@@ -138,7 +219,7 @@ func (d *Driver) CreateVolume(ctx context.Context, req *csi.CreateVolumeRequest)
 	}
 
 	drive := drives[0]
-	klog.Infof("Volume created: %s (%s)", drive.Name, drive.UUID)
+	klog.InfoS("Volume created", "name", drive.Name, "volumeId", drive.UUID)
 
 	// Tag the drive in CloudSigma for tracking
 	d.tagDrive(ctx, drive.UUID, req.Name)
@@ -151,7 +232,7 @@ func (d *Driver) CreateVolume(ctx context.Context, req *csi.CreateVolumeRequest)
 			AccessibleTopology: []*csi.Topology{
 				{
 					Segments: map[string]string{
-						TopologyKey: d.region,
+						TopologyKey: d.cloudRegion(),
 					},
 				},
 			},
@@ -159,47 +240,108 @@ func (d *Driver) CreateVolume(ctx context.Context, req *csi.CreateVolumeRequest)
 	}, nil
 }
 
-// DeleteVolume deletes a CloudSigma drive
+// DeleteVolume deletes a CloudSigma drive, recording a failure on the PV
 func (d *Driver) DeleteVolume(ctx context.Context, req *csi.DeleteVolumeRequest) (*csi.DeleteVolumeResponse, error) {
+	resp, err := d.deleteVolume(ctx, req)
+	if err != nil {
+		d.recordPVEvent(ctx, req.GetVolumeId(), EventReasonVolumeDeleteFailed,
+			fmt.Sprintf("Failed to delete volume %s: %s", req.GetVolumeId(), status.Convert(err).Message()))
+	}
+	return resp, err
+}
+
+// deleteVolume deletes a CloudSigma drive
+func (d *Driver) deleteVolume(ctx context.Context, req *csi.DeleteVolumeRequest) (*csi.DeleteVolumeResponse, error) {
 	if req.VolumeId == "" {
 		return nil, status.Error(codes.InvalidArgument, "volume ID is required")
 	}
 
-	if d.cloudClient == nil {
+	if d.cloud() == nil {
 		return nil, status.Error(codes.Internal, "CloudSigma client not initialized")
 	}
 
-	klog.Infof("Deleting volume: %s", req.VolumeId)
+	klog.InfoS("Deleting volume", "volumeId", req.VolumeId)
 
 	// Check if drive exists
-	drive, _, err := d.cloudClient.Drives.Get(ctx, req.VolumeId)
+	drive, _, err := d.cloud().Drives.Get(ctx, req.VolumeId)
 	if err != nil {
 		// If not found, consider it already deleted
-		if strings.Contains(err.Error(), "404") || strings.Contains(err.Error(), "not found") {
+		if isNotFound(err) {
 			klog.Infof("Volume already deleted: %s", req.VolumeId)
 			return &csi.DeleteVolumeResponse{}, nil
 		}
 		return nil, status.Errorf(codes.Internal, "failed to get volume: %v", err)
 	}
 
-	// Check if drive is mounted
+	// With the retain policy the drive is only released from the cluster, whether or not it
+	// is still mounted
+	if d.volumeDeletePolicy == VolumeDeletePolicyRetain {
+		d.untagDrive(ctx, req.VolumeId)
+		klog.InfoS("Volume retained, drive not deleted", "volumeId", req.VolumeId, "driveName", drive.Name)
+		return &csi.DeleteVolumeResponse{}, nil
+	}
+
+	// Check if drive is mounted. CloudSigma detach is asynchronous, so a PV deleted right
+	// after ControllerUnpublishVolume commonly still reports "mounted" for a few seconds.
 	if drive.Status == "mounted" {
-		return nil, status.Errorf(codes.FailedPrecondition, "volume %s is still mounted", req.VolumeId)
+		unmounted, gone, err := d.waitForDriveUnmounted(ctx, req.VolumeId)
+		if err != nil {
+			return nil, err
+		}
+		if gone {
+			klog.Infof("Volume already deleted: %s", req.VolumeId)
+			return &csi.DeleteVolumeResponse{}, nil
+		}
+		if !unmounted {
+			return nil, status.Errorf(codes.FailedPrecondition, "volume %s is still mounted", req.VolumeId)
+		}
 	}
 
 	// Untag the drive before deletion
 	d.untagDrive(ctx, req.VolumeId)
 
 	// Delete the drive
-	_, err = d.cloudClient.Drives.Delete(ctx, req.VolumeId)
+	_, err = d.cloud().Drives.Delete(ctx, req.VolumeId)
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "failed to delete volume: %v", err)
 	}
 
-	klog.Infof("Volume deleted: %s", req.VolumeId)
+	klog.InfoS("Volume deleted", "volumeId", req.VolumeId)
 	return &csi.DeleteVolumeResponse{}, nil
 }
 
+// waitForDriveUnmounted polls a drive until it is no longer mounted.
+// Returns gone=true if the drive disappeared while waiting.
+func (d *Driver) waitForDriveUnmounted(ctx context.Context, volumeID string) (unmounted bool, gone bool, err error) {
+	for i := 0; i < deleteVolumeMountedRetries; i++ {
+		klog.V(4).Infof("Volume %s still mounted, waiting for detach to complete (retry %d/%d)",
+			volumeID, i+1, deleteVolumeMountedRetries)
+
+		select {
+		case <-ctx.Done():
+			return false, false, status.Errorf(codes.DeadlineExceeded, "waiting for volume %s to unmount: %v", volumeID, ctx.Err())
+		case <-time.After(deleteVolumeRetryInterval):
+		}
+
+		drive, _, err := d.cloud().Drives.Get(ctx, volumeID)
+		if err != nil {
+			if isNotFound(err) {
+				return false, true, nil
+			}
+			klog.Warningf("Failed to check mount status of volume %s (retry %d/%d): %v",
+				volumeID, i+1, deleteVolumeMountedRetries, err)
+			continue
+		}
+		if drive.Status != "mounted" {
+			klog.Infof("Volume %s is now %s, proceeding with deletion", volumeID, drive.Status)
+			return true, false, nil
+		}
+	}
+
+	klog.Warningf("Volume %s still mounted after %d retries", volumeID, deleteVolumeMountedRetries)
+	return false, false, nil
+}
+
 // getServerLock returns a mutex for the given server ID, creating one if it doesn't exist
 func (d *Driver) getServerLock(serverID string) *sync.Mutex {
 	d.serverAttachMu.Lock()
@@ -214,16 +356,25 @@ func (d *Driver) getServerLock(serverID string) *sync.Mutex {
 	return lock
 }
 
-// ControllerPublishVolume attaches a volume to a node
+// ControllerPublishVolume attaches a volume to a node, recording a failure on the PVC
 func (d *Driver) ControllerPublishVolume(ctx context.Context, req *csi.ControllerPublishVolumeRequest) (*csi.ControllerPublishVolumeResponse, error) {
+	resp, err := d.controllerPublishVolume(ctx, req)
+	if err != nil {
+		d.recordPVCEvent(ctx, req.GetVolumeContext(), EventReasonVolumeAttachFailed,
+			fmt.Sprintf("Failed to attach volume %s to node %s: %s", req.GetVolumeId(), req.GetNodeId(), status.Convert(err).Message()))
+	}
+	return resp, err
+}
+
+// controllerPublishVolume attaches a volume to a node
+func (d *Driver) controllerPublishVolume(ctx context.Context, req *csi.ControllerPublishVolumeRequest) (*csi.ControllerPublishVolumeResponse, error) {
 	if req.VolumeId == "" {
 		return nil, status.Error(codes.InvalidArgument, "volume ID is required")
 	}
 	if req.NodeId == "" {
 		return nil, status.Error(codes.InvalidArgument, "node ID is required")
 	}
-
-	if d.cloudClient == nil {
+	if d.cloud() == nil {
 		return nil, status.Error(codes.Internal, "CloudSigma client not initialized")
 	}
 
@@ -232,10 +383,10 @@ func (d *Driver) ControllerPublishVolume(ctx context.Context, req *csi.Controlle
 	serverLock.Lock()
 	defer serverLock.Unlock()
 
-	klog.Infof("Attaching volume %s to node %s", req.VolumeId, req.NodeId)
+	klog.InfoS("Attaching volume", "volumeId", req.VolumeId, "nodeId", req.NodeId)
 
 	// Get the server
-	server, _, err := d.cloudClient.Servers.Get(ctx, req.NodeId)
+	server, serials, err := d.getServer(ctx, req.NodeId)
 	if err != nil {
 		return nil, status.Errorf(codes.NotFound, "node not found: %v", err)
 	}
@@ -243,22 +394,25 @@ func (d *Driver) ControllerPublishVolume(ctx context.Context, req *csi.Controlle
 	// Check if already attached
 	for _, sd := range server.Drives {
 		if sd.Drive != nil && sd.Drive.UUID == req.VolumeId {
-			klog.Infof("Volume %s already attached to node %s at channel %s", req.VolumeId, req.NodeId, sd.DevChannel)
+			klog.InfoS("Volume already attached", "volumeId", req.VolumeId, "nodeId", req.NodeId, "channel", sd.DevChannel)
 			return &csi.ControllerPublishVolumeResponse{
-				PublishContext: map[string]string{
-					"channel":  sd.DevChannel,
-					"volumeId": req.VolumeId,
-				},
+				PublishContext: publishContext(req.VolumeId, sd.DevChannel),
 			}, nil
 		}
 	}
 
 	// Get the drive
-	drive, _, err := d.cloudClient.Drives.Get(ctx, req.VolumeId)
+	drive, _, err := d.cloud().Drives.Get(ctx, req.VolumeId)
 	if err != nil {
 		return nil, status.Errorf(codes.NotFound, "volume not found: %v", err)
 	}
 
+	// Don't attach a drive that is still being provisioned
+	drive, err = d.waitForAttachReady(ctx, drive)
+	if err != nil {
+		return nil, err
+	}
+
 	// Check if drive is already mounted to another server
 	// If so, we need to detach it first to allow pod migration across nodes
 	if drive.Status == "mounted" && len(drive.MountedOn) > 0 {
@@ -270,9 +424,9 @@ func (d *Driver) ControllerPublishVolume(ctx context.Context, req *csi.Controlle
 				// Try to detach from the old node
 				// This handles the case where a pod is rescheduled to a different node
 				// and the old volumeattachment hasn't been cleaned up yet
-				oldServer, _, getErr := d.cloudClient.Servers.Get(ctx, mount.UUID)
+				oldServer, oldSerials, getErr := d.fetchServer(ctx, mount.UUID)
 				if getErr != nil {
-					if strings.Contains(getErr.Error(), "404") {
+					if isNotFound(getErr) {
 						klog.Infof("Old node %s no longer exists, proceeding with attachment", mount.UUID)
 						// Old server is gone, we can proceed
 						break
@@ -289,8 +443,7 @@ func (d *Driver) ControllerPublishVolume(ctx context.Context, req *csi.Controlle
 				}
 
 				oldServer.Drives = newDrives
-				updateReq := &cloudsigma.ServerUpdateRequest{Server: oldServer}
-				_, _, updateErr := d.cloudClient.Servers.Update(ctx, mount.UUID, updateReq)
+				updateErr := d.updateServer(ctx, mount.UUID, oldServer, oldSerials)
 				if updateErr != nil {
 					klog.Warningf("Failed to detach volume %s from old node %s: %v (will proceed anyway)",
 						req.VolumeId, mount.UUID, updateErr)
@@ -303,7 +456,7 @@ func (d *Driver) ControllerPublishVolume(ctx context.Context, req *csi.Controlle
 	}
 
 	// Find the next available device channel
-	devChannel := findNextDeviceChannel(server.Drives)
+	devChannel := findNextDeviceChannel(d.channelPolicy, server.Drives)
 
 	// Add drive to server (CloudSigma supports hotplug for running VMs)
 	server.Drives = append(server.Drives, cloudsigma.ServerDrive{
@@ -315,25 +468,40 @@ func (d *Driver) ControllerPublishVolume(ctx context.Context, req *csi.Controlle
 		},
 	})
 
-	klog.Infof("Hotplugging volume %s to node %s at channel %s (server status: %s)", req.VolumeId, req.NodeId, devChannel, server.Status)
+	// Only the new drive gets a serial; the others keep the one they have
+	if serials == nil {
+		serials = make(map[string]string)
+	}
+	serials[req.VolumeId] = driveSerial(req.VolumeId)
+
+	klog.InfoS("Hotplugging volume", "volumeId", req.VolumeId, "nodeId", req.NodeId, "channel", devChannel, "serverStatus", server.Status)
 
 	// Update server (hotplug - no stop/start required)
-	updateReq := &cloudsigma.ServerUpdateRequest{Server: server}
-	_, _, err = d.cloudClient.Servers.Update(ctx, req.NodeId, updateReq)
+	err = d.updateServer(ctx, req.NodeId, server, serials)
 	if err != nil {
-		return nil, status.Errorf(codes.Internal, "failed to attach volume: %v", err)
+		return d.attachUpdateError(ctx, req.VolumeId, req.NodeId, err)
 	}
 
-	klog.Infof("Volume %s attached to node %s at channel %s", req.VolumeId, req.NodeId, devChannel)
+	klog.InfoS("Volume attached", "volumeId", req.VolumeId, "nodeId", req.NodeId, "channel", devChannel)
+	d.setNodeAttachmentAnnotation(ctx, server, req.VolumeId, devChannel)
 
 	return &csi.ControllerPublishVolumeResponse{
-		PublishContext: map[string]string{
-			"channel":  devChannel,   // Used by node to find device via /dev/disk/by-path/
-			"volumeId": req.VolumeId, // For logging and verification
-		},
+		PublishContext: publishContext(req.VolumeId, devChannel),
 	}, nil
 }
 
+// publishContext builds the PublishContext for a volume attached at channel. It depends only on
+// the volume and its attachment, so repeated publishes (e.g. after a controller restart) hand the
+// node identical context.
+func publishContext(volumeID, channel string) map[string]string {
+	return map[string]string{
+		"channel":    channel,                  // Used by node to find device via /dev/disk/by-path/
+		"devicePath": deviceByIDPath(volumeID), // Stable udev link derived from the drive serial
+		"serial":     driveSerial(volumeID),    // Serial set on attach; node resolves /dev/disk/by-id/virtio-<serial>
+		"volumeId":   volumeID,                 // For logging and verification
+	}
+}
+
 // ControllerUnpublishVolume detaches a volume from a node
 func (d *Driver) ControllerUnpublishVolume(ctx context.Context, req *csi.ControllerUnpublishVolumeRequest) (*csi.ControllerUnpublishVolumeResponse, error) {
 	if req.VolumeId == "" {
@@ -343,17 +511,22 @@ func (d *Driver) ControllerUnpublishVolume(ctx context.Context, req *csi.Control
 		return nil, status.Error(codes.InvalidArgument, "node ID is required")
 	}
 
-	if d.cloudClient == nil {
+	if d.cloud() == nil {
 		return nil, status.Error(codes.Internal, "CloudSigma client not initialized")
 	}
 
-	klog.Infof("Detaching volume %s from node %s", req.VolumeId, req.NodeId)
+	klog.InfoS("Detaching volume", "volumeId", req.VolumeId, "nodeId", req.NodeId)
+
+	// Serialize the read-modify-write of the server's drive list with publishes to the same node
+	serverLock := d.getServerLock(req.NodeId)
+	serverLock.Lock()
 
 	// Get the server
-	server, _, err := d.cloudClient.Servers.Get(ctx, req.NodeId)
+	server, serials, err := d.getServer(ctx, req.NodeId)
 	if err != nil {
+		serverLock.Unlock()
 		// If server not found, consider volume already detached
-		if strings.Contains(err.Error(), "404") {
+		if isNotFound(err) {
 			klog.Infof("Node %s not found, volume %s considered detached", req.NodeId, req.VolumeId)
 			return &csi.ControllerUnpublishVolumeResponse{}, nil
 		}
@@ -372,25 +545,27 @@ func (d *Driver) ControllerUnpublishVolume(ctx context.Context, req *csi.Control
 	}
 
 	if !found {
+		serverLock.Unlock()
 		klog.Infof("Volume %s not attached to node %s", req.VolumeId, req.NodeId)
+		d.setNodeAttachmentAnnotation(ctx, server, req.VolumeId, "")
 		return &csi.ControllerUnpublishVolumeResponse{}, nil
 	}
 
-	klog.Infof("Hot-unplugging volume %s from node %s (server status: %s)", req.VolumeId, req.NodeId, server.Status)
+	klog.InfoS("Hot-unplugging volume", "volumeId", req.VolumeId, "nodeId", req.NodeId, "serverStatus", server.Status)
 
 	// Update server with removed drive (hotplug - no stop/start required)
 	server.Drives = newDrives
-	updateReq := &cloudsigma.ServerUpdateRequest{Server: server}
-	_, _, err = d.cloudClient.Servers.Update(ctx, req.NodeId, updateReq)
+	err = d.updateServer(ctx, req.NodeId, server, serials)
+	serverLock.Unlock()
 	if err != nil {
 		// Log the error but don't fail - if the server API call fails,
 		// the volume might already be detached or the server might be deleted
 		klog.Warningf("Failed to detach volume %s from node %s via API (continuing anyway): %v", req.VolumeId, req.NodeId, err)
 
 		// Verify if the volume is actually still attached by re-fetching the server
-		verifyServer, _, verifyErr := d.cloudClient.Servers.Get(ctx, req.NodeId)
+		verifyServer, _, verifyErr := d.cloud().Servers.Get(ctx, req.NodeId)
 		if verifyErr != nil {
-			if strings.Contains(verifyErr.Error(), "404") {
+			if isNotFound(verifyErr) {
 				klog.Infof("Node %s no longer exists, volume %s considered detached", req.NodeId, req.VolumeId)
 				return &csi.ControllerUnpublishVolumeResponse{}, nil
 			}
@@ -409,6 +584,7 @@ func (d *Driver) ControllerUnpublishVolume(ctx context.Context, req *csi.Control
 
 		if !stillAttached {
 			klog.Infof("Volume %s not attached to node %s after verification, considering detachment successful", req.VolumeId, req.NodeId)
+			d.setNodeAttachmentAnnotation(ctx, verifyServer, req.VolumeId, "")
 			return &csi.ControllerUnpublishVolumeResponse{}, nil
 		}
 
@@ -416,37 +592,23 @@ func (d *Driver) ControllerUnpublishVolume(ctx context.Context, req *csi.Control
 		return nil, status.Errorf(codes.Internal, "failed to detach volume: %v", err)
 	}
 
+	d.setNodeAttachmentAnnotation(ctx, server, req.VolumeId, "")
+
 	// Verify detachment by polling the drive status
 	// CloudSigma detach is asynchronous - the API accepts the request but actual detachment takes time
-	klog.Infof("Verifying volume %s is detached from node %s", req.VolumeId, req.NodeId)
-	maxRetries := 30 // 30 seconds max
-	for i := 0; i < maxRetries; i++ {
-		drive, _, err := d.cloudClient.Drives.Get(ctx, req.VolumeId)
-		if err != nil {
-			if strings.Contains(err.Error(), "404") {
-				// Drive deleted, consider it detached
-				klog.Infof("Volume %s no longer exists, considered detached", req.VolumeId)
-				return &csi.ControllerUnpublishVolumeResponse{}, nil
-			}
-			klog.Warningf("Failed to verify detachment of volume %s (retry %d/%d): %v", req.VolumeId, i+1, maxRetries, err)
-		} else {
-			// Check if drive is unmounted
-			if drive.Status == "unmounted" && len(drive.MountedOn) == 0 {
-				klog.Infof("Volume %s successfully detached from node %s (verified)", req.VolumeId, req.NodeId)
-				return &csi.ControllerUnpublishVolumeResponse{}, nil
-			}
-			klog.V(4).Infof("Volume %s still mounted (status: %s, mounted_on: %d), waiting... (retry %d/%d)",
-				req.VolumeId, drive.Status, len(drive.MountedOn), i+1, maxRetries)
-		}
+	klog.InfoS("Verifying volume is detached", "volumeId", req.VolumeId, "nodeId", req.NodeId)
+	if d.waitForDetach(ctx, req.VolumeId, req.NodeId) {
+		return &csi.ControllerUnpublishVolumeResponse{}, nil
+	}
 
-		if i < maxRetries-1 {
-			time.Sleep(1 * time.Second)
-		}
+	if !d.detachEscalation {
+		// Timeout - log warning but don't fail as the detach API call succeeded
+		klog.Warningf("Timeout waiting for volume %s detachment verification from node %s after %d checks (API call succeeded, assuming eventual consistency)",
+			req.VolumeId, req.NodeId, d.detachPollAttempts)
+		return &csi.ControllerUnpublishVolumeResponse{}, nil
 	}
 
-	// Timeout - log warning but don't fail as the detach API call succeeded
-	klog.Warningf("Timeout waiting for volume %s detachment verification from node %s after %d seconds (API call succeeded, assuming eventual consistency)",
-		req.VolumeId, req.NodeId, maxRetries)
+	d.escalateDetach(ctx, req.VolumeId, req.NodeId)
 	return &csi.ControllerUnpublishVolumeResponse{}, nil
 }
 
@@ -460,8 +622,8 @@ func (d *Driver) ValidateVolumeCapabilities(ctx context.Context, req *csi.Valida
 	}
 
 	// Check if volume exists
-	if d.cloudClient != nil {
-		_, _, err := d.cloudClient.Drives.Get(ctx, req.VolumeId)
+	if d.cloud() != nil {
+		_, _, err := d.cloud().Drives.Get(ctx, req.VolumeId)
 		if err != nil {
 			return nil, status.Errorf(codes.NotFound, "volume not found: %v", err)
 		}
@@ -504,10 +666,14 @@ func (d *Driver) ControllerExpandVolume(ctx context.Context, req *csi.Controller
 		return nil, status.Error(codes.InvalidArgument, "volume ID is required")
 	}
 
-	if d.cloudClient == nil {
+	if d.cloud() == nil {
 		return nil, status.Error(codes.Internal, "CloudSigma client not initialized")
 	}
 
+	if req.CapacityRange == nil {
+		return nil, status.Error(codes.InvalidArgument, "capacity range is required")
+	}
+
 	newSize := req.CapacityRange.RequiredBytes
 	if newSize < MinVolumeSize {
 		newSize = MinVolumeSize
@@ -516,11 +682,24 @@ func (d *Driver) ControllerExpandVolume(ctx context.Context, req *csi.Controller
 	klog.Infof("Expanding volume %s to %d bytes", req.VolumeId, newSize)
 
 	// Get the drive to retrieve its name and media (required by CloudSigma API)
-	drive, _, err := d.cloudClient.Drives.Get(ctx, req.VolumeId)
+	drive, _, err := d.cloud().Drives.Get(ctx, req.VolumeId)
 	if err != nil {
 		return nil, status.Errorf(codes.NotFound, "failed to get volume for resize: %v", err)
 	}
 
+	if int64(drive.Size) >= newSize {
+		klog.Infof("Volume %s is already %d bytes, no resize needed", req.VolumeId, drive.Size)
+		return &csi.ControllerExpandVolumeResponse{
+			CapacityBytes:         int64(drive.Size),
+			NodeExpansionRequired: true,
+		}, nil
+	}
+
+	// CloudSigma only resizes drives that aren't mounted on a running guest
+	if err := d.checkDriveResizable(ctx, drive); err != nil {
+		return nil, err
+	}
+
 	// Resize the drive
 	updateReq := &cloudsigma.DriveUpdateRequest{
 		Drive: &cloudsigma.Drive{
@@ -529,8 +708,12 @@ func (d *Driver) ControllerExpandVolume(ctx context.Context, req *csi.Controller
 			Size:  int(newSize),
 		},
 	}
-	_, _, err = d.cloudClient.Drives.Resize(ctx, req.VolumeId, updateReq)
+	_, _, err = d.cloud().Drives.Resize(ctx, req.VolumeId, updateReq)
 	if err != nil {
+		if isForbidden(err) {
+			// The drive was attached to a running server after the check above
+			return nil, status.Errorf(codes.FailedPrecondition, "failed to expand volume %s: %v; %s", req.VolumeId, err, offlineResizeHint)
+		}
 		return nil, status.Errorf(codes.Internal, "failed to expand volume: %v", err)
 	}
 
@@ -597,14 +780,41 @@ func (d *Driver) isValidVolumeCapability(cap *csi.VolumeCapability) bool {
 	return false
 }
 
+// listAllDrives lists every drive on the account, following pagination until exhausted
+func (d *Driver) listAllDrives(ctx context.Context, opts *cloudsigma.DriveListOptions) ([]cloudsigma.Drive, error) {
+	pageOpts := cloudsigma.DriveListOptions{}
+	if opts != nil {
+		pageOpts = *opts
+	}
+	pageOpts.Limit = listPageSize
+	pageOpts.Offset = 0
+
+	var drives []cloudsigma.Drive
+	for {
+		page, resp, err := d.cloud().Drives.List(ctx, &pageOpts)
+		if err != nil {
+			return nil, err
+		}
+
+		drives = append(drives, page...)
+		pageOpts.Offset += len(page)
+		if len(page) == 0 || resp == nil || resp.Meta == nil || pageOpts.Offset >= resp.Meta.TotalCount {
+			break
+		}
+	}
+
+	return drives, nil
+}
+
+// findDriveByName returns the drive of volume name by its volumeNameMetaKey meta or drive name
 func (d *Driver) findDriveByName(ctx context.Context, name string) (*cloudsigma.Drive, error) {
-	drives, _, err := d.cloudClient.Drives.List(ctx, nil)
+	drives, err := d.listAllDrives(ctx, nil)
 	if err != nil {
 		return nil, err
 	}
 
 	for _, drive := range drives {
-		if drive.Name == name {
+		if drive.Meta[volumeNameMetaKey] == name || drive.Name == name {
 			return &drive, nil
 		}
 	}
@@ -614,7 +824,7 @@ func (d *Driver) findDriveByName(ctx context.Context, name string) (*cloudsigma.
 
 func (d *Driver) waitForServerStatus(ctx context.Context, serverID, targetStatus string) error {
 	for i := 0; i < 60; i++ {
-		server, _, err := d.cloudClient.Servers.Get(ctx, serverID)
+		server, _, err := d.cloud().Servers.Get(ctx, serverID)
 		if err != nil {
 			return err
 		}
@@ -631,33 +841,11 @@ func (d *Driver) waitForServerStatus(ctx context.Context, serverID, targetStatus
 	return fmt.Errorf("timeout waiting for server %s to reach status %s", serverID, targetStatus)
 }
 
-func findNextDeviceChannel(drives []cloudsigma.ServerDrive) string {
+// findNextDeviceChannel returns the first channel of policy not used by drives
+func findNextDeviceChannel(policy devicechannel.Policy, drives []cloudsigma.ServerDrive) string {
 	usedChannels := make(map[string]bool)
 	for _, d := range drives {
 		usedChannels[d.DevChannel] = true
 	}
-
-	// CloudSigma device channel allocation:
-	// - Unit 3 is always skipped on each controller
-	// - Controller 0: only unit 2 is available for data disks (0:0 is boot, 0:1 unused, 0:3 skipped)
-	// - Controller 1+: units 0,1,2 are available (unit 3 is skipped)
-	// This gives us: 0:2, then 1:0, 1:1, 1:2, then 2:0, 2:1, 2:2, etc.
-
-	// Start with controller 0, unit 2 only
-	if !usedChannels["0:2"] {
-		return "0:2"
-	}
-
-	// Then try controllers 1-202, units 0-2 only (skip unit 3)
-	for controller := 1; controller <= 202; controller++ {
-		for unit := 0; unit < 3; unit++ { // Only 0, 1, 2 - skip unit 3
-			channel := fmt.Sprintf("%d:%d", controller, unit)
-			if !usedChannels[channel] {
-				return channel
-			}
-		}
-	}
-
-	// Fallback (should never reach here unless all slots are used!)
-	return "202:2" // Last available slot
+	return policy.Next(usedChannels)
 }
@@ -0,0 +1,633 @@
+/*
+Copyright 2025 Kube-DC Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cloudsigma/cloudsigma-sdk-go/cloudsigma"
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/kube-dc/cluster-api-provider-cloudsigma/pkg/cloud/fake"
+	"github.com/kube-dc/cluster-api-provider-cloudsigma/pkg/devicechannel"
+)
+
+// rewriteTransport redirects SDK requests to a local test server
+type rewriteTransport struct {
+	target *url.URL
+}
+
+func (t *rewriteTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req.URL.Scheme = t.target.Scheme
+	req.URL.Host = t.target.Host
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+// newTestDriver returns a controller driver whose CloudSigma client talks to handler
+func newTestDriver(t *testing.T, handler http.Handler) *Driver {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	target, _ := url.Parse(server.URL)
+	cloudClient := cloudsigma.NewClient(
+		cloudsigma.NewTokenCredentialsProvider("test-token"),
+		cloudsigma.WithHTTPClient(&http.Client{Transport: &rewriteTransport{target: target}}),
+	)
+
+	d, err := NewDriver(&Config{Name: DriverName, Version: DriverVersion, Mode: ControllerMode, Region: "zrh"})
+	if err != nil {
+		t.Fatalf("NewDriver() error = %v", err)
+	}
+	d.cloudClient = cloudClient
+	return d
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func TestDeleteVolume_WaitsForUnmount(t *testing.T) {
+	origInterval := deleteVolumeRetryInterval
+	deleteVolumeRetryInterval = time.Millisecond
+	defer func() { deleteVolumeRetryInterval = origInterval }()
+
+	const volumeID = "vol-1"
+
+	tests := []struct {
+		name           string
+		mountedPolls   int
+		wantCode       codes.Code
+		wantDeleteCall bool
+	}{
+		{
+			name:           "not mounted",
+			mountedPolls:   0,
+			wantCode:       codes.OK,
+			wantDeleteCall: true,
+		},
+		{
+			name:           "unmounts after a few polls",
+			mountedPolls:   3,
+			wantCode:       codes.OK,
+			wantDeleteCall: true,
+		},
+		{
+			name:           "stays mounted past the retry budget",
+			mountedPolls:   deleteVolumeMountedRetries + 5,
+			wantCode:       codes.FailedPrecondition,
+			wantDeleteCall: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			gets := 0
+			deleted := false
+
+			mux := http.NewServeMux()
+			mux.HandleFunc("/api/2.0/drives/"+volumeID+"/", func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				defer mu.Unlock()
+				switch r.Method {
+				case http.MethodGet:
+					driveStatus := "unmounted"
+					if gets < tt.mountedPolls {
+						driveStatus = "mounted"
+					}
+					gets++
+					writeJSON(w, cloudsigma.Drive{UUID: volumeID, Status: driveStatus})
+				case http.MethodDelete:
+					deleted = true
+					w.WriteHeader(http.StatusNoContent)
+				}
+			})
+			mux.HandleFunc("/api/2.0/tags/", func(w http.ResponseWriter, r *http.Request) {
+				writeJSON(w, map[string]interface{}{"objects": []cloudsigma.Tag{}})
+			})
+
+			d := newTestDriver(t, mux)
+			_, err := d.DeleteVolume(context.Background(), &csi.DeleteVolumeRequest{VolumeId: volumeID})
+
+			if got := status.Code(err); got != tt.wantCode {
+				t.Fatalf("DeleteVolume() code = %v, want %v (err: %v)", got, tt.wantCode, err)
+			}
+			if deleted != tt.wantDeleteCall {
+				t.Errorf("drive delete called = %v, want %v", deleted, tt.wantDeleteCall)
+			}
+			if tt.wantCode == codes.FailedPrecondition && !strings.Contains(err.Error(), "still mounted") {
+				t.Errorf("unexpected error message: %v", err)
+			}
+		})
+	}
+}
+
+func TestFindDriveByName_Paginates(t *testing.T) {
+	const total = 2*listPageSize + 3
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/2.0/drives/detail/", func(w http.ResponseWriter, r *http.Request) {
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+		if limit <= 0 {
+			t.Errorf("request without page limit: %s", r.URL)
+			limit = total
+		}
+
+		drives := []cloudsigma.Drive{}
+		for i := offset; i < total && i < offset+limit; i++ {
+			drives = append(drives, cloudsigma.Drive{UUID: fmt.Sprintf("drive-%d", i), Name: fmt.Sprintf("pvc-%d", i)})
+		}
+		writeJSON(w, map[string]interface{}{
+			"meta":    cloudsigma.Meta{Limit: limit, Offset: offset, TotalCount: total},
+			"objects": drives,
+		})
+	})
+
+	d := newTestDriver(t, mux)
+	drive, err := d.findDriveByName(context.Background(), fmt.Sprintf("pvc-%d", total-1))
+	if err != nil {
+		t.Fatalf("findDriveByName() error = %v", err)
+	}
+	if drive == nil || drive.UUID != fmt.Sprintf("drive-%d", total-1) {
+		t.Fatalf("findDriveByName() = %v, want drive on last page", drive)
+	}
+}
+
+func TestControllerPublishVolume_ServerCache(t *testing.T) {
+	const (
+		nodeID  = "node-1"
+		volumes = 5
+	)
+
+	tests := []struct {
+		name     string
+		ttl      time.Duration
+		wantGets int
+	}{
+		{name: "cache disabled", ttl: 0, wantGets: volumes},
+		{name: "cache enabled", ttl: time.Minute, wantGets: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			serverGets := 0
+			server := cloudsigma.Server{UUID: nodeID, Status: "running"}
+
+			mux := http.NewServeMux()
+			mux.HandleFunc("/api/2.0/servers/"+nodeID+"/", func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				defer mu.Unlock()
+				switch r.Method {
+				case http.MethodGet:
+					serverGets++
+				case http.MethodPut:
+					var updated cloudsigma.Server
+					if err := json.NewDecoder(r.Body).Decode(&updated); err != nil {
+						t.Errorf("failed to decode server update: %v", err)
+					}
+					server.Drives = updated.Drives
+				}
+				writeJSON(w, server)
+			})
+			mux.HandleFunc("/api/2.0/drives/", func(w http.ResponseWriter, r *http.Request) {
+				uuid := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/api/2.0/drives/"), "/")
+				writeJSON(w, cloudsigma.Drive{UUID: uuid, Status: "unmounted"})
+			})
+
+			d := newTestDriver(t, mux)
+			d.serverCache = newServerCache(tt.ttl)
+
+			channels := map[string]bool{}
+			for i := 0; i < volumes; i++ {
+				resp, err := d.ControllerPublishVolume(context.Background(), &csi.ControllerPublishVolumeRequest{
+					VolumeId: fmt.Sprintf("vol-%d", i),
+					NodeId:   nodeID,
+				})
+				if err != nil {
+					t.Fatalf("ControllerPublishVolume() error = %v", err)
+				}
+				channels[resp.PublishContext["channel"]] = true
+			}
+
+			if serverGets != tt.wantGets {
+				t.Errorf("Servers.Get calls = %d, want %d", serverGets, tt.wantGets)
+			}
+			if len(channels) != volumes {
+				t.Errorf("got %d distinct channels, want %d", len(channels), volumes)
+			}
+			if len(server.Drives) != volumes {
+				t.Errorf("server has %d drives, want %d", len(server.Drives), volumes)
+			}
+		})
+	}
+}
+
+func TestControllerPublishVolume_DeterministicPublishContext(t *testing.T) {
+	const (
+		nodeID   = "node-1"
+		volumeID = "5f0b8a3c-2d4e-4b6a-9c1d-7e8f9a0b1c2d"
+	)
+
+	var mu sync.Mutex
+	server := cloudsigma.Server{UUID: nodeID, Status: "running", Drives: []cloudsigma.ServerDrive{
+		{DevChannel: "0:0", Drive: &cloudsigma.Drive{UUID: "boot"}},
+	}}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/2.0/servers/"+nodeID+"/", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.Method == http.MethodPut {
+			var updated cloudsigma.Server
+			if err := json.NewDecoder(r.Body).Decode(&updated); err != nil {
+				t.Errorf("failed to decode server update: %v", err)
+			}
+			server.Drives = updated.Drives
+		}
+		writeJSON(w, server)
+	})
+	mux.HandleFunc("/api/2.0/drives/"+volumeID+"/", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, cloudsigma.Drive{UUID: volumeID, Status: "unmounted"})
+	})
+
+	req := &csi.ControllerPublishVolumeRequest{VolumeId: volumeID, NodeId: nodeID}
+	d := newTestDriver(t, mux)
+	var contexts [][]byte
+	// The second publish goes through the already-attached path; the third simulates a restarted
+	// controller with an empty server cache
+	for i, d := range []*Driver{d, d, newTestDriver(t, mux)} {
+		resp, err := d.ControllerPublishVolume(context.Background(), req)
+		if err != nil {
+			t.Fatalf("publish %d: ControllerPublishVolume() error = %v", i, err)
+		}
+		encoded, err := json.Marshal(resp.PublishContext)
+		if err != nil {
+			t.Fatal(err)
+		}
+		contexts = append(contexts, encoded)
+	}
+
+	for i := 1; i < len(contexts); i++ {
+		if !bytes.Equal(contexts[0], contexts[i]) {
+			t.Errorf("publish %d context = %s, want %s", i, contexts[i], contexts[0])
+		}
+	}
+	for _, want := range []string{`"devicePath":"/dev/disk/by-id/virtio-5f0b8a3c2d4e4b6a9c1d"`, `"serial":"5f0b8a3c2d4e4b6a9c1d"`} {
+		if !strings.Contains(string(contexts[0]), want) {
+			t.Errorf("publish context = %s, want it to contain %s", contexts[0], want)
+		}
+	}
+	if len(server.Drives) != 2 {
+		t.Errorf("server has %d drives, want the volume attached once", len(server.Drives))
+	}
+}
+
+func TestControllerPublishVolume_ChannelPolicy(t *testing.T) {
+	const (
+		nodeID   = "node-1"
+		volumeID = "vol-1"
+	)
+
+	tests := []struct {
+		policy string
+		want   string
+	}{
+		{policy: devicechannel.PolicyDefault, want: "0:2"},
+		{policy: devicechannel.PolicySkipController0, want: "1:0"},
+		{policy: devicechannel.PolicyFourUnits, want: "0:3"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			server := cloudsigma.Server{UUID: nodeID, Status: "running", Drives: []cloudsigma.ServerDrive{
+				{BootOrder: 1, DevChannel: "0:0", Drive: &cloudsigma.Drive{UUID: "boot"}},
+				{DevChannel: "1:1", Device: "virtio", Drive: &cloudsigma.Drive{UUID: "data"}},
+			}}
+			if tt.policy == devicechannel.PolicyFourUnits {
+				server.Drives = append(server.Drives, cloudsigma.ServerDrive{DevChannel: "0:2", Device: "virtio", Drive: &cloudsigma.Drive{UUID: "logs"}})
+			}
+
+			mux := http.NewServeMux()
+			mux.HandleFunc("/api/2.0/servers/"+nodeID+"/", func(w http.ResponseWriter, r *http.Request) {
+				writeJSON(w, server)
+			})
+			mux.HandleFunc("/api/2.0/drives/"+volumeID+"/", func(w http.ResponseWriter, r *http.Request) {
+				writeJSON(w, cloudsigma.Drive{UUID: volumeID, Status: "unmounted"})
+			})
+
+			d := newTestDriver(t, mux)
+			policy, err := devicechannel.PolicyByName(tt.policy)
+			if err != nil {
+				t.Fatalf("PolicyByName(%q) error = %v", tt.policy, err)
+			}
+			d.channelPolicy = policy
+
+			resp, err := d.ControllerPublishVolume(context.Background(), &csi.ControllerPublishVolumeRequest{VolumeId: volumeID, NodeId: nodeID})
+			if err != nil {
+				t.Fatalf("ControllerPublishVolume() error = %v", err)
+			}
+			if got := resp.PublishContext["channel"]; got != tt.want {
+				t.Errorf("channel = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewDriver_UnknownChannelPolicy(t *testing.T) {
+	if _, err := NewDriver(&Config{Name: DriverName, Mode: ControllerMode, ChannelPolicy: "round-robin"}); err == nil {
+		t.Error("NewDriver() with an unknown channel policy succeeded, want an error")
+	}
+}
+
+func TestControllerPublishVolume_DriveSerial(t *testing.T) {
+	const (
+		nodeID   = "node-1"
+		volumeID = "5f0b8a3c-2d4e-4b6a-9c1d-7e8f9a0b1c2d"
+		attached = "0a1b2c3d-4e5f-4a6b-8c7d-9e0f1a2b3c4d"
+	)
+
+	var body struct {
+		Drives []map[string]interface{} `json:"drives"`
+	}
+	server := serverWithSerials{
+		Server: &cloudsigma.Server{UUID: nodeID, Status: "running"},
+		Drives: []serverDriveUpdate{
+			{ServerDrive: cloudsigma.ServerDrive{BootOrder: 1, DevChannel: "0:0", Drive: &cloudsigma.Drive{UUID: "boot"}}},
+			{ServerDrive: cloudsigma.ServerDrive{DevChannel: "0:2", Device: "virtio", Drive: &cloudsigma.Drive{UUID: attached}}, Serial: "attached-serial"},
+		},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/2.0/servers/"+nodeID+"/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPut {
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				t.Errorf("failed to decode server update: %v", err)
+			}
+		}
+		writeJSON(w, server)
+	})
+	mux.HandleFunc("/api/2.0/drives/"+volumeID+"/", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, cloudsigma.Drive{UUID: volumeID, Status: "unmounted"})
+	})
+
+	d := newTestDriver(t, mux)
+	resp, err := d.ControllerPublishVolume(context.Background(), &csi.ControllerPublishVolumeRequest{VolumeId: volumeID, NodeId: nodeID})
+	if err != nil {
+		t.Fatalf("ControllerPublishVolume() error = %v", err)
+	}
+	if got, want := resp.PublishContext["serial"], driveSerial(volumeID); got != want {
+		t.Errorf("PublishContext[serial] = %q, want %q", got, want)
+	}
+
+	// Only the new drive gets its driveSerial; the others keep the serial the server lists
+	wantSerials := map[string]interface{}{"boot": nil, attached: "attached-serial", volumeID: driveSerial(volumeID)}
+	if len(body.Drives) != len(wantSerials) {
+		t.Fatalf("server update has %d drives, want %d", len(body.Drives), len(wantSerials))
+	}
+	for _, drive := range body.Drives {
+		uuid, _ := drive["drive"].(map[string]interface{})["uuid"].(string)
+		want, ok := wantSerials[uuid]
+		if !ok {
+			t.Errorf("unexpected drive %q in server update", uuid)
+			continue
+		}
+		if got := drive["serial"]; got != want {
+			t.Errorf("drive %s serial = %v, want %v", uuid, got, want)
+		}
+	}
+}
+
+func TestCreateVolume_StorageType(t *testing.T) {
+	tests := []struct {
+		name            string
+		defaultType     string
+		params          map[string]string
+		wantCode        codes.Code
+		wantStorageType string
+	}{
+		{name: "built-in default", wantCode: codes.OK, wantStorageType: StorageTypeDSSD},
+		{name: "configured default", defaultType: StorageTypeMagnetic, wantCode: codes.OK, wantStorageType: StorageTypeMagnetic},
+		{name: "parameter overrides default", defaultType: StorageTypeMagnetic, params: map[string]string{"storageType": "dssd"}, wantCode: codes.OK, wantStorageType: StorageTypeDSSD},
+		{name: "zadara parameter", params: map[string]string{"storageType": "zadara"}, wantCode: codes.OK, wantStorageType: StorageTypeMagnetic},
+		{name: "typo", params: map[string]string{"storageType": "ssd"}, wantCode: codes.InvalidArgument},
+		{name: "empty parameter", params: map[string]string{"storageType": ""}, wantCode: codes.InvalidArgument},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var created []string
+
+			mux := http.NewServeMux()
+			mux.HandleFunc("/api/2.0/drives/detail/", func(w http.ResponseWriter, r *http.Request) {
+				writeJSON(w, map[string]interface{}{"objects": []cloudsigma.Drive{}})
+			})
+			mux.HandleFunc("/api/2.0/drives/", func(w http.ResponseWriter, r *http.Request) {
+				var req cloudsigma.DriveCreateRequest
+				if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+					t.Errorf("failed to decode drive create request: %v", err)
+				}
+				for i := range req.Drives {
+					created = append(created, req.Drives[i].StorageType)
+					req.Drives[i].UUID = "vol-1"
+				}
+				writeJSON(w, map[string]interface{}{"objects": req.Drives})
+			})
+			mux.HandleFunc("/api/2.0/tags/", func(w http.ResponseWriter, r *http.Request) {
+				writeJSON(w, map[string]interface{}{"objects": []cloudsigma.Tag{}})
+			})
+
+			d := newTestDriver(t, mux)
+			if tt.defaultType != "" {
+				d.defaultStorageType = tt.defaultType
+			}
+			_, err := d.CreateVolume(context.Background(), &csi.CreateVolumeRequest{
+				Name:       "pvc-1",
+				Parameters: tt.params,
+				VolumeCapabilities: []*csi.VolumeCapability{{
+					AccessType: &csi.VolumeCapability_Mount{Mount: &csi.VolumeCapability_MountVolume{}},
+					AccessMode: &csi.VolumeCapability_AccessMode{Mode: csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER},
+				}},
+			})
+
+			if got := status.Code(err); got != tt.wantCode {
+				t.Fatalf("CreateVolume() code = %v, want %v (err: %v)", got, tt.wantCode, err)
+			}
+			if tt.wantCode != codes.OK {
+				if len(created) != 0 {
+					t.Errorf("drive created with invalid storage type: %v", created)
+				}
+				if !strings.Contains(status.Convert(err).Message(), "dssd, zadara") {
+					t.Errorf("error %q doesn't list the valid storage types", status.Convert(err).Message())
+				}
+				return
+			}
+			if len(created) != 1 || created[0] != tt.wantStorageType {
+				t.Errorf("created storage types = %v, want [%s]", created, tt.wantStorageType)
+			}
+		})
+	}
+}
+
+func TestNewDriver_DefaultStorageType(t *testing.T) {
+	if _, err := NewDriver(&Config{Mode: ControllerMode, DefaultStorageType: "ssd"}); err == nil {
+		t.Error("NewDriver() with an unknown default storage type succeeded, want error")
+	}
+	d, err := NewDriver(&Config{Mode: ControllerMode})
+	if err != nil {
+		t.Fatalf("NewDriver() error = %v", err)
+	}
+	if d.defaultStorageType != StorageTypeDSSD {
+		t.Errorf("defaultStorageType = %q, want %q", d.defaultStorageType, StorageTypeDSSD)
+	}
+}
+
+func TestVolumeLifecycle_FakeAPI(t *testing.T) {
+	ctx := context.Background()
+	api := fake.NewServer()
+	defer api.Close()
+
+	sdk := api.NewSDKClient()
+	boot := api.AddDrive(cloudsigma.Drive{Name: "node-1-drive-0", Size: 20 << 30})
+	servers, _, err := sdk.Servers.Create(ctx, &cloudsigma.ServerCreateRequest{
+		Servers: []cloudsigma.Server{{Name: "node-1", CPU: 2000, Memory: 2 << 30, VNCPassword: "secret",
+			Drives: []cloudsigma.ServerDrive{{BootOrder: 1, DevChannel: "0:0", Drive: &cloudsigma.Drive{UUID: boot.UUID}}}}},
+	})
+	if err != nil {
+		t.Fatalf("Servers.Create() error = %v", err)
+	}
+	nodeID := servers[0].UUID
+
+	d, err := NewDriver(&Config{
+		Name:               DriverName,
+		Version:            DriverVersion,
+		Mode:               ControllerMode,
+		Region:             fake.Region,
+		ClusterName:        "demo",
+		CloudClient:        sdk,
+		DetachPollInterval: time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("NewDriver() error = %v", err)
+	}
+
+	created, err := d.CreateVolume(ctx, &csi.CreateVolumeRequest{
+		Name:          "pvc-1",
+		CapacityRange: &csi.CapacityRange{RequiredBytes: MinVolumeSize},
+		VolumeCapabilities: []*csi.VolumeCapability{{
+			AccessType: &csi.VolumeCapability_Mount{Mount: &csi.VolumeCapability_MountVolume{}},
+			AccessMode: &csi.VolumeCapability_AccessMode{Mode: csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER},
+		}},
+	})
+	if err != nil {
+		t.Fatalf("CreateVolume() error = %v", err)
+	}
+	volumeID := created.Volume.VolumeId
+	if tag, ok := api.GetTag("volume:pvc-1"); !ok || len(tag.Resources) != 1 || tag.Resources[0].UUID != volumeID {
+		t.Errorf("volume tag = %+v, want it to hold %s", tag, volumeID)
+	}
+
+	if _, err := d.ControllerPublishVolume(ctx, &csi.ControllerPublishVolumeRequest{VolumeId: volumeID, NodeId: nodeID}); err != nil {
+		t.Fatalf("ControllerPublishVolume() error = %v", err)
+	}
+	if drive, _ := api.GetDrive(volumeID); drive.Status != "mounted" || len(drive.MountedOn) != 1 || drive.MountedOn[0].UUID != nodeID {
+		t.Errorf("published drive = %+v, want mounted on %s", drive, nodeID)
+	}
+
+	if _, err := d.ControllerUnpublishVolume(ctx, &csi.ControllerUnpublishVolumeRequest{VolumeId: volumeID, NodeId: nodeID}); err != nil {
+		t.Fatalf("ControllerUnpublishVolume() error = %v", err)
+	}
+	if server, _ := api.GetServer(nodeID); len(server.Drives) != 1 || server.Drives[0].Drive.UUID != boot.UUID {
+		t.Errorf("server drives after unpublish = %+v, want only the boot drive", server.Drives)
+	}
+
+	if _, err := d.DeleteVolume(ctx, &csi.DeleteVolumeRequest{VolumeId: volumeID}); err != nil {
+		t.Fatalf("DeleteVolume() error = %v", err)
+	}
+	if _, ok := api.GetDrive(volumeID); ok {
+		t.Error("drive still exists after DeleteVolume()")
+	}
+}
+
+func TestDeleteVolume_VolumeDeletePolicy(t *testing.T) {
+	tests := []struct {
+		policy    string
+		wantDrive bool
+	}{
+		{policy: "", wantDrive: false},
+		{policy: VolumeDeletePolicyDelete, wantDrive: false},
+		{policy: VolumeDeletePolicyRetain, wantDrive: true},
+	}
+
+	for _, tt := range tests {
+		t.Run("policy "+tt.policy, func(t *testing.T) {
+			ctx := context.Background()
+			api := fake.NewServer()
+			defer api.Close()
+
+			d, err := NewDriver(&Config{
+				Name:               DriverName,
+				Version:            DriverVersion,
+				Mode:               ControllerMode,
+				Region:             fake.Region,
+				ClusterName:        "demo",
+				CloudClient:        api.NewSDKClient(),
+				VolumeDeletePolicy: tt.policy,
+			})
+			if err != nil {
+				t.Fatalf("NewDriver() error = %v", err)
+			}
+			drive := api.AddDrive(cloudsigma.Drive{Name: "pvc-1", Size: MinVolumeSize})
+			d.tagDrive(ctx, drive.UUID, "pvc-1")
+
+			if _, err := d.DeleteVolume(ctx, &csi.DeleteVolumeRequest{VolumeId: drive.UUID}); err != nil {
+				t.Fatalf("DeleteVolume() error = %v", err)
+			}
+			if _, ok := api.GetDrive(drive.UUID); ok != tt.wantDrive {
+				t.Errorf("drive exists after DeleteVolume() = %v, want %v", ok, tt.wantDrive)
+			}
+			for _, name := range []string{"managed-by:cloudsigma-csi", "cluster:demo", "volume:pvc-1"} {
+				if tag, ok := api.GetTag(name); ok && len(tag.Resources) != 0 {
+					t.Errorf("tag %s still holds %v after DeleteVolume()", name, tag.Resources)
+				}
+			}
+		})
+	}
+}
+
+func TestNewDriver_UnknownVolumeDeletePolicy(t *testing.T) {
+	if _, err := NewDriver(&Config{Mode: ControllerMode, VolumeDeletePolicy: "keep"}); err == nil {
+		t.Error("NewDriver() with an unknown volume delete policy succeeded, want error")
+	}
+}
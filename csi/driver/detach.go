@@ -0,0 +1,142 @@
+/*
+Copyright 2025 Kube-DC Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/cloudsigma/cloudsigma-sdk-go/cloudsigma"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// defaultDetachPollAttempts is how many times the drive status is checked after a detach
+	defaultDetachPollAttempts = 30
+
+	// defaultDetachPollInterval is the delay between detach status checks
+	defaultDetachPollInterval = 1 * time.Second
+
+	// EventReasonDetachEscalated is recorded on the Node when a second, forced detach is issued
+	EventReasonDetachEscalated = "VolumeDetachEscalated"
+
+	// EventReasonDetachStuck is recorded on the Node when a volume is still attached after escalation
+	EventReasonDetachStuck = "VolumeDetachStuck"
+)
+
+// waitForDetach polls the drive until CloudSigma reports it unmounted. It returns true once the
+// drive is unmounted or gone, and false if it is still mounted after d.detachPollAttempts checks.
+func (d *Driver) waitForDetach(ctx context.Context, volumeID, nodeID string) bool {
+	for i := 0; i < d.detachPollAttempts; i++ {
+		drive, _, err := d.cloud().Drives.Get(ctx, volumeID)
+		if err != nil {
+			if isNotFound(err) {
+				// Drive deleted, consider it detached
+				klog.Infof("Volume %s no longer exists, considered detached", volumeID)
+				return true
+			}
+			klog.Warningf("Failed to verify detachment of volume %s (retry %d/%d): %v", volumeID, i+1, d.detachPollAttempts, err)
+		} else {
+			// Check if drive is unmounted
+			if drive.Status == "unmounted" && len(drive.MountedOn) == 0 {
+				klog.Infof("Volume %s successfully detached from node %s (verified)", volumeID, nodeID)
+				return true
+			}
+			klog.V(4).Infof("Volume %s still mounted (status: %s, mounted_on: %d), waiting... (retry %d/%d)",
+				volumeID, drive.Status, len(drive.MountedOn), i+1, d.detachPollAttempts)
+		}
+
+		if i < d.detachPollAttempts-1 {
+			select {
+			case <-ctx.Done():
+				return false
+			case <-time.After(d.detachPollInterval):
+			}
+		}
+	}
+	return false
+}
+
+// escalateDetach re-fetches the server, bypassing the cache, and submits the drive list without the
+// volume once more, then waits for the detach again. A volume that is still attached afterwards is
+// reported with a Node event; the caller returns success either way, since the detach was accepted.
+func (d *Driver) escalateDetach(ctx context.Context, volumeID, nodeID string) {
+	klog.Warningf("Volume %s still attached to node %s after %d checks, forcing a second detach",
+		volumeID, nodeID, d.detachPollAttempts)
+
+	serverLock := d.getServerLock(nodeID)
+	serverLock.Lock()
+	d.serverCache.invalidate(nodeID)
+	server, serials, err := d.fetchServer(ctx, nodeID)
+	if err != nil {
+		serverLock.Unlock()
+		if isNotFound(err) {
+			klog.Infof("Node %s no longer exists, volume %s considered detached", nodeID, volumeID)
+			return
+		}
+		klog.Warningf("Failed to re-fetch node %s for forced detach of volume %s: %v", nodeID, volumeID, err)
+		return
+	}
+
+	// Servers.Update clears the UUID of the server it is given, so keep the identity for events
+	eventTarget := &cloudsigma.Server{UUID: nodeID, Name: server.Name}
+
+	drives := make([]cloudsigma.ServerDrive, 0, len(server.Drives))
+	for _, sd := range server.Drives {
+		if sd.Drive != nil && sd.Drive.UUID == volumeID {
+			continue
+		}
+		drives = append(drives, sd)
+	}
+	server.Drives = drives
+	err = d.updateServer(ctx, nodeID, server, serials)
+	serverLock.Unlock()
+	if err != nil {
+		klog.Warningf("Forced detach of volume %s from node %s failed: %v", volumeID, nodeID, err)
+	}
+
+	d.recordNodeEvent(ctx, eventTarget, corev1.EventTypeWarning, EventReasonDetachEscalated,
+		fmt.Sprintf("Volume %s was not detached after %d checks, forced a second detach", volumeID, d.detachPollAttempts))
+
+	if d.waitForDetach(ctx, volumeID, nodeID) {
+		klog.Infof("Volume %s detached from node %s after forced detach", volumeID, nodeID)
+		return
+	}
+
+	klog.Warningf("Volume %s still attached to node %s after forced detach (API calls succeeded, assuming eventual consistency)",
+		volumeID, nodeID)
+	d.recordNodeEvent(ctx, eventTarget, corev1.EventTypeWarning, EventReasonDetachStuck,
+		fmt.Sprintf("Volume %s is still attached after a forced detach; it may not attach to another node until CloudSigma releases it", volumeID))
+}
+
+// recordNodeEvent records a Kubernetes event on the Node backed by server. It is best effort and a
+// no-op without a Kubernetes client.
+func (d *Driver) recordNodeEvent(ctx context.Context, server *cloudsigma.Server, eventType, reason, message string) {
+	if d.kubeClient == nil || server == nil {
+		return
+	}
+
+	node, err := d.findNodeForServer(ctx, server)
+	if err != nil || node == nil {
+		klog.V(4).Infof("No Kubernetes node found for server %s, skipping %s event", server.UUID, reason)
+		return
+	}
+
+	d.createEvent(ctx, corev1.ObjectReference{Kind: "Node", Name: node.Name, UID: node.UID}, eventType, reason, message)
+}
@@ -0,0 +1,142 @@
+/*
+Copyright 2025 Kube-DC Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cloudsigma/cloudsigma-sdk-go/cloudsigma"
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestControllerUnpublishVolume_DetachEscalation(t *testing.T) {
+	const (
+		nodeID   = "node-1"
+		volumeID = "vol-1"
+	)
+
+	tests := []struct {
+		name string
+		// detachedAfterPuts is the number of server updates after which the drive reports unmounted;
+		// 0 means it never does
+		detachedAfterPuts int
+		escalation        bool
+		wantPuts          int
+		wantEvents        []string
+	}{
+		{name: "detaches normally", detachedAfterPuts: 1, escalation: true, wantPuts: 1},
+		{name: "needs escalation", detachedAfterPuts: 2, escalation: true, wantPuts: 2,
+			wantEvents: []string{EventReasonDetachEscalated}},
+		{name: "permanently stuck", escalation: true, wantPuts: 2,
+			wantEvents: []string{EventReasonDetachEscalated, EventReasonDetachStuck}},
+		{name: "escalation disabled", escalation: false, wantPuts: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			puts := 0
+			server := cloudsigma.Server{UUID: nodeID, Status: "running", Drives: []cloudsigma.ServerDrive{
+				{DevChannel: "0:0", Drive: &cloudsigma.Drive{UUID: "boot"}},
+				{DevChannel: "0:1", Drive: &cloudsigma.Drive{UUID: volumeID}},
+			}}
+
+			mux := http.NewServeMux()
+			mux.HandleFunc("/api/2.0/servers/"+nodeID+"/", func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				defer mu.Unlock()
+				if r.Method == http.MethodPut {
+					puts++
+					var updated cloudsigma.Server
+					if err := json.NewDecoder(r.Body).Decode(&updated); err != nil {
+						t.Errorf("failed to decode server update: %v", err)
+					}
+					for _, sd := range updated.Drives {
+						if sd.Drive != nil && sd.Drive.UUID == volumeID {
+							t.Errorf("update %d still lists the volume", puts)
+						}
+					}
+					server.Drives = updated.Drives
+				}
+				writeJSON(w, server)
+			})
+			mux.HandleFunc("/api/2.0/drives/"+volumeID+"/", func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				defer mu.Unlock()
+				drive := cloudsigma.Drive{UUID: volumeID, Status: "mounted", MountedOn: []cloudsigma.ResourceLink{{UUID: nodeID}}}
+				if tt.detachedAfterPuts > 0 && puts >= tt.detachedAfterPuts {
+					drive = cloudsigma.Drive{UUID: volumeID, Status: "unmounted"}
+				}
+				writeJSON(w, drive)
+			})
+
+			d := newTestDriver(t, mux)
+			d.detachPollAttempts = 3
+			d.detachPollInterval = time.Millisecond
+			d.detachEscalation = tt.escalation
+			kubeClient := fake.NewSimpleClientset(&corev1.Node{
+				ObjectMeta: metav1.ObjectMeta{Name: "worker-0"},
+				Spec:       corev1.NodeSpec{ProviderID: "cloudsigma://" + nodeID},
+			})
+			d.kubeClient = kubeClient
+
+			_, err := d.ControllerUnpublishVolume(context.Background(), &csi.ControllerUnpublishVolumeRequest{
+				VolumeId: volumeID,
+				NodeId:   nodeID,
+			})
+			if err != nil {
+				t.Fatalf("ControllerUnpublishVolume() error = %v", err)
+			}
+
+			if puts != tt.wantPuts {
+				t.Errorf("server updates = %d, want %d", puts, tt.wantPuts)
+			}
+
+			events, err := kubeClient.CoreV1().Events(metav1.NamespaceDefault).List(context.Background(), metav1.ListOptions{})
+			if err != nil {
+				t.Fatalf("failed to list events: %v", err)
+			}
+			var reasons []string
+			for _, e := range events.Items {
+				if e.InvolvedObject.Kind != "Node" || e.InvolvedObject.Name != "worker-0" {
+					t.Errorf("event %s recorded on %s/%s, want Node/worker-0", e.Reason, e.InvolvedObject.Kind, e.InvolvedObject.Name)
+				}
+				reasons = append(reasons, e.Reason)
+			}
+			if len(reasons) != len(tt.wantEvents) {
+				t.Fatalf("events = %v, want %v", reasons, tt.wantEvents)
+			}
+			want := map[string]bool{}
+			for _, r := range tt.wantEvents {
+				want[r] = true
+			}
+			for _, r := range reasons {
+				if !want[r] {
+					t.Errorf("events = %v, want %v", reasons, tt.wantEvents)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,157 @@
+/*
+Copyright 2025 Kube-DC Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+// Locations the device serial is read from (variables so tests can use a fabricated tree)
+var (
+	sysBlockDir    = "/sys/block"
+	devDiskByIDDir = "/dev/disk/by-id"
+)
+
+// virtioSerialLen is the maximum virtio-blk serial length; longer serials are truncated
+const virtioSerialLen = 20
+
+// driveSerial is the virtio serial the driver gives the drives it attaches: the drive UUID without
+// dashes, cut to the virtio limit. It depends only on the UUID, so every attachment of a drive,
+// on any node, shows up under the same /dev/disk/by-id link.
+func driveSerial(volumeID string) string {
+	serial := strings.ToLower(strings.ReplaceAll(volumeID, "-", ""))
+	if len(serial) > virtioSerialLen {
+		serial = serial[:virtioSerialLen]
+	}
+	return serial
+}
+
+// deviceByIDPath returns the /dev/disk/by-id link udev creates for a drive attached with driveSerial
+func deviceByIDPath(volumeID string) string {
+	return "/dev/disk/by-id/virtio-" + driveSerial(volumeID)
+}
+
+// How long the node waits for udev to create the by-id link of a drive's serial
+var (
+	serialLinkPollAttempts = 10
+	serialLinkPollInterval = 500 * time.Millisecond
+)
+
+// findDeviceBySerial returns the block device behind /dev/disk/by-id/virtio-<serial>, waiting for
+// udev to create the link after a hotplug. It returns "" if the link does not appear, e.g. for a
+// drive attached before the driver assigned serials.
+func findDeviceBySerial(serial string) string {
+	link := filepath.Join(devDiskByIDDir, "virtio-"+serial)
+	for attempt := 1; ; attempt++ {
+		if device, err := filepath.EvalSymlinks(link); err == nil {
+			klog.Infof("Found device %s for serial %s", device, serial)
+			return device
+		}
+		if attempt >= serialLinkPollAttempts {
+			klog.Warningf("No device with serial %s after %d attempts", serial, attempt)
+			return ""
+		}
+		time.Sleep(serialLinkPollInterval)
+	}
+}
+
+// minSerialMatchLen guards against trivially short serials matching any UUID prefix
+const minSerialMatchLen = 8
+
+// deviceIdentity is the result of comparing a block device's serial with a volume ID
+type deviceIdentity int
+
+const (
+	// deviceIdentityUnknown means the device exposes no serial to compare against
+	deviceIdentityUnknown deviceIdentity = iota
+	// deviceIdentityMatch means the device serial corresponds to the volume's drive UUID
+	deviceIdentityMatch
+	// deviceIdentityMismatch means the device belongs to a different drive
+	deviceIdentityMismatch
+)
+
+// checkDeviceIdentity compares the serial of devicePath with the CloudSigma drive UUID.
+// CloudSigma sets the virtio serial from the drive UUID; virtio truncates it to 20 bytes,
+// so a serial matches when it is a prefix of the UUID (with or without dashes).
+// It returns the verdict and the serial that was found, if any.
+func checkDeviceIdentity(devicePath, volumeID string) (deviceIdentity, string) {
+	serials := deviceSerials(devicePath)
+	if len(serials) == 0 {
+		return deviceIdentityUnknown, ""
+	}
+
+	for _, serial := range serials {
+		if serialMatchesVolume(serial, volumeID) {
+			return deviceIdentityMatch, serial
+		}
+	}
+	return deviceIdentityMismatch, serials[0]
+}
+
+// deviceSerials collects the serials the kernel reports for a block device from
+// /sys/block/<dev>/serial and from /dev/disk/by-id/virtio-<serial> links pointing at it
+func deviceSerials(devicePath string) []string {
+	resolved, err := filepath.EvalSymlinks(devicePath)
+	if err != nil {
+		resolved = devicePath
+	}
+
+	var serials []string
+	if data, err := os.ReadFile(filepath.Join(sysBlockDir, filepath.Base(resolved), "serial")); err == nil {
+		if serial := strings.TrimSpace(string(data)); serial != "" {
+			serials = append(serials, serial)
+		}
+	}
+
+	entries, err := filepath.Glob(filepath.Join(devDiskByIDDir, "virtio-*"))
+	if err != nil {
+		klog.V(4).Infof("Failed to list %s: %v", devDiskByIDDir, err)
+	}
+	for _, entry := range entries {
+		if strings.Contains(entry, "-part") {
+			continue
+		}
+		target, err := filepath.EvalSymlinks(entry)
+		if err != nil || target != resolved {
+			continue
+		}
+		serials = append(serials, strings.TrimPrefix(filepath.Base(entry), "virtio-"))
+	}
+
+	return serials
+}
+
+// serialMatchesVolume reports whether a (possibly truncated) device serial identifies volumeID
+func serialMatchesVolume(serial, volumeID string) bool {
+	serial = strings.ToLower(strings.TrimSpace(serial))
+	volumeID = strings.ToLower(volumeID)
+	if len(serial) < minSerialMatchLen || volumeID == "" {
+		return false
+	}
+
+	if strings.HasPrefix(volumeID, serial) {
+		return true
+	}
+	compactSerial := strings.ReplaceAll(serial, "-", "")
+	compactVolume := strings.ReplaceAll(volumeID, "-", "")
+	return len(compactSerial) >= minSerialMatchLen && strings.HasPrefix(compactVolume, compactSerial)
+}
@@ -0,0 +1,151 @@
+/*
+Copyright 2025 Kube-DC Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const testVolumeID = "5f0b8a3c-2d4e-4b6a-9c1d-7e8f9a0b1c2d"
+
+// fakeDeviceTree fabricates /dev, /sys/block and /dev/disk/by-id under a temp dir and points
+// the package lookups at it. It returns the path of the fake /dev/<name> device.
+func fakeDeviceTree(t *testing.T, name, sysSerial, byIDSerial string) string {
+	t.Helper()
+	root := t.TempDir()
+
+	devDir := filepath.Join(root, "dev")
+	byID := filepath.Join(devDir, "disk", "by-id")
+	sysBlock := filepath.Join(root, "sys", "block")
+	for _, dir := range []string{byID, filepath.Join(sysBlock, name)} {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	device := filepath.Join(devDir, name)
+	if err := os.WriteFile(device, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if sysSerial != "" {
+		if err := os.WriteFile(filepath.Join(sysBlock, name, "serial"), []byte(sysSerial+"\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if byIDSerial != "" {
+		if err := os.Symlink(device, filepath.Join(byID, "virtio-"+byIDSerial)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	oldSys, oldByID := sysBlockDir, devDiskByIDDir
+	sysBlockDir, devDiskByIDDir = sysBlock, byID
+	t.Cleanup(func() { sysBlockDir, devDiskByIDDir = oldSys, oldByID })
+
+	return device
+}
+
+func TestCheckDeviceIdentity(t *testing.T) {
+	tests := []struct {
+		name       string
+		sysSerial  string
+		byIDSerial string
+		want       deviceIdentity
+	}{
+		{name: "full uuid in sysfs", sysSerial: testVolumeID, want: deviceIdentityMatch},
+		{name: "truncated uuid in sysfs", sysSerial: testVolumeID[:20], want: deviceIdentityMatch},
+		{name: "truncated compact uuid in by-id", byIDSerial: "5f0b8a3c2d4e4b6a9c1d", want: deviceIdentityMatch},
+		{name: "uppercase serial", sysSerial: "5F0B8A3C-2D4E-4B6A-9C", want: deviceIdentityMatch},
+		{name: "other drive", sysSerial: "9a8b7c6d-5e4f-3a2b-1c", want: deviceIdentityMismatch},
+		{name: "other drive in by-id", byIDSerial: "9a8b7c6d5e4f3a2b1c0d", want: deviceIdentityMismatch},
+		{name: "too short to trust", sysSerial: "5f0b", want: deviceIdentityMismatch},
+		{name: "no serial", want: deviceIdentityUnknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			device := fakeDeviceTree(t, "vdb", tt.sysSerial, tt.byIDSerial)
+
+			got, serial := checkDeviceIdentity(device, testVolumeID)
+			if got != tt.want {
+				t.Errorf("checkDeviceIdentity() = %v (serial %q), want %v", got, serial, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckDeviceIdentity_IgnoresOtherDevicesLinks(t *testing.T) {
+	device := fakeDeviceTree(t, "vdb", "", "")
+
+	// A by-id link for the right volume that points at a different disk must not vouch for vdb
+	other := filepath.Join(filepath.Dir(device), "vdc")
+	if err := os.WriteFile(other, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(other, filepath.Join(devDiskByIDDir, "virtio-"+testVolumeID[:20])); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, _ := checkDeviceIdentity(device, testVolumeID); got != deviceIdentityUnknown {
+		t.Errorf("checkDeviceIdentity() = %v, want unknown", got)
+	}
+}
+
+func TestDriveSerial(t *testing.T) {
+	if got, want := driveSerial(testVolumeID), "5f0b8a3c2d4e4b6a9c1d"; got != want {
+		t.Errorf("driveSerial(%q) = %q, want %q", testVolumeID, got, want)
+	}
+	if got := driveSerial(strings.ToUpper(testVolumeID)); got != driveSerial(testVolumeID) {
+		t.Errorf("driveSerial() of upper-case UUID = %q, want it case-insensitive", got)
+	}
+	if !serialMatchesVolume(driveSerial(testVolumeID), testVolumeID) {
+		t.Error("driveSerial() does not pass the device identity check for its own volume")
+	}
+}
+
+func TestFindPublishedDevice_Serial(t *testing.T) {
+	oldAttempts, oldInterval := serialLinkPollAttempts, serialLinkPollInterval
+	serialLinkPollAttempts, serialLinkPollInterval = 2, time.Millisecond
+	t.Cleanup(func() { serialLinkPollAttempts, serialLinkPollInterval = oldAttempts, oldInterval })
+
+	serial := driveSerial(testVolumeID)
+	device := fakeDeviceTree(t, "vdc", "", serial)
+	// Another attached volume, which must not be picked for testVolumeID
+	other := filepath.Join(filepath.Dir(device), "vdb")
+	if err := os.WriteFile(other, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(other, filepath.Join(devDiskByIDDir, "virtio-"+driveSerial("0a1b2c3d-4e5f-4a6b-8c7d-9e0f1a2b3c4d"))); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := findPublishedDevice(map[string]string{"serial": serial, "volumeId": testVolumeID, "channel": "0:3"}, &sync.Mutex{})
+	if err != nil || got != device {
+		t.Errorf("findPublishedDevice() = %q, %v; want %q", got, err, device)
+	}
+
+	// A drive attached without a serial falls back to the channel lookup
+	_, err = findPublishedDevice(map[string]string{"serial": "ffffffffffffffffffff", "volumeId": testVolumeID}, &sync.Mutex{})
+	if err == nil || !strings.Contains(err.Error(), "channel not found") {
+		t.Errorf("findPublishedDevice() without a serial link = %v, want the channel lookup error", err)
+	}
+}
@@ -0,0 +1,98 @@
+/*
+Copyright 2025 Kube-DC Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/cloudsigma/cloudsigma-sdk-go/cloudsigma"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// volumeNameMetaKey is the drive meta key holding the CSI volume name, which no longer
+	// has to be the drive name
+	volumeNameMetaKey = "csi_volume_name"
+
+	// maxDriveNameLength caps composed drive names
+	maxDriveNameLength = 128
+)
+
+// driveName returns the name of the drive backing volume volumeName. With the PVC metadata the
+// external-provisioner adds with --extra-create-metadata, the drive is named
+// <namespace>-<pvc name> so operators can tell drives apart in CloudSigma; otherwise it keeps
+// the CSI volume name.
+func driveName(volumeName string, params map[string]string) string {
+	name, namespace := params[pvcNameKey], params[pvcNamespaceKey]
+	if name == "" || namespace == "" {
+		return volumeName
+	}
+	composed := sanitizeDriveName(namespace + "-" + name)
+	if composed == "" {
+		return volumeName
+	}
+	return composed
+}
+
+// sanitizeDriveName replaces characters outside letters, digits, '-', '_' and '.' with '-',
+// and trims the result to maxDriveNameLength without leading or trailing separators
+func sanitizeDriveName(name string) string {
+	sanitized := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+			return r
+		default:
+			return '-'
+		}
+	}, name)
+	if len(sanitized) > maxDriveNameLength {
+		sanitized = sanitized[:maxDriveNameLength]
+	}
+	return strings.Trim(sanitized, "-_.")
+}
+
+// findVolumeDrive returns the drive of volume volumeName, or nil if there is none. Drives are
+// found by their volume tag; untagged drives, left by a create that failed before tagging or
+// created by older driver versions, are matched by drive meta or name.
+func (d *Driver) findVolumeDrive(ctx context.Context, volumeName string) (*cloudsigma.Drive, error) {
+	tags, _, err := d.cloud().Tags.List(ctx)
+	if err != nil {
+		klog.Warningf("Failed to list tags, looking up volume %s by drive name: %v", volumeName, err)
+		return d.findDriveByName(ctx, volumeName)
+	}
+
+	tagName := fmt.Sprintf("volume:%s", volumeName)
+	for _, tag := range tags {
+		if tag.Name != tagName {
+			continue
+		}
+		for _, r := range tag.Resources {
+			drive, _, err := d.cloud().Drives.Get(ctx, r.UUID)
+			if err != nil {
+				if isNotFound(err) {
+					continue
+				}
+				return nil, err
+			}
+			return drive, nil
+		}
+	}
+
+	return d.findDriveByName(ctx, volumeName)
+}
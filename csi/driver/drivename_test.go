@@ -0,0 +1,99 @@
+/*
+Copyright 2025 Kube-DC Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/cloudsigma/cloudsigma-sdk-go/cloudsigma"
+	"github.com/container-storage-interface/spec/lib/go/csi"
+
+	"github.com/kube-dc/cluster-api-provider-cloudsigma/pkg/cloud/fake"
+)
+
+func TestDriveName(t *testing.T) {
+	tests := []struct {
+		name   string
+		params map[string]string
+		want   string
+	}{
+		{name: "no metadata", want: "pvc-1"},
+		{name: "name without namespace", params: map[string]string{pvcNameKey: "data"}, want: "pvc-1"},
+		{name: "pvc metadata", params: map[string]string{pvcNameKey: "data", pvcNamespaceKey: "shop"}, want: "shop-data"},
+		{name: "dots kept", params: map[string]string{pvcNameKey: "data.v2", pvcNamespaceKey: "shop"}, want: "shop-data.v2"},
+		{name: "invalid characters replaced", params: map[string]string{pvcNameKey: "da ta/ä", pvcNamespaceKey: "shop"}, want: "shop-da-ta"},
+		{name: "nothing left after sanitizing", params: map[string]string{pvcNameKey: "/", pvcNamespaceKey: "%"}, want: "pvc-1"},
+		{
+			name:   "truncated",
+			params: map[string]string{pvcNameKey: strings.Repeat("a", 200), pvcNamespaceKey: "shop"},
+			want:   "shop-" + strings.Repeat("a", maxDriveNameLength-len("shop-")),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := driveName("pvc-1", tt.params); got != tt.want {
+				t.Errorf("driveName() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCreateVolume_DriveName(t *testing.T) {
+	ctx := context.Background()
+	api := fake.NewServer()
+	defer api.Close()
+
+	d, err := NewDriver(&Config{Name: DriverName, Version: DriverVersion, Mode: ControllerMode, Region: fake.Region, CloudClient: api.NewSDKClient()})
+	if err != nil {
+		t.Fatalf("NewDriver() error = %v", err)
+	}
+	create := func(volumeName string) string {
+		t.Helper()
+		resp, err := d.CreateVolume(ctx, &csi.CreateVolumeRequest{
+			Name:       volumeName,
+			Parameters: map[string]string{pvcNameKey: "data", pvcNamespaceKey: "shop"},
+			VolumeCapabilities: []*csi.VolumeCapability{{
+				AccessType: &csi.VolumeCapability_Mount{Mount: &csi.VolumeCapability_MountVolume{}},
+				AccessMode: &csi.VolumeCapability_AccessMode{Mode: csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER},
+			}},
+		})
+		if err != nil {
+			t.Fatalf("CreateVolume(%s) error = %v", volumeName, err)
+		}
+		return resp.Volume.VolumeId
+	}
+
+	first := create("pvc-1")
+	if drive, _ := api.GetDrive(first); drive.Name != "shop-data" || drive.Meta[volumeNameMetaKey] != "pvc-1" {
+		t.Errorf("drive name = %q, meta = %v; want shop-data with the volume name", drive.Name, drive.Meta)
+	}
+	if again := create("pvc-1"); again != first {
+		t.Errorf("repeated CreateVolume() = %s, want existing drive %s", again, first)
+	}
+	// A PVC recreated under the same name gets its own drive, even though the drive names match
+	if second := create("pvc-2"); second == first {
+		t.Errorf("CreateVolume() for another volume reused drive %s", first)
+	}
+
+	// A drive created before it could be tagged is found by its meta
+	untagged := api.AddDrive(cloudsigma.Drive{Name: "shop-data", Size: DefaultVolumeSize, Meta: map[string]interface{}{volumeNameMetaKey: "pvc-3"}})
+	if got := create("pvc-3"); got != untagged.UUID {
+		t.Errorf("CreateVolume() for an untagged drive = %s, want %s", got, untagged.UUID)
+	}
+}
@@ -18,16 +18,24 @@ package driver
 
 import (
 	"context"
+	"crypto/tls"
+	"fmt"
 	"net"
 	"net/url"
 	"os"
 	"path/filepath"
 	"sync"
+	"time"
 
 	"github.com/cloudsigma/cloudsigma-sdk-go/cloudsigma"
 	"github.com/container-storage-interface/spec/lib/go/csi"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"k8s.io/client-go/kubernetes"
 	"k8s.io/klog/v2"
+
+	"github.com/kube-dc/cluster-api-provider-cloudsigma/pkg/devicechannel"
+	"github.com/kube-dc/cluster-api-provider-cloudsigma/pkg/regions"
 )
 
 const (
@@ -63,8 +71,29 @@ type Driver struct {
 	mode        Mode
 	clusterName string
 
+	// Storage type used when a StorageClass doesn't set the storageType parameter
+	defaultStorageType string
+
+	// Whether DeleteVolume deletes drives or only untags them
+	volumeDeletePolicy string
+
+	// cloudClient and region are replaced by WatchTokenSecret when the CCM rewrites the token
+	// secret, so they are read through cloud and cloudRegion
+	cloudMu     sync.RWMutex
 	cloudClient *cloudsigma.Client
 
+	// newCloudClient builds a token-authenticated client for a region (default: newTokenClient)
+	newCloudClient func(token, region string) *cloudsigma.Client
+
+	// Optional Kubernetes client used to annotate Nodes with attached drives
+	kubeClient kubernetes.Interface
+
+	// Names of Nodes found by providerID rather than server name, keyed by server UUID
+	serverNodeNames sync.Map
+
+	// volumeEvents records controller failures as events on the PVC or PV
+	volumeEvents bool
+
 	srv *grpc.Server
 
 	// CSI capability flags
@@ -76,8 +105,33 @@ type Driver struct {
 	serverAttachMu    sync.Mutex
 	serverAttachLocks map[string]*sync.Mutex
 
-	// Mutex for serializing device discovery on node to prevent race conditions
+	// Short-lived server snapshots shared by publish/unpublish
+	serverCache *serverCache
+
+	// Order device channels are handed out in when attaching volumes
+	channelPolicy devicechannel.Policy
+
+	// Mutex for serializing channel-based device discovery on node to prevent race conditions
 	nodeDeviceMu sync.Mutex
+
+	// Volumes a node stage operation is running for
+	volumeLocks volumeLocks
+
+	// Names of the mount options the node passes to mount
+	allowedMountOptions map[string]bool
+
+	// Whether NodeStageVolume checks formatted volumes with fsck when their StorageClass doesn't
+	// say, and how long a check may take
+	fsck        bool
+	fsckTimeout time.Duration
+
+	// Detach verification and escalation in ControllerUnpublishVolume
+	detachPollAttempts int
+	detachPollInterval time.Duration
+	detachEscalation   bool
+
+	// tlsConfig secures TCP endpoints, nil when no certificate is configured
+	tlsConfig *tls.Config
 }
 
 // Config holds the driver configuration
@@ -94,6 +148,27 @@ type Config struct {
 	CloudSigmaToken    string // OAuth access token (preferred)
 	TokenFile          string // Path to token file (refreshed by CCM)
 	ClusterName        string // Cluster name for tagging drives
+	DefaultStorageType string // Storage type for volumes without a storageType parameter (default dssd)
+	ChannelPolicy      string // Device channel layout for attached volumes (default "default", see devicechannel)
+	VolumeDeletePolicy string // delete or retain; retain only untags drives in DeleteVolume (default delete)
+
+	AllowedMountOptions []string // Mount options volumes may use, by name (default DefaultAllowedMountOptions)
+
+	Fsck        bool          // Check formatted volumes with fsck before mounting unless their StorageClass sets fsck
+	FsckTimeout time.Duration // How long a filesystem check may run (default DefaultFsckTimeout)
+
+	KubeClient   kubernetes.Interface // Optional, enables Node attachment annotations and events
+	VolumeEvents bool                 // Record create, delete and attach failures on the PVC or PV; needs KubeClient
+
+	CloudClient *cloudsigma.Client // Optional, used instead of building a client from the credentials above
+
+	DetachPollAttempts      int           // Drive status checks after a detach (default 30)
+	DetachPollInterval      time.Duration // Delay between detach status checks (default 1s)
+	DisableDetachEscalation bool          // Don't force a second detach when verification times out
+
+	TLSCertFile     string // Server certificate for TCP endpoints; unix sockets never use TLS
+	TLSKeyFile      string // Key of TLSCertFile
+	TLSClientCAFile string // Optional CA client certificates must be signed by (mTLS)
 }
 
 // NewDriver creates a new CloudSigma CSI driver
@@ -103,33 +178,97 @@ func NewDriver(cfg *Config) (*Driver, error) {
 
 	// Create CloudSigma client
 	var cloudClient *cloudsigma.Client
+	var locate serverLocator
 	region := cfg.Region
 	if region == "" {
 		region = "zrh"
 	}
 
-	// Token-based auth takes priority (recommended for CCM-managed credentials)
-	if cfg.CloudSigmaToken != "" {
+	// A preconfigured client wins; otherwise token-based auth takes priority (recommended for
+	// CCM-managed credentials)
+	if cfg.CloudClient != nil {
+		cloudClient = cfg.CloudClient
+		klog.Info("Using preconfigured CloudSigma client")
+	} else if cfg.CloudSigmaToken != "" {
 		cred := cloudsigma.NewTokenCredentialsProvider(cfg.CloudSigmaToken)
-		cloudClient = cloudsigma.NewClient(cred, cloudsigma.WithLocation(region))
+		cloudClient = regions.NewSDKClient(cred, regions.Lookup(region).APIHost)
+		locate = sdkServerLocator(cred)
 		klog.Infof("CloudSigma client initialized with token auth for region: %s", region)
 	} else if cfg.CloudSigmaUsername != "" && cfg.CloudSigmaPassword != "" {
 		// Legacy username/password auth
 		cred := cloudsigma.NewUsernamePasswordCredentialsProvider(cfg.CloudSigmaUsername, cfg.CloudSigmaPassword)
-		cloudClient = cloudsigma.NewClient(cred, cloudsigma.WithLocation(region))
+		cloudClient = regions.NewSDKClient(cred, regions.Lookup(region).APIHost)
+		locate = sdkServerLocator(cred)
 		klog.Infof("CloudSigma client initialized with username/password auth for region: %s", region)
 	}
 
 	driver := &Driver{
-		name:              cfg.Name,
-		version:           cfg.Version,
-		nodeID:            cfg.NodeID,
-		region:            cfg.Region,
-		endpoint:          cfg.Endpoint,
-		mode:              cfg.Mode,
-		clusterName:       cfg.ClusterName,
-		cloudClient:       cloudClient,
-		serverAttachLocks: make(map[string]*sync.Mutex),
+		name:               cfg.Name,
+		version:            cfg.Version,
+		nodeID:             cfg.NodeID,
+		region:             cfg.Region,
+		endpoint:           cfg.Endpoint,
+		mode:               cfg.Mode,
+		clusterName:        cfg.ClusterName,
+		defaultStorageType: cfg.DefaultStorageType,
+		volumeDeletePolicy: cfg.VolumeDeletePolicy,
+		cloudClient:        cloudClient,
+		kubeClient:         cfg.KubeClient,
+		volumeEvents:       cfg.VolumeEvents,
+		serverAttachLocks:  make(map[string]*sync.Mutex),
+		serverCache:        newServerCache(defaultServerCacheTTL),
+		detachPollAttempts: cfg.DetachPollAttempts,
+		detachPollInterval: cfg.DetachPollInterval,
+		detachEscalation:   !cfg.DisableDetachEscalation,
+		fsck:               cfg.Fsck,
+		fsckTimeout:        cfg.FsckTimeout,
+	}
+	if driver.defaultStorageType == "" {
+		driver.defaultStorageType = StorageTypeDSSD
+	}
+	if err := validateStorageType(driver.defaultStorageType); err != nil {
+		return nil, fmt.Errorf("invalid default storage type: %w", err)
+	}
+	if driver.volumeDeletePolicy == "" {
+		driver.volumeDeletePolicy = VolumeDeletePolicyDelete
+	}
+	if err := validateVolumeDeletePolicy(driver.volumeDeletePolicy); err != nil {
+		return nil, err
+	}
+	if driver.volumeDeletePolicy == VolumeDeletePolicyRetain {
+		klog.Info("Volume delete policy is retain, DeleteVolume keeps drives in CloudSigma")
+	}
+	driver.allowedMountOptions = mountOptionSet(cfg.AllowedMountOptions)
+	// The node reports the region its server really runs in, which the configured one may not be
+	if cfg.Mode != ControllerMode && cfg.NodeID != "" && locate != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), nodeLocationTimeout)
+		driver.region = locateNode(ctx, locate, cfg.NodeID, cfg.Region)
+		cancel()
+	}
+	channelPolicy, err := devicechannel.PolicyByName(cfg.ChannelPolicy)
+	if err != nil {
+		return nil, err
+	}
+	driver.channelPolicy = channelPolicy
+	if driver.volumeEvents && driver.kubeClient == nil {
+		klog.Warning("Volume events need a Kubernetes client, not recording them")
+		driver.volumeEvents = false
+	}
+	if driver.detachPollAttempts <= 0 {
+		driver.detachPollAttempts = defaultDetachPollAttempts
+	}
+	if driver.detachPollInterval <= 0 {
+		driver.detachPollInterval = defaultDetachPollInterval
+	}
+	if driver.fsckTimeout <= 0 {
+		driver.fsckTimeout = DefaultFsckTimeout
+	}
+	if cfg.TLSCertFile != "" || cfg.TLSKeyFile != "" || cfg.TLSClientCAFile != "" {
+		tlsConfig, err := loadServerTLS(cfg.TLSCertFile, cfg.TLSKeyFile, cfg.TLSClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("invalid TLS configuration: %w", err)
+		}
+		driver.tlsConfig = tlsConfig
 	}
 
 	// Set controller capabilities
@@ -137,7 +276,6 @@ func NewDriver(cfg *Config) (*Driver, error) {
 		csi.ControllerServiceCapability_RPC_CREATE_DELETE_VOLUME,
 		csi.ControllerServiceCapability_RPC_PUBLISH_UNPUBLISH_VOLUME,
 		csi.ControllerServiceCapability_RPC_EXPAND_VOLUME,
-		csi.ControllerServiceCapability_RPC_CREATE_DELETE_SNAPSHOT,
 	}
 
 	// Set node capabilities
@@ -156,39 +294,69 @@ func NewDriver(cfg *Config) (*Driver, error) {
 	return driver, nil
 }
 
-// Run starts the CSI driver gRPC server
+// cloud returns the current CloudSigma client
+func (d *Driver) cloud() *cloudsigma.Client {
+	d.cloudMu.RLock()
+	defer d.cloudMu.RUnlock()
+	return d.cloudClient
+}
+
+// cloudRegion returns the region of the current CloudSigma client
+func (d *Driver) cloudRegion() string {
+	d.cloudMu.RLock()
+	defer d.cloudMu.RUnlock()
+	return d.region
+}
+
+// newTokenClient creates a client authenticating with token against the API of region
+func newTokenClient(token, region string) *cloudsigma.Client {
+	return regions.NewSDKClient(cloudsigma.NewTokenCredentialsProvider(token), regions.Lookup(region).APIHost)
+}
+
+// Run starts the driver
 func (d *Driver) Run() error {
-	u, err := url.Parse(d.endpoint)
+	listener, err := d.listen()
 	if err != nil {
 		return err
 	}
+	return d.serve(listener)
+}
+
+// listen opens the driver's unix socket or TCP endpoint
+func (d *Driver) listen() (net.Listener, error) {
+	u, err := url.Parse(d.endpoint)
+	if err != nil {
+		return nil, err
+	}
 
-	var listener net.Listener
 	if u.Scheme == "unix" {
 		socketPath := u.Path
 		// Remove existing socket file
 		if err := os.Remove(socketPath); err != nil && !os.IsNotExist(err) {
-			return err
+			return nil, err
 		}
 		// Create parent directory if needed
 		if err := os.MkdirAll(filepath.Dir(socketPath), 0755); err != nil {
-			return err
-		}
-		listener, err = net.Listen("unix", socketPath)
-		if err != nil {
-			return err
-		}
-	} else {
-		listener, err = net.Listen("tcp", u.Host)
-		if err != nil {
-			return err
+			return nil, err
 		}
+		return net.Listen("unix", socketPath)
 	}
+	return net.Listen("tcp", u.Host)
+}
 
+// serve runs the gRPC server on listener until it is stopped. TCP endpoints use TLS when a
+// certificate is configured.
+func (d *Driver) serve(listener net.Listener) error {
 	// Create gRPC server with logging interceptor
-	d.srv = grpc.NewServer(
-		grpc.UnaryInterceptor(loggingInterceptor),
-	)
+	opts := []grpc.ServerOption{grpc.UnaryInterceptor(loggingInterceptor)}
+	if listener.Addr().Network() == "tcp" {
+		if d.tlsConfig != nil {
+			opts = append(opts, grpc.Creds(credentials.NewTLS(d.tlsConfig)))
+		} else {
+			klog.Warningf("CSI endpoint %s is plain TCP without transport security, set a TLS certificate and key", d.endpoint)
+		}
+	}
+	d.srv = grpc.NewServer(opts...)
 
 	// Register CSI services based on mode
 	csi.RegisterIdentityServer(d.srv, d)
@@ -203,7 +371,7 @@ func (d *Driver) Run() error {
 		csi.RegisterNodeServer(d.srv, d)
 	}
 
-	klog.Infof("Starting CSI driver server at %s", d.endpoint)
+	klog.Infof("Starting CSI driver server at %s (tls=%v)", d.endpoint, d.tlsConfig != nil && listener.Addr().Network() == "tcp")
 	return d.srv.Serve(listener)
 }
 
@@ -0,0 +1,115 @@
+/*
+Copyright 2025 Kube-DC Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// EventReasonVolumeCreateFailed is recorded on the PVC when CreateVolume fails
+	EventReasonVolumeCreateFailed = "VolumeCreateFailed"
+
+	// EventReasonVolumeDeleteFailed is recorded on the PV when DeleteVolume fails
+	EventReasonVolumeDeleteFailed = "VolumeDeleteFailed"
+
+	// EventReasonVolumeAttachFailed is recorded on the PVC when ControllerPublishVolume fails
+	EventReasonVolumeAttachFailed = "VolumeAttachFailed"
+)
+
+// Parameters the external-provisioner adds with --extra-create-metadata. CreateVolume returns
+// its parameters as the volume context, so they also reach ControllerPublishVolume.
+const (
+	pvcNameKey      = "csi.storage.k8s.io/pvc/name"
+	pvcNamespaceKey = "csi.storage.k8s.io/pvc/namespace"
+)
+
+// recordPVCEvent records a warning on the PVC named in a volume's parameters or context. It is
+// best effort and a no-op unless volume events are enabled and the PVC is known.
+func (d *Driver) recordPVCEvent(ctx context.Context, volumeContext map[string]string, reason, message string) {
+	name, namespace := volumeContext[pvcNameKey], volumeContext[pvcNamespaceKey]
+	if !d.volumeEvents || name == "" || namespace == "" {
+		return
+	}
+
+	pvc, err := d.kubeClient.CoreV1().PersistentVolumeClaims(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		klog.V(4).Infof("Failed to get PVC %s/%s, skipping %s event: %v", namespace, name, reason, err)
+		return
+	}
+	d.createEvent(ctx, corev1.ObjectReference{
+		Kind:      "PersistentVolumeClaim",
+		Namespace: pvc.Namespace,
+		Name:      pvc.Name,
+		UID:       pvc.UID,
+	}, corev1.EventTypeWarning, reason, message)
+}
+
+// recordPVEvent records a warning on the PV of this driver whose volume handle is volumeID. It is
+// best effort and a no-op unless volume events are enabled.
+func (d *Driver) recordPVEvent(ctx context.Context, volumeID, reason, message string) {
+	if !d.volumeEvents || volumeID == "" {
+		return
+	}
+
+	pvs, err := d.kubeClient.CoreV1().PersistentVolumes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		klog.V(4).Infof("Failed to list PVs, skipping %s event: %v", reason, err)
+		return
+	}
+	for _, pv := range pvs.Items {
+		if pv.Spec.CSI != nil && pv.Spec.CSI.Driver == d.name && pv.Spec.CSI.VolumeHandle == volumeID {
+			d.createEvent(ctx, corev1.ObjectReference{Kind: "PersistentVolume", Name: pv.Name, UID: pv.UID},
+				corev1.EventTypeWarning, reason, message)
+			return
+		}
+	}
+	klog.V(4).Infof("No PV found for volume %s, skipping %s event", volumeID, reason)
+}
+
+// createEvent records an event on the referenced object, in its namespace or, for cluster-scoped
+// objects, in the default namespace
+func (d *Driver) createEvent(ctx context.Context, ref corev1.ObjectReference, eventType, reason, message string) {
+	namespace := ref.Namespace
+	if namespace == "" {
+		namespace = metav1.NamespaceDefault
+	}
+
+	now := metav1.Now()
+	event := &corev1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("%s.%x", ref.Name, now.UnixNano()),
+			Namespace: namespace,
+		},
+		InvolvedObject: ref,
+		Reason:         reason,
+		Message:        message,
+		Type:           eventType,
+		Source:         corev1.EventSource{Component: d.name},
+		FirstTimestamp: now,
+		LastTimestamp:  now,
+		Count:          1,
+	}
+	if _, err := d.kubeClient.CoreV1().Events(namespace).Create(ctx, event, metav1.CreateOptions{}); err != nil {
+		klog.Warningf("Failed to record %s event for %s %s: %v", reason, ref.Kind, ref.Name, err)
+	}
+}
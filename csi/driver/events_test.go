@@ -0,0 +1,149 @@
+/*
+Copyright 2025 Kube-DC Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/cloudsigma/cloudsigma-sdk-go/cloudsigma"
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestCreateVolume_RecordsPVCEvent(t *testing.T) {
+	pvcParams := map[string]string{
+		"storageType":   StorageTypeDSSD,
+		pvcNameKey:      "data",
+		pvcNamespaceKey: "team-a",
+	}
+
+	tests := []struct {
+		name         string
+		volumeEvents bool
+		params       map[string]string
+		wantEvent    bool
+	}{
+		{name: "enabled", volumeEvents: true, params: pvcParams, wantEvent: true},
+		{name: "disabled", volumeEvents: false, params: pvcParams},
+		{name: "no PVC metadata", volumeEvents: true, params: map[string]string{"storageType": StorageTypeDSSD}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mux := http.NewServeMux()
+			mux.HandleFunc("/api/2.0/drives/detail/", func(w http.ResponseWriter, r *http.Request) {
+				writeJSON(w, map[string]interface{}{"objects": []cloudsigma.Drive{}})
+			})
+			mux.HandleFunc("/api/2.0/drives/", func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusForbidden)
+				writeJSON(w, []cloudsigma.Error{{Message: "Not enough dssd storage in subscription"}})
+			})
+
+			d := newTestDriver(t, mux)
+			kubeClient := fake.NewSimpleClientset(&corev1.PersistentVolumeClaim{
+				ObjectMeta: metav1.ObjectMeta{Name: "data", Namespace: "team-a", UID: "pvc-uid"},
+			})
+			d.kubeClient = kubeClient
+			d.volumeEvents = tt.volumeEvents
+
+			_, err := d.CreateVolume(context.Background(), &csi.CreateVolumeRequest{
+				Name:               "pvc-0a1b2c",
+				VolumeCapabilities: []*csi.VolumeCapability{mountCapability()},
+				Parameters:         tt.params,
+			})
+			if err == nil {
+				t.Fatal("CreateVolume() succeeded, want an error")
+			}
+
+			events, err := kubeClient.CoreV1().Events("team-a").List(context.Background(), metav1.ListOptions{})
+			if err != nil {
+				t.Fatalf("failed to list events: %v", err)
+			}
+			if !tt.wantEvent {
+				if len(events.Items) != 0 {
+					t.Errorf("recorded events %+v, want none", events.Items)
+				}
+				return
+			}
+			if len(events.Items) != 1 {
+				t.Fatalf("recorded %d events, want 1", len(events.Items))
+			}
+			event := events.Items[0]
+			if event.Reason != EventReasonVolumeCreateFailed || event.Type != corev1.EventTypeWarning {
+				t.Errorf("event = %s/%s, want %s/%s", event.Type, event.Reason, corev1.EventTypeWarning, EventReasonVolumeCreateFailed)
+			}
+			if ref := event.InvolvedObject; ref.Kind != "PersistentVolumeClaim" || ref.Name != "data" || ref.UID != "pvc-uid" {
+				t.Errorf("event involves %+v, want PVC team-a/data", ref)
+			}
+			if !strings.Contains(event.Message, "Not enough dssd storage") {
+				t.Errorf("event message %q does not carry the CloudSigma error", event.Message)
+			}
+		})
+	}
+}
+
+func TestDeleteVolume_RecordsPVEvent(t *testing.T) {
+	const volumeID = "vol-1"
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/2.0/drives/"+volumeID+"/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		writeJSON(w, []cloudsigma.Error{{Message: "Permission denied"}})
+	})
+
+	d := newTestDriver(t, mux)
+	kubeClient := fake.NewSimpleClientset(
+		&corev1.PersistentVolume{
+			ObjectMeta: metav1.ObjectMeta{Name: "pvc-other"},
+			Spec: corev1.PersistentVolumeSpec{PersistentVolumeSource: corev1.PersistentVolumeSource{
+				CSI: &corev1.CSIPersistentVolumeSource{Driver: DriverName, VolumeHandle: "vol-2"},
+			}},
+		},
+		&corev1.PersistentVolume{
+			ObjectMeta: metav1.ObjectMeta{Name: "pvc-0a1b2c", UID: "pv-uid"},
+			Spec: corev1.PersistentVolumeSpec{PersistentVolumeSource: corev1.PersistentVolumeSource{
+				CSI: &corev1.CSIPersistentVolumeSource{Driver: DriverName, VolumeHandle: volumeID},
+			}},
+		},
+	)
+	d.kubeClient = kubeClient
+	d.volumeEvents = true
+
+	if _, err := d.DeleteVolume(context.Background(), &csi.DeleteVolumeRequest{VolumeId: volumeID}); err == nil {
+		t.Fatal("DeleteVolume() succeeded, want an error")
+	}
+
+	events, err := kubeClient.CoreV1().Events(metav1.NamespaceDefault).List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		t.Fatalf("failed to list events: %v", err)
+	}
+	if len(events.Items) != 1 {
+		t.Fatalf("recorded %d events, want 1", len(events.Items))
+	}
+	event := events.Items[0]
+	if event.Reason != EventReasonVolumeDeleteFailed {
+		t.Errorf("event reason = %s, want %s", event.Reason, EventReasonVolumeDeleteFailed)
+	}
+	if ref := event.InvolvedObject; ref.Kind != "PersistentVolume" || ref.Name != "pvc-0a1b2c" || ref.UID != "pv-uid" {
+		t.Errorf("event involves %+v, want PV pvc-0a1b2c", ref)
+	}
+}
@@ -0,0 +1,173 @@
+/*
+Copyright 2025 Kube-DC Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cloudsigma/cloudsigma-sdk-go/cloudsigma"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"k8s.io/klog/v2"
+)
+
+// sectorSize is the unit of /sys/block/<dev>/size, independent of the device's logical block size
+const sectorSize = 512
+
+// Node-side expansion steps (variables so tests can replace the parts that need a real host)
+var (
+	mountDeviceLookup = getDeviceFromMountPoint
+	filesystemResizer = resizeFilesystem
+	deviceRescanner   = rescanDevice
+)
+
+// How long NodeExpandVolume waits for the kernel to report a grown drive's new size
+var (
+	deviceSizePollAttempts = 10
+	deviceSizePollInterval = 500 * time.Millisecond
+)
+
+// offlineResizeHint tells the user how to get a drive into a state CloudSigma can resize
+const offlineResizeHint = "CloudSigma cannot resize a drive mounted on a running server, " +
+	"stop the pods using the volume so it is detached and the resize is retried"
+
+// checkDriveResizable returns FailedPrecondition when the drive is mounted on a server that isn't
+// stopped. CloudSigma rejects resizing such drives with 403, so there is no online expansion.
+// Servers that no longer exist are ignored.
+func (d *Driver) checkDriveResizable(ctx context.Context, drive *cloudsigma.Drive) error {
+	for _, mount := range drive.MountedOn {
+		server, _, err := d.cloud().Servers.Get(ctx, mount.UUID)
+		if err != nil {
+			if isNotFound(err) {
+				continue
+			}
+			return status.Errorf(codes.Internal, "failed to get server %s of volume %s: %v", mount.UUID, drive.UUID, err)
+		}
+		if server.Status != "stopped" {
+			return status.Errorf(codes.FailedPrecondition, "volume %s is attached to server %s (%s); %s",
+				drive.UUID, mount.UUID, server.Status, offlineResizeHint)
+		}
+	}
+	return nil
+}
+
+// isNotFound reports whether err is a 404 response from the CloudSigma API. The status code is
+// checked rather than the error text, which contains the request URL and so may contain "404" too.
+func isNotFound(err error) bool {
+	var sdkErr *cloudsigma.ErrorResponse
+	return errors.As(err, &sdkErr) && sdkErr.Response != nil && sdkErr.Response.StatusCode == http.StatusNotFound
+}
+
+// isForbidden reports whether err is a 403 response from the CloudSigma API
+func isForbidden(err error) bool {
+	var sdkErr *cloudsigma.ErrorResponse
+	return errors.As(err, &sdkErr) && sdkErr.Response != nil && sdkErr.Response.StatusCode == http.StatusForbidden
+}
+
+// findVolumeDevice returns the block device whose /dev/disk/by-id/virtio-<serial> link
+// identifies volumeID, or "" if the drive is not visible on this node
+func findVolumeDevice(volumeID string) (string, error) {
+	entries, err := filepath.Glob(filepath.Join(devDiskByIDDir, "virtio-*"))
+	if err != nil {
+		return "", err
+	}
+	for _, entry := range entries {
+		if strings.Contains(entry, "-part") {
+			continue
+		}
+		if !serialMatchesVolume(strings.TrimPrefix(filepath.Base(entry), "virtio-"), volumeID) {
+			continue
+		}
+		return filepath.EvalSymlinks(entry)
+	}
+	return "", nil
+}
+
+// rescanDevice asks the kernel to re-read the capacity of a block device. SCSI disks expose
+// /sys/block/<dev>/device/rescan; virtio-blk picks up the new size on its own and has no such
+// file, in which case this is a no-op.
+func rescanDevice(devicePath string) error {
+	rescan := filepath.Join(sysBlockDir, blockDeviceName(devicePath), "device", "rescan")
+	if _, err := os.Stat(rescan); os.IsNotExist(err) {
+		klog.V(4).Infof("No rescan trigger for %s, relying on the driver to report the new size", devicePath)
+		return nil
+	}
+	if err := os.WriteFile(rescan, []byte("1"), 0o200); err != nil {
+		return fmt.Errorf("failed to rescan %s: %w", devicePath, err)
+	}
+	return nil
+}
+
+// deviceSizeBytes returns the current capacity of a block device as reported by sysfs
+func deviceSizeBytes(devicePath string) (int64, error) {
+	data, err := os.ReadFile(filepath.Join(sysBlockDir, blockDeviceName(devicePath), "size"))
+	if err != nil {
+		return 0, err
+	}
+	sectors, err := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid size for %s: %w", devicePath, err)
+	}
+	return sectors * sectorSize, nil
+}
+
+// waitForDeviceSize polls the size of a block device until it is at least want bytes and returns
+// the observed size. With want <= 0 the current size is returned as is.
+func waitForDeviceSize(devicePath string, want int64) (int64, error) {
+	var size int64
+	var err error
+	for attempt := 0; attempt < deviceSizePollAttempts; attempt++ {
+		if attempt > 0 {
+			time.Sleep(deviceSizePollInterval)
+		}
+		size, err = deviceSizeBytes(devicePath)
+		if err != nil {
+			continue
+		}
+		if size >= want {
+			return size, nil
+		}
+		klog.V(4).Infof("Device %s is %d bytes, waiting for %d", devicePath, size, want)
+	}
+	if err != nil {
+		return 0, err
+	}
+	return size, fmt.Errorf("device %s still reports %d bytes, expected at least %d", devicePath, size, want)
+}
+
+// blockDeviceName returns the kernel name (e.g. "vdb") of a device path or symlink to it
+func blockDeviceName(devicePath string) string {
+	if resolved, err := filepath.EvalSymlinks(devicePath); err == nil {
+		devicePath = resolved
+	}
+	return filepath.Base(devicePath)
+}
+
+// isBlockVolumePath reports whether a published volume path is a raw block device target.
+// Block volumes are bind mounted onto a file, filesystem volumes onto a directory.
+func isBlockVolumePath(volumePath string) bool {
+	info, err := os.Stat(volumePath)
+	return err == nil && !info.IsDir()
+}
@@ -0,0 +1,275 @@
+/*
+Copyright 2025 Kube-DC Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cloudsigma/cloudsigma-sdk-go/cloudsigma"
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestNodeExpandVolume(t *testing.T) {
+	const sizeBytes = 20 << 30
+
+	blockCap := &csi.VolumeCapability{AccessType: &csi.VolumeCapability_Block{Block: &csi.VolumeCapability_BlockVolume{}}}
+	mountCap := &csi.VolumeCapability{AccessType: &csi.VolumeCapability_Mount{Mount: &csi.VolumeCapability_MountVolume{}}}
+
+	tests := []struct {
+		name       string
+		capability *csi.VolumeCapability
+		fileTarget bool // publish target is a file, as for block volumes
+		byIDSerial string
+		rescanFile bool
+		wantResize bool
+		wantCode   codes.Code
+	}{
+		{name: "block volume", capability: blockCap, byIDSerial: testVolumeID[:20]},
+		{name: "block volume with rescan trigger", capability: blockCap, byIDSerial: testVolumeID[:20], rescanFile: true},
+		{name: "block volume detected from target", fileTarget: true, byIDSerial: testVolumeID[:20]},
+		{name: "block volume without device", capability: blockCap, wantCode: codes.NotFound},
+		{name: "filesystem volume", capability: mountCap, wantResize: true},
+		{name: "filesystem detected from target", wantResize: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			device := fakeDeviceTree(t, "vdb", "", tt.byIDSerial)
+			sysDev := filepath.Join(sysBlockDir, "vdb")
+			if err := os.WriteFile(filepath.Join(sysDev, "size"), []byte("41943040\n"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			rescan := filepath.Join(sysDev, "device", "rescan")
+			if tt.rescanFile {
+				if err := os.MkdirAll(filepath.Dir(rescan), 0o755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(rescan, nil, 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			volumePath := filepath.Join(t.TempDir(), "target")
+			if tt.fileTarget {
+				if err := os.WriteFile(volumePath, nil, 0o600); err != nil {
+					t.Fatal(err)
+				}
+			} else if err := os.Mkdir(volumePath, 0o750); err != nil {
+				t.Fatal(err)
+			}
+
+			resized := false
+			oldLookup, oldResizer := mountDeviceLookup, filesystemResizer
+			t.Cleanup(func() { mountDeviceLookup, filesystemResizer = oldLookup, oldResizer })
+			mountDeviceLookup = func(mountPoint string) (string, error) {
+				if mountPoint != volumePath {
+					return "", errors.New("unexpected mount point")
+				}
+				return device, nil
+			}
+			filesystemResizer = func(devicePath, mountPoint string) error {
+				resized = true
+				return nil
+			}
+
+			d := &Driver{}
+			resp, err := d.NodeExpandVolume(context.Background(), &csi.NodeExpandVolumeRequest{
+				VolumeId:         testVolumeID,
+				VolumePath:       volumePath,
+				VolumeCapability: tt.capability,
+				CapacityRange:    &csi.CapacityRange{RequiredBytes: 10 << 30},
+			})
+			if tt.wantCode != codes.OK {
+				if status.Code(err) != tt.wantCode {
+					t.Fatalf("NodeExpandVolume() error = %v, want code %s", err, tt.wantCode)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NodeExpandVolume() error = %v", err)
+			}
+			if resized != tt.wantResize {
+				t.Errorf("filesystem resized = %v, want %v", resized, tt.wantResize)
+			}
+			if resp.CapacityBytes != sizeBytes {
+				t.Errorf("CapacityBytes = %d, want observed %d", resp.CapacityBytes, int64(sizeBytes))
+			}
+			if tt.rescanFile {
+				if data, _ := os.ReadFile(rescan); string(data) != "1" {
+					t.Errorf("rescan trigger = %q, want \"1\"", data)
+				}
+			}
+		})
+	}
+}
+
+func TestNodeExpandVolume_RescanThenGrow(t *testing.T) {
+	const oldSectors, newSectors = "20971520\n", "41943040\n"
+
+	tests := []struct {
+		name       string
+		rescanSize string // size the kernel reports after the rescan
+		wantSteps  []string
+		wantCode   codes.Code
+	}{
+		{name: "new size visible after rescan", rescanSize: newSectors, wantSteps: []string{"rescan", "resize"}},
+		{name: "new size never appears", rescanSize: oldSectors, wantSteps: []string{"rescan"}, wantCode: codes.Internal},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			device := fakeDeviceTree(t, "vdb", "", "")
+			sizeFile := filepath.Join(sysBlockDir, "vdb", "size")
+			if err := os.WriteFile(sizeFile, []byte(oldSectors), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			volumePath := t.TempDir()
+
+			var steps []string
+			oldLookup, oldResizer, oldRescanner := mountDeviceLookup, filesystemResizer, deviceRescanner
+			oldAttempts, oldInterval := deviceSizePollAttempts, deviceSizePollInterval
+			t.Cleanup(func() {
+				mountDeviceLookup, filesystemResizer, deviceRescanner = oldLookup, oldResizer, oldRescanner
+				deviceSizePollAttempts, deviceSizePollInterval = oldAttempts, oldInterval
+			})
+			deviceSizePollAttempts, deviceSizePollInterval = 3, 0
+			mountDeviceLookup = func(string) (string, error) { return device, nil }
+			deviceRescanner = func(devicePath string) error {
+				steps = append(steps, "rescan")
+				return os.WriteFile(sizeFile, []byte(tt.rescanSize), 0o644)
+			}
+			filesystemResizer = func(devicePath, mountPoint string) error {
+				if size, _ := deviceSizeBytes(devicePath); size < 20<<30 {
+					t.Errorf("filesystem grown while device reports %d bytes", size)
+				}
+				steps = append(steps, "resize")
+				return nil
+			}
+
+			d := &Driver{}
+			resp, err := d.NodeExpandVolume(context.Background(), &csi.NodeExpandVolumeRequest{
+				VolumeId:      testVolumeID,
+				VolumePath:    volumePath,
+				CapacityRange: &csi.CapacityRange{RequiredBytes: 20 << 30},
+			})
+			if status.Code(err) != tt.wantCode {
+				t.Fatalf("NodeExpandVolume() error = %v, want code %s", err, tt.wantCode)
+			}
+			if len(steps) != len(tt.wantSteps) {
+				t.Fatalf("steps = %v, want %v", steps, tt.wantSteps)
+			}
+			for i := range steps {
+				if steps[i] != tt.wantSteps[i] {
+					t.Errorf("steps = %v, want %v", steps, tt.wantSteps)
+					break
+				}
+			}
+			if err == nil && resp.CapacityBytes != 20<<30 {
+				t.Errorf("CapacityBytes = %d, want %d", resp.CapacityBytes, int64(20<<30))
+			}
+		})
+	}
+}
+
+func TestControllerExpandVolume_AttachedDrive(t *testing.T) {
+	const (
+		volumeID = "vol-1"
+		serverID = "server-1"
+		oldSize  = 10 << 30
+		newSize  = 20 << 30
+	)
+
+	tests := []struct {
+		name         string
+		driveStatus  string
+		driveSize    int
+		serverStatus string // "" when the server no longer exists
+		resizeStatus int    // HTTP status of the resize call, 0 for success
+		wantCode     codes.Code
+		wantResize   bool
+		wantCapacity int64
+	}{
+		{name: "detached drive", driveStatus: "unmounted", driveSize: oldSize, wantResize: true, wantCapacity: newSize},
+		{name: "attached to running server", driveStatus: "mounted", driveSize: oldSize, serverStatus: "running", wantCode: codes.FailedPrecondition},
+		{name: "attached to server starting", driveStatus: "mounted", driveSize: oldSize, serverStatus: "starting", wantCode: codes.FailedPrecondition},
+		{name: "attached to stopped server", driveStatus: "mounted", driveSize: oldSize, serverStatus: "stopped", wantResize: true, wantCapacity: newSize},
+		{name: "attached to deleted server", driveStatus: "mounted", driveSize: oldSize, wantResize: true, wantCapacity: newSize},
+		{name: "already expanded while attached", driveStatus: "mounted", driveSize: newSize, serverStatus: "running", wantCapacity: newSize},
+		{name: "attached after the check", driveStatus: "unmounted", driveSize: oldSize, resizeStatus: http.StatusForbidden, wantCode: codes.FailedPrecondition, wantResize: true},
+		{name: "resize fails", driveStatus: "unmounted", driveSize: oldSize, resizeStatus: http.StatusInternalServerError, wantCode: codes.Internal, wantResize: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			drive := cloudsigma.Drive{UUID: volumeID, Name: "pvc-1", Media: "disk", Status: tt.driveStatus, Size: tt.driveSize}
+			if tt.driveStatus == "mounted" {
+				drive.MountedOn = []cloudsigma.ResourceLink{{UUID: serverID}}
+			}
+
+			resized := false
+			mux := http.NewServeMux()
+			mux.HandleFunc("/api/2.0/drives/"+volumeID+"/", func(w http.ResponseWriter, r *http.Request) {
+				if !strings.HasSuffix(r.URL.Path, "/action/") {
+					writeJSON(w, drive)
+					return
+				}
+				resized = true
+				if tt.resizeStatus != 0 {
+					w.WriteHeader(tt.resizeStatus)
+					writeJSON(w, []cloudsigma.Error{{Message: "Cannot resize drive mounted on a running guest"}})
+					return
+				}
+				drive.Size = newSize
+				writeJSON(w, drive)
+			})
+			mux.HandleFunc("/api/2.0/servers/"+serverID+"/", func(w http.ResponseWriter, r *http.Request) {
+				if tt.serverStatus == "" {
+					w.WriteHeader(http.StatusNotFound)
+					writeJSON(w, []cloudsigma.Error{{Message: "Object not found"}})
+					return
+				}
+				writeJSON(w, cloudsigma.Server{UUID: serverID, Status: tt.serverStatus})
+			})
+
+			d := newTestDriver(t, mux)
+			resp, err := d.ControllerExpandVolume(context.Background(), &csi.ControllerExpandVolumeRequest{
+				VolumeId:      volumeID,
+				CapacityRange: &csi.CapacityRange{RequiredBytes: newSize},
+			})
+			if got := status.Code(err); got != tt.wantCode {
+				t.Fatalf("ControllerExpandVolume() code = %v, want %v (err = %v)", got, tt.wantCode, err)
+			}
+			if resized != tt.wantResize {
+				t.Errorf("resize called = %v, want %v", resized, tt.wantResize)
+			}
+			if err != nil {
+				return
+			}
+			if resp.CapacityBytes != tt.wantCapacity {
+				t.Errorf("CapacityBytes = %d, want %d", resp.CapacityBytes, tt.wantCapacity)
+			}
+			if !resp.NodeExpansionRequired {
+				t.Error("NodeExpansionRequired = false, want true")
+			}
+		})
+	}
+}
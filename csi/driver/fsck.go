@@ -0,0 +1,160 @@
+/*
+Copyright 2025 Kube-DC Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// ParameterFsck is the StorageClass parameter turning the filesystem check before mounting a
+	// formatted volume on ("true") or off ("false"). Unset, the node's --fsck flag decides.
+	ParameterFsck = "fsck"
+
+	// DefaultFsckTimeout is how long a filesystem check may run before staging fails
+	DefaultFsckTimeout = 5 * time.Minute
+)
+
+// errFsckUnrepaired means fsck found errors it could not repair
+var errFsckUnrepaired = errors.New("filesystem has errors fsck could not repair")
+
+// runFsck checks, and for ext filesystems repairs, the filesystem on an unmounted device (a
+// variable so tests can fake fsck)
+var runFsck = execFsck
+
+// parseFsckParameter returns whether the fsck parameter asks for a check, and false for ok if it
+// is unset
+func parseFsckParameter(params map[string]string) (enabled, ok bool, err error) {
+	value, ok := params[ParameterFsck]
+	if !ok {
+		return false, false, nil
+	}
+	enabled, err = strconv.ParseBool(value)
+	if err != nil {
+		return false, false, fmt.Errorf("invalid %s parameter %q: must be true or false", ParameterFsck, value)
+	}
+	return enabled, true, nil
+}
+
+// fsckSupported reports whether runFsck can check fsType
+func fsckSupported(fsType string) bool {
+	switch fsType {
+	case "ext2", "ext3", "ext4", "xfs":
+		return true
+	}
+	return false
+}
+
+// checkFilesystem runs fsck on a formatted device before it is mounted, if the volume context or
+// the driver's default asks for it, so a volume left unclean by a node crash is repaired rather
+// than mounted dirty and remounted read-only by the kernel
+func (d *Driver) checkFilesystem(ctx context.Context, volumeID, devicePath, fsType string, volumeContext map[string]string) error {
+	enabled, set, err := parseFsckParameter(volumeContext)
+	if err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	if !set {
+		enabled = d.fsck
+	}
+	if !enabled {
+		return nil
+	}
+	if !fsckSupported(fsType) {
+		klog.Warningf("Not checking filesystem %s of volume %s on %s: fsck is only run for ext and xfs", fsType, volumeID, devicePath)
+		return nil
+	}
+
+	timeout := d.fsckTimeout
+	if timeout <= 0 {
+		timeout = DefaultFsckTimeout
+	}
+	fsckCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	klog.InfoS("Checking filesystem", "volumeId", volumeID, "device", devicePath, "fsType", fsType)
+	started := time.Now()
+	err = runFsck(fsckCtx, devicePath, fsType)
+	switch {
+	case fsckCtx.Err() == context.DeadlineExceeded:
+		return status.Errorf(codes.DeadlineExceeded, "fsck of device %s did not finish within %v", devicePath, timeout)
+	case errors.Is(err, errFsckUnrepaired):
+		return status.Errorf(codes.FailedPrecondition, "refusing to mount volume %s: %v", volumeID, err)
+	case err != nil:
+		return status.Errorf(codes.Internal, "failed to check filesystem of device %s: %v", devicePath, err)
+	}
+	klog.InfoS("Filesystem checked", "volumeId", volumeID, "device", devicePath, "duration", time.Since(started).Round(time.Millisecond))
+	return nil
+}
+
+// execFsck runs e2fsck in preen mode on ext filesystems, which repairs what is safe to repair
+// unattended, and xfs_repair -n on xfs, which only checks: XFS replays its log on mount and has no
+// unattended repair
+func execFsck(ctx context.Context, devicePath, fsType string) error {
+	var cmd *exec.Cmd
+	if fsType == "xfs" {
+		cmd = exec.CommandContext(ctx, "xfs_repair", "-n", devicePath)
+	} else {
+		cmd = exec.CommandContext(ctx, "e2fsck", "-p", devicePath)
+	}
+	output, err := cmd.CombinedOutput()
+	exitCode := 0
+	if err != nil {
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) {
+			return err
+		}
+		exitCode = exitErr.ExitCode()
+	}
+	if err := fsckResult(fsType, exitCode); err != nil {
+		return fmt.Errorf("%w (exit status %d): %s", err, exitCode, output)
+	}
+	if exitCode != 0 {
+		klog.Infof("fsck of %s exited with status %d: %s", devicePath, exitCode, output)
+	}
+	return nil
+}
+
+// fsckResult maps the exit status of the fsck run for fsType to an error. e2fsck exits with 1 or 2
+// after repairing the filesystem. xfs_repair -n exits with 2 when the log is dirty, which the
+// mount replays, and with 1 when it found corruption.
+func fsckResult(fsType string, exitCode int) error {
+	if fsType == "xfs" {
+		switch exitCode {
+		case 0, 2:
+			return nil
+		case 1:
+			return errFsckUnrepaired
+		}
+		return fmt.Errorf("xfs_repair failed")
+	}
+	switch {
+	case exitCode <= 2:
+		return nil
+	case exitCode&4 != 0:
+		return errFsckUnrepaired
+	}
+	return fmt.Errorf("e2fsck failed")
+}
@@ -0,0 +1,118 @@
+/*
+Copyright 2025 Kube-DC Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestFsckResult(t *testing.T) {
+	tests := []struct {
+		fsType       string
+		exitCode     int
+		wantErr      bool
+		wantUnrepair bool
+	}{
+		{fsType: "ext4", exitCode: 0},
+		{fsType: "ext4", exitCode: 1},
+		{fsType: "ext4", exitCode: 2},
+		{fsType: "ext4", exitCode: 4, wantErr: true, wantUnrepair: true},
+		{fsType: "ext3", exitCode: 12, wantErr: true, wantUnrepair: true},
+		{fsType: "ext4", exitCode: 8, wantErr: true},
+		{fsType: "xfs", exitCode: 0},
+		{fsType: "xfs", exitCode: 2},
+		{fsType: "xfs", exitCode: 1, wantErr: true, wantUnrepair: true},
+		{fsType: "xfs", exitCode: 4, wantErr: true},
+	}
+	for _, tt := range tests {
+		err := fsckResult(tt.fsType, tt.exitCode)
+		if (err != nil) != tt.wantErr || errors.Is(err, errFsckUnrepaired) != tt.wantUnrepair {
+			t.Errorf("fsckResult(%s, %d) = %v, want error %v (unrepaired %v)", tt.fsType, tt.exitCode, err, tt.wantErr, tt.wantUnrepair)
+		}
+	}
+}
+
+func TestPrepareFilesystem(t *testing.T) {
+	tests := []struct {
+		name          string
+		existing      string
+		driverFsck    bool
+		volumeContext map[string]string
+		fsckErr       error
+		fsckHangs     bool
+		wantFsck      bool
+		wantFormat    bool
+		wantCode      codes.Code
+	}{
+		{name: "formatted, enabled on the driver", existing: "ext4", driverFsck: true, wantFsck: true},
+		{name: "formatted, enabled by the StorageClass", existing: "xfs", volumeContext: map[string]string{"fsck": "true"}, wantFsck: true},
+		{name: "formatted, disabled by the StorageClass", existing: "ext4", driverFsck: true, volumeContext: map[string]string{"fsck": "false"}},
+		{name: "formatted, not enabled", existing: "ext4"},
+		{name: "newly formatted devices are not checked", driverFsck: true, wantFormat: true},
+		{name: "unsupported filesystem", existing: "btrfs", driverFsck: true},
+		{name: "invalid parameter", existing: "ext4", volumeContext: map[string]string{"fsck": "maybe"}, wantCode: codes.InvalidArgument},
+		{name: "unrepaired errors", existing: "ext4", driverFsck: true, fsckErr: errFsckUnrepaired, wantFsck: true, wantCode: codes.FailedPrecondition},
+		{name: "fsck fails to run", existing: "ext4", driverFsck: true, fsckErr: errors.New("e2fsck: not found"), wantFsck: true, wantCode: codes.Internal},
+		{name: "fsck times out", existing: "ext4", driverFsck: true, fsckHangs: true, wantFsck: true, wantCode: codes.DeadlineExceeded},
+		{name: "cdroms are not checked", existing: "iso9660", driverFsck: true, volumeContext: map[string]string{"media": "cdrom"}},
+		{name: "cdroms are not formatted", driverFsck: true, volumeContext: map[string]string{"media": "cdrom"}, wantCode: codes.FailedPrecondition},
+		{name: "disks are formatted", volumeContext: map[string]string{"media": "disk"}, wantFormat: true},
+		{name: "invalid media", volumeContext: map[string]string{"media": "floppy"}, wantCode: codes.InvalidArgument},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var checked, formatted []string
+			oldProbe, oldFormat, oldFsck := probeFsType, formatDevice, runFsck
+			probeFsType = func(devicePath string) (string, error) { return tt.existing, nil }
+			formatDevice = func(devicePath, fsType string) error {
+				formatted = append(formatted, devicePath+" "+fsType)
+				return nil
+			}
+			runFsck = func(ctx context.Context, devicePath, fsType string) error {
+				checked = append(checked, devicePath+" "+fsType)
+				if tt.fsckHangs {
+					<-ctx.Done()
+					return ctx.Err()
+				}
+				return tt.fsckErr
+			}
+			t.Cleanup(func() { probeFsType, formatDevice, runFsck = oldProbe, oldFormat, oldFsck })
+
+			d := &Driver{fsck: tt.driverFsck, fsckTimeout: 10 * time.Millisecond}
+			err := d.prepareFilesystem(context.Background(), "vol-1", "/dev/vdb", "ext4", deviceIdentityMatch, tt.volumeContext)
+			if got := status.Code(err); got != tt.wantCode {
+				t.Errorf("prepareFilesystem() = %v, want code %v", err, tt.wantCode)
+			}
+			if (len(checked) > 0) != tt.wantFsck {
+				t.Errorf("fsck runs = %q, want a run: %v", checked, tt.wantFsck)
+			}
+			if tt.wantFsck && len(checked) > 0 && checked[0] != "/dev/vdb "+tt.existing {
+				t.Errorf("fsck ran on %q, want /dev/vdb %s", checked[0], tt.existing)
+			}
+			if (len(formatted) > 0) != tt.wantFormat {
+				t.Errorf("formats = %q, want a format: %v", formatted, tt.wantFormat)
+			}
+		})
+	}
+}
@@ -0,0 +1,438 @@
+/*
+Copyright 2025 Kube-DC Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/cloudsigma/cloudsigma-sdk-go/cloudsigma"
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+
+	"github.com/kube-dc/cluster-api-provider-cloudsigma/pkg/cloud/fake"
+)
+
+// The tests in this file call the driver over gRPC: it serves on a unix socket like it does for
+// the sidecars and is backed by the fake CloudSigma API. They check a hand-picked set of CSI spec
+// requirements and are not the csi-sanity suite. Node RPCs that need block devices and mount
+// privileges are skipped.
+
+// grpcTarget is a driver serving all CSI services, with clients for them
+type grpcTarget struct {
+	api        *fake.Server
+	identity   csi.IdentityClient
+	controller csi.ControllerClient
+	node       csi.NodeClient
+	nodeID     string
+}
+
+func newGRPCTarget(t *testing.T) *grpcTarget {
+	t.Helper()
+
+	api := fake.NewServer()
+	t.Cleanup(api.Close)
+
+	// The node is a server with a boot drive, as every cluster node has
+	sdk := api.NewSDKClient()
+	boot := api.AddDrive(cloudsigma.Drive{Name: "node-1-drive-0", Size: 20 << 30})
+	servers, _, err := sdk.Servers.Create(context.Background(), &cloudsigma.ServerCreateRequest{
+		Servers: []cloudsigma.Server{{Name: "node-1", CPU: 2000, Memory: 2 << 30, VNCPassword: "secret",
+			Drives: []cloudsigma.ServerDrive{{BootOrder: 1, DevChannel: "0:0", Drive: &cloudsigma.Drive{UUID: boot.UUID}}}}},
+	})
+	if err != nil {
+		t.Fatalf("Servers.Create() error = %v", err)
+	}
+	nodeID := servers[0].UUID
+
+	// Unix socket paths are limited to ~100 bytes, which t.TempDir() can exceed
+	dir, err := os.MkdirTemp("", "csi-grpc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	socket := filepath.Join(dir, "csi.sock")
+
+	d, err := NewDriver(&Config{
+		Name:               DriverName,
+		Version:            DriverVersion,
+		NodeID:             nodeID,
+		Region:             fake.Region,
+		Endpoint:           "unix://" + socket,
+		Mode:               AllMode,
+		CloudClient:        sdk,
+		DetachPollInterval: time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("NewDriver() error = %v", err)
+	}
+	go func() { _ = d.Run() }()
+	t.Cleanup(d.Stop)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	conn, err := grpc.DialContext(ctx, "unix://"+socket, grpc.WithTransportCredentials(insecure.NewCredentials()), grpc.WithBlock())
+	if err != nil {
+		t.Fatalf("failed to connect to the driver: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return &grpcTarget{
+		api:        api,
+		identity:   csi.NewIdentityClient(conn),
+		controller: csi.NewControllerClient(conn),
+		node:       csi.NewNodeClient(conn),
+		nodeID:     nodeID,
+	}
+}
+
+func mountCapability() *csi.VolumeCapability {
+	return &csi.VolumeCapability{
+		AccessType: &csi.VolumeCapability_Mount{Mount: &csi.VolumeCapability_MountVolume{}},
+		AccessMode: &csi.VolumeCapability_AccessMode{Mode: csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER},
+	}
+}
+
+// createVolume creates a volume of size bytes, failing the test on error
+func (s *grpcTarget) createVolume(t *testing.T, name string, size int64) *csi.Volume {
+	t.Helper()
+	resp, err := s.controller.CreateVolume(context.Background(), &csi.CreateVolumeRequest{
+		Name:               name,
+		CapacityRange:      &csi.CapacityRange{RequiredBytes: size},
+		VolumeCapabilities: []*csi.VolumeCapability{mountCapability()},
+	})
+	if err != nil {
+		t.Fatalf("CreateVolume(%s) error = %v", name, err)
+	}
+	return resp.Volume
+}
+
+func wantCode(t *testing.T, call string, err error, want codes.Code) {
+	t.Helper()
+	if got := status.Code(err); got != want {
+		t.Errorf("%s code = %v, want %v (err: %v)", call, got, want, err)
+	}
+}
+
+// missingVolumeID is a well-formed drive UUID the fake API does not know
+const missingVolumeID = "00000000-0000-4000-8000-0000ffffffff"
+
+func TestGRPC_Identity(t *testing.T) {
+	s := newGRPCTarget(t)
+	ctx := context.Background()
+
+	info, err := s.identity.GetPluginInfo(ctx, &csi.GetPluginInfoRequest{})
+	if err != nil {
+		t.Fatalf("GetPluginInfo() error = %v", err)
+	}
+	if info.Name != DriverName || info.VendorVersion == "" {
+		t.Errorf("GetPluginInfo() = %+v, want name %s and a version", info, DriverName)
+	}
+
+	caps, err := s.identity.GetPluginCapabilities(ctx, &csi.GetPluginCapabilitiesRequest{})
+	if err != nil {
+		t.Fatalf("GetPluginCapabilities() error = %v", err)
+	}
+	hasController := false
+	for _, c := range caps.Capabilities {
+		if c.GetService().GetType() == csi.PluginCapability_Service_CONTROLLER_SERVICE {
+			hasController = true
+		}
+	}
+	if !hasController {
+		t.Errorf("GetPluginCapabilities() = %v, want CONTROLLER_SERVICE", caps.Capabilities)
+	}
+
+	probe, err := s.identity.Probe(ctx, &csi.ProbeRequest{})
+	if err != nil || !probe.GetReady().GetValue() {
+		t.Errorf("Probe() = %v, %v, want ready", probe, err)
+	}
+}
+
+// Every advertised controller capability must be backed by a working RPC, and every RPC the
+// driver does not implement must answer Unimplemented and stay unadvertised
+func TestGRPC_ControllerCapabilities(t *testing.T) {
+	s := newGRPCTarget(t)
+	ctx := context.Background()
+
+	resp, err := s.controller.ControllerGetCapabilities(ctx, &csi.ControllerGetCapabilitiesRequest{})
+	if err != nil {
+		t.Fatalf("ControllerGetCapabilities() error = %v", err)
+	}
+	advertised := make(map[csi.ControllerServiceCapability_RPC_Type]bool)
+	for _, c := range resp.Capabilities {
+		advertised[c.GetRpc().GetType()] = true
+	}
+	for _, want := range []csi.ControllerServiceCapability_RPC_Type{
+		csi.ControllerServiceCapability_RPC_CREATE_DELETE_VOLUME,
+		csi.ControllerServiceCapability_RPC_PUBLISH_UNPUBLISH_VOLUME,
+		csi.ControllerServiceCapability_RPC_EXPAND_VOLUME,
+	} {
+		if !advertised[want] {
+			t.Errorf("capability %v is not advertised", want)
+		}
+	}
+
+	unimplemented := []struct {
+		capability csi.ControllerServiceCapability_RPC_Type
+		call       func() error
+	}{
+		{csi.ControllerServiceCapability_RPC_LIST_VOLUMES, func() error {
+			_, err := s.controller.ListVolumes(ctx, &csi.ListVolumesRequest{})
+			return err
+		}},
+		{csi.ControllerServiceCapability_RPC_GET_CAPACITY, func() error {
+			_, err := s.controller.GetCapacity(ctx, &csi.GetCapacityRequest{})
+			return err
+		}},
+		{csi.ControllerServiceCapability_RPC_CREATE_DELETE_SNAPSHOT, func() error {
+			_, err := s.controller.CreateSnapshot(ctx, &csi.CreateSnapshotRequest{Name: "snap", SourceVolumeId: missingVolumeID})
+			return err
+		}},
+		{csi.ControllerServiceCapability_RPC_LIST_SNAPSHOTS, func() error {
+			_, err := s.controller.ListSnapshots(ctx, &csi.ListSnapshotsRequest{})
+			return err
+		}},
+		{csi.ControllerServiceCapability_RPC_GET_VOLUME, func() error {
+			_, err := s.controller.ControllerGetVolume(ctx, &csi.ControllerGetVolumeRequest{VolumeId: missingVolumeID})
+			return err
+		}},
+		{csi.ControllerServiceCapability_RPC_MODIFY_VOLUME, func() error {
+			_, err := s.controller.ControllerModifyVolume(ctx, &csi.ControllerModifyVolumeRequest{VolumeId: missingVolumeID})
+			return err
+		}},
+	}
+	for _, tt := range unimplemented {
+		t.Run(tt.capability.String(), func(t *testing.T) {
+			if advertised[tt.capability] {
+				t.Errorf("capability %v is advertised but its RPC is not implemented", tt.capability)
+			}
+			wantCode(t, tt.capability.String(), tt.call(), codes.Unimplemented)
+		})
+	}
+}
+
+func TestGRPC_CreateDeleteVolume(t *testing.T) {
+	s := newGRPCTarget(t)
+	ctx := context.Background()
+
+	t.Run("argument validation", func(t *testing.T) {
+		_, err := s.controller.CreateVolume(ctx, &csi.CreateVolumeRequest{VolumeCapabilities: []*csi.VolumeCapability{mountCapability()}})
+		wantCode(t, "CreateVolume(no name)", err, codes.InvalidArgument)
+		_, err = s.controller.CreateVolume(ctx, &csi.CreateVolumeRequest{Name: "pvc-invalid"})
+		wantCode(t, "CreateVolume(no capabilities)", err, codes.InvalidArgument)
+		_, err = s.controller.DeleteVolume(ctx, &csi.DeleteVolumeRequest{})
+		wantCode(t, "DeleteVolume(no ID)", err, codes.InvalidArgument)
+		_, err = s.controller.ValidateVolumeCapabilities(ctx, &csi.ValidateVolumeCapabilitiesRequest{VolumeId: missingVolumeID})
+		wantCode(t, "ValidateVolumeCapabilities(no capabilities)", err, codes.InvalidArgument)
+	})
+
+	t.Run("idempotent create", func(t *testing.T) {
+		first := s.createVolume(t, "pvc-idempotent", MinVolumeSize)
+		second := s.createVolume(t, "pvc-idempotent", MinVolumeSize)
+		if first.VolumeId != second.VolumeId || first.CapacityBytes != second.CapacityBytes {
+			t.Errorf("repeated CreateVolume() = %+v, want %+v", second, first)
+		}
+
+		_, err := s.controller.CreateVolume(ctx, &csi.CreateVolumeRequest{
+			Name:               "pvc-idempotent",
+			CapacityRange:      &csi.CapacityRange{RequiredBytes: 2 * MinVolumeSize},
+			VolumeCapabilities: []*csi.VolumeCapability{mountCapability()},
+		})
+		wantCode(t, "CreateVolume(same name, larger size)", err, codes.AlreadyExists)
+
+		_, err = s.controller.CreateVolume(ctx, &csi.CreateVolumeRequest{
+			Name:               "pvc-idempotent",
+			CapacityRange:      &csi.CapacityRange{RequiredBytes: MinVolumeSize},
+			Parameters:         map[string]string{ParameterMedia: MediaCDROM},
+			VolumeCapabilities: []*csi.VolumeCapability{mountCapability()},
+		})
+		wantCode(t, "CreateVolume(same name, other media)", err, codes.AlreadyExists)
+	})
+
+	t.Run("validate capabilities", func(t *testing.T) {
+		volume := s.createVolume(t, "pvc-validate", MinVolumeSize)
+		resp, err := s.controller.ValidateVolumeCapabilities(ctx, &csi.ValidateVolumeCapabilitiesRequest{
+			VolumeId:           volume.VolumeId,
+			VolumeCapabilities: []*csi.VolumeCapability{mountCapability()},
+		})
+		if err != nil || resp.Confirmed == nil {
+			t.Errorf("ValidateVolumeCapabilities() = %v, %v, want confirmed", resp, err)
+		}
+
+		_, err = s.controller.ValidateVolumeCapabilities(ctx, &csi.ValidateVolumeCapabilitiesRequest{
+			VolumeId:           missingVolumeID,
+			VolumeCapabilities: []*csi.VolumeCapability{mountCapability()},
+		})
+		wantCode(t, "ValidateVolumeCapabilities(missing volume)", err, codes.NotFound)
+	})
+
+	t.Run("idempotent delete", func(t *testing.T) {
+		volume := s.createVolume(t, "pvc-delete", MinVolumeSize)
+		for i := 0; i < 2; i++ {
+			if _, err := s.controller.DeleteVolume(ctx, &csi.DeleteVolumeRequest{VolumeId: volume.VolumeId}); err != nil {
+				t.Fatalf("DeleteVolume() attempt %d error = %v", i+1, err)
+			}
+		}
+		if _, ok := s.api.GetDrive(volume.VolumeId); ok {
+			t.Error("drive still exists after DeleteVolume()")
+		}
+		if _, err := s.controller.DeleteVolume(ctx, &csi.DeleteVolumeRequest{VolumeId: missingVolumeID}); err != nil {
+			t.Errorf("DeleteVolume(missing volume) error = %v, want success", err)
+		}
+	})
+}
+
+func TestGRPC_PublishUnpublish(t *testing.T) {
+	s := newGRPCTarget(t)
+	ctx := context.Background()
+	volume := s.createVolume(t, "pvc-publish", MinVolumeSize)
+
+	publish := func(volumeID, nodeID string) (*csi.ControllerPublishVolumeResponse, error) {
+		return s.controller.ControllerPublishVolume(ctx, &csi.ControllerPublishVolumeRequest{
+			VolumeId:         volumeID,
+			NodeId:           nodeID,
+			VolumeCapability: mountCapability(),
+		})
+	}
+	unpublish := func(volumeID, nodeID string) error {
+		_, err := s.controller.ControllerUnpublishVolume(ctx, &csi.ControllerUnpublishVolumeRequest{VolumeId: volumeID, NodeId: nodeID})
+		return err
+	}
+
+	t.Run("argument validation", func(t *testing.T) {
+		_, err := publish("", s.nodeID)
+		wantCode(t, "ControllerPublishVolume(no volume ID)", err, codes.InvalidArgument)
+		_, err = publish(volume.VolumeId, "")
+		wantCode(t, "ControllerPublishVolume(no node ID)", err, codes.InvalidArgument)
+		wantCode(t, "ControllerUnpublishVolume(no volume ID)", unpublish("", s.nodeID), codes.InvalidArgument)
+		wantCode(t, "ControllerUnpublishVolume(no node ID)", unpublish(volume.VolumeId, ""), codes.InvalidArgument)
+	})
+
+	t.Run("missing volume or node", func(t *testing.T) {
+		_, err := publish(missingVolumeID, s.nodeID)
+		wantCode(t, "ControllerPublishVolume(missing volume)", err, codes.NotFound)
+		_, err = publish(volume.VolumeId, missingVolumeID)
+		wantCode(t, "ControllerPublishVolume(missing node)", err, codes.NotFound)
+		if err := unpublish(volume.VolumeId, missingVolumeID); err != nil {
+			t.Errorf("ControllerUnpublishVolume(missing node) error = %v, want success", err)
+		}
+	})
+
+	t.Run("idempotent publish and unpublish", func(t *testing.T) {
+		first, err := publish(volume.VolumeId, s.nodeID)
+		if err != nil {
+			t.Fatalf("ControllerPublishVolume() error = %v", err)
+		}
+		second, err := publish(volume.VolumeId, s.nodeID)
+		if err != nil {
+			t.Fatalf("repeated ControllerPublishVolume() error = %v", err)
+		}
+		for k, v := range first.PublishContext {
+			if second.PublishContext[k] != v {
+				t.Errorf("repeated publish context[%s] = %q, want %q", k, second.PublishContext[k], v)
+			}
+		}
+		if drive, _ := s.api.GetDrive(volume.VolumeId); drive.Status != "mounted" {
+			t.Errorf("drive status after publish = %q, want mounted", drive.Status)
+		}
+
+		for i := 0; i < 2; i++ {
+			if err := unpublish(volume.VolumeId, s.nodeID); err != nil {
+				t.Fatalf("ControllerUnpublishVolume() attempt %d error = %v", i+1, err)
+			}
+		}
+		if drive, _ := s.api.GetDrive(volume.VolumeId); drive.Status != "unmounted" {
+			t.Errorf("drive status after unpublish = %q, want unmounted", drive.Status)
+		}
+	})
+}
+
+func TestGRPC_ExpandVolume(t *testing.T) {
+	s := newGRPCTarget(t)
+	ctx := context.Background()
+	volume := s.createVolume(t, "pvc-expand", MinVolumeSize)
+
+	_, err := s.controller.ControllerExpandVolume(ctx, &csi.ControllerExpandVolumeRequest{VolumeId: volume.VolumeId})
+	wantCode(t, "ControllerExpandVolume(no capacity range)", err, codes.InvalidArgument)
+	_, err = s.controller.ControllerExpandVolume(ctx, &csi.ControllerExpandVolumeRequest{CapacityRange: &csi.CapacityRange{RequiredBytes: 2 * MinVolumeSize}})
+	wantCode(t, "ControllerExpandVolume(no volume ID)", err, codes.InvalidArgument)
+
+	resp, err := s.controller.ControllerExpandVolume(ctx, &csi.ControllerExpandVolumeRequest{
+		VolumeId:      volume.VolumeId,
+		CapacityRange: &csi.CapacityRange{RequiredBytes: 2 * MinVolumeSize},
+	})
+	if err != nil {
+		t.Fatalf("ControllerExpandVolume() error = %v", err)
+	}
+	if resp.CapacityBytes < 2*MinVolumeSize {
+		t.Errorf("ControllerExpandVolume() capacity = %d, want at least %d", resp.CapacityBytes, 2*MinVolumeSize)
+	}
+	if drive, _ := s.api.GetDrive(volume.VolumeId); int64(drive.Size) != resp.CapacityBytes {
+		t.Errorf("drive size = %d, want %d", drive.Size, resp.CapacityBytes)
+	}
+}
+
+func TestGRPC_Node(t *testing.T) {
+	s := newGRPCTarget(t)
+	ctx := context.Background()
+
+	info, err := s.node.NodeGetInfo(ctx, &csi.NodeGetInfoRequest{})
+	if err != nil {
+		t.Fatalf("NodeGetInfo() error = %v", err)
+	}
+	if info.NodeId != s.nodeID || info.MaxVolumesPerNode <= 0 || info.AccessibleTopology.GetSegments()[TopologyKey] != fake.Region {
+		t.Errorf("NodeGetInfo() = %+v, want node %s in %s", info, s.nodeID, fake.Region)
+	}
+
+	caps, err := s.node.NodeGetCapabilities(ctx, &csi.NodeGetCapabilitiesRequest{})
+	if err != nil {
+		t.Fatalf("NodeGetCapabilities() error = %v", err)
+	}
+	hasStage := false
+	for _, c := range caps.Capabilities {
+		if c.GetRpc().GetType() == csi.NodeServiceCapability_RPC_STAGE_UNSTAGE_VOLUME {
+			hasStage = true
+		}
+	}
+	if !hasStage {
+		t.Errorf("NodeGetCapabilities() = %v, want STAGE_UNSTAGE_VOLUME", caps.Capabilities)
+	}
+
+	t.Run("argument validation", func(t *testing.T) {
+		_, err := s.node.NodeStageVolume(ctx, &csi.NodeStageVolumeRequest{StagingTargetPath: "/staging", VolumeCapability: mountCapability()})
+		wantCode(t, "NodeStageVolume(no volume ID)", err, codes.InvalidArgument)
+		_, err = s.node.NodeStageVolume(ctx, &csi.NodeStageVolumeRequest{VolumeId: missingVolumeID, VolumeCapability: mountCapability()})
+		wantCode(t, "NodeStageVolume(no staging path)", err, codes.InvalidArgument)
+		_, err = s.node.NodeUnstageVolume(ctx, &csi.NodeUnstageVolumeRequest{StagingTargetPath: "/staging"})
+		wantCode(t, "NodeUnstageVolume(no volume ID)", err, codes.InvalidArgument)
+		_, err = s.node.NodePublishVolume(ctx, &csi.NodePublishVolumeRequest{VolumeId: missingVolumeID, VolumeCapability: mountCapability()})
+		wantCode(t, "NodePublishVolume(no target path)", err, codes.InvalidArgument)
+		_, err = s.node.NodeUnpublishVolume(ctx, &csi.NodeUnpublishVolumeRequest{TargetPath: "/target"})
+		wantCode(t, "NodeUnpublishVolume(no volume ID)", err, codes.InvalidArgument)
+	})
+
+	t.Run("stage and publish", func(t *testing.T) {
+		t.Skip("needs a hotplugged block device and mount privileges on the host")
+	})
+}
@@ -49,9 +49,10 @@ func (d *Driver) GetPluginCapabilities(ctx context.Context, req *csi.GetPluginCa
 			},
 		},
 		{
+			// CloudSigma can't resize a drive mounted on a running server, see checkDriveResizable
 			Type: &csi.PluginCapability_VolumeExpansion_{
 				VolumeExpansion: &csi.PluginCapability_VolumeExpansion{
-					Type: csi.PluginCapability_VolumeExpansion_ONLINE,
+					Type: csi.PluginCapability_VolumeExpansion_OFFLINE,
 				},
 			},
 		},
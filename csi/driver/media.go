@@ -0,0 +1,43 @@
+/*
+Copyright 2025 Kube-DC Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"fmt"
+)
+
+const (
+	// ParameterMedia is the StorageClass parameter choosing the CloudSigma media of a volume's
+	// drive: MediaDisk (the default) or MediaCDROM
+	ParameterMedia = "media"
+
+	// Drive media types
+	MediaDisk  = "disk"
+	MediaCDROM = "cdrom"
+)
+
+// parseMediaParameter returns the media the media parameter asks for, MediaDisk if it is unset
+func parseMediaParameter(params map[string]string) (string, error) {
+	media, ok := params[ParameterMedia]
+	if !ok {
+		return MediaDisk, nil
+	}
+	if media != MediaDisk && media != MediaCDROM {
+		return "", fmt.Errorf("unsupported %s parameter %q, must be one of: %s, %s", ParameterMedia, media, MediaDisk, MediaCDROM)
+	}
+	return media, nil
+}
@@ -0,0 +1,96 @@
+/*
+Copyright 2025 Kube-DC Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/cloudsigma/cloudsigma-sdk-go/cloudsigma"
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestCreateVolume_Media(t *testing.T) {
+	tests := []struct {
+		name      string
+		params    map[string]string
+		wantCode  codes.Code
+		wantMedia string
+	}{
+		{name: "default", wantCode: codes.OK, wantMedia: MediaDisk},
+		{name: "disk", params: map[string]string{"media": "disk"}, wantCode: codes.OK, wantMedia: MediaDisk},
+		{name: "cdrom", params: map[string]string{"media": "cdrom"}, wantCode: codes.OK, wantMedia: MediaCDROM},
+		{name: "unsupported", params: map[string]string{"media": "floppy"}, wantCode: codes.InvalidArgument},
+		{name: "empty parameter", params: map[string]string{"media": ""}, wantCode: codes.InvalidArgument},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var created []string
+
+			mux := http.NewServeMux()
+			mux.HandleFunc("/api/2.0/drives/detail/", func(w http.ResponseWriter, r *http.Request) {
+				writeJSON(w, map[string]interface{}{"objects": []cloudsigma.Drive{}})
+			})
+			mux.HandleFunc("/api/2.0/drives/", func(w http.ResponseWriter, r *http.Request) {
+				var req cloudsigma.DriveCreateRequest
+				if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+					t.Errorf("failed to decode drive create request: %v", err)
+				}
+				for i := range req.Drives {
+					created = append(created, req.Drives[i].Media)
+					req.Drives[i].UUID = "vol-1"
+				}
+				writeJSON(w, map[string]interface{}{"objects": req.Drives})
+			})
+			mux.HandleFunc("/api/2.0/tags/", func(w http.ResponseWriter, r *http.Request) {
+				writeJSON(w, map[string]interface{}{"objects": []cloudsigma.Tag{}})
+			})
+
+			d := newTestDriver(t, mux)
+			resp, err := d.CreateVolume(context.Background(), &csi.CreateVolumeRequest{
+				Name:       "pvc-1",
+				Parameters: tt.params,
+				VolumeCapabilities: []*csi.VolumeCapability{{
+					AccessType: &csi.VolumeCapability_Mount{Mount: &csi.VolumeCapability_MountVolume{}},
+					AccessMode: &csi.VolumeCapability_AccessMode{Mode: csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER},
+				}},
+			})
+
+			if got := status.Code(err); got != tt.wantCode {
+				t.Fatalf("CreateVolume() code = %v, want %v (err: %v)", got, tt.wantCode, err)
+			}
+			if tt.wantCode != codes.OK {
+				if len(created) != 0 {
+					t.Errorf("drive created with invalid media: %v", created)
+				}
+				return
+			}
+			if len(created) != 1 || created[0] != tt.wantMedia {
+				t.Errorf("created media = %v, want [%s]", created, tt.wantMedia)
+			}
+			// The node reads the media from the volume context to skip formatting cdroms
+			if got := resp.Volume.VolumeContext[ParameterMedia]; got != tt.params[ParameterMedia] {
+				t.Errorf("volume context media = %q, want %q", got, tt.params[ParameterMedia])
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+/*
+Copyright 2025 Kube-DC Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"slices"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"k8s.io/klog/v2"
+)
+
+// DefaultAllowedMountOptions are the StorageClass mount options the node plugin passes to mount
+// unless the operator configures its own list. Options taking a value, like commit=60, are
+// listed by name.
+var DefaultAllowedMountOptions = []string{
+	"ro", "rw",
+	"noatime", "nodiratime", "relatime", "strictatime", "lazytime",
+	"noexec", "nosuid", "nodev",
+	"sync", "async", "dirsync",
+	"discard", "nodiscard",
+	"commit", "data", "barrier", "nobarrier", // ext3/ext4
+	"nouuid", "inode64", "largeio", "logbufs", "logbsize", "allocsize", // xfs
+	"usrquota", "grpquota", "prjquota", "noquota",
+}
+
+// rejectedMountOptions relabel the volume for SELinux, re-enable device files or setuid binaries
+// the kubelet would otherwise keep off, or change what is mounted. A StorageClass asking for one
+// of them fails instead of having it silently dropped, unless the operator allows it explicitly.
+var rejectedMountOptions = []string{
+	"context", "fscontext", "defcontext", "rootcontext",
+	"dev", "suid",
+	"bind", "rbind", "remount", "move", "loop",
+}
+
+// mountOptionName returns the name of an option, without its value
+func mountOptionName(option string) string {
+	name, _, _ := strings.Cut(option, "=")
+	return strings.TrimSpace(name)
+}
+
+// filterMountOptions returns the options of a volume capability that may be passed to mount. An
+// option whose name is in allowed is kept, one of rejectedMountOptions is an InvalidArgument
+// error, and any other is dropped with a warning.
+func filterMountOptions(volumeID string, options []string, allowed map[string]bool) ([]string, error) {
+	kept := make([]string, 0, len(options))
+	var dropped []string
+	for _, option := range options {
+		name := mountOptionName(option)
+		switch {
+		case name == "":
+		case allowed[name]:
+			kept = append(kept, option)
+		case slices.Contains(rejectedMountOptions, name):
+			return nil, status.Errorf(codes.InvalidArgument, "mount option %q is not allowed", option)
+		default:
+			dropped = append(dropped, option)
+		}
+	}
+	if len(dropped) > 0 {
+		klog.Warningf("Dropping mount options of volume %s that are not allowed: %v", volumeID, dropped)
+	}
+	return kept, nil
+}
+
+// mountOptionSet builds the allowlist from option names; nil selects DefaultAllowedMountOptions
+func mountOptionSet(names []string) map[string]bool {
+	if names == nil {
+		names = DefaultAllowedMountOptions
+	}
+	allowed := make(map[string]bool, len(names))
+	for _, name := range names {
+		if name = mountOptionName(name); name != "" {
+			allowed[name] = true
+		}
+	}
+	return allowed
+}
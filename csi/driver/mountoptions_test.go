@@ -0,0 +1,103 @@
+/*
+Copyright 2025 Kube-DC Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestFilterMountOptions(t *testing.T) {
+	tests := []struct {
+		name     string
+		allowed  []string // nil for the defaults
+		options  []string
+		want     []string
+		wantCode codes.Code
+	}{
+		{name: "no options", want: []string{}},
+		{name: "common options are allowed", options: []string{"noatime", "ro", "nodiratime"}, want: []string{"noatime", "ro", "nodiratime"}},
+		{name: "options with values are matched by name", options: []string{"commit=60", "discard"}, want: []string{"commit=60", "discard"}},
+		{name: "unknown options are stripped", options: []string{"noatime", "journal_checksum", "user_xattr"}, want: []string{"noatime"}},
+		{name: "SELinux context is rejected", options: []string{"noatime", `rootcontext="system_u:object_r:tmp_t:s0"`}, wantCode: codes.InvalidArgument},
+		{name: "device files are rejected", options: []string{"dev"}, wantCode: codes.InvalidArgument},
+		{name: "loop devices are rejected", options: []string{"loop=/dev/loop0"}, wantCode: codes.InvalidArgument},
+		{name: "operator list replaces the defaults", allowed: []string{"noatime"}, options: []string{"noatime", "ro"}, want: []string{"noatime"}},
+		{name: "operator can allow a rejected option", allowed: []string{"context"}, options: []string{`context="system_u:object_r:container_file_t:s0"`}, want: []string{`context="system_u:object_r:container_file_t:s0"`}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := filterMountOptions("vol-1", tt.options, mountOptionSet(tt.allowed))
+			if tt.wantCode != codes.OK {
+				if status.Code(err) != tt.wantCode {
+					t.Fatalf("filterMountOptions() error = %v, want code %v", err, tt.wantCode)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("filterMountOptions() error = %v", err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("filterMountOptions() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNodeStageVolume_RejectsMountOptions(t *testing.T) {
+	d, err := NewDriver(&Config{Name: DriverName, Mode: NodeMode, NodeID: "node-1"})
+	if err != nil {
+		t.Fatalf("NewDriver() error = %v", err)
+	}
+
+	for _, call := range []struct {
+		name string
+		run  func(cap *csi.VolumeCapability) error
+	}{
+		{name: "NodeStageVolume", run: func(cap *csi.VolumeCapability) error {
+			_, err := d.NodeStageVolume(context.Background(), &csi.NodeStageVolumeRequest{
+				VolumeId: "vol-1", StagingTargetPath: t.TempDir(), VolumeCapability: cap,
+				PublishContext: map[string]string{"devicePath": "/dev/null"},
+			})
+			return err
+		}},
+		{name: "NodePublishVolume", run: func(cap *csi.VolumeCapability) error {
+			_, err := d.NodePublishVolume(context.Background(), &csi.NodePublishVolumeRequest{
+				VolumeId: "vol-1", StagingTargetPath: t.TempDir(), TargetPath: t.TempDir(), VolumeCapability: cap,
+			})
+			return err
+		}},
+	} {
+		t.Run(call.name, func(t *testing.T) {
+			cap := &csi.VolumeCapability{
+				AccessType: &csi.VolumeCapability_Mount{Mount: &csi.VolumeCapability_MountVolume{MountFlags: []string{"noatime", "suid"}}},
+				AccessMode: &csi.VolumeCapability_AccessMode{Mode: csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER},
+			}
+			err := call.run(cap)
+			if status.Code(err) != codes.InvalidArgument || !strings.Contains(err.Error(), `mount option "suid"`) {
+				t.Errorf("%s() error = %v, want InvalidArgument for the suid option", call.name, err)
+			}
+		})
+	}
+}
@@ -23,6 +23,7 @@ import (
 	"os/exec"
 	"path/filepath"
 	"strings"
+	"sync"
 
 	"github.com/container-storage-interface/spec/lib/go/csi"
 	"golang.org/x/sys/unix"
@@ -46,24 +47,47 @@ func (d *Driver) NodeStageVolume(ctx context.Context, req *csi.NodeStageVolumeRe
 
 	stagingPath := req.StagingTargetPath
 
-	// Serialize device discovery AND mounting to prevent race conditions when multiple volumes
-	// are attached to the same node simultaneously. We must hold the lock through the entire
-	// process to ensure one volume is fully mounted before the next one tries to find its device.
-	d.nodeDeviceMu.Lock()
-	defer d.nodeDeviceMu.Unlock()
+	// Refuse disallowed mount options before touching the device
+	var mountOptions []string
+	if mount := req.VolumeCapability.GetMount(); mount != nil {
+		var err error
+		if mountOptions, err = filterMountOptions(req.VolumeId, mount.MountFlags, d.allowedMountOptions); err != nil {
+			return nil, err
+		}
+	}
+
+	// Stage a volume one request at a time; the kubelet retries a volume that is busy
+	if !d.volumeLocks.TryAcquire(req.VolumeId) {
+		return nil, status.Errorf(codes.Aborted, "an operation on volume %s is already in progress", req.VolumeId)
+	}
+	defer d.volumeLocks.Release(req.VolumeId)
 
-	devicePath, err := findDeviceByPath(req.PublishContext)
+	// Formatting, checking and mounting only hold the volume lock, so a long fsck does not stall
+	// other volumes
+	devicePath, err := findPublishedDevice(req.PublishContext, &d.nodeDeviceMu)
 	if err != nil {
 		return nil, status.Errorf(codes.InvalidArgument, "failed to find device: %v", err)
 	}
 
-	klog.Infof("Staging volume %s at %s (device: %s)", req.VolumeId, stagingPath, devicePath)
+	klog.InfoS("Staging volume", "volumeId", req.VolumeId, "stagingPath", stagingPath, "device", devicePath)
 
 	// Check if device exists
 	if _, err := os.Stat(devicePath); os.IsNotExist(err) {
 		return nil, status.Errorf(codes.NotFound, "device %s not found", devicePath)
 	}
 
+	// Make sure the device really is this volume's drive before touching it
+	identity, serial := checkDeviceIdentity(devicePath, req.VolumeId)
+	switch identity {
+	case deviceIdentityMismatch:
+		return nil, status.Errorf(codes.FailedPrecondition,
+			"device %s has serial %q which does not match volume %s", devicePath, serial, req.VolumeId)
+	case deviceIdentityMatch:
+		klog.Infof("Verified device %s belongs to volume %s (serial: %s)", devicePath, req.VolumeId, serial)
+	default:
+		klog.Warningf("Device %s exposes no serial, cannot verify it belongs to volume %s", devicePath, req.VolumeId)
+	}
+
 	// Handle block volume
 	if req.VolumeCapability.GetBlock() != nil {
 		// For block volumes, staging is not needed
@@ -77,7 +101,20 @@ func (d *Driver) NodeStageVolume(ctx context.Context, req *csi.NodeStageVolumeRe
 		return nil, status.Error(codes.InvalidArgument, "volume capability must be mount or block")
 	}
 
+	media, err := parseMediaParameter(req.VolumeContext)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
 	fsType := mount.FsType
+	if media == MediaCDROM {
+		// A cdrom is mounted read-only with the filesystem its image carries
+		mountOptions = append(mountOptions, "ro")
+		if fsType == "" {
+			if fsType, err = probeFsType(devicePath); err != nil {
+				return nil, status.Errorf(codes.Internal, "failed to detect filesystem of device %s: %v", devicePath, err)
+			}
+		}
+	}
 	if fsType == "" {
 		fsType = "ext4"
 	}
@@ -94,31 +131,26 @@ func (d *Driver) NodeStageVolume(ctx context.Context, req *csi.NodeStageVolumeRe
 		return nil, status.Errorf(codes.Internal, "failed to check mount status: %v", err)
 	}
 	if mounted {
+		if err := checkStagedFsType(devicePath, fsType); err != nil {
+			return nil, err
+		}
 		klog.Infof("Volume %s already staged at %s", req.VolumeId, stagingPath)
 		return &csi.NodeStageVolumeResponse{}, nil
 	}
 
-	// Format if needed
-	formatted, err := isFormatted(devicePath)
-	if err != nil {
-		return nil, status.Errorf(codes.Internal, "failed to check if device is formatted: %v", err)
-	}
-	if !formatted {
-		klog.Infof("Formatting device %s with %s", devicePath, fsType)
-		if err := formatDevice(devicePath, fsType); err != nil {
-			return nil, status.Errorf(codes.Internal, "failed to format device: %v", err)
-		}
+	// Format if needed, otherwise check the existing filesystem if asked to
+	if err := d.prepareFilesystem(ctx, req.VolumeId, devicePath, fsType, identity, req.VolumeContext); err != nil {
+		return nil, err
 	}
 
 	// Mount the device
-	mountOptions := mount.MountFlags
 	klog.Infof("Mounting %s to %s with fsType=%s, options=%v", devicePath, stagingPath, fsType, mountOptions)
 
 	if err := mounter.Mount(devicePath, stagingPath, fsType, mountOptions); err != nil {
 		return nil, status.Errorf(codes.Internal, "failed to mount device: %v", err)
 	}
 
-	klog.Infof("Volume %s staged at %s", req.VolumeId, stagingPath)
+	klog.InfoS("Volume staged", "volumeId", req.VolumeId, "stagingPath", stagingPath)
 	return &csi.NodeStageVolumeResponse{}, nil
 }
 
@@ -133,7 +165,7 @@ func (d *Driver) NodeUnstageVolume(ctx context.Context, req *csi.NodeUnstageVolu
 
 	stagingPath := req.StagingTargetPath
 
-	klog.Infof("Unstaging volume %s from %s", req.VolumeId, stagingPath)
+	klog.InfoS("Unstaging volume", "volumeId", req.VolumeId, "stagingPath", stagingPath)
 
 	mounter := kmount.New("")
 
@@ -170,12 +202,15 @@ func (d *Driver) NodePublishVolume(ctx context.Context, req *csi.NodePublishVolu
 	targetPath := req.TargetPath
 	stagingPath := req.StagingTargetPath
 
-	klog.Infof("Publishing volume %s to %s", req.VolumeId, targetPath)
+	klog.InfoS("Publishing volume", "volumeId", req.VolumeId, "targetPath", targetPath)
 
 	// Handle block volume
 	if req.VolumeCapability.GetBlock() != nil {
 		devicePath := ""
-		if req.PublishContext != nil {
+		if serial := req.PublishContext["serial"]; serial != "" {
+			devicePath = findDeviceBySerial(serial)
+		}
+		if devicePath == "" {
 			devicePath = req.PublishContext["devicePath"]
 		}
 		if devicePath == "" {
@@ -205,7 +240,13 @@ func (d *Driver) NodePublishVolume(ctx context.Context, req *csi.NodePublishVolu
 		return &csi.NodePublishVolumeResponse{}, nil
 	}
 
-	// Handle filesystem volume
+	// Handle filesystem volume. The mount options were applied when staging; reject the same
+	// options here so a disallowed StorageClass fails on every node call.
+	if mount := req.VolumeCapability.GetMount(); mount != nil {
+		if _, err := filterMountOptions(req.VolumeId, mount.MountFlags, d.allowedMountOptions); err != nil {
+			return nil, err
+		}
+	}
 	if stagingPath == "" {
 		return nil, status.Error(codes.InvalidArgument, "staging target path is required for filesystem volumes")
 	}
@@ -238,7 +279,7 @@ func (d *Driver) NodePublishVolume(ctx context.Context, req *csi.NodePublishVolu
 		return nil, status.Errorf(codes.Internal, "failed to bind mount: %v", err)
 	}
 
-	klog.Infof("Volume %s published to %s", req.VolumeId, targetPath)
+	klog.InfoS("Volume published", "volumeId", req.VolumeId, "targetPath", targetPath)
 	return &csi.NodePublishVolumeResponse{}, nil
 }
 
@@ -253,7 +294,7 @@ func (d *Driver) NodeUnpublishVolume(ctx context.Context, req *csi.NodeUnpublish
 
 	targetPath := req.TargetPath
 
-	klog.Infof("Unpublishing volume %s from %s", req.VolumeId, targetPath)
+	klog.InfoS("Unpublishing volume", "volumeId", req.VolumeId, "targetPath", targetPath)
 
 	mounter := kmount.New("")
 
@@ -328,7 +369,9 @@ func (d *Driver) NodeGetVolumeStats(ctx context.Context, req *csi.NodeGetVolumeS
 	}, nil
 }
 
-// NodeExpandVolume expands the filesystem on the node
+// NodeExpandVolume makes a grown drive's new size visible on the node. Filesystem volumes are
+// resized in place; raw block volumes have no filesystem, so only the device size is refreshed.
+// The reported capacity is the size the kernel observes, not the size that was requested.
 func (d *Driver) NodeExpandVolume(ctx context.Context, req *csi.NodeExpandVolumeRequest) (*csi.NodeExpandVolumeResponse, error) {
 	if req.VolumeId == "" {
 		return nil, status.Error(codes.InvalidArgument, "volume ID is required")
@@ -339,23 +382,54 @@ func (d *Driver) NodeExpandVolume(ctx context.Context, req *csi.NodeExpandVolume
 
 	volumePath := req.VolumePath
 
-	klog.Infof("Expanding filesystem on volume %s at %s", req.VolumeId, volumePath)
+	// The capability is optional in the request; fall back to what is published at the path
+	block := isBlockVolumePath(volumePath)
+	if req.VolumeCapability != nil {
+		block = req.VolumeCapability.GetBlock() != nil
+	}
+
+	var devicePath string
+	var err error
+	if block {
+		klog.InfoS("Expanding block volume", "volumeId", req.VolumeId, "volumePath", volumePath)
 
-	// Get device path from mount point
-	devicePath, err := getDeviceFromMountPoint(volumePath)
+		// A block bind mount does not name the underlying device, so find it by drive serial
+		devicePath, err = findVolumeDevice(req.VolumeId)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to find device for volume %s: %v", req.VolumeId, err)
+		}
+		if devicePath == "" {
+			return nil, status.Errorf(codes.NotFound, "no device found for volume %s", req.VolumeId)
+		}
+	} else {
+		klog.InfoS("Expanding filesystem", "volumeId", req.VolumeId, "volumePath", volumePath)
+
+		devicePath, err = mountDeviceLookup(volumePath)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to get device from mount point: %v", err)
+		}
+	}
+
+	// The guest may not see the grown drive until the device is rescanned; growing the
+	// filesystem before the new size shows up would be a no-op
+	if err := deviceRescanner(devicePath); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to rescan device: %v", err)
+	}
+	capacity, err := waitForDeviceSize(devicePath, req.GetCapacityRange().GetRequiredBytes())
 	if err != nil {
-		return nil, status.Errorf(codes.Internal, "failed to get device from mount point: %v", err)
+		return nil, status.Errorf(codes.Internal, "new size of volume %s is not visible on the node: %v", req.VolumeId, err)
 	}
 
-	// Resize the filesystem
-	if err := resizeFilesystem(devicePath, volumePath); err != nil {
-		return nil, status.Errorf(codes.Internal, "failed to resize filesystem: %v", err)
+	if !block {
+		if err := filesystemResizer(devicePath, volumePath); err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to resize filesystem: %v", err)
+		}
 	}
 
-	klog.Infof("Filesystem expanded on volume %s", req.VolumeId)
+	klog.InfoS("Volume expanded", "volumeId", req.VolumeId, "device", devicePath, "capacityBytes", capacity, "block", block)
 
 	return &csi.NodeExpandVolumeResponse{
-		CapacityBytes: req.CapacityRange.RequiredBytes,
+		CapacityBytes: capacity,
 	}, nil
 }
 
@@ -383,7 +457,7 @@ func (d *Driver) NodeGetInfo(ctx context.Context, req *csi.NodeGetInfoRequest) (
 		MaxVolumesPerNode: 15, // CloudSigma limit per server
 		AccessibleTopology: &csi.Topology{
 			Segments: map[string]string{
-				TopologyKey: d.region,
+				TopologyKey: d.cloudRegion(),
 			},
 		},
 	}, nil
@@ -412,20 +486,76 @@ func isMounted(mounter kmount.Interface, path string) (bool, error) {
 	return false, nil
 }
 
-func isFormatted(devicePath string) (bool, error) {
+// prepareFilesystem formats an unformatted device with fsType, or runs checkFilesystem on the
+// filesystem a formatted device already carries. A newly formatted device is not checked. A cdrom
+// is read-only, so it is neither formatted nor checked, and one without a filesystem is refused.
+func (d *Driver) prepareFilesystem(ctx context.Context, volumeID, devicePath, fsType string, identity deviceIdentity, volumeContext map[string]string) error {
+	media, err := parseMediaParameter(volumeContext)
+	if err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	existing, err := probeFsType(devicePath)
+	if err != nil {
+		return status.Errorf(codes.Internal, "failed to check if device is formatted: %v", err)
+	}
+	if media == MediaCDROM {
+		if existing == "" {
+			return status.Errorf(codes.FailedPrecondition,
+				"cdrom volume %s on device %s carries no filesystem, and cdroms are never formatted", volumeID, devicePath)
+		}
+		return nil
+	}
+	if existing != "" {
+		return d.checkFilesystem(ctx, volumeID, devicePath, existing, volumeContext)
+	}
+
+	// Never format a disk whose identity is unconfirmed - it may hold another volume's data
+	if identity != deviceIdentityMatch {
+		return status.Errorf(codes.FailedPrecondition,
+			"refusing to format device %s: could not confirm it belongs to volume %s", devicePath, volumeID)
+	}
+	klog.Infof("Formatting device %s with %s", devicePath, fsType)
+	if err := formatDevice(devicePath, fsType); err != nil {
+		return status.Errorf(codes.Internal, "failed to format device: %v", err)
+	}
+	return nil
+}
+
+// probeFsType returns the filesystem on a device, "" if it has none (a variable so tests can fake blkid)
+var probeFsType = blkidFsType
+
+func blkidFsType(devicePath string) (string, error) {
 	cmd := exec.Command("blkid", "-p", "-s", "TYPE", "-o", "value", devicePath)
 	output, err := cmd.Output()
 	if err != nil {
 		// Exit code 2 means no filesystem found
 		if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 2 {
-			return false, nil
+			return "", nil
 		}
-		return false, err
+		return "", err
 	}
-	return len(strings.TrimSpace(string(output))) > 0, nil
+	return strings.TrimSpace(string(output)), nil
 }
 
-func formatDevice(devicePath, fsType string) error {
+// checkStagedFsType verifies that a volume found already staged carries the requested filesystem,
+// so staging it again with another fsType, e.g. after a StorageClass change, fails with
+// AlreadyExists as the CSI spec asks instead of reporting success on the wrong filesystem
+func checkStagedFsType(devicePath, fsType string) error {
+	existing, err := probeFsType(devicePath)
+	if err != nil {
+		return status.Errorf(codes.Internal, "failed to detect filesystem of device %s: %v", devicePath, err)
+	}
+	if !strings.EqualFold(existing, fsType) {
+		return status.Errorf(codes.AlreadyExists,
+			"device %s is already staged with filesystem %q, incompatible with the requested %q", devicePath, existing, fsType)
+	}
+	return nil
+}
+
+// formatDevice creates an fsType filesystem on a device (a variable so tests can fake mkfs)
+var formatDevice = mkfs
+
+func mkfs(devicePath, fsType string) error {
 	var cmd *exec.Cmd
 	switch fsType {
 	case "ext4":
@@ -488,6 +618,23 @@ func resizeFilesystem(devicePath, mountPoint string) error {
 	return nil
 }
 
+// findPublishedDevice finds the device of a published volume by the serial the controller gave
+// its drive, falling back to the channel for drives attached without one. A serial names one
+// device, so waiting for its link needs no lock; the channel lookup compares the node's devices
+// before and after it looks and holds lock so it is serialized across volumes.
+func findPublishedDevice(publishContext map[string]string, lock sync.Locker) (string, error) {
+	if serial := publishContext["serial"]; serial != "" {
+		if device := findDeviceBySerial(serial); device != "" {
+			return device, nil
+		}
+		klog.Warningf("Volume %s has no device with serial %s, falling back to channel lookup",
+			publishContext["volumeId"], serial)
+	}
+	lock.Lock()
+	defer lock.Unlock()
+	return findDeviceByPath(publishContext)
+}
+
 // findDeviceByPath finds the device using /dev/disk/by-path/ based on channel
 // This is battle-proof with NO FALLBACKS - either we find the correct device or we fail
 func findDeviceByPath(publishContext map[string]string) (string, error) {
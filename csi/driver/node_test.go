@@ -0,0 +1,60 @@
+/*
+Copyright 2025 Kube-DC Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"errors"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestCheckStagedFsType(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing string
+		probeErr error
+		fsType   string
+		wantCode codes.Code
+	}{
+		{name: "matching filesystem", existing: "ext4", fsType: "ext4", wantCode: codes.OK},
+		{name: "matching xfs", existing: "xfs", fsType: "xfs", wantCode: codes.OK},
+		{name: "staged as ext4, xfs requested", existing: "ext4", fsType: "xfs", wantCode: codes.AlreadyExists},
+		{name: "staged as xfs, ext4 requested", existing: "xfs", fsType: "ext4", wantCode: codes.AlreadyExists},
+		{name: "no filesystem on the staged device", existing: "", fsType: "ext4", wantCode: codes.AlreadyExists},
+		{name: "blkid fails", probeErr: errors.New("exit status 4"), fsType: "ext4", wantCode: codes.Internal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			oldProbe := probeFsType
+			probeFsType = func(devicePath string) (string, error) {
+				if devicePath != "/dev/vdb" {
+					t.Errorf("probed %s, want /dev/vdb", devicePath)
+				}
+				return tt.existing, tt.probeErr
+			}
+			t.Cleanup(func() { probeFsType = oldProbe })
+
+			err := checkStagedFsType("/dev/vdb", tt.fsType)
+			if got := status.Code(err); got != tt.wantCode {
+				t.Errorf("checkStagedFsType() = %v, want code %v", err, tt.wantCode)
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+/*
+Copyright 2025 Kube-DC Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"fmt"
+	"strconv"
+)
+
+const (
+	// ParameterIOPS is the StorageClass parameter limiting a volume's I/O operations per second
+	ParameterIOPS = "iops"
+	// ParameterThroughput is the StorageClass parameter limiting a volume's throughput in MiB/s
+	ParameterThroughput = "throughput"
+)
+
+// volumeQoS holds per-volume QoS limits; zero means unlimited
+type volumeQoS struct {
+	IOPS       int64
+	Throughput int64 // MiB/s
+}
+
+// qosTiers maps the storage types that accept per-volume QoS limits to the highest limits they
+// accept. CloudSigma's drive API has no QoS attributes for dssd or zadara drives, so no tier is
+// listed and QoS parameters are rejected rather than silently ignored.
+var qosTiers = map[string]volumeQoS{}
+
+// parseVolumeQoS reads the iops and throughput StorageClass parameters
+func parseVolumeQoS(params map[string]string) (volumeQoS, error) {
+	iops, err := parseLimitParameter(params, ParameterIOPS)
+	if err != nil {
+		return volumeQoS{}, err
+	}
+	throughput, err := parseLimitParameter(params, ParameterThroughput)
+	if err != nil {
+		return volumeQoS{}, err
+	}
+	return volumeQoS{IOPS: iops, Throughput: throughput}, nil
+}
+
+// parseLimitParameter returns the positive integer parameter key, or 0 if it is not set
+func parseLimitParameter(params map[string]string, key string) (int64, error) {
+	value, ok := params[key]
+	if !ok {
+		return 0, nil
+	}
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("%s must be a positive integer, got %q", key, value)
+	}
+	return n, nil
+}
+
+// validateVolumeQoS returns an error if storageType does not support the requested QoS limits
+func validateVolumeQoS(qos volumeQoS, storageType string) error {
+	if qos == (volumeQoS{}) {
+		return nil
+	}
+	limits, ok := qosTiers[storageType]
+	if !ok {
+		return fmt.Errorf("storageType %q does not support per-volume QoS limits, remove the %s and %s parameters",
+			storageType, ParameterIOPS, ParameterThroughput)
+	}
+	if qos.IOPS > 0 && limits.IOPS == 0 {
+		return fmt.Errorf("storageType %q supports no %s limit", storageType, ParameterIOPS)
+	}
+	if qos.IOPS > limits.IOPS {
+		return fmt.Errorf("%s %d exceeds the maximum of %d for storageType %q", ParameterIOPS, qos.IOPS, limits.IOPS, storageType)
+	}
+	if qos.Throughput > 0 && limits.Throughput == 0 {
+		return fmt.Errorf("storageType %q supports no %s limit", storageType, ParameterThroughput)
+	}
+	if qos.Throughput > limits.Throughput {
+		return fmt.Errorf("%s %d exceeds the maximum of %d MiB/s for storageType %q", ParameterThroughput, qos.Throughput, limits.Throughput, storageType)
+	}
+	return nil
+}
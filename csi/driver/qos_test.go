@@ -0,0 +1,126 @@
+/*
+Copyright 2025 Kube-DC Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestParseVolumeQoS(t *testing.T) {
+	tests := []struct {
+		name    string
+		params  map[string]string
+		want    volumeQoS
+		wantErr string
+	}{
+		{name: "unset"},
+		{name: "other parameters", params: map[string]string{"storageType": "dssd"}},
+		{name: "both", params: map[string]string{"iops": "3000", "throughput": "125"}, want: volumeQoS{IOPS: 3000, Throughput: 125}},
+		{name: "iops only", params: map[string]string{"iops": "500"}, want: volumeQoS{IOPS: 500}},
+		{name: "not a number", params: map[string]string{"iops": "fast"}, wantErr: "iops must be a positive integer"},
+		{name: "unit suffix", params: map[string]string{"throughput": "125Mi"}, wantErr: "throughput must be a positive integer"},
+		{name: "zero", params: map[string]string{"iops": "0"}, wantErr: "iops must be a positive integer"},
+		{name: "negative", params: map[string]string{"throughput": "-1"}, wantErr: "throughput must be a positive integer"},
+		{name: "empty", params: map[string]string{"iops": ""}, wantErr: "iops must be a positive integer"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseVolumeQoS(tt.params)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("parseVolumeQoS() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseVolumeQoS() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("parseVolumeQoS() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateVolumeQoS(t *testing.T) {
+	oldTiers := qosTiers
+	defer func() { qosTiers = oldTiers }()
+	qosTiers = map[string]volumeQoS{"limited": {IOPS: 10000}}
+
+	tests := []struct {
+		name        string
+		qos         volumeQoS
+		storageType string
+		wantErr     string
+	}{
+		{name: "no limits on any tier", storageType: StorageTypeDSSD},
+		{name: "dssd", qos: volumeQoS{IOPS: 1000}, storageType: StorageTypeDSSD, wantErr: "does not support per-volume QoS"},
+		{name: "zadara", qos: volumeQoS{Throughput: 100}, storageType: StorageTypeMagnetic, wantErr: "does not support per-volume QoS"},
+		{name: "within range", qos: volumeQoS{IOPS: 10000}, storageType: "limited"},
+		{name: "above range", qos: volumeQoS{IOPS: 10001}, storageType: "limited", wantErr: "exceeds the maximum of 10000"},
+		{name: "unsupported limit", qos: volumeQoS{Throughput: 100}, storageType: "limited", wantErr: "supports no throughput limit"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateVolumeQoS(tt.qos, tt.storageType)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("validateVolumeQoS() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("validateVolumeQoS() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCreateVolume_QoSUnsupportedTier(t *testing.T) {
+	created := false
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/2.0/drives/", func(w http.ResponseWriter, r *http.Request) {
+		created = true
+		http.Error(w, "unexpected drive request", http.StatusInternalServerError)
+	})
+	d := newTestDriver(t, mux)
+
+	_, err := d.CreateVolume(context.Background(), &csi.CreateVolumeRequest{
+		Name:       "pvc-1",
+		Parameters: map[string]string{"storageType": StorageTypeDSSD, ParameterIOPS: "3000"},
+		VolumeCapabilities: []*csi.VolumeCapability{{
+			AccessType: &csi.VolumeCapability_Mount{Mount: &csi.VolumeCapability_MountVolume{}},
+			AccessMode: &csi.VolumeCapability_AccessMode{Mode: csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER},
+		}},
+	})
+	if got := status.Code(err); got != codes.InvalidArgument {
+		t.Fatalf("CreateVolume() code = %v, want %v (err: %v)", got, codes.InvalidArgument, err)
+	}
+	if !strings.Contains(status.Convert(err).Message(), "does not support per-volume QoS") {
+		t.Errorf("error %q doesn't explain that the tier has no QoS", status.Convert(err).Message())
+	}
+	if created {
+		t.Error("CreateVolume() sent a drive request for a rejected QoS parameter")
+	}
+}
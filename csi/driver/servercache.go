@@ -0,0 +1,196 @@
+/*
+Copyright 2025 Kube-DC Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"maps"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/cloudsigma/cloudsigma-sdk-go/cloudsigma"
+	"k8s.io/klog/v2"
+)
+
+// defaultServerCacheTTL is how long a server snapshot is reused between publish/unpublish calls
+const defaultServerCacheTTL = 5 * time.Second
+
+// serverCache keeps short-lived snapshots of servers keyed by node UUID so back-to-back
+// attach/detach operations on the same node don't each issue a Servers.Get.
+// Entries are refreshed from the response of every successful Servers.Update made through
+// the driver and dropped when an update fails.
+type serverCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[string]serverCacheEntry
+}
+
+type serverCacheEntry struct {
+	server  *cloudsigma.Server
+	serials map[string]string
+	fetched time.Time
+}
+
+func newServerCache(ttl time.Duration) *serverCache {
+	return &serverCache{
+		ttl:     ttl,
+		entries: make(map[string]serverCacheEntry),
+	}
+}
+
+// get returns a copy of the cached server and its drive serials, or nil if there is no fresh entry
+func (c *serverCache) get(serverID string) (*cloudsigma.Server, map[string]string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[serverID]
+	if !ok {
+		return nil, nil
+	}
+	if time.Since(entry.fetched) > c.ttl {
+		delete(c.entries, serverID)
+		return nil, nil
+	}
+	return copyServer(entry.server), maps.Clone(entry.serials)
+}
+
+func (c *serverCache) put(serverID string, server *cloudsigma.Server, serials map[string]string) {
+	if c.ttl <= 0 || server == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[serverID] = serverCacheEntry{server: copyServer(server), serials: maps.Clone(serials), fetched: time.Now()}
+}
+
+func (c *serverCache) invalidate(serverID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, serverID)
+}
+
+// copyServer copies the server and its drive list so callers can modify the drives freely
+func copyServer(server *cloudsigma.Server) *cloudsigma.Server {
+	cp := *server
+	cp.Drives = append([]cloudsigma.ServerDrive(nil), server.Drives...)
+	return &cp
+}
+
+// getServer returns the server and the serials of its drives (see fetchServer) from the cache if
+// fresh, otherwise fetches them from the API. Callers that go on to modify the server must hold
+// the per-server lock.
+func (d *Driver) getServer(ctx context.Context, serverID string) (*cloudsigma.Server, map[string]string, error) {
+	if server, serials := d.serverCache.get(serverID); server != nil {
+		klog.V(5).Infof("Using cached snapshot of server %s", serverID)
+		return server, serials, nil
+	}
+
+	server, serials, err := d.fetchServer(ctx, serverID)
+	if err != nil {
+		return nil, nil, err
+	}
+	d.serverCache.put(serverID, server, serials)
+	return server, serials, nil
+}
+
+// fetchServer reads the server from the API, bypassing the cache. Besides the server it returns
+// the virtio serial of each drive that has one, keyed by drive UUID.
+func (d *Driver) fetchServer(ctx context.Context, serverID string) (*cloudsigma.Server, map[string]string, error) {
+	req, err := d.cloud().NewRequest(http.MethodGet, "servers/"+serverID+"/", nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	resp := new(serverWithSerials)
+	if _, err := d.cloud().Do(ctx, req, resp); err != nil {
+		return nil, nil, err
+	}
+	server, serials := resp.split()
+	return server, serials, nil
+}
+
+// serverDriveUpdate is a server drive plus its virtio serial, which the SDK does not model
+type serverDriveUpdate struct {
+	cloudsigma.ServerDrive
+	Serial string `json:"serial,omitempty"`
+}
+
+// serverWithSerials is a server whose drives carry serials, as the API returns and accepts it
+type serverWithSerials struct {
+	*cloudsigma.Server
+	Drives []serverDriveUpdate `json:"drives,omitempty"`
+}
+
+// split returns the server with its drives and the serials of the drives, keyed by drive UUID
+func (s *serverWithSerials) split() (*cloudsigma.Server, map[string]string) {
+	server := new(cloudsigma.Server)
+	if s.Server != nil {
+		*server = *s.Server
+	}
+	server.Drives = make([]cloudsigma.ServerDrive, 0, len(s.Drives))
+	serials := make(map[string]string)
+	for _, sd := range s.Drives {
+		server.Drives = append(server.Drives, sd.ServerDrive)
+		if sd.Serial != "" && sd.Drive != nil {
+			serials[sd.Drive.UUID] = sd.Serial
+		}
+	}
+	return server, serials
+}
+
+// newServerUpdate builds the update request for server. Each drive is sent with its serial from
+// serials, so drives keep the serial they have and only a drive the caller added a serial for gets
+// a new one; drives without an entry are sent without a serial, as the API listed them.
+//
+// The read-only fields a GET echoes back are left out, as the API may reject them on update, and
+// drives are referenced by UUID alone rather than with the drive details the server lists.
+func newServerUpdate(server *cloudsigma.Server, serials map[string]string) *serverWithSerials {
+	body := *server
+	body.UUID = ""
+	body.ResourceURI = ""
+	body.Runtime = nil
+	body.Status = ""
+	body.Owner = nil
+	update := &serverWithSerials{Server: &body, Drives: make([]serverDriveUpdate, 0, len(server.Drives))}
+	for _, sd := range server.Drives {
+		drive := serverDriveUpdate{ServerDrive: sd}
+		if sd.Drive != nil {
+			drive.Drive = &cloudsigma.Drive{UUID: sd.Drive.UUID}
+			drive.Serial = serials[sd.Drive.UUID]
+		}
+		update.Drives = append(update.Drives, drive)
+	}
+	return update
+}
+
+// updateServer updates the server, sending each drive with its serial from serials, and keeps the
+// cache in step with the result
+func (d *Driver) updateServer(ctx context.Context, serverID string, server *cloudsigma.Server, serials map[string]string) error {
+	req, err := d.cloud().NewRequest(http.MethodPut, "servers/"+serverID+"/", newServerUpdate(server, serials))
+	if err != nil {
+		return err
+	}
+	resp := new(serverWithSerials)
+	if _, err = d.cloud().Do(ctx, req, resp); err != nil {
+		d.serverCache.invalidate(serverID)
+		return err
+	}
+	updated, updatedSerials := resp.split()
+	d.serverCache.put(serverID, updated, updatedSerials)
+	return nil
+}
@@ -0,0 +1,115 @@
+/*
+Copyright 2025 Kube-DC Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/cloudsigma/cloudsigma-sdk-go/cloudsigma"
+)
+
+func TestNewServerUpdate_ReadOnlyFields(t *testing.T) {
+	server := &cloudsigma.Server{
+		UUID:        "node-1",
+		Name:        "node-1",
+		CPU:         2000,
+		Memory:      2 << 30,
+		Status:      "running",
+		ResourceURI: "/api/2.0/servers/node-1/",
+		Owner:       &cloudsigma.ResourceLink{UUID: "owner-1"},
+		Runtime:     &cloudsigma.ServerRuntime{},
+		Meta:        map[string]interface{}{"machine-uid": "uid-1"},
+		Drives: []cloudsigma.ServerDrive{{DevChannel: "0:2", Device: "virtio", Drive: &cloudsigma.Drive{
+			UUID:        "vol-1",
+			Status:      "mounted",
+			ResourceURI: "/api/2.0/drives/vol-1/",
+			MountedOn:   []cloudsigma.ResourceLink{{UUID: "node-1"}},
+			Owner:       &cloudsigma.ResourceLink{UUID: "owner-1"},
+		}}},
+	}
+
+	data, err := json.Marshal(newServerUpdate(server, nil))
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	var body map[string]interface{}
+	if err := json.Unmarshal(data, &body); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+
+	for _, field := range []string{"uuid", "status", "resource_uri", "owner", "runtime"} {
+		if _, ok := body[field]; ok {
+			t.Errorf("server update has read-only field %q", field)
+		}
+	}
+	for _, field := range []string{"name", "cpu", "mem", "meta"} {
+		if _, ok := body[field]; !ok {
+			t.Errorf("server update is missing field %q", field)
+		}
+	}
+	drive := body["drives"].([]interface{})[0].(map[string]interface{})["drive"].(map[string]interface{})
+	if len(drive) != 1 || drive["uuid"] != "vol-1" {
+		t.Errorf("server update drive = %v, want only its uuid", drive)
+	}
+
+	// The caller's server is left as it was
+	if server.Status != "running" || server.Drives[0].Drive.Status != "mounted" {
+		t.Errorf("newServerUpdate() modified its argument: %+v", server)
+	}
+}
+
+func TestNewServerUpdate_Serials(t *testing.T) {
+	server := &cloudsigma.Server{
+		Name: "node-1",
+		Drives: []cloudsigma.ServerDrive{
+			{DevChannel: "0:0", Device: "virtio", Drive: &cloudsigma.Drive{UUID: "boot"}},
+			{DevChannel: "0:1", Device: "virtio", Drive: &cloudsigma.Drive{UUID: "vol-1"}},
+			{DevChannel: "0:2", Device: "virtio", Drive: &cloudsigma.Drive{UUID: "vol-2"}},
+		},
+	}
+	serials := map[string]string{"vol-1": "custom-serial", "vol-2": driveSerial("vol-2")}
+
+	update := newServerUpdate(server, serials)
+
+	want := map[string]string{"boot": "", "vol-1": "custom-serial", "vol-2": driveSerial("vol-2")}
+	for _, drive := range update.Drives {
+		if got := drive.Serial; got != want[drive.Drive.UUID] {
+			t.Errorf("drive %s serial = %q, want %q", drive.Drive.UUID, got, want[drive.Drive.UUID])
+		}
+	}
+}
+
+func TestServerWithSerials_Split(t *testing.T) {
+	data := []byte(`{"uuid": "node-1", "name": "node-1", "drives": [
+		{"dev_channel": "0:0", "device": "virtio", "drive": {"uuid": "boot"}},
+		{"dev_channel": "0:1", "device": "virtio", "drive": {"uuid": "vol-1"}, "serial": "serial-1"}
+	]}`)
+	resp := new(serverWithSerials)
+	if err := json.Unmarshal(data, resp); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+
+	server, serials := resp.split()
+
+	if server.UUID != "node-1" || len(server.Drives) != 2 {
+		t.Errorf("split() server = %+v, want node-1 with 2 drives", server)
+	}
+	if len(serials) != 1 || serials["vol-1"] != "serial-1" {
+		t.Errorf("split() serials = %v, want only vol-1", serials)
+	}
+}
@@ -28,7 +28,7 @@ import (
 // tagDrive adds tags to a drive in CloudSigma for tracking which cluster/volume is using it.
 // Tags follow the same pattern as the LB controller: cluster:<name>, volume:<name>, managed-by:cloudsigma-csi
 func (d *Driver) tagDrive(ctx context.Context, driveUUID, volumeName string) {
-	if d.cloudClient == nil {
+	if d.cloud() == nil {
 		klog.V(2).Info("CloudSigma client not initialized, skipping drive tagging")
 		return
 	}
@@ -54,12 +54,12 @@ func (d *Driver) tagDrive(ctx context.Context, driveUUID, volumeName string) {
 
 // untagDrive removes a drive from all CSI-managed tags in CloudSigma.
 func (d *Driver) untagDrive(ctx context.Context, driveUUID string) {
-	if d.cloudClient == nil {
+	if d.cloud() == nil {
 		klog.V(2).Info("CloudSigma client not initialized, skipping drive untagging")
 		return
 	}
 
-	tags, _, err := d.cloudClient.Tags.List(ctx)
+	tags, _, err := d.cloud().Tags.List(ctx)
 	if err != nil {
 		klog.Warningf("Failed to list tags for drive cleanup %s: %v", driveUUID, err)
 		return
@@ -84,13 +84,24 @@ func (d *Driver) untagDrive(ctx context.Context, driveUUID string) {
 			continue
 		}
 
+		// The SDK drops an empty resource list from the update, so a tag left without
+		// resources is deleted instead
+		if len(newResources) == 0 {
+			if _, err := d.cloud().Tags.Delete(ctx, tag.UUID); err != nil {
+				klog.Warningf("Failed to delete tag %s emptied of drive %s: %v", tag.Name, driveUUID, err)
+			} else {
+				klog.V(2).Infof("Deleted tag %s emptied of drive %s", tag.Name, driveUUID)
+			}
+			continue
+		}
+
 		updateReq := &cloudsigma.TagUpdateRequest{
 			Tag: &cloudsigma.Tag{
 				Name:      tag.Name,
 				Resources: newResources,
 			},
 		}
-		_, _, err := d.cloudClient.Tags.Update(ctx, tag.UUID, updateReq)
+		_, _, err := d.cloud().Tags.Update(ctx, tag.UUID, updateReq)
 		if err != nil {
 			klog.Warningf("Failed to remove drive %s from tag %s: %v", driveUUID, tag.Name, err)
 		} else {
@@ -103,7 +114,7 @@ func (d *Driver) untagDrive(ctx context.Context, driveUUID string) {
 
 // ensureTagWithResource creates a tag if it doesn't exist and adds the resource to it.
 func (d *Driver) ensureTagWithResource(ctx context.Context, tagName, resourceUUID string) error {
-	tags, _, err := d.cloudClient.Tags.List(ctx)
+	tags, _, err := d.cloud().Tags.List(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to list tags: %w", err)
 	}
@@ -125,7 +136,7 @@ func (d *Driver) ensureTagWithResource(ctx context.Context, tagName, resourceUUI
 					Resources: tag.Resources,
 				},
 			}
-			_, _, err := d.cloudClient.Tags.Update(ctx, tag.UUID, updateReq)
+			_, _, err := d.cloud().Tags.Update(ctx, tag.UUID, updateReq)
 			if err != nil {
 				return fmt.Errorf("failed to update tag %s: %w", tagName, err)
 			}
@@ -143,7 +154,7 @@ func (d *Driver) ensureTagWithResource(ctx context.Context, tagName, resourceUUI
 			},
 		},
 	}
-	_, _, err = d.cloudClient.Tags.Create(ctx, createReq)
+	_, _, err = d.cloud().Tags.Create(ctx, createReq)
 	if err != nil {
 		return fmt.Errorf("failed to create tag %s: %w", tagName, err)
 	}
@@ -0,0 +1,228 @@
+/*
+Copyright 2025 Kube-DC Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/cloudsigma/cloudsigma-sdk-go/cloudsigma"
+	"github.com/container-storage-interface/spec/lib/go/csi"
+)
+
+// fakeTagAPI is an in-memory /tags/ endpoint
+type fakeTagAPI struct {
+	mu   sync.Mutex
+	tags []cloudsigma.Tag
+}
+
+func (f *fakeTagAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	uuid := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/2.0/tags/"), "/")
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, map[string]interface{}{"objects": f.tags})
+	case http.MethodPost:
+		var req cloudsigma.TagCreateRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		for i := range req.Tags {
+			req.Tags[i].UUID = fmt.Sprintf("tag-%d", len(f.tags))
+			f.tags = append(f.tags, req.Tags[i])
+		}
+		writeJSON(w, map[string]interface{}{"objects": req.Tags})
+	case http.MethodPut:
+		var updated cloudsigma.Tag
+		_ = json.NewDecoder(r.Body).Decode(&updated)
+		for i := range f.tags {
+			if f.tags[i].UUID == uuid {
+				f.tags[i].Resources = updated.Resources
+				writeJSON(w, f.tags[i])
+				return
+			}
+		}
+		w.WriteHeader(http.StatusNotFound)
+	case http.MethodDelete:
+		for i := range f.tags {
+			if f.tags[i].UUID == uuid {
+				f.tags = append(f.tags[:i], f.tags[i+1:]...)
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+// tagsOf returns the sorted names of the tags that contain resourceUUID
+func (f *fakeTagAPI) tagsOf(resourceUUID string) []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var names []string
+	for _, tag := range f.tags {
+		for _, r := range tag.Resources {
+			if r.UUID == resourceUUID {
+				names = append(names, tag.Name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestVolumeTagging_ClusterName(t *testing.T) {
+	const volumeID = "vol-1"
+
+	drv, err := NewDriver(&Config{Mode: ControllerMode, ClusterName: "prod-eu"})
+	if err != nil {
+		t.Fatalf("NewDriver() error = %v", err)
+	}
+	if drv.clusterName != "prod-eu" {
+		t.Fatalf("NewDriver() clusterName = %q, want %q", drv.clusterName, "prod-eu")
+	}
+
+	// Another cluster's volume shares the managed-by tag and must keep it
+	tagAPI := &fakeTagAPI{tags: []cloudsigma.Tag{
+		{UUID: "tag-other", Name: "managed-by:cloudsigma-csi", Resources: []cloudsigma.TagResource{{UUID: "vol-other"}}},
+	}}
+
+	mux := http.NewServeMux()
+	mux.Handle("/api/2.0/tags/", tagAPI)
+	mux.HandleFunc("/api/2.0/drives/detail/", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]interface{}{"objects": []cloudsigma.Drive{}})
+	})
+	mux.HandleFunc("/api/2.0/drives/", func(w http.ResponseWriter, r *http.Request) {
+		var req cloudsigma.DriveCreateRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		for i := range req.Drives {
+			req.Drives[i].UUID = volumeID
+		}
+		writeJSON(w, map[string]interface{}{"objects": req.Drives})
+	})
+	mux.HandleFunc("/api/2.0/drives/"+volumeID+"/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodDelete {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		writeJSON(w, cloudsigma.Drive{UUID: volumeID, Status: "unmounted"})
+	})
+
+	d := newTestDriver(t, mux)
+	d.clusterName = drv.clusterName
+
+	_, err = d.CreateVolume(context.Background(), &csi.CreateVolumeRequest{
+		Name: "pvc-1",
+		VolumeCapabilities: []*csi.VolumeCapability{{
+			AccessType: &csi.VolumeCapability_Mount{Mount: &csi.VolumeCapability_MountVolume{}},
+			AccessMode: &csi.VolumeCapability_AccessMode{Mode: csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER},
+		}},
+	})
+	if err != nil {
+		t.Fatalf("CreateVolume() error = %v", err)
+	}
+
+	want := []string{"cluster:prod-eu", "managed-by:cloudsigma-csi", "volume:pvc-1"}
+	if got := tagAPI.tagsOf(volumeID); strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("tags after CreateVolume = %v, want %v", got, want)
+	}
+
+	if _, err := d.DeleteVolume(context.Background(), &csi.DeleteVolumeRequest{VolumeId: volumeID}); err != nil {
+		t.Fatalf("DeleteVolume() error = %v", err)
+	}
+	if got := tagAPI.tagsOf(volumeID); len(got) != 0 {
+		t.Errorf("tags after DeleteVolume = %v, want none", got)
+	}
+	if got := tagAPI.tagsOf("vol-other"); len(got) != 1 {
+		t.Errorf("other volume tags after DeleteVolume = %v, want managed-by tag kept", got)
+	}
+}
+
+func TestCreateVolume_Tagging(t *testing.T) {
+	const volumeID = "vol-1"
+
+	tests := []struct {
+		name     string
+		existing bool
+		tagsDown bool
+		wantTags []string
+	}{
+		{name: "new drive", wantTags: []string{"managed-by:cloudsigma-csi", "volume:pvc-1"}},
+		{name: "existing untagged drive", existing: true, wantTags: []string{"managed-by:cloudsigma-csi", "volume:pvc-1"}},
+		{name: "tag API failing", tagsDown: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tagAPI := &fakeTagAPI{}
+			created := false
+
+			mux := http.NewServeMux()
+			mux.HandleFunc("/api/2.0/tags/", func(w http.ResponseWriter, r *http.Request) {
+				if tt.tagsDown {
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+				tagAPI.ServeHTTP(w, r)
+			})
+			mux.HandleFunc("/api/2.0/drives/detail/", func(w http.ResponseWriter, r *http.Request) {
+				drives := []cloudsigma.Drive{}
+				if tt.existing {
+					drives = append(drives, cloudsigma.Drive{UUID: volumeID, Name: "pvc-1", Size: DefaultVolumeSize, Status: "unmounted"})
+				}
+				writeJSON(w, map[string]interface{}{"objects": drives})
+			})
+			mux.HandleFunc("/api/2.0/drives/", func(w http.ResponseWriter, r *http.Request) {
+				created = true
+				var req cloudsigma.DriveCreateRequest
+				_ = json.NewDecoder(r.Body).Decode(&req)
+				for i := range req.Drives {
+					req.Drives[i].UUID = volumeID
+				}
+				writeJSON(w, map[string]interface{}{"objects": req.Drives})
+			})
+
+			d := newTestDriver(t, mux)
+			resp, err := d.CreateVolume(context.Background(), &csi.CreateVolumeRequest{
+				Name: "pvc-1",
+				VolumeCapabilities: []*csi.VolumeCapability{{
+					AccessType: &csi.VolumeCapability_Mount{Mount: &csi.VolumeCapability_MountVolume{}},
+					AccessMode: &csi.VolumeCapability_AccessMode{Mode: csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER},
+				}},
+			})
+			if err != nil {
+				t.Fatalf("CreateVolume() error = %v", err)
+			}
+			if resp.Volume.VolumeId != volumeID {
+				t.Errorf("VolumeId = %q, want %q", resp.Volume.VolumeId, volumeID)
+			}
+			if created == tt.existing {
+				t.Errorf("drive created = %v, want %v", created, !tt.existing)
+			}
+			if got := tagAPI.tagsOf(volumeID); strings.Join(got, ",") != strings.Join(tt.wantTags, ",") {
+				t.Errorf("tags = %v, want %v", got, tt.wantTags)
+			}
+		})
+	}
+}
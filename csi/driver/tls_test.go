@@ -0,0 +1,226 @@
+/*
+Copyright 2025 Kube-DC Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+// testCert is a generated certificate and its key
+type testCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	der  []byte
+}
+
+// newTestCert creates a certificate from template, signed by parent or self-signed when parent is nil
+func newTestCert(t *testing.T, template *x509.Certificate, parent *testCert) *testCert {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, signerKey := template, key
+	if parent != nil {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testCert{cert: cert, key: key, der: der}
+}
+
+func certTemplate(cn string, serial int64, notAfter time.Time) *x509.Certificate {
+	return &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     notAfter,
+	}
+}
+
+func newTestCA(t *testing.T, cn string) *testCert {
+	tmpl := certTemplate(cn, 1, time.Now().Add(time.Hour))
+	tmpl.IsCA = true
+	tmpl.BasicConstraintsValid = true
+	tmpl.KeyUsage = x509.KeyUsageCertSign
+	return newTestCert(t, tmpl, nil)
+}
+
+// writePEM writes the certificate and key of c to dir and returns their paths
+func (c *testCert) writePEM(t *testing.T, dir, name string) (string, string) {
+	t.Helper()
+	keyDER, err := x509.MarshalECPrivateKey(c.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certFile := filepath.Join(dir, name+".crt")
+	keyFile := filepath.Join(dir, name+".key")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile
+}
+
+func (c *testCert) tlsCertificate() tls.Certificate {
+	return tls.Certificate{Certificate: [][]byte{c.der}, PrivateKey: c.key}
+}
+
+func TestLoadServerTLS(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t, "csi-ca")
+	caFile, _ := ca.writePEM(t, dir, "ca")
+	server := newTestCert(t, certTemplate("csi-server", 2, time.Now().Add(time.Hour)), ca)
+	certFile, keyFile := server.writePEM(t, dir, "server")
+	expired := newTestCert(t, certTemplate("csi-expired", 3, time.Now().Add(-time.Minute)), ca)
+	expiredCert, expiredKey := expired.writePEM(t, dir, "expired")
+	notPEM := filepath.Join(dir, "not-pem")
+	if err := os.WriteFile(notPEM, []byte("not a certificate"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		cert     string
+		key      string
+		clientCA string
+		wantErr  string
+		wantMTLS bool
+	}{
+		{name: "server certificate", cert: certFile, key: keyFile},
+		{name: "with client CA", cert: certFile, key: keyFile, clientCA: caFile, wantMTLS: true},
+		{name: "missing key", cert: certFile, wantErr: "both a certificate and a key"},
+		{name: "client CA without certificate", clientCA: caFile, wantErr: "both a certificate and a key"},
+		{name: "unreadable certificate", cert: filepath.Join(dir, "missing.crt"), key: keyFile, wantErr: "failed to load"},
+		{name: "mismatched key", cert: certFile, key: expiredKey, wantErr: "failed to load"},
+		{name: "expired certificate", cert: expiredCert, key: expiredKey, wantErr: "only valid from"},
+		{name: "missing client CA", cert: certFile, key: keyFile, clientCA: filepath.Join(dir, "missing-ca.crt"), wantErr: "failed to read client CA"},
+		{name: "client CA without certificates", cert: certFile, key: keyFile, clientCA: notPEM, wantErr: "no certificates found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := loadServerTLS(tt.cert, tt.key, tt.clientCA)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := cfg.ClientAuth == tls.RequireAndVerifyClientCert; got != tt.wantMTLS {
+				t.Errorf("client certificate required = %v, want %v", got, tt.wantMTLS)
+			}
+		})
+	}
+}
+
+func TestDriverServesMutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t, "csi-ca")
+	caFile, _ := ca.writePEM(t, dir, "ca")
+	serverTmpl := certTemplate("csi-server", 2, time.Now().Add(time.Hour))
+	serverTmpl.IPAddresses = []net.IP{net.ParseIP("127.0.0.1")}
+	serverTmpl.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}
+	certFile, keyFile := newTestCert(t, serverTmpl, ca).writePEM(t, dir, "server")
+
+	clientTmpl := certTemplate("csi-client", 3, time.Now().Add(time.Hour))
+	clientTmpl.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}
+	client := newTestCert(t, clientTmpl, ca)
+	rogueCA := newTestCA(t, "rogue-ca")
+	rogue := newTestCert(t, clientTmpl, rogueCA)
+
+	d, err := NewDriver(&Config{
+		Name:            DriverName,
+		Version:         DriverVersion,
+		Endpoint:        "tcp://127.0.0.1:0",
+		NodeID:          "test-node",
+		Mode:            NodeMode,
+		TLSCertFile:     certFile,
+		TLSKeyFile:      keyFile,
+		TLSClientCAFile: caFile,
+	})
+	if err != nil {
+		t.Fatalf("NewDriver: %v", err)
+	}
+	listener, err := d.listen()
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	go func() { _ = d.serve(listener) }()
+	t.Cleanup(d.Stop)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+
+	tests := []struct {
+		name    string
+		certs   []tls.Certificate
+		wantErr bool
+	}{
+		{name: "trusted client certificate", certs: []tls.Certificate{client.tlsCertificate()}},
+		{name: "no client certificate", wantErr: true},
+		{name: "untrusted client certificate", certs: []tls.Certificate{rogue.tlsCertificate()}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			creds := credentials.NewTLS(&tls.Config{RootCAs: roots, Certificates: tt.certs, MinVersion: tls.VersionTLS12})
+			conn, err := grpc.DialContext(ctx, listener.Addr().String(), grpc.WithTransportCredentials(creds))
+			if err != nil {
+				t.Fatalf("dial: %v", err)
+			}
+			defer conn.Close()
+
+			_, err = csi.NewIdentityClient(conn).GetPluginInfo(ctx, &csi.GetPluginInfoRequest{})
+			if tt.wantErr && err == nil {
+				t.Fatal("expected the call to be rejected")
+			}
+			if !tt.wantErr && err != nil {
+				t.Fatalf("GetPluginInfo: %v", err)
+			}
+		})
+	}
+}
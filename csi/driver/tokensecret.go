@@ -0,0 +1,142 @@
+/*
+Copyright 2025 Kube-DC Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// DefaultTokenSecretNamespace is where the CCM writes the token secret unless configured otherwise
+	DefaultTokenSecretNamespace = "cloudsigma-csi"
+	// DefaultTokenSecretName is the name of the token secret unless configured otherwise
+	DefaultTokenSecretName = "cloudsigma-token"
+	// DefaultTokenSecretCheckInterval is how often WatchTokenSecret reads the token secret
+	DefaultTokenSecretCheckInterval = 30 * time.Second
+
+	// AnnotationIdentityGeneration is bumped by the CCM whenever it rewrites the token secret for
+	// another user or region
+	AnnotationIdentityGeneration = "cloudsigma.com/identity-generation"
+)
+
+// tokenSecret is what the driver uses from the token secret
+type tokenSecret struct {
+	token      string
+	region     string
+	generation string
+}
+
+// TokenFromSecret reads the access token and region the CCM provisions into the token secret
+func TokenFromSecret(ctx context.Context, kubeClient kubernetes.Interface, namespace, name string) (token, region string, err error) {
+	secret, err := readTokenSecret(ctx, kubeClient, namespace, name)
+	if err != nil {
+		return "", "", err
+	}
+	return secret.token, secret.region, nil
+}
+
+func readTokenSecret(ctx context.Context, kubeClient kubernetes.Interface, namespace, name string) (tokenSecret, error) {
+	if errs := validation.IsDNS1123Label(namespace); len(errs) > 0 {
+		return tokenSecret{}, fmt.Errorf("invalid token secret namespace %q: %s", namespace, strings.Join(errs, "; "))
+	}
+	if errs := validation.IsDNS1123Subdomain(name); len(errs) > 0 {
+		return tokenSecret{}, fmt.Errorf("invalid token secret name %q: %s", name, strings.Join(errs, "; "))
+	}
+
+	secret, err := kubeClient.CoreV1().Secrets(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return tokenSecret{}, fmt.Errorf("failed to get token secret %s/%s: %w", namespace, name, err)
+	}
+	token := strings.TrimSpace(string(secret.Data["access_token"]))
+	if token == "" {
+		return tokenSecret{}, fmt.Errorf("token secret %s/%s has no access_token", namespace, name)
+	}
+	return tokenSecret{
+		token:      token,
+		region:     string(secret.Data["region"]),
+		generation: secret.Annotations[AnnotationIdentityGeneration],
+	}, nil
+}
+
+// WatchTokenSecret reads the token secret every interval (default DefaultTokenSecretCheckInterval)
+// until ctx is done, and rebuilds the driver's CloudSigma client when the CCM refreshed the token
+// or bumped AnnotationIdentityGeneration for another user or region. The region of the secret
+// replaces the driver's region when set. The driver needs a Kubernetes client.
+func (d *Driver) WatchTokenSecret(ctx context.Context, namespace, name string, interval time.Duration) {
+	if d.kubeClient == nil {
+		klog.Warning("Token secret watch needs a Kubernetes client, not watching")
+		return
+	}
+	if interval <= 0 {
+		interval = DefaultTokenSecretCheckInterval
+	}
+	current, err := readTokenSecret(ctx, d.kubeClient, namespace, name)
+	if err != nil {
+		klog.Warningf("Failed to read token secret: %v", err)
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			current = d.syncTokenSecret(ctx, namespace, name, current)
+		}
+	}
+}
+
+// syncTokenSecret rebuilds the CloudSigma client if the token secret differs from current, and
+// returns the secret the client now uses
+func (d *Driver) syncTokenSecret(ctx context.Context, namespace, name string, current tokenSecret) tokenSecret {
+	secret, err := readTokenSecret(ctx, d.kubeClient, namespace, name)
+	if err != nil {
+		klog.Warningf("Failed to read token secret: %v", err)
+		return current
+	}
+	if secret == current {
+		return current
+	}
+
+	build := d.newCloudClient
+	if build == nil {
+		build = newTokenClient
+	}
+	d.cloudMu.Lock()
+	if secret.region != "" {
+		d.region = secret.region
+	}
+	d.cloudClient = build(secret.token, d.region)
+	region := d.region
+	d.cloudMu.Unlock()
+
+	if secret.generation != current.generation {
+		klog.Infof("Token secret %s/%s names a new identity (generation %s), rebuilt CloudSigma client for region %s",
+			namespace, name, secret.generation, region)
+	} else {
+		klog.V(2).Infof("Token secret %s/%s was refreshed, rebuilt CloudSigma client", namespace, name)
+	}
+	return secret
+}
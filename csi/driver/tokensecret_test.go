@@ -0,0 +1,137 @@
+/*
+Copyright 2025 Kube-DC Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cloudsigma/cloudsigma-sdk-go/cloudsigma"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestTokenFromSecret(t *testing.T) {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "csi-token", Namespace: "kube-system"},
+		Data: map[string][]byte{
+			"access_token": []byte("token-abc\n"),
+			"region":       []byte("sjc"),
+		},
+	}
+	empty := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "empty", Namespace: "kube-system"}}
+	kubeClient := fake.NewSimpleClientset(secret, empty)
+
+	tests := []struct {
+		name       string
+		namespace  string
+		secretName string
+		wantToken  string
+		wantRegion string
+		wantErr    bool
+	}{
+		{name: "configured location", namespace: "kube-system", secretName: "csi-token", wantToken: "token-abc", wantRegion: "sjc"},
+		{name: "default location missing", namespace: DefaultTokenSecretNamespace, secretName: DefaultTokenSecretName, wantErr: true},
+		{name: "no access token", namespace: "kube-system", secretName: "empty", wantErr: true},
+		{name: "invalid namespace", namespace: "Kube_System", secretName: "csi-token", wantErr: true},
+		{name: "invalid name", namespace: "kube-system", secretName: "csi/token", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, region, err := TokenFromSecret(context.Background(), kubeClient, tt.namespace, tt.secretName)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("TokenFromSecret() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if token != tt.wantToken || region != tt.wantRegion {
+				t.Errorf("TokenFromSecret() = %q, %q, want %q, %q", token, region, tt.wantToken, tt.wantRegion)
+			}
+		})
+	}
+}
+
+func TestSyncTokenSecret(t *testing.T) {
+	ctx := context.Background()
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        DefaultTokenSecretName,
+			Namespace:   DefaultTokenSecretNamespace,
+			Annotations: map[string]string{AnnotationIdentityGeneration: "1"},
+		},
+		Data: map[string][]byte{"access_token": []byte("token-a"), "region": []byte("zrh")},
+	}
+	kubeClient := fake.NewSimpleClientset(secret)
+
+	type build struct{ token, region string }
+	var builds []build
+	original := cloudsigma.NewClient(cloudsigma.NewTokenCredentialsProvider("token-a"))
+	d := &Driver{
+		region:      "zrh",
+		cloudClient: original,
+		kubeClient:  kubeClient,
+		newCloudClient: func(token, region string) *cloudsigma.Client {
+			builds = append(builds, build{token, region})
+			return cloudsigma.NewClient(cloudsigma.NewTokenCredentialsProvider(token))
+		},
+	}
+	current, err := readTokenSecret(ctx, kubeClient, DefaultTokenSecretNamespace, DefaultTokenSecretName)
+	if err != nil {
+		t.Fatalf("readTokenSecret() error = %v", err)
+	}
+
+	// update rewrites the secret and syncs the driver with it
+	update := func(token, region, generation string) {
+		t.Helper()
+		secret.Data = map[string][]byte{"access_token": []byte(token), "region": []byte(region)}
+		secret.Annotations[AnnotationIdentityGeneration] = generation
+		if _, err := kubeClient.CoreV1().Secrets(secret.Namespace).Update(ctx, secret, metav1.UpdateOptions{}); err != nil {
+			t.Fatalf("failed to update secret: %v", err)
+		}
+		current = d.syncTokenSecret(ctx, DefaultTokenSecretNamespace, DefaultTokenSecretName, current)
+	}
+
+	update("token-a", "zrh", "1")
+	if len(builds) != 0 || d.cloud() != original {
+		t.Fatalf("client rebuilt %v for an unchanged secret", builds)
+	}
+
+	update("token-b", "zrh", "1")
+	if len(builds) != 1 || builds[0] != (build{"token-b", "zrh"}) {
+		t.Fatalf("builds = %v, want one for the refreshed token", builds)
+	}
+	if d.cloud() == original {
+		t.Error("driver still uses the client of the old token")
+	}
+
+	update("token-c", "sjc", "2")
+	if len(builds) != 2 || builds[1] != (build{"token-c", "sjc"}) {
+		t.Fatalf("builds = %v, want one for the new identity in sjc", builds)
+	}
+	if got := d.cloudRegion(); got != "sjc" {
+		t.Errorf("region = %q, want sjc", got)
+	}
+
+	// A secret that can't be read keeps the current client
+	if err := kubeClient.CoreV1().Secrets(secret.Namespace).Delete(ctx, secret.Name, metav1.DeleteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	rebuilt := d.cloud()
+	d.syncTokenSecret(ctx, DefaultTokenSecretNamespace, DefaultTokenSecretName, current)
+	if len(builds) != 2 || d.cloud() != rebuilt {
+		t.Error("client rebuilt without a token secret")
+	}
+}
@@ -0,0 +1,107 @@
+/*
+Copyright 2025 Kube-DC Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"time"
+
+	"github.com/cloudsigma/cloudsigma-sdk-go/cloudsigma"
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"k8s.io/klog/v2"
+
+	"github.com/kube-dc/cluster-api-provider-cloudsigma/pkg/regions"
+)
+
+// nodeLocationTimeout bounds the region lookup of the node plugin at startup
+const nodeLocationTimeout = 15 * time.Second
+
+// serverLocator reports whether the server with uuid exists in region
+type serverLocator func(ctx context.Context, region, uuid string) (bool, error)
+
+// sdkServerLocator looks servers up through the API of each region with cred
+func sdkServerLocator(cred cloudsigma.CredentialsProvider) serverLocator {
+	return func(ctx context.Context, region, uuid string) (bool, error) {
+		client := regions.NewSDKClient(cred, regions.Lookup(region).APIHost)
+		_, resp, err := client.Servers.Get(ctx, uuid)
+		if err == nil {
+			return true, nil
+		}
+		if resp != nil && resp.StatusCode == 404 {
+			return false, nil
+		}
+		return false, err
+	}
+}
+
+// locateNode returns the region whose API knows the node's server, trying the configured region
+// first and then every other known region. CloudSigma locations are separate clouds, so a server
+// only exists in the one it was launched in. If no region knows the server, e.g. because the API
+// is unreachable, the configured region is returned.
+func locateNode(ctx context.Context, locate serverLocator, nodeID, configured string) string {
+	candidates := []string{configured}
+	for _, region := range regions.Names() {
+		if region != configured {
+			candidates = append(candidates, region)
+		}
+	}
+
+	var errs int
+	for _, region := range candidates {
+		if region == "" {
+			continue
+		}
+		found, err := locate(ctx, region, nodeID)
+		if err != nil {
+			klog.V(2).Infof("Failed to look up server %s in region %s: %v", nodeID, region, err)
+			errs++
+			continue
+		}
+		if found {
+			if region != configured {
+				klog.Warningf("Server %s runs in region %s, not the configured %q; using %s for topology", nodeID, region, configured, region)
+			}
+			return region
+		}
+	}
+
+	klog.Warningf("Could not find server %s in any region (%d lookups failed), using the configured region %q for topology",
+		nodeID, errs, configured)
+	return configured
+}
+
+// checkAccessibility fails a CreateVolume whose requisite topologies exclude the region the
+// controller creates volumes in. Preferred topologies only rank the requisite ones, so they never
+// fail a request. A topology without a region segment accepts any region, and a controller without
+// a configured region accepts all.
+func (d *Driver) checkAccessibility(req *csi.TopologyRequirement) error {
+	region := d.cloudRegion()
+	topologies := req.GetRequisite()
+	if len(topologies) == 0 || region == "" {
+		return nil
+	}
+	for _, topology := range topologies {
+		segment, ok := topology.GetSegments()[TopologyKey]
+		if !ok || segment == region {
+			return nil
+		}
+	}
+	return status.Errorf(codes.ResourceExhausted,
+		"volumes are created in region %q, which the accessibility requirements exclude", region)
+}
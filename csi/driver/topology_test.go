@@ -0,0 +1,109 @@
+/*
+Copyright 2025 Kube-DC Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// fakeLocator finds the server in region, and fails every lookup in the regions listed in down
+func fakeLocator(region string, down ...string) serverLocator {
+	return func(_ context.Context, r, _ string) (bool, error) {
+		for _, d := range down {
+			if r == d || d == "*" {
+				return false, errors.New("dial tcp: i/o timeout")
+			}
+		}
+		return r == region, nil
+	}
+}
+
+func TestLocateNode(t *testing.T) {
+	tests := []struct {
+		name       string
+		locate     serverLocator
+		configured string
+		want       string
+	}{
+		{name: "server in the configured region", locate: fakeLocator("zrh"), configured: "zrh", want: "zrh"},
+		{name: "server in another region", locate: fakeLocator("sjc"), configured: "zrh", want: "sjc"},
+		{name: "no region configured", locate: fakeLocator("lvs"), configured: "", want: "lvs"},
+		{name: "configured region unreachable", locate: fakeLocator("tyo", "zrh"), configured: "zrh", want: "tyo"},
+		{name: "API unreachable", locate: fakeLocator("sjc", "*"), configured: "zrh", want: "zrh"},
+		{name: "server unknown everywhere", locate: fakeLocator(""), configured: "zrh", want: "zrh"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := locateNode(context.Background(), tt.locate, "node-1", tt.configured); got != tt.want {
+				t.Errorf("locateNode() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNodeGetInfo_LocatedRegion(t *testing.T) {
+	d, err := NewDriver(&Config{Name: DriverName, Mode: NodeMode, NodeID: "node-1", Region: "zrh"})
+	if err != nil {
+		t.Fatalf("NewDriver() error = %v", err)
+	}
+	d.region = locateNode(context.Background(), fakeLocator("sjc"), "node-1", "zrh")
+
+	resp, err := d.NodeGetInfo(context.Background(), &csi.NodeGetInfoRequest{})
+	if err != nil {
+		t.Fatalf("NodeGetInfo() error = %v", err)
+	}
+	if got := resp.AccessibleTopology.Segments[TopologyKey]; got != "sjc" {
+		t.Errorf("topology region = %q, want sjc", got)
+	}
+}
+
+func TestCheckAccessibility(t *testing.T) {
+	region := func(r string) *csi.Topology {
+		return &csi.Topology{Segments: map[string]string{TopologyKey: r}}
+	}
+
+	tests := []struct {
+		name     string
+		region   string
+		req      *csi.TopologyRequirement
+		wantCode codes.Code
+	}{
+		{name: "no requirements", region: "zrh", wantCode: codes.OK},
+		{name: "requisite region", region: "zrh", req: &csi.TopologyRequirement{Requisite: []*csi.Topology{region("sjc"), region("zrh")}}, wantCode: codes.OK},
+		{name: "other region only", region: "zrh", req: &csi.TopologyRequirement{Requisite: []*csi.Topology{region("sjc")}}, wantCode: codes.ResourceExhausted},
+		{name: "preferred without requisite", region: "zrh", req: &csi.TopologyRequirement{Preferred: []*csi.Topology{region("sjc")}}, wantCode: codes.OK},
+		{name: "preferred outside requisite", region: "zrh", req: &csi.TopologyRequirement{Requisite: []*csi.Topology{region("zrh")}, Preferred: []*csi.Topology{region("sjc")}}, wantCode: codes.OK},
+		{name: "topology without a region", region: "zrh", req: &csi.TopologyRequirement{Requisite: []*csi.Topology{{Segments: map[string]string{"kubernetes.io/hostname": "worker-0"}}}}, wantCode: codes.OK},
+		{name: "controller without a region", region: "", req: &csi.TopologyRequirement{Requisite: []*csi.Topology{region("sjc")}}, wantCode: codes.OK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &Driver{region: tt.region}
+			if got := status.Code(d.checkAccessibility(tt.req)); got != tt.wantCode {
+				t.Errorf("checkAccessibility() code = %v, want %v", got, tt.wantCode)
+			}
+		})
+	}
+}
@@ -0,0 +1,48 @@
+/*
+Copyright 2025 Kube-DC Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import "sync"
+
+// volumeLocks tracks the volumes a node operation is running for. A volume is only in the set
+// while its operation runs, so the set does not grow with the volumes a node has seen. The zero
+// value is ready to use.
+type volumeLocks struct {
+	mu     sync.Mutex
+	locked map[string]struct{}
+}
+
+// TryAcquire marks volumeID busy, or returns false if an operation already holds it
+func (l *volumeLocks) TryAcquire(volumeID string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, busy := l.locked[volumeID]; busy {
+		return false
+	}
+	if l.locked == nil {
+		l.locked = make(map[string]struct{})
+	}
+	l.locked[volumeID] = struct{}{}
+	return true
+}
+
+// Release marks volumeID free again
+func (l *volumeLocks) Release(volumeID string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.locked, volumeID)
+}
@@ -0,0 +1,42 @@
+/*
+Copyright 2025 Kube-DC Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import "testing"
+
+func TestVolumeLocks(t *testing.T) {
+	var locks volumeLocks
+
+	if !locks.TryAcquire("vol-a") {
+		t.Fatal("TryAcquire(vol-a) = false on a free volume")
+	}
+	if locks.TryAcquire("vol-a") {
+		t.Error("TryAcquire(vol-a) = true while it is held")
+	}
+	if !locks.TryAcquire("vol-b") {
+		t.Error("TryAcquire(vol-b) = false, other volumes must not be blocked")
+	}
+
+	locks.Release("vol-a")
+	locks.Release("vol-b")
+	if len(locks.locked) != 0 {
+		t.Errorf("locked = %v after release, want empty", locks.locked)
+	}
+	if !locks.TryAcquire("vol-a") {
+		t.Error("TryAcquire(vol-a) = false after release")
+	}
+}
@@ -18,9 +18,8 @@ import (
 	"fmt"
 	"os"
 	"strings"
-	"time"
 
-	"github.com/cloudsigma/cloudsigma-sdk-go/cloudsigma"
+	"github.com/kube-dc/cluster-api-provider-cloudsigma/pkg/cloud"
 )
 
 // Default patterns to match test resources
@@ -42,130 +41,85 @@ func main() {
 	flag.BoolVar(&listAll, "all", false, "List all servers and drives")
 	flag.Parse()
 
-	cred := cloudsigma.NewUsernamePasswordCredentialsProvider(
-		os.Getenv("CLOUDSIGMA_USERNAME"),
-		os.Getenv("CLOUDSIGMA_PASSWORD"),
-	)
 	region := os.Getenv("CLOUDSIGMA_REGION")
 	if region == "" {
 		region = "next"
 	}
-	client := cloudsigma.NewClient(cred, cloudsigma.WithLocation(region))
+	client, err := cloud.NewClient(os.Getenv("CLOUDSIGMA_USERNAME"), os.Getenv("CLOUDSIGMA_PASSWORD"), region)
+	if err != nil {
+		fmt.Printf("Error creating client: %v\n", err)
+		os.Exit(1)
+	}
 	ctx := context.Background()
 
 	fmt.Printf("CloudSigma Region: %s\n", region)
 	fmt.Println(strings.Repeat("=", 60))
 
+	if listAll {
+		servers, err := client.ListServers(ctx)
+		if err != nil {
+			fmt.Printf("Error listing servers: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("\nTotal servers: %d\n", len(servers))
+		for _, s := range servers {
+			fmt.Printf("  • %s (UUID: %s, Status: %s)\n", s.Name, s.UUID, s.Status)
+		}
+
+		drives, err := client.ListDrives(ctx, nil)
+		if err != nil {
+			fmt.Printf("Error listing drives: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("\nTotal drives: %d\n", len(drives))
+		for _, d := range drives {
+			fmt.Printf("  • %s (UUID: %s, Size: %dGB)\n", d.Name, d.UUID, d.Size/1024/1024/1024)
+		}
+		return
+	}
+
 	// Determine patterns to use
 	patterns := defaultPatterns
 	if pattern != "" {
 		patterns = []string{pattern}
 	}
 
-	// List servers
-	servers, _, err := client.Servers.List(ctx)
-	if err != nil {
-		fmt.Printf("Error listing servers: %v\n", err)
-		os.Exit(1)
-	}
-	fmt.Printf("\nTotal servers: %d\n", len(servers))
-
-	var matchedServers []cloudsigma.Server
-	for _, s := range servers {
-		if listAll {
-			fmt.Printf("  • %s (UUID: %s, Status: %s)\n", s.Name, s.UUID, s.Status)
-			continue
-		}
-		if matchesAny(s.Name, patterns) {
-			matchedServers = append(matchedServers, s)
-			fmt.Printf("  ⚠️  %s (UUID: %s, Status: %s)\n", s.Name, s.UUID, s.Status)
-		}
-	}
-
-	// List drives
-	drives, _, err := client.Drives.List(ctx, nil)
+	resources, err := client.FindManagedResources(ctx, patterns)
 	if err != nil {
-		fmt.Printf("Error listing drives: %v\n", err)
+		fmt.Printf("Error finding resources: %v\n", err)
 		os.Exit(1)
 	}
-	fmt.Printf("\nTotal drives: %d\n", len(drives))
 
-	var matchedDrives []cloudsigma.Drive
-	for _, d := range drives {
-		if listAll {
-			fmt.Printf("  • %s (UUID: %s, Size: %dGB)\n", d.Name, d.UUID, d.Size/1024/1024/1024)
-			continue
-		}
-		if matchesAny(d.Name, patterns) {
-			matchedDrives = append(matchedDrives, d)
-			fmt.Printf("  ⚠️  %s (UUID: %s, Size: %dGB)\n", d.Name, d.UUID, d.Size/1024/1024/1024)
-		}
+	fmt.Println("\nServers:")
+	for _, s := range resources.Servers {
+		fmt.Printf("  ⚠️  %s (UUID: %s, Status: %s)\n", s.Name, s.UUID, s.Status)
 	}
-
-	if listAll {
-		return
+	fmt.Println("\nDrives:")
+	for _, d := range resources.Drives {
+		fmt.Printf("  ⚠️  %s (UUID: %s, Size: %dGB)\n", d.Name, d.UUID, d.Size/1024/1024/1024)
 	}
 
 	fmt.Println(strings.Repeat("-", 60))
-	fmt.Printf("Matched servers: %d\n", len(matchedServers))
-	fmt.Printf("Matched drives:  %d\n", len(matchedDrives))
+	fmt.Printf("Matched servers: %d\n", len(resources.Servers))
+	fmt.Printf("Matched drives:  %d\n", len(resources.Drives))
 
 	if !deleteResources {
-		if len(matchedServers) > 0 || len(matchedDrives) > 0 {
+		if len(resources.Servers) > 0 || len(resources.Drives) > 0 {
 			fmt.Println("\nRun with --delete to remove these resources")
 		}
 		return
 	}
 
-	// Delete resources
 	fmt.Println("\n🗑️  Deleting resources...")
-
-	// Stop and delete servers
-	for _, s := range matchedServers {
-		fmt.Printf("  Stopping server %s...", s.Name)
-		if s.Status == "running" {
-			_, _, err := client.Servers.Stop(ctx, s.UUID)
-			if err != nil {
-				fmt.Printf(" error: %v\n", err)
-				continue
-			}
-			fmt.Printf(" stopped, waiting...")
-			time.Sleep(5 * time.Second)
-		}
-		fmt.Printf(" deleting...")
-		_, err := client.Servers.Delete(ctx, s.UUID)
-		if err != nil {
-			fmt.Printf(" error: %v\n", err)
-			continue
-		}
-		fmt.Println(" ✓ deleted")
-	}
-
-	// Wait for servers to be fully deleted
-	if len(matchedServers) > 0 {
-		fmt.Println("  Waiting for servers to be fully deleted...")
-		time.Sleep(10 * time.Second)
-	}
-
-	// Delete drives
-	for _, d := range matchedDrives {
-		fmt.Printf("  Deleting drive %s...", d.Name)
-		_, err := client.Drives.Delete(ctx, d.UUID)
-		if err != nil {
-			fmt.Printf(" error: %v\n", err)
-			continue
+	report, err := client.DeleteManagedResources(ctx, resources, false)
+	fmt.Printf("Deleted servers: %d\n", len(report.DeletedServers))
+	fmt.Printf("Deleted drives:  %d\n", len(report.DeletedDrives))
+	if err != nil {
+		for _, f := range report.Failures {
+			fmt.Printf("  ❌ %s %s (UUID: %s): %v\n", f.Kind, f.Name, f.UUID, f.Err)
 		}
-		fmt.Println(" ✓ deleted")
+		os.Exit(1)
 	}
 
 	fmt.Println("\n✅ Cleanup complete")
 }
-
-func matchesAny(name string, patterns []string) bool {
-	for _, p := range patterns {
-		if strings.Contains(name, p) {
-			return true
-		}
-	}
-	return false
-}
@@ -29,6 +29,9 @@ import (
 	"time"
 
 	"k8s.io/klog/v2"
+	"k8s.io/utils/clock"
+
+	"github.com/kube-dc/cluster-api-provider-cloudsigma/pkg/regions"
 )
 
 const (
@@ -38,11 +41,15 @@ const (
 	// Default HTTP timeout for OAuth requests
 	defaultHTTPTimeout = 30 * time.Second
 
+	// Default bounds of the impersonated token cache
+	defaultMaxCachedTokens = 1000
+	defaultTokenIdleTTL    = time.Hour
+
 	// UMA grant type for RPT token exchange
 	umaGrantType = "urn:ietf:params:oauth:grant-type:uma-ticket"
 
-	// Service provider API audience
-	serviceProviderAudience = "service_provider_api"
+	// DefaultAudience is the audience requested for the RPT token: CloudSigma's service provider API
+	DefaultAudience = "service_provider_api"
 )
 
 // ImpersonationConfig holds configuration for the impersonation client
@@ -56,11 +63,32 @@ type ImpersonationConfig struct {
 	// ClientSecret is the service account client secret
 	ClientSecret string
 
+	// Audience is the audience requested in the UMA ticket (RPT) exchange (default: DefaultAudience).
+	// Partners running their own Keycloak may register the service provider API under another name.
+	Audience string
+
+	// Scopes are requested in the UMA ticket exchange, space separated in the scope parameter (optional)
+	Scopes []string
+
 	// TokenExpiryBuffer is the time before expiry to refresh tokens
 	TokenExpiryBuffer time.Duration
 
 	// HTTPTimeout is the timeout for HTTP requests
 	HTTPTimeout time.Duration
+
+	// Clock is used for token expiry checks (default: the real clock)
+	Clock clock.PassiveClock
+
+	// HTTPClient overrides the client used for OAuth and impersonation requests, e.g. to route
+	// them to a fake API in tests. HTTPTimeout is ignored when set.
+	HTTPClient *http.Client
+
+	// MaxCachedTokens caps the impersonated tokens cached, one per user and region; the least
+	// recently used is evicted beyond it (default: 1000)
+	MaxCachedTokens int
+
+	// TokenIdleTTL evicts cached impersonated tokens not used for this long (default: 1h)
+	TokenIdleTTL time.Duration
 }
 
 // CachedToken holds an impersonated token with expiry information
@@ -69,17 +97,26 @@ type CachedToken struct {
 	ExpiresAt time.Time
 	UserEmail string
 	Region    string
+
+	// lastUsed is when the token was last cached or returned, for LRU and idle eviction
+	lastUsed time.Time
 }
 
 // IsExpired checks if the token is expired (including buffer)
 func (t *CachedToken) IsExpired(buffer time.Duration) bool {
-	return time.Now().Add(buffer).After(t.ExpiresAt)
+	return t.IsExpiredAt(time.Now(), buffer)
+}
+
+// IsExpiredAt checks if the token is expired at the given time (including buffer)
+func (t *CachedToken) IsExpiredAt(now time.Time, buffer time.Duration) bool {
+	return now.Add(buffer).After(t.ExpiresAt)
 }
 
 // ImpersonationClient handles CloudSigma OAuth impersonation flow
 type ImpersonationClient struct {
 	config     ImpersonationConfig
 	httpClient *http.Client
+	clock      clock.PassiveClock
 
 	// Service account token cache
 	saToken          string
@@ -108,18 +145,36 @@ func NewImpersonationClient(config ImpersonationConfig) (*ImpersonationClient, e
 		return nil, fmt.Errorf("ClientSecret is required")
 	}
 
+	if config.Audience == "" {
+		config.Audience = DefaultAudience
+	}
 	if config.TokenExpiryBuffer == 0 {
 		config.TokenExpiryBuffer = defaultTokenExpiryBuffer
 	}
 	if config.HTTPTimeout == 0 {
 		config.HTTPTimeout = defaultHTTPTimeout
 	}
+	if config.Clock == nil {
+		config.Clock = clock.RealClock{}
+	}
+	if config.MaxCachedTokens <= 0 {
+		config.MaxCachedTokens = defaultMaxCachedTokens
+	}
+	if config.TokenIdleTTL <= 0 {
+		config.TokenIdleTTL = defaultTokenIdleTTL
+	}
 
-	return &ImpersonationClient{
-		config: config,
-		httpClient: &http.Client{
+	httpClient := config.HTTPClient
+	if httpClient == nil {
+		httpClient = &http.Client{
 			Timeout: config.HTTPTimeout,
-		},
+		}
+	}
+
+	return &ImpersonationClient{
+		config:     config,
+		httpClient: httpClient,
+		clock:      config.Clock,
 		tokenCache: make(map[string]*CachedToken),
 	}, nil
 }
@@ -136,15 +191,18 @@ func (c *ImpersonationClient) GetImpersonatedToken(ctx context.Context, userEmai
 
 	cacheKey := fmt.Sprintf("%s:%s", userEmail, region)
 
-	// Check cache first
-	c.cacheMutex.RLock()
+	// Check cache first. The write lock is needed to record the use for LRU eviction.
+	now := c.clock.Now()
+	c.cacheMutex.Lock()
 	cached, exists := c.tokenCache[cacheKey]
-	c.cacheMutex.RUnlock()
-
-	if exists && !cached.IsExpired(c.config.TokenExpiryBuffer) {
+	if exists && !cached.IsExpiredAt(now, c.config.TokenExpiryBuffer) {
+		cached.lastUsed = now
+		token := cached.Token
+		c.cacheMutex.Unlock()
 		klog.V(4).Infof("Using cached impersonated token for user %s in region %s", userEmail, region)
-		return cached.Token, nil
+		return token, nil
 	}
+	c.cacheMutex.Unlock()
 
 	// Token not cached or expired, get a new one
 	klog.V(2).Infof("Getting new impersonated token for user %s in region %s", userEmail, region)
@@ -161,12 +219,43 @@ func (c *ImpersonationClient) GetImpersonatedToken(ctx context.Context, userEmai
 		ExpiresAt: expiresAt,
 		UserEmail: userEmail,
 		Region:    region,
+		lastUsed:  c.clock.Now(),
 	}
+	c.evictTokensLocked(cacheKey)
 	c.cacheMutex.Unlock()
 
 	return token, nil
 }
 
+// evictTokensLocked drops cached tokens idle for longer than TokenIdleTTL, then the least recently
+// used ones until at most MaxCachedTokens remain. The entry under keep, just cached for a caller,
+// is never evicted; callers hold the token string itself, so eviction cannot affect a token in
+// use. Must hold cacheMutex.
+func (c *ImpersonationClient) evictTokensLocked(keep string) {
+	now := c.clock.Now()
+	for key, cached := range c.tokenCache {
+		if key != keep && now.Sub(cached.lastUsed) > c.config.TokenIdleTTL {
+			delete(c.tokenCache, key)
+			klog.V(4).Infof("Evicted idle impersonated token for user %s in region %s", cached.UserEmail, cached.Region)
+		}
+	}
+
+	for len(c.tokenCache) > c.config.MaxCachedTokens {
+		var oldestKey string
+		var oldest *CachedToken
+		for key, cached := range c.tokenCache {
+			if key != keep && (oldest == nil || cached.lastUsed.Before(oldest.lastUsed)) {
+				oldestKey, oldest = key, cached
+			}
+		}
+		if oldest == nil {
+			return
+		}
+		delete(c.tokenCache, oldestKey)
+		klog.V(4).Infof("Evicted least recently used impersonated token for user %s in region %s", oldest.UserEmail, oldest.Region)
+	}
+}
+
 // fetchImpersonatedToken performs the full OAuth impersonation flow
 func (c *ImpersonationClient) fetchImpersonatedToken(ctx context.Context, userEmail, region string) (string, time.Time, error) {
 	// Step 1: Get service account access token
@@ -194,7 +283,7 @@ func (c *ImpersonationClient) fetchImpersonatedToken(ctx context.Context, userEm
 func (c *ImpersonationClient) getServiceAccountToken(ctx context.Context) (string, error) {
 	// Check cache
 	c.saTokenMutex.RLock()
-	if c.saToken != "" && time.Now().Add(c.config.TokenExpiryBuffer).Before(c.saTokenExpiresAt) {
+	if c.saToken != "" && c.clock.Now().Add(c.config.TokenExpiryBuffer).Before(c.saTokenExpiresAt) {
 		token := c.saToken
 		c.saTokenMutex.RUnlock()
 		klog.V(4).Info("Using cached service account token")
@@ -202,6 +291,25 @@ func (c *ImpersonationClient) getServiceAccountToken(ctx context.Context) (strin
 	}
 	c.saTokenMutex.RUnlock()
 
+	return c.requestServiceAccountToken(ctx)
+}
+
+// VerifyServiceAccount fetches a fresh service account token and exchanges it for an RPT token,
+// bypassing the caches, to confirm that the OAuth endpoint is reachable, the client credentials are
+// accepted and the service account may use the service provider API
+func (c *ImpersonationClient) VerifyServiceAccount(ctx context.Context) error {
+	saToken, err := c.requestServiceAccountToken(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get service account token: %w", err)
+	}
+	if _, err := c.requestRPTToken(ctx, saToken); err != nil {
+		return fmt.Errorf("failed to get RPT token: %w", err)
+	}
+	return nil
+}
+
+// requestServiceAccountToken requests a new service account token and stores it in the cache
+func (c *ImpersonationClient) requestServiceAccountToken(ctx context.Context) (string, error) {
 	klog.V(2).Info("Fetching new service account token")
 
 	tokenURL := fmt.Sprintf("%s/realms/cloudsigma/protocol/openid-connect/token", c.config.OAuthURL)
@@ -240,7 +348,7 @@ func (c *ImpersonationClient) getServiceAccountToken(ctx context.Context) (strin
 	// Cache the token
 	c.saTokenMutex.Lock()
 	c.saToken = tokenResp.AccessToken
-	c.saTokenExpiresAt = time.Now().Add(time.Duration(tokenResp.ExpiresIn) * time.Second)
+	c.saTokenExpiresAt = c.clock.Now().Add(time.Duration(tokenResp.ExpiresIn) * time.Second)
 	c.saTokenMutex.Unlock()
 
 	klog.V(2).Info("Successfully obtained service account token")
@@ -251,7 +359,7 @@ func (c *ImpersonationClient) getServiceAccountToken(ctx context.Context) (strin
 func (c *ImpersonationClient) getRPTToken(ctx context.Context, accessToken string) (string, error) {
 	// Check cache
 	c.rptTokenMutex.RLock()
-	if c.rptToken != "" && time.Now().Add(c.config.TokenExpiryBuffer).Before(c.rptTokenExpiresAt) {
+	if c.rptToken != "" && c.clock.Now().Add(c.config.TokenExpiryBuffer).Before(c.rptTokenExpiresAt) {
 		token := c.rptToken
 		c.rptTokenMutex.RUnlock()
 		klog.V(4).Info("Using cached RPT token")
@@ -259,13 +367,21 @@ func (c *ImpersonationClient) getRPTToken(ctx context.Context, accessToken strin
 	}
 	c.rptTokenMutex.RUnlock()
 
+	return c.requestRPTToken(ctx, accessToken)
+}
+
+// requestRPTToken exchanges accessToken for a new RPT token and stores it in the cache
+func (c *ImpersonationClient) requestRPTToken(ctx context.Context, accessToken string) (string, error) {
 	klog.V(2).Info("Fetching new RPT token")
 
 	tokenURL := fmt.Sprintf("%s/realms/cloudsigma/protocol/openid-connect/token", c.config.OAuthURL)
 
 	data := url.Values{}
 	data.Set("grant_type", umaGrantType)
-	data.Set("audience", serviceProviderAudience)
+	data.Set("audience", c.config.Audience)
+	if len(c.config.Scopes) > 0 {
+		data.Set("scope", strings.Join(c.config.Scopes, " "))
+	}
 
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, tokenURL, strings.NewReader(data.Encode()))
 	if err != nil {
@@ -297,7 +413,7 @@ func (c *ImpersonationClient) getRPTToken(ctx context.Context, accessToken strin
 	// Cache the token
 	c.rptTokenMutex.Lock()
 	c.rptToken = tokenResp.AccessToken
-	c.rptTokenExpiresAt = time.Now().Add(time.Duration(tokenResp.ExpiresIn) * time.Second)
+	c.rptTokenExpiresAt = c.clock.Now().Add(time.Duration(tokenResp.ExpiresIn) * time.Second)
 	c.rptTokenMutex.Unlock()
 
 	klog.V(2).Info("Successfully obtained RPT token")
@@ -309,7 +425,7 @@ func (c *ImpersonationClient) impersonateUser(ctx context.Context, rptToken, sub
 	klog.V(2).Infof("Impersonating user %s in region %s", userEmail, region)
 
 	// Build impersonation URL for the specific region
-	impersonateURL := fmt.Sprintf("https://direct.%s.cloudsigma.com/service_provider/api/v1/user/impersonate", region)
+	impersonateURL := fmt.Sprintf("https://%s/service_provider/api/v1/user/impersonate", regions.Lookup(region).DirectHost)
 
 	payload := impersonateRequest{
 		UserEmail:    userEmail,
@@ -354,10 +470,10 @@ func (c *ImpersonationClient) impersonateUser(ctx context.Context, rptToken, sub
 	}
 
 	// Calculate expiry time
-	expiresAt := time.Now().Add(time.Duration(impersonateResp.ExpiresIn) * time.Second)
+	expiresAt := c.clock.Now().Add(time.Duration(impersonateResp.ExpiresIn) * time.Second)
 	if impersonateResp.ExpiresIn == 0 {
 		// Default to 15 minutes if not specified
-		expiresAt = time.Now().Add(15 * time.Minute)
+		expiresAt = c.clock.Now().Add(15 * time.Minute)
 	}
 
 	klog.V(2).Infof("Successfully impersonated user %s", userEmail)
@@ -23,6 +23,8 @@ import (
 	"net/http/httptest"
 	"testing"
 	"time"
+
+	testingclock "k8s.io/utils/clock/testing"
 )
 
 func TestNewImpersonationClient(t *testing.T) {
@@ -257,6 +259,68 @@ func TestImpersonationClient_GetRPTToken(t *testing.T) {
 	}
 }
 
+func TestImpersonationClient_GetRPTToken_Audience(t *testing.T) {
+	tests := []struct {
+		name      string
+		audience  string
+		scopes    []string
+		wantAud   string
+		wantScope string
+	}{
+		{
+			name:    "default audience",
+			wantAud: DefaultAudience,
+		},
+		{
+			name:      "custom audience and scopes",
+			audience:  "partner_api",
+			scopes:    []string{"openid", "servers"},
+			wantAud:   "partner_api",
+			wantScope: "openid servers",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotAud, gotScope string
+			var hasScope bool
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if err := r.ParseForm(); err != nil {
+					w.WriteHeader(http.StatusBadRequest)
+					return
+				}
+				gotAud = r.PostForm.Get("audience")
+				gotScope = r.PostForm.Get("scope")
+				_, hasScope = r.PostForm["scope"]
+				w.Header().Set("Content-Type", "application/json")
+				json.NewEncoder(w).Encode(tokenResponse{AccessToken: "test-rpt-token", ExpiresIn: 900})
+			}))
+			defer server.Close()
+
+			client, err := NewImpersonationClient(ImpersonationConfig{
+				OAuthURL:     server.URL,
+				ClientID:     "test-client",
+				ClientSecret: "test-secret",
+				Audience:     tt.audience,
+				Scopes:       tt.scopes,
+			})
+			if err != nil {
+				t.Fatalf("failed to create client: %v", err)
+			}
+
+			if _, err := client.getRPTToken(context.Background(), "test-sa-token"); err != nil {
+				t.Fatalf("getRPTToken() error = %v", err)
+			}
+			if gotAud != tt.wantAud {
+				t.Errorf("audience = %q, want %q", gotAud, tt.wantAud)
+			}
+			if gotScope != tt.wantScope || hasScope != (tt.wantScope != "") {
+				t.Errorf("scope = %q (sent: %v), want %q", gotScope, hasScope, tt.wantScope)
+			}
+		})
+	}
+}
+
 func TestImpersonationClient_ImpersonateUser(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// Check authorization header
@@ -364,3 +428,157 @@ func TestImpersonationClient_ClearUserToken(t *testing.T) {
 		t.Error("user2 token should still exist")
 	}
 }
+
+func TestImpersonationClient_VerifyServiceAccount(t *testing.T) {
+	var calls int
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if status != http.StatusOK {
+			w.WriteHeader(status)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(tokenResponse{AccessToken: "test-sa-token", ExpiresIn: 900})
+	}))
+	defer server.Close()
+
+	client, _ := NewImpersonationClient(ImpersonationConfig{
+		OAuthURL:     server.URL,
+		ClientID:     "test-client",
+		ClientSecret: "test-secret",
+	})
+
+	ctx := context.Background()
+	if err := client.VerifyServiceAccount(ctx); err != nil {
+		t.Fatalf("VerifyServiceAccount() error = %v", err)
+	}
+
+	// A cached token must not mask an OAuth outage
+	status = http.StatusServiceUnavailable
+	if err := client.VerifyServiceAccount(ctx); err == nil {
+		t.Error("VerifyServiceAccount() expected error when OAuth is unavailable")
+	}
+	// The service account token and RPT requests, then the failed service account token request
+	if calls != 3 {
+		t.Errorf("OAuth called %d times, want 3", calls)
+	}
+}
+
+func TestImpersonationClient_TokenExpiryUsesClock(t *testing.T) {
+	var calls int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(tokenResponse{AccessToken: "test-sa-token", ExpiresIn: 900})
+	}))
+	defer server.Close()
+
+	clk := testingclock.NewFakeClock(time.Now())
+	client, _ := NewImpersonationClient(ImpersonationConfig{
+		OAuthURL:          server.URL,
+		ClientID:          "test-client",
+		ClientSecret:      "test-secret",
+		TokenExpiryBuffer: time.Minute,
+		Clock:             clk,
+	})
+
+	ctx := context.Background()
+	if _, err := client.getServiceAccountToken(ctx); err != nil {
+		t.Fatalf("getServiceAccountToken() error = %v", err)
+	}
+
+	// Still outside the expiry buffer: served from cache
+	clk.Step(13 * time.Minute)
+	if _, err := client.getServiceAccountToken(ctx); err != nil {
+		t.Fatalf("getServiceAccountToken() error = %v", err)
+	}
+	if calls != 1 {
+		t.Fatalf("OAuth called %d times, want 1", calls)
+	}
+
+	// Within the buffer of the 15m expiry: refreshed
+	clk.Step(time.Minute + time.Second)
+	if _, err := client.getServiceAccountToken(ctx); err != nil {
+		t.Fatalf("getServiceAccountToken() error = %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("OAuth called %d times, want 2 after expiry", calls)
+	}
+}
+
+// handlerTransport serves every request, whatever its host, from an in-process handler
+type handlerTransport struct{ http.Handler }
+
+func (t handlerTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	rec := httptest.NewRecorder()
+	t.ServeHTTP(rec, r)
+	return rec.Result(), nil
+}
+
+func TestImpersonationClient_TokenCacheEviction(t *testing.T) {
+	impersonated := map[string]int{}
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/service_provider/api/v1/user/impersonate" {
+			var req impersonateRequest
+			json.NewDecoder(r.Body).Decode(&req)
+			impersonated[req.UserEmail]++
+			json.NewEncoder(w).Encode(impersonateResponse{AccessToken: "token-" + req.UserEmail, ExpiresIn: 3600})
+			return
+		}
+		json.NewEncoder(w).Encode(tokenResponse{AccessToken: "test-token", ExpiresIn: 3600})
+	})
+
+	clk := testingclock.NewFakeClock(time.Now())
+	client, _ := NewImpersonationClient(ImpersonationConfig{
+		OAuthURL:        "https://oauth.example.com",
+		ClientID:        "test-client",
+		ClientSecret:    "test-secret",
+		Clock:           clk,
+		HTTPClient:      &http.Client{Transport: handlerTransport{handler}},
+		MaxCachedTokens: 2,
+		TokenIdleTTL:    10 * time.Minute,
+	})
+	ctx := context.Background()
+	get := func(user string) {
+		t.Helper()
+		token, err := client.GetImpersonatedToken(ctx, user, "next")
+		if err != nil {
+			t.Fatalf("GetImpersonatedToken(%s) error = %v", user, err)
+		}
+		if token != "token-"+user {
+			t.Errorf("GetImpersonatedToken(%s) = %q, want token-%s", user, token, user)
+		}
+		clk.Step(time.Second)
+	}
+	cached := func(user string) bool {
+		_, ok := client.tokenCache[user+":next"]
+		return ok
+	}
+
+	// user1 is used again after user2, so user2 is the least recently used when user3 arrives
+	get("user1")
+	get("user2")
+	get("user1")
+	get("user3")
+	if !cached("user1") || cached("user2") || !cached("user3") {
+		t.Errorf("cached users = %v, want user1 and user3", client.tokenCache)
+	}
+	if impersonated["user1"] != 1 {
+		t.Errorf("user1 impersonated %d times, want 1 (second call served from cache)", impersonated["user1"])
+	}
+
+	// An evicted user is impersonated again on the next request
+	get("user2")
+	if impersonated["user2"] != 2 {
+		t.Errorf("user2 impersonated %d times after eviction, want 2", impersonated["user2"])
+	}
+
+	// Entries unused for longer than the idle TTL are dropped when the next token is cached
+	clk.Step(11 * time.Minute)
+	get("user4")
+	if len(client.tokenCache) != 1 || !cached("user4") {
+		t.Errorf("cached users = %v, want only user4 after idle eviction", client.tokenCache)
+	}
+}
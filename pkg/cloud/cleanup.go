@@ -0,0 +1,251 @@
+/*
+Copyright 2025 Kube-DC Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloud
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cloudsigma/cloudsigma-sdk-go/cloudsigma"
+	"k8s.io/klog/v2"
+)
+
+// Stop polling used by DeleteManagedResources (variables so tests can shorten them)
+var (
+	cleanupPollInterval = 5 * time.Second
+	cleanupStopTimeout  = 2 * time.Minute
+)
+
+// ManagedResources is a set of servers and drives selected for cleanup
+type ManagedResources struct {
+	Servers []cloudsigma.Server
+	Drives  []cloudsigma.Drive
+}
+
+// MatchesAnyPattern reports whether name contains any of the given substrings
+func MatchesAnyPattern(name string, patterns []string) bool {
+	for _, p := range patterns {
+		if p != "" && strings.Contains(name, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// FindManagedResources returns the servers and drives whose names contain any of the patterns.
+// Drives attached to a matched server are included even if their own name does not match,
+// since they would otherwise be left behind once the server is gone.
+func (c *Client) FindManagedResources(ctx context.Context, patterns []string) (*ManagedResources, error) {
+	if len(patterns) == 0 {
+		return nil, fmt.Errorf("at least one pattern is required")
+	}
+
+	servers, err := c.ListServers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	drives, err := c.ListDrives(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resources := &ManagedResources{}
+	attached := make(map[string]bool)
+	for _, server := range servers {
+		if !MatchesAnyPattern(server.Name, patterns) {
+			continue
+		}
+		resources.Servers = append(resources.Servers, server)
+		for _, drive := range server.Drives {
+			if drive.Drive != nil {
+				attached[drive.Drive.UUID] = true
+			}
+		}
+	}
+	for _, drive := range drives {
+		if attached[drive.UUID] || MatchesAnyPattern(drive.Name, patterns) {
+			resources.Drives = append(resources.Drives, drive)
+		}
+	}
+
+	klog.V(2).Infof("Found %d servers and %d drives matching %v", len(resources.Servers), len(resources.Drives), patterns)
+	return resources, nil
+}
+
+// Drive meta CreateServer marks the drives it clones with, so cleanup never mistakes a user drive
+// that happens to be named like a clone for one
+const (
+	// ClonedForMetaKey names the server a drive was cloned for
+	ClonedForMetaKey = "capcs-cloned-for"
+	// ClonedAtMetaKey records when the clone was requested, in RFC 3339
+	ClonedAtMetaKey = "capcs-cloned-at"
+)
+
+// cloneMeta returns the meta marking a drive cloned for serverName at now
+func cloneMeta(serverName string, now time.Time) map[string]interface{} {
+	return map[string]interface{}{
+		ClonedForMetaKey: serverName,
+		ClonedAtMetaKey:  now.UTC().Format(time.RFC3339),
+	}
+}
+
+// ClonedFor returns the server drive was cloned for by CreateServer, or false if it carries no
+// clone mark
+func ClonedFor(drive cloudsigma.Drive) (string, bool) {
+	owner, _ := drive.Meta[ClonedForMetaKey].(string)
+	return owner, owner != ""
+}
+
+// ClonedAt returns when drive was cloned by CreateServer, or false if it carries no valid mark
+func ClonedAt(drive cloudsigma.Drive) (time.Time, bool) {
+	value, _ := drive.Meta[ClonedAtMetaKey].(string)
+	at, err := time.Parse(time.RFC3339, value)
+	return at, err == nil
+}
+
+// OrphanedClonedDrives returns the drives CreateServer marked as cloned for serverName that no
+// server uses: they are neither mounted nor in the drive list of any of servers. Drives are
+// recognized by their clone mark, not their name, so a user drive named like a clone is kept.
+func OrphanedClonedDrives(serverName string, drives []cloudsigma.Drive, servers []cloudsigma.Server) []cloudsigma.Drive {
+	used := make(map[string]bool)
+	for _, server := range servers {
+		for _, drive := range server.Drives {
+			if drive.Drive != nil {
+				used[drive.Drive.UUID] = true
+			}
+		}
+	}
+
+	var orphans []cloudsigma.Drive
+	for _, drive := range drives {
+		if owner, ok := ClonedFor(drive); !ok || owner != serverName || len(drive.MountedOn) > 0 || used[drive.UUID] {
+			continue
+		}
+		orphans = append(orphans, drive)
+	}
+	return orphans
+}
+
+// FindOrphanedClonedDrives returns the drives cloned for serverName by a create attempt that never
+// produced a server, e.g. because the controller crashed between cloning and creating it
+func (c *Client) FindOrphanedClonedDrives(ctx context.Context, serverName string) ([]cloudsigma.Drive, error) {
+	servers, err := c.ListServers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	drives, err := c.ListDrives(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	return OrphanedClonedDrives(serverName, drives, servers), nil
+}
+
+// CleanupFailure records a resource DeleteManagedResources could not remove
+type CleanupFailure struct {
+	Kind string // "server" or "drive"
+	UUID string
+	Name string
+	Err  error
+}
+
+// CleanupReport lists what DeleteManagedResources removed and what it could not
+type CleanupReport struct {
+	DeletedServers []string
+	DeletedDrives  []string
+	Failures       []CleanupFailure
+}
+
+// Err returns an error summarizing the failures, or nil if everything was deleted
+func (r *CleanupReport) Err() error {
+	if len(r.Failures) == 0 {
+		return nil
+	}
+	msgs := make([]string, 0, len(r.Failures))
+	for _, f := range r.Failures {
+		msgs = append(msgs, fmt.Sprintf("%s %s (%s): %v", f.Kind, f.Name, f.UUID, f.Err))
+	}
+	return fmt.Errorf("failed to delete %d resource(s): %s", len(r.Failures), strings.Join(msgs, "; "))
+}
+
+// DeleteManagedResources deletes the given servers, then the given drives. Servers that are not
+// stopped are stopped and polled until stopped before deletion, since CloudSigma rejects deleting
+// a running server. A failure on one resource does not abort the others; the returned report
+// lists every failure and the error is non-nil if there was any. With dryRun set nothing is
+// changed; the resources are only logged.
+func (c *Client) DeleteManagedResources(ctx context.Context, resources *ManagedResources, dryRun bool) (*CleanupReport, error) {
+	report := &CleanupReport{}
+	if resources == nil {
+		return report, nil
+	}
+
+	if dryRun {
+		for _, server := range resources.Servers {
+			klog.Infof("[dry-run] Would delete server %s (%s, status: %s)", server.Name, server.UUID, server.Status)
+		}
+		for _, drive := range resources.Drives {
+			klog.Infof("[dry-run] Would delete drive %s (%s)", drive.Name, drive.UUID)
+		}
+		return report, nil
+	}
+
+	for _, server := range resources.Servers {
+		if err := c.stopAndDeleteServer(ctx, server); err != nil {
+			klog.Warningf("Failed to delete server %s (%s): %v", server.Name, server.UUID, err)
+			report.Failures = append(report.Failures, CleanupFailure{Kind: "server", UUID: server.UUID, Name: server.Name, Err: err})
+			continue
+		}
+		klog.Infof("Deleted server %s (%s)", server.Name, server.UUID)
+		report.DeletedServers = append(report.DeletedServers, server.UUID)
+	}
+
+	for _, drive := range resources.Drives {
+		if err := c.DeleteDrive(ctx, drive.UUID); err != nil {
+			klog.Warningf("Failed to delete drive %s (%s): %v", drive.Name, drive.UUID, err)
+			report.Failures = append(report.Failures, CleanupFailure{Kind: "drive", UUID: drive.UUID, Name: drive.Name, Err: err})
+			continue
+		}
+		klog.Infof("Deleted drive %s (%s)", drive.Name, drive.UUID)
+		report.DeletedDrives = append(report.DeletedDrives, drive.UUID)
+	}
+
+	return report, report.Err()
+}
+
+// stopAndDeleteServer stops the server if needed, waits until it is stopped and deletes it
+func (c *Client) stopAndDeleteServer(ctx context.Context, server cloudsigma.Server) error {
+	if server.Status != "stopped" {
+		if server.Status == "running" || server.Status == "starting" || server.Status == "paused" {
+			if err := c.StopServer(ctx, server.UUID); err != nil {
+				return err
+			}
+		}
+		current, err := c.WaitForServerStatus(ctx, server.UUID, "stopped", cleanupStopTimeout, cleanupPollInterval)
+		if err != nil {
+			return err
+		}
+		if current == nil {
+			return nil // already gone
+		}
+	}
+
+	if _, err := c.sdk.Servers.Delete(ctx, server.UUID); err != nil {
+		return fmt.Errorf("failed to delete server: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,295 @@
+/*
+Copyright 2025 Kube-DC Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloud
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cloudsigma/cloudsigma-sdk-go/cloudsigma"
+)
+
+// cleanupAPI is a minimal CloudSigma mock tracking server status and mutating calls
+type cleanupAPI struct {
+	mu       sync.Mutex
+	servers  map[string]*cloudsigma.Server
+	drives   []cloudsigma.Drive
+	stopping map[string]int // remaining GETs before a stopping server reports stopped
+	calls    []string
+}
+
+func (a *cleanupAPI) record(call string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.calls = append(a.calls, call)
+}
+
+func (a *cleanupAPI) handler(t *testing.T) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/2.0/servers/detail/", func(w http.ResponseWriter, r *http.Request) {
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		objects := []cloudsigma.Server{}
+		for _, s := range a.servers {
+			objects = append(objects, *s)
+		}
+		writeJSON(w, map[string]interface{}{"meta": cloudsigma.Meta{TotalCount: len(objects)}, "objects": objects})
+	})
+	mux.HandleFunc("/api/2.0/drives/detail/", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]interface{}{"meta": cloudsigma.Meta{TotalCount: len(a.drives)}, "objects": a.drives})
+	})
+	mux.HandleFunc("/api/2.0/drives/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete {
+			t.Errorf("unexpected %s %s", r.Method, r.URL.Path)
+		}
+		a.record("delete-drive:" + strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/2.0/drives/"), "/"))
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("/api/2.0/servers/", func(w http.ResponseWriter, r *http.Request) {
+		rest := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/2.0/servers/"), "/")
+		uuid := strings.Split(rest, "/")[0]
+
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		server, ok := a.servers[uuid]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		switch {
+		case strings.HasSuffix(rest, "/action") && r.URL.Query().Get("do") == "stop":
+			a.calls = append(a.calls, "stop:"+uuid)
+			server.Status = "stopping"
+			writeJSON(w, cloudsigma.ServerAction{Action: "stop", Result: "success", UUID: uuid})
+		case r.Method == http.MethodGet:
+			if server.Status == "stopping" {
+				if a.stopping[uuid] <= 0 {
+					server.Status = "stopped"
+				} else {
+					a.stopping[uuid]--
+				}
+			}
+			writeJSON(w, server)
+		case r.Method == http.MethodDelete:
+			if server.Status != "stopped" {
+				w.WriteHeader(http.StatusConflict)
+				return
+			}
+			a.calls = append(a.calls, "delete-server:"+uuid)
+			delete(a.servers, uuid)
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			t.Errorf("unexpected %s %s", r.Method, r.URL.Path)
+		}
+	})
+	return mux
+}
+
+func newCleanupAPI() *cleanupAPI {
+	return &cleanupAPI{
+		servers: map[string]*cloudsigma.Server{
+			"srv-1": {UUID: "srv-1", Name: "test-cluster-md-0", Status: "running", Drives: []cloudsigma.ServerDrive{
+				{Drive: &cloudsigma.Drive{UUID: "drv-boot"}},
+			}},
+			"srv-2": {UUID: "srv-2", Name: "production-worker", Status: "running"},
+		},
+		drives: []cloudsigma.Drive{
+			{UUID: "drv-boot", Name: "ubuntu-clone"},
+			{UUID: "drv-orphan", Name: "test-cluster-md-0-drive-1"},
+			{UUID: "drv-prod", Name: "production-data"},
+		},
+		stopping: map[string]int{"srv-1": 2},
+	}
+}
+
+func TestFindManagedResources(t *testing.T) {
+	api := newCleanupAPI()
+	c := newTestClient(t, api.handler(t))
+
+	resources, err := c.FindManagedResources(context.Background(), []string{"test-cluster"})
+	if err != nil {
+		t.Fatalf("FindManagedResources() error = %v", err)
+	}
+
+	if len(resources.Servers) != 1 || resources.Servers[0].UUID != "srv-1" {
+		t.Errorf("servers = %+v, want only srv-1", resources.Servers)
+	}
+	var drives []string
+	for _, d := range resources.Drives {
+		drives = append(drives, d.UUID)
+	}
+	if strings.Join(drives, ",") != "drv-boot,drv-orphan" {
+		t.Errorf("drives = %v, want [drv-boot drv-orphan]", drives)
+	}
+
+	if _, err := c.FindManagedResources(context.Background(), nil); err == nil {
+		t.Error("expected error without patterns")
+	}
+}
+
+func TestDeleteManagedResources(t *testing.T) {
+	oldInterval := cleanupPollInterval
+	cleanupPollInterval = time.Millisecond
+	defer func() { cleanupPollInterval = oldInterval }()
+
+	t.Run("dry run", func(t *testing.T) {
+		api := newCleanupAPI()
+		c := newTestClient(t, api.handler(t))
+
+		resources, err := c.FindManagedResources(context.Background(), []string{"test-cluster"})
+		if err != nil {
+			t.Fatalf("FindManagedResources() error = %v", err)
+		}
+		if _, err := c.DeleteManagedResources(context.Background(), resources, true); err != nil {
+			t.Fatalf("DeleteManagedResources() error = %v", err)
+		}
+		if len(api.calls) != 0 {
+			t.Errorf("dry run made mutating calls: %v", api.calls)
+		}
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		api := newCleanupAPI()
+		c := newTestClient(t, api.handler(t))
+
+		resources, err := c.FindManagedResources(context.Background(), []string{"test-cluster"})
+		if err != nil {
+			t.Fatalf("FindManagedResources() error = %v", err)
+		}
+		report, err := c.DeleteManagedResources(context.Background(), resources, false)
+		if err != nil {
+			t.Fatalf("DeleteManagedResources() error = %v", err)
+		}
+		if len(report.DeletedServers) != 1 || len(report.DeletedDrives) != 2 {
+			t.Errorf("report = %+v, want 1 server and 2 drives deleted", report)
+		}
+
+		want := []string{"stop:srv-1", "delete-server:srv-1", "delete-drive:drv-boot", "delete-drive:drv-orphan"}
+		if strings.Join(api.calls, ",") != strings.Join(want, ",") {
+			t.Errorf("calls = %v, want %v", api.calls, want)
+		}
+		if _, ok := api.servers["srv-2"]; !ok {
+			t.Error("unmatched server was deleted")
+		}
+	})
+
+	t.Run("server never stops", func(t *testing.T) {
+		oldTimeout := cleanupStopTimeout
+		cleanupStopTimeout = 20 * time.Millisecond
+		defer func() { cleanupStopTimeout = oldTimeout }()
+
+		api := newCleanupAPI()
+		api.servers["srv-3"] = &cloudsigma.Server{UUID: "srv-3", Name: "test-cluster-stuck", Status: "running"}
+		api.stopping["srv-3"] = 1 << 30
+		c := newTestClient(t, api.handler(t))
+
+		resources, err := c.FindManagedResources(context.Background(), []string{"test-cluster"})
+		if err != nil {
+			t.Fatalf("FindManagedResources() error = %v", err)
+		}
+		report, err := c.DeleteManagedResources(context.Background(), resources, false)
+		if err == nil {
+			t.Fatal("expected an error for the stuck server")
+		}
+
+		if len(report.Failures) != 1 || report.Failures[0].UUID != "srv-3" {
+			t.Fatalf("failures = %+v, want only srv-3", report.Failures)
+		}
+		if !IsServerStatusTimeoutError(report.Failures[0].Err) {
+			t.Errorf("failure error = %v, want ServerStatusTimeoutError", report.Failures[0].Err)
+		}
+		if _, ok := api.servers["srv-3"]; !ok {
+			t.Error("stuck server should not have been deleted")
+		}
+		if _, ok := api.servers["srv-1"]; ok {
+			t.Error("srv-1 should still be deleted after another server failed")
+		}
+		if len(report.DeletedDrives) != 2 {
+			t.Errorf("deleted drives = %v, want 2", report.DeletedDrives)
+		}
+	})
+}
+
+func TestWaitForServerStatus(t *testing.T) {
+	api := newCleanupAPI()
+	api.servers["srv-1"].Status = "stopping"
+	c := newTestClient(t, api.handler(t))
+
+	server, err := c.WaitForServerStatus(context.Background(), "srv-1", "stopped", time.Second, time.Millisecond)
+	if err != nil {
+		t.Fatalf("WaitForServerStatus() error = %v", err)
+	}
+	if server == nil || server.Status != "stopped" {
+		t.Errorf("server = %+v, want stopped", server)
+	}
+
+	server, err = c.WaitForServerStatus(context.Background(), "missing", "stopped", time.Second, time.Millisecond)
+	if err != nil || server != nil {
+		t.Errorf("WaitForServerStatus(missing) = %v, %v, want nil, nil", server, err)
+	}
+}
+
+func TestOrphanedClonedDrives(t *testing.T) {
+	servers := []cloudsigma.Server{
+		{UUID: "srv-1", Name: "worker-0", Drives: []cloudsigma.ServerDrive{{Drive: &cloudsigma.Drive{UUID: "drv-attached"}}}},
+		{UUID: "srv-2", Name: "worker-1", Drives: []cloudsigma.ServerDrive{{}}},
+	}
+	now := time.Now()
+	drives := []cloudsigma.Drive{
+		{UUID: "drv-attached", Name: "worker-0-drive-0", Meta: cloneMeta("worker-0", now)},
+		{UUID: "drv-mounted", Name: "worker-0-drive-1", Meta: cloneMeta("worker-0", now), MountedOn: []cloudsigma.ResourceLink{{UUID: "srv-3"}}},
+		{UUID: "drv-orphan-0", Name: "worker-0-drive-0", Meta: cloneMeta("worker-0", now), Status: "unmounted"},
+		{UUID: "drv-orphan-1", Name: "worker-0-drive-1", Meta: cloneMeta("worker-0", now), Status: "cloning_dst"},
+		{UUID: "drv-other-machine", Name: "worker-1-drive-0", Meta: cloneMeta("worker-1", now)},
+		{UUID: "drv-unmarked", Name: "worker-0-drive-2", Status: "unmounted"},
+		{UUID: "drv-user", Name: "worker-0-data"},
+	}
+
+	var got []string
+	for _, d := range OrphanedClonedDrives("worker-0", drives, servers) {
+		got = append(got, d.UUID)
+	}
+	if strings.Join(got, ",") != "drv-orphan-0,drv-orphan-1" {
+		t.Errorf("OrphanedClonedDrives() = %v, want [drv-orphan-0 drv-orphan-1]", got)
+	}
+	if orphans := OrphanedClonedDrives("worker-2", drives, servers); len(orphans) != 0 {
+		t.Errorf("OrphanedClonedDrives(worker-2) = %+v, want none", orphans)
+	}
+}
+
+func TestCloneMeta(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	drive := cloudsigma.Drive{Meta: cloneMeta("worker-0", now)}
+
+	if owner, ok := ClonedFor(drive); !ok || owner != "worker-0" {
+		t.Errorf("ClonedFor() = %q, %v, want worker-0, true", owner, ok)
+	}
+	if at, ok := ClonedAt(drive); !ok || !at.Equal(now) {
+		t.Errorf("ClonedAt() = %v, %v, want %v, true", at, ok, now)
+	}
+	if _, ok := ClonedFor(cloudsigma.Drive{}); ok {
+		t.Error("ClonedFor() on an unmarked drive = true, want false")
+	}
+	if _, ok := ClonedAt(cloudsigma.Drive{Meta: map[string]interface{}{ClonedAtMetaKey: "yesterday"}}); ok {
+		t.Error("ClonedAt() with a malformed timestamp = true, want false")
+	}
+}
@@ -19,12 +19,19 @@ package cloud
 import (
 	"context"
 	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
 
 	"github.com/cloudsigma/cloudsigma-sdk-go/cloudsigma"
 	"github.com/kube-dc/cluster-api-provider-cloudsigma/pkg/auth"
+	"github.com/kube-dc/cluster-api-provider-cloudsigma/pkg/regions"
 	"k8s.io/klog/v2"
 )
 
+// listPageSize is the number of objects requested per page when enumerating resources
+const listPageSize = 100
+
 // Client wraps the CloudSigma SDK client with CAPI-specific functionality
 type Client struct {
 	sdk         *cloudsigma.Client
@@ -38,6 +45,17 @@ type Client struct {
 	impersonatedUser    string
 	useImpersonation    bool
 	accessToken         string // Current access token for impersonation
+
+	// tokenSource authenticates direct API calls for clients from NewClientWithTokenSource
+	tokenSource TokenSource
+}
+
+// TokenSource supplies bearer tokens for direct API calls, see NewClientWithTokenSource
+type TokenSource interface {
+	// Token returns a token, reusing a cached one while it is valid
+	Token(ctx context.Context) (string, error)
+	// Invalidate drops the cached token after the API rejected it
+	Invalidate()
 }
 
 // NewClient creates a new CloudSigma client wrapper using username/password credentials.
@@ -48,16 +66,18 @@ func NewClient(username, password, region string) (*Client, error) {
 	}
 
 	if region == "" {
-		region = "zrh" // Default to Zurich
+		region = regions.Default
 	}
+	regions.WarnIfUnknown(region)
+	endpoints := regions.Lookup(region)
 
 	klog.V(4).Infof("Creating CloudSigma client for region: %s (credential mode)", region)
 
 	cred := cloudsigma.NewUsernamePasswordCredentialsProvider(username, password)
-	sdk := cloudsigma.NewClient(cred, cloudsigma.WithLocation(region))
+	sdk := regions.NewSDKClient(cred, endpoints.APIHost)
 
 	// Determine API endpoint based on region
-	apiEndpoint := fmt.Sprintf("https://%s.cloudsigma.com/api/2.0", region)
+	apiEndpoint := endpoints.APIEndpoint()
 
 	return &Client{
 		sdk:              sdk,
@@ -69,6 +89,68 @@ func NewClient(username, password, region string) (*Client, error) {
 	}, nil
 }
 
+// NewClientWithEndpoint creates a username/password client that talks to a custom API endpoint
+// (e.g. "https://cloud.example.com/api/2.0") instead of the public <region>.cloudsigma.com one.
+// The endpoint path must be the API root ("/api/2.0").
+func NewClientWithEndpoint(username, password, endpoint string) (*Client, error) {
+	if username == "" || password == "" {
+		return nil, fmt.Errorf("username and password are required")
+	}
+
+	target, err := url.Parse(endpoint)
+	if err != nil || target.Host == "" {
+		return nil, fmt.Errorf("invalid API endpoint %q", endpoint)
+	}
+
+	klog.V(4).Infof("Creating CloudSigma client for endpoint: %s (credential mode)", endpoint)
+
+	cred := cloudsigma.NewUsernamePasswordCredentialsProvider(username, password)
+	sdk := cloudsigma.NewClient(cred, cloudsigma.WithHTTPClient(&http.Client{
+		Transport: &endpointTransport{target: target},
+	}))
+
+	return &Client{
+		sdk:              sdk,
+		username:         username,
+		password:         password,
+		apiEndpoint:      strings.TrimSuffix(endpoint, "/"),
+		useImpersonation: false,
+	}, nil
+}
+
+// endpointTransport redirects SDK requests, which are always built for <location>.cloudsigma.com,
+// to the scheme and host of a custom endpoint
+type endpointTransport struct {
+	target *url.URL
+}
+
+func (t *endpointTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.URL.Scheme = t.target.Scheme
+	req.URL.Host = t.target.Host
+	req.Host = t.target.Host
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+// NewClientWithTokenSource creates a client for the direct (non-SDK) API calls - IPs, NICs and
+// tags - that authenticates with tokens from source. endpoint is the API root (e.g.
+// "https://zrh.cloudsigma.com/api/2.0"). A request rejected with 401 is retried once with a fresh
+// token. Methods backed by the SDK are not available on this client.
+func NewClientWithTokenSource(endpoint string, source TokenSource) (*Client, error) {
+	if source == nil {
+		return nil, fmt.Errorf("token source is required")
+	}
+	target, err := url.Parse(endpoint)
+	if err != nil || target.Host == "" {
+		return nil, fmt.Errorf("invalid API endpoint %q", endpoint)
+	}
+
+	return &Client{
+		apiEndpoint: strings.TrimSuffix(endpoint, "/"),
+		tokenSource: source,
+	}, nil
+}
+
 // NewClientWithImpersonation creates a new CloudSigma client that uses OAuth impersonation.
 // This allows the controller to create resources in the specified user's CloudSigma account.
 func NewClientWithImpersonation(ctx context.Context, impersonationClient *auth.ImpersonationClient, userEmail, region string) (*Client, error) {
@@ -79,8 +161,9 @@ func NewClientWithImpersonation(ctx context.Context, impersonationClient *auth.I
 		return nil, fmt.Errorf("userEmail is required for impersonation")
 	}
 	if region == "" {
-		region = "zrh" // Default to Zurich
+		region = regions.Default
 	}
+	regions.WarnIfUnknown(region)
 
 	klog.V(4).Infof("Creating CloudSigma client for region: %s (impersonation mode, user: %s)", region, userEmail)
 
@@ -91,16 +174,16 @@ func NewClientWithImpersonation(ctx context.Context, impersonationClient *auth.I
 	}
 
 	// Create SDK client with token-based authentication
-	// IMPORTANT: Use "direct.<region>" for the SDK location when impersonating.
-	// The impersonation token is issued by the service provider API at direct.<region>.cloudsigma.com
-	// and must be used on that same endpoint. Using it on <region>.cloudsigma.com creates resources
-	// in the service account's default user instead of the impersonated user.
+	// IMPORTANT: Use the region's direct host (direct.<region>.cloudsigma.com) when impersonating.
+	// The impersonation token is issued by the service provider API on that host and must be used
+	// on that same endpoint. Using it on <region>.cloudsigma.com creates resources in the service
+	// account's default user instead of the impersonated user.
+	endpoints := regions.Lookup(region)
 	cred := cloudsigma.NewTokenCredentialsProvider(token)
-	directLocation := "direct." + region
-	sdk := cloudsigma.NewClient(cred, cloudsigma.WithLocation(directLocation))
+	sdk := regions.NewSDKClient(cred, endpoints.DirectHost)
 
 	// API endpoint for direct HTTP calls (must match SDK endpoint)
-	apiEndpoint := fmt.Sprintf("https://direct.%s.cloudsigma.com/api/2.0", region)
+	apiEndpoint := endpoints.DirectAPIEndpoint()
 
 	return &Client{
 		sdk:                 sdk,
@@ -129,8 +212,7 @@ func (c *Client) RefreshImpersonatedToken(ctx context.Context) error {
 
 	// Recreate SDK client with new token (use direct endpoint for impersonation)
 	cred := cloudsigma.NewTokenCredentialsProvider(token)
-	directLocation := "direct." + c.region
-	c.sdk = cloudsigma.NewClient(cred, cloudsigma.WithLocation(directLocation))
+	c.sdk = regions.NewSDKClient(cred, regions.Lookup(c.region).DirectHost)
 	c.accessToken = token
 
 	return nil
@@ -156,11 +238,17 @@ func (c *Client) Username() string {
 	return c.username
 }
 
-// VerifyConnection tests the connection to CloudSigma API
+// VerifyConnection tests the connection to CloudSigma API. Impersonated clients list one IP, which
+// needs no static credentials; clients with legacy credentials read the account profile.
 func (c *Client) VerifyConnection(ctx context.Context) error {
 	klog.V(4).Info("Verifying CloudSigma API connection")
 
-	_, _, err := c.sdk.Profile.Get(ctx)
+	var err error
+	if c.useImpersonation {
+		err = c.doDirectRequest(ctx, http.MethodGet, "ips/?limit=1", nil, nil)
+	} else {
+		_, _, err = c.sdk.Profile.Get(ctx)
+	}
 	if err != nil {
 		return fmt.Errorf("failed to verify CloudSigma connection: %w", err)
 	}
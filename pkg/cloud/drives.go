@@ -18,6 +18,7 @@ package cloud
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
@@ -25,14 +26,18 @@ import (
 	"k8s.io/klog/v2"
 )
 
-// CloneDrive clones a drive (typically a library image) to create a new drive
-func (c *Client) CloneDrive(ctx context.Context, sourceUUID, name string, size int64) (*cloudsigma.Drive, error) {
-	klog.V(2).Infof("Cloning drive %s to %s (size: %d bytes)", sourceUUID, name, size)
+// CloneDrive clones a drive (typically a library image) to create a new drive. The clone gets
+// media ("disk" or "cdrom"), or the media of the source when media is empty, and is marked as
+// cloned for the server named owner (see ClonedFor).
+func (c *Client) CloneDrive(ctx context.Context, sourceUUID, name string, size int64, media, owner string) (*cloudsigma.Drive, error) {
+	klog.V(2).Infof("Cloning drive %s to %s (size: %d bytes, media: %s)", sourceUUID, name, size, media)
 
 	req := &cloudsigma.DriveCloneRequest{
 		Drive: &cloudsigma.Drive{
-			Name: name,
-			Size: int(size),
+			Name:  name,
+			Size:  int(size),
+			Media: media,
+			Meta:  cloneMeta(owner, time.Now()),
 		},
 	}
 
@@ -55,6 +60,82 @@ func (c *Client) CloneDrive(ctx context.Context, sourceUUID, name string, size i
 	return drive, nil
 }
 
+// Server stop polling used by ResizeServerDrive (variables so tests can shorten them)
+var (
+	resizePollInterval = 5 * time.Second
+	resizeStopTimeout  = 3 * time.Minute
+)
+
+// ResizeServerDrive grows a drive to newSizeBytes. CloudSigma only resizes drives that are not in
+// use by a running server, so every running server the drive is mounted on is stopped first and
+// started again once the resize is done or has failed. Shrinking is rejected; an unchanged size is a no-op.
+func (c *Client) ResizeServerDrive(ctx context.Context, driveUUID string, newSizeBytes int64) (err error) {
+	drive, err := c.GetDrive(ctx, driveUUID)
+	if err != nil {
+		return err
+	}
+	if drive == nil {
+		return fmt.Errorf("drive %s not found", driveUUID)
+	}
+	if newSizeBytes == int64(drive.Size) {
+		return nil
+	}
+	if newSizeBytes < int64(drive.Size) {
+		return fmt.Errorf("cannot shrink drive %s from %d to %d bytes", driveUUID, drive.Size, newSizeBytes)
+	}
+
+	// Stop running servers the drive is attached to. Whatever happens after a server was stopped,
+	// it is started again, so a failed resize does not leave the machine down.
+	var stopped []string
+	defer func() {
+		// Start even if the caller gave up, e.g. the reconcile timed out during the resize
+		startCtx := context.WithoutCancel(ctx)
+		for _, uuid := range stopped {
+			if startErr := c.StartServer(startCtx, uuid); startErr != nil {
+				err = errors.Join(err, fmt.Errorf("failed to start server %s after resizing drive %s: %w", uuid, driveUUID, startErr))
+			}
+		}
+	}()
+	for _, mount := range drive.MountedOn {
+		server, err := c.GetServer(ctx, mount.UUID)
+		if err != nil {
+			return err
+		}
+		if server == nil || server.Status == "stopped" {
+			continue
+		}
+
+		klog.Infof("Stopping server %s to resize drive %s", server.UUID, driveUUID)
+		if err := c.StopServer(ctx, server.UUID); err != nil {
+			return err
+		}
+		stopped = append(stopped, server.UUID)
+		if _, err := c.WaitForServerStatus(ctx, server.UUID, "stopped", resizeStopTimeout, resizePollInterval); err != nil {
+			return err
+		}
+	}
+
+	klog.Infof("Resizing drive %s from %d to %d bytes", driveUUID, drive.Size, newSizeBytes)
+	resized, _, err := c.sdk.Drives.Resize(ctx, driveUUID, &cloudsigma.DriveUpdateRequest{
+		Drive: &cloudsigma.Drive{
+			Name:  drive.Name,
+			Media: drive.Media,
+			Size:  int(newSizeBytes),
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to resize drive: %w", err)
+	}
+	if len(resized) > 0 && resized[0].Status == "resizing" {
+		if _, err := c.WaitForDriveReady(ctx, driveUUID, 5*time.Minute); err != nil {
+			return fmt.Errorf("drive did not become ready after resize: %w", err)
+		}
+	}
+
+	klog.Infof("Drive %s resized to %d bytes", driveUUID, newSizeBytes)
+	return nil
+}
+
 // WaitForDriveReady waits for a drive to reach "mounted" or "unmounted" status
 func (c *Client) WaitForDriveReady(ctx context.Context, uuid string, timeout time.Duration) (*cloudsigma.Drive, error) {
 	klog.V(2).Infof("Waiting for drive to be ready: %s", uuid)
@@ -112,6 +193,35 @@ func (c *Client) GetDrive(ctx context.Context, uuid string) (*cloudsigma.Drive,
 	return drive, nil
 }
 
+// ListDrives lists all drives matching opts, following pagination until every page is collected
+func (c *Client) ListDrives(ctx context.Context, opts *cloudsigma.DriveListOptions) ([]cloudsigma.Drive, error) {
+	klog.V(4).Info("Listing drives")
+
+	pageOpts := cloudsigma.DriveListOptions{}
+	if opts != nil {
+		pageOpts = *opts
+	}
+	pageOpts.Limit = listPageSize
+	pageOpts.Offset = 0
+
+	var drives []cloudsigma.Drive
+	for {
+		page, resp, err := c.sdk.Drives.List(ctx, &pageOpts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list drives: %w", err)
+		}
+
+		drives = append(drives, page...)
+		pageOpts.Offset += len(page)
+		if len(page) == 0 || resp == nil || resp.Meta == nil || pageOpts.Offset >= resp.Meta.TotalCount {
+			break
+		}
+	}
+
+	klog.V(4).Infof("Found %d drives", len(drives))
+	return drives, nil
+}
+
 // DeleteDrive deletes a drive
 func (c *Client) DeleteDrive(ctx context.Context, uuid string) error {
 	klog.V(2).Infof("Deleting drive: %s", uuid)
@@ -0,0 +1,154 @@
+/*
+Copyright 2025 Kube-DC Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloud
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cloudsigma/cloudsigma-sdk-go/cloudsigma"
+)
+
+func TestListDrivesPaginates(t *testing.T) {
+	const total = listPageSize + 1
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/2.0/drives/detail/", pagedHandler(t, total, func(i int) interface{} {
+		return cloudsigma.Drive{UUID: fmt.Sprintf("drive-%d", i)}
+	}))
+
+	c := newTestClient(t, mux)
+	drives, err := c.ListDrives(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("ListDrives() error = %v", err)
+	}
+	if len(drives) != total {
+		t.Fatalf("ListDrives() returned %d drives, want %d", len(drives), total)
+	}
+	if drives[total-1].UUID != fmt.Sprintf("drive-%d", total-1) {
+		t.Errorf("last drive = %s, want drive-%d", drives[total-1].UUID, total-1)
+	}
+}
+
+func TestResizeServerDrive(t *testing.T) {
+	oldInterval := resizePollInterval
+	resizePollInterval = time.Millisecond
+	defer func() { resizePollInterval = oldInterval }()
+
+	tests := []struct {
+		name         string
+		serverStatus string
+		newSize      int64
+		resizeFails  bool
+		wantCalls    []string
+		wantErr      bool
+	}{
+		{
+			name:         "running server is stopped and restarted",
+			serverStatus: "running",
+			newSize:      20,
+			wantCalls:    []string{"stop", "resize:20", "start"},
+		},
+		{
+			name:         "running server is restarted when the resize fails",
+			serverStatus: "running",
+			newSize:      20,
+			resizeFails:  true,
+			wantCalls:    []string{"stop", "resize:20", "start"},
+			wantErr:      true,
+		},
+		{
+			name:         "stopped server is left stopped",
+			serverStatus: "stopped",
+			newSize:      20,
+			wantCalls:    []string{"resize:20"},
+		},
+		{
+			name:         "same size is a no-op",
+			serverStatus: "running",
+			newSize:      10,
+		},
+		{
+			name:         "shrink is rejected",
+			serverStatus: "running",
+			newSize:      5,
+			wantErr:      true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status := tt.serverStatus
+			var calls []string
+
+			mux := http.NewServeMux()
+			mux.HandleFunc("/api/2.0/drives/drv-1/", func(w http.ResponseWriter, r *http.Request) {
+				writeJSON(w, cloudsigma.Drive{UUID: "drv-1", Name: "worker-0-drive-0", Size: 10, Status: "mounted",
+					MountedOn: []cloudsigma.ResourceLink{{UUID: "srv-1"}}})
+			})
+			mux.HandleFunc("/api/2.0/drives/drv-1/action/", func(w http.ResponseWriter, r *http.Request) {
+				var req cloudsigma.DriveUpdateRequest
+				if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+					t.Errorf("failed to decode resize request: %v", err)
+				}
+				if status != "stopped" {
+					t.Errorf("drive resized while server is %s", status)
+				}
+				calls = append(calls, fmt.Sprintf("resize:%d", req.Drive.Size))
+				if tt.resizeFails {
+					http.Error(w, `[{"error_type": "backend", "error_message": "resize failed"}]`, http.StatusInternalServerError)
+					return
+				}
+				writeJSON(w, map[string]interface{}{"objects": []cloudsigma.Drive{{UUID: "drv-1", Size: req.Drive.Size, Status: "mounted"}}})
+			})
+			mux.HandleFunc("/api/2.0/servers/srv-1/", func(w http.ResponseWriter, r *http.Request) {
+				// A stopping server reports stopped on the next poll
+				if status == "stopping" {
+					status = "stopped"
+				}
+				writeJSON(w, cloudsigma.Server{UUID: "srv-1", Status: status})
+			})
+			mux.HandleFunc("/api/2.0/servers/srv-1/action/", func(w http.ResponseWriter, r *http.Request) {
+				action := r.URL.Query().Get("do")
+				calls = append(calls, action)
+				if action == "stop" {
+					status = "stopping"
+				} else {
+					status = "running"
+				}
+				writeJSON(w, cloudsigma.ServerAction{Action: action, Result: "success", UUID: "srv-1"})
+			})
+
+			c := newTestClient(t, mux)
+			err := c.ResizeServerDrive(context.Background(), "drv-1", tt.newSize)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ResizeServerDrive() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(tt.wantCalls) > 0 && status != tt.serverStatus {
+				t.Errorf("server status = %s, want %s", status, tt.serverStatus)
+			}
+			if strings.Join(calls, ",") != strings.Join(tt.wantCalls, ",") {
+				t.Errorf("calls = %v, want %v", calls, tt.wantCalls)
+			}
+		})
+	}
+}
@@ -19,6 +19,12 @@ package cloud
 import (
 	"errors"
 	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cloudsigma/cloudsigma-sdk-go/cloudsigma"
 )
 
 // PermissionDeniedError indicates the impersonated user cannot access a CloudSigma resource.
@@ -68,3 +74,169 @@ func GetPermissionDeniedError(err error) *PermissionDeniedError {
 	}
 	return nil
 }
+
+// IPPoolExhaustedError indicates no free IP is left in the pool an allocation was requested from
+type IPPoolExhaustedError struct {
+	Pool string // "public" or "vlan:<uuid>"
+}
+
+func (e *IPPoolExhaustedError) Error() string {
+	return fmt.Sprintf("no available IPs in %s pool", e.Pool)
+}
+
+// IsIPPoolExhaustedError checks if an error is an IPPoolExhaustedError
+func IsIPPoolExhaustedError(err error) bool {
+	var pee *IPPoolExhaustedError
+	return errors.As(err, &pee)
+}
+
+// ServerStatusTimeoutError indicates a server did not reach the wanted status in time
+type ServerStatusTimeoutError struct {
+	UUID   string
+	Want   string
+	Status string // last observed status
+}
+
+func (e *ServerStatusTimeoutError) Error() string {
+	return fmt.Sprintf("timed out waiting for server %s to become %s (status: %s)", e.UUID, e.Want, e.Status)
+}
+
+// IsServerStatusTimeoutError checks if an error is a ServerStatusTimeoutError
+func IsServerStatusTimeoutError(err error) bool {
+	var ste *ServerStatusTimeoutError
+	return errors.As(err, &ste)
+}
+
+// InvalidServerSpecError indicates a server spec that CloudSigma would reject, caught before submission
+type InvalidServerSpecError struct {
+	Reason string
+}
+
+func (e *InvalidServerSpecError) Error() string {
+	return fmt.Sprintf("invalid server spec: %s", e.Reason)
+}
+
+// QuotaExceededError indicates the CloudSigma account cannot provide the resources a request needs.
+// It is not terminal: capacity comes back when other servers are removed or the balance is topped up.
+type QuotaExceededError struct {
+	Resources []string // exhausted resources, e.g. "cpu", "mem", "dssd", "ip"
+}
+
+func (e *QuotaExceededError) Error() string {
+	return fmt.Sprintf("account quota exceeded for %s", strings.Join(e.Resources, ", "))
+}
+
+// IsQuotaExceededError checks if an error is a QuotaExceededError
+func IsQuotaExceededError(err error) bool {
+	var qee *QuotaExceededError
+	return errors.As(err, &qee)
+}
+
+// DuplicateServerNameError indicates more than one server carries a name that should be unique
+type DuplicateServerNameError struct {
+	Name  string
+	UUIDs []string
+}
+
+func (e *DuplicateServerNameError) Error() string {
+	return fmt.Sprintf("found %d servers named %s: %s", len(e.UUIDs), e.Name, strings.Join(e.UUIDs, ", "))
+}
+
+// IsDuplicateServerNameError checks if an error is a DuplicateServerNameError
+func IsDuplicateServerNameError(err error) bool {
+	var dne *DuplicateServerNameError
+	return errors.As(err, &dne)
+}
+
+// APIError is returned when a direct CloudSigma API call responds with a non-2xx status
+type APIError struct {
+	StatusCode int
+	Body       string
+	// RetryAfter is the delay asked for by the Retry-After header, 0 if the response had none
+	RetryAfter time.Duration
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("API error %d: %s", e.StatusCode, e.Body)
+}
+
+// StatusCodeFromError returns the HTTP status code carried by a CloudSigma error, or 0 if unknown
+func StatusCodeFromError(err error) int {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode
+	}
+	var pde *PermissionDeniedError
+	if errors.As(err, &pde) {
+		return pde.StatusCode
+	}
+	var sdkErr *cloudsigma.ErrorResponse
+	if errors.As(err, &sdkErr) && sdkErr.Response != nil {
+		return sdkErr.Response.StatusCode
+	}
+	return 0
+}
+
+// ParseRetryAfter returns the delay a Retry-After header value asks for, given either in seconds
+// or as an HTTP date. It returns 0 for an empty or malformed value and for a date in the past.
+func ParseRetryAfter(value string, now time.Time) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds <= 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil && date.After(now) {
+		return date.Sub(now)
+	}
+	return 0
+}
+
+// IsRateLimitedError checks if CloudSigma throttled a request with 429 Too Many Requests
+func IsRateLimitedError(err error) bool {
+	return StatusCodeFromError(err) == http.StatusTooManyRequests
+}
+
+// RetryAfterFromError returns the delay CloudSigma asked for when it throttled a request. ok is
+// false if err is not a 429; delay is 0 if the response did not carry a Retry-After header.
+func RetryAfterFromError(err error) (delay time.Duration, ok bool) {
+	if !IsRateLimitedError(err) {
+		return 0, false
+	}
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.RetryAfter, true
+	}
+	var sdkErr *cloudsigma.ErrorResponse
+	if errors.As(err, &sdkErr) && sdkErr.Response != nil && sdkErr.Response.Response != nil {
+		return ParseRetryAfter(sdkErr.Response.Header.Get("Retry-After"), time.Now()), true
+	}
+	return 0, true
+}
+
+// IsTerminalError checks if an error will not go away by retrying the same request.
+// Permission denied and 4xx client errors are terminal; 5xx, throttling, conflicts,
+// timeouts and errors without a status code (network, not-ready) are retryable.
+func IsTerminalError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if IsPermissionDeniedError(err) {
+		return true
+	}
+	var ise *InvalidServerSpecError
+	if errors.As(err, &ise) {
+		return true
+	}
+
+	code := StatusCodeFromError(err)
+	switch code {
+	case http.StatusRequestTimeout, http.StatusConflict, http.StatusTooManyRequests:
+		return false
+	}
+	return code >= 400 && code < 500
+}
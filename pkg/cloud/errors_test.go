@@ -0,0 +1,129 @@
+/*
+Copyright 2025 Kube-DC Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloud
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/cloudsigma/cloudsigma-sdk-go/cloudsigma"
+)
+
+func TestIsTerminalError(t *testing.T) {
+	sdkErr := func(code int) error {
+		req, _ := http.NewRequest(http.MethodGet, "https://zrh.cloudsigma.com/api/2.0/drives/x/", nil)
+		return &cloudsigma.ErrorResponse{
+			Response: &cloudsigma.Response{Response: &http.Response{StatusCode: code, Request: req}},
+		}
+	}
+
+	tests := []struct {
+		name         string
+		err          error
+		wantTerminal bool
+	}{
+		{name: "nil", err: nil, wantTerminal: false},
+		{name: "plain error", err: errors.New("connection reset"), wantTerminal: false},
+		{name: "permission denied", err: NewPermissionDeniedError("server", "uuid", 403, "user@example.com", errors.New("forbidden")), wantTerminal: true},
+		{name: "api 400", err: &APIError{StatusCode: 400, Body: "invalid meta"}, wantTerminal: true},
+		{name: "wrapped api 400", err: fmt.Errorf("failed to create server: %w", &APIError{StatusCode: 400}), wantTerminal: true},
+		{name: "api 409 conflict", err: &APIError{StatusCode: 409}, wantTerminal: false},
+		{name: "api 429 throttled", err: &APIError{StatusCode: 429}, wantTerminal: false},
+		{name: "api 500", err: &APIError{StatusCode: 500}, wantTerminal: false},
+		{name: "api 503", err: &APIError{StatusCode: 503}, wantTerminal: false},
+		{name: "sdk 404 on clone", err: fmt.Errorf("failed to clone drive: %w", sdkErr(404)), wantTerminal: true},
+		{name: "sdk 502", err: sdkErr(502), wantTerminal: false},
+		{name: "invalid server spec", err: &InvalidServerSpecError{Reason: "meta key \"base64_fields\" is reserved"}, wantTerminal: true},
+		{name: "quota exceeded", err: &QuotaExceededError{Resources: []string{"cpu"}}, wantTerminal: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsTerminalError(tt.err); got != tt.wantTerminal {
+				t.Errorf("IsTerminalError() = %v, want %v", got, tt.wantTerminal)
+			}
+		})
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{value: "", want: 0},
+		{value: "30", want: 30 * time.Second},
+		{value: " 5 ", want: 5 * time.Second},
+		{value: "0", want: 0},
+		{value: "-3", want: 0},
+		{value: now.Add(90 * time.Second).Format(http.TimeFormat), want: 90 * time.Second},
+		{value: now.Add(-time.Minute).Format(http.TimeFormat), want: 0},
+		{value: "soon", want: 0},
+	}
+	for _, tt := range tests {
+		if got := ParseRetryAfter(tt.value, now); got != tt.want {
+			t.Errorf("ParseRetryAfter(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestRetryAfterFromError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Retry-After", "42")
+		w.WriteHeader(http.StatusTooManyRequests)
+		_, _ = w.Write([]byte(`[{"error_type":"throttled","error_message":"Request was throttled"}]`))
+	}))
+	defer server.Close()
+	client, err := NewClientWithEndpoint("user", "pass", server.URL+"/api/2.0")
+	if err != nil {
+		t.Fatalf("NewClientWithEndpoint() error = %v", err)
+	}
+	ctx := context.Background()
+
+	// Direct requests keep the header on the APIError
+	_, directErr := client.GetAccountUsage(ctx)
+	// SDK requests keep the whole response
+	_, sdkErr := client.GetServer(ctx, "server-uuid")
+
+	tests := []struct {
+		name      string
+		err       error
+		wantDelay time.Duration
+		wantOK    bool
+	}{
+		{name: "direct request", err: directErr, wantDelay: 42 * time.Second, wantOK: true},
+		{name: "sdk request", err: sdkErr, wantDelay: 42 * time.Second, wantOK: true},
+		{name: "429 without Retry-After", err: fmt.Errorf("failed: %w", &APIError{StatusCode: 429}), wantOK: true},
+		{name: "other status", err: &APIError{StatusCode: 503, RetryAfter: time.Minute}, wantOK: false},
+		{name: "plain error", err: errors.New("connection reset"), wantOK: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			delay, ok := RetryAfterFromError(tt.err)
+			if delay != tt.wantDelay || ok != tt.wantOK {
+				t.Errorf("RetryAfterFromError(%v) = %v, %v; want %v, %v", tt.err, delay, ok, tt.wantDelay, tt.wantOK)
+			}
+		})
+	}
+}
@@ -0,0 +1,220 @@
+/*
+Copyright 2025 Kube-DC Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fake
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/cloudsigma/cloudsigma-sdk-go/cloudsigma"
+)
+
+func (s *Server) handleDrives(w http.ResponseWriter, r *http.Request, parts []string) {
+	switch {
+	case (len(parts) == 0 || parts[0] == "detail" && len(parts) == 1) && r.Method == http.MethodGet:
+		s.listDrives(w, r)
+
+	case len(parts) == 0 && r.Method == http.MethodPost:
+		var req objectsRequest[cloudsigma.Drive]
+		if err := decodeBody(r, &req); err != nil {
+			writeError(w, http.StatusBadRequest, "validation", err.Error())
+			return
+		}
+		for _, drive := range req.Objects {
+			if drive.Name == "" || drive.Size <= 0 {
+				writeError(w, http.StatusBadRequest, "validation", "name and size are required")
+				return
+			}
+		}
+		created := make([]cloudsigma.Drive, 0, len(req.Objects))
+		for _, drive := range req.Objects {
+			drive.Status = ""
+			created = append(created, deepCopy(*s.newDrive(drive)))
+		}
+		writeJSON(w, http.StatusCreated, listResponse{Objects: created})
+
+	case len(parts) == 1:
+		drive, ok := s.drives[parts[0]]
+		if !ok {
+			writeError(w, http.StatusNotFound, "notexist", "drive "+parts[0]+" does not exist")
+			return
+		}
+		switch r.Method {
+		case http.MethodGet:
+			writeJSON(w, http.StatusOK, deepCopy(*drive))
+		case http.MethodDelete:
+			if len(drive.MountedOn) > 0 {
+				writeError(w, http.StatusBadRequest, "permission", "drive "+drive.UUID+" is mounted")
+				return
+			}
+			delete(s.drives, drive.UUID)
+			s.untag(drive.UUID)
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+
+	case len(parts) == 2 && parts[1] == "action" && r.Method == http.MethodPost:
+		drive, ok := s.drives[parts[0]]
+		if !ok {
+			writeError(w, http.StatusNotFound, "notexist", "drive "+parts[0]+" does not exist")
+			return
+		}
+		var req cloudsigma.Drive
+		if err := decodeBody(r, &req); err != nil {
+			writeError(w, http.StatusBadRequest, "validation", err.Error())
+			return
+		}
+		switch action := r.URL.Query().Get("do"); action {
+		case "clone":
+			s.cloneDrive(w, drive, req)
+		case "resize":
+			s.resizeDrive(w, drive, req)
+		default:
+			writeError(w, http.StatusBadRequest, "validation", "unsupported drive action "+action)
+		}
+
+	default:
+		writeError(w, http.StatusNotFound, "notexist", "unknown endpoint "+r.URL.Path)
+	}
+}
+
+// listDrives lists drives, filtered by the comma-separated name and uuid query parameters
+func (s *Server) listDrives(w http.ResponseWriter, r *http.Request) {
+	var names, uuids []string
+	if v := r.URL.Query().Get("name"); v != "" {
+		names = strings.Split(v, ",")
+	}
+	if v := r.URL.Query().Get("uuid"); v != "" {
+		uuids = strings.Split(v, ",")
+	}
+
+	drives := make([]cloudsigma.Drive, 0, len(s.drives))
+	for _, uuid := range sortedKeys(s.drives) {
+		drive := s.drives[uuid]
+		if names != nil && !slices.Contains(names, drive.Name) {
+			continue
+		}
+		if uuids != nil && !slices.Contains(uuids, drive.UUID) {
+			continue
+		}
+		drives = append(drives, deepCopy(*drive))
+	}
+	writeJSON(w, http.StatusOK, page(r, drives))
+}
+
+// cloneDrive copies a drive; the clone may be renamed, given another media and more meta, and
+// grown but not shrunk
+func (s *Server) cloneDrive(w http.ResponseWriter, source *cloudsigma.Drive, req cloudsigma.Drive) {
+	clone := cloudsigma.Drive{
+		Name:        req.Name,
+		Size:        req.Size,
+		Media:       req.Media,
+		StorageType: source.StorageType,
+		Meta:        map[string]interface{}{},
+	}
+	for k, v := range source.Meta {
+		clone.Meta[k] = v
+	}
+	for k, v := range req.Meta {
+		clone.Meta[k] = v
+	}
+	if clone.Name == "" {
+		clone.Name = source.Name
+	}
+	if clone.Media == "" {
+		clone.Media = source.Media
+	}
+	if clone.Size == 0 {
+		clone.Size = source.Size
+	}
+	if clone.Size < source.Size {
+		writeError(w, http.StatusBadRequest, "validation", "clone cannot be smaller than the source drive")
+		return
+	}
+	writeJSON(w, http.StatusAccepted, listResponse{Objects: []cloudsigma.Drive{deepCopy(*s.newDrive(clone))}})
+}
+
+// resizeDrive grows a drive. Like CloudSigma it refuses to shrink drives or to resize drives in
+// use by a running server.
+func (s *Server) resizeDrive(w http.ResponseWriter, drive *cloudsigma.Drive, req cloudsigma.Drive) {
+	if req.Size < drive.Size {
+		writeError(w, http.StatusBadRequest, "validation", "drives cannot be shrunk")
+		return
+	}
+	for _, m := range drive.MountedOn {
+		if server, ok := s.servers[m.UUID]; ok && server.Status == "running" {
+			writeError(w, http.StatusBadRequest, "permission", "drive "+drive.UUID+" is in use by running server "+m.UUID)
+			return
+		}
+	}
+	drive.Size = req.Size
+	writeJSON(w, http.StatusAccepted, listResponse{Objects: []cloudsigma.Drive{deepCopy(*drive)}})
+}
+
+// newDrive stores an unmounted copy of drive, assigning a UUID when it has none and the
+// unmounted status when it has no status
+func (s *Server) newDrive(drive cloudsigma.Drive) *cloudsigma.Drive {
+	d := deepCopy(drive)
+	if d.UUID == "" {
+		d.UUID = s.nextUUID()
+	}
+	d.ResourceURI = resourceURI("drives", d.UUID)
+	if d.Status == "" {
+		d.Status = "unmounted"
+	}
+	d.MountedOn = nil
+	if d.Media == "" {
+		d.Media = "disk"
+	}
+	if d.StorageType == "" {
+		d.StorageType = "dssd"
+	}
+	s.drives[d.UUID] = &d
+	return &d
+}
+
+// mount records that a drive is attached to a server
+func (s *Server) mount(serverUUID, driveUUID string) {
+	drive, ok := s.drives[driveUUID]
+	if !ok {
+		return
+	}
+	for _, m := range drive.MountedOn {
+		if m.UUID == serverUUID {
+			return
+		}
+	}
+	drive.MountedOn = append(drive.MountedOn, cloudsigma.ResourceLink{UUID: serverUUID, ResourceURI: resourceURI("servers", serverUUID)})
+	drive.Status = "mounted"
+}
+
+// unmount records that a drive was detached from a server
+func (s *Server) unmount(serverUUID, driveUUID string) {
+	drive, ok := s.drives[driveUUID]
+	if !ok {
+		return
+	}
+	drive.MountedOn = slices.DeleteFunc(drive.MountedOn, func(m cloudsigma.ResourceLink) bool {
+		return m.UUID == serverUUID
+	})
+	if len(drive.MountedOn) == 0 {
+		drive.MountedOn = nil
+		drive.Status = "unmounted"
+	}
+}
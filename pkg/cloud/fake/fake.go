@@ -0,0 +1,382 @@
+/*
+Copyright 2025 Kube-DC Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package fake provides an in-memory CloudSigma API served over httptest. It implements the
+// subset of endpoints the controllers, CCM and CSI driver use (servers, drives, IPs, tags,
+// subscriptions, the OAuth token endpoint and impersonation) so they can be tested without credentials.
+package fake
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/cloudsigma/cloudsigma-sdk-go/cloudsigma"
+
+	"github.com/kube-dc/cluster-api-provider-cloudsigma/pkg/auth"
+	"github.com/kube-dc/cluster-api-provider-cloudsigma/pkg/cloud"
+)
+
+// Credentials accepted by the fake
+const (
+	Username     = "fake@example.com"
+	Password     = "fake-password"
+	ClientID     = "fake-client"
+	ClientSecret = "fake-secret"
+
+	// Region is the location SDK clients built by the fake are configured for
+	Region = "zrh"
+)
+
+const (
+	apiPrefix       = "/api/2.0/"
+	tokenPath       = "/realms/cloudsigma/protocol/openid-connect/token"
+	impersonatePath = "/service_provider/api/v1/user/impersonate"
+
+	// tokenTTL is the expires_in of every token the fake issues, in seconds
+	tokenTTL = 3600
+)
+
+// Server is a fake CloudSigma API. All state is kept in memory and guarded by a single mutex;
+// every change is applied synchronously, so drives are never "cloning" and detaches complete
+// before the request returns.
+type Server struct {
+	srv *httptest.Server
+
+	mu      sync.Mutex
+	lastID  uint64
+	servers map[string]*cloudsigma.Server
+	drives  map[string]*cloudsigma.Drive
+	ips     map[string]*cloud.IPDetail
+	vlans   map[string]*cloudsigma.VLAN
+	tags    map[string]*cloudsigma.Tag
+
+	subscriptions []cloudsigma.Subscription
+
+	// readOnly lists the API resources, e.g. "tags", whose writes fail; see SetReadOnly
+	readOnly map[string]bool
+
+	// Issued tokens: service account, RPT and impersonated user tokens (token -> user email)
+	saTokens   map[string]bool
+	rptTokens  map[string]bool
+	userTokens map[string]string
+}
+
+// NewServer starts a fake CloudSigma API. Callers must Close it when done.
+func NewServer() *Server {
+	s := &Server{
+		servers:    make(map[string]*cloudsigma.Server),
+		drives:     make(map[string]*cloudsigma.Drive),
+		ips:        make(map[string]*cloud.IPDetail),
+		vlans:      make(map[string]*cloudsigma.VLAN),
+		readOnly:   make(map[string]bool),
+		tags:       make(map[string]*cloudsigma.Tag),
+		saTokens:   make(map[string]bool),
+		rptTokens:  make(map[string]bool),
+		userTokens: make(map[string]string),
+	}
+	s.srv = httptest.NewServer(s)
+	return s
+}
+
+// Close shuts the fake down
+func (s *Server) Close() {
+	s.srv.Close()
+}
+
+// URL is the base URL of the fake, also used as the OAuth URL
+func (s *Server) URL() string {
+	return s.srv.URL
+}
+
+// APIEndpoint is the API 2.0 endpoint of the fake
+func (s *Server) APIEndpoint() string {
+	return s.srv.URL + "/api/2.0"
+}
+
+// HTTPClient returns a client that sends every request to the fake whatever its host, for
+// code that builds URLs from a region (the SDK, impersonation on direct.<region>)
+func (s *Server) HTTPClient() *http.Client {
+	target, _ := url.Parse(s.srv.URL)
+	return &http.Client{Transport: &rewriteTransport{target: target}}
+}
+
+// NewClient returns a pkg/cloud client using the fake
+func (s *Server) NewClient() (*cloud.Client, error) {
+	return cloud.NewClientWithEndpoint(Username, Password, s.APIEndpoint())
+}
+
+// NewSDKClient returns an SDK client using the fake, as the CSI driver takes via Config.CloudClient
+func (s *Server) NewSDKClient() *cloudsigma.Client {
+	cred := cloudsigma.NewUsernamePasswordCredentialsProvider(Username, Password)
+	return cloudsigma.NewClient(cred, cloudsigma.WithLocation(Region), cloudsigma.WithHTTPClient(s.HTTPClient()))
+}
+
+// NewImpersonationClient returns an impersonation client whose OAuth and impersonation requests
+// go to the fake
+func (s *Server) NewImpersonationClient() (*auth.ImpersonationClient, error) {
+	return auth.NewImpersonationClient(auth.ImpersonationConfig{
+		OAuthURL:     s.URL(),
+		ClientID:     ClientID,
+		ClientSecret: ClientSecret,
+		HTTPClient:   s.HTTPClient(),
+	})
+}
+
+// rewriteTransport redirects requests to the scheme and host of target
+type rewriteTransport struct {
+	target *url.URL
+}
+
+func (t *rewriteTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.URL.Scheme = t.target.Scheme
+	req.URL.Host = t.target.Host
+	req.Host = t.target.Host
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+// AddDrive seeds a drive, e.g. an OS image to clone. A UUID is assigned when unset and the drive
+// starts unmounted. It returns the stored drive.
+func (s *Server) AddDrive(drive cloudsigma.Drive) cloudsigma.Drive {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	d := s.newDrive(drive)
+	return deepCopy(*d)
+}
+
+// AddIP seeds an IP, which CloudSigma only hands out through subscriptions
+func (s *Server) AddIP(ip cloud.IPDetail) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored := deepCopy(ip)
+	s.ips[ip.UUID] = &stored
+}
+
+// AddVLAN seeds a VLAN; its subscription scopes the IPs it may use
+func (s *Server) AddVLAN(vlan cloudsigma.VLAN) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored := deepCopy(vlan)
+	s.vlans[vlan.UUID] = &stored
+}
+
+// SetReadOnly makes writes to an API resource, e.g. "tags", fail with a server error until it is
+// set back to false
+func (s *Server) SetReadOnly(resource string, readOnly bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.readOnly[resource] = readOnly
+}
+
+// GetIP returns a copy of an IP, or false if it does not exist
+func (s *Server) GetIP(uuid string) (cloud.IPDetail, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ip, ok := s.ips[uuid]
+	if !ok {
+		return cloud.IPDetail{}, false
+	}
+	return s.ipView(ip), true
+}
+
+// GetServer returns a copy of a server, or false if it does not exist
+func (s *Server) GetServer(uuid string) (cloudsigma.Server, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	server, ok := s.servers[uuid]
+	if !ok {
+		return cloudsigma.Server{}, false
+	}
+	return deepCopy(*server), true
+}
+
+// GetDrive returns a copy of a drive, or false if it does not exist
+func (s *Server) GetDrive(uuid string) (cloudsigma.Drive, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	drive, ok := s.drives[uuid]
+	if !ok {
+		return cloudsigma.Drive{}, false
+	}
+	return deepCopy(*drive), true
+}
+
+// GetTag returns a copy of the tag named name, or false if there is none
+func (s *Server) GetTag(name string) (cloudsigma.Tag, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, tag := range s.tags {
+		if tag.Name == name {
+			return s.tagView(tag), true
+		}
+	}
+	return cloudsigma.Tag{}, false
+}
+
+// ServeHTTP routes a request to the OAuth, impersonation or API handlers
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch {
+	case r.URL.Path == tokenPath:
+		s.handleToken(w, r)
+	case r.URL.Path == impersonatePath:
+		s.handleImpersonate(w, r)
+	case strings.HasPrefix(r.URL.Path, apiPrefix):
+		if !s.authorized(r) {
+			writeError(w, http.StatusUnauthorized, "permission", "invalid credentials")
+			return
+		}
+		parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, apiPrefix), "/"), "/")
+		if s.readOnly[parts[0]] && r.Method != http.MethodGet {
+			writeError(w, http.StatusInternalServerError, "servererror", parts[0]+" are read-only")
+			return
+		}
+		switch parts[0] {
+		case "servers":
+			s.handleServers(w, r, parts[1:])
+		case "drives":
+			s.handleDrives(w, r, parts[1:])
+		case "ips":
+			s.handleIPs(w, r, parts[1:])
+		case "vlans":
+			s.handleVLANs(w, r, parts[1:])
+		case "tags":
+			s.handleTags(w, r, parts[1:])
+		case "subscriptions":
+			s.handleSubscriptions(w, r, parts[1:])
+		case "profile":
+			writeJSON(w, http.StatusOK, cloudsigma.Profile{Email: Username})
+		default:
+			writeError(w, http.StatusNotFound, "notexist", "unknown endpoint "+r.URL.Path)
+		}
+	default:
+		writeError(w, http.StatusNotFound, "notexist", "unknown endpoint "+r.URL.Path)
+	}
+}
+
+// authorized accepts basic auth with the fake credentials or an impersonated user token
+func (s *Server) authorized(r *http.Request) bool {
+	if user, pass, ok := r.BasicAuth(); ok {
+		return user == Username && pass == Password
+	}
+	token, ok := bearerToken(r)
+	if !ok {
+		return false
+	}
+	_, ok = s.userTokens[token]
+	return ok
+}
+
+// nextUUID returns a new UUID in the canonical form ParseProviderID accepts
+func (s *Server) nextUUID() string {
+	s.lastID++
+	return fmt.Sprintf("00000000-0000-4000-8000-%012x", s.lastID)
+}
+
+// listResponse is the envelope of CloudSigma list and create responses
+type listResponse struct {
+	Meta    cloudsigma.Meta `json:"meta"`
+	Objects interface{}     `json:"objects"`
+}
+
+// page applies the limit and offset query parameters to items. CloudSigma treats limit=0 as
+// "everything" and defaults to 20 otherwise.
+func page[T any](r *http.Request, items []T) listResponse {
+	limit, offset := 20, 0
+	if v, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil {
+		limit = v
+	}
+	if v, err := strconv.Atoi(r.URL.Query().Get("offset")); err == nil && v > 0 {
+		offset = v
+	}
+
+	total := len(items)
+	if offset > total {
+		offset = total
+	}
+	end := total
+	if limit > 0 && offset+limit < total {
+		end = offset + limit
+	}
+	return listResponse{
+		Meta:    cloudsigma.Meta{Limit: limit, Offset: offset, TotalCount: total},
+		Objects: items[offset:end],
+	}
+}
+
+// objectsRequest is the envelope of CloudSigma create requests
+type objectsRequest[T any] struct {
+	Objects []T `json:"objects"`
+}
+
+// resourceRef reads a resource reference, which CloudSigma accepts either as a UUID string or as
+// an object with a uuid field
+func resourceRef(raw json.RawMessage) string {
+	var uuid string
+	if err := json.Unmarshal(raw, &uuid); err == nil {
+		return uuid
+	}
+	var obj struct {
+		UUID string `json:"uuid"`
+	}
+	_ = json.Unmarshal(raw, &obj)
+	return obj.UUID
+}
+
+func resourceURI(kind, uuid string) string {
+	return fmt.Sprintf("%s%s/%s/", apiPrefix, kind, uuid)
+}
+
+func bearerToken(r *http.Request) (string, bool) {
+	return strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+}
+
+// decodeBody decodes a JSON request body into v; an empty body leaves v untouched
+func decodeBody(r *http.Request, v interface{}) error {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	return nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes an error in the CloudSigma format the SDK decodes
+func writeError(w http.ResponseWriter, status int, errType, message string) {
+	writeJSON(w, status, []cloudsigma.Error{{Type: errType, Message: message}})
+}
+
+// deepCopy copies v through its JSON form, so callers never share state with the fake
+func deepCopy[T any](v T) T {
+	var out T
+	data, _ := json.Marshal(v)
+	_ = json.Unmarshal(data, &out)
+	return out
+}
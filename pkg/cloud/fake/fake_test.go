@@ -0,0 +1,408 @@
+/*
+Copyright 2025 Kube-DC Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fake
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/cloudsigma/cloudsigma-sdk-go/cloudsigma"
+
+	infrav1 "github.com/kube-dc/cluster-api-provider-cloudsigma/api/v1beta1"
+	"github.com/kube-dc/cluster-api-provider-cloudsigma/pkg/cloud"
+)
+
+const gib = 1024 * 1024 * 1024
+
+func newTestServer(t *testing.T) *Server {
+	t.Helper()
+	s := NewServer()
+	t.Cleanup(s.Close)
+	return s
+}
+
+func TestServerLifecycle(t *testing.T) {
+	ctx := context.Background()
+	s := newTestServer(t)
+	image := s.AddDrive(cloudsigma.Drive{Name: "ubuntu-24.04", Size: 10 * gib})
+
+	client, err := s.NewClient()
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+
+	created, err := client.CreateServer(ctx, cloud.ServerSpec{
+		Name:   "node-1",
+		CPU:    2000,
+		Memory: 2048,
+		Disks:  []infrav1.CloudSigmaDisk{{UUID: image.UUID, Device: "virtio", BootOrder: 1, Size: 20 * gib}},
+	})
+	if err != nil {
+		t.Fatalf("CreateServer() error = %v", err)
+	}
+	if _, ok := cloud.ParseProviderID(cloud.ProviderIDPrefix + created.UUID); !ok {
+		t.Errorf("server UUID %q is not a valid provider ID", created.UUID)
+	}
+
+	server, ok := s.GetServer(created.UUID)
+	if !ok {
+		t.Fatalf("server %s was not stored", created.UUID)
+	}
+	if server.Status != "stopped" || server.Memory != 2048*1024*1024 || len(server.Drives) != 1 {
+		t.Fatalf("stored server = %+v, want a stopped 2 GiB server with one drive", server)
+	}
+	bootUUID := server.Drives[0].Drive.UUID
+	boot, _ := s.GetDrive(bootUUID)
+	if bootUUID == image.UUID || boot.Size != 20*gib || boot.Status != "mounted" {
+		t.Errorf("boot drive = %+v, want a mounted 20 GiB clone of the image", boot)
+	}
+
+	if err := client.StartServer(ctx, created.UUID); err != nil {
+		t.Fatalf("StartServer() error = %v", err)
+	}
+	got, err := client.GetServer(ctx, created.UUID)
+	if err != nil || got.Status != "running" {
+		t.Fatalf("GetServer() = %+v, %v, want a running server", got, err)
+	}
+
+	if err := client.DeleteServer(ctx, created.UUID); err != nil {
+		t.Fatalf("DeleteServer() error = %v", err)
+	}
+	if _, ok := s.GetServer(created.UUID); ok {
+		t.Error("server still exists after DeleteServer()")
+	}
+	if _, ok := s.GetDrive(bootUUID); ok {
+		t.Error("boot drive still exists after DeleteServer()")
+	}
+	if _, ok := s.GetDrive(image.UUID); !ok {
+		t.Error("image drive was deleted with the server")
+	}
+}
+
+func TestSharedDriveIsAttachedWithoutCloning(t *testing.T) {
+	ctx := context.Background()
+	s := newTestServer(t)
+	image := s.AddDrive(cloudsigma.Drive{Name: "ubuntu-24.04", Size: 10 * gib})
+	dataset := s.AddDrive(cloudsigma.Drive{Name: "dataset", Size: 5 * gib, AllowMultimount: true})
+
+	client, err := s.NewClient()
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+
+	noClone := false
+	create := func(name string) (bootUUID, serverUUID string) {
+		t.Helper()
+		created, err := client.CreateServer(ctx, cloud.ServerSpec{
+			Name:   name,
+			CPU:    2000,
+			Memory: 2048,
+			Disks: []infrav1.CloudSigmaDisk{
+				{UUID: image.UUID, Device: "virtio", BootOrder: 1},
+				{UUID: dataset.UUID, Device: "virtio", Clone: &noClone},
+			},
+		})
+		if err != nil {
+			t.Fatalf("CreateServer(%s) error = %v", name, err)
+		}
+		server, _ := s.GetServer(created.UUID)
+		if len(server.Drives) != 2 {
+			t.Fatalf("server %s drives = %+v, want 2", name, server.Drives)
+		}
+		if server.Drives[0].Drive.UUID == image.UUID {
+			t.Errorf("server %s boots from the image itself, want a clone", name)
+		}
+		if server.Drives[1].Drive.UUID != dataset.UUID {
+			t.Errorf("server %s data drive = %s, want the shared drive %s", name, server.Drives[1].Drive.UUID, dataset.UUID)
+		}
+		return server.Drives[0].Drive.UUID, created.UUID
+	}
+	boot1, server1 := create("node-1")
+	boot2, _ := create("node-2")
+
+	if err := client.DeleteServer(ctx, server1); err != nil {
+		t.Fatalf("DeleteServer() error = %v", err)
+	}
+	if _, ok := s.GetDrive(boot1); ok {
+		t.Error("boot drive of the deleted server still exists")
+	}
+	if _, ok := s.GetDrive(boot2); !ok {
+		t.Error("boot drive of the other server was deleted")
+	}
+	if _, ok := s.GetDrive(dataset.UUID); !ok {
+		t.Error("shared drive was deleted with the server")
+	}
+}
+
+func TestSharedDriveMustAllowMultimount(t *testing.T) {
+	ctx := context.Background()
+	s := newTestServer(t)
+	image := s.AddDrive(cloudsigma.Drive{Name: "ubuntu-24.04", Size: 10 * gib})
+	dataset := s.AddDrive(cloudsigma.Drive{Name: "dataset", Size: 5 * gib})
+
+	client, err := s.NewClient()
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+
+	noClone := false
+	spec := func(name string) cloud.ServerSpec {
+		return cloud.ServerSpec{
+			Name:   name,
+			CPU:    2000,
+			Memory: 2048,
+			Disks: []infrav1.CloudSigmaDisk{
+				{UUID: image.UUID, Device: "virtio", BootOrder: 1},
+				{UUID: dataset.UUID, Device: "virtio", Clone: &noClone},
+			},
+		}
+	}
+	if _, err := client.CreateServer(ctx, spec("node-1")); err != nil {
+		t.Fatalf("CreateServer(node-1) error = %v", err)
+	}
+	_, err = client.CreateServer(ctx, spec("node-2"))
+	if err == nil || !cloud.IsTerminalError(err) {
+		t.Fatalf("CreateServer(node-2) error = %v, want a terminal error", err)
+	}
+	if clones, err := client.FindOrphanedClonedDrives(ctx, "node-2"); err != nil || len(clones) != 0 {
+		t.Errorf("FindOrphanedClonedDrives(node-2) = %+v, %v, want no clones left by the rejected create", clones, err)
+	}
+}
+
+func TestDiskMedia(t *testing.T) {
+	ctx := context.Background()
+	s := newTestServer(t)
+	image := s.AddDrive(cloudsigma.Drive{Name: "ubuntu-24.04", Size: 10 * gib})
+	iso := s.AddDrive(cloudsigma.Drive{Name: "installer.iso", Size: gib, Media: "cdrom"})
+	config := s.AddDrive(cloudsigma.Drive{Name: "config", Size: gib})
+
+	client, err := s.NewClient()
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+
+	created, err := client.CreateServer(ctx, cloud.ServerSpec{
+		Name:   "node-1",
+		CPU:    2000,
+		Memory: 2048,
+		Disks: []infrav1.CloudSigmaDisk{
+			{UUID: image.UUID, Device: "virtio", BootOrder: 1},
+			{UUID: iso.UUID, Device: "ide"},
+			{UUID: config.UUID, Device: "ide", Media: infrav1.DiskMediaCDROM},
+		},
+	})
+	if err != nil {
+		t.Fatalf("CreateServer() error = %v", err)
+	}
+	server, _ := s.GetServer(created.UUID)
+	for i, want := range []string{"disk", "cdrom", "cdrom"} {
+		drive, _ := s.GetDrive(server.Drives[i].Drive.UUID)
+		if drive.Media != want {
+			t.Errorf("drive %d media = %q, want %q", i, drive.Media, want)
+		}
+	}
+
+	// A shared drive is attached as it is, so it must already have the media asked for
+	noClone := false
+	_, err = client.CreateServer(ctx, cloud.ServerSpec{
+		Name:   "node-2",
+		CPU:    2000,
+		Memory: 2048,
+		Disks: []infrav1.CloudSigmaDisk{
+			{UUID: image.UUID, Device: "virtio", BootOrder: 1},
+			{UUID: config.UUID, Device: "ide", Clone: &noClone, Media: infrav1.DiskMediaCDROM},
+		},
+	})
+	if err == nil || !cloud.IsTerminalError(err) {
+		t.Errorf("CreateServer() with a shared disk of another media error = %v, want a terminal error", err)
+	}
+}
+
+func TestDriveAttachDetach(t *testing.T) {
+	ctx := context.Background()
+	s := newTestServer(t)
+	sdk := s.NewSDKClient()
+
+	servers, _, err := sdk.Servers.Create(ctx, &cloudsigma.ServerCreateRequest{
+		Servers: []cloudsigma.Server{{Name: "node-1", CPU: 2000, Memory: 2 * gib, VNCPassword: "secret"}},
+	})
+	if err != nil {
+		t.Fatalf("Servers.Create() error = %v", err)
+	}
+	server := servers[0]
+	serverUUID := server.UUID // Servers.Update clears the UUID of the request
+
+	drives, _, err := sdk.Drives.Create(ctx, &cloudsigma.DriveCreateRequest{
+		Drives: []cloudsigma.Drive{{Name: "pvc-1", Size: gib, Media: "disk"}},
+	})
+	if err != nil {
+		t.Fatalf("Drives.Create() error = %v", err)
+	}
+	volume := drives[0]
+	if volume.Status != "unmounted" {
+		t.Errorf("new drive status = %q, want unmounted", volume.Status)
+	}
+
+	// Attach
+	server.Drives = append(server.Drives, cloudsigma.ServerDrive{DevChannel: "0:1", Drive: &cloudsigma.Drive{UUID: volume.UUID}})
+	if _, _, err := sdk.Servers.Update(ctx, serverUUID, &cloudsigma.ServerUpdateRequest{Server: &server}); err != nil {
+		t.Fatalf("attach: Servers.Update() error = %v", err)
+	}
+	attached, _, err := sdk.Drives.Get(ctx, volume.UUID)
+	if err != nil {
+		t.Fatalf("Drives.Get() error = %v", err)
+	}
+	if attached.Status != "mounted" || len(attached.MountedOn) != 1 || attached.MountedOn[0].UUID != serverUUID {
+		t.Errorf("attached drive = %+v, want mounted on %s", attached, serverUUID)
+	}
+	if _, err := sdk.Drives.Delete(ctx, volume.UUID); err == nil {
+		t.Error("Drives.Delete() of a mounted drive succeeded")
+	}
+
+	// A drive can't be attached to a second server
+	other, _, err := sdk.Servers.Create(ctx, &cloudsigma.ServerCreateRequest{
+		Servers: []cloudsigma.Server{{Name: "node-2", CPU: 2000, Memory: 2 * gib, VNCPassword: "secret",
+			Drives: []cloudsigma.ServerDrive{{DevChannel: "0:1", Drive: &cloudsigma.Drive{UUID: volume.UUID}}}}},
+	})
+	if err == nil {
+		t.Errorf("Servers.Create() with a mounted drive = %+v, want error", other)
+	}
+
+	// Detach
+	server.Drives = nil
+	update := map[string]interface{}{"name": server.Name, "drives": []cloudsigma.ServerDrive{}}
+	req, err := sdk.NewRequest(http.MethodPut, "servers/"+serverUUID+"/", update)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sdk.Do(ctx, req, nil); err != nil {
+		t.Fatalf("detach: PUT server error = %v", err)
+	}
+	detached, _, err := sdk.Drives.Get(ctx, volume.UUID)
+	if err != nil {
+		t.Fatalf("Drives.Get() error = %v", err)
+	}
+	if detached.Status != "unmounted" || len(detached.MountedOn) != 0 {
+		t.Errorf("detached drive = %+v, want unmounted", detached)
+	}
+
+	// Delete
+	if _, err := sdk.Drives.Delete(ctx, volume.UUID); err != nil {
+		t.Fatalf("Drives.Delete() error = %v", err)
+	}
+	if _, resp, err := sdk.Drives.Get(ctx, volume.UUID); err == nil || resp == nil || resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Drives.Get() after delete error = %v, want 404", err)
+	}
+}
+
+func TestTagFilters(t *testing.T) {
+	ctx := context.Background()
+	s := newTestServer(t)
+	sdk := s.NewSDKClient()
+
+	_, _, err := sdk.Tags.Create(ctx, &cloudsigma.TagCreateRequest{
+		Tags: []cloudsigma.Tag{{Name: "service:default/web"}, {Name: "service:default/api"}, {Name: "cluster:demo"}},
+	})
+	if err != nil {
+		t.Fatalf("Tags.Create() error = %v", err)
+	}
+
+	tests := []struct {
+		query string
+		want  int
+	}{
+		{query: "", want: 3},
+		{query: "?name=cluster:demo", want: 1},
+		{query: "?name__startswith=service:", want: 2},
+		{query: "?name=missing", want: 0},
+	}
+	for _, tt := range tests {
+		req, _ := sdk.NewRequest(http.MethodGet, "tags/"+tt.query, nil)
+		var result struct {
+			Objects []cloudsigma.Tag `json:"objects"`
+		}
+		if _, err := sdk.Do(ctx, req, &result); err != nil {
+			t.Fatalf("GET tags/%s error = %v", tt.query, err)
+		}
+		if len(result.Objects) != tt.want {
+			t.Errorf("GET tags/%s returned %d tags, want %d", tt.query, len(result.Objects), tt.want)
+		}
+	}
+}
+
+func TestIPSubscriptionPurchase(t *testing.T) {
+	ctx := context.Background()
+	s := newTestServer(t)
+	client, err := s.NewClient()
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+
+	ip, err := client.PurchaseIPSubscription(ctx, "1 month")
+	if err != nil {
+		t.Fatalf("PurchaseIPSubscription() error = %v", err)
+	}
+	subs := s.Subscriptions()
+	if len(subs) != 1 || subs[0].Resource != "ip" || subs[0].Period != "1 month" || !subs[0].AutoRenew || subs[0].SubscribedObject != ip {
+		t.Errorf("subscriptions = %+v, want one auto-renewed monthly IP subscription for %s", subs, ip)
+	}
+
+	// The purchased IP is listed with its subscription, which makes it part of the static pool
+	ips, err := client.ListIPsDetail(ctx)
+	if err != nil {
+		t.Fatalf("ListIPsDetail() error = %v", err)
+	}
+	if len(ips) != 1 || ips[0].UUID != ip || ips[0].Subscription == nil {
+		t.Errorf("ListIPsDetail() = %+v, want %s with a subscription", ips, ip)
+	}
+}
+
+func TestImpersonation(t *testing.T) {
+	ctx := context.Background()
+	s := newTestServer(t)
+
+	ic, err := s.NewImpersonationClient()
+	if err != nil {
+		t.Fatalf("NewImpersonationClient() error = %v", err)
+	}
+	token, err := ic.GetImpersonatedToken(ctx, "tenant@example.com", Region)
+	if err != nil {
+		t.Fatalf("GetImpersonatedToken() error = %v", err)
+	}
+	if user, ok := s.UserForToken(token); !ok || user != "tenant@example.com" {
+		t.Errorf("UserForToken() = %q, %v, want tenant@example.com", user, ok)
+	}
+
+	for _, tc := range []struct {
+		token   string
+		wantErr bool
+	}{
+		{token: token},
+		{token: "forged", wantErr: true},
+	} {
+		sdk := cloudsigma.NewClient(cloudsigma.NewTokenCredentialsProvider(tc.token),
+			cloudsigma.WithLocation("direct."+Region), cloudsigma.WithHTTPClient(s.HTTPClient()))
+		_, resp, err := sdk.Profile.Get(ctx)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("Profile.Get() with token %q error = %v, wantErr %v", tc.token, err, tc.wantErr)
+		}
+		if tc.wantErr && (resp == nil || resp.StatusCode != http.StatusUnauthorized) {
+			t.Errorf("Profile.Get() with token %q response = %v, want 401", tc.token, resp)
+		}
+	}
+}
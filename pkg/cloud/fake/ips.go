@@ -0,0 +1,86 @@
+/*
+Copyright 2025 Kube-DC Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fake
+
+import (
+	"net/http"
+
+	"github.com/cloudsigma/cloudsigma-sdk-go/cloudsigma"
+
+	"github.com/kube-dc/cluster-api-provider-cloudsigma/pkg/cloud"
+)
+
+func (s *Server) handleIPs(w http.ResponseWriter, r *http.Request, parts []string) {
+	if r.Method == http.MethodPut && len(parts) == 1 {
+		s.updateIP(w, r, parts[0])
+		return
+	}
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	switch {
+	case len(parts) == 0 || parts[0] == "detail" && len(parts) == 1:
+		ips := make([]cloud.IPDetail, 0, len(s.ips))
+		for _, uuid := range sortedKeys(s.ips) {
+			ips = append(ips, s.ipView(s.ips[uuid]))
+		}
+		writeJSON(w, http.StatusOK, page(r, ips))
+
+	case len(parts) == 1:
+		ip, ok := s.ips[parts[0]]
+		if !ok {
+			writeError(w, http.StatusNotFound, "notexist", "IP "+parts[0]+" does not exist")
+			return
+		}
+		writeJSON(w, http.StatusOK, s.ipView(ip))
+
+	default:
+		writeError(w, http.StatusNotFound, "notexist", "unknown endpoint "+r.URL.Path)
+	}
+}
+
+// updateIP replaces the meta of an IP, the only field of an IP a client may change
+func (s *Server) updateIP(w http.ResponseWriter, r *http.Request, uuid string) {
+	ip, ok := s.ips[uuid]
+	if !ok {
+		writeError(w, http.StatusNotFound, "notexist", "IP "+uuid+" does not exist")
+		return
+	}
+	var req cloud.IPDetail
+	if err := decodeBody(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "validation", err.Error())
+		return
+	}
+	ip.Meta = deepCopy(req.Meta)
+	writeJSON(w, http.StatusOK, s.ipView(ip))
+}
+
+// ipView returns a copy of ip linked to the server whose NIC uses it as a static IP, if any
+func (s *Server) ipView(ip *cloud.IPDetail) cloud.IPDetail {
+	view := deepCopy(*ip)
+	for _, uuid := range sortedKeys(s.servers) {
+		for _, nic := range s.servers[uuid].NICs {
+			if conf := nic.IP4Configuration; conf != nil && conf.IPAddress != nil && conf.IPAddress.UUID == ip.UUID {
+				view.Server = &cloudsigma.ResourceLink{UUID: uuid, ResourceURI: resourceURI("servers", uuid)}
+				return view
+			}
+		}
+	}
+	return view
+}
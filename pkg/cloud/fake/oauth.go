@@ -0,0 +1,124 @@
+/*
+Copyright 2025 Kube-DC Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fake
+
+import (
+	"net/http"
+)
+
+const (
+	umaGrantType            = "urn:ietf:params:oauth:grant-type:uma-ticket"
+	serviceProviderAudience = "service_provider_api"
+)
+
+// tokenResponse is the body of OAuth token and impersonation responses
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int    `json:"expires_in"`
+}
+
+// handleToken issues service account tokens for the client credentials grant and RPT tokens for
+// the UMA grant, mirroring the Keycloak token endpoint
+func (s *Server) handleToken(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		writeOAuthError(w, http.StatusBadRequest, "invalid_request")
+		return
+	}
+
+	switch r.PostForm.Get("grant_type") {
+	case "client_credentials":
+		if r.PostForm.Get("client_id") != ClientID || r.PostForm.Get("client_secret") != ClientSecret {
+			writeOAuthError(w, http.StatusUnauthorized, "unauthorized_client")
+			return
+		}
+		token := "sa-" + s.nextUUID()
+		s.saTokens[token] = true
+		writeJSON(w, http.StatusOK, tokenResponse{AccessToken: token, TokenType: "Bearer", ExpiresIn: tokenTTL})
+
+	case umaGrantType:
+		token, ok := bearerToken(r)
+		if !ok || !s.saTokens[token] {
+			writeOAuthError(w, http.StatusUnauthorized, "invalid_token")
+			return
+		}
+		if r.PostForm.Get("audience") != serviceProviderAudience {
+			writeOAuthError(w, http.StatusBadRequest, "invalid_request")
+			return
+		}
+		rpt := "rpt-" + s.nextUUID()
+		s.rptTokens[rpt] = true
+		writeJSON(w, http.StatusOK, tokenResponse{AccessToken: rpt, TokenType: "Bearer", ExpiresIn: tokenTTL})
+
+	default:
+		writeOAuthError(w, http.StatusBadRequest, "unsupported_grant_type")
+	}
+}
+
+// handleImpersonate issues an API token for a user, given an RPT token and a service account
+// subject token
+func (s *Server) handleImpersonate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if token, ok := bearerToken(r); !ok || !s.rptTokens[token] {
+		writeOAuthError(w, http.StatusUnauthorized, "invalid_token")
+		return
+	}
+
+	var req struct {
+		UserEmail    string `json:"user_email"`
+		SubjectToken string `json:"subject_token"`
+	}
+	if err := decodeBody(r, &req); err != nil || req.UserEmail == "" {
+		writeOAuthError(w, http.StatusBadRequest, "invalid_request")
+		return
+	}
+	if !s.saTokens[req.SubjectToken] {
+		writeOAuthError(w, http.StatusUnauthorized, "invalid_token")
+		return
+	}
+
+	token := "user-" + s.nextUUID()
+	s.userTokens[token] = req.UserEmail
+	writeJSON(w, http.StatusOK, tokenResponse{AccessToken: token, TokenType: "Bearer", ExpiresIn: tokenTTL})
+}
+
+// UserForToken returns the user an impersonated token was issued for
+func (s *Server) UserForToken(token string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	user, ok := s.userTokens[token]
+	return user, ok
+}
+
+// RevokeUserTokens invalidates every impersonated token issued so far, as if they had expired;
+// API requests using one are rejected with 401 until the client impersonates again
+func (s *Server) RevokeUserTokens() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.userTokens = make(map[string]string)
+}
+
+func writeOAuthError(w http.ResponseWriter, status int, code string) {
+	writeJSON(w, status, map[string]string{"error": code})
+}
@@ -0,0 +1,295 @@
+/*
+Copyright 2025 Kube-DC Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fake
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/cloudsigma/cloudsigma-sdk-go/cloudsigma"
+)
+
+// serverDriveBody is a server drive as clients send it; drive is a UUID or a drive object
+type serverDriveBody struct {
+	BootOrder  int             `json:"boot_order,omitempty"`
+	DevChannel string          `json:"dev_channel,omitempty"`
+	Device     string          `json:"device,omitempty"`
+	Drive      json.RawMessage `json:"drive"`
+}
+
+// serverNICBody is a server NIC as clients send it; vlan and ip are UUIDs or objects
+type serverNICBody struct {
+	VLAN     json.RawMessage `json:"vlan,omitempty"`
+	IPv4Conf *struct {
+		Conf string          `json:"conf"`
+		IP   json.RawMessage `json:"ip,omitempty"`
+	} `json:"ip_v4_conf,omitempty"`
+	MACAddress string `json:"mac,omitempty"`
+	Model      string `json:"model,omitempty"`
+}
+
+func (s *Server) handleServers(w http.ResponseWriter, r *http.Request, parts []string) {
+	switch {
+	case (len(parts) == 0 || parts[0] == "detail" && len(parts) == 1) && r.Method == http.MethodGet:
+		servers := make([]cloudsigma.Server, 0, len(s.servers))
+		for _, uuid := range sortedKeys(s.servers) {
+			servers = append(servers, deepCopy(*s.servers[uuid]))
+		}
+		writeJSON(w, http.StatusOK, page(r, servers))
+
+	case len(parts) == 0 && r.Method == http.MethodPost:
+		var req objectsRequest[map[string]json.RawMessage]
+		if err := decodeBody(r, &req); err != nil {
+			writeError(w, http.StatusBadRequest, "validation", err.Error())
+			return
+		}
+		created := make([]cloudsigma.Server, 0, len(req.Objects))
+		for _, raw := range req.Objects {
+			server, err := s.createServer(raw)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, "validation", err.Error())
+				return
+			}
+			created = append(created, deepCopy(*server))
+		}
+		writeJSON(w, http.StatusCreated, listResponse{Objects: created})
+
+	case len(parts) == 1:
+		server, ok := s.servers[parts[0]]
+		if !ok {
+			writeError(w, http.StatusNotFound, "notexist", "server "+parts[0]+" does not exist")
+			return
+		}
+		switch r.Method {
+		case http.MethodGet:
+			writeJSON(w, http.StatusOK, deepCopy(*server))
+		case http.MethodPut:
+			var raw map[string]json.RawMessage
+			if err := decodeBody(r, &raw); err != nil {
+				writeError(w, http.StatusBadRequest, "validation", err.Error())
+				return
+			}
+			if err := s.updateServer(server, raw); err != nil {
+				writeError(w, http.StatusBadRequest, "validation", err.Error())
+				return
+			}
+			writeJSON(w, http.StatusOK, deepCopy(*server))
+		case http.MethodDelete:
+			if server.Status != "stopped" {
+				writeError(w, http.StatusBadRequest, "permission", "server "+server.UUID+" must be stopped to be deleted")
+				return
+			}
+			for _, sd := range server.Drives {
+				s.unmount(server.UUID, sd.Drive.UUID)
+			}
+			delete(s.servers, server.UUID)
+			s.untag(server.UUID)
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+
+	case len(parts) == 2 && parts[1] == "action" && r.Method == http.MethodPost:
+		server, ok := s.servers[parts[0]]
+		if !ok {
+			writeError(w, http.StatusNotFound, "notexist", "server "+parts[0]+" does not exist")
+			return
+		}
+		action := r.URL.Query().Get("do")
+		switch action {
+		case "start":
+			if server.Status != "stopped" {
+				writeError(w, http.StatusBadRequest, "permission", "server "+server.UUID+" is "+server.Status)
+				return
+			}
+			server.Status = "running"
+		case "stop", "shutdown":
+			if server.Status != "running" {
+				writeError(w, http.StatusBadRequest, "permission", "server "+server.UUID+" is "+server.Status)
+				return
+			}
+			server.Status = "stopped"
+		default:
+			writeError(w, http.StatusBadRequest, "validation", "unsupported server action "+action)
+			return
+		}
+		writeJSON(w, http.StatusAccepted, cloudsigma.ServerAction{Action: action, Result: "success", UUID: server.UUID})
+
+	default:
+		writeError(w, http.StatusNotFound, "notexist", "unknown endpoint "+r.URL.Path)
+	}
+}
+
+// createServer creates a stopped server from a create request object
+func (s *Server) createServer(raw map[string]json.RawMessage) (*cloudsigma.Server, error) {
+	uuid := s.nextUUID()
+	server, err := s.applyServer(&cloudsigma.Server{UUID: uuid, ResourceURI: resourceURI("servers", uuid), Status: "stopped"}, raw)
+	if err != nil {
+		return nil, err
+	}
+	if server.Name == "" || server.CPU == 0 || server.Memory == 0 || server.VNCPassword == "" {
+		return nil, fmt.Errorf("name, cpu, mem and vnc_password are required")
+	}
+	s.commitServer(nil, &server)
+	s.servers[uuid] = &server
+	return &server, nil
+}
+
+// updateServer applies a PUT request to a stored server. Nothing changes if it is invalid.
+func (s *Server) updateServer(server *cloudsigma.Server, raw map[string]json.RawMessage) error {
+	updated, err := s.applyServer(server, raw)
+	if err != nil {
+		return err
+	}
+	s.commitServer(server.Drives, &updated)
+	*server = updated
+	return nil
+}
+
+// applyServer returns a copy of server with the fields present in raw applied. Like CloudSigma's
+// PUT, drives and NICs are replaced as a whole.
+func (s *Server) applyServer(server *cloudsigma.Server, raw map[string]json.RawMessage) (cloudsigma.Server, error) {
+	updated := deepCopy(*server)
+	fields := map[string]interface{}{
+		"name":                  &updated.Name,
+		"cpu":                   &updated.CPU,
+		"mem":                   &updated.Memory,
+		"smp":                   &updated.SMP,
+		"cpu_type":              &updated.CPUType,
+		"enable_numa":           &updated.EnableNuma,
+		"cpus_instead_of_cores": &updated.CPUsInsteadOfCores,
+		"vnc_password":          &updated.VNCPassword,
+	}
+	for key, value := range raw {
+		var err error
+		switch key {
+		case "drives":
+			updated.Drives, err = s.serverDrives(server.UUID, value)
+		case "nics":
+			updated.NICs, err = s.serverNICs(value)
+		case "meta":
+			updated.Meta = nil
+			err = json.Unmarshal(value, &updated.Meta)
+		default:
+			if field, ok := fields[key]; ok {
+				err = json.Unmarshal(value, field)
+			}
+		}
+		if err != nil {
+			return cloudsigma.Server{}, fmt.Errorf("invalid %s: %w", key, err)
+		}
+	}
+	return updated, nil
+}
+
+// commitServer unmounts the drives that left a server and mounts the ones it now has
+func (s *Server) commitServer(oldDrives []cloudsigma.ServerDrive, server *cloudsigma.Server) {
+	for _, sd := range oldDrives {
+		s.unmount(server.UUID, sd.Drive.UUID)
+	}
+	for _, sd := range server.Drives {
+		s.mount(server.UUID, sd.Drive.UUID)
+	}
+}
+
+// serverDrives resolves the drives of a server request against the stored drives
+func (s *Server) serverDrives(serverUUID string, raw json.RawMessage) ([]cloudsigma.ServerDrive, error) {
+	var body []serverDriveBody
+	if err := json.Unmarshal(raw, &body); err != nil {
+		return nil, err
+	}
+
+	drives := make([]cloudsigma.ServerDrive, 0, len(body))
+	channels := make(map[string]bool, len(body))
+	for _, sd := range body {
+		uuid := resourceRef(sd.Drive)
+		drive, ok := s.drives[uuid]
+		if !ok {
+			return nil, fmt.Errorf("drive %q does not exist", uuid)
+		}
+		if !drive.AllowMultimount {
+			for _, m := range drive.MountedOn {
+				if m.UUID != serverUUID {
+					return nil, fmt.Errorf("drive %s is mounted on server %s", uuid, m.UUID)
+				}
+			}
+		}
+		if sd.DevChannel != "" && channels[sd.DevChannel] {
+			return nil, fmt.Errorf("dev_channel %s is used twice", sd.DevChannel)
+		}
+		channels[sd.DevChannel] = true
+
+		device := sd.Device
+		if device == "" {
+			device = "virtio"
+		}
+		drives = append(drives, cloudsigma.ServerDrive{
+			BootOrder:  sd.BootOrder,
+			DevChannel: sd.DevChannel,
+			Device:     device,
+			Drive:      &cloudsigma.Drive{UUID: uuid, ResourceURI: resourceURI("drives", uuid)},
+		})
+	}
+	return drives, nil
+}
+
+// serverNICs converts the NICs of a server request, checking that static IPs exist
+func (s *Server) serverNICs(raw json.RawMessage) ([]cloudsigma.ServerNIC, error) {
+	var body []serverNICBody
+	if err := json.Unmarshal(raw, &body); err != nil {
+		return nil, err
+	}
+
+	nics := make([]cloudsigma.ServerNIC, 0, len(body))
+	for _, n := range body {
+		nic := cloudsigma.ServerNIC{MACAddress: n.MACAddress, Model: n.Model}
+		if nic.MACAddress == "" {
+			s.lastID++
+			nic.MACAddress = fmt.Sprintf("22:00:00:%02x:%02x:%02x", byte(s.lastID>>16), byte(s.lastID>>8), byte(s.lastID))
+		}
+		if nic.Model == "" {
+			nic.Model = "virtio"
+		}
+		if vlan := resourceRef(n.VLAN); vlan != "" {
+			nic.VLAN = &cloudsigma.VLAN{UUID: vlan, ResourceURI: resourceURI("vlans", vlan)}
+		}
+		if n.IPv4Conf != nil {
+			nic.IP4Configuration = &cloudsigma.ServerIPConfiguration{Type: n.IPv4Conf.Conf}
+			if ip := resourceRef(n.IPv4Conf.IP); ip != "" {
+				if _, ok := s.ips[ip]; !ok {
+					return nil, fmt.Errorf("IP %q does not exist", ip)
+				}
+				nic.IP4Configuration.IPAddress = &cloudsigma.IP{UUID: ip, ResourceURI: resourceURI("ips", ip)}
+			}
+		}
+		nics = append(nics, nic)
+	}
+	return nics, nil
+}
+
+// sortedKeys returns the keys of m in order; UUIDs are issued in increasing order, so this lists
+// resources in creation order
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,116 @@
+/*
+Copyright 2025 Kube-DC Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fake
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/cloudsigma/cloudsigma-sdk-go/cloudsigma"
+
+	"github.com/kube-dc/cluster-api-provider-cloudsigma/pkg/cloud"
+)
+
+// purchasedIPPrefix is where the IPs of purchased subscriptions come from (198.18.0.0/15 is
+// reserved for benchmarking, so it never clashes with IPs a test seeds)
+const purchasedIPPrefix = "198.18.0."
+
+// Subscriptions returns copies of the subscriptions purchased through the fake
+func (s *Server) Subscriptions() []cloudsigma.Subscription {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return deepCopy(s.subscriptions)
+}
+
+// handleSubscriptions lists and creates subscriptions and updates their auto_renew. Only IP
+// subscriptions of one IP can be created; each gets a new IP from purchasedIPPrefix as its
+// subscribed object.
+func (s *Server) handleSubscriptions(w http.ResponseWriter, r *http.Request, parts []string) {
+	if len(parts) == 1 {
+		s.handleSubscription(w, r, parts[0])
+		return
+	}
+	if len(parts) != 0 {
+		writeError(w, http.StatusNotFound, "notexist", "unknown endpoint "+r.URL.Path)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, page(r, s.subscriptions))
+
+	case http.MethodPost:
+		var req objectsRequest[cloudsigma.Subscription]
+		if err := decodeBody(r, &req); err != nil {
+			writeError(w, http.StatusBadRequest, "validation", err.Error())
+			return
+		}
+		created := make([]cloudsigma.Subscription, 0, len(req.Objects))
+		for _, sub := range req.Objects {
+			if sub.Resource != "ip" || sub.Amount != "1" || sub.Period == "" {
+				writeError(w, http.StatusBadRequest, "validation", "the fake only sells IP subscriptions of one IP")
+				return
+			}
+			id := len(s.subscriptions) + 1
+			ip := fmt.Sprintf("%s%d", purchasedIPPrefix, id)
+			s.ips[ip] = &cloud.IPDetail{UUID: ip, Subscription: &cloud.IPSubscription{ID: id}}
+
+			sub.ID = strconv.Itoa(id)
+			sub.Status = "active"
+			sub.SubscribedObject = ip
+			s.subscriptions = append(s.subscriptions, sub)
+			created = append(created, sub)
+		}
+		writeJSON(w, http.StatusCreated, listResponse{Objects: created})
+
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *Server) handleSubscription(w http.ResponseWriter, r *http.Request, id string) {
+	var sub *cloudsigma.Subscription
+	for i := range s.subscriptions {
+		if s.subscriptions[i].ID == id {
+			sub = &s.subscriptions[i]
+		}
+	}
+	if sub == nil {
+		writeError(w, http.StatusNotFound, "notexist", "subscription "+id+" does not exist")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, sub)
+	case http.MethodPut:
+		var update struct {
+			AutoRenew *bool `json:"auto_renew"`
+		}
+		if err := decodeBody(r, &update); err != nil {
+			writeError(w, http.StatusBadRequest, "validation", err.Error())
+			return
+		}
+		if update.AutoRenew != nil {
+			sub.AutoRenew = *update.AutoRenew
+		}
+		writeJSON(w, http.StatusOK, sub)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,161 @@
+/*
+Copyright 2025 Kube-DC Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fake
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/cloudsigma/cloudsigma-sdk-go/cloudsigma"
+)
+
+// tagBody is a tag as clients send it; resources are UUIDs or resource objects
+type tagBody struct {
+	Name      string                 `json:"name"`
+	Meta      map[string]interface{} `json:"meta,omitempty"`
+	Resources []json.RawMessage      `json:"resources,omitempty"`
+}
+
+func (s *Server) handleTags(w http.ResponseWriter, r *http.Request, parts []string) {
+	switch {
+	case (len(parts) == 0 || parts[0] == "detail" && len(parts) == 1) && r.Method == http.MethodGet:
+		name := r.URL.Query().Get("name")
+		prefix := r.URL.Query().Get("name__startswith")
+		tags := make([]cloudsigma.Tag, 0, len(s.tags))
+		for _, uuid := range sortedKeys(s.tags) {
+			tag := s.tags[uuid]
+			if name != "" && tag.Name != name || !strings.HasPrefix(tag.Name, prefix) {
+				continue
+			}
+			tags = append(tags, s.tagView(tag))
+		}
+		writeJSON(w, http.StatusOK, page(r, tags))
+
+	case len(parts) == 0 && r.Method == http.MethodPost:
+		var req objectsRequest[tagBody]
+		if err := decodeBody(r, &req); err != nil {
+			writeError(w, http.StatusBadRequest, "validation", err.Error())
+			return
+		}
+		for _, body := range req.Objects {
+			if body.Name == "" {
+				writeError(w, http.StatusBadRequest, "validation", "name is required")
+				return
+			}
+		}
+		created := make([]cloudsigma.Tag, 0, len(req.Objects))
+		for _, body := range req.Objects {
+			uuid := s.nextUUID()
+			tag := &cloudsigma.Tag{
+				UUID:        uuid,
+				ResourceURI: resourceURI("tags", uuid),
+				Name:        body.Name,
+				Meta:        body.Meta,
+				Resources:   s.tagResources(body.Resources),
+			}
+			s.tags[uuid] = tag
+			created = append(created, s.tagView(tag))
+		}
+		writeJSON(w, http.StatusCreated, listResponse{Objects: created})
+
+	case len(parts) == 1:
+		tag, ok := s.tags[parts[0]]
+		if !ok {
+			writeError(w, http.StatusNotFound, "notexist", "tag "+parts[0]+" does not exist")
+			return
+		}
+		switch r.Method {
+		case http.MethodGet:
+			writeJSON(w, http.StatusOK, s.tagView(tag))
+		case http.MethodPut:
+			data, err := io.ReadAll(r.Body)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, "validation", err.Error())
+				return
+			}
+			var raw map[string]json.RawMessage
+			var body tagBody
+			if err := json.Unmarshal(data, &raw); err != nil {
+				writeError(w, http.StatusBadRequest, "validation", err.Error())
+				return
+			}
+			if err := json.Unmarshal(data, &body); err != nil {
+				writeError(w, http.StatusBadRequest, "validation", err.Error())
+				return
+			}
+			if _, ok := raw["name"]; ok {
+				tag.Name = body.Name
+			}
+			if _, ok := raw["meta"]; ok {
+				tag.Meta = body.Meta
+			}
+			if _, ok := raw["resources"]; ok {
+				tag.Resources = s.tagResources(body.Resources)
+			}
+			writeJSON(w, http.StatusOK, s.tagView(tag))
+		case http.MethodDelete:
+			delete(s.tags, tag.UUID)
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+
+	default:
+		writeError(w, http.StatusNotFound, "notexist", "unknown endpoint "+r.URL.Path)
+	}
+}
+
+// tagResources resolves resource references, typing the ones the fake knows about
+func (s *Server) tagResources(refs []json.RawMessage) []cloudsigma.TagResource {
+	resources := make([]cloudsigma.TagResource, 0, len(refs))
+	for _, ref := range refs {
+		uuid := resourceRef(ref)
+		if uuid == "" {
+			continue
+		}
+		resource := cloudsigma.TagResource{UUID: uuid}
+		switch {
+		case s.servers[uuid] != nil:
+			resource.ResourceType = "servers"
+		case s.drives[uuid] != nil:
+			resource.ResourceType = "drives"
+		case s.ips[uuid] != nil:
+			resource.ResourceType = "ips"
+		}
+		if resource.ResourceType != "" {
+			resource.ResourceURI = resourceURI(resource.ResourceType, uuid)
+		}
+		resources = append(resources, resource)
+	}
+	return resources
+}
+
+// untag removes a deleted resource from every tag
+func (s *Server) untag(uuid string) {
+	for _, tag := range s.tags {
+		tag.Resources = slices.DeleteFunc(tag.Resources, func(r cloudsigma.TagResource) bool {
+			return r.UUID == uuid
+		})
+	}
+}
+
+func (s *Server) tagView(tag *cloudsigma.Tag) cloudsigma.Tag {
+	return deepCopy(*tag)
+}
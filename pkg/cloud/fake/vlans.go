@@ -0,0 +1,50 @@
+/*
+Copyright 2025 Kube-DC Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fake
+
+import (
+	"net/http"
+
+	"github.com/cloudsigma/cloudsigma-sdk-go/cloudsigma"
+)
+
+func (s *Server) handleVLANs(w http.ResponseWriter, r *http.Request, parts []string) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	switch {
+	case len(parts) == 0 || parts[0] == "detail" && len(parts) == 1:
+		vlans := make([]cloudsigma.VLAN, 0, len(s.vlans))
+		for _, uuid := range sortedKeys(s.vlans) {
+			vlans = append(vlans, deepCopy(*s.vlans[uuid]))
+		}
+		writeJSON(w, http.StatusOK, page(r, vlans))
+
+	case len(parts) == 1:
+		vlan, ok := s.vlans[parts[0]]
+		if !ok {
+			writeError(w, http.StatusNotFound, "notexist", "VLAN "+parts[0]+" does not exist")
+			return
+		}
+		writeJSON(w, http.StatusOK, deepCopy(*vlan))
+
+	default:
+		writeError(w, http.StatusNotFound, "notexist", "unknown endpoint "+r.URL.Path)
+	}
+}
@@ -0,0 +1,168 @@
+/*
+Copyright 2025 Kube-DC Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloud
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"k8s.io/klog/v2"
+
+	"github.com/kube-dc/cluster-api-provider-cloudsigma/pkg/auth"
+	"github.com/kube-dc/cluster-api-provider-cloudsigma/pkg/regions"
+)
+
+const (
+	// DefaultReachabilityTimeout bounds a single reachability probe
+	DefaultReachabilityTimeout = 5 * time.Second
+
+	// DefaultReachabilityCacheTTL is how long a probe result is reused before the API is called again
+	DefaultReachabilityCacheTTL = 30 * time.Second
+)
+
+// ReachabilityCheck reports whether the CloudSigma API (or OAuth endpoint)
+// can be reached. Results are cached for CacheTTL so frequent kubelet probes don't hammer the API.
+type ReachabilityCheck struct {
+	probe    func(ctx context.Context) error
+	timeout  time.Duration
+	cacheTTL time.Duration
+
+	mu        sync.Mutex
+	checkedAt time.Time
+	lastErr   error
+}
+
+// NewReachabilityCheck returns a check that runs probe with the given timeout and caches its result
+// for cacheTTL. Zero durations fall back to the defaults.
+func NewReachabilityCheck(probe func(ctx context.Context) error, timeout, cacheTTL time.Duration) *ReachabilityCheck {
+	if timeout == 0 {
+		timeout = DefaultReachabilityTimeout
+	}
+	if cacheTTL == 0 {
+		cacheTTL = DefaultReachabilityCacheTTL
+	}
+	return &ReachabilityCheck{
+		probe:    probe,
+		timeout:  timeout,
+		cacheTTL: cacheTTL,
+	}
+}
+
+// Check runs the probe (or returns the cached result) and matches the controller-runtime
+// healthz.Checker signature
+func (c *ReachabilityCheck) Check(req *http.Request) error {
+	ctx := context.Background()
+	if req != nil {
+		ctx = req.Context()
+	}
+	return c.check(ctx)
+}
+
+func (c *ReachabilityCheck) check(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.checkedAt.IsZero() && time.Since(c.checkedAt) < c.cacheTTL {
+		return c.lastErr
+	}
+
+	probeCtx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	err := c.probe(probeCtx)
+	if err != nil {
+		klog.Warningf("CloudSigma API reachability check failed: %v", err)
+		err = fmt.Errorf("CloudSigma API unreachable: %w", err)
+	}
+	c.checkedAt = time.Now()
+	c.lastErr = err
+	return err
+}
+
+// APIProbe returns a probe for NewReachabilityCheck. With impersonation configured it runs
+// VerifyImpersonation for userEmail in regionNames; otherwise it calls the API of the first region
+// with the legacy credentials.
+func APIProbe(impersonationClient *auth.ImpersonationClient, userEmail, username, password string, regionNames []string) (func(ctx context.Context) error, error) {
+	if impersonationClient != nil {
+		return func(ctx context.Context) error {
+			return VerifyImpersonation(ctx, impersonationClient, userEmail, regionNames)
+		}, nil
+	}
+
+	var region string
+	if len(regionNames) > 0 {
+		region = regionNames[0]
+	}
+	client, err := NewClient(username, password, region)
+	if err != nil {
+		return nil, err
+	}
+	return client.VerifyConnection, nil
+}
+
+// VerifyImpersonation checks that the impersonation setup works: that the OAuth endpoint issues a
+// service account and an RPT token and, when userEmail is set, that userEmail can be impersonated
+// in each of regionNames and the region's API accepts the token. Regions are checked in parallel;
+// the error names every region that failed.
+func VerifyImpersonation(ctx context.Context, impersonationClient *auth.ImpersonationClient, userEmail string, regionNames []string) error {
+	return verifyImpersonation(ctx, impersonationClient, userEmail, regionNames, func(region string) string {
+		return regions.Lookup(region).DirectAPIEndpoint()
+	})
+}
+
+// verifyImpersonation is VerifyImpersonation with the API endpoint of each region from apiEndpoint
+func verifyImpersonation(ctx context.Context, impersonationClient *auth.ImpersonationClient, userEmail string, regionNames []string, apiEndpoint func(region string) string) error {
+	if err := impersonationClient.VerifyServiceAccount(ctx); err != nil {
+		return err
+	}
+	if userEmail == "" {
+		return nil
+	}
+
+	errs := make([]error, len(regionNames))
+	var wg sync.WaitGroup
+	for i, region := range regionNames {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			client, err := NewClientWithImpersonation(ctx, impersonationClient, userEmail, region)
+			if err == nil {
+				client.apiEndpoint = apiEndpoint(region)
+				err = client.VerifyConnection(ctx)
+			}
+			if err != nil {
+				errs[i] = fmt.Errorf("region %s: %w", region, err)
+			}
+		}()
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// ServeHTTP lets the check be mounted directly on a plain http.ServeMux
+func (c *ReachabilityCheck) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if err := c.Check(r); err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("ok"))
+}
@@ -0,0 +1,180 @@
+/*
+Copyright 2025 Kube-DC Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloud
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/kube-dc/cluster-api-provider-cloudsigma/pkg/auth"
+)
+
+func TestReachabilityCheck(t *testing.T) {
+	tests := []struct {
+		name      string
+		status    int
+		wantReady bool
+	}{
+		{name: "api reachable", status: http.StatusOK, wantReady: true},
+		{name: "api unavailable", status: http.StatusServiceUnavailable, wantReady: false},
+		{name: "credentials rejected", status: http.StatusUnauthorized, wantReady: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			mux := http.NewServeMux()
+			mux.HandleFunc("/api/2.0/profile/", func(w http.ResponseWriter, r *http.Request) {
+				calls.Add(1)
+				if tt.status != http.StatusOK {
+					w.WriteHeader(tt.status)
+					return
+				}
+				writeJSON(w, map[string]interface{}{"uuid": "user-uuid", "email": "user@example.com"})
+			})
+
+			c := newTestClient(t, mux)
+			check := NewReachabilityCheck(c.VerifyConnection, time.Second, time.Minute)
+
+			for i := 0; i < 3; i++ {
+				rec := httptest.NewRecorder()
+				check.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+				wantCode := http.StatusOK
+				if !tt.wantReady {
+					wantCode = http.StatusServiceUnavailable
+				}
+				if rec.Code != wantCode {
+					t.Fatalf("probe %d: status = %d, want %d (body %q)", i, rec.Code, wantCode, rec.Body.String())
+				}
+			}
+
+			if got := calls.Load(); got != 1 {
+				t.Errorf("API called %d times, want 1 (result should be cached)", got)
+			}
+		})
+	}
+}
+
+func TestReachabilityCheckCacheExpiry(t *testing.T) {
+	var calls atomic.Int32
+	check := NewReachabilityCheck(func(ctx context.Context) error {
+		calls.Add(1)
+		return nil
+	}, time.Second, time.Nanosecond)
+
+	for i := 0; i < 2; i++ {
+		time.Sleep(time.Millisecond)
+		if err := check.Check(nil); err != nil {
+			t.Fatalf("Check() error = %v", err)
+		}
+	}
+	if got := calls.Load(); got != 2 {
+		t.Errorf("probe called %d times, want 2 after cache expiry", got)
+	}
+}
+
+func TestReachabilityCheckTimeout(t *testing.T) {
+	check := NewReachabilityCheck(func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}, 10*time.Millisecond, time.Minute)
+
+	if err := check.Check(nil); err == nil {
+		t.Fatal("expected a hanging probe to report not ready")
+	}
+}
+
+func TestVerifyImpersonation(t *testing.T) {
+	tests := []struct {
+		name         string
+		clientSecret string
+		userEmail    string
+		apiStatus    int
+		wantErr      string
+		wantAPICalls int32
+	}{
+		{name: "verified in every region", clientSecret: "secret", userEmail: "user@example.com", apiStatus: http.StatusOK, wantAPICalls: 2},
+		{name: "no user only checks OAuth", clientSecret: "secret", apiStatus: http.StatusOK},
+		{name: "client credentials rejected", clientSecret: "wrong", userEmail: "user@example.com", apiStatus: http.StatusOK, wantErr: "service account token"},
+		{name: "API rejects the token", clientSecret: "secret", userEmail: "user@example.com", apiStatus: http.StatusForbidden, wantErr: "region sjc", wantAPICalls: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var apiCalls atomic.Int32
+			mux := http.NewServeMux()
+			mux.HandleFunc("/realms/cloudsigma/protocol/openid-connect/token", func(w http.ResponseWriter, r *http.Request) {
+				_ = r.ParseForm()
+				switch {
+				case r.PostForm.Get("grant_type") == "client_credentials" && r.PostForm.Get("client_secret") == "secret":
+					writeJSON(w, map[string]interface{}{"access_token": "sa-token", "expires_in": 900})
+				case r.Header.Get("Authorization") == "Bearer sa-token":
+					writeJSON(w, map[string]interface{}{"access_token": "rpt-token", "expires_in": 900})
+				default:
+					w.WriteHeader(http.StatusUnauthorized)
+				}
+			})
+			mux.HandleFunc("/service_provider/api/v1/user/impersonate", func(w http.ResponseWriter, r *http.Request) {
+				writeJSON(w, map[string]interface{}{"access_token": "user-token", "expires_in": 900})
+			})
+			mux.HandleFunc("/api/2.0/ips/", func(w http.ResponseWriter, r *http.Request) {
+				apiCalls.Add(1)
+				if r.Header.Get("Authorization") != "Bearer user-token" || r.URL.Query().Get("limit") != "1" {
+					w.WriteHeader(http.StatusBadRequest)
+					return
+				}
+				if tt.apiStatus != http.StatusOK {
+					w.WriteHeader(tt.apiStatus)
+					return
+				}
+				writeJSON(w, map[string]interface{}{"meta": map[string]int{"total_count": 0}, "objects": []interface{}{}})
+			})
+			server := httptest.NewServer(mux)
+			defer server.Close()
+
+			target, _ := url.Parse(server.URL)
+			impersonationClient, err := auth.NewImpersonationClient(auth.ImpersonationConfig{
+				OAuthURL:     server.URL,
+				ClientID:     "client",
+				ClientSecret: tt.clientSecret,
+				HTTPClient:   &http.Client{Transport: &rewriteTransport{target: target}},
+			})
+			if err != nil {
+				t.Fatalf("NewImpersonationClient() error = %v", err)
+			}
+
+			err = verifyImpersonation(context.Background(), impersonationClient, tt.userEmail, []string{"zrh", "sjc"},
+				func(string) string { return server.URL + "/api/2.0" })
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("verifyImpersonation() error = %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("verifyImpersonation() error = %v, want one containing %q", err, tt.wantErr)
+			}
+			if got := apiCalls.Load(); got != tt.wantAPICalls {
+				t.Errorf("API called %d times, want %d", got, tt.wantAPICalls)
+			}
+		})
+	}
+}
@@ -18,14 +18,28 @@ package cloud
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"net/http"
+	"slices"
 
 	"github.com/cloudsigma/cloudsigma-sdk-go/cloudsigma"
 	"k8s.io/klog/v2"
 )
 
-// AllocatePublicIP allocates a new public IP address
-func (c *Client) AllocatePublicIP(ctx context.Context, name string) (*cloudsigma.IP, error) {
+// IPReservedForMetaKey is the IP meta key naming the owner an IP is reserved for. A free IP is
+// not held by anything until a server uses it, so allocations reserve it first.
+const IPReservedForMetaKey = "capcs-reserved-for"
+
+// IPReservedFor returns the owner an IP's meta reserves it for, or "" if it is not reserved
+func IPReservedFor(meta map[string]interface{}) string {
+	owner, _ := meta[IPReservedForMetaKey].(string)
+	return owner
+}
+
+// AllocatePublicIP allocates a new public IP address. Reserved IPs and IPs in exclude are
+// skipped, e.g. ones already picked for another NIC of the same server.
+func (c *Client) AllocatePublicIP(ctx context.Context, name string, exclude ...string) (*cloudsigma.IP, error) {
 	klog.V(2).Infof("Allocating public IP: %s", name)
 
 	// Allocate IP using list operation (CloudSigma auto-assigns from pool)
@@ -36,13 +50,13 @@ func (c *Client) AllocatePublicIP(ctx context.Context, name string) (*cloudsigma
 
 	// Find an unassigned IP
 	for _, availableIP := range ips {
-		if availableIP.Server == nil {
+		if availableIP.Server == nil && IPReservedFor(availableIP.Meta) == "" && !slices.Contains(exclude, availableIP.UUID) {
 			klog.V(2).Infof("Found available IP: %s (UUID: %s)", availableIP.Gateway, availableIP.UUID)
 			return &availableIP, nil
 		}
 	}
 
-	return nil, fmt.Errorf("no available public IPs in pool")
+	return nil, &IPPoolExhaustedError{Pool: "public"}
 }
 
 // GetIP retrieves an IP by UUID
@@ -61,12 +75,393 @@ func (c *Client) GetIP(ctx context.Context, uuid string) (*cloudsigma.IP, error)
 	return ip, nil
 }
 
-// DeleteIP releases a public IP address (returns it to pool)
+// DeleteIP releases a public IP the provider allocated back to the pool.
+// CloudSigma has no IP delete call: releasing means detaching the IP from any server NIC
+// and dropping our management tags. IPs bought as a subscription are never released,
+// and IPs still attached to a server we did not tag are left alone.
 func (c *Client) DeleteIP(ctx context.Context, uuid string) error {
 	klog.V(2).Infof("Releasing IP back to pool: %s", uuid)
 
-	// IPs from CloudSigma pool are automatically released when server is deleted
-	// No explicit delete action needed
-	klog.V(2).Infof("IP will be automatically released when server is deleted: %s", uuid)
+	ip, err := c.getIPDetail(ctx, uuid)
+	if err != nil {
+		return err
+	}
+	if ip == nil {
+		klog.V(2).Infof("IP not found, assuming already released: %s", uuid)
+		return nil
+	}
+
+	if ip.Subscription != nil {
+		klog.V(2).Infof("IP %s belongs to subscription %d, not releasing", uuid, ip.Subscription.ID)
+		return nil
+	}
+
+	if ip.Server != nil {
+		managed, err := c.isManagedResource(ctx, uuid)
+		if err != nil {
+			return err
+		}
+		if !managed {
+			klog.V(2).Infof("IP %s is attached to server %s and not managed by us, not releasing", uuid, ip.Server.UUID)
+			return nil
+		}
+
+		klog.V(2).Infof("Detaching IP %s from server %s", uuid, ip.Server.UUID)
+		if err := c.setNICIPv4Conf(ctx, ip.Server.UUID, nicByIP(uuid), map[string]interface{}{"conf": "dhcp"}); err != nil {
+			return fmt.Errorf("failed to detach IP %s: %w", uuid, err)
+		}
+	}
+
+	c.untagResource(ctx, uuid)
+
+	if IPReservedFor(ip.Meta) != "" {
+		if err := c.setIPReservation(ctx, ip, ""); err != nil {
+			return err
+		}
+	}
+
+	klog.V(2).Infof("IP released: %s", uuid)
+	return nil
+}
+
+// getIPDetail retrieves an IP including its subscription, returning nil if it does not exist
+func (c *Client) getIPDetail(ctx context.Context, uuid string) (*IPDetail, error) {
+	var ip IPDetail
+	if err := c.doDirectRequest(ctx, http.MethodGet, fmt.Sprintf("ips/%s/", uuid), nil, &ip); err != nil {
+		if StatusCodeFromError(err) == http.StatusNotFound {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get IP: %w", err)
+	}
+	return &ip, nil
+}
+
+// TagIP marks an IP as allocated by the provider so DeleteIP may release it later
+func (c *Client) TagIP(ctx context.Context, ipUUID, clusterName string) {
+	if c.sdk == nil {
+		klog.V(2).Info("CloudSigma SDK client not initialized, skipping IP tagging")
+		return
+	}
+
+	desiredTags := []string{ManagedByTag}
+	if clusterName != "" {
+		desiredTags = append(desiredTags, fmt.Sprintf("cluster:%s", clusterName))
+	}
+
+	for _, tagName := range desiredTags {
+		if err := c.ensureTagWithResource(ctx, tagName, ipUUID); err != nil {
+			klog.Warningf("Failed to tag IP %s with %s: %v", ipUUID, tagName, err)
+		}
+	}
+}
+
+// IPDetail is an IP as returned by the /ips/detail/ endpoint. Unlike the SDK IP type it
+// carries the subscription the IP belongs to, which is what scopes it to a VLAN.
+type IPDetail struct {
+	UUID         string                   `json:"uuid"`
+	Gateway      string                   `json:"gateway,omitempty"`
+	Netmask      int                      `json:"netmask,omitempty"`
+	Server       *cloudsigma.ResourceLink `json:"server,omitempty"`
+	Subscription *IPSubscription          `json:"subscription,omitempty"`
+	Meta         map[string]interface{}   `json:"meta,omitempty"`
+}
+
+// IPSubscription identifies the subscription an IP was purchased under
+type IPSubscription struct {
+	ID int `json:"id"`
+}
+
+// ListIPsDetail lists all IPs of the account including their subscription
+func (c *Client) ListIPsDetail(ctx context.Context) ([]IPDetail, error) {
+	var result struct {
+		Objects []IPDetail `json:"objects"`
+	}
+	if err := c.doDirectRequest(ctx, http.MethodGet, "ips/detail/?limit=0", nil, &result); err != nil {
+		return nil, fmt.Errorf("failed to list IPs: %w", err)
+	}
+	return result.Objects, nil
+}
+
+// AllocateVLANIP returns a free IP from the subscription backing the given VLAN. Reserved IPs and
+// IPs in exclude are skipped.
+func (c *Client) AllocateVLANIP(ctx context.Context, vlanUUID string, exclude ...string) (*IPDetail, error) {
+	klog.V(2).Infof("Allocating IP for VLAN: %s", vlanUUID)
+
+	vlan, err := c.GetVLAN(ctx, vlanUUID)
+	if err != nil {
+		return nil, err
+	}
+	if vlan == nil {
+		return nil, fmt.Errorf("VLAN %s not found", vlanUUID)
+	}
+	if vlan.Subscription == nil {
+		return nil, fmt.Errorf("VLAN %s has no subscription", vlanUUID)
+	}
+
+	ips, err := c.ListIPsDetail(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range ips {
+		ip := ips[i]
+		if ip.Server != nil || ip.Subscription == nil || ip.Subscription.ID != vlan.Subscription.ID ||
+			IPReservedFor(ip.Meta) != "" || slices.Contains(exclude, ip.UUID) {
+			continue
+		}
+		klog.V(2).Infof("Found available IP %s in VLAN %s", ip.UUID, vlanUUID)
+		return &ip, nil
+	}
+
+	return nil, &IPPoolExhaustedError{Pool: "vlan:" + vlanUUID}
+}
+
+// ReserveIP reserves a free IP for owner and reports whether it now holds the reservation.
+// It is false when the IP is gone, attached to a server or reserved for another owner.
+// CloudSigma has no conditional update, so the reservation is read back to catch an owner
+// that wrote theirs at the same time.
+func (c *Client) ReserveIP(ctx context.Context, uuid, owner string) (bool, error) {
+	ip, err := c.getIPDetail(ctx, uuid)
+	if err != nil {
+		return false, err
+	}
+	if ip == nil || ip.Server != nil {
+		return false, nil
+	}
+	switch IPReservedFor(ip.Meta) {
+	case owner:
+		return true, nil
+	case "":
+	default:
+		return false, nil
+	}
+
+	if err := c.setIPReservation(ctx, ip, owner); err != nil {
+		return false, err
+	}
+	return c.IsIPReservedFor(ctx, uuid, owner)
+}
+
+// IsIPReservedFor reports whether an IP exists and is reserved for owner
+func (c *Client) IsIPReservedFor(ctx context.Context, uuid, owner string) (bool, error) {
+	ip, err := c.getIPDetail(ctx, uuid)
+	if err != nil {
+		return false, err
+	}
+	return ip != nil && IPReservedFor(ip.Meta) == owner, nil
+}
+
+// setIPReservation saves the IP's meta with the reservation set to owner, or cleared if owner is ""
+func (c *Client) setIPReservation(ctx context.Context, ip *IPDetail, owner string) error {
+	meta := make(map[string]interface{}, len(ip.Meta)+1)
+	for k, v := range ip.Meta {
+		meta[k] = v
+	}
+	if owner == "" {
+		delete(meta, IPReservedForMetaKey)
+	} else {
+		meta[IPReservedForMetaKey] = owner
+	}
+
+	body := map[string]interface{}{"meta": meta}
+	if err := c.doDirectRequest(ctx, http.MethodPut, fmt.Sprintf("ips/%s/", ip.UUID), body, nil); err != nil {
+		return fmt.Errorf("failed to update reservation of IP %s: %w", ip.UUID, err)
+	}
+	return nil
+}
+
+// AttachIPToNIC configures the NIC with the given MAC as static with ipUUID.
+// If mac is empty the first NIC with an IPv4 configuration is used.
+func (c *Client) AttachIPToNIC(ctx context.Context, serverUUID, mac, ipUUID string) error {
+	klog.V(2).Infof("Attaching IP %s to server %s (mac %q)", ipUUID, serverUUID, mac)
+	return c.setNICIPv4Conf(ctx, serverUUID, nicByMAC(mac), map[string]interface{}{
+		"conf": "static",
+		"ip":   map[string]interface{}{"uuid": ipUUID},
+	})
+}
+
+// DetachIPFromNIC switches the NIC with the given MAC back to DHCP.
+// If mac is empty the first NIC with an IPv4 configuration is used.
+func (c *Client) DetachIPFromNIC(ctx context.Context, serverUUID, mac string) error {
+	klog.V(2).Infof("Detaching IP from server %s (mac %q)", serverUUID, mac)
+	return c.setNICIPv4Conf(ctx, serverUUID, nicByMAC(mac), map[string]interface{}{
+		"conf": "dhcp",
+	})
+}
+
+// nicByMAC matches the NIC with the given MAC, or the first NIC with an IPv4 configuration if mac is empty
+func nicByMAC(mac string) func(nic map[string]interface{}) bool {
+	return func(nic map[string]interface{}) bool {
+		if mac == "" {
+			return nic["ip_v4_conf"] != nil
+		}
+		nicMAC, _ := nic["mac"].(string)
+		return nicMAC == mac
+	}
+}
+
+// nicByIP matches the NIC statically configured with the given IP UUID
+func nicByIP(ipUUID string) func(nic map[string]interface{}) bool {
+	return func(nic map[string]interface{}) bool {
+		conf, _ := nic["ip_v4_conf"].(map[string]interface{})
+		ip, _ := conf["ip"].(map[string]interface{})
+		return ip != nil && ip["uuid"] == ipUUID
+	}
+}
+
+// nicConfBeforeManualMetaKey is the server meta key SetNICManualMode saves the NIC's previous
+// IPv4 configuration under, so RevertNICManualMode can restore it
+const nicConfBeforeManualMetaKey = "ccm_nic_conf_before_manual"
+
+// nicConfBeforeManual is the value saved under nicConfBeforeManualMetaKey
+type nicConfBeforeManual struct {
+	MAC      string                 `json:"mac"`
+	IPv4Conf map[string]interface{} `json:"ip_v4_conf"`
+}
+
+// SetNICManualMode switches the server's first NIC with an IPv4 configuration to "manual" mode,
+// in which the CloudSigma firewall passes traffic for every IP the account owns with a
+// subscription, so those IPs can be configured on the server without attaching them to the NIC.
+// The NIC's previous configuration is saved in the server meta for RevertNICManualMode.
+// It reports whether the NIC was switched: false if a NIC already is in manual mode.
+func (c *Client) SetNICManualMode(ctx context.Context, serverUUID string) (bool, error) {
+	server, err := c.getServerForUpdate(ctx, serverUUID)
+	if err != nil {
+		return false, err
+	}
+
+	var previous *nicConfBeforeManual
+	nics, _ := server["nics"].([]interface{})
+	for _, n := range nics {
+		nic, _ := n.(map[string]interface{})
+		conf, _ := nic["ip_v4_conf"].(map[string]interface{})
+		if conf != nil && conf["conf"] == "manual" {
+			return false, nil
+		}
+		if conf != nil && previous == nil {
+			mac, _ := nic["mac"].(string)
+			previous = &nicConfBeforeManual{MAC: mac, IPv4Conf: restorableIPv4Conf(conf)}
+		}
+	}
+
+	if previous == nil {
+		return false, fmt.Errorf("no matching NIC found on server %s", serverUUID)
+	}
+
+	saved, err := json.Marshal(previous)
+	if err != nil {
+		return false, err
+	}
+	meta, _ := server["meta"].(map[string]interface{})
+	if meta == nil {
+		meta = map[string]interface{}{}
+	}
+	meta[nicConfBeforeManualMetaKey] = string(saved)
+	server["meta"] = meta
+
+	if err := c.putServerNICConf(ctx, serverUUID, server, nicByMAC(previous.MAC), map[string]interface{}{"conf": "manual"}); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// RevertNICManualMode restores the IPv4 configuration SetNICManualMode saved for the server's
+// NIC and drops the saved copy. A NIC that is no longer in manual mode is left as it is. It
+// reports whether a configuration was restored: false if the server has none saved, e.g.
+// because its NIC was already in manual mode when SetNICManualMode ran.
+func (c *Client) RevertNICManualMode(ctx context.Context, serverUUID string) (bool, error) {
+	server, err := c.getServerForUpdate(ctx, serverUUID)
+	if err != nil {
+		return false, err
+	}
+
+	meta, _ := server["meta"].(map[string]interface{})
+	saved, _ := meta[nicConfBeforeManualMetaKey].(string)
+	if saved == "" {
+		return false, nil
+	}
+	var previous nicConfBeforeManual
+	if err := json.Unmarshal([]byte(saved), &previous); err != nil {
+		return false, fmt.Errorf("invalid saved NIC configuration on server %s: %w", serverUUID, err)
+	}
+	delete(meta, nicConfBeforeManualMetaKey)
+
+	restored := false
+	nics, _ := server["nics"].([]interface{})
+	for _, n := range nics {
+		nic, _ := n.(map[string]interface{})
+		conf, _ := nic["ip_v4_conf"].(map[string]interface{})
+		if conf == nil || conf["conf"] != "manual" || !nicByMAC(previous.MAC)(nic) {
+			continue
+		}
+		nic["ip_v4_conf"] = previous.IPv4Conf
+		delete(nic, "runtime")
+		restored = true
+		break
+	}
+	if err := c.putServer(ctx, serverUUID, server); err != nil {
+		return false, err
+	}
+	return restored, nil
+}
+
+// restorableIPv4Conf returns the parts of a NIC's ip_v4_conf the API accepts back on update: the
+// mode, and for static configurations the IP by UUID
+func restorableIPv4Conf(conf map[string]interface{}) map[string]interface{} {
+	restorable := map[string]interface{}{"conf": conf["conf"]}
+	if ip, ok := conf["ip"].(map[string]interface{}); ok && ip["uuid"] != nil {
+		restorable["ip"] = map[string]interface{}{"uuid": ip["uuid"]}
+	}
+	return restorable
+}
+
+// setNICIPv4Conf replaces ip_v4_conf of the first NIC accepted by match, keeping every other NIC as is
+func (c *Client) setNICIPv4Conf(ctx context.Context, serverUUID string, match func(nic map[string]interface{}) bool, conf map[string]interface{}) error {
+	server, err := c.getServerForUpdate(ctx, serverUUID)
+	if err != nil {
+		return err
+	}
+	return c.putServerNICConf(ctx, serverUUID, server, match, conf)
+}
+
+// getServerForUpdate gets a server as a raw map, so an update sends back every field unchanged
+func (c *Client) getServerForUpdate(ctx context.Context, serverUUID string) (map[string]interface{}, error) {
+	var server map[string]interface{}
+	if err := c.doDirectRequest(ctx, http.MethodGet, fmt.Sprintf("servers/%s/", serverUUID), nil, &server); err != nil {
+		return nil, fmt.Errorf("failed to get server: %w", err)
+	}
+	return server, nil
+}
+
+// putServerNICConf sets ip_v4_conf of the first NIC of server accepted by match and saves the server
+func (c *Client) putServerNICConf(ctx context.Context, serverUUID string, server map[string]interface{}, match func(nic map[string]interface{}) bool, conf map[string]interface{}) error {
+	nics, _ := server["nics"].([]interface{})
+	found := false
+	for _, n := range nics {
+		nic, ok := n.(map[string]interface{})
+		if !ok || !match(nic) {
+			continue
+		}
+		nic["ip_v4_conf"] = conf
+		delete(nic, "runtime")
+		found = true
+		break
+	}
+	if !found {
+		return fmt.Errorf("no matching NIC found on server %s", serverUUID)
+	}
+	return c.putServer(ctx, serverUUID, server)
+}
+
+// putServer saves a server got with getServerForUpdate
+func (c *Client) putServer(ctx context.Context, serverUUID string, server map[string]interface{}) error {
+	// Strip read-only fields the API rejects on update
+	for _, field := range []string{"resource_uri", "runtime", "status", "uuid", "owner", "permissions", "mounted_on", "grantees"} {
+		delete(server, field)
+	}
+
+	if err := c.doDirectRequest(ctx, http.MethodPut, fmt.Sprintf("servers/%s/", serverUUID), server, nil); err != nil {
+		return fmt.Errorf("failed to update server: %w", err)
+	}
 	return nil
 }
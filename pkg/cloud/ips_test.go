@@ -0,0 +1,523 @@
+/*
+Copyright 2025 Kube-DC Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloud
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/cloudsigma/cloudsigma-sdk-go/cloudsigma"
+)
+
+// rewriteTransport redirects SDK requests to a local test server
+type rewriteTransport struct {
+	target *url.URL
+}
+
+func (t *rewriteTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req.URL.Scheme = t.target.Scheme
+	req.URL.Host = t.target.Host
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+// newTestClient returns a Client whose SDK and direct API calls go to handler
+func newTestClient(t *testing.T, handler http.Handler) *Client {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	target, _ := url.Parse(server.URL)
+	sdk := cloudsigma.NewClient(
+		cloudsigma.NewUsernamePasswordCredentialsProvider("user", "pass"),
+		cloudsigma.WithHTTPClient(&http.Client{Transport: &rewriteTransport{target: target}}),
+	)
+	return &Client{
+		sdk:         sdk,
+		region:      "zrh",
+		username:    "user",
+		password:    "pass",
+		apiEndpoint: server.URL + "/api/2.0",
+	}
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func TestAllocateVLANIP(t *testing.T) {
+	const vlanUUID = "vlan-1"
+
+	tests := []struct {
+		name          string
+		ips           []IPDetail
+		exclude       []string
+		wantUUID      string
+		wantExhausted bool
+	}{
+		{
+			name: "free IP in VLAN subscription",
+			ips: []IPDetail{
+				{UUID: "ip-other", Subscription: &IPSubscription{ID: 7}},
+				{UUID: "ip-used", Subscription: &IPSubscription{ID: 42}, Server: &cloudsigma.ResourceLink{UUID: "srv"}},
+				{UUID: "ip-free", Subscription: &IPSubscription{ID: 42}},
+			},
+			wantUUID: "ip-free",
+		},
+		{
+			name: "excluded IP skipped",
+			ips: []IPDetail{
+				{UUID: "ip-picked", Subscription: &IPSubscription{ID: 42}},
+				{UUID: "ip-free", Subscription: &IPSubscription{ID: 42}},
+			},
+			exclude:  []string{"ip-picked"},
+			wantUUID: "ip-free",
+		},
+		{
+			name: "reserved IP skipped",
+			ips: []IPDetail{
+				{UUID: "ip-reserved", Subscription: &IPSubscription{ID: 42}, Meta: map[string]interface{}{IPReservedForMetaKey: "machine-1"}},
+				{UUID: "ip-free", Subscription: &IPSubscription{ID: 42}},
+			},
+			wantUUID: "ip-free",
+		},
+		{
+			name: "all VLAN IPs in use",
+			ips: []IPDetail{
+				{UUID: "ip-other", Subscription: &IPSubscription{ID: 7}},
+				{UUID: "ip-used", Subscription: &IPSubscription{ID: 42}, Server: &cloudsigma.ResourceLink{UUID: "srv"}},
+			},
+			wantExhausted: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mux := http.NewServeMux()
+			mux.HandleFunc("/api/2.0/vlans/"+vlanUUID+"/", func(w http.ResponseWriter, r *http.Request) {
+				writeJSON(w, map[string]interface{}{
+					"uuid":         vlanUUID,
+					"subscription": map[string]interface{}{"id": 42},
+				})
+			})
+			mux.HandleFunc("/api/2.0/ips/detail/", func(w http.ResponseWriter, r *http.Request) {
+				writeJSON(w, map[string]interface{}{"objects": tt.ips})
+			})
+
+			c := newTestClient(t, mux)
+			ip, err := c.AllocateVLANIP(context.Background(), vlanUUID, tt.exclude...)
+
+			if tt.wantExhausted {
+				if !IsIPPoolExhaustedError(err) {
+					t.Fatalf("AllocateVLANIP() error = %v, want IPPoolExhaustedError", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("AllocateVLANIP() error = %v", err)
+			}
+			if ip.UUID != tt.wantUUID {
+				t.Errorf("AllocateVLANIP() = %s, want %s", ip.UUID, tt.wantUUID)
+			}
+		})
+	}
+}
+
+func TestAttachAndDetachIPOnNIC(t *testing.T) {
+	const serverUUID = "srv-1"
+
+	var put map[string]interface{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/2.0/servers/"+serverUUID+"/", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			writeJSON(w, map[string]interface{}{
+				"uuid":         serverUUID,
+				"name":         "node",
+				"status":       "running",
+				"resource_uri": "/api/2.0/servers/" + serverUUID + "/",
+				"nics": []interface{}{
+					map[string]interface{}{"mac": "aa:aa", "vlan": map[string]interface{}{"uuid": "vlan-1"}},
+					map[string]interface{}{"mac": "bb:bb", "ip_v4_conf": map[string]interface{}{"conf": "dhcp"}},
+				},
+			})
+		case http.MethodPut:
+			put = nil
+			if err := json.NewDecoder(r.Body).Decode(&put); err != nil {
+				t.Errorf("failed to decode PUT body: %v", err)
+			}
+			writeJSON(w, put)
+		}
+	})
+
+	c := newTestClient(t, mux)
+
+	nicConf := func(i int) map[string]interface{} {
+		t.Helper()
+		nics := put["nics"].([]interface{})
+		conf, _ := nics[i].(map[string]interface{})["ip_v4_conf"].(map[string]interface{})
+		return conf
+	}
+
+	if err := c.AttachIPToNIC(context.Background(), serverUUID, "", "ip-1"); err != nil {
+		t.Fatalf("AttachIPToNIC() error = %v", err)
+	}
+	for _, field := range []string{"uuid", "status", "resource_uri"} {
+		if _, ok := put[field]; ok {
+			t.Errorf("read-only field %q sent in PUT", field)
+		}
+	}
+	if conf := nicConf(1); conf["conf"] != "static" || conf["ip"].(map[string]interface{})["uuid"] != "ip-1" {
+		t.Errorf("unexpected ip_v4_conf after attach: %v", conf)
+	}
+	if _, ok := put["nics"].([]interface{})[0].(map[string]interface{})["vlan"]; !ok {
+		t.Errorf("VLAN NIC not preserved")
+	}
+
+	if err := c.DetachIPFromNIC(context.Background(), serverUUID, "bb:bb"); err != nil {
+		t.Fatalf("DetachIPFromNIC() error = %v", err)
+	}
+	if conf := nicConf(1); conf["conf"] != "dhcp" {
+		t.Errorf("unexpected ip_v4_conf after detach: %v", conf)
+	}
+
+	if err := c.AttachIPToNIC(context.Background(), serverUUID, "cc:cc", "ip-1"); err == nil {
+		t.Errorf("AttachIPToNIC() with unknown MAC should fail")
+	}
+}
+
+func TestDeleteIP(t *testing.T) {
+	const (
+		ipUUID     = "ip-1"
+		serverUUID = "srv-1"
+	)
+
+	tests := []struct {
+		name          string
+		ip            IPDetail
+		managed       bool
+		wantDetach    bool
+		wantUntag     bool
+		wantUnreserve bool
+	}{
+		{
+			name:       "managed pool IP attached to a server is released",
+			ip:         IPDetail{UUID: ipUUID, Server: &cloudsigma.ResourceLink{UUID: serverUUID}},
+			managed:    true,
+			wantDetach: true,
+			wantUntag:  true,
+		},
+		{
+			name:      "free pool IP is untagged",
+			ip:        IPDetail{UUID: ipUUID},
+			managed:   true,
+			wantUntag: true,
+		},
+		{
+			name:          "reserved free pool IP loses its reservation",
+			ip:            IPDetail{UUID: ipUUID, Meta: map[string]interface{}{IPReservedForMetaKey: "machine-1", "note": "kept"}},
+			managed:       true,
+			wantUntag:     true,
+			wantUnreserve: true,
+		},
+		{
+			name:    "subscription IP is left untouched",
+			ip:      IPDetail{UUID: ipUUID, Server: &cloudsigma.ResourceLink{UUID: serverUUID}, Subscription: &IPSubscription{ID: 42}},
+			managed: true,
+		},
+		{
+			name: "unmanaged IP attached to a server is left untouched",
+			ip:   IPDetail{UUID: ipUUID, Server: &cloudsigma.ResourceLink{UUID: serverUUID}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			detached := false
+			untagged := false
+			unreserved := false
+
+			mux := http.NewServeMux()
+			mux.HandleFunc("/api/2.0/ips/"+ipUUID+"/", func(w http.ResponseWriter, r *http.Request) {
+				if r.Method == http.MethodPut {
+					var body IPDetail
+					_ = json.NewDecoder(r.Body).Decode(&body)
+					unreserved = IPReservedFor(body.Meta) == "" && body.Meta["note"] == "kept"
+				}
+				writeJSON(w, tt.ip)
+			})
+			mux.HandleFunc("/api/2.0/servers/"+serverUUID+"/", func(w http.ResponseWriter, r *http.Request) {
+				switch r.Method {
+				case http.MethodGet:
+					writeJSON(w, map[string]interface{}{
+						"uuid": serverUUID,
+						"nics": []interface{}{
+							map[string]interface{}{"mac": "aa:aa", "ip_v4_conf": map[string]interface{}{
+								"conf": "static",
+								"ip":   map[string]interface{}{"uuid": ipUUID},
+							}},
+						},
+					})
+				case http.MethodPut:
+					var body map[string]interface{}
+					_ = json.NewDecoder(r.Body).Decode(&body)
+					conf := body["nics"].([]interface{})[0].(map[string]interface{})["ip_v4_conf"].(map[string]interface{})
+					detached = conf["conf"] == "dhcp"
+					writeJSON(w, body)
+				}
+			})
+			mux.HandleFunc("/api/2.0/tags/", func(w http.ResponseWriter, r *http.Request) {
+				resources := []cloudsigma.TagResource{}
+				if tt.managed {
+					resources = append(resources, cloudsigma.TagResource{UUID: ipUUID})
+				}
+				writeJSON(w, map[string]interface{}{"objects": []cloudsigma.Tag{
+					{UUID: "tag-1", Name: "managed-by:cloudsigma-capcs", Resources: resources},
+				}})
+			})
+			mux.HandleFunc("/api/2.0/tags/tag-1/", func(w http.ResponseWriter, r *http.Request) {
+				if r.Method == http.MethodPut {
+					untagged = true
+				}
+				writeJSON(w, cloudsigma.Tag{UUID: "tag-1"})
+			})
+
+			c := newTestClient(t, mux)
+			if err := c.DeleteIP(context.Background(), ipUUID); err != nil {
+				t.Fatalf("DeleteIP() error = %v", err)
+			}
+			if detached != tt.wantDetach {
+				t.Errorf("detached = %v, want %v", detached, tt.wantDetach)
+			}
+			if untagged != tt.wantUntag {
+				t.Errorf("untagged = %v, want %v", untagged, tt.wantUntag)
+			}
+			if unreserved != tt.wantUnreserve {
+				t.Errorf("unreserved = %v, want %v", unreserved, tt.wantUnreserve)
+			}
+		})
+	}
+}
+
+func TestReserveIP(t *testing.T) {
+	const ipUUID = "ip-1"
+
+	tests := []struct {
+		name string
+		ip   IPDetail
+		// racer, if set, overwrites the reservation right after ours is saved
+		racer    string
+		wantPut  bool
+		wantHeld bool
+	}{
+		{
+			name:     "free IP is reserved",
+			ip:       IPDetail{UUID: ipUUID},
+			wantPut:  true,
+			wantHeld: true,
+		},
+		{
+			name:     "IP already reserved for the owner",
+			ip:       IPDetail{UUID: ipUUID, Meta: map[string]interface{}{IPReservedForMetaKey: "machine-1"}},
+			wantHeld: true,
+		},
+		{
+			name: "IP reserved for another owner",
+			ip:   IPDetail{UUID: ipUUID, Meta: map[string]interface{}{IPReservedForMetaKey: "machine-2"}},
+		},
+		{
+			name: "IP attached to a server",
+			ip:   IPDetail{UUID: ipUUID, Server: &cloudsigma.ResourceLink{UUID: "srv-1"}},
+		},
+		{
+			name:    "reservation overwritten by a concurrent owner",
+			ip:      IPDetail{UUID: ipUUID},
+			racer:   "machine-2",
+			wantPut: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ip := tt.ip
+			put := false
+			mux := http.NewServeMux()
+			mux.HandleFunc("/api/2.0/ips/"+ipUUID+"/", func(w http.ResponseWriter, r *http.Request) {
+				if r.Method == http.MethodPut {
+					put = true
+					var body IPDetail
+					_ = json.NewDecoder(r.Body).Decode(&body)
+					ip.Meta = body.Meta
+					if tt.racer != "" {
+						ip.Meta = map[string]interface{}{IPReservedForMetaKey: tt.racer}
+					}
+				}
+				writeJSON(w, ip)
+			})
+
+			c := newTestClient(t, mux)
+			held, err := c.ReserveIP(context.Background(), ipUUID, "machine-1")
+			if err != nil {
+				t.Fatalf("ReserveIP() error = %v", err)
+			}
+			if held != tt.wantHeld {
+				t.Errorf("ReserveIP() = %v, want %v", held, tt.wantHeld)
+			}
+			if put != tt.wantPut {
+				t.Errorf("reservation saved = %v, want %v", put, tt.wantPut)
+			}
+		})
+	}
+}
+
+func TestSetNICManualMode(t *testing.T) {
+	const serverUUID = "srv-1"
+
+	tests := []struct {
+		name         string
+		conf         string
+		wantSwitched bool
+	}{
+		{name: "dhcp NIC is switched", conf: "dhcp", wantSwitched: true},
+		{name: "manual NIC is left alone", conf: "manual"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var put map[string]interface{}
+			mux := http.NewServeMux()
+			mux.HandleFunc("/api/2.0/servers/"+serverUUID+"/", func(w http.ResponseWriter, r *http.Request) {
+				switch r.Method {
+				case http.MethodGet:
+					writeJSON(w, map[string]interface{}{
+						"uuid": serverUUID,
+						"nics": []interface{}{
+							map[string]interface{}{"mac": "aa:aa", "vlan": map[string]interface{}{"uuid": "vlan-1"}},
+							map[string]interface{}{"mac": "bb:bb", "ip_v4_conf": map[string]interface{}{
+								"conf": tt.conf,
+								"ip":   map[string]interface{}{"uuid": "10.0.0.1"},
+							}},
+						},
+					})
+				case http.MethodPut:
+					_ = json.NewDecoder(r.Body).Decode(&put)
+					writeJSON(w, put)
+				}
+			})
+			c := newTestClient(t, mux)
+
+			switched, err := c.SetNICManualMode(context.Background(), serverUUID)
+			if err != nil {
+				t.Fatalf("SetNICManualMode() error = %v", err)
+			}
+			if switched != tt.wantSwitched {
+				t.Errorf("SetNICManualMode() = %v, want %v", switched, tt.wantSwitched)
+			}
+			if !tt.wantSwitched {
+				if put != nil {
+					t.Errorf("server updated although a NIC is already manual: %v", put)
+				}
+				return
+			}
+			conf := put["nics"].([]interface{})[1].(map[string]interface{})["ip_v4_conf"].(map[string]interface{})
+			if conf["conf"] != "manual" || conf["ip"] != nil {
+				t.Errorf("ip_v4_conf after switch = %v, want manual without an IP", conf)
+			}
+		})
+	}
+}
+
+func TestNICManualMode_RoundTrip(t *testing.T) {
+	const serverUUID = "srv-1"
+
+	tests := []struct {
+		name string
+		conf map[string]interface{}
+	}{
+		{name: "dhcp", conf: map[string]interface{}{"conf": "dhcp"}},
+		{name: "static", conf: map[string]interface{}{
+			"conf": "static",
+			"ip":   map[string]interface{}{"uuid": "10.0.0.1", "resource_uri": "/api/2.0/ips/10.0.0.1/"},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The server is stored as saved, so each call sees the previous one's update
+			server := map[string]interface{}{
+				"uuid": serverUUID,
+				"meta": map[string]interface{}{"owner": "team-a"},
+				"nics": []interface{}{
+					map[string]interface{}{"mac": "aa:aa", "vlan": map[string]interface{}{"uuid": "vlan-1"}},
+					map[string]interface{}{"mac": "bb:bb", "ip_v4_conf": tt.conf},
+				},
+			}
+			puts := 0
+			mux := http.NewServeMux()
+			mux.HandleFunc("/api/2.0/servers/"+serverUUID+"/", func(w http.ResponseWriter, r *http.Request) {
+				switch r.Method {
+				case http.MethodGet:
+					writeJSON(w, server)
+				case http.MethodPut:
+					puts++
+					server = nil
+					_ = json.NewDecoder(r.Body).Decode(&server)
+					writeJSON(w, server)
+				}
+			})
+			c := newTestClient(t, mux)
+			ctx := context.Background()
+			nicConf := func() map[string]interface{} {
+				return server["nics"].([]interface{})[1].(map[string]interface{})["ip_v4_conf"].(map[string]interface{})
+			}
+
+			if switched, err := c.SetNICManualMode(ctx, serverUUID); err != nil || !switched {
+				t.Fatalf("SetNICManualMode() = %v, %v; want switched", switched, err)
+			}
+			if conf := nicConf(); conf["conf"] != "manual" {
+				t.Fatalf("ip_v4_conf after switch = %v, want manual", conf)
+			}
+
+			reverted, err := c.RevertNICManualMode(ctx, serverUUID)
+			if err != nil || !reverted {
+				t.Fatalf("RevertNICManualMode() = %v, %v; want reverted", reverted, err)
+			}
+			want := map[string]interface{}{"conf": tt.conf["conf"]}
+			if ip, ok := tt.conf["ip"].(map[string]interface{}); ok {
+				want["ip"] = map[string]interface{}{"uuid": ip["uuid"]}
+			}
+			if conf := nicConf(); !reflect.DeepEqual(conf, want) {
+				t.Errorf("ip_v4_conf after revert = %v, want %v", conf, want)
+			}
+			if meta := server["meta"]; !reflect.DeepEqual(meta, map[string]interface{}{"owner": "team-a"}) {
+				t.Errorf("meta after revert = %v, want the saved configuration dropped and other keys kept", meta)
+			}
+
+			// Nothing is saved any more, so a second revert leaves the server alone
+			puts = 0
+			if reverted, err := c.RevertNICManualMode(ctx, serverUUID); err != nil || reverted || puts != 0 {
+				t.Errorf("second RevertNICManualMode() = %v, %v with %d updates; want no-op", reverted, err, puts)
+			}
+		})
+	}
+}
@@ -0,0 +1,87 @@
+/*
+Copyright 2025 Kube-DC Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloud
+
+import "strings"
+
+// NodeMetaPrefix prefixes the server meta keys through which the management cluster hands the
+// labels and annotations of a Machine to the CCM of the workload cluster, which applies them to
+// the Machine's node
+const NodeMetaPrefix = "node-meta."
+
+const (
+	nodeLabelMetaPrefix      = NodeMetaPrefix + "label."
+	nodeAnnotationMetaPrefix = NodeMetaPrefix + "annotation."
+)
+
+// nodeMetadataDomains are the label and annotation domains, subdomains included, that CAPI
+// propagates from a Machine to its Node
+var nodeMetadataDomains = []string{
+	"node-role.kubernetes.io",
+	"node-restriction.kubernetes.io",
+	"node.cluster.x-k8s.io",
+}
+
+// IsNodeMetadataKey reports whether a label or annotation key is in one of the domains CAPI
+// propagates from a Machine to its Node. Only such keys are handed to the node.
+func IsNodeMetadataKey(key string) bool {
+	domain, _, ok := strings.Cut(key, "/")
+	if !ok {
+		return false
+	}
+	for _, d := range nodeMetadataDomains {
+		if domain == d || strings.HasSuffix(domain, "."+d) {
+			return true
+		}
+	}
+	return false
+}
+
+// NodeMeta returns the server meta keys carrying the labels and annotations for the node,
+// skipping keys IsNodeMetadataKey rejects
+func NodeMeta(labels, annotations map[string]string) map[string]string {
+	meta := make(map[string]string)
+	for key, value := range labels {
+		if IsNodeMetadataKey(key) {
+			meta[nodeLabelMetaPrefix+key] = value
+		}
+	}
+	for key, value := range annotations {
+		if IsNodeMetadataKey(key) {
+			meta[nodeAnnotationMetaPrefix+key] = value
+		}
+	}
+	return meta
+}
+
+// ParseNodeMeta returns the node labels and annotations carried by server meta written with
+// NodeMeta. Other keys, and keys IsNodeMetadataKey rejects, are ignored.
+func ParseNodeMeta(meta map[string]interface{}) (labels, annotations map[string]string) {
+	labels, annotations = map[string]string{}, map[string]string{}
+	for key, raw := range meta {
+		value, ok := raw.(string)
+		if !ok {
+			continue
+		}
+		if name := strings.TrimPrefix(key, nodeLabelMetaPrefix); name != key && IsNodeMetadataKey(name) {
+			labels[name] = value
+		} else if name := strings.TrimPrefix(key, nodeAnnotationMetaPrefix); name != key && IsNodeMetadataKey(name) {
+			annotations[name] = value
+		}
+	}
+	return labels, annotations
+}
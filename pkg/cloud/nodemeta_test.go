@@ -0,0 +1,61 @@
+/*
+Copyright 2025 Kube-DC Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloud
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestIsNodeMetadataKey(t *testing.T) {
+	tests := map[string]bool{
+		"node-role.kubernetes.io/worker":              true,
+		"node-restriction.kubernetes.io/pool":         true,
+		"node.cluster.x-k8s.io/tier":                  true,
+		"gpu.node.cluster.x-k8s.io/model":             true,
+		"cluster.x-k8s.io/cluster-name":               false,
+		"xnode.cluster.x-k8s.io/tier":                 false,
+		"node-role.kubernetes.io":                     false,
+		"topology.kubernetes.io/zone":                 false,
+		"machine.cluster.x-k8s.io/exclude-node-drain": false,
+	}
+	for key, want := range tests {
+		if got := IsNodeMetadataKey(key); got != want {
+			t.Errorf("IsNodeMetadataKey(%q) = %v, want %v", key, got, want)
+		}
+	}
+}
+
+func TestNodeMetaRoundTrip(t *testing.T) {
+	labels := map[string]string{"node-role.kubernetes.io/worker": "", "node.cluster.x-k8s.io/pool": "gpu"}
+	annotations := map[string]string{"node.cluster.x-k8s.io/owner": "team-a"}
+
+	meta := map[string]interface{}{
+		"machine-uid":                 "uid-1",
+		NodeMetaPrefix + "label.":     "dropped",
+		NodeMetaPrefix + "other":      "x",
+		NodeMetaPrefix + "label.team": "not a node metadata key",
+	}
+	for key, value := range NodeMeta(labels, map[string]string{"node.cluster.x-k8s.io/owner": "team-a", "other": "x"}) {
+		meta[key] = value
+	}
+
+	gotLabels, gotAnnotations := ParseNodeMeta(meta)
+	if !reflect.DeepEqual(gotLabels, labels) || !reflect.DeepEqual(gotAnnotations, annotations) {
+		t.Errorf("ParseNodeMeta() = %v, %v; want %v, %v", gotLabels, gotAnnotations, labels, annotations)
+	}
+}
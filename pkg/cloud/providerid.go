@@ -0,0 +1,40 @@
+/*
+Copyright 2025 Kube-DC Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloud
+
+import (
+	"regexp"
+	"strings"
+)
+
+// ProviderIDPrefix is the scheme of the provider IDs of nodes running on CloudSigma servers
+const ProviderIDPrefix = "cloudsigma://"
+
+var serverUUIDPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// ParseProviderID returns the server UUID of a cloudsigma://<uuid> provider ID. ok is false for an
+// empty ID, another scheme, or anything after the prefix that is not exactly one server UUID.
+func ParseProviderID(providerID string) (uuid string, ok bool) {
+	if !strings.HasPrefix(providerID, ProviderIDPrefix) {
+		return "", false
+	}
+	uuid = strings.TrimPrefix(providerID, ProviderIDPrefix)
+	if !serverUUIDPattern.MatchString(uuid) {
+		return "", false
+	}
+	return uuid, true
+}
@@ -0,0 +1,52 @@
+/*
+Copyright 2025 Kube-DC Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloud
+
+import "testing"
+
+func TestParseProviderID(t *testing.T) {
+	const uuid = "0d2a5cb1-8b7e-4c1f-9f5a-2e6b7c8d9e0f"
+
+	tests := []struct {
+		name       string
+		providerID string
+		wantUUID   string
+		wantOK     bool
+	}{
+		{name: "valid", providerID: "cloudsigma://" + uuid, wantUUID: uuid, wantOK: true},
+		{name: "upper case UUID", providerID: "cloudsigma://0D2A5CB1-8B7E-4C1F-9F5A-2E6B7C8D9E0F", wantUUID: "0D2A5CB1-8B7E-4C1F-9F5A-2E6B7C8D9E0F", wantOK: true},
+		{name: "empty", providerID: ""},
+		{name: "prefix only", providerID: "cloudsigma://"},
+		{name: "bare UUID", providerID: uuid},
+		{name: "other scheme", providerID: "aws://" + uuid},
+		{name: "prefix not at start", providerID: "x-cloudsigma://" + uuid},
+		{name: "not a UUID", providerID: "cloudsigma://server-1"},
+		{name: "truncated UUID", providerID: "cloudsigma://" + uuid[:35]},
+		{name: "trailing path", providerID: "cloudsigma://" + uuid + "/extra"},
+		{name: "leading path", providerID: "cloudsigma://zrh/" + uuid},
+		{name: "trailing whitespace", providerID: "cloudsigma://" + uuid + "\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotUUID, gotOK := ParseProviderID(tt.providerID)
+			if gotUUID != tt.wantUUID || gotOK != tt.wantOK {
+				t.Errorf("ParseProviderID(%q) = %q, %v; want %q, %v", tt.providerID, gotUUID, gotOK, tt.wantUUID, tt.wantOK)
+			}
+		})
+	}
+}
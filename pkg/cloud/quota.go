@@ -0,0 +1,121 @@
+/*
+Copyright 2025 Kube-DC Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloud
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"k8s.io/klog/v2"
+)
+
+// Resource names used by the CloudSigma /currentusage/ endpoint
+const (
+	QuotaResourceCPU    = "cpu"  // MHz
+	QuotaResourceMemory = "mem"  // bytes
+	QuotaResourceDrives = "dssd" // bytes of SSD storage
+	QuotaResourceIPs    = "ip"   // public IPv4 addresses
+)
+
+// ResourceUsage is the subscription and consumption of one resource
+type ResourceUsage struct {
+	Burst      int64 `json:"burst"`
+	Subscribed int64 `json:"subscribed"`
+	Using      int64 `json:"using"`
+}
+
+// AccountUsage is the account's current resource usage as returned by /currentusage/
+type AccountUsage struct {
+	Balance struct {
+		Balance  string `json:"balance"`
+		Currency string `json:"currency"`
+	} `json:"balance"`
+	Usage map[string]ResourceUsage `json:"usage"`
+}
+
+// GetAccountUsage returns the account's subscribed and used resources
+func (c *Client) GetAccountUsage(ctx context.Context) (*AccountUsage, error) {
+	var usage AccountUsage
+	if err := c.doDirectRequest(ctx, http.MethodGet, "currentusage/", nil, &usage); err != nil {
+		return nil, fmt.Errorf("failed to get account usage: %w", err)
+	}
+	return &usage, nil
+}
+
+// ServerResourceRequest returns the amount of each quota resource a server created from spec consumes
+func ServerResourceRequest(spec ServerSpec) map[string]int64 {
+	request := map[string]int64{
+		QuotaResourceCPU:    int64(spec.CPU),
+		QuotaResourceMemory: int64(spec.Memory) * 1024 * 1024,
+	}
+	for _, disk := range spec.Disks {
+		request[QuotaResourceDrives] += disk.Size
+	}
+
+	// NICs without a VLAN get a public IP; so does a server without any NICs
+	publicIPs := int64(0)
+	for _, nic := range spec.NICs {
+		if nic.VLAN == "" {
+			publicIPs++
+		}
+	}
+	if len(spec.NICs) == 0 {
+		publicIPs = 1
+	}
+	request[QuotaResourceIPs] = publicIPs
+	return request
+}
+
+// ExhaustedResources returns the resources, sorted by name, that cannot cover request.
+// Usage beyond the subscription is billed as burst from the account balance, so a resource
+// only counts as exhausted when the subscription does not cover it and the balance is not
+// positive. An unparsable balance is assumed to allow burst.
+func (u *AccountUsage) ExhaustedResources(request map[string]int64) []string {
+	if balance, err := strconv.ParseFloat(u.Balance.Balance, 64); err != nil || balance > 0 {
+		return nil
+	}
+
+	var exhausted []string
+	for resource, amount := range request {
+		usage, ok := u.Usage[resource]
+		if !ok || amount <= 0 {
+			continue
+		}
+		if usage.Using+amount > usage.Subscribed {
+			exhausted = append(exhausted, resource)
+		}
+	}
+	sort.Strings(exhausted)
+	return exhausted
+}
+
+// CheckServerQuota returns a QuotaExceededError if the account cannot provide the resources a
+// server created from spec needs
+func (c *Client) CheckServerQuota(ctx context.Context, spec ServerSpec) error {
+	usage, err := c.GetAccountUsage(ctx)
+	if err != nil {
+		return err
+	}
+	if exhausted := usage.ExhaustedResources(ServerResourceRequest(spec)); len(exhausted) > 0 {
+		klog.V(2).Infof("Account quota exhausted for server %s: %v", spec.Name, exhausted)
+		return &QuotaExceededError{Resources: exhausted}
+	}
+	return nil
+}
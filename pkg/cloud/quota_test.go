@@ -0,0 +1,117 @@
+/*
+Copyright 2025 Kube-DC Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloud
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+
+	infrav1 "github.com/kube-dc/cluster-api-provider-cloudsigma/api/v1beta1"
+)
+
+// currentUsageResponse is a trimmed /currentusage/ response: 8 of 10 GHz CPU, 14 of 16 GiB RAM,
+// 90 of 100 GiB SSD and 2 of 2 public IPs in use
+const currentUsageResponse = `{
+	"balance": {"balance": "%s", "currency": "USD"},
+	"usage": {
+		"cpu": {"burst": 0, "subscribed": 10000, "using": 8000},
+		"mem": {"burst": 0, "subscribed": 17179869184, "using": 15032385536},
+		"dssd": {"burst": 0, "subscribed": 107374182400, "using": 96636764160},
+		"ip": {"burst": 0, "subscribed": 2, "using": 2},
+		"windows_web_server_2008": {"burst": 0, "subscribed": 0, "using": 0}
+	}
+}`
+
+func TestCheckServerQuota(t *testing.T) {
+	tests := []struct {
+		name    string
+		balance string
+		spec    ServerSpec
+		want    []string
+	}{
+		{
+			name:    "fits the subscription",
+			balance: "0.00",
+			spec: ServerSpec{CPU: 2000, Memory: 1024, Disks: []infrav1.CloudSigmaDisk{{Size: 10 << 30}},
+				NICs: []infrav1.CloudSigmaNIC{{VLAN: "vlan-1"}}},
+		},
+		{
+			name:    "exhausted without balance",
+			balance: "0.00",
+			spec:    ServerSpec{CPU: 4000, Memory: 4096, Disks: []infrav1.CloudSigmaDisk{{Size: 20 << 30}}},
+			want:    []string{QuotaResourceCPU, QuotaResourceDrives, QuotaResourceIPs, QuotaResourceMemory},
+		},
+		{
+			name:    "burst covered by balance",
+			balance: "125.50",
+			spec:    ServerSpec{CPU: 4000, Memory: 4096, Disks: []infrav1.CloudSigmaDisk{{Size: 20 << 30}}},
+		},
+		{
+			name:    "negative balance",
+			balance: "-3.10",
+			spec:    ServerSpec{CPU: 4000, Memory: 1024, NICs: []infrav1.CloudSigmaNIC{{VLAN: "vlan-1"}}},
+			want:    []string{QuotaResourceCPU},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mux := http.NewServeMux()
+			mux.HandleFunc("/api/2.0/currentusage/", func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				_, _ = w.Write([]byte(fmt.Sprintf(currentUsageResponse, tt.balance)))
+			})
+			c := newTestClient(t, mux)
+
+			err := c.CheckServerQuota(context.Background(), tt.spec)
+			if tt.want == nil {
+				if err != nil {
+					t.Fatalf("CheckServerQuota() error = %v, want nil", err)
+				}
+				return
+			}
+			var qee *QuotaExceededError
+			if !errors.As(err, &qee) {
+				t.Fatalf("CheckServerQuota() error = %v, want QuotaExceededError", err)
+			}
+			if !reflect.DeepEqual(qee.Resources, tt.want) {
+				t.Errorf("exhausted resources = %v, want %v", qee.Resources, tt.want)
+			}
+		})
+	}
+}
+
+func TestServerResourceRequest(t *testing.T) {
+	spec := ServerSpec{
+		CPU:    2000,
+		Memory: 2048,
+		Disks:  []infrav1.CloudSigmaDisk{{Size: 10 << 30}, {Size: 5 << 30}},
+		NICs:   []infrav1.CloudSigmaNIC{{}, {VLAN: "vlan-1"}, {}},
+	}
+	want := map[string]int64{
+		QuotaResourceCPU:    2000,
+		QuotaResourceMemory: 2 << 30,
+		QuotaResourceDrives: 15 << 30,
+		QuotaResourceIPs:    2,
+	}
+	if got := ServerResourceRequest(spec); !reflect.DeepEqual(got, want) {
+		t.Errorf("ServerResourceRequest() = %v, want %v", got, want)
+	}
+}
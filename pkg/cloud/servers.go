@@ -18,26 +18,163 @@ package cloud
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/json"
 	"fmt"
+	"math/big"
+	"net/http"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/cloudsigma/cloudsigma-sdk-go/cloudsigma"
 	"k8s.io/klog/v2"
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
 
 	infrav1 "github.com/kube-dc/cluster-api-provider-cloudsigma/api/v1beta1"
+	"github.com/kube-dc/cluster-api-provider-cloudsigma/pkg/devicechannel"
 )
 
-// ServerSpec defines the specifications for creating a server
+// ServerSpec defines the specifications for creating a server. SMP, CPUModel and CPUFlags are
+// left to CloudSigma when unset; see CloudSigmaMachineSpec.
 type ServerSpec struct {
 	Name          string
 	CPU           int
 	Memory        int
+	SMP           int
+	CPUModel      string
+	CPUFlags      []string
 	Disks         []infrav1.CloudSigmaDisk
 	NICs          []infrav1.CloudSigmaNIC
 	Tags          []string
 	Meta          map[string]string
-	BootstrapData string // Cloud-init user data
+	BootstrapData string // Base64-encoded user data
+	// VNCPassword is the server console password; a random one is generated when empty
+	VNCPassword string
+	// BootstrapFormat selects the meta field BootstrapData is stored under; defaults to cloud-config
+	BootstrapFormat BootstrapFormat
+}
+
+// BootstrapFormat is the format of the bootstrap data produced by the CAPI bootstrap provider
+type BootstrapFormat string
+
+const (
+	// BootstrapFormatCloudConfig is cloud-init user data, read from meta["cloudinit-user-data"]
+	BootstrapFormatCloudConfig BootstrapFormat = "cloud-config"
+	// BootstrapFormatIgnition is an Ignition config (Flatcar, Fedora CoreOS), read from meta["ignition"]
+	BootstrapFormatIgnition BootstrapFormat = "ignition"
+)
+
+// ParseBootstrapFormat validates a bootstrap format string. An empty value means cloud-config.
+func ParseBootstrapFormat(format string) (BootstrapFormat, error) {
+	switch BootstrapFormat(format) {
+	case "", BootstrapFormatCloudConfig:
+		return BootstrapFormatCloudConfig, nil
+	case BootstrapFormatIgnition:
+		return BootstrapFormatIgnition, nil
+	default:
+		return "", fmt.Errorf("unsupported bootstrap format %q", format)
+	}
+}
+
+// vncPasswordAlphabet avoids characters that are easily confused when typed into a console client
+const vncPasswordAlphabet = "abcdefghijkmnpqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+// VNCPasswordLength is the length of generated console passwords
+const VNCPasswordLength = 16
+
+// GenerateVNCPassword returns a random console password
+func GenerateVNCPassword() (string, error) {
+	buf := make([]byte, VNCPasswordLength)
+	max := big.NewInt(int64(len(vncPasswordAlphabet)))
+	for i := range buf {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", fmt.Errorf("failed to generate VNC password: %w", err)
+		}
+		buf[i] = vncPasswordAlphabet[n.Int64()]
+	}
+	return string(buf), nil
+}
+
+// ReservedMetaKeys are server meta keys set by the provider; they may not be supplied in spec.meta
+var ReservedMetaKeys = []string{"cloudinit-user-data", "base64_fields", "ssh_public_key", "ignition"}
+
+// MaxServerMetaBytes is the budget for the JSON-encoded server meta, bootstrap data included.
+// CloudSigma rejects larger meta with an opaque 400.
+const MaxServerMetaBytes = 128 * 1024
+
+// ValidateServerMeta checks user-supplied meta for reserved keys and verifies that, together with
+// the bootstrap data, it fits in MaxServerMetaBytes. Errors are *InvalidServerSpecError.
+func ValidateServerMeta(meta map[string]string, bootstrapData string) error {
+	for _, key := range ReservedMetaKeys {
+		if _, ok := meta[key]; ok {
+			return &InvalidServerSpecError{Reason: fmt.Sprintf("meta key %q is reserved", key)}
+		}
+	}
+
+	full := make(map[string]string, len(meta)+2)
+	for k, v := range meta {
+		full[k] = v
+	}
+	if bootstrapData != "" {
+		// The ignition field name is shorter, so this is an upper bound for either format
+		full["cloudinit-user-data"] = bootstrapData
+		full["base64_fields"] = "cloudinit-user-data"
+	}
+	encoded, err := json.Marshal(full)
+	if err != nil {
+		return fmt.Errorf("failed to encode meta: %w", err)
+	}
+	if len(encoded) > MaxServerMetaBytes {
+		return &InvalidServerSpecError{Reason: fmt.Sprintf("meta is %d bytes including bootstrap data, limit is %d",
+			len(encoded), MaxServerMetaBytes)}
+	}
+	return nil
+}
+
+// DiskDeviceChannels returns the device channel each disk is attached at. The disk with
+// boot_order 1 gets devicechannel.Boot; all other disks, including bootable ones with a lower
+// priority, get the next free channels in spec order, the same way the CSI driver picks channels
+// for hotplugged volumes. More than one disk with boot_order 1, or a shared disk with a boot
+// order, is an *InvalidServerSpecError.
+func DiskDeviceChannels(disks []infrav1.CloudSigmaDisk) ([]string, error) {
+	channels := make([]string, len(disks))
+	used := map[string]bool{devicechannel.Boot: true}
+	boot := -1
+	for i, disk := range disks {
+		if !disk.IsCloned() && disk.BootOrder != 0 {
+			return nil, &InvalidServerSpecError{Reason: fmt.Sprintf("disk %d is shared (clone: false) and cannot be bootable", i)}
+		}
+		if disk.BootOrder != 1 {
+			continue
+		}
+		if boot >= 0 {
+			return nil, &InvalidServerSpecError{Reason: fmt.Sprintf("disks %d and %d both have boot_order 1", boot, i)}
+		}
+		boot = i
+		channels[i] = devicechannel.Boot
+	}
+	for i := range disks {
+		if channels[i] == "" {
+			channels[i] = devicechannel.Next(used)
+			used[channels[i]] = true
+		}
+	}
+	return channels, nil
+}
+
+// ClonedDriveName is the name CreateServer gives the clone of the server's i-th disk
+func ClonedDriveName(serverName string, i int) string {
+	return fmt.Sprintf("%s-drive-%d", serverName, i)
+}
+
+// bootstrapMetaField returns the server meta key the guest reads bootstrap data from
+func bootstrapMetaField(format BootstrapFormat) string {
+	if format == BootstrapFormatIgnition {
+		return "ignition"
+	}
+	return "cloudinit-user-data"
 }
 
 // CreateServer creates a new CloudSigma server
@@ -45,14 +182,46 @@ func (c *Client) CreateServer(ctx context.Context, spec ServerSpec) (*cloudsigma
 	klog.Infof("==> CreateServer called for: %s (CPU: %d MHz, Memory: %d MB, Disks: %d)",
 		spec.Name, spec.CPU, spec.Memory, len(spec.Disks))
 
+	// Reject meta CloudSigma would refuse before cloning any drives
+	if err := ValidateServerMeta(spec.Meta, spec.BootstrapData); err != nil {
+		return nil, err
+	}
+	if _, err := DiskDeviceChannels(spec.Disks); err != nil {
+		return nil, err
+	}
+
+	// Never fall back to a password shared by every server
+	if spec.VNCPassword == "" {
+		generated, err := GenerateVNCPassword()
+		if err != nil {
+			return nil, err
+		}
+		spec.VNCPassword = generated
+	}
+
+	// Shared drives are attached as they are; make sure they exist before cloning anything
+	for i, disk := range spec.Disks {
+		if !disk.IsCloned() {
+			if err := c.checkSharedDrive(ctx, i, disk); err != nil {
+				return nil, err
+			}
+		}
+	}
+
 	// Clone drives first (CloudSigma requires unique drive per server)
+	drives := make([]string, 0, len(spec.Disks))
 	clonedDrives := make([]string, 0, len(spec.Disks))
 	for i, disk := range spec.Disks {
 		klog.Infof("==> Disk %d: UUID=%s, Size=%d", i, disk.UUID, disk.Size)
-		driveName := fmt.Sprintf("%s-drive-%d", spec.Name, i)
+		if !disk.IsCloned() {
+			klog.Infof("==> Attaching shared drive %s without cloning", disk.UUID)
+			drives = append(drives, disk.UUID)
+			continue
+		}
+		driveName := ClonedDriveName(spec.Name, i)
 		klog.Infof("==> Starting drive clone: source=%s, name=%s", disk.UUID, driveName)
 
-		clonedDrive, err := c.CloneDrive(ctx, disk.UUID, driveName, disk.Size)
+		clonedDrive, err := c.CloneDrive(ctx, disk.UUID, driveName, disk.Size, disk.Media, spec.Name)
 		if err != nil {
 			klog.Errorf("==> Clone failed: %v", err)
 			// Clean up any drives we created
@@ -62,111 +231,20 @@ func (c *Client) CreateServer(ctx context.Context, spec ServerSpec) (*cloudsigma
 			return nil, fmt.Errorf("failed to clone drive %s: %w", disk.UUID, err)
 		}
 		klog.Infof("==> Clone succeeded: %s", clonedDrive.UUID)
+		drives = append(drives, clonedDrive.UUID)
 		clonedDrives = append(clonedDrives, clonedDrive.UUID)
 	}
 
 	klog.Infof("==> All drives cloned: %v", clonedDrives)
 
-	// Build custom server object (using strings for drive/VLAN references)
-	server := &CustomServer{
-		Name:        spec.Name,
-		CPU:         spec.CPU,
-		Memory:      spec.Memory * 1024 * 1024, // Convert MB to bytes
-		VNCPassword: "kubernetes",              // Required by CloudSigma API
-	}
-
-	// Add cloned disks
-	for i, disk := range spec.Disks {
-		driveUUID := clonedDrives[i]
-		klog.Infof("==> Adding drive %d: UUID=%s", i, driveUUID)
-
-		serverDrive := CustomServerDrive{
-			BootOrder:  disk.BootOrder,
-			DevChannel: fmt.Sprintf("0:%d", disk.BootOrder),
-			Device:     disk.Device,
-			Drive:      driveUUID, // Just the UUID string
-		}
-		klog.Infof("==> ServerDrive: BootOrder=%d, DevChannel=%s, Device=%s, Drive=%s",
-			serverDrive.BootOrder, serverDrive.DevChannel, serverDrive.Device, serverDrive.Drive)
-		server.Drives = append(server.Drives, serverDrive)
-	}
-
-	klog.Infof("==> Total server drives: %d", len(server.Drives))
-
-	// Add NICs with VLAN and IPv4 configuration (if specified)
-	if len(spec.NICs) > 0 {
-		klog.Infof("==> Configuring %d NIC(s)", len(spec.NICs))
-		for i, nic := range spec.NICs {
-			if nic.VLAN != "" {
-				// NIC with VLAN
-				customNIC := CustomServerNIC{
-					VLAN: nic.VLAN, // VLAN UUID string
-				}
-
-				// Add IPv4 configuration if specified
-				if nic.IPv4Conf.Conf != "" {
-					customNIC.IPv4Conf = &CustomIPv4Conf{
-						Conf: nic.IPv4Conf.Conf,
-					}
-
-					// Add static IP reference if provided
-					if nic.IPv4Conf.IP != nil && nic.IPv4Conf.IP.UUID != "" {
-						customNIC.IPv4Conf.IP = &CustomIPRef{
-							UUID: nic.IPv4Conf.IP.UUID,
-						}
-					}
-
-					klog.Infof("==> NIC %d: VLAN=%s, IPv4Conf=%s", i, nic.VLAN, nic.IPv4Conf.Conf)
-				} else {
-					klog.Warningf("==> NIC %d: VLAN specified but no IPv4 config", i)
-				}
-
-				server.NICs = append(server.NICs, customNIC)
-			} else {
-				// NIC without VLAN - create PUBLIC IP with DHCP
-				klog.Infof("==> NIC %d: No VLAN specified, creating PUBLIC IP with DHCP", i)
-				customNIC := CustomServerNIC{
-					IPv4Conf: &CustomIPv4Conf{
-						Conf: "dhcp",
-					},
-				}
-				server.NICs = append(server.NICs, customNIC)
-			}
-		}
-	} else {
-		// No NICs array specified at all - add PUBLIC IP with DHCP
-		// CloudSigma requires either VLAN or ip_v4_conf - we use ip_v4_conf for public network
-		klog.Infof("==> No NICs specified in template, adding PUBLIC IP with DHCP")
-		publicNIC := CustomServerNIC{
-			IPv4Conf: &CustomIPv4Conf{
-				Conf: "dhcp",
-			},
-		}
-		server.NICs = append(server.NICs, publicNIC)
-	}
-
-	// Add metadata (cloud-init)
-	if spec.BootstrapData != "" {
-		if server.Meta == nil {
-			server.Meta = make(map[string]string)
-		}
-		server.Meta["base64_fields"] = "cloudinit-user-data"
-		server.Meta["cloudinit-user-data"] = spec.BootstrapData
-	}
-
-	// Add custom metadata
-	if len(spec.Meta) > 0 {
-		if server.Meta == nil {
-			server.Meta = make(map[string]string)
-		}
-		for k, v := range spec.Meta {
-			server.Meta[k] = v
+	server, err := buildCustomServer(spec, drives)
+	if err != nil {
+		for _, uuid := range clonedDrives {
+			_ = c.DeleteDrive(ctx, uuid)
 		}
+		return nil, err
 	}
 
-	// Note: Tags are not directly supported in CustomServer structure
-	// They would need to be added to CustomServer if required
-
 	// Create server using direct API call (SDK has serialization issues)
 	createdServer, err := c.createServerDirect(ctx, server)
 	if err != nil {
@@ -177,9 +255,37 @@ func (c *Client) CreateServer(ctx context.Context, spec ServerSpec) (*cloudsigma
 		return nil, fmt.Errorf("failed to create server: %w", err)
 	}
 	klog.V(2).Infof("Server created successfully: %s (UUID: %s)", createdServer.Name, createdServer.UUID)
+
+	// Tags reference the servers they are on, so the server is added to them once it exists
+	for _, tagName := range server.Tags {
+		if err := c.ensureTagWithResource(ctx, tagName, createdServer.UUID); err != nil {
+			klog.Warningf("Failed to tag server %s with %s: %v", createdServer.UUID, tagName, err)
+		}
+	}
 	return createdServer, nil
 }
 
+// checkSharedDrive verifies that the shared drive of disk i can be attached to a new server:
+// it must be a drive in the account with the disk's media, if set, and one already in use must
+// allow multimount
+func (c *Client) checkSharedDrive(ctx context.Context, i int, disk infrav1.CloudSigmaDisk) error {
+	uuid := disk.UUID
+	drive, err := c.GetDrive(ctx, uuid)
+	if err != nil {
+		return err
+	}
+	if drive == nil {
+		return &InvalidServerSpecError{Reason: fmt.Sprintf("shared disk %d: drive %s does not exist in the account", i, uuid)}
+	}
+	if disk.Media != "" && drive.Media != disk.Media {
+		return &InvalidServerSpecError{Reason: fmt.Sprintf("shared disk %d: drive %s has media %s, not %s", i, uuid, drive.Media, disk.Media)}
+	}
+	if len(drive.MountedOn) > 0 && !drive.AllowMultimount {
+		return &InvalidServerSpecError{Reason: fmt.Sprintf("shared disk %d: drive %s is mounted on another server and does not allow multimount", i, uuid)}
+	}
+	return nil
+}
+
 // GetServer retrieves a server by UUID
 // Returns nil, nil if server not found (404)
 // Returns PermissionDeniedError if user cannot access the server (403)
@@ -189,7 +295,7 @@ func (c *Client) GetServer(ctx context.Context, uuid string) (*cloudsigma.Server
 	server, resp, err := c.sdk.Servers.Get(ctx, uuid)
 	if err != nil {
 		errStr := err.Error()
-		
+
 		// Check HTTP status code from response (if available)
 		if resp != nil {
 			switch resp.StatusCode {
@@ -201,7 +307,7 @@ func (c *Client) GetServer(ctx context.Context, uuid string) (*cloudsigma.Server
 				return nil, NewPermissionDeniedError("server", uuid, 403, c.impersonatedUser, err)
 			}
 		}
-		
+
 		// Also check error message for status codes (SDK sometimes embeds them in the message)
 		if strings.Contains(errStr, "404") || strings.Contains(errStr, "not found") {
 			klog.V(2).Infof("Server not found (from error): %s", uuid)
@@ -211,7 +317,7 @@ func (c *Client) GetServer(ctx context.Context, uuid string) (*cloudsigma.Server
 			klog.Warningf("Permission denied for server %s (user: %s, error: %s) - triggering self-healing", uuid, c.impersonatedUser, errStr)
 			return nil, NewPermissionDeniedError("server", uuid, 403, c.impersonatedUser, err)
 		}
-		
+
 		return nil, fmt.Errorf("failed to get server: %w", err)
 	}
 
@@ -329,6 +435,32 @@ func (c *Client) StartServer(ctx context.Context, uuid string) error {
 	return nil
 }
 
+// WaitForServerStatus polls a server every interval until it reports the wanted status.
+// It returns nil, nil if the server disappears while waiting. On timeout the last observed
+// server is returned together with a *ServerStatusTimeoutError.
+func (c *Client) WaitForServerStatus(ctx context.Context, uuid, status string, timeout, interval time.Duration) (*cloudsigma.Server, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		server, err := c.GetServer(ctx, uuid)
+		if err != nil {
+			return nil, err
+		}
+		if server == nil || server.Status == status {
+			return server, nil
+		}
+		if !time.Now().Before(deadline) {
+			return server, &ServerStatusTimeoutError{UUID: uuid, Want: status, Status: server.Status}
+		}
+
+		klog.V(2).Infof("Waiting for server %s to become %s (status: %s)", uuid, status, server.Status)
+		select {
+		case <-ctx.Done():
+			return server, ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
+
 // StopServer stops a running server
 func (c *Client) StopServer(ctx context.Context, uuid string) error {
 	klog.V(2).Infof("Stopping server: %s", uuid)
@@ -342,7 +474,25 @@ func (c *Client) StopServer(ctx context.Context, uuid string) error {
 	return nil
 }
 
-// DeleteServer deletes a server and its associated drives
+// ServerOwnedDrives returns the UUIDs of the drives CreateServer cloned for the server, the only
+// drives deleted with it. A drive is only owned while it carries the server's clone mark (see
+// ClonedFor) and no other server mounts it: shared drives attached without cloning, volumes
+// attached later and user drives that merely look like clones are left alone.
+func ServerOwnedDrives(server cloudsigma.Server, drives []cloudsigma.Drive) []string {
+	var owned []string
+	for _, drive := range drives {
+		owner, marked := ClonedFor(drive)
+		shared := slices.ContainsFunc(drive.MountedOn, func(m cloudsigma.ResourceLink) bool { return m.UUID != server.UUID })
+		if marked && owner == server.Name && !shared {
+			owned = append(owned, drive.UUID)
+			continue
+		}
+		klog.V(2).Infof("Keeping drive %s (%s), it was not cloned for server %s", drive.UUID, drive.Name, server.Name)
+	}
+	return owned
+}
+
+// DeleteServer deletes a server and the drives cloned for it
 func (c *Client) DeleteServer(ctx context.Context, uuid string) error {
 	klog.V(2).Infof("Deleting server: %s", uuid)
 
@@ -357,13 +507,23 @@ func (c *Client) DeleteServer(ctx context.Context, uuid string) error {
 		return nil
 	}
 
-	// Remember drive UUIDs and IP UUIDs for cleanup
-	driveUUIDs := make([]string, 0, len(server.Drives))
+	// Remember the drives cloned for this server for cleanup; shared drives and volumes
+	// attached later stay
+	attached := make([]cloudsigma.Drive, 0, len(server.Drives))
 	for _, drive := range server.Drives {
-		if drive.Drive != nil {
-			driveUUIDs = append(driveUUIDs, drive.Drive.UUID)
+		if drive.Drive == nil {
+			continue
+		}
+		d, err := c.GetDrive(ctx, drive.Drive.UUID)
+		if err != nil {
+			klog.Errorf("Failed to get drive %s, keeping it: %v", drive.Drive.UUID, err)
+			continue
+		}
+		if d != nil {
+			attached = append(attached, *d)
 		}
 	}
+	driveUUIDs := ServerOwnedDrives(*server, attached)
 
 	// Remember IP UUIDs for cleanup (public IPs without VLAN)
 	ipUUIDs := make([]string, 0)
@@ -414,13 +574,27 @@ func (c *Client) DeleteServer(ctx context.Context, uuid string) error {
 	return nil
 }
 
-// ListServers lists all servers
+// ListServers lists all servers, following pagination until every page is collected.
+// The SDK's Servers.List takes no paging options and only returns the first page.
 func (c *Client) ListServers(ctx context.Context) ([]cloudsigma.Server, error) {
 	klog.V(4).Info("Listing servers")
 
-	servers, _, err := c.sdk.Servers.List(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("failed to list servers: %w", err)
+	var servers []cloudsigma.Server
+	for offset := 0; ; {
+		var page struct {
+			Meta    cloudsigma.Meta     `json:"meta"`
+			Objects []cloudsigma.Server `json:"objects"`
+		}
+		path := fmt.Sprintf("servers/detail/?limit=%d&offset=%d", listPageSize, offset)
+		if err := c.doDirectRequest(ctx, http.MethodGet, path, nil, &page); err != nil {
+			return nil, fmt.Errorf("failed to list servers: %w", err)
+		}
+
+		servers = append(servers, page.Objects...)
+		offset += len(page.Objects)
+		if len(page.Objects) == 0 || offset >= page.Meta.TotalCount {
+			break
+		}
 	}
 
 	klog.V(4).Infof("Found %d servers", len(servers))
@@ -432,9 +606,9 @@ func (c *Client) ListServers(ctx context.Context) ([]cloudsigma.Server, error) {
 func (c *Client) FindServerByNameOrMeta(ctx context.Context, name string, machineUID string) (*cloudsigma.Server, error) {
 	klog.Infof("Finding server by name=%s or machineUID=%s", name, machineUID)
 
-	servers, _, err := c.sdk.Servers.List(ctx)
+	servers, err := c.ListServers(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list servers: %w", err)
+		return nil, err
 	}
 
 	klog.Infof("Listed %d servers, searching for match...", len(servers))
@@ -452,18 +626,46 @@ func (c *Client) FindServerByNameOrMeta(ctx context.Context, name string, machin
 	}
 
 	// Fallback: check by name
-	for _, server := range servers {
-		if server.Name == name {
-			klog.Infof("Found server by name: name=%s, uuid=%s", server.Name, server.UUID)
-			return &server, nil
-		}
+	server, err := matchServerByName(servers, name)
+	if err != nil {
+		return nil, err
+	}
+	if server != nil {
+		klog.Infof("Found server by name: name=%s, uuid=%s", server.Name, server.UUID)
+		return server, nil
 	}
 
 	klog.Infof("No server found matching name=%s or machineUID=%s", name, machineUID)
 	return nil, nil
 }
 
-// FindServerByName finds a server by name, returns nil if not found (legacy, prefer FindServerByNameOrMeta)
+// FindServerByName finds a server by its exact name, returns nil if not found.
+// Names are derived from the CAPI machine and expected to be unique; if several servers share
+// the name a DuplicateServerNameError is returned rather than guessing which one to adopt.
+// The API has no name filter, so all servers are listed and matched locally.
 func (c *Client) FindServerByName(ctx context.Context, name string) (*cloudsigma.Server, error) {
-	return c.FindServerByNameOrMeta(ctx, name, "")
+	servers, err := c.ListServers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return matchServerByName(servers, name)
+}
+
+// matchServerByName returns the only server with the given name, nil if there is none
+func matchServerByName(servers []cloudsigma.Server, name string) (*cloudsigma.Server, error) {
+	var found *cloudsigma.Server
+	var uuids []string
+	for i := range servers {
+		if servers[i].Name != name {
+			continue
+		}
+		if found == nil {
+			found = &servers[i]
+		}
+		uuids = append(uuids, servers[i].UUID)
+	}
+	if len(uuids) > 1 {
+		return nil, &DuplicateServerNameError{Name: name, UUIDs: uuids}
+	}
+	return found, nil
 }
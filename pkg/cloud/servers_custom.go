@@ -20,12 +20,17 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/cloudsigma/cloudsigma-sdk-go/cloudsigma"
 	"k8s.io/klog/v2"
+
+	infrav1 "github.com/kube-dc/cluster-api-provider-cloudsigma/api/v1beta1"
 )
 
 // CustomServerDrive represents a server drive with string drive reference
@@ -62,6 +67,133 @@ type CustomServer struct {
 	Drives      []CustomServerDrive `json:"drives"`
 	NICs        []CustomServerNIC   `json:"nics,omitempty"` // Omit if empty - CloudSigma auto-assigns public IP
 	Meta        map[string]string   `json:"meta,omitempty"`
+
+	// Tags are the names of the tags the server is added to once it exists. CloudSigma tags list
+	// the resources they are on, so they are not part of the create request.
+	Tags []string `json:"-"`
+
+	// CPU options, omitted when unset so CloudSigma's defaults apply
+	SMP                int    `json:"smp,omitempty"`
+	CPUType            string `json:"cpu_type,omitempty"`
+	EnableNUMA         bool   `json:"enable_numa,omitempty"`
+	CPUsInsteadOfCores bool   `json:"cpus_instead_of_cores,omitempty"`
+	HVRelaxed          bool   `json:"hv_relaxed,omitempty"`
+	HVTSC              bool   `json:"hv_tsc,omitempty"`
+}
+
+// applyCPUOptions copies the optional SMP, CPU model and CPU flags of spec onto server
+func applyCPUOptions(server *CustomServer, spec ServerSpec) {
+	server.SMP = spec.SMP
+	server.CPUType = spec.CPUModel
+	for _, flag := range spec.CPUFlags {
+		switch flag {
+		case infrav1.CPUFlagNUMA:
+			server.EnableNUMA = true
+		case infrav1.CPUFlagCPUsInsteadOfCores:
+			server.CPUsInsteadOfCores = true
+		case infrav1.CPUFlagHVRelaxed:
+			server.HVRelaxed = true
+		case infrav1.CPUFlagHVTSC:
+			server.HVTSC = true
+		default:
+			klog.Warningf("Ignoring unknown CPU flag %q", flag)
+		}
+	}
+}
+
+// buildCustomServer assembles the create request for spec from the UUIDs of the drives attached
+// for its disks, in spec order: the clone of each cloned disk and the shared drive itself
+// otherwise. It performs no I/O; spec.VNCPassword must already be set.
+func buildCustomServer(spec ServerSpec, drives []string) (*CustomServer, error) {
+	if len(drives) != len(spec.Disks) {
+		return nil, fmt.Errorf("got %d drives for %d disks", len(drives), len(spec.Disks))
+	}
+	if spec.VNCPassword == "" {
+		return nil, fmt.Errorf("VNC password is required")
+	}
+	if err := ValidateServerMeta(spec.Meta, spec.BootstrapData); err != nil {
+		return nil, err
+	}
+	channels, err := DiskDeviceChannels(spec.Disks)
+	if err != nil {
+		return nil, err
+	}
+
+	server := &CustomServer{
+		Name:        spec.Name,
+		CPU:         spec.CPU,
+		Memory:      spec.Memory * 1024 * 1024, // Convert MB to bytes
+		VNCPassword: spec.VNCPassword,          // Required by CloudSigma API
+		NICs:        buildServerNICs(spec.NICs),
+		Meta:        buildServerMeta(spec),
+		Tags:        spec.Tags,
+	}
+	applyCPUOptions(server, spec)
+
+	for i, disk := range spec.Disks {
+		server.Drives = append(server.Drives, CustomServerDrive{
+			BootOrder:  disk.BootOrder,
+			DevChannel: channels[i],
+			Device:     disk.Device,
+			Drive:      drives[i],
+		})
+	}
+
+	return server, nil
+}
+
+// buildServerNICs converts the NICs of a machine spec. CloudSigma requires either a VLAN or an
+// ip_v4_conf on every NIC, so NICs without either, and a spec without NICs, get a public DHCP address.
+func buildServerNICs(nics []infrav1.CloudSigmaNIC) []CustomServerNIC {
+	if len(nics) == 0 {
+		return []CustomServerNIC{{IPv4Conf: &CustomIPv4Conf{Conf: "dhcp"}}}
+	}
+
+	result := make([]CustomServerNIC, 0, len(nics))
+	for i, nic := range nics {
+		hasStaticIP := nic.IPv4Conf.IP != nil && nic.IPv4Conf.IP.UUID != ""
+		switch {
+		case nic.VLAN != "":
+			customNIC := CustomServerNIC{VLAN: nic.VLAN}
+			if nic.IPv4Conf.Conf != "" {
+				customNIC.IPv4Conf = &CustomIPv4Conf{Conf: nic.IPv4Conf.Conf}
+				if hasStaticIP {
+					customNIC.IPv4Conf.IP = &CustomIPRef{UUID: nic.IPv4Conf.IP.UUID}
+				}
+			} else {
+				klog.Warningf("NIC %d: VLAN %s specified but no IPv4 config", i, nic.VLAN)
+			}
+			result = append(result, customNIC)
+		case nic.IPv4Conf.Conf == "static" && hasStaticIP:
+			// Public network with a static IP
+			result = append(result, CustomServerNIC{IPv4Conf: &CustomIPv4Conf{
+				Conf: "static",
+				IP:   &CustomIPRef{UUID: nic.IPv4Conf.IP.UUID},
+			}})
+		default:
+			// Public network with DHCP
+			result = append(result, CustomServerNIC{IPv4Conf: &CustomIPv4Conf{Conf: "dhcp"}})
+		}
+	}
+	return result
+}
+
+// buildServerMeta merges the bootstrap data, stored base64-encoded under the field of its format,
+// with the user-supplied meta. It returns nil when there is neither.
+func buildServerMeta(spec ServerSpec) map[string]string {
+	if spec.BootstrapData == "" && len(spec.Meta) == 0 {
+		return nil
+	}
+	meta := make(map[string]string, len(spec.Meta)+2)
+	if spec.BootstrapData != "" {
+		field := bootstrapMetaField(spec.BootstrapFormat)
+		meta["base64_fields"] = field
+		meta[field] = spec.BootstrapData
+	}
+	for k, v := range spec.Meta {
+		meta[k] = v
+	}
+	return meta
 }
 
 // CustomServerCreateRequest wraps servers for creation
@@ -82,7 +214,7 @@ func (c *Client) createServerDirect(ctx context.Context, server *CustomServer) (
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	klog.Infof("Request body: %s", string(body))
+	// The body is not logged: it carries the VNC password and the bootstrap data
 
 	// Construct API URL (CloudSigma SDK doesn't expose BaseURL)
 	// We'll use the environment variable or default
@@ -123,7 +255,7 @@ func (c *Client) createServerDirect(ctx context.Context, server *CustomServer) (
 	}
 
 	if resp.StatusCode >= 400 {
-		return nil, fmt.Errorf("API error %d: %s", resp.StatusCode, string(respBody))
+		return nil, &APIError{StatusCode: resp.StatusCode, Body: string(respBody)}
 	}
 
 	// Parse response
@@ -142,6 +274,94 @@ func (c *Client) createServerDirect(ctx context.Context, server *CustomServer) (
 	return &result.Objects[0], nil
 }
 
+// doDirectRequest performs an authenticated request against the CloudSigma API without the SDK.
+// path is relative to the API endpoint (e.g. "ips/detail/"). If out is non-nil the JSON response
+// is decoded into it. Non-2xx responses are returned as *APIError, with the Retry-After delay of a
+// 429 in RetryAfter. With a token source, a 401 is retried once with a fresh token.
+func (c *Client) doDirectRequest(ctx context.Context, method, path string, in, out interface{}) error {
+	var body []byte
+	if in != nil {
+		var err error
+		body, err = json.Marshal(in)
+		if err != nil {
+			return fmt.Errorf("failed to marshal request: %w", err)
+		}
+	}
+
+	apiEndpoint := c.apiEndpoint
+	if apiEndpoint == "" {
+		apiEndpoint = "https://next.cloudsigma.com/api/2.0"
+	}
+	url := fmt.Sprintf("%s/%s", apiEndpoint, path)
+
+	respBody, err := c.sendDirectRequest(ctx, method, url, body)
+	var apiErr *APIError
+	if c.tokenSource != nil && errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusUnauthorized {
+		klog.V(2).Infof("CloudSigma API rejected the token for %s %s, retrying with a fresh one", method, url)
+		c.tokenSource.Invalidate()
+		respBody, err = c.sendDirectRequest(ctx, method, url, body)
+	}
+	if err != nil {
+		return err
+	}
+
+	if out != nil && len(respBody) > 0 {
+		if err := json.Unmarshal(respBody, out); err != nil {
+			return fmt.Errorf("failed to unmarshal response: %w", err)
+		}
+	}
+	return nil
+}
+
+// sendDirectRequest sends one request for doDirectRequest and returns the response body
+func (c *Client) sendDirectRequest(ctx context.Context, method, url string, body []byte) ([]byte, error) {
+	var reqBody io.Reader
+	if body != nil {
+		reqBody = bytes.NewReader(body)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, method, url, reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Accept", "application/json")
+
+	switch {
+	case c.tokenSource != nil:
+		token, err := c.tokenSource.Token(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get token: %w", err)
+		}
+		httpReq.Header.Set("Authorization", "Bearer "+token)
+	case c.useImpersonation && c.accessToken != "":
+		httpReq.Header.Set("Authorization", "Bearer "+c.accessToken)
+	default:
+		httpReq.SetBasicAuth(c.username, c.password)
+	}
+
+	httpClient := &http.Client{}
+	resp, err := httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode >= 400 {
+		return nil, &APIError{
+			StatusCode: resp.StatusCode,
+			Body:       string(respBody),
+			RetryAfter: ParseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+		}
+	}
+	return respBody, nil
+}
+
 // UpdateServerNIC updates a server's NIC configuration
 // This is used for IP failover - attaching/detaching static IPs
 type NICUpdateRequest struct {
@@ -197,7 +417,7 @@ func (c *Client) UpdateServerNICs(ctx context.Context, serverUUID string, nics [
 	}
 
 	if resp.StatusCode >= 400 {
-		return fmt.Errorf("API error %d: %s", resp.StatusCode, string(respBody))
+		return &APIError{StatusCode: resp.StatusCode, Body: string(respBody)}
 	}
 
 	klog.Infof("NICs updated successfully for server %s", serverUUID)
@@ -248,3 +468,70 @@ func (c *Client) GetServerNICs(ctx context.Context, serverUUID string) ([]clouds
 	return server.NICs, nil
 }
 
+// SyncServerMeta makes the server meta keys starting with prefix exactly those in managed, leaving
+// all other keys alone. Meta changes apply to running servers, so nothing is restarted. It reports
+// whether the server was updated.
+func (c *Client) SyncServerMeta(ctx context.Context, serverUUID, prefix string, managed map[string]string) (bool, error) {
+	server, err := c.getServerForUpdate(ctx, serverUUID)
+	if err != nil {
+		return false, err
+	}
+	meta, _ := server["meta"].(map[string]interface{})
+	if meta == nil {
+		meta = map[string]interface{}{}
+	}
+
+	changed := false
+	for key := range meta {
+		if _, ok := managed[key]; strings.HasPrefix(key, prefix) && !ok {
+			delete(meta, key)
+			changed = true
+		}
+	}
+	for key, value := range managed {
+		if current, ok := meta[key].(string); !ok || current != value {
+			meta[key] = value
+			changed = true
+		}
+	}
+	if !changed {
+		return false, nil
+	}
+
+	server["meta"] = meta
+	if err := c.putServer(ctx, serverUUID, server); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// vncActionResponse is the response of the open_vnc server action
+type vncActionResponse struct {
+	Action string `json:"action"`
+	Result string `json:"result"`
+	VNCURL string `json:"vnc_url"`
+}
+
+// OpenVNCTunnel opens a VNC tunnel to the server console and returns its URL. The tunnel stays
+// open until CloseVNCTunnel is called or the server stops; clients authenticate with the
+// server's VNC password.
+func (c *Client) OpenVNCTunnel(ctx context.Context, serverUUID string) (string, error) {
+	var resp vncActionResponse
+	if err := c.doDirectRequest(ctx, http.MethodPost, fmt.Sprintf("servers/%s/action/?do=open_vnc", serverUUID), nil, &resp); err != nil {
+		return "", fmt.Errorf("failed to open VNC tunnel: %w", err)
+	}
+	if resp.VNCURL == "" {
+		return "", fmt.Errorf("no VNC URL returned for server %s (result: %s)", serverUUID, resp.Result)
+	}
+	klog.Infof("Opened VNC tunnel for server %s", serverUUID)
+	return resp.VNCURL, nil
+}
+
+// CloseVNCTunnel closes the server's VNC tunnel
+func (c *Client) CloseVNCTunnel(ctx context.Context, serverUUID string) error {
+	if err := c.doDirectRequest(ctx, http.MethodPost, fmt.Sprintf("servers/%s/action/?do=close_vnc", serverUUID), nil, nil); err != nil {
+		return fmt.Errorf("failed to close VNC tunnel: %w", err)
+	}
+	klog.Infof("Closed VNC tunnel for server %s", serverUUID)
+	return nil
+}
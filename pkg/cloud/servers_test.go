@@ -0,0 +1,725 @@
+/*
+Copyright 2025 Kube-DC Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloud
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cloudsigma/cloudsigma-sdk-go/cloudsigma"
+
+	infrav1 "github.com/kube-dc/cluster-api-provider-cloudsigma/api/v1beta1"
+)
+
+// pagedHandler serves total objects built by makeObject in pages honouring limit/offset
+func pagedHandler(t *testing.T, total int, makeObject func(i int) interface{}) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+		if limit <= 0 {
+			t.Errorf("request without page limit: %s", r.URL)
+			limit = total
+		}
+
+		objects := []interface{}{}
+		for i := offset; i < total && i < offset+limit; i++ {
+			objects = append(objects, makeObject(i))
+		}
+		writeJSON(w, map[string]interface{}{
+			"meta":    cloudsigma.Meta{Limit: limit, Offset: offset, TotalCount: total},
+			"objects": objects,
+		})
+	}
+}
+
+func TestListServersPaginates(t *testing.T) {
+	const total = 2*listPageSize + 7
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/2.0/servers/detail/", pagedHandler(t, total, func(i int) interface{} {
+		return cloudsigma.Server{UUID: fmt.Sprintf("srv-%d", i), Name: fmt.Sprintf("server-%d", i)}
+	}))
+
+	c := newTestClient(t, mux)
+	servers, err := c.ListServers(context.Background())
+	if err != nil {
+		t.Fatalf("ListServers() error = %v", err)
+	}
+	if len(servers) != total {
+		t.Fatalf("ListServers() returned %d servers, want %d", len(servers), total)
+	}
+
+	// A server on the last page must be findable
+	server, err := c.FindServerByNameOrMeta(context.Background(), fmt.Sprintf("server-%d", total-1), "")
+	if err != nil {
+		t.Fatalf("FindServerByNameOrMeta() error = %v", err)
+	}
+	if server == nil {
+		t.Fatalf("FindServerByNameOrMeta() did not find server on last page")
+	}
+}
+
+func TestFindServerByName(t *testing.T) {
+	servers := []cloudsigma.Server{
+		{UUID: "srv-1", Name: "worker-0"},
+		{UUID: "srv-2", Name: "worker-1"},
+		{UUID: "srv-3", Name: "worker-1"},
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/2.0/servers/detail/", pagedHandler(t, len(servers), func(i int) interface{} {
+		return servers[i]
+	}))
+	c := newTestClient(t, mux)
+
+	tests := []struct {
+		name      string
+		find      string
+		wantUUID  string
+		wantError bool
+	}{
+		{name: "found", find: "worker-0", wantUUID: "srv-1"},
+		{name: "not found", find: "worker-2"},
+		{name: "multiple matches", find: "worker-1", wantError: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, err := c.FindServerByName(context.Background(), tt.find)
+			if tt.wantError {
+				if !IsDuplicateServerNameError(err) {
+					t.Fatalf("FindServerByName() error = %v, want DuplicateServerNameError", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("FindServerByName() error = %v", err)
+			}
+			gotUUID := ""
+			if server != nil {
+				gotUUID = server.UUID
+			}
+			if gotUUID != tt.wantUUID {
+				t.Errorf("FindServerByName() = %q, want %q", gotUUID, tt.wantUUID)
+			}
+		})
+	}
+
+	// The name fallback of FindServerByNameOrMeta is just as strict
+	server, err := c.FindServerByNameOrMeta(context.Background(), "worker-1", "")
+	if !IsDuplicateServerNameError(err) || server != nil {
+		t.Errorf("FindServerByNameOrMeta() = %v, %v, want DuplicateServerNameError", server, err)
+	}
+}
+
+func TestCreateServerBootstrapFormat(t *testing.T) {
+	tests := []struct {
+		name      string
+		format    BootstrapFormat
+		wantField string
+		absent    string
+	}{
+		{name: "default is cloud-init", format: "", wantField: "cloudinit-user-data", absent: "ignition"},
+		{name: "cloud-config", format: BootstrapFormatCloudConfig, wantField: "cloudinit-user-data", absent: "ignition"},
+		{name: "ignition", format: BootstrapFormatIgnition, wantField: "ignition", absent: "cloudinit-user-data"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got CustomServerCreateRequest
+			mux := http.NewServeMux()
+			mux.HandleFunc("/api/2.0/servers/", func(w http.ResponseWriter, r *http.Request) {
+				if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+					t.Errorf("failed to decode request: %v", err)
+				}
+				writeJSON(w, map[string]interface{}{"objects": []cloudsigma.Server{{UUID: "srv-1", Name: "worker-0"}}})
+			})
+
+			c := newTestClient(t, mux)
+			_, err := c.CreateServer(context.Background(), ServerSpec{
+				Name:            "worker-0",
+				CPU:             2000,
+				Memory:          4096,
+				BootstrapData:   "ZGF0YQ==",
+				BootstrapFormat: tt.format,
+			})
+			if err != nil {
+				t.Fatalf("CreateServer() error = %v", err)
+			}
+
+			if len(got.Servers) != 1 {
+				t.Fatalf("expected 1 server in request, got %d", len(got.Servers))
+			}
+			meta := got.Servers[0].Meta
+			if meta[tt.wantField] != "ZGF0YQ==" {
+				t.Errorf("meta[%q] = %q, want bootstrap data", tt.wantField, meta[tt.wantField])
+			}
+			if meta["base64_fields"] != tt.wantField {
+				t.Errorf("meta[base64_fields] = %q, want %q", meta["base64_fields"], tt.wantField)
+			}
+			if _, ok := meta[tt.absent]; ok {
+				t.Errorf("meta[%q] should not be set", tt.absent)
+			}
+		})
+	}
+}
+
+func TestCreateServerCPUOptions(t *testing.T) {
+	cpuKeys := []string{"smp", "cpu_type", "enable_numa", "cpus_instead_of_cores", "hv_relaxed", "hv_tsc"}
+
+	tests := []struct {
+		name     string
+		smp      int
+		cpuModel string
+		cpuFlags []string
+		want     map[string]interface{}
+	}{
+		{name: "unset", want: map[string]interface{}{}},
+		{
+			name: "smp and model", smp: 4, cpuModel: "intel",
+			want: map[string]interface{}{"smp": float64(4), "cpu_type": "intel"},
+		},
+		{
+			name:     "flags",
+			cpuFlags: []string{infrav1.CPUFlagNUMA, infrav1.CPUFlagHVRelaxed, infrav1.CPUFlagHVTSC},
+			want:     map[string]interface{}{"enable_numa": true, "hv_relaxed": true, "hv_tsc": true},
+		},
+		{
+			name:     "cpus instead of cores",
+			smp:      2,
+			cpuFlags: []string{infrav1.CPUFlagCPUsInsteadOfCores},
+			want:     map[string]interface{}{"smp": float64(2), "cpus_instead_of_cores": true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got struct {
+				Objects []map[string]interface{} `json:"objects"`
+			}
+			mux := http.NewServeMux()
+			mux.HandleFunc("/api/2.0/servers/", func(w http.ResponseWriter, r *http.Request) {
+				if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+					t.Errorf("failed to decode request: %v", err)
+				}
+				writeJSON(w, map[string]interface{}{"objects": []cloudsigma.Server{{UUID: "srv-1", Name: "worker-0"}}})
+			})
+
+			c := newTestClient(t, mux)
+			_, err := c.CreateServer(context.Background(), ServerSpec{
+				Name:     "worker-0",
+				CPU:      4000,
+				Memory:   4096,
+				SMP:      tt.smp,
+				CPUModel: tt.cpuModel,
+				CPUFlags: tt.cpuFlags,
+			})
+			if err != nil {
+				t.Fatalf("CreateServer() error = %v", err)
+			}
+			if len(got.Objects) != 1 {
+				t.Fatalf("expected 1 server in request, got %d", len(got.Objects))
+			}
+
+			server := got.Objects[0]
+			for _, key := range cpuKeys {
+				value, ok := server[key]
+				want, wantOK := tt.want[key]
+				if ok != wantOK || value != want {
+					t.Errorf("request %s = %v (set %v), want %v (set %v)", key, value, ok, want, wantOK)
+				}
+			}
+		})
+	}
+}
+
+func TestParseBootstrapFormat(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    BootstrapFormat
+		wantErr bool
+	}{
+		{in: "", want: BootstrapFormatCloudConfig},
+		{in: "cloud-config", want: BootstrapFormatCloudConfig},
+		{in: "ignition", want: BootstrapFormatIgnition},
+		{in: "butane", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseBootstrapFormat(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseBootstrapFormat(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseBootstrapFormat(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestValidateServerMeta(t *testing.T) {
+	tests := []struct {
+		name          string
+		meta          map[string]string
+		bootstrapData string
+		wantErr       string
+	}{
+		{name: "empty", meta: nil},
+		{name: "user keys", meta: map[string]string{"machine-uid": "abc", "team": "storage"}, bootstrapData: "ZGF0YQ=="},
+		{name: "reserved cloud-init key", meta: map[string]string{"cloudinit-user-data": "x"}, wantErr: `"cloudinit-user-data" is reserved`},
+		{name: "reserved base64_fields", meta: map[string]string{"base64_fields": "x"}, wantErr: `"base64_fields" is reserved`},
+		{name: "reserved ssh key", meta: map[string]string{"ssh_public_key": "ssh-ed25519 AAAA"}, wantErr: `"ssh_public_key" is reserved`},
+		{name: "oversize user meta", meta: map[string]string{"blob": strings.Repeat("a", MaxServerMetaBytes)}, wantErr: "limit is"},
+		{name: "oversize bootstrap data", bootstrapData: strings.Repeat("a", MaxServerMetaBytes), wantErr: "including bootstrap data"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateServerMeta(tt.meta, tt.bootstrapData)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("ValidateServerMeta() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("ValidateServerMeta() error = %v, want containing %q", err, tt.wantErr)
+			}
+			if !IsTerminalError(err) {
+				t.Errorf("validation error should be terminal: %v", err)
+			}
+		})
+	}
+}
+
+func TestCreateServerRejectsInvalidMeta(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected API call %s %s", r.Method, r.URL.Path)
+	})
+
+	c := newTestClient(t, mux)
+	_, err := c.CreateServer(context.Background(), ServerSpec{
+		Name:  "worker-0",
+		Disks: []infrav1.CloudSigmaDisk{{UUID: "image", BootOrder: 1, Size: 10}},
+		Meta:  map[string]string{"base64_fields": "x"},
+	})
+	if err == nil {
+		t.Fatal("expected CreateServer to reject reserved meta key")
+	}
+}
+
+func TestDiskDeviceChannels(t *testing.T) {
+	tests := []struct {
+		name      string
+		bootOrder []int
+		want      []string
+		wantErr   bool
+	}{
+		{name: "boot disk only", bootOrder: []int{1}, want: []string{"0:0"}},
+		{name: "boot disk after data disks", bootOrder: []int{0, 0, 1}, want: []string{"0:2", "1:0", "0:0"}},
+		{name: "secondary bootable disk is not at a boot channel", bootOrder: []int{1, 2, 0}, want: []string{"0:0", "0:2", "1:0"}},
+		{name: "data disks past controller 0", bootOrder: []int{1, 0, 0, 0, 0}, want: []string{"0:0", "0:2", "1:0", "1:1", "1:2"}},
+		{name: "no boot disk keeps 0:0 free", bootOrder: []int{0, 3}, want: []string{"0:2", "1:0"}},
+		{name: "two boot disks", bootOrder: []int{1, 0, 1}, wantErr: true},
+	}
+
+	noClone := false
+	shared := []infrav1.CloudSigmaDisk{{UUID: "dataset", Device: "virtio", BootOrder: 1, Clone: &noClone}}
+	if _, err := DiskDeviceChannels(shared); err == nil || !IsTerminalError(err) {
+		t.Errorf("DiskDeviceChannels() with a shared boot disk error = %v, want a terminal error", err)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			disks := make([]infrav1.CloudSigmaDisk, 0, len(tt.bootOrder))
+			for _, order := range tt.bootOrder {
+				disks = append(disks, infrav1.CloudSigmaDisk{UUID: "image", Device: "virtio", BootOrder: order})
+			}
+
+			got, err := DiskDeviceChannels(disks)
+			if tt.wantErr {
+				if err == nil || !IsTerminalError(err) {
+					t.Fatalf("DiskDeviceChannels() error = %v, want a terminal error", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("DiskDeviceChannels() error = %v", err)
+			}
+			if strings.Join(got, " ") != strings.Join(tt.want, " ") {
+				t.Errorf("DiskDeviceChannels() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCreateServerDriveChannels(t *testing.T) {
+	var got CustomServerCreateRequest
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/2.0/drives/", func(w http.ResponseWriter, r *http.Request) {
+		// Clone: /api/2.0/drives/<source>/action/?do=clone
+		var req cloudsigma.DriveCloneRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		writeJSON(w, map[string]interface{}{"objects": []cloudsigma.Drive{{UUID: req.Drive.Name, Name: req.Drive.Name, Status: "unmounted"}}})
+	})
+	mux.HandleFunc("/api/2.0/servers/", func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("failed to decode request: %v", err)
+		}
+		writeJSON(w, map[string]interface{}{"objects": []cloudsigma.Server{{UUID: "srv-1", Name: "worker-0"}}})
+	})
+
+	c := newTestClient(t, mux)
+	_, err := c.CreateServer(context.Background(), ServerSpec{
+		Name:   "worker-0",
+		CPU:    2000,
+		Memory: 4096,
+		Disks: []infrav1.CloudSigmaDisk{
+			{UUID: "data-image", Device: "virtio", BootOrder: 0},
+			{UUID: "os-image", Device: "virtio", BootOrder: 1},
+		},
+	})
+	if err != nil {
+		t.Fatalf("CreateServer() error = %v", err)
+	}
+
+	if len(got.Servers) != 1 {
+		t.Fatalf("expected 1 server in request, got %d", len(got.Servers))
+	}
+	want := []CustomServerDrive{
+		{BootOrder: 0, DevChannel: "0:2", Device: "virtio", Drive: "worker-0-drive-0"},
+		{BootOrder: 1, DevChannel: "0:0", Device: "virtio", Drive: "worker-0-drive-1"},
+	}
+	if !reflect.DeepEqual(got.Servers[0].Drives, want) {
+		t.Errorf("server drives = %+v, want %+v", got.Servers[0].Drives, want)
+	}
+}
+
+func TestServerOwnedDrives(t *testing.T) {
+	now := time.Now()
+	server := cloudsigma.Server{UUID: "srv-0", Name: "worker-0"}
+	mounted := func(uuids ...string) []cloudsigma.ResourceLink {
+		var links []cloudsigma.ResourceLink
+		for _, uuid := range uuids {
+			links = append(links, cloudsigma.ResourceLink{UUID: uuid})
+		}
+		return links
+	}
+	drives := []cloudsigma.Drive{
+		{UUID: "boot", Name: "worker-0-drive-0", Meta: cloneMeta("worker-0", now), MountedOn: mounted("srv-0")},
+		{UUID: "dataset", Name: "shared-dataset", MountedOn: mounted("srv-0", "srv-1")},
+		{UUID: "pvc", Name: "pvc-0a1b2c", MountedOn: mounted("srv-0")},
+		{UUID: "other", Name: "worker-01-drive-0", Meta: cloneMeta("worker-01", now), MountedOn: mounted("srv-0")},
+		{UUID: "lookalike", Name: "worker-0-drive-1", MountedOn: mounted("srv-0")},
+		{UUID: "multimounted", Name: "worker-0-drive-2", Meta: cloneMeta("worker-0", now), MountedOn: mounted("srv-0", "srv-1")},
+		{UUID: "data", Name: "worker-0-drive-3", Meta: cloneMeta("worker-0", now), MountedOn: mounted("srv-0")},
+	}
+
+	got := ServerOwnedDrives(server, drives)
+	if want := []string{"boot", "data"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ServerOwnedDrives() = %v, want %v", got, want)
+	}
+}
+
+func TestCreateServerNICs(t *testing.T) {
+	var got CustomServerCreateRequest
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/2.0/servers/", func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("failed to decode request: %v", err)
+		}
+		writeJSON(w, map[string]interface{}{"objects": []cloudsigma.Server{{UUID: "srv-1", Name: "cp-0"}}})
+	})
+
+	c := newTestClient(t, mux)
+	_, err := c.CreateServer(context.Background(), ServerSpec{
+		Name:   "cp-0",
+		CPU:    2000,
+		Memory: 4096,
+		NICs: []infrav1.CloudSigmaNIC{
+			{IPv4Conf: infrav1.CloudSigmaIPConf{Conf: "static", IP: &infrav1.CloudSigmaIPRef{UUID: "public-ip"}}},
+			{VLAN: "vlan-uuid", IPv4Conf: infrav1.CloudSigmaIPConf{Conf: "static", IP: &infrav1.CloudSigmaIPRef{UUID: "vlan-ip"}}},
+			{},
+		},
+	})
+	if err != nil {
+		t.Fatalf("CreateServer() error = %v", err)
+	}
+
+	if len(got.Servers) != 1 {
+		t.Fatalf("expected 1 server in request, got %d", len(got.Servers))
+	}
+	want := []CustomServerNIC{
+		{IPv4Conf: &CustomIPv4Conf{Conf: "static", IP: &CustomIPRef{UUID: "public-ip"}}},
+		{VLAN: "vlan-uuid", IPv4Conf: &CustomIPv4Conf{Conf: "static", IP: &CustomIPRef{UUID: "vlan-ip"}}},
+		{IPv4Conf: &CustomIPv4Conf{Conf: "dhcp"}},
+	}
+	if !reflect.DeepEqual(got.Servers[0].NICs, want) {
+		t.Errorf("server NICs = %+v, want %+v", got.Servers[0].NICs, want)
+	}
+}
+
+func TestBuildServerNICs(t *testing.T) {
+	dhcp := CustomServerNIC{IPv4Conf: &CustomIPv4Conf{Conf: "dhcp"}}
+	staticIP := &infrav1.CloudSigmaIPRef{UUID: "ip-uuid"}
+
+	tests := []struct {
+		name string
+		nics []infrav1.CloudSigmaNIC
+		want []CustomServerNIC
+	}{
+		{name: "no NICs", want: []CustomServerNIC{dhcp}},
+		{name: "empty NIC", nics: []infrav1.CloudSigmaNIC{{}}, want: []CustomServerNIC{dhcp}},
+		{
+			name: "VLAN static",
+			nics: []infrav1.CloudSigmaNIC{{VLAN: "vlan-uuid", IPv4Conf: infrav1.CloudSigmaIPConf{Conf: "static", IP: staticIP}}},
+			want: []CustomServerNIC{{VLAN: "vlan-uuid", IPv4Conf: &CustomIPv4Conf{Conf: "static", IP: &CustomIPRef{UUID: "ip-uuid"}}}},
+		},
+		{
+			name: "VLAN dhcp",
+			nics: []infrav1.CloudSigmaNIC{{VLAN: "vlan-uuid", IPv4Conf: infrav1.CloudSigmaIPConf{Conf: "dhcp"}}},
+			want: []CustomServerNIC{{VLAN: "vlan-uuid", IPv4Conf: &CustomIPv4Conf{Conf: "dhcp"}}},
+		},
+		{
+			name: "VLAN without IPv4 config",
+			nics: []infrav1.CloudSigmaNIC{{VLAN: "vlan-uuid"}},
+			want: []CustomServerNIC{{VLAN: "vlan-uuid"}},
+		},
+		{
+			name: "VLAN ignores empty IP reference",
+			nics: []infrav1.CloudSigmaNIC{{VLAN: "vlan-uuid", IPv4Conf: infrav1.CloudSigmaIPConf{Conf: "manual", IP: &infrav1.CloudSigmaIPRef{}}}},
+			want: []CustomServerNIC{{VLAN: "vlan-uuid", IPv4Conf: &CustomIPv4Conf{Conf: "manual"}}},
+		},
+		{
+			name: "no VLAN dhcp",
+			nics: []infrav1.CloudSigmaNIC{{IPv4Conf: infrav1.CloudSigmaIPConf{Conf: "dhcp"}}},
+			want: []CustomServerNIC{dhcp},
+		},
+		{
+			name: "no VLAN static",
+			nics: []infrav1.CloudSigmaNIC{{IPv4Conf: infrav1.CloudSigmaIPConf{Conf: "static", IP: staticIP}}},
+			want: []CustomServerNIC{{IPv4Conf: &CustomIPv4Conf{Conf: "static", IP: &CustomIPRef{UUID: "ip-uuid"}}}},
+		},
+		{
+			name: "no VLAN static without IP falls back to dhcp",
+			nics: []infrav1.CloudSigmaNIC{{IPv4Conf: infrav1.CloudSigmaIPConf{Conf: "static"}}},
+			want: []CustomServerNIC{dhcp},
+		},
+		{
+			name: "order is kept",
+			nics: []infrav1.CloudSigmaNIC{
+				{VLAN: "vlan-uuid", IPv4Conf: infrav1.CloudSigmaIPConf{Conf: "dhcp"}},
+				{},
+			},
+			want: []CustomServerNIC{{VLAN: "vlan-uuid", IPv4Conf: &CustomIPv4Conf{Conf: "dhcp"}}, dhcp},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := buildServerNICs(tt.nics); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("buildServerNICs() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildServerMeta(t *testing.T) {
+	tests := []struct {
+		name string
+		spec ServerSpec
+		want map[string]string
+	}{
+		{name: "nothing to set"},
+		{
+			name: "cloud-config",
+			spec: ServerSpec{BootstrapData: "dXNlci1kYXRh"},
+			want: map[string]string{"base64_fields": "cloudinit-user-data", "cloudinit-user-data": "dXNlci1kYXRh"},
+		},
+		{
+			name: "ignition",
+			spec: ServerSpec{BootstrapData: "aWduaXRpb24=", BootstrapFormat: BootstrapFormatIgnition},
+			want: map[string]string{"base64_fields": "ignition", "ignition": "aWduaXRpb24="},
+		},
+		{
+			name: "user meta only",
+			spec: ServerSpec{Meta: map[string]string{"machine-uid": "uid-1"}},
+			want: map[string]string{"machine-uid": "uid-1"},
+		},
+		{
+			name: "bootstrap data and user meta",
+			spec: ServerSpec{BootstrapData: "dXNlci1kYXRh", Meta: map[string]string{"machine-uid": "uid-1"}},
+			want: map[string]string{"base64_fields": "cloudinit-user-data", "cloudinit-user-data": "dXNlci1kYXRh", "machine-uid": "uid-1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := buildServerMeta(tt.spec); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("buildServerMeta() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildCustomServer(t *testing.T) {
+	spec := ServerSpec{
+		Name:        "cp-0",
+		CPU:         2000,
+		Memory:      4096,
+		VNCPassword: "console",
+		Disks: []infrav1.CloudSigmaDisk{
+			{UUID: "data-image", Device: "virtio"},
+			{UUID: "boot-image", Device: "virtio", BootOrder: 1},
+		},
+		BootstrapData: "dXNlci1kYXRh",
+		Tags:          []string{"env:prod", "team:platform"},
+	}
+
+	got, err := buildCustomServer(spec, []string{"data-clone", "boot-clone"})
+	if err != nil {
+		t.Fatalf("buildCustomServer() error = %v", err)
+	}
+	want := &CustomServer{
+		Name:        "cp-0",
+		CPU:         2000,
+		Memory:      4096 * 1024 * 1024,
+		VNCPassword: "console",
+		Drives: []CustomServerDrive{
+			{DevChannel: "0:2", Device: "virtio", Drive: "data-clone"},
+			{BootOrder: 1, DevChannel: "0:0", Device: "virtio", Drive: "boot-clone"},
+		},
+		NICs: []CustomServerNIC{{IPv4Conf: &CustomIPv4Conf{Conf: "dhcp"}}},
+		Meta: map[string]string{"base64_fields": "cloudinit-user-data", "cloudinit-user-data": "dXNlci1kYXRh"},
+		Tags: []string{"env:prod", "team:platform"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("buildCustomServer() = %+v, want %+v", got, want)
+	}
+}
+
+func TestCreateServerTags(t *testing.T) {
+	var tagged []string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/2.0/drives/", func(w http.ResponseWriter, r *http.Request) {
+		var req cloudsigma.DriveCloneRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		writeJSON(w, map[string]interface{}{"objects": []cloudsigma.Drive{{UUID: req.Drive.Name, Name: req.Drive.Name, Status: "unmounted"}}})
+	})
+	mux.HandleFunc("/api/2.0/servers/", func(w http.ResponseWriter, r *http.Request) {
+		var raw map[string][]map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&raw); err != nil {
+			t.Errorf("failed to decode request: %v", err)
+		}
+		if _, ok := raw["objects"][0]["tags"]; ok {
+			t.Errorf("server create request has tags: %v", raw["objects"][0]["tags"])
+		}
+		writeJSON(w, map[string]interface{}{"objects": []cloudsigma.Server{{UUID: "srv-1", Name: "worker-0"}}})
+	})
+	mux.HandleFunc("/api/2.0/tags/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			var req cloudsigma.TagCreateRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				t.Errorf("failed to decode tag request: %v", err)
+			}
+			for _, tag := range req.Tags {
+				if len(tag.Resources) != 1 || tag.Resources[0].UUID != "srv-1" {
+					t.Errorf("tag %s resources = %+v, want srv-1", tag.Name, tag.Resources)
+				}
+				tagged = append(tagged, tag.Name)
+			}
+			writeJSON(w, map[string]interface{}{"objects": req.Tags})
+			return
+		}
+		writeJSON(w, map[string]interface{}{"objects": []cloudsigma.Tag{}})
+	})
+
+	c := newTestClient(t, mux)
+	_, err := c.CreateServer(context.Background(), ServerSpec{
+		Name:        "worker-0",
+		CPU:         2000,
+		Memory:      4096,
+		VNCPassword: "console",
+		Disks:       []infrav1.CloudSigmaDisk{{UUID: "os-image", Device: "virtio", BootOrder: 1}},
+		Tags:        []string{"env:prod", "team:platform"},
+	})
+	if err != nil {
+		t.Fatalf("CreateServer() error = %v", err)
+	}
+	if want := []string{"env:prod", "team:platform"}; !reflect.DeepEqual(tagged, want) {
+		t.Errorf("tagged server with %v, want %v", tagged, want)
+	}
+}
+
+func TestBuildCustomServerErrors(t *testing.T) {
+	valid := ServerSpec{
+		Name:        "cp-0",
+		VNCPassword: "console",
+		Disks:       []infrav1.CloudSigmaDisk{{UUID: "image", BootOrder: 1}},
+	}
+
+	tests := []struct {
+		name         string
+		mutate       func(spec *ServerSpec)
+		clonedDrives []string
+		wantTerminal bool
+	}{
+		{name: "missing cloned drive", clonedDrives: []string{}},
+		{name: "extra cloned drive", clonedDrives: []string{"clone-0", "clone-1"}},
+		{name: "no VNC password", mutate: func(spec *ServerSpec) { spec.VNCPassword = "" }},
+		{
+			name:         "reserved meta key",
+			mutate:       func(spec *ServerSpec) { spec.Meta = map[string]string{"cloudinit-user-data": "x"} },
+			wantTerminal: true,
+		},
+		{
+			name: "two boot disks",
+			mutate: func(spec *ServerSpec) {
+				spec.Disks = []infrav1.CloudSigmaDisk{{UUID: "a", BootOrder: 1}, {UUID: "b", BootOrder: 1}}
+			},
+			clonedDrives: []string{"clone-0", "clone-1"},
+			wantTerminal: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec := valid
+			if tt.mutate != nil {
+				tt.mutate(&spec)
+			}
+			clonedDrives := tt.clonedDrives
+			if clonedDrives == nil {
+				clonedDrives = []string{"clone-0"}
+			}
+
+			server, err := buildCustomServer(spec, clonedDrives)
+			if err == nil {
+				t.Fatalf("buildCustomServer() = %+v, want an error", server)
+			}
+			if IsTerminalError(err) != tt.wantTerminal {
+				t.Errorf("IsTerminalError(%v) = %v, want %v", err, !tt.wantTerminal, tt.wantTerminal)
+			}
+		})
+	}
+}
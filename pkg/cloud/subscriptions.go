@@ -0,0 +1,69 @@
+/*
+Copyright 2025 Kube-DC Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloud
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/cloudsigma/cloudsigma-sdk-go/cloudsigma"
+	"k8s.io/klog/v2"
+)
+
+// PurchaseIPSubscription buys a subscription for one static IP, billed per period (e.g. "1 month")
+// and renewed automatically, and returns the IP CloudSigma assigned to it. This spends money on
+// the account: callers must gate and cap it.
+func (c *Client) PurchaseIPSubscription(ctx context.Context, period string) (string, error) {
+	klog.V(2).Infof("Purchasing IP subscription for %s", period)
+
+	payload := cloudsigma.SubscriptionCreateRequest{Subscriptions: []cloudsigma.Subscription{
+		{Amount: "1", Period: period, Resource: "ip", AutoRenew: true},
+	}}
+	var result cloudsigma.SubscriptionCreateRequest
+	if err := c.doDirectRequest(ctx, http.MethodPost, "subscriptions/", payload, &result); err != nil {
+		return "", fmt.Errorf("failed to purchase IP subscription: %w", err)
+	}
+	if len(result.Subscriptions) == 0 || result.Subscriptions[0].SubscribedObject == "" {
+		return "", fmt.Errorf("IP subscription purchased, but CloudSigma returned no IP for it")
+	}
+
+	sub := result.Subscriptions[0]
+	klog.V(2).Infof("Purchased IP %s (subscription %s)", sub.SubscribedObject, sub.ID)
+	return sub.SubscribedObject, nil
+}
+
+// StopIPSubscriptionRenewal turns off the automatic renewal of the subscription ipUUID was
+// purchased under, so the IP goes back to CloudSigma when the paid period ends; subscriptions
+// can't be cancelled outright. An IP that no longer exists or has no subscription is left alone.
+func (c *Client) StopIPSubscriptionRenewal(ctx context.Context, ipUUID string) error {
+	ip, err := c.getIPDetail(ctx, ipUUID)
+	if err != nil {
+		return err
+	}
+	if ip == nil || ip.Subscription == nil {
+		klog.V(2).Infof("IP %s has no subscription, nothing to stop", ipUUID)
+		return nil
+	}
+
+	payload := map[string]interface{}{"auto_renew": false}
+	if err := c.doDirectRequest(ctx, http.MethodPut, fmt.Sprintf("subscriptions/%d/", ip.Subscription.ID), payload, nil); err != nil {
+		return fmt.Errorf("failed to stop renewal of subscription %d of IP %s: %w", ip.Subscription.ID, ipUUID, err)
+	}
+	klog.V(2).Infof("Stopped renewal of subscription %d of IP %s", ip.Subscription.ID, ipUUID)
+	return nil
+}
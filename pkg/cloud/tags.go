@@ -19,12 +19,17 @@ package cloud
 import (
 	"context"
 	"fmt"
+	"net/http"
+	"net/url"
 	"strings"
 
 	"github.com/cloudsigma/cloudsigma-sdk-go/cloudsigma"
 	"k8s.io/klog/v2"
 )
 
+// ManagedByTag marks CloudSigma resources created and owned by this provider
+const ManagedByTag = "managed-by:cloudsigma-capcs"
+
 // TagServer adds tags to a server in CloudSigma for tracking which cluster/pool owns it.
 // Tags: cluster:<name>, pool:<name>, managed-by:cloudsigma-capcs
 func (c *Client) TagServer(ctx context.Context, serverUUID, clusterName, poolName string) {
@@ -34,7 +39,7 @@ func (c *Client) TagServer(ctx context.Context, serverUUID, clusterName, poolNam
 	}
 
 	desiredTags := []string{
-		"managed-by:cloudsigma-capcs",
+		ManagedByTag,
 	}
 	if clusterName != "" {
 		desiredTags = append(desiredTags, fmt.Sprintf("cluster:%s", clusterName))
@@ -59,9 +64,71 @@ func (c *Client) UntagServer(ctx context.Context, serverUUID string) {
 		return
 	}
 
+	c.untagResource(ctx, serverUUID)
+	klog.Infof("Untagged server %s from all CAPCS-managed tags", serverUUID)
+}
+
+// isManagedResource reports whether a resource carries the managed-by:cloudsigma-capcs tag.
+func (c *Client) isManagedResource(ctx context.Context, resourceUUID string) (bool, error) {
+	tags, _, err := c.sdk.Tags.List(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to list tags: %w", err)
+	}
+
+	for _, tag := range tags {
+		if tag.Name != ManagedByTag {
+			continue
+		}
+		for _, r := range tag.Resources {
+			if r.UUID == resourceUUID {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// IsClusterServer reports whether a server carries the managed-by:cloudsigma-capcs tag and, when
+// clusterName is set, the cluster:<clusterName> tag - i.e. whether this provider created it for
+// that cluster and may safely adopt it.
+func (c *Client) IsClusterServer(ctx context.Context, serverUUID, clusterName string) (bool, error) {
+	if c.sdk == nil {
+		return false, fmt.Errorf("CloudSigma SDK client not initialized")
+	}
+
+	tags, _, err := c.sdk.Tags.List(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to list tags: %w", err)
+	}
+
+	required := map[string]bool{ManagedByTag: false}
+	if clusterName != "" {
+		required[fmt.Sprintf("cluster:%s", clusterName)] = false
+	}
+	for _, tag := range tags {
+		if _, ok := required[tag.Name]; !ok {
+			continue
+		}
+		for _, r := range tag.Resources {
+			if r.UUID == serverUUID {
+				required[tag.Name] = true
+				break
+			}
+		}
+	}
+	for _, tagged := range required {
+		if !tagged {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// untagResource removes a resource from all CAPCS-managed tags in CloudSigma.
+func (c *Client) untagResource(ctx context.Context, resourceUUID string) {
 	tags, _, err := c.sdk.Tags.List(ctx)
 	if err != nil {
-		klog.Warningf("Failed to list tags for server cleanup %s: %v", serverUUID, err)
+		klog.Warningf("Failed to list tags for cleanup of %s: %v", resourceUUID, err)
 		return
 	}
 
@@ -73,7 +140,7 @@ func (c *Client) UntagServer(ctx context.Context, serverUUID string) {
 		var newResources []cloudsigma.TagResource
 		found := false
 		for _, r := range tag.Resources {
-			if r.UUID == serverUUID {
+			if r.UUID == resourceUUID {
 				found = true
 			} else {
 				newResources = append(newResources, r)
@@ -92,13 +159,11 @@ func (c *Client) UntagServer(ctx context.Context, serverUUID string) {
 		}
 		_, _, err := c.sdk.Tags.Update(ctx, tag.UUID, updateReq)
 		if err != nil {
-			klog.Warningf("Failed to remove server %s from tag %s: %v", serverUUID, tag.Name, err)
+			klog.Warningf("Failed to remove %s from tag %s: %v", resourceUUID, tag.Name, err)
 		} else {
-			klog.V(2).Infof("Removed server %s from tag %s", serverUUID, tag.Name)
+			klog.V(2).Infof("Removed %s from tag %s", resourceUUID, tag.Name)
 		}
 	}
-
-	klog.Infof("Untagged server %s from all CAPCS-managed tags", serverUUID)
 }
 
 // ensureTagWithResource creates a tag if it doesn't exist and adds the resource to it.
@@ -153,7 +218,101 @@ func (c *Client) ensureTagWithResource(ctx context.Context, tagName, resourceUUI
 
 // isCAPCSManagedTag checks if a tag name is managed by the CAPCS controller.
 func isCAPCSManagedTag(name string) bool {
-	return name == "managed-by:cloudsigma-capcs" ||
+	return name == ManagedByTag ||
 		strings.HasPrefix(name, "cluster:") ||
 		strings.HasPrefix(name, "pool:")
 }
+
+// tagWrite is the body of a tag create or update. Unlike cloudsigma.Tag it always sends resources,
+// so an update that removes a tag's last resource clears the list instead of leaving it unchanged.
+type tagWrite struct {
+	Name      string                 `json:"name"`
+	Resources []tagResourceRef       `json:"resources"`
+	Meta      map[string]interface{} `json:"meta,omitempty"`
+}
+
+type tagResourceRef struct {
+	UUID string `json:"uuid"`
+}
+
+func newTagWrite(tag cloudsigma.Tag) tagWrite {
+	w := tagWrite{Name: tag.Name, Resources: make([]tagResourceRef, 0, len(tag.Resources)), Meta: tag.Meta}
+	for _, r := range tag.Resources {
+		w.Resources = append(w.Resources, tagResourceRef{UUID: r.UUID})
+	}
+	return w
+}
+
+// ListTags lists the tags matching query (e.g. name or name__startswith), or all tags for an
+// empty query. The filters only narrow the listing; callers still check names themselves.
+func (c *Client) ListTags(ctx context.Context, query url.Values) ([]cloudsigma.Tag, error) {
+	q := url.Values{"limit": {"0"}}
+	for key, values := range query {
+		q[key] = values
+	}
+	var result struct {
+		Objects []cloudsigma.Tag `json:"objects"`
+	}
+	if err := c.doDirectRequest(ctx, http.MethodGet, "tags/?"+q.Encode(), nil, &result); err != nil {
+		return nil, fmt.Errorf("failed to list tags: %w", err)
+	}
+	return result.Objects, nil
+}
+
+// CreateTag creates a tag with the name, resources and meta of tag
+func (c *Client) CreateTag(ctx context.Context, tag cloudsigma.Tag) error {
+	payload := map[string]interface{}{"objects": []tagWrite{newTagWrite(tag)}}
+	if err := c.doDirectRequest(ctx, http.MethodPost, "tags/", payload, nil); err != nil {
+		return fmt.Errorf("failed to create tag %s: %w", tag.Name, err)
+	}
+	return nil
+}
+
+// UpdateTag replaces the name, resources and meta of the tag with tag.UUID
+func (c *Client) UpdateTag(ctx context.Context, tag cloudsigma.Tag) error {
+	if err := c.doDirectRequest(ctx, http.MethodPut, fmt.Sprintf("tags/%s/", tag.UUID), newTagWrite(tag), nil); err != nil {
+		return fmt.Errorf("failed to update tag %s: %w", tag.Name, err)
+	}
+	return nil
+}
+
+// DeleteTag deletes a tag; the tagged resources are not affected
+func (c *Client) DeleteTag(ctx context.Context, uuid string) error {
+	if err := c.doDirectRequest(ctx, http.MethodDelete, fmt.Sprintf("tags/%s/", uuid), nil, nil); err != nil {
+		return fmt.Errorf("failed to delete tag %s: %w", uuid, err)
+	}
+	return nil
+}
+
+// AddResourceToTag adds resourceUUID to tag, as last listed by the caller. A tag without a UUID
+// does not exist yet and is created with just this resource. It is a no-op if the tag already
+// has the resource.
+func (c *Client) AddResourceToTag(ctx context.Context, tag cloudsigma.Tag, resourceUUID string) error {
+	if tag.UUID == "" {
+		tag.Resources = []cloudsigma.TagResource{{UUID: resourceUUID}}
+		return c.CreateTag(ctx, tag)
+	}
+	for _, r := range tag.Resources {
+		if r.UUID == resourceUUID {
+			return nil
+		}
+	}
+	tag.Resources = append(append([]cloudsigma.TagResource(nil), tag.Resources...), cloudsigma.TagResource{UUID: resourceUUID})
+	return c.UpdateTag(ctx, tag)
+}
+
+// RemoveResourceFromTag removes resourceUUID from tag, as last listed by the caller, keeping its
+// other resources. It is a no-op if the tag does not have the resource.
+func (c *Client) RemoveResourceFromTag(ctx context.Context, tag cloudsigma.Tag, resourceUUID string) error {
+	resources := make([]cloudsigma.TagResource, 0, len(tag.Resources))
+	for _, r := range tag.Resources {
+		if r.UUID != resourceUUID {
+			resources = append(resources, r)
+		}
+	}
+	if len(resources) == len(tag.Resources) {
+		return nil
+	}
+	tag.Resources = resources
+	return c.UpdateTag(ctx, tag)
+}
@@ -0,0 +1,297 @@
+/*
+Copyright 2025 Kube-DC Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloud
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/cloudsigma/cloudsigma-sdk-go/cloudsigma"
+)
+
+// tagRequest is one tag write seen by the test server
+type tagRequest struct {
+	Method string
+	Path   string
+	Body   map[string]interface{}
+}
+
+// newTagsTestClient returns a Client whose tag calls go to a server that lists tags and records writes
+func newTagsTestClient(t *testing.T, tags []cloudsigma.Tag) (*Client, *[]tagRequest, *url.Values) {
+	t.Helper()
+
+	var writes []tagRequest
+	var query url.Values
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/2.0/tags/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			query = r.URL.Query()
+			writeJSON(w, map[string]interface{}{"objects": tags})
+			return
+		}
+		req := tagRequest{Method: r.Method, Path: r.URL.Path}
+		_ = json.NewDecoder(r.Body).Decode(&req.Body)
+		writes = append(writes, req)
+		writeJSON(w, req.Body)
+	})
+	return newTestClient(t, mux), &writes, &query
+}
+
+// tagResourceUUIDs returns the resource UUIDs of a tag write body
+func tagResourceUUIDs(t *testing.T, body map[string]interface{}) []string {
+	t.Helper()
+	resources, ok := body["resources"].([]interface{})
+	if !ok {
+		t.Fatalf("tag write without a resources list: %v", body)
+	}
+	uuids := []string{}
+	for _, r := range resources {
+		uuids = append(uuids, r.(map[string]interface{})["uuid"].(string))
+	}
+	return uuids
+}
+
+func TestListTags(t *testing.T) {
+	c, _, query := newTagsTestClient(t, []cloudsigma.Tag{{UUID: "t1", Name: "service:default-web"}})
+
+	tags, err := c.ListTags(context.Background(), url.Values{"name__startswith": {"service:"}})
+	if err != nil {
+		t.Fatalf("ListTags() error = %v", err)
+	}
+	if len(tags) != 1 || tags[0].UUID != "t1" {
+		t.Errorf("ListTags() = %+v, want tag t1", tags)
+	}
+	if got := query.Encode(); got != "limit=0&name__startswith=service%3A" {
+		t.Errorf("ListTags() query = %q, want the filter with limit=0", got)
+	}
+}
+
+func TestCreateUpdateDeleteTag(t *testing.T) {
+	c, writes, _ := newTagsTestClient(t, nil)
+	ctx := context.Background()
+
+	tag := cloudsigma.Tag{
+		Name:      "lock:alpha:10.0.0.1",
+		Resources: []cloudsigma.TagResource{{UUID: "10.0.0.1"}},
+		Meta:      map[string]interface{}{"acquired": "2025-01-01T00:00:00Z"},
+	}
+	if err := c.CreateTag(ctx, tag); err != nil {
+		t.Fatalf("CreateTag() error = %v", err)
+	}
+	tag.UUID = "t1"
+	tag.Resources = nil
+	if err := c.UpdateTag(ctx, tag); err != nil {
+		t.Fatalf("UpdateTag() error = %v", err)
+	}
+	if err := c.DeleteTag(ctx, "t1"); err != nil {
+		t.Fatalf("DeleteTag() error = %v", err)
+	}
+
+	if len(*writes) != 3 {
+		t.Fatalf("tag writes = %+v, want create, update and delete", *writes)
+	}
+	create, update, del := (*writes)[0], (*writes)[1], (*writes)[2]
+
+	if create.Method != http.MethodPost || create.Path != "/api/2.0/tags/" {
+		t.Errorf("create sent %s %s, want POST /api/2.0/tags/", create.Method, create.Path)
+	}
+	objects, _ := create.Body["objects"].([]interface{})
+	if len(objects) != 1 {
+		t.Fatalf("create body = %v, want one object", create.Body)
+	}
+	created := objects[0].(map[string]interface{})
+	if got := tagResourceUUIDs(t, created); len(got) != 1 || got[0] != "10.0.0.1" {
+		t.Errorf("created tag resources = %v, want [10.0.0.1]", got)
+	}
+	if meta, _ := created["meta"].(map[string]interface{}); meta["acquired"] != "2025-01-01T00:00:00Z" {
+		t.Errorf("created tag meta = %v, want the acquired timestamp", created["meta"])
+	}
+
+	if update.Method != http.MethodPut || update.Path != "/api/2.0/tags/t1/" {
+		t.Errorf("update sent %s %s, want PUT /api/2.0/tags/t1/", update.Method, update.Path)
+	}
+	// An update without resources clears them rather than leaving them unchanged
+	if got := tagResourceUUIDs(t, update.Body); len(got) != 0 {
+		t.Errorf("updated tag resources = %v, want an empty list", got)
+	}
+
+	if del.Method != http.MethodDelete || del.Path != "/api/2.0/tags/t1/" {
+		t.Errorf("delete sent %s %s, want DELETE /api/2.0/tags/t1/", del.Method, del.Path)
+	}
+}
+
+func TestAddResourceToTag(t *testing.T) {
+	existing := cloudsigma.Tag{UUID: "t1", Name: "cluster:alpha", Resources: []cloudsigma.TagResource{{UUID: "ip-1"}}}
+
+	tests := []struct {
+		name          string
+		tag           cloudsigma.Tag
+		wantMethod    string
+		wantResources []string
+	}{
+		{
+			name:          "missing tag is created",
+			tag:           cloudsigma.Tag{Name: "cluster:alpha"},
+			wantMethod:    http.MethodPost,
+			wantResources: []string{"ip-2"},
+		},
+		{
+			name:          "existing tag keeps its resources",
+			tag:           existing,
+			wantMethod:    http.MethodPut,
+			wantResources: []string{"ip-1", "ip-2"},
+		},
+		{
+			name: "tag already has the resource",
+			tag:  cloudsigma.Tag{UUID: "t1", Name: "cluster:alpha", Resources: []cloudsigma.TagResource{{UUID: "ip-2"}}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, writes, _ := newTagsTestClient(t, nil)
+
+			if err := c.AddResourceToTag(context.Background(), tt.tag, "ip-2"); err != nil {
+				t.Fatalf("AddResourceToTag() error = %v", err)
+			}
+
+			if tt.wantMethod == "" {
+				if len(*writes) != 0 {
+					t.Errorf("tag writes = %+v, want none", *writes)
+				}
+				return
+			}
+			if len(*writes) != 1 || (*writes)[0].Method != tt.wantMethod {
+				t.Fatalf("tag writes = %+v, want one %s", *writes, tt.wantMethod)
+			}
+			body := (*writes)[0].Body
+			if tt.wantMethod == http.MethodPost {
+				body = body["objects"].([]interface{})[0].(map[string]interface{})
+			}
+			got := tagResourceUUIDs(t, body)
+			if len(got) != len(tt.wantResources) {
+				t.Fatalf("tag resources = %v, want %v", got, tt.wantResources)
+			}
+			for i := range got {
+				if got[i] != tt.wantResources[i] {
+					t.Errorf("tag resources = %v, want %v", got, tt.wantResources)
+				}
+			}
+		})
+	}
+
+	if len(existing.Resources) != 1 {
+		t.Errorf("AddResourceToTag() modified the caller's tag: %+v", existing)
+	}
+}
+
+func TestRemoveResourceFromTag(t *testing.T) {
+	c, writes, _ := newTagsTestClient(t, nil)
+	ctx := context.Background()
+	tag := cloudsigma.Tag{UUID: "t1", Name: "service:default-web", Resources: []cloudsigma.TagResource{{UUID: "ip-1"}, {UUID: "ip-2"}}}
+
+	if err := c.RemoveResourceFromTag(ctx, tag, "ip-3"); err != nil {
+		t.Fatalf("RemoveResourceFromTag() error = %v", err)
+	}
+	if len(*writes) != 0 {
+		t.Fatalf("removing an absent resource wrote %+v, want nothing", *writes)
+	}
+
+	if err := c.RemoveResourceFromTag(ctx, tag, "ip-1"); err != nil {
+		t.Fatalf("RemoveResourceFromTag() error = %v", err)
+	}
+	if len(*writes) != 1 || (*writes)[0].Method != http.MethodPut || (*writes)[0].Path != "/api/2.0/tags/t1/" {
+		t.Fatalf("tag writes = %+v, want one PUT /api/2.0/tags/t1/", *writes)
+	}
+	if got := tagResourceUUIDs(t, (*writes)[0].Body); len(got) != 1 || got[0] != "ip-2" {
+		t.Errorf("tag resources = %v, want [ip-2]", got)
+	}
+}
+
+// countingTokenSource hands out numbered tokens and counts invalidations
+type countingTokenSource struct {
+	issued      int
+	invalidated int
+}
+
+func (s *countingTokenSource) Token(context.Context) (string, error) {
+	s.issued++
+	return fmt.Sprintf("token-%d", s.issued), nil
+}
+
+func (s *countingTokenSource) Invalidate() { s.invalidated++ }
+
+func TestTokenSourceRetriesUnauthorizedOnce(t *testing.T) {
+	tests := []struct {
+		name            string
+		validToken      string
+		wantErr         bool
+		wantCalls       int
+		wantInvalidated int
+	}{
+		{name: "valid token", validToken: "token-1", wantCalls: 1},
+		{name: "revoked token is refreshed", validToken: "token-2", wantCalls: 2, wantInvalidated: 1},
+		{name: "second 401 is returned", validToken: "none", wantErr: true, wantCalls: 2, wantInvalidated: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls int
+			var bodies []string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls++
+				var body map[string]interface{}
+				_ = json.NewDecoder(r.Body).Decode(&body)
+				bodies = append(bodies, body["name"].(string))
+				if r.Header.Get("Authorization") != "Bearer "+tt.validToken {
+					w.WriteHeader(http.StatusUnauthorized)
+					return
+				}
+				writeJSON(w, body)
+			}))
+			defer server.Close()
+
+			source := &countingTokenSource{}
+			c, err := NewClientWithTokenSource(server.URL+"/api/2.0/", source)
+			if err != nil {
+				t.Fatalf("NewClientWithTokenSource() error = %v", err)
+			}
+
+			err = c.UpdateTag(context.Background(), cloudsigma.Tag{UUID: "t1", Name: "cluster:alpha"})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("UpdateTag() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr && StatusCodeFromError(err) != http.StatusUnauthorized {
+				t.Errorf("UpdateTag() error = %v, want the 401", err)
+			}
+			if calls != tt.wantCalls || source.invalidated != tt.wantInvalidated {
+				t.Errorf("API called %d times with %d invalidations, want %d and %d",
+					calls, source.invalidated, tt.wantCalls, tt.wantInvalidated)
+			}
+			for _, name := range bodies {
+				if name != "cluster:alpha" {
+					t.Errorf("request body name = %q, want the body resent on retry", name)
+				}
+			}
+		})
+	}
+}
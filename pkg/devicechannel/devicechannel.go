@@ -0,0 +1,116 @@
+/*
+Copyright 2025 Kube-DC Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package devicechannel allocates the device channels ("<controller>:<unit>") drives are
+// attached at on CloudSigma servers. It is shared by server creation and the CSI driver, so
+// drives attached at creation and volumes hotplugged later never collide.
+package devicechannel
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Boot is the channel reserved for a server's boot disk
+const Boot = "0:0"
+
+// maxController is the highest disk controller CloudSigma exposes
+const maxController = 202
+
+// Policy decides the order channels are handed out in. The layout CloudSigma accepts for
+// hotplugged drives has differed between regions and hypervisor versions, so the CSI driver
+// lets operators pick one.
+type Policy interface {
+	// Next returns the first channel of the policy's layout not in used
+	Next(used map[string]bool) string
+}
+
+// Names of the built-in policies
+const (
+	// PolicyDefault skips unit 3 everywhere and uses only unit 2 on controller 0:
+	// 0:2, 1:0, 1:1, 1:2, 2:0, ...
+	PolicyDefault = "default"
+
+	// PolicySkipController0 leaves controller 0 to the boot disk: 1:0, 1:1, 1:2, 2:0, ...
+	PolicySkipController0 = "skip-controller-0"
+
+	// PolicyFourUnits also uses unit 3, for hypervisors that expose it: 0:2, 0:3, 1:0, ..., 1:3, 2:0, ...
+	PolicyFourUnits = "four-units"
+)
+
+var policies = map[string]Policy{
+	PolicyDefault:         &layout{controller0Units: []int{2}, units: 3},
+	PolicySkipController0: &layout{units: 3},
+	PolicyFourUnits:       &layout{controller0Units: []int{2, 3}, units: 4},
+}
+
+// Default is the policy server creation uses and the CSI driver uses unless configured otherwise
+var Default = policies[PolicyDefault]
+
+// PolicyByName returns the built-in policy called name; an empty name selects the default
+func PolicyByName(name string) (Policy, error) {
+	if name == "" {
+		return Default, nil
+	}
+	policy, ok := policies[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown channel policy %q, must be one of: %s", name, strings.Join(PolicyNames(), ", "))
+	}
+	return policy, nil
+}
+
+// PolicyNames returns the names of the built-in policies, sorted
+func PolicyNames() []string {
+	names := make([]string, 0, len(policies))
+	for name := range policies {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// layout hands out the listed units of controller 0, then units 0 to units-1 of every further
+// controller. 0:0 is the boot disk and 0:1 is never used.
+type layout struct {
+	controller0Units []int
+	units            int
+}
+
+func (l *layout) Next(used map[string]bool) string {
+	for _, unit := range l.controller0Units {
+		if channel := fmt.Sprintf("0:%d", unit); !used[channel] {
+			return channel
+		}
+	}
+	for controller := 1; controller <= maxController; controller++ {
+		for unit := 0; unit < l.units; unit++ {
+			channel := fmt.Sprintf("%d:%d", controller, unit)
+			if !used[channel] {
+				return channel
+			}
+		}
+	}
+	// Fallback (should never reach here unless all slots are used!)
+	return fmt.Sprintf("%d:%d", maxController, l.units-1)
+}
+
+// Next returns the first channel not in used under the default policy. CloudSigma skips unit 3
+// on every controller, and on controller 0 only unit 2 is handed out (0:0 is the boot disk, 0:1
+// is left unused). This gives 0:2, then 1:0, 1:1, 1:2, then 2:0, 2:1, 2:2, and so on.
+func Next(used map[string]bool) string {
+	return Default.Next(used)
+}
@@ -0,0 +1,90 @@
+/*
+Copyright 2025 Kube-DC Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package devicechannel
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestNext(t *testing.T) {
+	tests := []struct {
+		name string
+		used []string
+		want string
+	}{
+		{name: "empty server", want: "0:2"},
+		{name: "boot disk only", used: []string{Boot}, want: "0:2"},
+		{name: "0:1 and 0:3 are skipped", used: []string{Boot, "0:2"}, want: "1:0"},
+		{name: "unit 3 is skipped", used: []string{"0:2", "1:0", "1:1", "1:2"}, want: "2:0"},
+		{name: "gaps are reused", used: []string{"0:2", "1:1"}, want: "1:0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			used := make(map[string]bool, len(tt.used))
+			for _, ch := range tt.used {
+				used[ch] = true
+			}
+			if got := Next(used); got != tt.want {
+				t.Errorf("Next(%v) = %q, want %q", tt.used, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPolicySequences(t *testing.T) {
+	tests := []struct {
+		policy string
+		want   []string
+	}{
+		{policy: PolicyDefault, want: []string{"0:2", "1:0", "1:1", "1:2", "2:0", "2:1", "2:2", "3:0"}},
+		{policy: PolicySkipController0, want: []string{"1:0", "1:1", "1:2", "2:0", "2:1", "2:2", "3:0", "3:1"}},
+		{policy: PolicyFourUnits, want: []string{"0:2", "0:3", "1:0", "1:1", "1:2", "1:3", "2:0", "2:1"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			policy, err := PolicyByName(tt.policy)
+			if err != nil {
+				t.Fatalf("PolicyByName(%q) error = %v", tt.policy, err)
+			}
+			used := map[string]bool{Boot: true}
+			var got []string
+			for range tt.want {
+				channel := policy.Next(used)
+				used[channel] = true
+				got = append(got, channel)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("channels = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPolicyByName(t *testing.T) {
+	if policy, err := PolicyByName(""); err != nil || policy != Default {
+		t.Errorf("PolicyByName(\"\") = %v, %v, want the default policy", policy, err)
+	}
+	if _, err := PolicyByName("round-robin"); err == nil {
+		t.Error("PolicyByName(\"round-robin\") succeeded, want an error")
+	}
+	if got, want := PolicyNames(), []string{PolicyDefault, PolicyFourUnits, PolicySkipController0}; !slices.Equal(got, want) {
+		t.Errorf("PolicyNames() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,55 @@
+/*
+Copyright 2025 Kube-DC Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package logging configures klog output for the CSI driver and CCM binaries.
+package logging
+
+import (
+	"fmt"
+	"io"
+
+	"go.uber.org/zap/zapcore"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+)
+
+const (
+	// FormatText is klog's default human-readable output
+	FormatText = "text"
+
+	// FormatJSON emits one JSON object per line, with klog key/value pairs as fields
+	FormatJSON = "json"
+)
+
+// Configure routes klog output according to format. In JSON mode klog hands every entry to a zap
+// logger writing to w; verbosity is still controlled by klog's -v flag.
+func Configure(format string, w io.Writer) error {
+	switch format {
+	case "", FormatText:
+		return nil
+	case FormatJSON:
+		logger := zap.New(
+			zap.WriteTo(w),
+			zap.JSONEncoder(),
+			// klog has already applied -v before handing entries over, so let every level through
+			zap.Level(zapcore.Level(-128)),
+		)
+		klog.SetLogger(logger)
+		return nil
+	default:
+		return fmt.Errorf("unsupported log format %q (want %q or %q)", format, FormatText, FormatJSON)
+	}
+}
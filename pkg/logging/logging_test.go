@@ -0,0 +1,67 @@
+/*
+Copyright 2025 Kube-DC Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package logging
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"k8s.io/klog/v2"
+)
+
+func TestConfigureJSON(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Configure(FormatJSON, &buf); err != nil {
+		t.Fatalf("Configure() error = %v", err)
+	}
+	defer klog.ClearLogger()
+
+	klog.InfoS("Attaching volume", "volumeId", "vol-1", "nodeId", "node-1")
+	klog.Infof("Service %s assigned", "default/web")
+	klog.Flush()
+
+	var lines []map[string]interface{}
+	scanner := bufio.NewScanner(&buf)
+	for scanner.Scan() {
+		var entry map[string]interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			t.Fatalf("line %q is not valid JSON: %v", scanner.Text(), err)
+		}
+		lines = append(lines, entry)
+	}
+	if len(lines) != 2 {
+		t.Fatalf("got %d log lines, want 2: %s", len(lines), buf.String())
+	}
+
+	if lines[0]["msg"] != "Attaching volume" || lines[0]["volumeId"] != "vol-1" || lines[0]["nodeId"] != "node-1" {
+		t.Errorf("structured entry = %v, want msg, volumeId and nodeId fields", lines[0])
+	}
+	if lines[1]["msg"] != "Service default/web assigned" {
+		t.Errorf("formatted entry = %v, want msg %q", lines[1], "Service default/web assigned")
+	}
+}
+
+func TestConfigureInvalidFormat(t *testing.T) {
+	if err := Configure("xml", &bytes.Buffer{}); err == nil {
+		t.Error("expected an unsupported format to be rejected")
+	}
+	if err := Configure(FormatText, &bytes.Buffer{}); err != nil {
+		t.Errorf("Configure(text) error = %v", err)
+	}
+}
@@ -0,0 +1,203 @@
+/*
+Copyright 2025 Kube-DC Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package regions resolves CloudSigma regions to the hosts serving them. Most regions follow the
+// <region>.cloudsigma.com pattern, with the service provider API at direct.<region>.cloudsigma.com;
+// regions that do not can be described with Override.
+package regions
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/cloudsigma/cloudsigma-sdk-go/cloudsigma"
+	"k8s.io/klog/v2"
+)
+
+// Default is the region used when none is configured
+const Default = "zrh"
+
+// Endpoints are the hosts serving a CloudSigma region
+type Endpoints struct {
+	// APIHost serves the public API, e.g. "zrh.cloudsigma.com"
+	APIHost string
+	// DirectHost serves the service provider API, which issues impersonated tokens and is the
+	// only host accepting them, e.g. "direct.zrh.cloudsigma.com"
+	DirectHost string
+}
+
+// APIEndpoint returns the public API root, e.g. "https://zrh.cloudsigma.com/api/2.0"
+func (e Endpoints) APIEndpoint() string {
+	return "https://" + e.APIHost + "/api/2.0"
+}
+
+// DirectAPIEndpoint returns the API root on the service provider host
+func (e Endpoints) DirectAPIEndpoint() string {
+	return "https://" + e.DirectHost + "/api/2.0"
+}
+
+// known lists the regions this provider is known to work with
+var known = map[string]Endpoints{
+	"zrh":  standard("zrh"),
+	"lvs":  standard("lvs"),
+	"sjc":  standard("sjc"),
+	"tyo":  standard("tyo"),
+	"next": standard("next"),
+}
+
+var (
+	mu        sync.RWMutex
+	overrides = map[string]Endpoints{}
+	warned    = map[string]bool{}
+)
+
+// standard returns the endpoints of a region following the <region>.cloudsigma.com pattern
+func standard(region string) Endpoints {
+	return Endpoints{
+		APIHost:    region + ".cloudsigma.com",
+		DirectHost: "direct." + region + ".cloudsigma.com",
+	}
+}
+
+// Lookup returns the endpoints of region: an override if set, then the known table, then the
+// <region>.cloudsigma.com pattern. An empty region is Default.
+func Lookup(region string) Endpoints {
+	if region == "" {
+		region = Default
+	}
+	mu.RLock()
+	defer mu.RUnlock()
+	if e, ok := overrides[region]; ok {
+		return e
+	}
+	if e, ok := known[region]; ok {
+		return e
+	}
+	return standard(region)
+}
+
+// Known reports whether region is in the known table or has been overridden
+func Known(region string) bool {
+	mu.RLock()
+	defer mu.RUnlock()
+	_, overridden := overrides[region]
+	_, ok := known[region]
+	return overridden || ok
+}
+
+// Names returns the known and overridden regions, sorted
+func Names() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	names := make([]string, 0, len(known)+len(overrides))
+	for region := range known {
+		names = append(names, region)
+	}
+	for region := range overrides {
+		if _, ok := known[region]; !ok {
+			names = append(names, region)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// WarnIfUnknown logs a warning, once per region, when region is neither known nor overridden
+func WarnIfUnknown(region string) {
+	if region == "" || Known(region) {
+		return
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if warned[region] {
+		return
+	}
+	warned[region] = true
+	e := standard(region)
+	klog.Warningf("Unknown CloudSigma region %q, assuming API host %s and service provider host %s; set --cloudsigma-region-endpoints if it uses other hosts",
+		region, e.APIHost, e.DirectHost)
+}
+
+// Override sets the endpoints of region, taking precedence over the known table
+func Override(region string, e Endpoints) {
+	mu.Lock()
+	defer mu.Unlock()
+	overrides[region] = e
+}
+
+// ParseOverrides parses a comma-separated list of region=api-host[|direct-host] entries, e.g.
+// "abc=api.abc.example.com|sp.abc.example.com". The direct host defaults to direct.<api-host>.
+func ParseOverrides(spec string) (map[string]Endpoints, error) {
+	result := map[string]Endpoints{}
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		region, hosts, ok := strings.Cut(entry, "=")
+		if !ok || region == "" || hosts == "" {
+			return nil, fmt.Errorf("invalid region endpoint %q, want region=api-host[|direct-host]", entry)
+		}
+		apiHost, directHost, _ := strings.Cut(hosts, "|")
+		if directHost == "" {
+			directHost = "direct." + apiHost
+		}
+		for _, host := range []string{apiHost, directHost} {
+			if host == "" || strings.ContainsAny(host, "/ ") {
+				return nil, fmt.Errorf("invalid host %q for region %s", host, region)
+			}
+		}
+		result[region] = Endpoints{APIHost: apiHost, DirectHost: directHost}
+	}
+	return result, nil
+}
+
+// ApplyOverrides parses spec with ParseOverrides and overrides every region in it
+func ApplyOverrides(spec string) error {
+	parsed, err := ParseOverrides(spec)
+	if err != nil {
+		return err
+	}
+	for region, e := range parsed {
+		Override(region, e)
+	}
+	return nil
+}
+
+// NewSDKClient creates an SDK client for the API at host. The SDK builds its URLs for
+// <location>.cloudsigma.com, so requests for any other host are redirected to it.
+func NewSDKClient(cred cloudsigma.CredentialsProvider, host string) *cloudsigma.Client {
+	if location, ok := strings.CutSuffix(host, ".cloudsigma.com"); ok {
+		return cloudsigma.NewClient(cred, cloudsigma.WithLocation(location))
+	}
+	return cloudsigma.NewClient(cred, cloudsigma.WithHTTPClient(&http.Client{Transport: &hostTransport{host: host}}))
+}
+
+// hostTransport sends requests to host over HTTPS
+type hostTransport struct {
+	host string
+}
+
+func (t *hostTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.URL.Scheme = "https"
+	req.URL.Host = t.host
+	req.Host = t.host
+	return http.DefaultTransport.RoundTrip(req)
+}
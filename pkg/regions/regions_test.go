@@ -0,0 +1,124 @@
+/*
+Copyright 2025 Kube-DC Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package regions
+
+import (
+	"strings"
+	"testing"
+)
+
+// withOverride overrides region for the duration of the test
+func withOverride(t *testing.T, region string, e Endpoints) {
+	t.Helper()
+	Override(region, e)
+	t.Cleanup(func() {
+		mu.Lock()
+		defer mu.Unlock()
+		delete(overrides, region)
+	})
+}
+
+func TestLookup(t *testing.T) {
+	withOverride(t, "abc", Endpoints{APIHost: "api.abc.example.com", DirectHost: "sp.abc.example.com"})
+	withOverride(t, "sjc", Endpoints{APIHost: "sjc.example.com", DirectHost: "direct.sjc.example.com"})
+
+	tests := []struct {
+		region     string
+		wantAPI    string
+		wantDirect string
+		wantKnown  bool
+	}{
+		{region: "zrh", wantAPI: "https://zrh.cloudsigma.com/api/2.0", wantDirect: "https://direct.zrh.cloudsigma.com/api/2.0", wantKnown: true},
+		{region: "next", wantAPI: "https://next.cloudsigma.com/api/2.0", wantDirect: "https://direct.next.cloudsigma.com/api/2.0", wantKnown: true},
+		{region: "", wantAPI: "https://zrh.cloudsigma.com/api/2.0", wantDirect: "https://direct.zrh.cloudsigma.com/api/2.0"},
+		// Unknown regions fall back to the <region>.cloudsigma.com pattern
+		{region: "xyz", wantAPI: "https://xyz.cloudsigma.com/api/2.0", wantDirect: "https://direct.xyz.cloudsigma.com/api/2.0"},
+		{region: "abc", wantAPI: "https://api.abc.example.com/api/2.0", wantDirect: "https://sp.abc.example.com/api/2.0", wantKnown: true},
+		// Overrides win over the known table
+		{region: "sjc", wantAPI: "https://sjc.example.com/api/2.0", wantDirect: "https://direct.sjc.example.com/api/2.0", wantKnown: true},
+	}
+	for _, tt := range tests {
+		e := Lookup(tt.region)
+		if got := e.APIEndpoint(); got != tt.wantAPI {
+			t.Errorf("Lookup(%q).APIEndpoint() = %q, want %q", tt.region, got, tt.wantAPI)
+		}
+		if got := e.DirectAPIEndpoint(); got != tt.wantDirect {
+			t.Errorf("Lookup(%q).DirectAPIEndpoint() = %q, want %q", tt.region, got, tt.wantDirect)
+		}
+		if got := Known(tt.region); got != tt.wantKnown {
+			t.Errorf("Known(%q) = %v, want %v", tt.region, got, tt.wantKnown)
+		}
+	}
+}
+
+func TestNames(t *testing.T) {
+	withOverride(t, "abc", Endpoints{APIHost: "api.abc.example.com", DirectHost: "sp.abc.example.com"})
+	withOverride(t, "sjc", Endpoints{APIHost: "sjc.example.com", DirectHost: "direct.sjc.example.com"})
+
+	got := strings.Join(Names(), ",")
+	if want := "abc,lvs,next,sjc,tyo,zrh"; got != want {
+		t.Errorf("Names() = %s, want %s", got, want)
+	}
+}
+
+func TestParseOverrides(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    string
+		want    map[string]Endpoints
+		wantErr bool
+	}{
+		{name: "empty", spec: "", want: map[string]Endpoints{}},
+		{
+			name: "direct host derived",
+			spec: "abc=abc.example.com",
+			want: map[string]Endpoints{"abc": {APIHost: "abc.example.com", DirectHost: "direct.abc.example.com"}},
+		},
+		{
+			name: "several regions",
+			spec: "abc=api.abc.example.com|sp.abc.example.com, def=def.example.com",
+			want: map[string]Endpoints{
+				"abc": {APIHost: "api.abc.example.com", DirectHost: "sp.abc.example.com"},
+				"def": {APIHost: "def.example.com", DirectHost: "direct.def.example.com"},
+			},
+		},
+		{name: "missing host", spec: "abc=", wantErr: true},
+		{name: "missing region", spec: "=abc.example.com", wantErr: true},
+		{name: "no separator", spec: "abc.example.com", wantErr: true},
+		{name: "url instead of host", spec: "abc=https://abc.example.com/api/2.0", wantErr: true},
+		{name: "empty direct host", spec: "abc=abc.example.com|", want: map[string]Endpoints{"abc": {APIHost: "abc.example.com", DirectHost: "direct.abc.example.com"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseOverrides(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseOverrides(%q) error = %v, wantErr %v", tt.spec, err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("ParseOverrides(%q) = %v, want %v", tt.spec, got, tt.want)
+			}
+			for region, e := range tt.want {
+				if got[region] != e {
+					t.Errorf("ParseOverrides(%q)[%s] = %+v, want %+v", tt.spec, region, got[region], e)
+				}
+			}
+		})
+	}
+}